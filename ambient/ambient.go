@@ -0,0 +1,141 @@
+// Package ambient colors smart lights elsewhere in the room after the album
+// on the display: on each track change the artwork's most prominent colors
+// are sent to Philips Hue groups and WLED segments.
+package ambient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/logging"
+	"musicDisplay/theme"
+)
+
+var logger = logging.For("ambient")
+
+const (
+	// maxColors is how many colors are taken from the artwork, one per
+	// group or segment in turn.
+	maxColors = 5
+	// requestTimeout bounds one update of one light.
+	requestTimeout = 5 * time.Second
+)
+
+// Light is a set of lights that can take the album's colors.
+type Light interface {
+	// SetColors colors the lights from colors, most prominent first, which
+	// is never empty.
+	SetColors(ctx context.Context, colors []color.RGBA) error
+	// Name identifies the lights in logs.
+	Name() string
+}
+
+// Syncer sends the colors of each new artwork to its lights. Artwork made
+// only of black, white, and greys leaves the lights as they are.
+type Syncer struct {
+	lights []Light
+	// wake tells Run there are new colors to send.
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending []color.RGBA
+	last    []color.RGBA
+}
+
+// NewSyncer returns a syncer for lights. Nothing is sent until Run starts.
+func NewSyncer(lights ...Light) *Syncer {
+	return &Syncer{lights: lights, wake: make(chan struct{}, 1)}
+}
+
+// Show queues the colors of img for the lights, replacing colors queued
+// before that have not been sent yet. It does not wait for the lights.
+func (s *Syncer) Show(img image.Image) {
+	colors := theme.ArtColors(img, maxColors)
+	if len(colors) == 0 {
+		return
+	}
+	s.mu.Lock()
+	if slices.Equal(colors, s.last) {
+		s.mu.Unlock()
+		return
+	}
+	s.pending = colors
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued colors to every light until ctx is done. A light that
+// fails is logged and tried again with the next artwork.
+func (s *Syncer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		}
+		s.mu.Lock()
+		colors := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		if colors == nil {
+			continue
+		}
+		failed := false
+		for _, light := range s.lights {
+			lightCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+			err := light.SetColors(lightCtx, colors)
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				failed = true
+				logger.Warn("ambient light update failed", "light", light.Name(), "err", err)
+				continue
+			}
+			logger.Debug("ambient light updated", "light", light.Name(), "colors", len(colors))
+		}
+		if !failed {
+			s.mu.Lock()
+			s.last = colors
+			s.mu.Unlock()
+		}
+	}
+}
+
+// sendJSON sends body to endpoint with method and checks for a 2xx reply,
+// returning the reply's body.
+func sendJSON(ctx context.Context, client *http.Client, method, endpoint, service string, body interface{}) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("ambient: %s: encode request: %w", service, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("ambient: %s: build request: %w", service, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ambient: %s: request failed: %w", service, err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("ambient: %s: %s: %s", service, resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return reply, nil
+}
@@ -0,0 +1,216 @@
+package ambient
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	blue = color.RGBA{R: 0x20, G: 0x40, B: 0xc0, A: 0xff}
+	red  = color.RGBA{R: 0xe0, G: 0x10, B: 0x10, A: 0xff}
+)
+
+// art returns artwork made of a blue area and a smaller red one.
+func art() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(blue), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 32, 16), image.NewUniform(red), image.Point{}, draw.Src)
+	return img
+}
+
+// fakeLight records the colors it is sent.
+type fakeLight struct {
+	mu    sync.Mutex
+	sent  [][]color.RGBA
+	err   error
+	calls chan struct{}
+}
+
+func newFakeLight() *fakeLight {
+	return &fakeLight{calls: make(chan struct{}, 10)}
+}
+
+func (l *fakeLight) Name() string { return "fake" }
+
+func (l *fakeLight) SetColors(_ context.Context, colors []color.RGBA) error {
+	l.mu.Lock()
+	l.sent = append(l.sent, colors)
+	err := l.err
+	l.mu.Unlock()
+	l.calls <- struct{}{}
+	return err
+}
+
+func (l *fakeLight) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-l.calls:
+	case <-time.After(5 * time.Second):
+		t.Fatal("light was not updated")
+	}
+}
+
+func (l *fakeLight) idle(t *testing.T) {
+	t.Helper()
+	select {
+	case <-l.calls:
+		t.Fatal("light updated again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSyncerSendsNewColorsOnce(t *testing.T) {
+	light := newFakeLight()
+	s := NewSyncer(light)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	s.Show(art())
+	light.wait(t)
+	light.mu.Lock()
+	got := light.sent[0]
+	light.mu.Unlock()
+	if len(got) != 2 || got[0] != blue || got[1] != red {
+		t.Fatalf("sent %v, want blue then red", got)
+	}
+
+	s.Show(art())
+	light.idle(t)
+
+	// Black and white artwork has no colors to send.
+	grey := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(grey, grey.Bounds(), image.White, image.Point{}, draw.Src)
+	s.Show(grey)
+	light.idle(t)
+}
+
+func TestSyncerRetriesAfterFailure(t *testing.T) {
+	light := newFakeLight()
+	light.err = io.ErrUnexpectedEOF
+	s := NewSyncer(light)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	s.Show(art())
+	light.wait(t)
+	s.Show(art())
+	light.wait(t)
+}
+
+func TestHue(t *testing.T) {
+	var mu sync.Mutex
+	actions := map[string]map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		var action map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&action)
+		mu.Lock()
+		actions[r.URL.Path] = action
+		mu.Unlock()
+		if strings.Contains(r.URL.Path, "/groups/9/") {
+			io.WriteString(w, `[{"error":{"type":3,"description":"resource, /groups/9, not available"}}]`)
+			return
+		}
+		io.WriteString(w, `[{"success":{"/groups/1/action/on":true}}]`)
+	}))
+	defer srv.Close()
+
+	hue := NewHue(srv.URL, "key", []string{"1", "2"}, 50)
+	if err := hue.SetColors(context.Background(), []color.RGBA{red, blue}); err != nil {
+		t.Fatalf("SetColors: %v", err)
+	}
+	first := actions["/api/key/groups/1/action"]
+	if first == nil || first["on"] != true || first["bri"] != float64(127) || first["transitiontime"] != float64(hueTransition) {
+		t.Fatalf("group 1 action = %v", first)
+	}
+	x, y := xy(red)
+	if xy := first["xy"].([]interface{}); xy[0] != x || xy[1] != y {
+		t.Fatalf("group 1 xy = %v, want red [%v %v]", xy, x, y)
+	}
+	x, y = xy(blue)
+	if xy := actions["/api/key/groups/2/action"]["xy"].([]interface{}); xy[0] != x || xy[1] != y {
+		t.Fatalf("group 2 xy = %v, want blue [%v %v]", xy, x, y)
+	}
+
+	hue = NewHue(srv.URL, "key", []string{"9"}, 0)
+	err := hue.SetColors(context.Background(), []color.RGBA{red})
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Fatalf("SetColors error = %v, want the bridge's error", err)
+	}
+	if _, ok := actions["/api/key/groups/9/action"]["bri"]; ok {
+		t.Fatal("brightness set with none configured")
+	}
+}
+
+func TestXY(t *testing.T) {
+	for _, tc := range []struct {
+		c    color.RGBA
+		x, y float64
+	}{
+		{color.RGBA{R: 0xff, A: 0xff}, 0.7006, 0.2993},
+		{color.RGBA{G: 0xff, A: 0xff}, 0.1724, 0.7468},
+		{color.RGBA{B: 0xff, A: 0xff}, 0.1355, 0.0399},
+		{color.RGBA{A: 0xff}, 0.3227, 0.3290},
+	} {
+		if x, y := xy(tc.c); x != tc.x || y != tc.y {
+			t.Errorf("xy(%v) = %v, %v, want %v, %v", tc.c, x, y, tc.x, tc.y)
+		}
+	}
+}
+
+func TestWLED(t *testing.T) {
+	var got []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/json/state" {
+			t.Errorf("request = %s %s, want POST /json/state", r.Method, r.URL.Path)
+		}
+		var state map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&state)
+		got = append(got, state)
+		io.WriteString(w, `{"success":true}`)
+	}))
+	defer srv.Close()
+
+	colors := []color.RGBA{blue, red, {G: 0xff, A: 0xff}, {R: 0xff, G: 0xff, A: 0xff}}
+	if err := NewWLED(srv.URL, nil).SetColors(context.Background(), colors); err != nil {
+		t.Fatalf("SetColors: %v", err)
+	}
+	if err := NewWLED(srv.URL, []int{2, 3}).SetColors(context.Background(), colors[:1]); err != nil {
+		t.Fatalf("SetColors: %v", err)
+	}
+	encode := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	if s := encode(got[0]["seg"]); s != `[{"col":[[32,64,192],[224,16,16],[0,255,0]],"id":0}]` {
+		t.Fatalf("segments = %s, want segment 0 with three colors", s)
+	}
+	if s := encode(got[1]["seg"]); s != `[{"col":[[32,64,192]],"id":2},{"col":[[32,64,192]],"id":3}]` {
+		t.Fatalf("segments = %s, want segments 2 and 3 in blue", s)
+	}
+	if got[0]["on"] != true {
+		t.Fatalf("state = %v, want the lights on", got[0])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := NewWLED(failing.URL, nil).SetColors(context.Background(), colors); err == nil {
+		t.Fatal("SetColors succeeded against a failing controller")
+	}
+}
@@ -0,0 +1,124 @@
+package ambient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// hueTransition is how long Hue lights take to fade to a new color, in the
+// bridge's tenths of a second.
+const hueTransition = 10
+
+// Hue colors Philips Hue groups, rooms or zones, through a bridge's local
+// API: the first group takes the artwork's most prominent color, the next
+// group the next color, and so on.
+type Hue struct {
+	Bridge string
+	// Username is the application key the bridge issued when its link
+	// button was pressed.
+	Username string
+	Groups   []string
+	// Brightness sets the groups' brightness, in percent, along with the
+	// color; zero leaves it alone.
+	Brightness int
+
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewHue returns a light for groups on the bridge at host, an address
+// with an optional port.
+func NewHue(bridge, username string, groups []string, brightness int) *Hue {
+	return &Hue{
+		Bridge:     bridge,
+		Username:   username,
+		Groups:     groups,
+		Brightness: brightness,
+		baseURL:    baseURL(bridge),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Name implements Light.
+func (h *Hue) Name() string {
+	return "hue " + h.Bridge
+}
+
+// SetColors implements Light.
+func (h *Hue) SetColors(ctx context.Context, colors []color.RGBA) error {
+	for i, group := range h.Groups {
+		x, y := xy(colors[i%len(colors)])
+		action := map[string]interface{}{
+			"on":             true,
+			"xy":             [2]float64{x, y},
+			"transitiontime": hueTransition,
+		}
+		if h.Brightness > 0 {
+			action["bri"] = max(h.Brightness*254/100, 1)
+		}
+		endpoint := fmt.Sprintf("%s/api/%s/groups/%s/action", h.baseURL, url.PathEscape(h.Username), url.PathEscape(group))
+		reply, err := sendJSON(ctx, h.httpClient, http.MethodPut, endpoint, "hue", action)
+		if err != nil {
+			return err
+		}
+		// The bridge answers 200 and lists errors, such as an unknown
+		// group or username, in the body.
+		var results []struct {
+			Error *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(reply, &results); err != nil {
+			return fmt.Errorf("ambient: hue: decode response: %w", err)
+		}
+		var problems []string
+		for _, result := range results {
+			if result.Error != nil {
+				problems = append(problems, result.Error.Description)
+			}
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("ambient: hue: group %s: %w", group, errors.New(strings.Join(problems, "; ")))
+		}
+	}
+	return nil
+}
+
+// xy converts c to the CIE 1931 chromaticity Hue lights take. The bridge
+// fits colors outside a bulb's gamut to the nearest it can show.
+func xy(c color.RGBA) (float64, float64) {
+	linear := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f > 0.04045 {
+			return math.Pow((f+0.055)/1.055, 2.4)
+		}
+		return f / 12.92
+	}
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+	sum := x + y + z
+	if sum == 0 {
+		// Black has no chromaticity; use white's.
+		return 0.3227, 0.3290
+	}
+	return math.Round(x/sum*10000) / 10000, math.Round(y/sum*10000) / 10000
+}
+
+// baseURL returns the http address of host, unless it already has a
+// scheme.
+func baseURL(host string) string {
+	host = strings.TrimRight(host, "/")
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return "http://" + host
+}
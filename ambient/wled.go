@@ -0,0 +1,63 @@
+package ambient
+
+import (
+	"context"
+	"image/color"
+	"net/http"
+)
+
+// wledTransition is how long WLED takes to fade to new colors, in its
+// tenths of a second.
+const wledTransition = 10
+
+// WLED colors the segments of a WLED controller through its JSON API. With
+// no segments listed, the first segment takes the artwork's three most
+// prominent colors as its primary, secondary, and tertiary colors, which
+// WLED's effects blend between; otherwise each listed segment takes one
+// color in turn.
+type WLED struct {
+	Host     string
+	Segments []int
+
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewWLED returns a light for segments of the controller at host.
+func NewWLED(host string, segments []int) *WLED {
+	return &WLED{Host: host, Segments: segments, baseURL: baseURL(host), httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// Name implements Light.
+func (w *WLED) Name() string {
+	return "wled " + w.Host
+}
+
+// wledSegment is a segment in WLED's state.
+type wledSegment struct {
+	ID     int      `json:"id"`
+	Colors [][3]int `json:"col"`
+}
+
+// SetColors implements Light.
+func (w *WLED) SetColors(ctx context.Context, colors []color.RGBA) error {
+	rgb := func(c color.RGBA) [3]int { return [3]int{int(c.R), int(c.G), int(c.B)} }
+	var segments []wledSegment
+	if len(w.Segments) == 0 {
+		segment := wledSegment{}
+		for _, c := range colors[:min(len(colors), 3)] {
+			segment.Colors = append(segment.Colors, rgb(c))
+		}
+		segments = append(segments, segment)
+	}
+	for i, id := range w.Segments {
+		segments = append(segments, wledSegment{ID: id, Colors: [][3]int{rgb(colors[i%len(colors)])}})
+	}
+	state := map[string]interface{}{
+		"on":         true,
+		"transition": wledTransition,
+		"seg":        segments,
+	}
+	_, err := sendJSON(ctx, w.httpClient, http.MethodPost, w.baseURL+"/json/state", "wled", state)
+	return err
+}
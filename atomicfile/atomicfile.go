@@ -0,0 +1,40 @@
+// Package atomicfile replaces files so that readers, and a crash midway,
+// see either the old contents or the new ones, never a mix.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces path with data and gives it perm. The data goes to a temp
+// file of its own next to path, is synced to disk, and is renamed over path,
+// so writers racing on the same path cannot clobber each other's half-written
+// file; the last rename wins. The directory must exist.
+func Write(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("atomicfile: create temp file for %s: %w", path, err)
+	}
+	name := tmp.Name()
+	defer os.Remove(name)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("atomicfile: write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("atomicfile: sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("atomicfile: write %s: %w", path, err)
+	}
+	if err := os.Chmod(name, perm); err != nil {
+		return fmt.Errorf("atomicfile: chmod %s: %w", path, err)
+	}
+	if err := os.Rename(name, path); err != nil {
+		return fmt.Errorf("atomicfile: replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, []byte("new"), 0o644); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("file = %q, %v; want new", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("mode = %v, %v; want 0644", info.Mode().Perm(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d files, want the temp file gone", len(entries))
+	}
+}
+
+func TestConcurrentWritesLeaveOneWholeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "devices.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Write(path, []byte(fmt.Sprintf("writer %d", i)), 0o644); err != nil {
+				t.Errorf("Write %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var i int
+	if _, err := fmt.Sscanf(string(data), "writer %d", &i); err != nil {
+		t.Fatalf("file = %q, want one writer's contents", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d files, want only %s", len(entries), filepath.Base(path))
+	}
+}
+
+func TestWriteMissingDirectory(t *testing.T) {
+	if err := Write(filepath.Join(t.TempDir(), "missing", "file"), []byte("x"), 0o644); err == nil {
+		t.Fatal("Write into a missing directory succeeded, want error")
+	}
+}
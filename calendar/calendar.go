@@ -0,0 +1,153 @@
+// Package calendar fetches an iCalendar (ICS) feed, such as a Google,
+// iCloud, or Nextcloud calendar's secret address, and draws the next event
+// as a screen the renderer can show while no music plays.
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+)
+
+var logger = logging.For("calendar")
+
+const (
+	// DefaultRefresh is how often a Source fetches the feed.
+	DefaultRefresh = 15 * time.Minute
+	// DefaultHorizon is how far ahead a Source looks for the next event.
+	DefaultHorizon = 7 * 24 * time.Hour
+
+	requestTimeout = 20 * time.Second
+	retryDelay     = time.Minute
+	// maxFeedBytes bounds the size of a feed.
+	maxFeedBytes = 16 << 20
+)
+
+// Feed fetches events from an ICS address. Username and Password are sent
+// with basic authentication when set, as CalDAV servers such as Nextcloud
+// expect for their export addresses.
+type Feed struct {
+	URL      string
+	Username string
+	Password string
+
+	httpClient *http.Client
+}
+
+// NewFeed returns a feed for rawURL. webcal:// addresses are fetched over
+// HTTPS.
+func NewFeed(rawURL, username, password string) *Feed {
+	if rest, ok := strings.CutPrefix(rawURL, "webcal://"); ok {
+		rawURL = "https://" + rest
+	}
+	return &Feed{URL: rawURL, Username: username, Password: password, httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// Fetch downloads and parses the feed. Floating times are read in loc.
+func (f *Feed) Fetch(ctx context.Context, loc *time.Location) ([]Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("calendar: build request: %w", err)
+	}
+	if f.Username != "" || f.Password != "" {
+		req.SetBasicAuth(f.Username, f.Password)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		// The error quotes the URL, which for a secret address is the
+		// credential.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("calendar: fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar: fetch feed: %s", resp.Status)
+	}
+	return Parse(io.LimitReader(resp.Body, maxFeedBytes), loc)
+}
+
+// Source keeps a feed's events fresh and answers which event is next.
+type Source struct {
+	feed    *Feed
+	refresh time.Duration
+	horizon time.Duration
+	loc     *time.Location
+
+	mu     sync.Mutex
+	events []Event
+	ok     bool
+}
+
+// NewSource returns a source that fetches feed every refresh
+// (DefaultRefresh when zero) and looks horizon (DefaultHorizon when zero)
+// ahead for the next event, once Run starts.
+func NewSource(feed *Feed, refresh, horizon time.Duration) *Source {
+	if refresh <= 0 {
+		refresh = DefaultRefresh
+	}
+	if horizon <= 0 {
+		horizon = DefaultHorizon
+	}
+	return &Source{feed: feed, refresh: refresh, horizon: horizon, loc: time.Local}
+}
+
+// Next returns the event to show at now. loaded is false until the feed has
+// been fetched once; ok is false when no event falls within the horizon.
+func (s *Source) Next(now time.Time) (event Occurrence, ok, loaded bool) {
+	s.mu.Lock()
+	events, loaded := s.events, s.ok
+	s.mu.Unlock()
+	if !loaded {
+		return Occurrence{}, false, false
+	}
+	event, ok = Next(events, now, s.horizon)
+	return event, ok, true
+}
+
+// Run fetches the feed straight away and then every refresh until ctx is
+// canceled, retrying sooner after a failure. The last events fetched stay
+// in use while the feed cannot be reached.
+func (s *Source) Run(ctx context.Context, clk clock.Clock) {
+	clk = clock.Or(clk)
+	for {
+		wait := s.refresh
+		if err := s.update(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("calendar fetch failed", "err", err)
+			wait = retryDelay
+		}
+		timer := clk.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+func (s *Source) update(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	events, err := s.feed.Fetch(ctx, s.loc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.events, s.ok = events, true
+	s.mu.Unlock()
+	logger.Debug("calendar updated", "events", len(events))
+	return nil
+}
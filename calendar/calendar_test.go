@@ -0,0 +1,216 @@
+package calendar
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+	"musicDisplay/theme"
+)
+
+const feed = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Stand-up\\, team\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240506T093000\r\n" +
+	"DURATION:PT15M\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20240508T093000\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT5M\r\n" +
+	"DURATION:PT1H\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20240510T093000\r\n" +
+	"SUMMARY:Stand-up (moved)\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240510T110000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20240510T111500\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"SUMMARY:Public holiday\r\n" +
+	"DTSTART;VALUE=DATE:20240509\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:dentist\r\n" +
+	"SUMMARY:Dentist appointment with a very long description that\r\n" +
+	"  keeps going\r\n" +
+	"DTSTART:20240507T120000Z\r\n" +
+	"DTEND:20240507T130000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20240507T080000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:broken\r\n" +
+	"DTSTART:tomorrow\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseFeed(t *testing.T) ([]Event, *time.Location) {
+	t.Helper()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	events, err := Parse(strings.NewReader(feed), berlin)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	return events, berlin
+}
+
+func TestParse(t *testing.T) {
+	events, berlin := parseFeed(t)
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4 without the cancelled and broken ones", len(events))
+	}
+	standup := events[0]
+	if standup.Summary != "Stand-up, team" || standup.End.Sub(standup.Start) != 15*time.Minute {
+		t.Fatalf("standup = %q lasting %v, want the alarm's duration ignored", standup.Summary, standup.End.Sub(standup.Start))
+	}
+	if len(standup.exdates) != 2 {
+		t.Fatalf("standup exdates = %v, want the EXDATE and the moved occurrence", standup.exdates)
+	}
+	holiday := events[2]
+	if !holiday.AllDay || !holiday.Start.Equal(time.Date(2024, 5, 9, 0, 0, 0, 0, berlin)) || holiday.End.Sub(holiday.Start) != 24*time.Hour {
+		t.Fatalf("holiday = %+v, want an all-day event", holiday)
+	}
+	if got := events[3].Summary; got != "Dentist appointment with a very long description that keeps going" {
+		t.Fatalf("folded summary = %q", got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	events, berlin := parseFeed(t)
+	at := func(day, hour, minute int) time.Time { return time.Date(2024, 5, day, hour, minute, 0, 0, berlin) }
+	for _, tc := range []struct {
+		name    string
+		now     time.Time
+		summary string
+		start   time.Time
+	}{
+		{"upcoming on Monday", at(6, 8, 0), "Stand-up, team", at(6, 9, 30)},
+		{"in progress", at(6, 9, 40), "Stand-up, team", at(6, 9, 30)},
+		{"UTC event", at(6, 10, 0), "Dentist appointment with a very long description that keeps going", at(7, 14, 0)},
+		{"excluded Wednesday", at(7, 15, 0), "Public holiday", at(9, 0, 0)},
+		{"timed event beats all-day in progress", at(9, 12, 0), "Stand-up (moved)", at(10, 11, 0)},
+		{"moved occurrence", at(10, 9, 0), "Stand-up (moved)", at(10, 11, 0)},
+		{"weeks later", at(27, 9, 31), "Stand-up, team", at(27, 9, 30)},
+	} {
+		got, ok := Next(events, tc.now, DefaultHorizon)
+		if !ok || got.Summary != tc.summary || !got.Start.Equal(tc.start) {
+			t.Errorf("%s: Next = %q at %v (%v), want %q at %v", tc.name, got.Summary, got.Start, ok, tc.summary, tc.start)
+		}
+	}
+	if _, ok := Next(events[2:3], at(12, 0, 0), DefaultHorizon); ok {
+		t.Error("Next found a past one-off event")
+	}
+}
+
+func TestRuleCountUntilAndMonthEnds(t *testing.T) {
+	start := time.Date(2024, 1, 31, 8, 0, 0, 0, time.UTC)
+	collect := func(rule string) []string {
+		r, err := parseRule(rule, time.UTC)
+		if err != nil {
+			t.Fatalf("parseRule(%q) error: %v", rule, err)
+		}
+		var got []string
+		Event{Start: start, rule: r}.occurrences(start, func(t time.Time) bool {
+			got = append(got, t.Format("2006-01-02"))
+			return len(got) < 5
+		})
+		return got
+	}
+	if got := strings.Join(collect("FREQ=MONTHLY;COUNT=3"), " "); got != "2024-01-31 2024-03-31 2024-05-31" {
+		t.Errorf("monthly = %s, want months without a 31st skipped", got)
+	}
+	if got := strings.Join(collect("FREQ=DAILY;INTERVAL=2;UNTIL=20240205T000000Z"), " "); got != "2024-01-31 2024-02-02 2024-02-04" {
+		t.Errorf("daily = %s", got)
+	}
+	if got := strings.Join(collect("FREQ=MONTHLY;BYDAY=2MO"), " "); got != "2024-01-31" {
+		t.Errorf("unsupported rule = %s, want only the first occurrence", got)
+	}
+}
+
+func TestFeedFetchesWithBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "pw" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	events, err := NewFeed(server.URL, "me", "pw").Fetch(context.Background(), time.UTC)
+	if err != nil || len(events) != 4 {
+		t.Fatalf("Fetch = %d events, %v", len(events), err)
+	}
+	if _, err := NewFeed(server.URL, "", "").Fetch(context.Background(), time.UTC); err == nil {
+		t.Fatal("expected an error without credentials")
+	}
+	if got := NewFeed("webcal://example.com/cal.ics", "", "").URL; got != "https://example.com/cal.ics" {
+		t.Fatalf("webcal URL = %q", got)
+	}
+}
+
+func TestWrapSplitsAndTruncates(t *testing.T) {
+	lines := wrap(text.Medium, "Dentist appointment with a very long description that keeps going", 62, 3)
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "..") {
+		t.Fatalf("lines = %q, want three ending in ..", lines)
+	}
+	for _, line := range lines {
+		if w := text.Medium.Measure(line); w > 62 {
+			t.Fatalf("line %q is %d pixels wide", line, w)
+		}
+	}
+	if got := wrap(text.Medium, "Supercalifragilistic", 30, 3); len(got) < 2 || strings.Join(got, "") != "Supercalifragilistic" && !strings.HasSuffix(got[len(got)-1], "..") {
+		t.Fatalf("long word = %q, want it split across lines", got)
+	}
+	if got := wrap(text.Medium, "Lunch", 62, 3); len(got) != 1 || got[0] != "Lunch" {
+		t.Fatalf("short title = %q", got)
+	}
+}
+
+func TestScreenDrawsNextEvent(t *testing.T) {
+	events, berlin := parseFeed(t)
+	source := &Source{events: events, ok: true, horizon: DefaultHorizon}
+	screen := NewScreen(source)
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, berlin)
+	if got := screen.dayLabel(Occurrence{Start: now.Add(time.Hour)}, now); got != "Today" {
+		t.Fatalf("dayLabel = %q, want Today", got)
+	}
+	if got := screen.dayLabel(Occurrence{Start: now.Add(26 * time.Hour)}, now); got != "Tomorrow" {
+		t.Fatalf("dayLabel = %q, want Tomorrow", got)
+	}
+	if got := screen.dayLabel(Occurrence{Start: now.Add(80 * time.Hour)}, now); got != "Thu 9 May" {
+		t.Fatalf("dayLabel = %q, want the date", got)
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := screen.Draw(frame, render.FrameState{Palette: theme.DefaultPalette, Now: now}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	rows := map[bool]bool{}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if frame.RGBAAt(x, y) == theme.DefaultPalette.Text {
+				rows[y >= 32] = true
+			}
+		}
+	}
+	if !rows[false] || !rows[true] {
+		t.Fatalf("text drawn in top half %v, bottom half %v, want the time above the title", rows[false], rows[true])
+	}
+}
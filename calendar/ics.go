@@ -0,0 +1,442 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences bounds how far a recurring event is expanded when looking
+// for the next occurrence.
+const maxOccurrences = 5000
+
+// Event is one VEVENT from a calendar feed. Recurring events carry their
+// rule and are expanded by Next.
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+	// AllDay is set for events with a date rather than a time, which start
+	// and end at midnight in the feed's local time zone.
+	AllDay bool
+
+	rule    *rrule
+	exdates []time.Time
+	// recurrenceID marks an edited occurrence of the recurring event with
+	// the same UID.
+	recurrenceID time.Time
+}
+
+// Occurrence is a single instance of an event.
+type Occurrence struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+}
+
+// rrule is the subset of RFC 5545 recurrence rules Next expands: FREQ with
+// INTERVAL, COUNT, UNTIL, and BYDAY for weekly rules. Rules using other
+// parts only produce their first occurrence.
+type rrule struct {
+	freq      string
+	interval  int
+	count     int
+	until     time.Time
+	byDay     []time.Weekday
+	supported bool
+}
+
+// Parse reads the events from an iCalendar feed. Floating times and dates
+// are read in loc. Cancelled events and events with values that cannot be
+// read are left out.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("calendar: read feed: %w", err)
+	}
+	var events []Event
+	var current *Event
+	var duration time.Duration
+	var skip bool
+	// nested counts components inside the event, such as alarms, whose
+	// properties are not the event's.
+	nested := 0
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current, duration, skip, nested = &Event{}, 0, false, 0
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil && !current.Start.IsZero() && !skip {
+				finish(current, duration)
+				events = append(events, *current)
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+			continue
+		case name == "END":
+			nested--
+			continue
+		case nested > 0:
+			continue
+		}
+		var err error
+		switch name {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescape(value)
+		case "STATUS":
+			skip = skip || strings.EqualFold(value, "CANCELLED")
+		case "DTSTART":
+			current.Start, current.AllDay, err = parseTime(value, params, loc)
+		case "DTEND":
+			current.End, _, err = parseTime(value, params, loc)
+		case "DURATION":
+			duration, err = parseDuration(value)
+		case "RRULE":
+			current.rule, err = parseRule(value, loc)
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				var t time.Time
+				if t, _, err = parseTime(v, params, loc); err != nil {
+					break
+				}
+				current.exdates = append(current.exdates, t)
+			}
+		case "RECURRENCE-ID":
+			current.recurrenceID, _, err = parseTime(value, params, loc)
+		}
+		if err != nil {
+			logger.Debug("skipping calendar event", "uid", current.UID, "property", name, "err", err)
+			skip = true
+		}
+	}
+	return mergeOverrides(events), nil
+}
+
+// finish fills in an event's end from its duration, or the default length.
+func finish(e *Event, duration time.Duration) {
+	switch {
+	case !e.End.IsZero():
+	case duration > 0:
+		e.End = e.Start.Add(duration)
+	case e.AllDay:
+		e.End = e.Start.AddDate(0, 0, 1)
+	default:
+		e.End = e.Start
+	}
+}
+
+// mergeOverrides drops the occurrences that edited instances replace from
+// their recurring events. The edited instances stay as events of their own.
+func mergeOverrides(events []Event) []Event {
+	masters := map[string]int{}
+	for i, e := range events {
+		if e.rule != nil && e.recurrenceID.IsZero() {
+			masters[e.UID] = i
+		}
+	}
+	for _, e := range events {
+		if i, ok := masters[e.UID]; ok && !e.recurrenceID.IsZero() {
+			events[i].exdates = append(events[i].exdates, e.recurrenceID)
+		}
+	}
+	return events
+}
+
+// unfold reads content lines, joining lines continued with leading
+// whitespace.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "NAME;PARAM=V:VALUE" into its parts. Colons inside
+// quoted parameter values do not end the parameters.
+func splitProperty(line string) (string, map[string]string, string) {
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		if key, value, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// parseTime reads a DATE or DATE-TIME value. UTC times end in Z; others use
+// their TZID or, when it is missing or unknown, loc.
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration reads the day, hour, minute, and second parts of an
+// RFC 5545 duration such as "PT1H30M" or "P1D".
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(value), "+"), "P")
+	if s == value || s == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var total time.Duration
+	number := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+		switch c {
+		case 'W':
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case 'D':
+			total += time.Duration(n) * 24 * time.Hour
+		case 'H':
+			total += time.Duration(n) * time.Hour
+		case 'M':
+			total += time.Duration(n) * time.Minute
+		case 'S':
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	return total, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRule reads an RRULE value.
+func parseRule(value string, loc *time.Location) (*rrule, error) {
+	rule := &rrule{interval: 1, supported: true}
+	for _, part := range strings.Split(value, ";") {
+		key, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(v)
+		case "COUNT":
+			rule.count, err = strconv.Atoi(v)
+		case "UNTIL":
+			rule.until, _, err = parseTime(v, nil, loc)
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				wd, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					// Ordinal days such as "2MO" need monthly expansion.
+					rule.supported = false
+					continue
+				}
+				rule.byDay = append(rule.byDay, wd)
+			}
+		case "WKST":
+		default:
+			rule.supported = false
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q", value)
+		}
+	}
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		rule.supported = false
+	}
+	if len(rule.byDay) > 0 && rule.freq != "WEEKLY" {
+		rule.supported = false
+	}
+	if rule.interval < 1 {
+		rule.interval = 1
+	}
+	return rule, nil
+}
+
+// occurrences calls fn with the start of each occurrence in order, before
+// exclusions, until fn returns false or the rule ends. Daily and weekly
+// rules without a COUNT skip ahead to shortly before from.
+func (e Event) occurrences(from time.Time, fn func(time.Time) bool) {
+	rule := e.rule
+	if rule == nil || !rule.supported {
+		fn(e.Start)
+		return
+	}
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !rule.until.IsZero() && t.After(rule.until) {
+			return false
+		}
+		if rule.count > 0 && emitted >= rule.count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+	first := 0
+	if rule.count == 0 && from.After(e.Start) {
+		switch rule.freq {
+		case "DAILY":
+			first = int(from.Sub(e.Start).Hours()/24)/rule.interval - 1
+		case "WEEKLY":
+			first = int(from.Sub(e.Start).Hours()/(24*7))/rule.interval - 1
+		}
+		first = max(first, 0)
+	}
+	for n := first; n < first+maxOccurrences; n++ {
+		step := n * rule.interval
+		switch rule.freq {
+		case "DAILY":
+			if !emit(e.Start.AddDate(0, 0, step)) {
+				return
+			}
+		case "WEEKLY":
+			if len(rule.byDay) == 0 {
+				if !emit(e.Start.AddDate(0, 0, 7*step)) {
+					return
+				}
+				continue
+			}
+			// Weeks run from Monday, the default WKST.
+			monday := e.Start.AddDate(0, 0, -((int(e.Start.Weekday())+6)%7)+7*step)
+			days := slices.Clone(rule.byDay)
+			slices.SortFunc(days, func(a, b time.Weekday) int { return (int(a)+6)%7 - (int(b)+6)%7 })
+			for _, wd := range days {
+				t := monday.AddDate(0, 0, (int(wd)+6)%7)
+				if t.Before(e.Start) {
+					continue
+				}
+				if !emit(t) {
+					return
+				}
+			}
+		case "MONTHLY", "YEARLY":
+			t := e.Start.AddDate(0, step, 0)
+			if rule.freq == "YEARLY" {
+				t = e.Start.AddDate(step, 0, 0)
+			}
+			// Months without the start's day, such as February 30th,
+			// are skipped rather than rolled over.
+			if t.Day() != e.Start.Day() {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// Next returns the event to show at now, looking up to horizon ahead: a
+// timed event in progress, else the one starting soonest, else an all-day
+// event in progress, so an all-day entry does not hide the day's meetings.
+// Among events in progress, the one that started last wins.
+func Next(events []Event, now time.Time, horizon time.Duration) (Occurrence, bool) {
+	var best Occurrence
+	found := false
+	limit := now.Add(horizon)
+	rank := func(o Occurrence) int {
+		switch {
+		case o.Start.After(now):
+			return 1
+		case o.AllDay:
+			return 2
+		}
+		return 0
+	}
+	better := func(o Occurrence) bool {
+		if !found || rank(o) != rank(best) {
+			return !found || rank(o) < rank(best)
+		}
+		if rank(o) == 1 {
+			return o.Start.Before(best.Start)
+		}
+		return o.Start.After(best.Start)
+	}
+	for _, e := range events {
+		length := e.End.Sub(e.Start)
+		e.occurrences(now.Add(-length), func(start time.Time) bool {
+			if start.After(limit) {
+				return false
+			}
+			end := start.Add(length)
+			if !end.After(now) && !(length == 0 && start.Equal(now)) {
+				return true
+			}
+			if slices.ContainsFunc(e.exdates, start.Equal) {
+				return true
+			}
+			o := Occurrence{Summary: e.Summary, Start: start, End: end, AllDay: e.AllDay}
+			if better(o) {
+				best, found = o, true
+			}
+			// Later occurrences of this event start later still.
+			return start.Before(now)
+		})
+	}
+	return best, found
+}
+
+// unescape reverses the escaping of TEXT values.
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,126 @@
+package calendar
+
+import (
+	"image"
+	"strings"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+// ScreenName is the name the calendar screen is shown by, such as in
+// idle_screen or a rotation.
+const ScreenName = "calendar"
+
+// titleLines is how many lines of the event title the screen shows.
+const titleLines = 3
+
+// Screen draws the next event from a Source: when it is on the first line,
+// its start time large below that, and its title wrapped underneath. It
+// implements render.Screen.
+type Screen struct {
+	source *Source
+	// TwelveHour shows times as "2:30pm" instead of "14:30".
+	TwelveHour bool
+}
+
+// NewScreen returns a screen showing the next event from source.
+func NewScreen(source *Source) *Screen {
+	return &Screen{source: source}
+}
+
+// Name implements render.Screen.
+func (s *Screen) Name() string {
+	return ScreenName
+}
+
+// Draw implements render.Screen: the next event's day in the accent color,
+// its start time in large digits, or "until" its end while it runs, and its
+// title wrapped onto the lines below. With no event it says "No events", or
+// "Loading" before the calendar was first read.
+func (s *Screen) Draw(frame *image.RGBA, state render.FrameState) error {
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	font := text.Medium
+	lineHeight := (font.Height() + 1) * scale
+	event, ok, loaded := s.source.Next(state.Now)
+	if !ok {
+		message := "No events"
+		if !loaded {
+			message = "Loading"
+		}
+		width := font.Measure(message) * scale
+		font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, bounds.Min.Y+(bounds.Dy()-font.Ascent()*scale)/2, message, state.Palette.Text, scale)
+		return nil
+	}
+
+	x, y := bounds.Min.X+scale, bounds.Min.Y+2*scale
+	font.Draw(frame, x, y, s.dayLabel(event, state.Now), state.Palette.Accent, scale)
+	y += lineHeight + scale
+
+	when := s.clock(event.Start.In(state.Now.Location()))
+	switch {
+	case event.AllDay:
+		when = "All day"
+	case !event.Start.After(state.Now):
+		when = "until " + s.clock(event.End.In(state.Now.Location()))
+	}
+	timeScale := 2 * scale
+	if font.Measure(when)*timeScale > bounds.Dx()-2*scale {
+		timeScale = scale
+	}
+	font.Draw(frame, x, y, when, state.Palette.Text, timeScale)
+	y += font.Ascent()*timeScale + 3*scale
+
+	for _, line := range wrap(font, event.Summary, (bounds.Dx()-2*scale)/scale, titleLines) {
+		font.Draw(frame, x, y, line, state.Palette.Text, scale)
+		y += lineHeight
+	}
+	return nil
+}
+
+// dayLabel says when event is relative to now: "Now", "Today",
+// "Tomorrow", or a weekday and date such as "Fri 3 May".
+func (s *Screen) dayLabel(event Occurrence, now time.Time) string {
+	if !event.Start.After(now) {
+		return "Now"
+	}
+	start := event.Start.In(now.Location())
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	switch {
+	case start.Before(today.AddDate(0, 0, 1)):
+		return "Today"
+	case start.Before(today.AddDate(0, 0, 2)):
+		return "Tomorrow"
+	}
+	return start.Format("Mon 2 Jan")
+}
+
+func (s *Screen) clock(t time.Time) string {
+	if s.TwelveHour {
+		return t.Format("3:04pm")
+	}
+	return t.Format("15:04")
+}
+
+// wrap breaks s into at most lines lines no wider than width pixels,
+// ending the last line with ".." when s does not fit.
+func wrap(font *text.Font, s string, width, lines int) []string {
+	out := font.Wrap(s, width)
+	if len(out) <= lines {
+		return out
+	}
+	return ellipsize(font, out[:lines], width)
+}
+
+// ellipsize marks the last of lines as cut short.
+func ellipsize(font *text.Font, lines []string, width int) []string {
+	last := []rune(lines[len(lines)-1])
+	for len(last) > 0 && font.Measure(string(last)+"..") > width {
+		last = last[:len(last)-1]
+	}
+	lines[len(lines)-1] = strings.TrimRight(string(last), " ") + ".."
+	return lines
+}
@@ -0,0 +1,218 @@
+// Package clock abstracts the passage of time so timer-driven code such as the
+// event listener and the schedulers can be driven deterministically in tests.
+// Production code uses Real; tests use a Fake and move it forward with
+// Advance.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is the subset of time.Timer used by this program.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the subset of time.Ticker used by this program.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real is the wall clock.
+var Real Clock = realClock{}
+
+// Or returns c, or Real when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time        { return t.t.C }
+func (t realTimer) Stop() bool                 { return t.t.Stop() }
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time   { return t.t.C }
+func (t realTicker) Stop()                 { t.t.Stop() }
+func (t realTicker) Reset(d time.Duration) { t.t.Reset(d) }
+
+// Fake is a manually advanced clock. Its timers fire only from Advance, which
+// delivers each tick the way a real timer does: into a one-slot channel,
+// dropping it if the previous tick has not been received.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// NewTimer creates a timer that fires once d has elapsed on the fake clock.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+// NewTicker creates a ticker that fires every d on the fake clock.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(d, d)}
+}
+
+// After returns a channel that receives the fake time once d has elapsed.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.add(d, 0).c
+}
+
+// Advance moves the clock forward by d, firing due timers in deadline order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	target := f.now.Add(d)
+	for {
+		due := f.nextDueLocked(target)
+		if due == nil {
+			break
+		}
+		f.now = due.when
+		select {
+		case due.c <- f.now:
+		default:
+		}
+		if due.period > 0 {
+			due.when = due.when.Add(due.period)
+		} else {
+			f.removeLocked(due)
+		}
+	}
+	f.now = target
+}
+
+// Timers reports how many timers and tickers are armed. Tests use it to wait
+// until the code under test has started waiting.
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1), when: f.now.Add(d), period: period}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+func (f *Fake) nextDueLocked(target time.Time) *fakeTimer {
+	sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].when.Before(f.timers[j].when) })
+	if len(f.timers) == 0 || f.timers[0].when.After(target) {
+		return nil
+	}
+	return f.timers[0]
+}
+
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	f      *Fake
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+// Stop disarms the timer and discards an undelivered tick, matching
+// time.Timer since Go 1.23.
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.drainLocked()
+	return t.f.removeLocked(t)
+}
+
+// Reset rearms the timer, discarding an undelivered tick.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.drainLocked()
+	active := t.f.removeLocked(t)
+	t.when = t.f.now.Add(d)
+	if t.period > 0 {
+		t.period = d
+	}
+	t.f.timers = append(t.f.timers, t)
+	return active
+}
+
+func (t *fakeTimer) drainLocked() {
+	select {
+	case <-t.c:
+	default:
+	}
+}
+
+// fakeTicker adapts fakeTimer to Ticker, whose Stop and Reset return nothing.
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop()                 { t.fakeTimer.Stop() }
+func (t fakeTicker) Reset(d time.Duration) { t.fakeTimer.Reset(d) }
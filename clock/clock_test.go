@@ -0,0 +1,74 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimersFireInOrder(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	late := f.NewTimer(2 * time.Minute)
+	early := f.NewTimer(time.Minute)
+	ticker := f.NewTicker(30 * time.Second)
+
+	f.Advance(59 * time.Second)
+	select {
+	case <-early.C():
+		t.Fatalf("timer fired early")
+	default:
+	}
+	if got := <-ticker.C(); !got.Equal(start.Add(30 * time.Second)) {
+		t.Fatalf("tick at %v, want %v", got, start.Add(30*time.Second))
+	}
+
+	f.Advance(time.Second)
+	if got := <-early.C(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("timer fired at %v, want %v", got, start.Add(time.Minute))
+	}
+	if f.Since(start) != time.Minute {
+		t.Fatalf("Since = %v, want 1m", f.Since(start))
+	}
+
+	if !late.Stop() {
+		t.Fatalf("Stop on an armed timer returned false")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-late.C():
+		t.Fatalf("stopped timer fired")
+	default:
+	}
+
+	// The ticker kept firing but the undelivered ticks were dropped.
+	ticker.Stop()
+	if n := f.Timers(); n != 0 {
+		t.Fatalf("%d timers armed after stopping all", n)
+	}
+}
+
+func TestFakeTimerReset(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	timer := f.NewTimer(time.Second)
+	f.Advance(time.Second)
+	// Reset discards the undelivered tick and rearms.
+	if timer.Reset(time.Minute) {
+		t.Fatalf("Reset of a fired timer reported it active")
+	}
+	select {
+	case <-timer.C():
+		t.Fatalf("stale tick delivered after Reset")
+	default:
+	}
+	f.Advance(time.Minute)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatalf("reset timer did not fire")
+	}
+
+	after := f.After(time.Second)
+	f.Advance(time.Second)
+	<-after
+}
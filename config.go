@@ -6,26 +6,742 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
+
+	"musicDisplay/ambient"
+	"musicDisplay/diagnostics"
+	"musicDisplay/logging"
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/overlay"
+	"musicDisplay/powersave"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+	"musicDisplay/visualizer"
 )
 
 // Config contains optional configuration overrides loaded from disk.
 type Config struct {
-	Room               string `json:"room"`
-	Brightness         *int   `json:"brightness,omitempty"`
-	IdleTimeoutSeconds *int   `json:"idle_timeout_seconds,omitempty"`
+	Room               string                 `json:"room"`
+	Brightness         *int                   `json:"brightness,omitempty"`
+	BrightnessSchedule map[string]int         `json:"brightness_schedule,omitempty"`
+	Matrix             *MatrixConfig          `json:"matrix,omitempty"`
+	IdleTimeoutSeconds *int                   `json:"idle_timeout_seconds,omitempty"`
+	StateTimeouts      *StateTimeoutsConfig   `json:"state_timeouts,omitempty"`
+	RequestTimeouts    *RequestTimeoutsConfig `json:"request_timeouts,omitempty"`
+	DimLevel           *int                   `json:"dim_level,omitempty"`
+	ProgressBar        bool                   `json:"progress_bar,omitempty"`
+	Ticker             *TickerConfig          `json:"ticker,omitempty"`
+	SourceBadge        *SourceBadgeConfig     `json:"source_badge,omitempty"`
+	PlayMode           *PlayModeConfig        `json:"play_mode,omitempty"`
+	Transition         *TransitionConfig      `json:"transition,omitempty"`
+	BurnIn             *BurnInConfig          `json:"burn_in,omitempty"`
+	Rotation           *RotationConfig        `json:"rotation,omitempty"`
+	Weather            *WeatherConfig         `json:"weather,omitempty"`
+	Calendar           *CalendarConfig        `json:"calendar,omitempty"`
+	Timer              *TimerConfig           `json:"timer,omitempty"`
+	WiFi               *WiFiConfig            `json:"wifi,omitempty"`
+	Lyrics             *LyricsConfig          `json:"lyrics,omitempty"`
+	Visualizer         *VisualizerConfig      `json:"visualizer,omitempty"`
+	Ambient            *AmbientConfig         `json:"ambient,omitempty"`
+	PowerSave          *PowerSaveConfig       `json:"power_save,omitempty"`
+	// FrameRate is how many frames per second the renderer produces while
+	// something on screen moves.
+	FrameRate  int  `json:"frame_rate,omitempty"`
+	ArtPalette bool `json:"art_palette,omitempty"`
+	// LargeText starts in the large-text mode, which the control API and
+	// MQTT can also toggle.
+	LargeText      bool           `json:"large_text,omitempty"`
+	IdleScreen     string         `json:"idle_screen,omitempty"`
+	IdleAnimation  string         `json:"idle_animation,omitempty"`
+	Clock          *ClockConfig   `json:"clock,omitempty"`
+	Latitude       *float64       `json:"latitude,omitempty"`
+	Longitude      *float64       `json:"longitude,omitempty"`
+	Themes         []ThemeConfig  `json:"themes,omitempty"`
+	SpecialDays    []SpecialDay   `json:"special_days,omitempty"`
+	Scenes         []SceneConfig  `json:"scenes,omitempty"`
+	Scripts        []ScriptConfig `json:"scripts,omitempty"`
+	Spotify        *SpotifyConfig `json:"spotify,omitempty"`
+	ITunesLookup   bool           `json:"itunes_lookup,omitempty"`
+	ITunesCountry  string         `json:"itunes_country,omitempty"`
+	SilenceMinutes map[string]int `json:"silence_minutes,omitempty"`
+	Export         *ExportConfig  `json:"export,omitempty"`
+	MPRIS          bool           `json:"mpris,omitempty"`
+	API            *APIConfig     `json:"api,omitempty"`
+	Daemon         *DaemonConfig  `json:"daemon,omitempty"`
+	Startup        *StartupConfig `json:"startup,omitempty"`
+	MQTT           *MQTTConfig    `json:"mqtt,omitempty"`
+	Display        string         `json:"display,omitempty"`
+	// GamutPreview makes the emulated displays simulate the matrix's color
+	// depth, like -gamut-preview.
+	GamutPreview bool `json:"gamut_preview,omitempty"`
+	// RemoteSink is the host:port of the frame sink the remote display
+	// pushes frames to, or several separated by commas.
+	RemoteSink    string               `json:"remote_sink,omitempty"`
+	Logging       *LoggingConfig       `json:"logging,omitempty"`
+	Callback      *CallbackConfig      `json:"callback,omitempty"`
+	Discovery     string               `json:"discovery,omitempty"`
+	Devices       []DeviceConfig       `json:"devices,omitempty"`
+	DeviceCache   string               `json:"device_cache,omitempty"`
+	ArtCache      *ArtCacheConfig      `json:"art_cache,omitempty"`
+	ArtProcessing *ArtProcessingConfig `json:"art_processing,omitempty"`
+	ArtFetch      *ArtFetchConfig      `json:"art_fetch,omitempty"`
+	Placeholder   *PlaceholderConfig   `json:"placeholder,omitempty"`
+	// Profiles are named overrides selected with -profile. A profile holds
+	// any of the settings above; they replace the top-level values, with
+	// nested objects merged field by field.
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
+}
+
+// LoggingConfig selects the log level and format. Packages overrides the
+// level for individual packages: main, sonos, render, matrixdisplay, and so on.
+type LoggingConfig struct {
+	Level    string            `json:"level,omitempty"`
+	Format   string            `json:"format,omitempty"`
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+func (c *LoggingConfig) options() logging.Options {
+	return logging.Options{Level: c.Level, Format: c.Format, Packages: c.Packages}
+}
+
+// DeviceConfig names a speaker by address so discovery can be skipped. Port
+// defaults to 1400.
+type DeviceConfig struct {
+	Room string `json:"room,omitempty"`
+	IP   string `json:"ip"`
+	Port int    `json:"port,omitempty"`
+}
+
+// CallbackConfig fixes where the Sonos event callback server listens and the
+// address speakers are told to use. Omitted fields keep the automatic choice:
+// a free port on the interface that routes to the speaker.
+type CallbackConfig struct {
+	Port      int    `json:"port,omitempty"`
+	Bind      string `json:"bind,omitempty"`
+	Advertise string `json:"advertise,omitempty"`
+}
+
+// ArtCacheConfig sets how album art is cached. Mode is "disk" (default) or
+// "memory"; Dir moves the disk cache, e.g. onto a tmpfs; MemoryEntries bounds
+// the in-memory cache. Format is "png" (default), "jpeg", or "original", and
+// Quality the JPEG quality (default 85). MaxSizeMB, MaxFiles, and MaxAgeDays
+// bound the disk cache, which is pruned at startup and hourly; 0 is
+// unlimited.
+type ArtCacheConfig struct {
+	Mode          string `json:"mode,omitempty"`
+	Dir           string `json:"dir,omitempty"`
+	MemoryEntries int    `json:"memory_entries,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Quality       int    `json:"quality,omitempty"`
+	MaxSizeMB     int    `json:"max_size_mb,omitempty"`
+	MaxFiles      int    `json:"max_files,omitempty"`
+	MaxAgeDays    int    `json:"max_age_days,omitempty"`
+}
+
+// ArtProcessingConfig tunes how album art is scaled for the panel. Scaler is
+// "bilinear" (default), "catmull-rom", "box", or "nearest"; Gamma, when set,
+// corrects for the linear LEDs; Dither is "none" (default), "ordered", or "floyd-steinberg",
+// reducing each channel to DitherBits bits (default 5).
+type ArtProcessingConfig struct {
+	Scaler     string  `json:"scaler,omitempty"`
+	Gamma      float64 `json:"gamma,omitempty"`
+	Dither     string  `json:"dither,omitempty"`
+	DitherBits int     `json:"dither_bits,omitempty"`
+}
+
+// ArtFetchConfig limits the album art accepted from speakers and stations:
+// MaxKB bounds the download and MaxDimension each side of the image. Zero
+// keeps the defaults of 4096 KB and 4096 pixels. Attempts requests, DelayMS
+// apart, are made for art the speaker has not got yet (defaults 3 and 200);
+// while the track plays it is fetched again for GraceSeconds after that
+// (default 30, 0 turns it off).
+type ArtFetchConfig struct {
+	MaxKB        int  `json:"max_kb,omitempty"`
+	MaxDimension int  `json:"max_dimension,omitempty"`
+	Attempts     int  `json:"attempts,omitempty"`
+	DelayMS      int  `json:"delay_ms,omitempty"`
+	GraceSeconds *int `json:"grace_seconds,omitempty"`
+}
+
+// PlaceholderConfig enables artwork for tracks that have none. Image, when
+// set, is shown for all of them; otherwise the title or station name is drawn
+// on a colored square.
+type PlaceholderConfig struct {
+	Image string `json:"image,omitempty"`
+}
+
+// MQTTConfig connects the display to an MQTT broker. Topics live under
+// TopicPrefix (default "walldisplay"); Home Assistant discovery is on unless
+// Discovery is false.
+type MQTTConfig struct {
+	Broker          string `json:"broker"`
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	TopicPrefix     string `json:"topic_prefix,omitempty"`
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+	Discovery       *bool  `json:"discovery,omitempty"`
+}
+
+// DaemonConfig tunes the daemon command. HealthAddr is where GET /healthz
+// is served, 127.0.0.1:8066 by default or "off"; CheckSeconds is how often
+// the speaker is checked, 60 by default.
+type DaemonConfig struct {
+	HealthAddr   string `json:"health_addr,omitempty"`
+	CheckSeconds int    `json:"check_seconds,omitempty"`
+}
+
+// StartupConfig says what happens when the configured room's speaker is not
+// found at startup. With WaitForSpeakers the idle screen is shown and the
+// room is looked for every RetrySeconds, 30 by default, instead of exiting.
+type StartupConfig struct {
+	WaitForSpeakers bool `json:"wait_for_speakers,omitempty"`
+	RetrySeconds    int  `json:"retry_seconds,omitempty"`
+}
+
+// APIConfig enables the HTTP control API on Addr, e.g. ":8065".
+type APIConfig struct {
+	Addr string `json:"addr"`
+}
+
+// ExportConfig writes the current track and artwork to files in Dir.
+type ExportConfig struct {
+	Dir string `json:"dir"`
+}
+
+// StateTimeoutsConfig sets per-state display timeouts. States that are
+// omitted keep the defaults: playing never times out, paused and stopped
+// switch to the idle screen after idle_timeout_seconds.
+type StateTimeoutsConfig struct {
+	Playing *StateTimeoutConfig `json:"playing,omitempty"`
+	Paused  *StateTimeoutConfig `json:"paused,omitempty"`
+	Stopped *StateTimeoutConfig `json:"stopped,omitempty"`
+}
+
+// StateTimeoutConfig dims the display after DimAfterSeconds and switches to
+// the idle screen after IdleAfterSeconds. Omitted fields keep the state's
+// default; 0 disables the step.
+type StateTimeoutConfig struct {
+	DimAfterSeconds  *int `json:"dim_after_seconds,omitempty"`
+	IdleAfterSeconds *int `json:"idle_after_seconds,omitempty"`
+}
+
+// RequestTimeoutsConfig bounds the requests made to the speakers, in
+// seconds by kind. Omitted fields keep the defaults; Slow overrides the
+// longer ones used for the oldest S1 players, such as the ZP90 and ZP120.
+type RequestTimeoutsConfig struct {
+	ControlSeconds     int                    `json:"control_seconds,omitempty"`
+	SubscribeSeconds   int                    `json:"subscribe_seconds,omitempty"`
+	PollSeconds        int                    `json:"poll_seconds,omitempty"`
+	DescriptionSeconds int                    `json:"description_seconds,omitempty"`
+	ArtSeconds         int                    `json:"art_seconds,omitempty"`
+	Slow               *RequestTimeoutsConfig `json:"slow,omitempty"`
+}
+
+func (c *RequestTimeoutsConfig) validate() error {
+	for name, seconds := range map[string]int{
+		"control_seconds":     c.ControlSeconds,
+		"subscribe_seconds":   c.SubscribeSeconds,
+		"poll_seconds":        c.PollSeconds,
+		"description_seconds": c.DescriptionSeconds,
+		"art_seconds":         c.ArtSeconds,
+	} {
+		if seconds < 0 || seconds > 120 {
+			return fmt.Errorf("%s must be between 0 and 120, got %d", name, seconds)
+		}
+	}
+	if c.Slow != nil {
+		if c.Slow.Slow != nil {
+			return fmt.Errorf("slow cannot have its own slow section")
+		}
+		if err := c.Slow.validate(); err != nil {
+			return fmt.Errorf("slow: %w", err)
+		}
+	}
+	return nil
+}
+
+// timeouts returns the timeouts set, leaving the others zero; nil sets
+// none.
+func (c *RequestTimeoutsConfig) timeouts() sonos.Timeouts {
+	if c == nil {
+		return sonos.Timeouts{}
+	}
+	return sonos.Timeouts{
+		Control:     time.Duration(c.ControlSeconds) * time.Second,
+		Subscribe:   time.Duration(c.SubscribeSeconds) * time.Second,
+		Poll:        time.Duration(c.PollSeconds) * time.Second,
+		Description: time.Duration(c.DescriptionSeconds) * time.Second,
+		Art:         time.Duration(c.ArtSeconds) * time.Second,
+	}
+}
+
+// MatrixConfig describes the panel hardware. Omitted fields default to a
+// single 64x64 panel on an Adafruit RGB Matrix Bonnet.
+type MatrixConfig struct {
+	Rows              int    `json:"rows,omitempty"`
+	Cols              int    `json:"cols,omitempty"`
+	Chain             int    `json:"chain,omitempty"`
+	Parallel          int    `json:"parallel,omitempty"`
+	HardwareMapping   string `json:"hardware_mapping,omitempty"`
+	PWMBits           int    `json:"pwm_bits,omitempty"`
+	PWMLSBNanoseconds int    `json:"pwm_lsb_nanoseconds,omitempty"`
+	Slowdown          int    `json:"slowdown,omitempty"`
+	ScanMode          string `json:"scan_mode,omitempty"`
+	// Panels arranges the chained panels into a video wall, in chain order.
+	Panels []PanelConfig `json:"panels,omitempty"`
+	// PanelBrightness multiplies each panel's brightness, in chain order.
+	PanelBrightness []float64 `json:"panel_brightness,omitempty"`
+}
+
+// PanelConfig places one panel of a video wall: the top-left corner of the
+// part of the frame it shows and how far it is mounted turned clockwise.
+type PanelConfig struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Rotate int `json:"rotate,omitempty"`
+}
+
+// hardware converts the configuration into the matrixdisplay form with
+// defaults applied.
+func (m *MatrixConfig) hardware() matrixdisplay.MatrixConfig {
+	if m == nil {
+		return matrixdisplay.MatrixConfig{}.WithDefaults()
+	}
+	return matrixdisplay.MatrixConfig{
+		Rows:              m.Rows,
+		Cols:              m.Cols,
+		ChainLength:       m.Chain,
+		Parallel:          m.Parallel,
+		HardwareMapping:   strings.TrimSpace(m.HardwareMapping),
+		PWMBits:           m.PWMBits,
+		PWMLSBNanoseconds: m.PWMLSBNanoseconds,
+		Slowdown:          m.Slowdown,
+		ScanMode:          strings.ToLower(strings.TrimSpace(m.ScanMode)),
+		Panels:            m.panels(),
+		PanelBrightness:   m.PanelBrightness,
+	}.WithDefaults()
+}
+
+func (m *MatrixConfig) panels() []matrixdisplay.PanelPlacement {
+	var panels []matrixdisplay.PanelPlacement
+	for _, p := range m.Panels {
+		panels = append(panels, matrixdisplay.PanelPlacement{X: p.X, Y: p.Y, Rotate: p.Rotate})
+	}
+	return panels
+}
+
+// TickerConfig enables the scrolling "Artist – Title" band. Omitted fields use
+// the renderer defaults. UpNext appends the queued track.
+type TickerConfig struct {
+	Rows     int    `json:"rows,omitempty"`
+	Position string `json:"position,omitempty"`
+	Speed    int    `json:"speed,omitempty"`
+	UpNext   bool   `json:"up_next,omitempty"`
+}
+
+// TransitionConfig animates artwork changes. Style is "none" (default),
+// "crossfade", "wipe", or "slide"; DurationMS defaults to 400 and FPS to 30.
+type TransitionConfig struct {
+	Style      string `json:"style,omitempty"`
+	DurationMS int    `json:"duration_ms,omitempty"`
+	FPS        int    `json:"fps,omitempty"`
+}
+
+// BurnInConfig protects the panel from uneven wear. DriftMinutes moves the
+// frame by a pixel that often; BlackMinutes shows a black frame for
+// BlackSeconds (default 2) that often; BrightnessCycleMinutes dims to
+// BrightnessMin percent (default 70) and back over that period. Zero turns a
+// mechanism off.
+type BurnInConfig struct {
+	DriftMinutes           int `json:"drift_minutes,omitempty"`
+	BlackMinutes           int `json:"black_minutes,omitempty"`
+	BlackSeconds           int `json:"black_seconds,omitempty"`
+	BrightnessCycleMinutes int `json:"brightness_cycle_minutes,omitempty"`
+	BrightnessMin          int `json:"brightness_min,omitempty"`
+}
+
+func (c *BurnInConfig) validate() error {
+	if c.DriftMinutes < 0 || c.BlackMinutes < 0 || c.BrightnessCycleMinutes < 0 {
+		return fmt.Errorf("intervals must not be negative")
+	}
+	if c.BlackSeconds < 0 || c.BlackSeconds > 60 {
+		return fmt.Errorf("black_seconds must be between 1 and 60, got %d", c.BlackSeconds)
+	}
+	if c.BlackMinutes > 0 && c.BlackSeconds >= c.BlackMinutes*60 {
+		return fmt.Errorf("black_seconds must be shorter than black_minutes")
+	}
+	if c.BrightnessMin < 0 || c.BrightnessMin > 100 {
+		return fmt.Errorf("brightness_min must be between 1 and 100, got %d", c.BrightnessMin)
+	}
+	return nil
+}
+
+func (c *BurnInConfig) options() render.BurnInOptions {
+	return render.BurnInOptions{
+		DriftInterval:    time.Duration(c.DriftMinutes) * time.Minute,
+		BlackInterval:    time.Duration(c.BlackMinutes) * time.Minute,
+		BlackDuration:    time.Duration(c.BlackSeconds) * time.Second,
+		BrightnessPeriod: time.Duration(c.BrightnessCycleMinutes) * time.Minute,
+		BrightnessMin:    c.BrightnessMin,
+	}
+}
+
+// RotationConfig cycles the display through Screens, each shown for Seconds
+// (default 30). Screens are "art", "clock", "animation", "script",
+// "weather", "calendar", or "blank". When is "always" (default), "playing",
+// or "idle".
+type RotationConfig struct {
+	Screens []string `json:"screens"`
+	Seconds int      `json:"seconds,omitempty"`
+	When    string   `json:"when,omitempty"`
+}
+
+func (c *RotationConfig) validate() error {
+	if len(c.Screens) == 0 {
+		return fmt.Errorf("screens must not be empty")
+	}
+	for _, screen := range c.Screens {
+		if screen == render.ScreenArt {
+			continue
+		}
+		if screen == "" {
+			return fmt.Errorf("screen names must not be empty")
+		}
+		if err := validateIdleScreen(screen); err != nil {
+			return fmt.Errorf("screens must be \"art\", \"blank\", \"clock\", \"animation\", \"script\", \"weather\", or \"calendar\", got %q", screen)
+		}
+	}
+	if c.Seconds < 0 || (c.Seconds > 0 && c.Seconds < 5) {
+		return fmt.Errorf("seconds must be at least 5, got %d", c.Seconds)
+	}
+	switch c.When {
+	case "", render.RotateAlways, render.RotatePlaying, render.RotateIdle:
+	default:
+		return fmt.Errorf("when must be \"always\", \"playing\", or \"idle\", got %q", c.When)
+	}
+	return nil
+}
+
+func (c *RotationConfig) options() render.RotationOptions {
+	return render.RotationOptions{
+		Screens:  c.Screens,
+		Interval: time.Duration(c.Seconds) * time.Second,
+		When:     c.When,
+	}
+}
+
+// WeatherConfig enables the weather screen. Provider is "open-meteo"
+// (default, no key needed) or "openweathermap", which needs APIKey.
+// Latitude and Longitude default to the top-level ones; Units is "metric"
+// (default) or "imperial"; RefreshMinutes defaults to 15.
+type WeatherConfig struct {
+	Provider       string   `json:"provider,omitempty"`
+	APIKey         string   `json:"api_key,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+	Units          string   `json:"units,omitempty"`
+	RefreshMinutes int      `json:"refresh_minutes,omitempty"`
+}
+
+// CalendarConfig enables the calendar screen. URL is an ICS address, such
+// as a calendar's secret iCal link or a CalDAV export; Username and
+// Password are sent with basic authentication when set. RefreshMinutes
+// defaults to 15 and Days, how far ahead to look for the next event, to 7.
+type CalendarConfig struct {
+	URL            string `json:"url"`
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	RefreshMinutes int    `json:"refresh_minutes,omitempty"`
+	Days           int    `json:"days,omitempty"`
+}
+
+// TimerConfig configures the countdown timer started over the control API.
+// A finished timer flashes for AlarmSeconds (default 15). With Chime set,
+// the room's speaker also plays ChimeURL, or its own chime when that is
+// empty, at ChimeVolume (0 keeps the speaker's volume); this needs an S2
+// speaker.
+type TimerConfig struct {
+	AlarmSeconds int    `json:"alarm_seconds,omitempty"`
+	Chime        bool   `json:"chime,omitempty"`
+	ChimeURL     string `json:"chime_url,omitempty"`
+	ChimeVolume  int    `json:"chime_volume,omitempty"`
+}
+
+func (c *TimerConfig) validate() error {
+	if c.AlarmSeconds < 0 || c.AlarmSeconds > 600 {
+		return fmt.Errorf("alarm_seconds must be between 1 and 600, got %d", c.AlarmSeconds)
+	}
+	if c.ChimeVolume < 0 || c.ChimeVolume > 100 {
+		return fmt.Errorf("chime_volume must be between 0 and 100, got %d", c.ChimeVolume)
+	}
+	if c.ChimeURL != "" {
+		if u, err := url.Parse(c.ChimeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("chime_url must be an http or https address, got %q", c.ChimeURL)
+		}
+	}
+	return nil
+}
+
+// WiFiConfig enables the badge warning of a weak Wi-Fi signal, which shows
+// while the signal is below WarnBelowDBm (default -75), checked every
+// CheckSeconds (default 30). Corner is where the badge sits: "top-right"
+// (default), "top-left", "bottom-left", or "bottom-right".
+type WiFiConfig struct {
+	WarnBelowDBm int    `json:"warn_below_dbm,omitempty"`
+	CheckSeconds int    `json:"check_seconds,omitempty"`
+	Corner       string `json:"corner,omitempty"`
+}
+
+func (c *WiFiConfig) validate() error {
+	if c.WarnBelowDBm != 0 && (c.WarnBelowDBm < -100 || c.WarnBelowDBm > -30) {
+		return fmt.Errorf("warn_below_dbm must be between -100 and -30, got %d", c.WarnBelowDBm)
+	}
+	if c.CheckSeconds != 0 && (c.CheckSeconds < 5 || c.CheckSeconds > 3600) {
+		return fmt.Errorf("check_seconds must be between 5 and 3600, got %d", c.CheckSeconds)
+	}
+	if c.Corner != "" && !overlay.ValidCorner(c.Corner) {
+		return fmt.Errorf("corner must be top-left, top-right, bottom-left, or bottom-right, got %q", c.Corner)
+	}
+	return nil
+}
+
+func (c *WiFiConfig) options() diagnostics.WiFiOptions {
+	return diagnostics.WiFiOptions{
+		WarnBelow: c.WarnBelowDBm,
+		Interval:  time.Duration(c.CheckSeconds) * time.Second,
+		Corner:    c.Corner,
+	}
+}
+
+// LyricsConfig enables the lyrics mode, which shows the playing track's
+// lyrics in place of its artwork. URL is the LRCLIB API to ask (the public
+// one by default). Enabled, on by default, starts the app with the mode on
+// or off; the control API toggles it.
+type LyricsConfig struct {
+	URL     string `json:"url,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+func (c *LyricsConfig) validate() error {
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https address, got %q", c.URL)
+		}
+	}
+	return nil
+}
+
+// VisualizerConfig enables the visualizer, which shows the sound picked up
+// by a microphone in place of the artwork. Device is the ALSA capture
+// device ("default" by default); Style is "bars" (default) or "wave".
+// Enabled, on by default, starts the app with the visualizer on or off;
+// the control API toggles it.
+type VisualizerConfig struct {
+	Device  string `json:"device,omitempty"`
+	Style   string `json:"style,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+func (c *VisualizerConfig) validate() error {
+	if !visualizer.ValidStyle(c.Style) {
+		return fmt.Errorf("style must be %q or %q, got %q", visualizer.StyleBars, visualizer.StyleWave, c.Style)
+	}
+	return nil
+}
+
+func (c *VisualizerConfig) options() visualizer.Options {
+	return visualizer.Options{Device: c.Device, Style: c.Style}
+}
+
+// AmbientConfig sends the artwork's colors to smart lights on each track
+// change: Hue groups through a bridge, and WLED controllers.
+type AmbientConfig struct {
+	Hue  *HueConfig   `json:"hue,omitempty"`
+	WLED []WLEDConfig `json:"wled,omitempty"`
+}
+
+// HueConfig colors Groups, the IDs of rooms, zones, or other groups, on the
+// Hue bridge at Bridge with the application key Username. Brightness, 1 to
+// 100, also sets the groups' brightness.
+type HueConfig struct {
+	Bridge     string   `json:"bridge"`
+	Username   string   `json:"username"`
+	Groups     []string `json:"groups"`
+	Brightness int      `json:"brightness,omitempty"`
+}
+
+// WLEDConfig colors Segments of the WLED controller at Host, or its first
+// segment when none are listed.
+type WLEDConfig struct {
+	Host     string `json:"host"`
+	Segments []int  `json:"segments,omitempty"`
+}
+
+func (c *AmbientConfig) validate() error {
+	if c.Hue == nil && len(c.WLED) == 0 {
+		return fmt.Errorf("needs hue or wled lights")
+	}
+	if h := c.Hue; h != nil {
+		if strings.TrimSpace(h.Bridge) == "" || strings.TrimSpace(h.Username) == "" {
+			return fmt.Errorf("hue needs bridge and username")
+		}
+		if len(h.Groups) == 0 {
+			return fmt.Errorf("hue needs at least one group")
+		}
+		for _, group := range h.Groups {
+			if strings.TrimSpace(group) == "" {
+				return fmt.Errorf("hue groups must not be empty")
+			}
+		}
+		if h.Brightness < 0 || h.Brightness > 100 {
+			return fmt.Errorf("hue brightness must be between 1 and 100, got %d", h.Brightness)
+		}
+	}
+	for _, w := range c.WLED {
+		if strings.TrimSpace(w.Host) == "" {
+			return fmt.Errorf("wled host must not be empty")
+		}
+		for _, segment := range w.Segments {
+			if segment < 0 {
+				return fmt.Errorf("wled %s: segments must not be negative, got %d", w.Host, segment)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *AmbientConfig) lights() []ambient.Light {
+	var lights []ambient.Light
+	if h := c.Hue; h != nil {
+		lights = append(lights, ambient.NewHue(h.Bridge, h.Username, h.Groups, h.Brightness))
+	}
+	for _, w := range c.WLED {
+		lights = append(lights, ambient.NewWLED(w.Host, w.Segments))
+	}
+	return lights
+}
+
+// PowerSaveConfig saves energy for displays on a battery or UPS.
+// AdaptiveFPS, on by default, drops the frame rate to 1 while animated
+// content stands still. Governors, when set, switches the CPU frequency
+// governor between Idle ("powersave" by default) while the room is idle
+// and Active ("ondemand" by default) while it plays, which needs root.
+type PowerSaveConfig struct {
+	AdaptiveFPS *bool           `json:"adaptive_fps,omitempty"`
+	Governors   *GovernorConfig `json:"governors,omitempty"`
+}
+
+// GovernorConfig names the CPU governors PowerSaveConfig switches between.
+type GovernorConfig struct {
+	Idle   string `json:"idle,omitempty"`
+	Active string `json:"active,omitempty"`
+}
+
+func (c *PowerSaveConfig) validate() error {
+	if g := c.Governors; g != nil {
+		for _, name := range []string{g.Idle, g.Active} {
+			if !powersave.ValidGovernor(name) {
+				return fmt.Errorf("governors must be one of %s, got %q", strings.Join(powersave.Governors, ", "), name)
+			}
+		}
+	}
+	return nil
+}
+
+// SourceBadgeConfig enables the Spotify, radio, AirPlay, and TV icon. Corner
+// is "top-left" (default), "top-right", "bottom-left", or "bottom-right";
+// Size is 8 (default) or 12 pixels.
+type SourceBadgeConfig struct {
+	Corner string `json:"corner,omitempty"`
+	Size   int    `json:"size,omitempty"`
+}
+
+// PlayModeConfig enables the shuffle, repeat, and crossfade icons. Corner
+// is "top-right" (default), "top-left", "bottom-left", or "bottom-right";
+// Size is 8 (default) or 12 pixels.
+type PlayModeConfig struct {
+	Corner string `json:"corner,omitempty"`
+	Size   int    `json:"size,omitempty"`
+}
+
+// ClockConfig configures the idle clock screen. Format is "24h" (default) or
+// "12h"; Brightness is a percentage of the text color (default 40).
+type ClockConfig struct {
+	Format     string `json:"format,omitempty"`
+	Brightness *int   `json:"brightness,omitempty"`
+}
+
+// ThemeConfig describes a palette/brightness variant and the daily time it
+// takes effect ("HH:MM", "sunrise", or "sunset").
+type ThemeConfig struct {
+	Name            string `json:"name"`
+	Start           string `json:"start"`
+	Brightness      *int   `json:"brightness,omitempty"`
+	TextColor       string `json:"text_color,omitempty"`
+	AccentColor     string `json:"accent_color,omitempty"`
+	BackgroundColor string `json:"background_color,omitempty"`
+	IdleScreen      string `json:"idle_screen,omitempty"`
+	// Scenes replace the top-level scene rules while the theme is active.
+	Scenes []SceneConfig `json:"scenes,omitempty"`
 }
 
-func loadConfig(path string) (Config, error) {
+// SpotifyConfig enables showing the account's Spotify playback while the Sonos
+// room is idle. The refresh token needs the user-read-playback-state scope.
+type SpotifyConfig struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	PollSeconds  int    `json:"poll_seconds,omitempty"`
+}
+
+// SpecialDay shows a banner and/or an idle animation on the dates matched by
+// Dates ("12-25", "2025-06-14", "12-24..12-26", comma-separated).
+type SpecialDay struct {
+	Name    string `json:"name"`
+	Dates   string `json:"dates"`
+	Banner  string `json:"banner,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Scene   string `json:"scene,omitempty"`
+	Overlay bool   `json:"overlay,omitempty"`
+}
+
+// SceneConfig shows a screen while a condition holds, e.g. When
+// "state==paused for 5m" with Show "clock". See package scene for the
+// condition syntax.
+type SceneConfig struct {
+	When string `json:"when"`
+	Show string `json:"show"`
+}
+
+// ScriptConfig loads the Lua script at Path. Screen "overlay" (default)
+// draws it over the album art; "idle" draws it as the "script" idle screen.
+type ScriptConfig struct {
+	Path   string `json:"path"`
+	Screen string `json:"screen,omitempty"`
+}
+
+// loadConfig reads and validates the config file, applying the named profile
+// when profile is non-empty.
+func loadConfig(path, profile string) (Config, error) {
 	var cfg Config
 	if strings.TrimSpace(path) == "" {
+		if profile != "" {
+			return cfg, fmt.Errorf("load config: profile %q requested without a config file", profile)
+		}
 		return cfg, nil
 	}
 
 	file, err := os.Open(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
+			if profile != "" {
+				return cfg, fmt.Errorf("load config: profile %q requested but %q does not exist", profile, path)
+			}
 			return cfg, nil
 		}
 		return cfg, fmt.Errorf("load config: open %q: %w", path, err)
@@ -37,23 +753,348 @@ func loadConfig(path string) (Config, error) {
 		return cfg, fmt.Errorf("load config: read %q: %w", path, err)
 	}
 
-	if len(bytes.TrimSpace(data)) == 0 {
+	if len(bytes.TrimSpace(data)) == 0 && profile == "" {
 		return cfg, nil
 	}
 
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return cfg, fmt.Errorf("load config: parse %q: %w", path, err)
 	}
+	if profile != "" {
+		raw, ok := cfg.Profiles[profile]
+		if !ok {
+			return cfg, fmt.Errorf("load config: unknown profile %q (available: %s)", profile, profileNames(cfg.Profiles))
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("load config: parse profile %q: %w", profile, err)
+		}
+	}
+	if cfg.Display != "" {
+		var mode displayMode
+		if err := mode.Set(cfg.Display); err != nil {
+			return cfg, fmt.Errorf("load config: display: %w", err)
+		}
+	}
+
+	if cfg.Logging != nil {
+		if err := cfg.Logging.options().Validate(); err != nil {
+			return cfg, fmt.Errorf("load config: %w", err)
+		}
+	}
 
 	if cfg.Brightness != nil {
 		if *cfg.Brightness < 1 || *cfg.Brightness > 100 {
 			return cfg, fmt.Errorf("load config: brightness must be between 1 and 100, got %d", *cfg.Brightness)
 		}
 	}
+	if err := cfg.Matrix.hardware().Validate(); err != nil {
+		return cfg, fmt.Errorf("load config: matrix: %w", err)
+	}
 	if cfg.IdleTimeoutSeconds != nil {
 		if *cfg.IdleTimeoutSeconds <= 0 {
 			return cfg, fmt.Errorf("load config: idle_timeout_seconds must be positive, got %d", *cfg.IdleTimeoutSeconds)
 		}
 	}
+	if cfg.StateTimeouts != nil {
+		for name, st := range map[string]*StateTimeoutConfig{
+			"playing": cfg.StateTimeouts.Playing,
+			"paused":  cfg.StateTimeouts.Paused,
+			"stopped": cfg.StateTimeouts.Stopped,
+		} {
+			if st == nil {
+				continue
+			}
+			if st.DimAfterSeconds != nil && *st.DimAfterSeconds < 0 {
+				return cfg, fmt.Errorf("load config: state_timeouts %s dim_after_seconds must not be negative, got %d", name, *st.DimAfterSeconds)
+			}
+			if st.IdleAfterSeconds != nil && *st.IdleAfterSeconds < 0 {
+				return cfg, fmt.Errorf("load config: state_timeouts %s idle_after_seconds must not be negative, got %d", name, *st.IdleAfterSeconds)
+			}
+		}
+	}
+	if cfg.DimLevel != nil && (*cfg.DimLevel < 1 || *cfg.DimLevel > 100) {
+		return cfg, fmt.Errorf("load config: dim_level must be between 1 and 100, got %d", *cfg.DimLevel)
+	}
+	if cfg.Ticker != nil {
+		if cfg.Ticker.Rows != 0 && (cfg.Ticker.Rows < 8 || cfg.Ticker.Rows > 16) {
+			return cfg, fmt.Errorf("load config: ticker rows must be between 8 and 16, got %d", cfg.Ticker.Rows)
+		}
+		switch cfg.Ticker.Position {
+		case "", "overlay", "below":
+		default:
+			return cfg, fmt.Errorf("load config: ticker position must be \"overlay\" or \"below\", got %q", cfg.Ticker.Position)
+		}
+		if cfg.Ticker.Speed < 0 {
+			return cfg, fmt.Errorf("load config: ticker speed must not be negative, got %d", cfg.Ticker.Speed)
+		}
+	}
+	if cfg.FrameRate < 0 || cfg.FrameRate > 60 {
+		return cfg, fmt.Errorf("load config: frame_rate must be between 1 and 60, got %d", cfg.FrameRate)
+	}
+	if cfg.Transition != nil {
+		switch cfg.Transition.Style {
+		case "", "none", "crossfade", "wipe", "slide":
+		default:
+			return cfg, fmt.Errorf("load config: transition style must be \"none\", \"crossfade\", \"wipe\", or \"slide\", got %q", cfg.Transition.Style)
+		}
+		if cfg.Transition.DurationMS < 0 || cfg.Transition.DurationMS > 5000 {
+			return cfg, fmt.Errorf("load config: transition duration_ms must be between 0 and 5000, got %d", cfg.Transition.DurationMS)
+		}
+		if cfg.Transition.FPS < 0 || cfg.Transition.FPS > 60 {
+			return cfg, fmt.Errorf("load config: transition fps must be between 0 and 60, got %d", cfg.Transition.FPS)
+		}
+	}
+	if cfg.BurnIn != nil {
+		if err := cfg.BurnIn.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: burn_in: %w", err)
+		}
+	}
+	if cfg.Rotation != nil {
+		if err := cfg.Rotation.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: rotation: %w", err)
+		}
+	}
+	if cfg.Timer != nil {
+		if err := cfg.Timer.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: timer: %w", err)
+		}
+	}
+	if cfg.WiFi != nil {
+		if err := cfg.WiFi.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: wifi: %w", err)
+		}
+	}
+	if cfg.Lyrics != nil {
+		if err := cfg.Lyrics.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: lyrics: %w", err)
+		}
+	}
+	if cfg.Visualizer != nil {
+		if err := cfg.Visualizer.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: visualizer: %w", err)
+		}
+	}
+	if cfg.RequestTimeouts != nil {
+		if err := cfg.RequestTimeouts.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: request_timeouts: %w", err)
+		}
+	}
+	if cfg.Ambient != nil {
+		if err := cfg.Ambient.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: ambient: %w", err)
+		}
+	}
+	if cfg.PowerSave != nil {
+		if err := cfg.PowerSave.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: power_save: %w", err)
+		}
+	}
+	if cfg.SourceBadge != nil {
+		if cfg.SourceBadge.Corner != "" && !overlay.ValidCorner(cfg.SourceBadge.Corner) {
+			return cfg, fmt.Errorf("load config: source_badge corner must be top-left, top-right, bottom-left, or bottom-right, got %q", cfg.SourceBadge.Corner)
+		}
+		switch cfg.SourceBadge.Size {
+		case 0, overlay.IconSize, overlay.LargeIconSize:
+		default:
+			return cfg, fmt.Errorf("load config: source_badge size must be 8 or 12, got %d", cfg.SourceBadge.Size)
+		}
+	}
+	if cfg.PlayMode != nil {
+		if cfg.PlayMode.Corner != "" && !overlay.ValidCorner(cfg.PlayMode.Corner) {
+			return cfg, fmt.Errorf("load config: play_mode corner must be top-left, top-right, bottom-left, or bottom-right, got %q", cfg.PlayMode.Corner)
+		}
+		switch cfg.PlayMode.Size {
+		case 0, overlay.IconSize, overlay.LargeIconSize:
+		default:
+			return cfg, fmt.Errorf("load config: play_mode size must be 8 or 12, got %d", cfg.PlayMode.Size)
+		}
+		if cfg.SourceBadge != nil && cornerOr(cfg.PlayMode.Corner, overlay.TopRight) == cornerOr(cfg.SourceBadge.Corner, overlay.TopLeft) {
+			return cfg, fmt.Errorf("load config: play_mode and source_badge cannot share the %s corner", cornerOr(cfg.PlayMode.Corner, overlay.TopRight))
+		}
+	}
+	if err := validateIdleScreen(cfg.IdleScreen); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if err := validateIdleAnimation(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if err := validateScripts(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Clock != nil {
+		switch cfg.Clock.Format {
+		case "", "24h", "12h":
+		default:
+			return cfg, fmt.Errorf("load config: clock format must be \"24h\" or \"12h\", got %q", cfg.Clock.Format)
+		}
+		if cfg.Clock.Brightness != nil && (*cfg.Clock.Brightness < 1 || *cfg.Clock.Brightness > 100) {
+			return cfg, fmt.Errorf("load config: clock brightness must be between 1 and 100, got %d", *cfg.Clock.Brightness)
+		}
+	}
+	if cfg.MQTT != nil && strings.TrimSpace(cfg.MQTT.Broker) == "" {
+		return cfg, fmt.Errorf("load config: mqtt broker must not be empty")
+	}
+	if cfg.API != nil && strings.TrimSpace(cfg.API.Addr) == "" {
+		return cfg, fmt.Errorf("load config: api addr must not be empty")
+	}
+	if cfg.Export != nil && strings.TrimSpace(cfg.Export.Dir) == "" {
+		return cfg, fmt.Errorf("load config: export dir must not be empty")
+	}
+	if cfg.Spotify != nil {
+		if strings.TrimSpace(cfg.Spotify.ClientID) == "" || strings.TrimSpace(cfg.Spotify.ClientSecret) == "" || strings.TrimSpace(cfg.Spotify.RefreshToken) == "" {
+			return cfg, fmt.Errorf("load config: spotify needs client_id, client_secret, and refresh_token")
+		}
+		if cfg.Spotify.PollSeconds < 0 {
+			return cfg, fmt.Errorf("load config: spotify poll_seconds must not be negative, got %d", cfg.Spotify.PollSeconds)
+		}
+	}
+	if c := strings.TrimSpace(cfg.ITunesCountry); c != "" && len(c) != 2 {
+		return cfg, fmt.Errorf("load config: itunes_country must be a two-letter country code, got %q", cfg.ITunesCountry)
+	}
+	for source, minutes := range cfg.SilenceMinutes {
+		if _, err := sonos.ParseSourceKind(source); err != nil {
+			return cfg, fmt.Errorf("load config: silence_minutes: %w", err)
+		}
+		if minutes <= 0 {
+			return cfg, fmt.Errorf("load config: silence_minutes for %q must be positive, got %d", source, minutes)
+		}
+	}
+	if (cfg.Latitude == nil) != (cfg.Longitude == nil) {
+		return cfg, fmt.Errorf("load config: latitude and longitude must be set together")
+	}
+	if cfg.Latitude != nil && (*cfg.Latitude < -90 || *cfg.Latitude > 90) {
+		return cfg, fmt.Errorf("load config: latitude must be between -90 and 90, got %g", *cfg.Latitude)
+	}
+	if cfg.Longitude != nil && (*cfg.Longitude < -180 || *cfg.Longitude > 180) {
+		return cfg, fmt.Errorf("load config: longitude must be between -180 and 180, got %g", *cfg.Longitude)
+	}
+	if err := validateWeather(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if err := validateCalendar(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	for _, t := range cfg.Themes {
+		if t.Brightness != nil && (*t.Brightness < 1 || *t.Brightness > 100) {
+			return cfg, fmt.Errorf("load config: theme %q brightness must be between 1 and 100, got %d", t.Name, *t.Brightness)
+		}
+		if err := validateIdleScreen(t.IdleScreen); err != nil {
+			return cfg, fmt.Errorf("load config: theme %q: %w", t.Name, err)
+		}
+	}
+	if _, err := sonos.ParseDiscoveryMethod(cfg.Discovery); err != nil {
+		return cfg, fmt.Errorf("load config: discovery: %w", err)
+	}
+	for i, d := range cfg.Devices {
+		if strings.TrimSpace(d.IP) == "" {
+			return cfg, fmt.Errorf("load config: devices[%d]: ip is required", i)
+		}
+		if d.Port < 0 || d.Port > 65535 {
+			return cfg, fmt.Errorf("load config: devices[%d]: port must be between 0 and 65535, got %d", i, d.Port)
+		}
+	}
+	if cfg.Callback != nil {
+		if err := cfg.Callback.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: callback: %w", err)
+		}
+	}
+	if cfg.ArtProcessing != nil {
+		if err := cfg.ArtProcessing.processing().Validate(); err != nil {
+			return cfg, fmt.Errorf("load config: art_processing: %w", err)
+		}
+	}
+	if cfg.Daemon != nil {
+		if err := cfg.Daemon.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: daemon: %w", err)
+		}
+	}
+	if cfg.Startup != nil {
+		if err := cfg.Startup.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: startup: %w", err)
+		}
+	}
+	if cfg.ArtFetch != nil {
+		if err := cfg.ArtFetch.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: art_fetch: %w", err)
+		}
+	}
+	if cfg.ArtCache != nil {
+		if err := cfg.ArtCache.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: art_cache: %w", err)
+		}
+	}
+	if cfg.Placeholder != nil {
+		if err := cfg.Placeholder.validate(); err != nil {
+			return cfg, fmt.Errorf("load config: placeholder: %w", err)
+		}
+	}
+	if _, err := buildThemeSchedule(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if _, err := buildSpecialDays(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if _, err := buildScenes(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
+	if _, err := buildBrightnessSchedule(cfg); err != nil {
+		return cfg, fmt.Errorf("load config: %w", err)
+	}
 	return cfg, nil
 }
+
+// profileNames lists the configured profiles for error messages.
+func profileNames(profiles map[string]json.RawMessage) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func validateIdleScreen(screen string) error {
+	switch screen {
+	case "", "blank", "clock", "animation", "script", "weather", "calendar":
+		return nil
+	}
+	return fmt.Errorf("idle_screen must be \"blank\", \"clock\", \"animation\", \"script\", \"weather\", or \"calendar\", got %q", screen)
+}
+
+// buildStateTimeouts returns the per-state timeouts, or nil when only
+// idle_timeout_seconds is configured.
+func buildStateTimeouts(cfg Config, idleTimeout time.Duration) *sonos.StateTimeouts {
+	if cfg.StateTimeouts == nil {
+		return nil
+	}
+	timeouts := sonos.StateTimeouts{
+		Paused:  sonos.StateTimeout{Idle: idleTimeout},
+		Stopped: sonos.StateTimeout{Idle: idleTimeout},
+	}
+	apply := func(dst *sonos.StateTimeout, src *StateTimeoutConfig) {
+		if src == nil {
+			return
+		}
+		if src.DimAfterSeconds != nil {
+			dst.Dim = time.Duration(*src.DimAfterSeconds) * time.Second
+		}
+		if src.IdleAfterSeconds != nil {
+			dst.Idle = time.Duration(*src.IdleAfterSeconds) * time.Second
+		}
+	}
+	apply(&timeouts.Playing, cfg.StateTimeouts.Playing)
+	apply(&timeouts.Paused, cfg.StateTimeouts.Paused)
+	apply(&timeouts.Stopped, cfg.StateTimeouts.Stopped)
+	return &timeouts
+}
+
+// cornerOr returns corner, or fallback when corner is unset.
+func cornerOr(corner, fallback string) string {
+	if corner == "" {
+		return fallback
+	}
+	return corner
+}
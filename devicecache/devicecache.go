@@ -0,0 +1,106 @@
+// Package devicecache remembers where speakers were last found, so the next
+// start can try those addresses before running a full network discovery.
+package devicecache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"musicDisplay/atomicfile"
+	"musicDisplay/sonos"
+)
+
+// Entry is the last known address of one speaker.
+type Entry struct {
+	USN      string    `json:"usn"`
+	Location string    `json:"location"`
+	IP       string    `json:"ip"`
+	Room     string    `json:"room"`
+	SeenAt   time.Time `json:"seen_at"`
+}
+
+type file struct {
+	Devices []Entry `json:"devices"`
+}
+
+// DefaultPath returns the cache file in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("devicecache: %w", err)
+	}
+	return filepath.Join(dir, "walldisplay", "devices.json"), nil
+}
+
+// Load reads the cache at path. A missing file is an empty cache.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("devicecache: read %s: %w", path, err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("devicecache: parse %s: %w", path, err)
+	}
+	return f.Devices, nil
+}
+
+// Save records devices at path, keyed by USN, replacing earlier entries for
+// the same speakers and keeping the rest. Devices without a USN are skipped.
+func Save(path string, devices []sonos.Device, now time.Time) error {
+	existing, err := Load(path)
+	if err != nil {
+		// A corrupt cache is rebuilt from scratch.
+		existing = nil
+	}
+	byUSN := make(map[string]Entry, len(existing)+len(devices))
+	for _, entry := range existing {
+		byUSN[entry.USN] = entry
+	}
+	for _, device := range devices {
+		if device.USN == "" || !device.IsSonos {
+			continue
+		}
+		byUSN[device.USN] = Entry{
+			USN:      device.USN,
+			Location: device.Location,
+			IP:       device.IP,
+			Room:     sonos.RoomName(device),
+			SeenAt:   now.UTC(),
+		}
+	}
+	f := file{Devices: make([]Entry, 0, len(byUSN))}
+	for _, entry := range byUSN {
+		f.Devices = append(f.Devices, entry)
+	}
+	sort.Slice(f.Devices, func(i, j int) bool { return f.Devices[i].USN < f.Devices[j].USN })
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("devicecache: encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("devicecache: create dir: %w", err)
+	}
+	if err := atomicfile.Write(path, data, 0o644); err != nil {
+		return fmt.Errorf("devicecache: %w", err)
+	}
+	return nil
+}
+
+// Device converts the entry back into a device that still needs validating.
+func (e Entry) Device() sonos.Device {
+	headers := map[string]string{}
+	if e.Room != "" {
+		headers["ROOMNAME"] = e.Room
+	}
+	return sonos.Device{IP: e.IP, Location: e.Location, USN: e.USN, Server: "cache", Headers: headers}
+}
@@ -0,0 +1,60 @@
+package devicecache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"musicDisplay/sonos"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "devices.json")
+	if entries, err := Load(path); err != nil || entries != nil {
+		t.Fatalf("Load of missing file = %v, %v", entries, err)
+	}
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	kitchen := sonos.Device{
+		IP:       "192.168.1.23",
+		Location: "http://192.168.1.23:1400/xml/device_description.xml",
+		USN:      "uuid:RINCON_A::urn:schemas-upnp-org:device:ZonePlayer:1",
+		IsSonos:  true,
+		Metadata: sonos.DeviceMetadata{RoomName: "Kitchen"},
+	}
+	den := sonos.Device{IP: "192.168.1.24", Location: "http://192.168.1.24:1400/x.xml", USN: "uuid:RINCON_B", IsSonos: true, Headers: map[string]string{"ROOMNAME": "Den"}}
+	router := sonos.Device{IP: "192.168.1.1", USN: "uuid:router"}
+	if err := Save(path, []sonos.Device{kitchen, den, router}, now); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	// A later save with only the kitchen at a new address keeps the den.
+	kitchen.IP = "192.168.1.30"
+	kitchen.Location = "http://192.168.1.30:1400/xml/device_description.xml"
+	if err := Save(path, []sonos.Device{kitchen}, now.Add(time.Hour)); err != nil {
+		t.Fatalf("second Save error: %v", err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (non-Sonos devices are skipped): %+v", len(entries), entries)
+	}
+	if entries[0].Room != "Kitchen" || entries[0].IP != "192.168.1.30" || !entries[0].SeenAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("kitchen entry = %+v", entries[0])
+	}
+	device := entries[1].Device()
+	if sonos.RoomName(device) != "Den" || device.Location != den.Location || device.USN != den.USN {
+		t.Fatalf("den device = %+v", device)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("Load accepted a corrupt file")
+	}
+}
@@ -0,0 +1,146 @@
+// Package diagnostics helps troubleshoot the display on the wall: a screen
+// with the display's and speaker's addresses, the age of the event
+// subscription and of the last event, the Wi-Fi signal, and the uptime, and
+// a badge warning of a weak Wi-Fi signal.
+package diagnostics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+)
+
+var logger = logging.For("diagnostics")
+
+const (
+	// ScreenName is the name the diagnostics screen is shown by.
+	ScreenName = "diagnostics"
+
+	// DefaultTimeout is how long the screen stays up once shown, so a
+	// forgotten one does not hold the display for good.
+	DefaultTimeout = 2 * time.Minute
+
+	// focusPriority is the screen's focus priority while shown: above the
+	// music but below a running kitchen timer.
+	focusPriority = 50
+
+	// wifiMaxAge is how long a Wi-Fi reading is shown before it is taken
+	// again.
+	wifiMaxAge = 5 * time.Second
+	// wifiTimeout bounds one Wi-Fi reading.
+	wifiTimeout = 2 * time.Second
+)
+
+// Screen is the diagnostics screen. It implements render.FocusScreen,
+// asking for focus from Show until Hide or until its timeout runs out.
+type Screen struct {
+	clk     clock.Clock
+	timeout time.Duration
+	started time.Time
+	// readWiFi reads the Wi-Fi signal; tests replace it.
+	readWiFi func(ctx context.Context) (Signal, bool)
+
+	mu      sync.Mutex
+	shownAt time.Time
+	shown   bool
+	health  sonos.ListenerHealth
+	wifi    Signal
+	wifiOK  bool
+	// wifiRead is when the Wi-Fi reading was last started.
+	wifiRead time.Time
+}
+
+var _ render.FocusScreen = (*Screen)(nil)
+
+// New returns a hidden diagnostics screen that stays up for timeout once
+// shown (DefaultTimeout when zero). The uptime it shows counts from now.
+func New(clk clock.Clock, timeout time.Duration) *Screen {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	clk = clock.Or(clk)
+	return &Screen{clk: clk, timeout: timeout, started: clk.Now(), readWiFi: ReadWiFi}
+}
+
+// Update records the listener's latest health, as reported through
+// sonos.ListenerOptions.OnHealth.
+func (s *Screen) Update(health sonos.ListenerHealth) {
+	s.mu.Lock()
+	s.health = health
+	s.mu.Unlock()
+}
+
+// Show brings the screen up, restarting its timeout.
+func (s *Screen) Show() {
+	s.mu.Lock()
+	s.shown, s.shownAt = true, s.clk.Now()
+	s.mu.Unlock()
+	logger.Info("diagnostics shown")
+}
+
+// Hide takes the screen down.
+func (s *Screen) Hide() {
+	s.mu.Lock()
+	s.shown = false
+	s.mu.Unlock()
+}
+
+// Toggle hides the screen when it is up and shows it otherwise.
+func (s *Screen) Toggle() {
+	if s.Shown(s.clk.Now()) {
+		s.Hide()
+		return
+	}
+	s.Show()
+}
+
+// Shown reports whether the screen is up at now.
+func (s *Screen) Shown(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shown && now.Sub(s.shownAt) < s.timeout
+}
+
+// Name implements render.Screen.
+func (s *Screen) Name() string {
+	return ScreenName
+}
+
+// Focus implements render.FocusScreen.
+func (s *Screen) Focus(state render.FrameState) int {
+	if !s.Shown(state.Now) {
+		return 0
+	}
+	return focusPriority
+}
+
+// Animating implements render.AnimatedLayer, so the renderer redraws the
+// ages as they tick up.
+func (s *Screen) Animating() bool {
+	return s.Shown(s.clk.Now())
+}
+
+// signal returns the latest Wi-Fi reading and starts a new one in the
+// background once it is older than wifiMaxAge, so drawing never waits on
+// it.
+func (s *Screen) signal(now time.Time) (Signal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wifiRead.IsZero() || now.Sub(s.wifiRead) >= wifiMaxAge {
+		s.wifiRead = now
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), wifiTimeout)
+			defer cancel()
+			signal, ok := s.readWiFi(ctx)
+			s.mu.Lock()
+			s.wifi, s.wifiOK = signal, ok
+			s.mu.Unlock()
+		}()
+	}
+	return s.wifi, s.wifiOK
+}
@@ -0,0 +1,185 @@
+package diagnostics
+
+import (
+	"context"
+	"image"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+func TestShowTimesOut(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	s := New(clk, time.Minute)
+	if s.Focus(render.FrameState{Now: clk.Now()}) != 0 || s.Animating() {
+		t.Fatal("a new screen should be hidden")
+	}
+	s.Toggle()
+	if s.Focus(render.FrameState{Now: clk.Now()}) == 0 || !s.Animating() {
+		t.Fatal("Toggle should show the screen")
+	}
+	clk.Advance(time.Minute)
+	if s.Focus(render.FrameState{Now: clk.Now()}) != 0 {
+		t.Fatal("screen still shown after its timeout")
+	}
+	s.Toggle()
+	s.Toggle()
+	if s.Shown(clk.Now()) {
+		t.Fatal("second Toggle should hide the screen")
+	}
+}
+
+func TestDraw(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	s := New(clk, 0)
+	read := make(chan struct{}, 1)
+	s.readWiFi = func(context.Context) (Signal, bool) {
+		read <- struct{}{}
+		return Signal{Interface: "wlan0", DBm: -56}, true
+	}
+	s.Update(sonos.ListenerHealth{Speaker: "192.168.1.20", Callback: "http://192.168.1.50:8080/events", Subscribed: clk.Now(), Polling: true})
+	s.Show()
+	clk.Advance(90 * time.Second)
+
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := s.Draw(frame, render.FrameState{Palette: theme.DefaultPalette, Now: clk.Now()}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		t.Fatal("Draw did not read the Wi-Fi signal")
+	}
+	lit := 0
+	for i := 3; i < len(frame.Pix); i += 4 {
+		if frame.Pix[i] != 0 {
+			lit++
+		}
+	}
+	if lit == 0 {
+		t.Fatal("Draw left the frame empty")
+	}
+
+	// A second draw within wifiMaxAge reuses the reading.
+	if err := s.Draw(frame, render.FrameState{Palette: theme.DefaultPalette, Now: clk.Now()}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	select {
+	case <-read:
+		t.Fatal("Wi-Fi read again within wifiMaxAge")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Second:                 "0s",
+		45 * time.Second:             "45s",
+		12*time.Minute + time.Second: "12m",
+		3*time.Hour + 5*time.Minute:  "3h5m",
+		52 * time.Hour:               "2d4h",
+	} {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParseProcWireless(t *testing.T) {
+	const data = `Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+ face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+ wlan0: 0000   70.  -40.  -256        0      0      0      0      0        0
+`
+	if got, ok := parseProcWireless(data); !ok || got != (Signal{Interface: "wlan0", DBm: -40}) {
+		t.Fatalf("parseProcWireless = %+v, %v", got, ok)
+	}
+	unsigned := `Inter-| sta-|   Quality        |
+ face | tus | link level noise |
+ wlan0: 0000   54.  200.  0
+`
+	if got, ok := parseProcWireless(unsigned); !ok || got.DBm != -56 {
+		t.Fatalf("parseProcWireless unsigned = %+v, %v", got, ok)
+	}
+	if _, ok := parseProcWireless(data[:len(data)-len(" wlan0: 0000   70.  -40.  -256        0      0      0      0      0        0\n")]); ok {
+		t.Fatal("parseProcWireless found a signal without interfaces")
+	}
+}
+
+func TestParseIw(t *testing.T) {
+	const dev = `phy#0
+	Unnamed/non-netdev interface
+		wdev 0x2
+	Interface wlan0
+		ifindex 3
+		type managed
+`
+	if got := parseIwInterfaces(dev); len(got) != 1 || got[0] != "wlan0" {
+		t.Fatalf("parseIwInterfaces = %q", got)
+	}
+	const link = `Connected to aa:bb:cc:dd:ee:ff (on wlan0)
+	SSID: home
+	freq: 5180
+	signal: -56 dBm
+	tx bitrate: 433.3 MBit/s
+`
+	if got, ok := parseIwLink(link); !ok || got != -56 {
+		t.Fatalf("parseIwLink = %d, %v", got, ok)
+	}
+	if _, ok := parseIwLink("Not connected.\n"); ok {
+		t.Fatal("parseIwLink found a signal while not connected")
+	}
+}
+
+func TestWiFiMonitorBadge(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	m := NewWiFiMonitor(clk, WiFiOptions{WarnBelow: -70})
+	readings := []int{-60, -72, -74, -69, -66}
+	m.read = func(context.Context) (Signal, bool) {
+		dbm := readings[0]
+		readings = readings[1:]
+		return Signal{Interface: "wlan0", DBm: dbm}, true
+	}
+	for _, want := range []struct {
+		changed, visible bool
+	}{
+		{false, false}, // -60
+		{true, true},   // -72 drops below the threshold
+		{true, true},   // -74 shows a new reading
+		{true, true},   // -69 is within the hysteresis; the badge stays
+		{true, false},  // -66 has recovered
+	} {
+		if changed := m.check(context.Background()); changed != want.changed || m.Visible() != want.visible {
+			signal, _ := m.Signal()
+			t.Fatalf("at %d dBm: changed %v visible %v, want %v %v", signal.DBm, changed, m.Visible(), want.changed, want.visible)
+		}
+	}
+}
+
+func TestWiFiMonitorDraw(t *testing.T) {
+	m := NewWiFiMonitor(clock.NewFake(time.Now()), WiFiOptions{})
+	m.read = func(context.Context) (Signal, bool) { return Signal{Interface: "wlan0", DBm: -82}, true }
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	m.check(context.Background())
+	if err := m.Draw(frame, render.FrameState{Palette: theme.DefaultPalette}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	// The badge sits in the top-right corner, its reading below the icon.
+	if frame.RGBAAt(62, 1).A == 0 || frame.RGBAAt(1, 1).A != 0 {
+		t.Fatal("badge not in the top-right corner")
+	}
+	amber := 0
+	for y := 11; y < 20; y++ {
+		for x := 40; x < 64; x++ {
+			if frame.RGBAAt(x, y) == warningColor {
+				amber++
+			}
+		}
+	}
+	if amber == 0 {
+		t.Fatal("reading not drawn below the icon")
+	}
+}
@@ -0,0 +1,81 @@
+package diagnostics
+
+import (
+	"fmt"
+	"image"
+	"net/url"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+// Draw implements render.Screen: a title, then one line each for the
+// display's address, the speaker's, the subscription's age, the time since
+// the last event, the Wi-Fi signal, and the uptime, with a warning at the
+// bottom while the listener polls. Labels are in the accent color and
+// values in the text color, in the small font so the addresses fit.
+func (s *Screen) Draw(frame *image.RGBA, state render.FrameState) error {
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	font := text.Small
+	lineHeight := (font.Height() + 2) * scale
+	label, value := state.Palette.Accent, state.Palette.Text
+
+	s.mu.Lock()
+	health := s.health
+	s.mu.Unlock()
+	wifi, wifiOK := s.signal(state.Now)
+
+	x, y := bounds.Min.X+scale, bounds.Min.Y+2*scale
+	font.Draw(frame, x, y, "Diagnostics", label, scale)
+	y += lineHeight + scale
+
+	lines := [][2]string{
+		{"IP", "-"},
+		{"SPK", "-"},
+		{"SUB", "-"},
+		{"EVT", "none"},
+		{"WIFI", "n/a"},
+		{"UP", formatAge(state.Now.Sub(s.started))},
+	}
+	if u, err := url.Parse(health.Callback); err == nil && u.Hostname() != "" {
+		lines[0][1] = u.Hostname()
+	}
+	if health.Speaker != "" {
+		lines[1][1] = health.Speaker
+	}
+	if !health.Subscribed.IsZero() {
+		lines[2][1] = formatAge(state.Now.Sub(health.Subscribed))
+	}
+	if !health.LastEvent.IsZero() {
+		lines[3][1] = formatAge(state.Now.Sub(health.LastEvent))
+	}
+	if wifiOK {
+		lines[4][1] = fmt.Sprintf("%ddBm", wifi.DBm)
+	}
+	for _, line := range lines {
+		font.Draw(frame, x, y, line[0], label, scale)
+		font.Draw(frame, x+(font.Measure(line[0])+font.Measure(" "))*scale, y, line[1], value, scale)
+		y += lineHeight
+	}
+	if health.Polling {
+		font.Draw(frame, x, y, "Polling", label, scale)
+	}
+	return nil
+}
+
+// formatAge formats d compactly for the narrow panel: "45s", "12m",
+// "3h5m", or "2d4h".
+func formatAge(d time.Duration) string {
+	d = max(d, 0)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d/time.Hour), int(d/time.Minute)%60)
+	}
+	return fmt.Sprintf("%dd%dh", int(d/(24*time.Hour)), int(d/time.Hour)%24)
+}
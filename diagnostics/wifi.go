@@ -0,0 +1,102 @@
+package diagnostics
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// procWireless is the kernel's table of wireless interfaces on Linux.
+const procWireless = "/proc/net/wireless"
+
+// Signal is the strength of a Wi-Fi link.
+type Signal struct {
+	Interface string
+	// DBm is the received signal level, such as -56.
+	DBm int
+}
+
+// ReadWiFi returns the signal of the first connected Wi-Fi interface, read
+// from /proc/net/wireless or, where that is empty, from iw. It reports
+// false on wired setups and systems with neither.
+func ReadWiFi(ctx context.Context) (Signal, bool) {
+	if data, err := os.ReadFile(procWireless); err == nil {
+		if signal, ok := parseProcWireless(string(data)); ok {
+			return signal, true
+		}
+	}
+	out, err := exec.CommandContext(ctx, "iw", "dev").Output()
+	if err != nil {
+		return Signal{}, false
+	}
+	for _, iface := range parseIwInterfaces(string(out)) {
+		out, err := exec.CommandContext(ctx, "iw", "dev", iface, "link").Output()
+		if err != nil {
+			continue
+		}
+		if dbm, ok := parseIwLink(string(out)); ok {
+			return Signal{Interface: iface, DBm: dbm}, true
+		}
+	}
+	return Signal{}, false
+}
+
+// parseProcWireless reads the first interface's signal level from the
+// contents of /proc/net/wireless:
+//
+//	Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+//	 face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+//	 wlan0: 0000   70.  -40.  -256        0      0      0      0      0        0
+func parseProcWireless(data string) (Signal, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		iface, rest, ok := strings.Cut(scanner.Text(), ":")
+		fields := strings.Fields(rest)
+		if !ok || strings.Contains(iface, "|") || len(fields) < 3 {
+			continue
+		}
+		level, err := strconv.ParseFloat(strings.TrimSuffix(fields[2], "."), 64)
+		if err != nil || level == 0 {
+			continue
+		}
+		// Some drivers report the level as an unsigned byte.
+		if level > 0 {
+			level -= 256
+		}
+		return Signal{Interface: strings.TrimSpace(iface), DBm: int(level)}, true
+	}
+	return Signal{}, false
+}
+
+// parseIwInterfaces returns the interface names in the output of "iw dev".
+func parseIwInterfaces(out string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "Interface "); ok {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return names
+}
+
+// parseIwLink returns the signal level in the output of
+// "iw dev <interface> link", which has a line such as "signal: -56 dBm"
+// while connected and says "Not connected." otherwise.
+func parseIwLink(out string) (int, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "signal:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0, false
+		}
+		dbm, err := strconv.Atoi(fields[0])
+		return dbm, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,179 @@
+package diagnostics
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/overlay"
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+const (
+	// DefaultWarnBelow is the signal, in dBm, below which the Wi-Fi badge
+	// shows. Around -75 dBm speakers' NOTIFY requests start going missing.
+	DefaultWarnBelow = -75
+	// DefaultWiFiInterval is how often the Wi-Fi signal is checked.
+	DefaultWiFiInterval = 30 * time.Second
+
+	// wifiHysteresis is how far above the threshold the signal must recover
+	// before the badge goes, so a signal hovering around it does not flicker.
+	wifiHysteresis = 3
+	// badgePriority places the badge under notifications.
+	badgePriority = 10
+)
+
+// warningColor is the amber of the Wi-Fi badge's reading.
+var warningColor = color.RGBA{R: 0xff, G: 0xa0, A: 0xff}
+
+// WiFiOptions configures a WiFiMonitor.
+type WiFiOptions struct {
+	// WarnBelow is the signal, in dBm, below which the badge shows
+	// (DefaultWarnBelow when zero).
+	WarnBelow int
+	// Interval is how often the signal is checked (DefaultWiFiInterval when
+	// zero).
+	Interval time.Duration
+	// Corner is the overlay corner the badge sits in (overlay.TopRight by
+	// default, away from the source badge).
+	Corner string
+}
+
+// WiFiMonitor checks the Wi-Fi signal and, while it is weak, shows a badge
+// with the reading in a corner of the display. It implements
+// render.Overlay.
+type WiFiMonitor struct {
+	clk  clock.Clock
+	opts WiFiOptions
+	// read reads the Wi-Fi signal; tests replace it.
+	read func(ctx context.Context) (Signal, bool)
+
+	mu     sync.Mutex
+	signal Signal
+	ok     bool
+	weak   bool
+}
+
+var _ render.Overlay = (*WiFiMonitor)(nil)
+
+// NewWiFiMonitor returns a monitor that has not checked the signal yet.
+func NewWiFiMonitor(clk clock.Clock, opts WiFiOptions) *WiFiMonitor {
+	if opts.WarnBelow == 0 {
+		opts.WarnBelow = DefaultWarnBelow
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWiFiInterval
+	}
+	if !overlay.ValidCorner(opts.Corner) {
+		opts.Corner = overlay.TopRight
+	}
+	return &WiFiMonitor{clk: clock.Or(clk), opts: opts, read: ReadWiFi}
+}
+
+// Run checks the signal every Interval until ctx is done, calling onChange
+// when the badge appears, goes, or shows a new reading, such as
+// render.Renderer.Wake.
+func (m *WiFiMonitor) Run(ctx context.Context, onChange func()) {
+	ticker := m.clk.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if m.check(ctx) && onChange != nil {
+			onChange()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// check reads the signal once and reports whether the badge changed.
+func (m *WiFiMonitor) check(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, wifiTimeout)
+	signal, ok := m.read(ctx)
+	cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, wasWeak := m.signal, m.weak
+	m.signal, m.ok = signal, ok
+	switch {
+	case !m.weak && ok && signal.DBm < m.opts.WarnBelow:
+		m.weak = true
+		logger.Warn("wifi signal weak; speaker events may go missing", "interface", signal.Interface, "dbm", signal.DBm, "threshold", m.opts.WarnBelow)
+	case m.weak && (!ok || signal.DBm >= m.opts.WarnBelow+wifiHysteresis):
+		m.weak = false
+		logger.Info("wifi signal recovered", "interface", signal.Interface, "dbm", signal.DBm)
+	}
+	return m.weak != wasWeak || (m.weak && signal.DBm != before.DBm)
+}
+
+// Signal returns the last reading, and false before the first or on
+// systems without Wi-Fi.
+func (m *WiFiMonitor) Signal() (Signal, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.signal, m.ok
+}
+
+// Priority implements render.Overlay.
+func (m *WiFiMonitor) Priority() int {
+	return badgePriority
+}
+
+// Visible implements render.Overlay: the badge shows while the signal is
+// weak.
+func (m *WiFiMonitor) Visible() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.weak
+}
+
+// Draw implements render.Layer: the Wi-Fi icon in the configured corner
+// and the reading in dBm next to it, toward the middle of the panel.
+func (m *WiFiMonitor) Draw(frame *image.RGBA, state render.FrameState) error {
+	m.mu.Lock()
+	weak, dbm := m.weak, m.signal.DBm
+	m.mu.Unlock()
+	if !weak {
+		return nil
+	}
+	icon, err := overlay.Icon(overlay.IconWiFi)
+	if err != nil {
+		return err
+	}
+	bounds := frame.Bounds()
+	size := overlay.IconSize
+	if min(bounds.Dx(), bounds.Dy()) >= 128 {
+		size = overlay.LargeIconSize
+	}
+	overlay.StampIcon(frame, icon, m.opts.Corner, size)
+
+	// The reading sits on its own backing below the icon in the top
+	// corners and above it in the bottom ones.
+	font := text.Small
+	label := strconv.Itoa(dbm)
+	box := image.Rect(0, 0, font.Measure(label)+2, font.Height()+2)
+	var origin image.Point
+	switch m.opts.Corner {
+	case overlay.TopLeft:
+		origin = image.Pt(bounds.Min.X+1, bounds.Min.Y+size+3)
+	case overlay.BottomLeft:
+		origin = image.Pt(bounds.Min.X+1, bounds.Max.Y-size-3-box.Dy())
+	case overlay.BottomRight:
+		origin = image.Pt(bounds.Max.X-1-box.Dx(), bounds.Max.Y-size-3-box.Dy())
+	default:
+		origin = image.Pt(bounds.Max.X-1-box.Dx(), bounds.Min.Y+size+3)
+	}
+	box = box.Add(origin)
+	draw.Draw(frame, box.Intersect(bounds), image.NewUniform(color.RGBA{A: 0xd0}), image.Point{}, draw.Over)
+	font.Draw(frame, box.Min.X+1, box.Min.Y+1, label, warningColor, 1)
+	return nil
+}
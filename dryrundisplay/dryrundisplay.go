@@ -0,0 +1,201 @@
+// Package dryrundisplay is a display backend that needs no hardware: it logs
+// a description of every frame and saves the frames as numbered PNG files, so
+// the pipeline can be checked on any machine, including CI.
+package dryrundisplay
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"musicDisplay/logging"
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/sonos"
+)
+
+// Display records frames instead of drawing them.
+type Display struct {
+	dir      string
+	geometry matrixdisplay.Geometry
+	logger   *slog.Logger
+
+	mu         sync.Mutex
+	frames     int
+	brightness int
+	gamutBits  int
+	layout     string
+	status     sonos.PlaybackStatus
+}
+
+// New writes frames to dir, creating it if needed, for a display of the given
+// geometry. An empty dir selects a new temporary directory. Descriptions are
+// logged at info level.
+func New(dir string, geometry matrixdisplay.Geometry, brightness int) (*Display, error) {
+	return newDisplay(dir, geometry, brightness, logging.For("dryrun"))
+}
+
+func newDisplay(dir string, geometry matrixdisplay.Geometry, brightness int, logger *slog.Logger) (*Display, error) {
+	if strings.TrimSpace(dir) == "" {
+		tmp, err := os.MkdirTemp("", "walldisplay-dry-run-")
+		if err != nil {
+			return nil, fmt.Errorf("dryrundisplay: create frame dir: %w", err)
+		}
+		dir = tmp
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("dryrundisplay: create frame dir: %w", err)
+	}
+	if brightness <= 0 {
+		brightness = 100
+	}
+	d := &Display{dir: dir, geometry: geometry.OrDefault(), logger: logger, brightness: brightness}
+	d.logger.Info("dry run: writing frames", "dir", dir)
+	return d, nil
+}
+
+// Dir returns the directory frames are written to.
+func (d *Display) Dir() string {
+	return d.dir
+}
+
+// Size reports the frame size of the display's geometry.
+func (d *Display) Size() (int, int) {
+	return d.geometry.Width, d.geometry.Height
+}
+
+// PreviewGamut saves frames as a matrix with pwmBits of color depth and the
+// current brightness shows them, as matrixdisplay.SimulateGamut does; 0
+// saves them unchanged.
+func (d *Display) PreviewGamut(pwmBits int) {
+	d.mu.Lock()
+	d.gamutBits = pwmBits
+	d.mu.Unlock()
+}
+
+// SetLayout sets the layout description included with each frame.
+func (d *Display) SetLayout(layout string) {
+	d.mu.Lock()
+	d.layout = layout
+	d.mu.Unlock()
+}
+
+// UpdateStatus records the playback status so frames can be described by the
+// track they show.
+func (d *Display) UpdateStatus(status sonos.PlaybackStatus) {
+	d.mu.Lock()
+	d.status = status
+	d.mu.Unlock()
+}
+
+// Show logs img and saves it as the next frame file.
+func (d *Display) Show(img image.Image) error {
+	if img == nil {
+		return errors.New("dryrundisplay: nil image")
+	}
+	bounds := img.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(frame, frame.Bounds(), img, bounds.Min, draw.Src)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.frames++
+	path := filepath.Join(d.dir, fmt.Sprintf("frame-%05d.png", d.frames))
+	saved := frame
+	if d.gamutBits > 0 {
+		saved = matrixdisplay.SimulateGamut(frame, d.gamutBits, d.brightness)
+	}
+	if err := writePNG(path, saved); err != nil {
+		return err
+	}
+	d.logger.Info("dry run: frame",
+		"frame", d.frames, "width", bounds.Dx(), "height", bounds.Dy(), "lit_percent", litPercent(frame),
+		"brightness", d.brightness, "track", describeTrack(d.status), "layout", d.layoutLocked(), "path", path)
+	return nil
+}
+
+// Clear logs that the display was blanked.
+func (d *Display) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logger.Info("dry run: clear")
+	return nil
+}
+
+// SetBrightness logs the new brightness.
+func (d *Display) SetBrightness(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("dryrundisplay: brightness must be between 1 and 100, got %d", level)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.brightness = level
+	d.logger.Info("dry run: brightness", "brightness", level)
+	return nil
+}
+
+// Close logs how many frames were written.
+func (d *Display) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logger.Info("dry run: done", "frames", d.frames, "dir", d.dir)
+	return nil
+}
+
+func (d *Display) layoutLocked() string {
+	if d.layout == "" {
+		return "default"
+	}
+	return d.layout
+}
+
+func describeTrack(status sonos.PlaybackStatus) string {
+	track := status.Track
+	if track.Title == "" && track.Artist == "" {
+		return "none"
+	}
+	desc := fmt.Sprintf("%q", track.Title)
+	if track.Artist != "" {
+		desc += " by " + track.Artist
+	}
+	if status.State != "" {
+		desc += " (" + strings.ToLower(status.State) + ")"
+	}
+	return desc
+}
+
+// litPercent returns the share of pixels that are not black.
+func litPercent(frame *image.RGBA) int {
+	bounds := frame.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0
+	}
+	lit := 0
+	for i := 0; i+3 < len(frame.Pix); i += 4 {
+		if frame.Pix[i] != 0 || frame.Pix[i+1] != 0 || frame.Pix[i+2] != 0 {
+			lit++
+		}
+	}
+	return lit * 100 / total
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("dryrundisplay: create %s: %w", path, err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("dryrundisplay: encode %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("dryrundisplay: write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dryrundisplay
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/sonos"
+)
+
+func TestShowLogsAndWritesFrames(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "frames")
+	var out bytes.Buffer
+	lastLine := func() string {
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		return lines[len(lines)-1]
+	}
+	d, err := newDisplay(dir, matrixdisplay.Geometry{}, 80, slog.New(slog.NewTextHandler(&out, nil)))
+	if err != nil {
+		t.Fatalf("newDisplay error: %v", err)
+	}
+	d.SetLayout("art+progress")
+	d.UpdateStatus(sonos.PlaybackStatus{State: "PLAYING", Track: sonos.TrackInfo{Title: "Song", Artist: "Band"}})
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 32; y++ {
+			img.SetRGBA(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	if err := d.Show(img); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+
+	got := lastLine()
+	for _, want := range []string{"frame=1", "width=64", "height=64", "lit_percent=50", "brightness=80", `"\"Song\" by Band (playing)"`, "layout=art+progress", "frame-00001.png"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("log line %q missing %q", got, want)
+		}
+	}
+
+	file, err := os.Open(filepath.Join(dir, "frame-00001.png"))
+	if err != nil {
+		t.Fatalf("open frame: %v", err)
+	}
+	defer file.Close()
+	saved, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("decode frame: %v", err)
+	}
+	if r, _, _, _ := saved.At(0, 0).RGBA(); r>>8 != 200 {
+		t.Fatalf("saved frame pixel red = %d, want 200", r>>8)
+	}
+
+	if err := d.SetBrightness(0); err == nil {
+		t.Fatalf("SetBrightness(0) succeeded")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if got := lastLine(); !strings.Contains(got, "frames=1") {
+		t.Fatalf("Close logged %q", got)
+	}
+}
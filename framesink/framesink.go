@@ -0,0 +1,651 @@
+// Package framesink pushes finished frames to another WallDisplay over TCP,
+// so a more powerful machine can run discovery and composition while a small
+// board such as a Pi Zero only drives the matrix.
+//
+// The protocol is deliberately small. On connect the sink sends a hello:
+// the magic "WDSK", a version byte, and its frame width and height as
+// big-endian uint16s. The client then sends messages, each a type byte, a
+// big-endian uint32 payload length, and the payload:
+//
+//	'T'  time: no payload
+//	'F'  frame: int64 presentation time, uint16 width, uint16 height, then
+//	     width*height RGB triples
+//	'C'  clear: no payload
+//	'B'  brightness: one byte, 1..100
+//
+// The sink answers 'T' with 'N' and its clock as an int64 of Unix
+// nanoseconds. The client sends a few of these on connect and, like NTP,
+// keeps the offset from the exchange with the shortest round trip, so it can
+// stamp a frame with when the sink should show it in the sink's own clock.
+// Several sinks given the same moment show a frame together, which keeps
+// transitions and tickers in step across the panels of one wall. A
+// presentation time of 0 shows the frame on arrival.
+//
+// The sink answers every other message with 'K', or with 'E', a uint16
+// length, and an error message, so display errors reach the sender and a
+// slow panel paces it. A frame with a presentation time is answered once it
+// is queued; an error showing it is reported with the next reply.
+package framesink
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/matrixdisplay"
+)
+
+const (
+	magic   = "WDSK"
+	version = 2
+
+	msgTime       = 'T'
+	msgFrame      = 'F'
+	msgClear      = 'C'
+	msgBrightness = 'B'
+
+	replyOK    = 'K'
+	replyError = 'E'
+	replyTime  = 'N'
+
+	// maxFrameEdge bounds the frames a sink accepts, and so the memory a
+	// single message can make it allocate.
+	maxFrameEdge = 1024
+
+	dialTimeout = 5 * time.Second
+	ioTimeout   = 10 * time.Second
+
+	// syncSamples is how many time exchanges a client makes to measure the
+	// sink's clock offset, and resyncEvery how often it measures again to
+	// follow clock drift.
+	syncSamples = 5
+	resyncEvery = 10 * time.Minute
+
+	// maxPresentDelay bounds how far ahead a frame is held. A frame due
+	// later than that comes from a stale offset, so it is shown at once.
+	maxPresentDelay = 2 * time.Second
+
+	// queueDepth is how many frames a sink holds for their presentation
+	// time before the sender has to wait.
+	queueDepth = 4
+)
+
+var logger = logging.For("framesink")
+
+// Display is what a sink drives: the matrix controller or any other backend.
+type Display interface {
+	Show(img image.Image) error
+	Clear() error
+	SetBrightness(level int) error
+}
+
+// Server accepts frames from clients and shows them on a display.
+type Server struct {
+	listener net.Listener
+	out      Display
+	geometry matrixdisplay.Geometry
+	clock    clock.Clock
+
+	// ops queues display calls from all clients for present, which makes
+	// them one at a time and in order.
+	ops       chan op
+	done      chan struct{}
+	closeOnce sync.Once
+
+	wg     sync.WaitGroup
+	connMu sync.Mutex
+	conns  map[net.Conn]struct{}
+}
+
+// op is one display call. at is when to make it, or zero for as soon as
+// the calls before it are done; result, when set, receives its error, and
+// otherwise an error is kept in pending for the client's next reply.
+type op struct {
+	at      time.Time
+	run     func() error
+	result  chan error
+	pending *pendingError
+}
+
+// pendingError is the failure of a client's scheduled frame, not yet
+// reported to it.
+type pendingError struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *pendingError) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *pendingError) take() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.err
+	p.err = nil
+	return err
+}
+
+// Listen starts a sink on addr that shows received frames on out and tells
+// clients to compose for geometry.
+func Listen(addr string, out Display, geometry matrixdisplay.Geometry) (*Server, error) {
+	return listen(addr, out, geometry, clock.Real)
+}
+
+func listen(addr string, out Display, geometry matrixdisplay.Geometry, clk clock.Clock) (*Server, error) {
+	if out == nil {
+		return nil, errors.New("framesink: nil display")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("framesink: listen %s: %w", addr, err)
+	}
+	s := &Server{
+		listener: ln,
+		out:      out,
+		geometry: geometry.OrDefault(),
+		clock:    clk,
+		ops:      make(chan op, queueDepth),
+		done:     make(chan struct{}),
+		conns:    map[net.Conn]struct{}{},
+	}
+	s.wg.Add(2)
+	go s.serve()
+	go s.present()
+	return s, nil
+}
+
+// Addr returns the address the sink listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops accepting frames and drops connected clients. Closing again
+// does nothing.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.closeOnce.Do(func() { close(s.done) })
+	s.connMu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.handle(conn); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Warn("frame client dropped", "remote", conn.RemoteAddr().String(), "err", err)
+			}
+			s.connMu.Lock()
+			delete(s.conns, conn)
+			s.connMu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// handle greets a client and applies its messages until it disconnects.
+func (s *Server) handle(conn net.Conn) error {
+	logger.Debug("frame client connected", "remote", conn.RemoteAddr().String())
+	hello := make([]byte, 0, 9)
+	hello = append(hello, magic...)
+	hello = append(hello, version)
+	hello = binary.BigEndian.AppendUint16(hello, uint16(s.geometry.Width))
+	hello = binary.BigEndian.AppendUint16(hello, uint16(s.geometry.Height))
+	if _, err := conn.Write(hello); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	pending := &pendingError{}
+	for {
+		kind, payload, err := readMessage(r)
+		if err != nil {
+			return err
+		}
+		if kind == msgTime {
+			reply := binary.BigEndian.AppendUint64([]byte{replyTime}, uint64(s.clock.Now().UnixNano()))
+			if _, err := conn.Write(reply); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeReply(conn, s.apply(kind, payload, pending)); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) apply(kind byte, payload []byte, pending *pendingError) error {
+	switch kind {
+	case msgFrame:
+		if len(payload) < 8 {
+			return errors.New("framesink: short frame header")
+		}
+		img, err := decodeFrame(payload[8:])
+		if err != nil {
+			return err
+		}
+		var at time.Time
+		if nanos := int64(binary.BigEndian.Uint64(payload[:8])); nanos != 0 {
+			at = time.Unix(0, nanos)
+		}
+		return s.do(at, pending, func() error { return s.out.Show(img) })
+	case msgClear:
+		return s.do(time.Time{}, pending, s.out.Clear)
+	case msgBrightness:
+		if len(payload) != 1 {
+			return fmt.Errorf("framesink: brightness payload is %d bytes, want 1", len(payload))
+		}
+		return s.do(time.Time{}, pending, func() error { return s.out.SetBrightness(int(payload[0])) })
+	}
+	return fmt.Errorf("framesink: unknown message type %q", kind)
+}
+
+// do queues run for at. A call for now waits for its result; a scheduled
+// one returns once queued. Either reports the failure of an earlier frame
+// the same client scheduled, kept in pending.
+func (s *Server) do(at time.Time, pending *pendingError, run func() error) error {
+	o := op{at: at, run: run, pending: pending}
+	if at.IsZero() {
+		o.result = make(chan error, 1)
+	}
+	select {
+	case s.ops <- o:
+	case <-s.done:
+		return net.ErrClosed
+	}
+	var err error
+	if o.result != nil {
+		select {
+		case err = <-o.result:
+		case <-s.done:
+			return net.ErrClosed
+		}
+	}
+	return errors.Join(err, pending.take())
+}
+
+// present makes the queued display calls, holding each frame until its
+// presentation time.
+func (s *Server) present() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case o := <-s.ops:
+			if !o.at.IsZero() && !s.waitUntil(o.at) {
+				return
+			}
+			err := o.run()
+			if o.result != nil {
+				o.result <- err
+				continue
+			}
+			if err != nil {
+				o.pending.set(err)
+			}
+		}
+	}
+}
+
+// waitUntil sleeps until at by the sink's clock. It returns false if the
+// sink closes meanwhile.
+func (s *Server) waitUntil(at time.Time) bool {
+	wait := at.Sub(s.clock.Now())
+	if wait <= 0 {
+		return true
+	}
+	if wait > maxPresentDelay {
+		logger.Debug("frame due too far ahead; showing it now", "in", wait)
+		return true
+	}
+	timer := s.clock.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Client is a display that sends frames to a sink. It reconnects on the next
+// call after the connection drops, so a rebooting sink catches up with the
+// following frame.
+type Client struct {
+	addr  string
+	clock clock.Clock
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	geometry matrixdisplay.Geometry
+	// offset is the sink's clock minus ours, as measured at synced.
+	offset time.Duration
+	synced time.Time
+}
+
+// Dial connects to the sink at addr, learns its frame size, and measures
+// its clock offset.
+func Dial(addr string) (*Client, error) {
+	return dial(addr, clock.Real)
+}
+
+func dial(addr string, clk clock.Clock) (*Client, error) {
+	c := &Client{addr: addr, clock: clk}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Size reports the sink's frame size, so the renderer composes for it.
+func (c *Client) Size() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.geometry.Width, c.geometry.Height
+}
+
+// Show sends img to the sink, which shows it on arrival.
+func (c *Client) Show(img image.Image) error {
+	return c.ShowAt(img, time.Time{})
+}
+
+// ShowAt sends img to the sink to be shown at at, by this machine's clock.
+// A zero at shows it on arrival.
+func (c *Client) ShowAt(img image.Image, at time.Time) error {
+	if img == nil {
+		return errors.New("framesink: nil image")
+	}
+	frame, err := encodeFrame(img)
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, 8, 8+len(frame))
+	return c.send(msgFrame, append(payload, frame...), at)
+}
+
+// Clear blanks the sink's display.
+func (c *Client) Clear() error {
+	return c.send(msgClear, nil, time.Time{})
+}
+
+// SetBrightness changes the sink's brightness (1..100).
+func (c *Client) SetBrightness(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("framesink: brightness must be between 1 and 100, got %d", level)
+	}
+	return c.send(msgBrightness, []byte{byte(level)}, time.Time{})
+}
+
+// Close disconnects from the sink.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connect dials the sink and reads its hello. Callers must hold c.mu.
+func (c *Client) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("framesink: dial %s: %w", c.addr, err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	reader := bufio.NewReader(conn)
+	hello := make([]byte, 9)
+	if _, err := io.ReadFull(reader, hello); err != nil {
+		conn.Close()
+		return fmt.Errorf("framesink: read hello from %s: %w", c.addr, err)
+	}
+	if string(hello[:4]) != magic {
+		conn.Close()
+		return fmt.Errorf("framesink: %s is not a frame sink", c.addr)
+	}
+	if hello[4] != version {
+		conn.Close()
+		return fmt.Errorf("framesink: %s speaks protocol version %d, want %d", c.addr, hello[4], version)
+	}
+	c.conn, c.reader = conn, reader
+	c.geometry = matrixdisplay.Geometry{
+		Width:  int(binary.BigEndian.Uint16(hello[5:7])),
+		Height: int(binary.BigEndian.Uint16(hello[7:9])),
+	}.OrDefault()
+	if err := c.syncClock(); err != nil {
+		conn.Close()
+		c.conn = nil
+		return fmt.Errorf("framesink: sync clock with %s: %w", c.addr, err)
+	}
+	return nil
+}
+
+// syncClock measures the sink's clock offset from a few time exchanges,
+// trusting the one with the shortest round trip, whose reply was least
+// delayed in either direction. Callers must hold c.mu.
+func (c *Client) syncClock() error {
+	var best time.Duration = -1
+	for i := 0; i < syncSamples; i++ {
+		_ = c.conn.SetDeadline(time.Now().Add(ioTimeout))
+		sent := c.clock.Now()
+		if err := writeMessage(c.conn, msgTime, nil); err != nil {
+			return err
+		}
+		remote, err := readTimeReply(c.reader)
+		if err != nil {
+			return err
+		}
+		roundTrip := c.clock.Since(sent)
+		if best < 0 || roundTrip < best {
+			best = roundTrip
+			c.offset = remote.Sub(sent.Add(roundTrip / 2))
+		}
+	}
+	c.synced = c.clock.Now()
+	logger.Debug("synced sink clock", "sink", c.addr, "offset", c.offset, "round_trip", best)
+	return nil
+}
+
+// send delivers one message and waits for the sink's reply. A frame's
+// first eight bytes are filled with at in the sink's clock. A connection
+// that turns out to be dead, for example after the sink restarted, is
+// replaced and the message sent once more.
+func (c *Client) send(kind byte, payload []byte, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		fresh := c.conn == nil
+		if fresh {
+			if err := c.connect(); err != nil {
+				return err
+			}
+		}
+		err = nil
+		if !fresh && c.clock.Since(c.synced) > resyncEvery {
+			err = c.syncClock()
+		}
+		if err == nil {
+			if kind == msgFrame && !at.IsZero() {
+				binary.BigEndian.PutUint64(payload[:8], uint64(at.Add(c.offset).UnixNano()))
+			}
+			_ = c.conn.SetDeadline(time.Now().Add(ioTimeout))
+			err = writeMessage(c.conn, kind, payload)
+		}
+		if err == nil {
+			err = readReply(c.reader)
+		}
+		var remote *remoteError
+		if err == nil || errors.As(err, &remote) {
+			return err
+		}
+		c.conn.Close()
+		c.conn = nil
+		if fresh {
+			break
+		}
+	}
+	return fmt.Errorf("framesink: send to %s: %w", c.addr, err)
+}
+
+// remoteError is an error the sink's display reported.
+type remoteError struct {
+	msg string
+}
+
+func (e *remoteError) Error() string {
+	return "framesink: sink: " + e.msg
+}
+
+func writeMessage(w io.Writer, kind byte, payload []byte) error {
+	header := []byte{kind}
+	header = binary.BigEndian.AppendUint32(header, uint32(len(payload)))
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > 12+maxFrameEdge*maxFrameEdge*3 {
+		return 0, nil, fmt.Errorf("framesink: message of %d bytes is too large", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func writeReply(w io.Writer, err error) error {
+	if err == nil {
+		_, werr := w.Write([]byte{replyOK})
+		return werr
+	}
+	msg := err.Error()
+	if len(msg) > 0xffff {
+		msg = msg[:0xffff]
+	}
+	reply := []byte{replyError}
+	reply = binary.BigEndian.AppendUint16(reply, uint16(len(msg)))
+	_, werr := w.Write(append(reply, msg...))
+	return werr
+}
+
+func readReply(r io.Reader) error {
+	kind := make([]byte, 1)
+	if _, err := io.ReadFull(r, kind); err != nil {
+		return err
+	}
+	switch kind[0] {
+	case replyOK:
+		return nil
+	case replyError:
+		size := make([]byte, 2)
+		if _, err := io.ReadFull(r, size); err != nil {
+			return err
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(size))
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return err
+		}
+		return &remoteError{msg: string(msg)}
+	}
+	return fmt.Errorf("unknown reply %q", kind[0])
+}
+
+// readTimeReply reads the sink's answer to a time message.
+func readTimeReply(r io.Reader) (time.Time, error) {
+	reply := make([]byte, 9)
+	if _, err := io.ReadFull(r, reply); err != nil {
+		return time.Time{}, err
+	}
+	if reply[0] != replyTime {
+		return time.Time{}, fmt.Errorf("unknown time reply %q", reply[0])
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(reply[1:]))), nil
+}
+
+// encodeFrame packs img as a frame payload.
+func encodeFrame(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > maxFrameEdge || height > maxFrameEdge {
+		return nil, fmt.Errorf("framesink: frame size %dx%d out of range", width, height)
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Bounds().Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+	payload := make([]byte, 4, 4+width*height*3)
+	binary.BigEndian.PutUint16(payload[0:2], uint16(width))
+	binary.BigEndian.PutUint16(payload[2:4], uint16(height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := rgba.RGBAAt(x, y)
+			payload = append(payload, c.R, c.G, c.B)
+		}
+	}
+	return payload, nil
+}
+
+// decodeFrame unpacks a frame payload.
+func decodeFrame(payload []byte) (*image.RGBA, error) {
+	if len(payload) < 4 {
+		return nil, errors.New("framesink: short frame header")
+	}
+	width := int(binary.BigEndian.Uint16(payload[0:2]))
+	height := int(binary.BigEndian.Uint16(payload[2:4]))
+	if width <= 0 || height <= 0 || width > maxFrameEdge || height > maxFrameEdge {
+		return nil, fmt.Errorf("framesink: frame size %dx%d out of range", width, height)
+	}
+	pixels := payload[4:]
+	if len(pixels) != width*height*3 {
+		return nil, fmt.Errorf("framesink: frame of %dx%d has %d pixel bytes, want %d", width, height, len(pixels), width*height*3)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		img.SetRGBA(i%width, i/width, color.RGBA{R: pixels[3*i], G: pixels[3*i+1], B: pixels[3*i+2], A: 0xff})
+	}
+	return img, nil
+}
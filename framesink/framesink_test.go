@@ -0,0 +1,320 @@
+package framesink
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/matrixdisplay"
+)
+
+type recordingDisplay struct {
+	mu         sync.Mutex
+	shown      []*image.RGBA
+	shownAt    []time.Time
+	cleared    int
+	brightness int
+	err        error
+}
+
+func (d *recordingDisplay) Show(img image.Image) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.shown = append(d.shown, img.(*image.RGBA))
+	d.shownAt = append(d.shownAt, time.Now())
+	return d.err
+}
+
+func (d *recordingDisplay) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cleared++
+	return d.err
+}
+
+func (d *recordingDisplay) SetBrightness(level int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.brightness = level
+	return d.err
+}
+
+func TestClientDrivesSink(t *testing.T) {
+	out := &recordingDisplay{}
+	server, err := Listen("127.0.0.1:0", out, matrixdisplay.Geometry{Width: 128, Height: 64})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer server.Close()
+
+	client, err := Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+	if w, h := client.Size(); w != 128 || h != 64 {
+		t.Fatalf("Size = %dx%d, want the sink's 128x64", w, h)
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	frame.SetRGBA(5, 7, color.RGBA{R: 10, G: 20, B: 30, A: 0xff})
+	if err := client.Show(frame); err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+	if err := client.SetBrightness(40); err != nil {
+		t.Fatalf("SetBrightness: %v", err)
+	}
+	if err := client.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+
+	out.mu.Lock()
+	if len(out.shown) != 1 || out.shown[0].Bounds().Dx() != 128 || out.shown[0].RGBAAt(5, 7) != (color.RGBA{R: 10, G: 20, B: 30, A: 0xff}) {
+		t.Fatalf("sink showed %d frames, want the sent frame", len(out.shown))
+	}
+	if out.brightness != 40 || out.cleared != 1 {
+		t.Fatalf("brightness %d, cleared %d; want 40 and 1", out.brightness, out.cleared)
+	}
+	out.err = errors.New("panel unplugged")
+	out.mu.Unlock()
+
+	if err := client.Clear(); err == nil || !strings.Contains(err.Error(), "panel unplugged") {
+		t.Fatalf("Clear error = %v, want the sink's display error", err)
+	}
+	if err := client.SetBrightness(0); err == nil {
+		t.Fatal("SetBrightness(0) succeeded, want error")
+	}
+}
+
+func TestClientReconnectsAfterSinkRestart(t *testing.T) {
+	out := &recordingDisplay{}
+	server, err := Listen("127.0.0.1:0", out, matrixdisplay.Geometry{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := server.Addr()
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+	server.Close()
+
+	server, err = Listen(addr, out, matrixdisplay.Geometry{})
+	if err != nil {
+		t.Fatalf("Listen again: %v", err)
+	}
+	defer server.Close()
+	if err := client.Show(image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("Show after restart: %v", err)
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if len(out.shown) != 1 {
+		t.Fatalf("sink showed %d frames after restart, want 1", len(out.shown))
+	}
+}
+
+func TestDecodeFrameRejectsBadPayloads(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0, 1},
+		{0, 2, 0, 2, 1, 2, 3},
+		{0, 0, 0, 1},
+	} {
+		if _, err := decodeFrame(payload); err == nil {
+			t.Fatalf("decodeFrame(%v) succeeded, want error", payload)
+		}
+	}
+}
+
+// awaitFailedShow waits for out to fail showing a scheduled frame, then
+// lets it succeed again.
+func awaitFailedShow(t *testing.T, out *recordingDisplay) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		out.mu.Lock()
+		shown := len(out.shown)
+		if shown > 0 {
+			out.err = nil
+		}
+		out.mu.Unlock()
+		if shown > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("sink never showed the scheduled frame")
+		}
+	}
+}
+
+// skewedClock is the wall clock set off by skew, as on a sink whose clock
+// disagrees with the sender's.
+type skewedClock struct {
+	clock.Clock
+	skew time.Duration
+}
+
+func (c skewedClock) Now() time.Time { return c.Clock.Now().Add(c.skew) }
+
+func TestClientShowsFramesAtTheirTimeOnASkewedSink(t *testing.T) {
+	out := &recordingDisplay{}
+	server, err := listen("127.0.0.1:0", out, matrixdisplay.Geometry{}, skewedClock{clock.Real, time.Hour})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer server.Close()
+	client, err := Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+	if off := client.offset - time.Hour; off < -50*time.Millisecond || off > 50*time.Millisecond {
+		t.Fatalf("offset = %v, want about an hour", client.offset)
+	}
+
+	at := time.Now().Add(200 * time.Millisecond)
+	if err := client.ShowAt(image.NewRGBA(image.Rect(0, 0, 64, 64)), at); err != nil {
+		t.Fatalf("ShowAt: %v", err)
+	}
+	if time.Now().After(at) {
+		t.Fatal("ShowAt waited for the presentation time, want it to return once queued")
+	}
+	// Clear queues behind the frame, so once it returns the frame is shown.
+	if err := client.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if len(out.shownAt) != 1 {
+		t.Fatalf("sink showed %d frames, want 1", len(out.shownAt))
+	}
+	if early := at.Sub(out.shownAt[0]); early > 20*time.Millisecond {
+		t.Fatalf("frame shown %v early", early)
+	}
+}
+
+func TestScheduledFrameErrorReachesNextReply(t *testing.T) {
+	out := &recordingDisplay{err: errors.New("panel unplugged")}
+	server, err := Listen("127.0.0.1:0", out, matrixdisplay.Geometry{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer server.Close()
+	client, err := Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.ShowAt(image.NewRGBA(image.Rect(0, 0, 64, 64)), time.Now().Add(20*time.Millisecond)); err != nil {
+		t.Fatalf("ShowAt: %v", err)
+	}
+	awaitFailedShow(t, out)
+	if err := client.Clear(); err == nil || !strings.Contains(err.Error(), "panel unplugged") {
+		t.Fatalf("Clear error = %v, want the scheduled frame's error", err)
+	}
+	if err := client.Clear(); err != nil {
+		t.Fatalf("second Clear: %v, want the error reported once", err)
+	}
+}
+
+func TestGroupShowsFramesTogether(t *testing.T) {
+	var outs []*recordingDisplay
+	var addrs []string
+	for _, skew := range []time.Duration{time.Hour, -30 * time.Minute} {
+		out := &recordingDisplay{}
+		server, err := listen("127.0.0.1:0", out, matrixdisplay.Geometry{Width: 128, Height: 64}, skewedClock{clock.Real, skew})
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer server.Close()
+		outs = append(outs, out)
+		addrs = append(addrs, server.Addr())
+	}
+	group, err := DialGroup(addrs)
+	if err != nil {
+		t.Fatalf("DialGroup: %v", err)
+	}
+	defer group.Close()
+	if w, h := group.Size(); w != 128 || h != 64 {
+		t.Fatalf("Size = %dx%d, want 128x64", w, h)
+	}
+
+	if err := group.Show(image.NewRGBA(image.Rect(0, 0, 128, 64))); err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+	if err := group.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	var shown []time.Time
+	for i, out := range outs {
+		out.mu.Lock()
+		if len(out.shownAt) != 1 || out.cleared != 1 {
+			t.Fatalf("sink %d showed %d frames and cleared %d times, want 1 and 1", i, len(out.shownAt), out.cleared)
+		}
+		shown = append(shown, out.shownAt[0])
+		out.mu.Unlock()
+	}
+	if apart := shown[0].Sub(shown[1]).Abs(); apart > 20*time.Millisecond {
+		t.Fatalf("sinks showed the frame %v apart", apart)
+	}
+}
+
+func TestDialGroupRejectsMismatchedSinks(t *testing.T) {
+	var addrs []string
+	for _, width := range []int{64, 128} {
+		server, err := Listen("127.0.0.1:0", &recordingDisplay{}, matrixdisplay.Geometry{Width: width, Height: 64})
+		if err != nil {
+			t.Fatalf("Listen: %v", err)
+		}
+		defer server.Close()
+		addrs = append(addrs, server.Addr())
+	}
+	if _, err := DialGroup(addrs); err == nil {
+		t.Fatal("DialGroup succeeded for sinks of different sizes, want error")
+	}
+}
+
+func TestScheduledFrameErrorStaysWithItsClient(t *testing.T) {
+	out := &recordingDisplay{err: errors.New("panel unplugged")}
+	server, err := Listen("127.0.0.1:0", out, matrixdisplay.Geometry{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer server.Close()
+	sender, err := Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer sender.Close()
+	other, err := Dial(server.Addr())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer other.Close()
+
+	if err := sender.ShowAt(image.NewRGBA(image.Rect(0, 0, 64, 64)), time.Now().Add(20*time.Millisecond)); err != nil {
+		t.Fatalf("ShowAt: %v", err)
+	}
+	awaitFailedShow(t, out)
+	if err := other.Clear(); err != nil {
+		t.Fatalf("other client's Clear = %v, want no error from the sender's frame", err)
+	}
+	if err := sender.Clear(); err == nil || !strings.Contains(err.Error(), "panel unplugged") {
+		t.Fatalf("sender's Clear = %v, want its frame's error", err)
+	}
+}
+
+func TestServerCloseTwice(t *testing.T) {
+	server, err := Listen("127.0.0.1:0", &recordingDisplay{}, matrixdisplay.Geometry{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	server.Close()
+	server.Close()
+}
@@ -0,0 +1,97 @@
+package framesink
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+// presentLead is how far ahead a group schedules each frame, long enough
+// for it to reach every sink on a local network before it is due.
+const presentLead = 150 * time.Millisecond
+
+// Group mirrors frames on several sinks, such as the panels on one wall,
+// and has every sink show each frame at the same moment.
+type Group struct {
+	clients []*Client
+	clock   clock.Clock
+}
+
+// DialGroup connects to the sinks at addrs, which must all have the same
+// frame size.
+func DialGroup(addrs []string) (*Group, error) {
+	return dialGroup(addrs, clock.Real)
+}
+
+func dialGroup(addrs []string, clk clock.Clock) (*Group, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("framesink: no sinks to dial")
+	}
+	g := &Group{clock: clk}
+	for _, addr := range addrs {
+		client, err := dial(addr, clk)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		g.clients = append(g.clients, client)
+	}
+	w, h := g.clients[0].Size()
+	for _, client := range g.clients[1:] {
+		if cw, ch := client.Size(); cw != w || ch != h {
+			g.Close()
+			return nil, fmt.Errorf("framesink: %s has %dx%d frames but %s has %dx%d", client.addr, cw, ch, g.clients[0].addr, w, h)
+		}
+	}
+	return g, nil
+}
+
+// Size reports the sinks' frame size.
+func (g *Group) Size() (int, int) {
+	return g.clients[0].Size()
+}
+
+// Show sends img to every sink, to be shown a moment from now.
+func (g *Group) Show(img image.Image) error {
+	at := g.clock.Now().Add(presentLead)
+	return g.each(func(c *Client) error { return c.ShowAt(img, at) })
+}
+
+// Clear blanks every sink's display.
+func (g *Group) Clear() error {
+	return g.each((*Client).Clear)
+}
+
+// SetBrightness changes every sink's brightness (1..100).
+func (g *Group) SetBrightness(level int) error {
+	return g.each(func(c *Client) error { return c.SetBrightness(level) })
+}
+
+// Close disconnects from every sink.
+func (g *Group) Close() error {
+	var errs []error
+	for _, client := range g.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// each calls fn for every sink at once, so a slow sink does not delay the
+// others.
+func (g *Group) each(fn func(*Client) error) error {
+	errs := make([]error, len(g.clients))
+	var wg sync.WaitGroup
+	for i, client := range g.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(client)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
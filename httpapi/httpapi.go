@@ -0,0 +1,644 @@
+// Package httpapi serves a small REST API for scripting the running display
+// from tools such as Home Assistant or curl. It listens on its own address,
+// separate from the UPnP event callback server.
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	xdraw "golang.org/x/image/draw"
+
+	"musicDisplay/theme"
+)
+
+// maxImageBytes bounds uploads to POST /display/image.
+const maxImageBytes = 10 << 20
+
+// roomsTimeout bounds the discovery behind GET /api/rooms.
+const roomsTimeout = 20 * time.Second
+
+// maxTimer bounds the countdowns POST /timer starts.
+const maxTimer = 24 * time.Hour
+
+// maxNotification bounds the length of a notification's text, and
+// maxNotificationTime how long POST /display/notify may keep one up.
+const (
+	maxNotification     = 200
+	maxNotificationTime = 10 * time.Minute
+)
+
+// maxArtSize bounds the width and height GET /api/art/{signature} scales to.
+const maxArtSize = 1024
+
+// maxProxiedArt bounds the art GET /api/art/current passes through, and
+// artProxyTimeout the request to the speaker behind it.
+const (
+	maxProxiedArt   = 10 << 20
+	artProxyTimeout = 10 * time.Second
+)
+
+// artClient fetches the art GET /api/art/current passes through.
+var artClient = &http.Client{Timeout: artProxyTimeout}
+
+// ErrNoDisplay is returned by a Backend when no display is attached.
+var ErrNoDisplay = errors.New("no display attached")
+
+// ErrNotFound is returned by a Backend for an unknown resource.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnsupported is returned by a Backend when the speaker cannot carry out
+// a request, such as an audio clip on an S1 speaker.
+var ErrUnsupported = errors.New("not supported by the speaker")
+
+// ErrBusy is returned by a Backend when too many requests are already
+// waiting, such as notifications queued for the display.
+var ErrBusy = errors.New("too many requests waiting")
+
+// Status is the body of GET /status.
+type Status struct {
+	Room    string `json:"room"`
+	State   string `json:"state"`
+	Playing bool   `json:"playing"`
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Album   string `json:"album"`
+	// Source is the input kind ("radio", "tv", "airplay", ...) and Service
+	// the music service, such as "Spotify", when the track URI names one.
+	Source          string  `json:"source,omitempty"`
+	Service         string  `json:"service,omitempty"`
+	PositionSeconds float64 `json:"position_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Brightness      int     `json:"brightness,omitempty"`
+	// LargeText is set while the large-text mode replaces the art.
+	LargeText bool `json:"large_text"`
+	// Lyrics is set while the lyrics mode is on, whether or not the track
+	// has lyrics to show.
+	Lyrics bool `json:"lyrics"`
+	// Visualizer is set while the visualizer is on, whether or not the
+	// microphone picks anything up.
+	Visualizer bool `json:"visualizer"`
+	// Art is the path of the track's album art on this API, if it has any.
+	Art string `json:"art,omitempty"`
+	// Timer is the countdown or stopwatch, while one runs.
+	Timer *Timer `json:"timer,omitempty"`
+	// WiFiDBm is the Wi-Fi signal, when the Wi-Fi badge is enabled and
+	// the host has Wi-Fi.
+	WiFiDBm *int `json:"wifi_dbm,omitempty"`
+}
+
+// Timer is the countdown timer or stopwatch in GET /status. Mode is
+// "countdown", "done" while a finished countdown flashes, or "stopwatch".
+type Timer struct {
+	Mode             string  `json:"mode"`
+	RemainingSeconds float64 `json:"remaining_seconds,omitempty"`
+	ElapsedSeconds   float64 `json:"elapsed_seconds,omitempty"`
+}
+
+// Room is one entry of GET /api/rooms.
+type Room struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Track   string `json:"track"`
+	Current bool   `json:"current"`
+}
+
+// ArtPurge is the body of DELETE /api/art: how many cached files were
+// removed and their total size.
+type ArtPurge struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Palette is the body of GET /api/palette: the most prominent colors of
+// the current track's album art, most prominent first, and the art's path
+// on this API. Colors is empty for art made only of black, white, and
+// greys.
+type Palette struct {
+	Art    string         `json:"art,omitempty"`
+	Colors []theme.Swatch `json:"colors"`
+}
+
+// Clip is the body of POST /room/clip: a sound to play over the room's
+// music. An empty URL plays the speaker's chime; Volume 0 keeps the
+// speaker's volume; Priority is "low" (default) or "high".
+type Clip struct {
+	URL      string `json:"url,omitempty"`
+	Volume   int    `json:"volume,omitempty"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// Favorite is one entry of GET /api/favorites: a Sonos favorite, numbered
+// from 0 in the order the Sonos app lists them. Description is its kind,
+// such as "TuneIn Station".
+type Favorite struct {
+	Index       int    `json:"index"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// Backend carries out API requests against the running program.
+type Backend interface {
+	Status() Status
+	Clear() error
+	SetBrightness(level int) error
+	// SetLargeText turns the large-text mode on or off.
+	SetLargeText(on bool) error
+	// SetDiagnostics shows or hides the diagnostics screen.
+	SetDiagnostics(on bool) error
+	// SetLyrics turns the lyrics mode on or off, or returns ErrNotFound
+	// when lyrics are not configured.
+	SetLyrics(on bool) error
+	// SetVisualizer turns the visualizer on or off, or returns ErrNotFound
+	// when it is not configured.
+	SetVisualizer(on bool) error
+	// Notify scrolls text across the display over whatever it shows, once
+	// when d is zero and otherwise for d. Notifications wait for those of
+	// the same or a higher priority.
+	Notify(text string, d time.Duration, priority int) error
+	// SwitchRoom starts switching to room. The switch completes
+	// asynchronously once the room's device has been discovered.
+	SwitchRoom(room string) error
+	// SelectRoom switches to room like SwitchRoom and saves it as the
+	// configured room, so it survives a restart.
+	SelectRoom(room string) error
+	// Rooms discovers the rooms on the network and what they are playing.
+	Rooms(ctx context.Context) ([]Room, error)
+	ShowImage(img image.Image) error
+	// Art returns the cached album art named signature, or ErrNotFound.
+	Art(signature string) (image.Image, error)
+	// CurrentArtURL returns the address the current track's album art is
+	// served from, usually the speaker, or ErrNotFound when it has none.
+	CurrentArtURL() (string, error)
+	// PurgeArt empties the album art cache.
+	PurgeArt() (ArtPurge, error)
+	// Palette returns the colors of the current track's album art, or
+	// ErrNotFound when the track has none.
+	Palette() (Palette, error)
+	// PlayClip plays clip on the room's speaker without touching its queue.
+	PlayClip(ctx context.Context, clip Clip) error
+	// Favorites lists the household's Sonos favorites.
+	Favorites(ctx context.Context) ([]Favorite, error)
+	// PlayFavorite starts the favorite numbered index in the room, or
+	// returns ErrNotFound when there is no such favorite.
+	PlayFavorite(ctx context.Context, index int) error
+	// StartTimer starts a countdown of d on the display, replacing a
+	// running countdown or stopwatch.
+	StartTimer(d time.Duration) error
+	// StartStopwatch starts the stopwatch on the display.
+	StartStopwatch() error
+	// StopTimer stops the countdown or stopwatch.
+	StopTimer() error
+}
+
+// Server is the running API server.
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// New starts serving the API for backend on addr.
+func New(addr string, backend Backend) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("httpapi: listen %s: %w", addr, err)
+	}
+	s := &Server{
+		listener: ln,
+		server:   &http.Server{Handler: Handler(backend), ReadHeaderTimeout: 5 * time.Second},
+	}
+	go func() {
+		_ = s.server.Serve(ln)
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// Handler returns the API routes for backend.
+func Handler(backend Backend) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, backend.Status())
+	})
+	mux.HandleFunc("POST /display/clear", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, backend.Clear(), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/brightness", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Brightness *int `json:"brightness"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Brightness == nil {
+			writeError(w, http.StatusBadRequest, `expected {"brightness": 1..100}`)
+			return
+		}
+		if *body.Brightness < 1 || *body.Brightness > 100 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("brightness must be between 1 and 100, got %d", *body.Brightness))
+			return
+		}
+		respond(w, backend.SetBrightness(*body.Brightness), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/large_text", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected {"enabled": true|false}`)
+			return
+		}
+		respond(w, backend.SetLargeText(*body.Enabled), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected {"enabled": true|false}`)
+			return
+		}
+		respond(w, backend.SetDiagnostics(*body.Enabled), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/lyrics", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected {"enabled": true|false}`)
+			return
+		}
+		respond(w, backend.SetLyrics(*body.Enabled), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/visualizer", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected {"enabled": true|false}`)
+			return
+		}
+		respond(w, backend.SetVisualizer(*body.Enabled), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /display/notify", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text     string  `json:"text"`
+			Seconds  float64 `json:"seconds"`
+			Priority int     `json:"priority"`
+		}
+		if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Text) == "" {
+			writeError(w, http.StatusBadRequest, `expected {"text": "...", "seconds": 10}`)
+			return
+		}
+		if n := utf8.RuneCountInString(body.Text); n > maxNotification {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("text must be at most %d characters, got %d", maxNotification, n))
+			return
+		}
+		d := time.Duration(body.Seconds * float64(time.Second))
+		if d < 0 || d > maxNotificationTime {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("seconds must be between 0 and %d", int(maxNotificationTime.Seconds())))
+			return
+		}
+		respond(w, backend.Notify(body.Text, d, body.Priority), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /timer", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Duration string  `json:"duration"`
+			Seconds  float64 `json:"seconds"`
+		}
+		const usage = `expected {"duration": "5m30s"} or {"seconds": 330}`
+		if err := decodeJSON(r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, usage)
+			return
+		}
+		d := time.Duration(body.Seconds * float64(time.Second))
+		if body.Duration != "" {
+			parsed, err := time.ParseDuration(body.Duration)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, usage)
+				return
+			}
+			d = parsed
+		}
+		if d < time.Second || d > maxTimer {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("duration must be between 1s and %s, got %s", maxTimer, d))
+			return
+		}
+		respond(w, backend.StartTimer(d), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /stopwatch", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, backend.StartStopwatch(), http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /timer", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, backend.StopTimer(), http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /room", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Room    string `json:"room"`
+			Persist bool   `json:"persist"`
+		}
+		if err := decodeJSON(r, &body); err != nil || strings.TrimSpace(body.Room) == "" {
+			writeError(w, http.StatusBadRequest, `expected {"room": "<name>", "persist": false}`)
+			return
+		}
+		room := strings.TrimSpace(body.Room)
+		if body.Persist {
+			respond(w, backend.SelectRoom(room), http.StatusAccepted)
+			return
+		}
+		respond(w, backend.SwitchRoom(room), http.StatusAccepted)
+	})
+	mux.HandleFunc("POST /room/clip", func(w http.ResponseWriter, r *http.Request) {
+		var clip Clip
+		if r.ContentLength != 0 {
+			if err := decodeJSON(r, &clip); err != nil {
+				writeError(w, http.StatusBadRequest, `expected {"url": "<sound>", "volume": 0..100, "priority": "low"|"high"}`)
+				return
+			}
+		}
+		if clip.Volume < 0 || clip.Volume > 100 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("volume must be between 0 and 100, got %d", clip.Volume))
+			return
+		}
+		switch strings.ToLower(clip.Priority) {
+		case "", "low", "high":
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("priority must be \"low\" or \"high\", got %q", clip.Priority))
+			return
+		}
+		if clip.URL != "" {
+			if u, err := url.Parse(clip.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				writeError(w, http.StatusBadRequest, "url must be an http or https address")
+				return
+			}
+		}
+		respond(w, backend.PlayClip(r.Context(), clip), http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/favorites", func(w http.ResponseWriter, r *http.Request) {
+		favorites, err := backend.Favorites(r.Context())
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		if favorites == nil {
+			favorites = []Favorite{}
+		}
+		writeJSON(w, http.StatusOK, favorites)
+	})
+	mux.HandleFunc("POST /room/favorite", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Index *int `json:"index"`
+		}
+		if err := decodeJSON(r, &body); err != nil || body.Index == nil || *body.Index < 0 {
+			writeError(w, http.StatusBadRequest, `expected {"index": <favorite number from GET /api/favorites>}`)
+			return
+		}
+		respond(w, backend.PlayFavorite(r.Context(), *body.Index), http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/rooms", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), roomsTimeout)
+		defer cancel()
+		rooms, err := backend.Rooms(ctx)
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		if rooms == nil {
+			rooms = []Room{}
+		}
+		writeJSON(w, http.StatusOK, rooms)
+	})
+	mux.HandleFunc("GET /setup", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(setupHTML))
+	})
+	mux.HandleFunc("POST /display/image", func(w http.ResponseWriter, r *http.Request) {
+		img, _, err := image.Decode(http.MaxBytesReader(w, r.Body, maxImageBytes))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode image: %v", err))
+			return
+		}
+		respond(w, backend.ShowImage(img), http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/art/current", func(w http.ResponseWriter, r *http.Request) {
+		upstream, err := backend.CurrentArtURL()
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		proxyArt(w, r, upstream)
+	})
+	mux.HandleFunc("GET /api/art/{signature}", func(w http.ResponseWriter, r *http.Request) {
+		width, height, err := artSize(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		img, err := backend.Art(r.PathValue("signature"))
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		if width > 0 {
+			img = scaleImage(img, width, height)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("encode image: %v", err))
+			return
+		}
+		// Art is keyed by track, so its bytes never change.
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		_, _ = w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("GET /api/palette", func(w http.ResponseWriter, r *http.Request) {
+		palette, err := backend.Palette()
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		if palette.Colors == nil {
+			palette.Colors = []theme.Swatch{}
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, palette)
+	})
+	mux.HandleFunc("DELETE /api/art", func(w http.ResponseWriter, r *http.Request) {
+		purged, err := backend.PurgeArt()
+		if err != nil {
+			respond(w, err, http.StatusOK)
+			return
+		}
+		writeJSON(w, http.StatusOK, purged)
+	})
+	return mux
+}
+
+// proxyArt streams the album art at upstream to w, so pages can show the
+// speaker's art from this origin. The path serves each track's art in turn,
+// so browsers must revalidate; the ETag is derived from upstream, which
+// names the art, and a browser that already has it is answered without
+// asking the speaker.
+func proxyArt(w http.ResponseWriter, r *http.Request, upstream string) {
+	sum := sha256.Sum256([]byte(upstream))
+	etag := fmt.Sprintf(`"%x"`, sum[:8])
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if match := r.Header.Get("If-None-Match"); match == "*" || strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream, nil)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("album art address: %v", err))
+		return
+	}
+	resp, err := artClient.Do(req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("fetch album art: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		respond(w, fmt.Errorf("album art: %w", ErrNotFound), http.StatusOK)
+		return
+	case resp.StatusCode != http.StatusOK:
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("fetch album art: speaker answered %s", resp.Status))
+		return
+	case resp.ContentLength > maxProxiedArt:
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("album art is %d bytes, over the %d byte limit", resp.ContentLength, maxProxiedArt))
+		return
+	}
+
+	// Speakers and radio stations do not all label their art, so anything
+	// not declared an image is sniffed.
+	body := bufio.NewReaderSize(io.LimitReader(resp.Body, maxProxiedArt), 512)
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); !strings.HasPrefix(mediaType, "image/") {
+		head, _ := body.Peek(512)
+		contentType = http.DetectContentType(head)
+		if !strings.HasPrefix(contentType, "image/") {
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("album art is %s, not an image", contentType))
+			return
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	if resp.ContentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if modified := resp.Header.Get("Last-Modified"); modified != "" {
+		w.Header().Set("Last-Modified", modified)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+// ArtPath returns the path GET /api/art/{signature} serves signature on.
+func ArtPath(signature string) string {
+	if signature == "" {
+		return ""
+	}
+	return "/api/art/" + url.PathEscape(signature)
+}
+
+// artSize reads the w and h query parameters. Either alone gives a square;
+// neither leaves the art at its cached size, reported as 0, 0.
+func artSize(query url.Values) (int, int, error) {
+	parse := func(name string) (int, error) {
+		value := strings.TrimSpace(query.Get(name))
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxArtSize {
+			return 0, fmt.Errorf("%s must be between 1 and %d, got %q", name, maxArtSize, value)
+		}
+		return n, nil
+	}
+	width, err := parse("w")
+	if err != nil {
+		return 0, 0, err
+	}
+	height, err := parse("h")
+	if err != nil {
+		return 0, 0, err
+	}
+	if width == 0 {
+		width = height
+	}
+	if height == 0 {
+		height = width
+	}
+	return width, height, nil
+}
+
+func scaleImage(src image.Image, width, height int) image.Image {
+	if b := src.Bounds(); b.Dx() == width && b.Dy() == height {
+		return src
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+func decodeJSON(r *http.Request, dst interface{}) error {
+	return json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(dst)
+}
+
+// respond writes success, or maps err to an error response.
+func respond(w http.ResponseWriter, err error, success int) {
+	switch {
+	case err == nil:
+		w.WriteHeader(success)
+	case errors.Is(err, ErrNoDisplay):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrUnsupported):
+		writeError(w, http.StatusNotImplemented, err.Error())
+	case errors.Is(err, ErrBusy):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,522 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"musicDisplay/theme"
+)
+
+type fakeBackend struct {
+	status      Status
+	cleared     bool
+	brightness  int
+	room        string
+	shown       image.Image
+	art         map[string]image.Image
+	artURL      string
+	rooms       []Room
+	persisted   bool
+	largeText   bool
+	diagnostics bool
+	lyrics      bool
+	visualizer  bool
+	notified    string
+	notifyFor   time.Duration
+	palette     *Palette
+	clip        *Clip
+	favorites   []Favorite
+	played      int
+	timer       time.Duration
+	stopwatch   bool
+	err         error
+}
+
+func (f *fakeBackend) Status() Status { return f.status }
+func (f *fakeBackend) Clear() error   { f.cleared = true; return f.err }
+func (f *fakeBackend) SetBrightness(level int) error {
+	f.brightness = level
+	return f.err
+}
+func (f *fakeBackend) SetDiagnostics(on bool) error {
+	f.diagnostics = on
+	return f.err
+}
+func (f *fakeBackend) SetLyrics(on bool) error {
+	f.lyrics = on
+	return f.err
+}
+func (f *fakeBackend) SetVisualizer(on bool) error {
+	f.visualizer = on
+	return f.err
+}
+func (f *fakeBackend) Notify(text string, d time.Duration, priority int) error {
+	f.notified, f.notifyFor = text, d
+	return f.err
+}
+func (f *fakeBackend) SetLargeText(on bool) error {
+	f.largeText = on
+	return f.err
+}
+func (f *fakeBackend) PlayClip(ctx context.Context, clip Clip) error {
+	f.clip = &clip
+	return f.err
+}
+func (f *fakeBackend) Favorites(ctx context.Context) ([]Favorite, error) {
+	return f.favorites, f.err
+}
+func (f *fakeBackend) PlayFavorite(ctx context.Context, index int) error {
+	if index >= len(f.favorites) {
+		return ErrNotFound
+	}
+	f.played = index
+	return f.err
+}
+func (f *fakeBackend) StartTimer(d time.Duration) error {
+	f.timer = d
+	return f.err
+}
+func (f *fakeBackend) StartStopwatch() error {
+	f.stopwatch = true
+	return f.err
+}
+func (f *fakeBackend) StopTimer() error {
+	f.timer, f.stopwatch = 0, false
+	return f.err
+}
+func (f *fakeBackend) SwitchRoom(room string) error {
+	f.room = room
+	return f.err
+}
+func (f *fakeBackend) SelectRoom(room string) error {
+	f.room = room
+	f.persisted = true
+	return f.err
+}
+func (f *fakeBackend) Rooms(ctx context.Context) ([]Room, error) {
+	return f.rooms, f.err
+}
+func (f *fakeBackend) ShowImage(img image.Image) error {
+	f.shown = img
+	return f.err
+}
+
+func (f *fakeBackend) Art(signature string) (image.Image, error) {
+	if img, ok := f.art[signature]; ok {
+		return img, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (f *fakeBackend) CurrentArtURL() (string, error) {
+	if f.artURL == "" {
+		return "", ErrNotFound
+	}
+	return f.artURL, nil
+}
+
+func (f *fakeBackend) PurgeArt() (ArtPurge, error) {
+	purged := ArtPurge{Files: len(f.art)}
+	f.art = nil
+	return purged, f.err
+}
+
+func (f *fakeBackend) Palette() (Palette, error) {
+	if f.palette == nil {
+		return Palette{}, ErrNotFound
+	}
+	return *f.palette, nil
+}
+
+func TestStatus(t *testing.T) {
+	backend := &fakeBackend{status: Status{Room: "Kitchen", Playing: true, Title: "Song"}}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatalf("get status: %v", err)
+	}
+	defer resp.Body.Close()
+	var got Status
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if got != backend.status {
+		t.Fatalf("status = %+v, want %+v", got, backend.status)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	backend := &fakeBackend{}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	post := func(path, contentType string, body []byte) int {
+		t.Helper()
+		resp, err := http.Post(server.URL+path, contentType, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/display/clear", "", nil); code != http.StatusNoContent || !backend.cleared {
+		t.Fatalf("clear = %d, cleared %v", code, backend.cleared)
+	}
+	if code := post("/display/brightness", "application/json", []byte(`{"brightness": 40}`)); code != http.StatusNoContent || backend.brightness != 40 {
+		t.Fatalf("brightness = %d, level %d", code, backend.brightness)
+	}
+	if code := post("/display/brightness", "application/json", []byte(`{"brightness": 0}`)); code != http.StatusBadRequest {
+		t.Fatalf("brightness 0 = %d, want 400", code)
+	}
+	if code := post("/display/large_text", "application/json", []byte(`{"enabled": true}`)); code != http.StatusNoContent || !backend.largeText {
+		t.Fatalf("large text = %d, enabled %v", code, backend.largeText)
+	}
+	if code := post("/display/large_text", "application/json", []byte(`{}`)); code != http.StatusBadRequest {
+		t.Fatalf("large text without enabled = %d, want 400", code)
+	}
+	if code := post("/display/diagnostics", "application/json", []byte(`{"enabled": true}`)); code != http.StatusNoContent || !backend.diagnostics {
+		t.Fatalf("diagnostics = %d, enabled %v", code, backend.diagnostics)
+	}
+	if code := post("/display/lyrics", "application/json", []byte(`{"enabled": true}`)); code != http.StatusNoContent || !backend.lyrics {
+		t.Fatalf("lyrics = %d, enabled %v", code, backend.lyrics)
+	}
+	if code := post("/display/visualizer", "application/json", []byte(`{"enabled": true}`)); code != http.StatusNoContent || !backend.visualizer {
+		t.Fatalf("visualizer = %d, enabled %v", code, backend.visualizer)
+	}
+	if code := post("/display/notify", "application/json", []byte(`{"text": "Door open", "seconds": 12.5}`)); code != http.StatusNoContent || backend.notified != "Door open" || backend.notifyFor != 12500*time.Millisecond {
+		t.Fatalf("notify = %d, text %q for %s", code, backend.notified, backend.notifyFor)
+	}
+	for _, body := range []string{`{"text": " "}`, `{"text": "hi", "seconds": -1}`, `{"text": "hi", "seconds": 601}`, `{"text": "` + strings.Repeat("x", maxNotification+1) + `"}`} {
+		if code := post("/display/notify", "application/json", []byte(body)); code != http.StatusBadRequest {
+			t.Fatalf("notify %s = %d, want 400", body, code)
+		}
+	}
+	if code := post("/room", "application/json", []byte(`{"room": " Kitchen "}`)); code != http.StatusAccepted || backend.room != "Kitchen" || backend.persisted {
+		t.Fatalf("room = %d, %q, persisted %v", code, backend.room, backend.persisted)
+	}
+	if code := post("/room", "application/json", []byte(`{"room": "Den", "persist": true}`)); code != http.StatusAccepted || backend.room != "Den" || !backend.persisted {
+		t.Fatalf("persisted room = %d, %q, persisted %v", code, backend.room, backend.persisted)
+	}
+	if code := post("/timer", "application/json", []byte(`{"duration": "5m30s"}`)); code != http.StatusNoContent || backend.timer != 330*time.Second {
+		t.Fatalf("timer = %d, %v", code, backend.timer)
+	}
+	if code := post("/timer", "application/json", []byte(`{"seconds": 90}`)); code != http.StatusNoContent || backend.timer != 90*time.Second {
+		t.Fatalf("timer seconds = %d, %v", code, backend.timer)
+	}
+	for _, body := range []string{`{}`, `{"duration": "soon"}`, `{"duration": "25h"}`, `{"seconds": -5}`} {
+		if code := post("/timer", "application/json", []byte(body)); code != http.StatusBadRequest {
+			t.Fatalf("timer %s = %d, want 400", body, code)
+		}
+	}
+	if code := post("/stopwatch", "", nil); code != http.StatusNoContent || !backend.stopwatch {
+		t.Fatalf("stopwatch = %d, %v", code, backend.stopwatch)
+	}
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/timer", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent || backend.stopwatch {
+		t.Fatalf("stop timer = %v, %v", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+	if code := post("/room/clip", "", nil); code != http.StatusNoContent || backend.clip == nil || *backend.clip != (Clip{}) {
+		t.Fatalf("chime = %d, %+v", code, backend.clip)
+	}
+	if code := post("/room/clip", "application/json", []byte(`{"url": "http://nas/ding.mp3", "volume": 30, "priority": "high"}`)); code != http.StatusNoContent || backend.clip.URL != "http://nas/ding.mp3" || backend.clip.Volume != 30 {
+		t.Fatalf("clip = %d, %+v", code, backend.clip)
+	}
+	if code := post("/room/clip", "application/json", []byte(`{"url": "file:///etc/passwd"}`)); code != http.StatusBadRequest {
+		t.Fatalf("file clip = %d, want 400", code)
+	}
+	if code := post("/room/clip", "application/json", []byte(`{"volume": 101}`)); code != http.StatusBadRequest {
+		t.Fatalf("loud clip = %d, want 400", code)
+	}
+	backend.err = ErrUnsupported
+	if code := post("/room/clip", "", nil); code != http.StatusNotImplemented {
+		t.Fatalf("unsupported clip = %d, want 501", code)
+	}
+	backend.err = nil
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	if code := post("/display/image", "image/png", buf.Bytes()); code != http.StatusNoContent || backend.shown == nil {
+		t.Fatalf("image = %d", code)
+	}
+	if code := post("/display/image", "image/png", []byte("not an image")); code != http.StatusBadRequest {
+		t.Fatalf("bad image = %d, want 400", code)
+	}
+
+	backend.err = ErrNoDisplay
+	resp, err := http.Post(server.URL+"/display/clear", "", nil)
+	if err != nil {
+		t.Fatalf("post clear: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(body["error"], "no display") {
+		t.Fatalf("clear without display = %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestArtResizes(t *testing.T) {
+	backend := &fakeBackend{art: map[string]image.Image{"abc123": image.NewRGBA(image.Rect(0, 0, 64, 64))}}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	get := func(path string) (int, image.Image) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		img, err := png.Decode(resp.Body)
+		if err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return resp.StatusCode, img
+	}
+
+	cases := []struct {
+		path string
+		size image.Point
+	}{
+		{"/api/art/abc123", image.Pt(64, 64)},
+		{"/api/art/abc123?w=32", image.Pt(32, 32)},
+		{"/api/art/abc123?w=128&h=96", image.Pt(128, 96)},
+	}
+	for _, tc := range cases {
+		code, img := get(tc.path)
+		if code != http.StatusOK || img.Bounds().Size() != tc.size {
+			t.Fatalf("%s = %d, size %v; want %v", tc.path, code, img, tc.size)
+		}
+	}
+	if code, _ := get("/api/art/missing"); code != http.StatusNotFound {
+		t.Fatalf("missing art = %d, want 404", code)
+	}
+	if code, _ := get("/api/art/abc123?w=0"); code != http.StatusBadRequest {
+		t.Fatalf("w=0 = %d, want 400", code)
+	}
+	if code, _ := get("/api/art/abc123?h=5000"); code != http.StatusBadRequest {
+		t.Fatalf("h=5000 = %d, want 400", code)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/art", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	var purged ArtPurge
+	if err := json.NewDecoder(resp.Body).Decode(&purged); err != nil || resp.StatusCode != http.StatusOK || purged.Files != 1 {
+		t.Fatalf("purge = %d, %+v, %v; want 200 with one file", resp.StatusCode, purged, err)
+	}
+	resp.Body.Close()
+	if code, _ := get("/api/art/abc123"); code != http.StatusNotFound {
+		t.Fatalf("purged art = %d, want 404", code)
+	}
+}
+
+func TestCurrentArtProxy(t *testing.T) {
+	var cover bytes.Buffer
+	if err := png.Encode(&cover, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	requests := 0
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Query().Get("u") {
+		case "song":
+			// Unlabeled, as some stations serve it.
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(cover.Bytes())
+		case "page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>moved</html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer speaker.Close()
+	backend := &fakeBackend{}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	get := func(etag string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/art/current", nil)
+		if err != nil {
+			t.Fatalf("build request: %v", err)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("get art: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := get(""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("no track = %d, want 404", resp.StatusCode)
+	}
+	backend.artURL = speaker.URL + "/getaa?s=1&u=song"
+	resp := get("")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" || !bytes.Equal(body, cover.Bytes()) {
+		t.Fatalf("art = %d %q, %d bytes; want the PNG", resp.StatusCode, resp.Header.Get("Content-Type"), len(body))
+	}
+	if resp.Header.Get("Cache-Control") != "no-cache" || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("headers %v, want no-cache and any origin", resp.Header)
+	}
+	etag := resp.Header.Get("ETag")
+	if resp := get(etag); resp.StatusCode != http.StatusNotModified || requests != 1 {
+		t.Fatalf("revalidation = %d after %d speaker requests, want 304 after 1", resp.StatusCode, requests)
+	}
+
+	backend.artURL = speaker.URL + "/getaa?s=1&u=next"
+	if resp := get(etag); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("art the speaker lacks = %d, want 404", resp.StatusCode)
+	}
+	backend.artURL = speaker.URL + "/getaa?s=1&u=page"
+	if resp := get(""); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("html art = %d, want 502", resp.StatusCode)
+	}
+}
+
+func TestPalette(t *testing.T) {
+	backend := &fakeBackend{}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/palette")
+	if err != nil {
+		t.Fatalf("get palette: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("palette without art = %d, want 404", resp.StatusCode)
+	}
+
+	backend.palette = &Palette{Art: "/api/art/abc123"}
+	resp, err = http.Get(server.URL + "/api/palette")
+	if err != nil {
+		t.Fatalf("get palette: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"colors":[]`) {
+		t.Fatalf("grey art palette = %d %s, want an empty color list", resp.StatusCode, body)
+	}
+
+	backend.palette.Colors = theme.Swatches([]color.RGBA{{R: 0x20, G: 0x40, B: 0xc0, A: 0xff}})
+	resp, err = http.Get(server.URL + "/api/palette")
+	if err != nil {
+		t.Fatalf("get palette: %v", err)
+	}
+	var got Palette
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil || len(got.Colors) != 1 || got.Colors[0].Hex != "#2040c0" || got.Colors[0].RGB != [3]int{0x20, 0x40, 0xc0} || got.Art != "/api/art/abc123" {
+		t.Fatalf("palette = %+v, %v", got, err)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	backend := &fakeBackend{played: -1}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/favorites")
+	if err != nil {
+		t.Fatalf("get favorites: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "[]" {
+		t.Fatalf("no favorites = %d %s, want an empty list", resp.StatusCode, body)
+	}
+
+	backend.favorites = []Favorite{{Index: 0, Title: "Radio Paradise", Description: "TuneIn Station"}, {Index: 1, Title: "Morning Mix"}}
+	resp, err = http.Get(server.URL + "/api/favorites")
+	if err != nil {
+		t.Fatalf("get favorites: %v", err)
+	}
+	var got []Favorite
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil || len(got) != 2 || got[0] != backend.favorites[0] {
+		t.Fatalf("favorites = %+v, %v", got, err)
+	}
+
+	post := func(body string) int {
+		resp, err := http.Post(server.URL+"/room/favorite", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("post favorite: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post(`{"index": 1}`); code != http.StatusNoContent || backend.played != 1 {
+		t.Fatalf("play favorite = %d, played %d", code, backend.played)
+	}
+	if code := post(`{"index": 5}`); code != http.StatusNotFound {
+		t.Fatalf("unknown favorite = %d, want 404", code)
+	}
+	for _, body := range []string{`{}`, `{"index": -1}`, `{"index": "one"}`} {
+		if code := post(body); code != http.StatusBadRequest {
+			t.Fatalf("favorite %s = %d, want 400", body, code)
+		}
+	}
+}
+
+func TestRoomsAndSetupPage(t *testing.T) {
+	backend := &fakeBackend{rooms: []Room{
+		{Name: "Kitchen", State: "Playing", Track: "Artist – Song", Current: true},
+		{Name: "Den", State: "Stopped"},
+	}}
+	server := httptest.NewServer(Handler(backend))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/rooms")
+	if err != nil {
+		t.Fatalf("get rooms: %v", err)
+	}
+	defer resp.Body.Close()
+	var rooms []Room
+	if err := json.NewDecoder(resp.Body).Decode(&rooms); err != nil {
+		t.Fatalf("decode rooms: %v", err)
+	}
+	if len(rooms) != 2 || rooms[0] != backend.rooms[0] {
+		t.Fatalf("rooms = %+v, want %+v", rooms, backend.rooms)
+	}
+
+	page, err := http.Get(server.URL + "/setup")
+	if err != nil {
+		t.Fatalf("get setup: %v", err)
+	}
+	defer page.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(page.Body)
+	if page.StatusCode != http.StatusOK || !strings.HasPrefix(page.Header.Get("Content-Type"), "text/html") || !strings.Contains(body.String(), "api/rooms") {
+		t.Fatalf("setup page = %d %q", page.StatusCode, page.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,99 @@
+package httpapi
+
+// setupHTML is the page served at /setup. It lists the rooms from
+// /api/rooms and switches to one with POST /room, saving the choice.
+const setupHTML = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>WallDisplay setup</title>
+<style>
+  body { background: #111; color: #ccc; font: 14px sans-serif; max-width: 640px; margin: 40px auto; padding: 0 16px; }
+  h1 { font-size: 20px; font-weight: normal; }
+  table { width: 100%; border-collapse: collapse; }
+  td { padding: 8px 6px; border-bottom: 1px solid #222; }
+  td.track { color: #888; }
+  tr.current td:first-child::before { content: "▶ "; color: #6c6; }
+  button { background: #222; color: #ccc; border: 1px solid #444; border-radius: 4px; padding: 6px 18px; font: inherit; cursor: pointer; }
+  button:disabled { opacity: 0.4; cursor: default; }
+</style>
+</head>
+<body>
+<h1>Choose the room to display</h1>
+<p id="result">Looking for rooms…</p>
+<table id="rooms"></table>
+<p><button id="rescan">Search again</button></p>
+<script>
+  const table = document.getElementById("rooms");
+  const result = document.getElementById("result");
+  const rescan = document.getElementById("rescan");
+
+  function cell(row, text, cls) {
+    const td = row.insertCell();
+    td.textContent = text;
+    if (cls) td.className = cls;
+    return td;
+  }
+
+  async function select(name) {
+    result.textContent = "Switching to " + name + "…";
+    try {
+      const resp = await fetch("room", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ room: name, persist: true }),
+      });
+      if (!resp.ok) {
+        result.textContent = (await resp.json()).error || resp.statusText;
+        return;
+      }
+      result.textContent = "Switched to " + name + " and saved to the config file.";
+      for (const row of table.rows) {
+        const current = row.dataset.room === name;
+        row.classList.toggle("current", current);
+        row.querySelector("button").disabled = current;
+      }
+    } catch (e) {
+      result.textContent = String(e);
+    }
+  }
+
+  async function load() {
+    rescan.disabled = true;
+    result.textContent = "Looking for rooms…";
+    table.replaceChildren();
+    try {
+      const resp = await fetch("api/rooms");
+      const body = await resp.json();
+      if (!resp.ok) {
+        result.textContent = body.error || resp.statusText;
+        return;
+      }
+      result.textContent = body.length ? "" : "No rooms found.";
+      for (const room of body) {
+        const row = table.insertRow();
+        row.dataset.room = room.name;
+        row.classList.toggle("current", room.current);
+        cell(row, room.name);
+        cell(row, room.state);
+        cell(row, room.track, "track");
+        const button = document.createElement("button");
+        button.textContent = "Show";
+        button.disabled = room.current;
+        button.addEventListener("click", () => select(room.name));
+        row.insertCell().appendChild(button);
+      }
+    } catch (e) {
+      result.textContent = String(e);
+    } finally {
+      rescan.disabled = false;
+    }
+  }
+
+  rescan.addEventListener("click", load);
+  load();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,158 @@
+// Package logging provides the structured logger shared by the program's
+// packages. Each package obtains its logger with For; Setup later selects the
+// output format and the levels, including per-package overrides, so loggers
+// created at init time pick up the configuration once it is known.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Output formats accepted by Setup.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// PackageKey is the attribute that names the package a record came from.
+const PackageKey = "pkg"
+
+// Options configures the shared logger.
+type Options struct {
+	// Level is the minimum level logged: debug, info, warn, or error. It
+	// defaults to info.
+	Level string
+	// Format is text (default) or json. JSON suits journald and other log
+	// collectors.
+	Format string
+	// Packages overrides Level for individual packages, keyed by the name
+	// passed to For.
+	Packages map[string]string
+}
+
+type state struct {
+	handler  slog.Handler
+	level    slog.Level
+	packages map[string]slog.Level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// Setup installs the shared logger writing to w. It also becomes the slog
+// default, and output from the standard log package is routed through it at
+// info level.
+func Setup(w io.Writer, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	level, _ := ParseLevel(opts.Level)
+	packages := make(map[string]slog.Level, len(opts.Packages))
+	for pkg, value := range opts.Packages {
+		packages[pkg], _ = ParseLevel(value)
+	}
+
+	// Levels are filtered per package before records reach the handler.
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	if strings.EqualFold(strings.TrimSpace(opts.Format), FormatJSON) {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOpts)
+	}
+
+	current.Store(&state{handler: handler, level: level, packages: packages})
+	slog.SetDefault(For("main"))
+	return nil
+}
+
+// Validate checks the level names and the format.
+func (o Options) Validate() error {
+	if _, err := ParseLevel(o.Level); err != nil {
+		return err
+	}
+	for pkg, value := range o.Packages {
+		if _, err := ParseLevel(value); err != nil {
+			return fmt.Errorf("logging: package %q: %w", pkg, err)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(o.Format)) {
+	case "", FormatText, FormatJSON:
+		return nil
+	}
+	return fmt.Errorf("logging: unknown format %q (want text or json)", o.Format)
+}
+
+// ParseLevel parses debug, info, warn (or warning), and error. An empty
+// string is info.
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("logging: unknown level %q (want debug, info, warn, or error)", value)
+}
+
+// For returns the logger for pkg. Its records carry a pkg attribute and are
+// filtered by the package's level.
+func For(pkg string) *slog.Logger {
+	return slog.New(&packageHandler{pkg: pkg})
+}
+
+// packageHandler resolves the installed handler on every call so loggers
+// created before Setup follow its configuration.
+type packageHandler struct {
+	pkg string
+	// wrap applies WithAttrs and WithGroup calls, in order, to the installed
+	// handler.
+	wrap []func(slog.Handler) slog.Handler
+}
+
+func (h *packageHandler) Enabled(_ context.Context, level slog.Level) bool {
+	s := current.Load()
+	min, ok := s.packages[h.pkg]
+	if !ok {
+		min = s.level
+	}
+	return level >= min
+}
+
+func (h *packageHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler := current.Load().handler.WithAttrs([]slog.Attr{slog.String(PackageKey, h.pkg)})
+	for _, wrap := range h.wrap {
+		handler = wrap(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *packageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *packageHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *packageHandler) with(wrap func(slog.Handler) slog.Handler) slog.Handler {
+	wraps := make([]func(slog.Handler) slog.Handler, len(h.wrap), len(h.wrap)+1)
+	copy(wraps, h.wrap)
+	return &packageHandler{pkg: h.pkg, wrap: append(wraps, wrap)}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPackageLevelsAndJSON(t *testing.T) {
+	// Loggers created before Setup follow it.
+	sonosLog := For("sonos")
+	renderLog := For("render").With("room", "Kitchen")
+
+	var out bytes.Buffer
+	err := Setup(&out, Options{Level: "warn", Format: "json", Packages: map[string]string{"sonos": "debug"}})
+	if err != nil {
+		t.Fatalf("Setup error: %v", err)
+	}
+
+	sonosLog.Debug("subscribed", "sid", "uuid:1")
+	renderLog.Info("dropped")
+	renderLog.Warn("render failed")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(lines), out.String())
+	}
+	var first, second map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("decode record: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("decode record: %v", err)
+	}
+	if first["level"] != "DEBUG" || first[PackageKey] != "sonos" || first["sid"] != "uuid:1" {
+		t.Fatalf("sonos record = %v", first)
+	}
+	if second["level"] != "WARN" || second[PackageKey] != "render" || second["room"] != "Kitchen" {
+		t.Fatalf("render record = %v", second)
+	}
+}
+
+func TestSetupRejectsUnknownValues(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(&out, Options{Level: "loud"}); err == nil {
+		t.Fatalf("unknown level accepted")
+	}
+	if err := Setup(&out, Options{Format: "xml"}); err == nil {
+		t.Fatalf("unknown format accepted")
+	}
+	if err := Setup(&out, Options{Packages: map[string]string{"sonos": "verbose"}}); err == nil {
+		t.Fatalf("unknown package level accepted")
+	}
+}
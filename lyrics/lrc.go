@@ -0,0 +1,99 @@
+package lyrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseLRC reads synced lyrics in the LRC format: lines of text, each
+// after one or more "[mm:ss.xx]" timestamps. Metadata tags such as
+// "[ar:Artist]" and lines without a timestamp are skipped, and an "[offset:
+// ms]" tag shifts every line. The lines are returned in time order.
+func ParseLRC(s string) []Line {
+	var lines []Line
+	offset := time.Duration(0)
+	for _, raw := range strings.Split(s, "\n") {
+		rest := strings.TrimSpace(raw)
+		var times []time.Duration
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				break
+			}
+			tag := rest[1:end]
+			rest = strings.TrimSpace(rest[end+1:])
+			if at, ok := parseTimestamp(tag); ok {
+				times = append(times, at)
+				continue
+			}
+			if value, ok := strings.CutPrefix(tag, "offset:"); ok {
+				if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+					// A positive offset shows the lyrics sooner.
+					offset = -time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+		for _, at := range times {
+			lines = append(lines, Line{At: at, Text: rest})
+		}
+	}
+	for i := range lines {
+		lines[i].At = max(lines[i].At+offset, 0)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].At < lines[j].At })
+	return lines
+}
+
+// parseTimestamp reads "mm:ss", "mm:ss.x", "mm:ss.xx", or "mm:ss.xxx".
+func parseTimestamp(tag string) (time.Duration, bool) {
+	minutes, seconds, ok := strings.Cut(tag, ":")
+	if !ok {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, false
+	}
+	whole, fraction, _ := strings.Cut(seconds, ".")
+	sec, err := strconv.Atoi(whole)
+	if err != nil || sec < 0 || sec >= 60 {
+		return 0, false
+	}
+	at := time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	if fraction != "" {
+		if len(fraction) > 3 {
+			fraction = fraction[:3]
+		}
+		f, err := strconv.Atoi(fraction)
+		if err != nil || f < 0 {
+			return 0, false
+		}
+		for i := len(fraction); i < 3; i++ {
+			f *= 10
+		}
+		at += time.Duration(f) * time.Millisecond
+	}
+	return at, true
+}
+
+// ParsePlain splits unsynced lyrics into lines, keeping single blank lines
+// between verses and dropping blank lines at either end.
+func ParsePlain(s string) []Line {
+	var lines []Line
+	blank := false
+	for _, raw := range strings.Split(s, "\n") {
+		text := strings.TrimSpace(raw)
+		if text == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, Line{})
+			blank = false
+		}
+		lines = append(lines, Line{Text: text})
+	}
+	return lines
+}
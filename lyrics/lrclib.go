@@ -0,0 +1,133 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"musicDisplay/sonos"
+)
+
+const (
+	// DefaultURL is LRCLIB's public API.
+	DefaultURL = "https://lrclib.net/api"
+	// userAgent identifies the app, as LRCLIB asks of its clients.
+	userAgent = "WallDisplay (https://github.com/patojar/WallDisplay)"
+	// durationSlack is how far a search result's length may be from the
+	// track's to count as the same recording.
+	durationSlack = 2 * time.Second
+)
+
+// LRCLib fetches lyrics from LRCLIB (lrclib.net) or a server running its
+// API. It needs no API key.
+type LRCLib struct {
+	URL string
+
+	httpClient *http.Client
+}
+
+// NewLRCLib returns a provider for the LRCLIB API at baseURL (DefaultURL
+// when empty).
+func NewLRCLib(baseURL string) *LRCLib {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &LRCLib{URL: strings.TrimRight(baseURL, "/"), httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// lrclibRecord is a track in LRCLIB's responses. The lyrics are null when
+// it has none.
+type lrclibRecord struct {
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+}
+
+// Fetch implements Provider. It asks for the exact track first, which needs
+// the album and length to match, and then searches by artist and title,
+// since speakers often report a different album name than LRCLIB has.
+func (p *LRCLib) Fetch(ctx context.Context, track sonos.TrackInfo) (Lyrics, error) {
+	query := url.Values{
+		"artist_name": {track.Artist},
+		"track_name":  {track.Title},
+	}
+	search := query.Encode()
+	if track.Album != "" {
+		query.Set("album_name", track.Album)
+	}
+	if track.Duration > 0 {
+		query.Set("duration", strconv.Itoa(int(track.Duration.Round(time.Second)/time.Second)))
+	}
+	var record lrclibRecord
+	err := p.get(ctx, "/get?"+query.Encode(), &record)
+	if err == nil {
+		return record.lyrics()
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Lyrics{}, err
+	}
+
+	var results []lrclibRecord
+	if err := p.get(ctx, "/search?"+search, &results); err != nil {
+		return Lyrics{}, err
+	}
+	for _, result := range results {
+		if track.Duration > 0 && result.Duration > 0 && (time.Duration(result.Duration*float64(time.Second))-track.Duration).Abs() > durationSlack {
+			continue
+		}
+		if lyrics, err := result.lyrics(); err == nil {
+			return lyrics, nil
+		}
+	}
+	return Lyrics{}, ErrNotFound
+}
+
+// lyrics returns the record's synced lyrics, or its plain ones when it has
+// no synced ones.
+func (r lrclibRecord) lyrics() (Lyrics, error) {
+	if r.Instrumental {
+		return Lyrics{}, ErrNotFound
+	}
+	if lines := ParseLRC(r.SyncedLyrics); len(lines) > 0 {
+		return Lyrics{Lines: lines, Synced: true}, nil
+	}
+	if lines := ParsePlain(r.PlainLyrics); len(lines) > 0 {
+		return Lyrics{Lines: lines}, nil
+	}
+	return Lyrics{}, ErrNotFound
+}
+
+// get fetches path from the API and decodes its JSON body into dst. A 404
+// is ErrNotFound.
+func (p *LRCLib) get(ctx context.Context, path string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+path, nil)
+	if err != nil {
+		return fmt.Errorf("lyrics: lrclib: build request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("lyrics: lrclib: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("lyrics: lrclib: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(dst); err != nil {
+		return fmt.Errorf("lyrics: lrclib: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Package lyrics fetches song lyrics from LRCLIB and shows them as a screen
+// that takes over from the artwork while a track with lyrics plays: synced
+// lyrics line by line in time with the music, and plain lyrics scrolling
+// through over the length of the track. Tracks without lyrics keep showing
+// their artwork.
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+)
+
+var logger = logging.For("lyrics")
+
+const (
+	// ScreenName is the name the lyrics screen is shown by.
+	ScreenName = "lyrics"
+
+	requestTimeout = 10 * time.Second
+	// maxResponseBytes bounds a provider's response.
+	maxResponseBytes = 4 << 20
+
+	// cacheSize is how many tracks' lyrics, or their lack of them, are
+	// kept, so replaying an album or a playlist asks the provider once.
+	cacheSize = 200
+	// retryDelay is how soon lyrics that failed to load are asked for
+	// again.
+	retryDelay = time.Minute
+	// missAge is how long a track without lyrics is remembered before the
+	// provider is asked again, since lyrics are added to it over time.
+	missAge = 24 * time.Hour
+	// maxLead is the furthest the screen runs ahead of the last position
+	// the speaker reported, which comes about once a second.
+	maxLead = 2 * time.Second
+
+	// focusPriority is the screen's focus priority while a track with
+	// lyrics plays: above the music but below diagnostics and a running
+	// kitchen timer.
+	focusPriority = 20
+)
+
+// ErrNotFound is returned by a Provider for a track without lyrics,
+// including instrumentals.
+var ErrNotFound = errors.New("lyrics: not found")
+
+// Line is one line of lyrics. At is when it is sung, for synced lyrics.
+// Blank lines separate verses.
+type Line struct {
+	At   time.Duration
+	Text string
+}
+
+// Lyrics are a track's lyrics, synced when every line carries its time.
+type Lyrics struct {
+	Lines  []Line
+	Synced bool
+}
+
+// Provider fetches lyrics from a lyrics service.
+type Provider interface {
+	Fetch(ctx context.Context, track sonos.TrackInfo) (Lyrics, error)
+}
+
+// Screen is the lyrics screen. It implements render.FocusScreen: while it
+// is enabled, it asks for focus whenever a track with lyrics plays, and
+// fetches the lyrics of each new track in the background.
+type Screen struct {
+	clk      clock.Clock
+	provider Provider
+
+	mu      sync.Mutex
+	enabled bool
+	cache   map[string]*entry
+	// recent holds the cached keys, least recently used first.
+	recent []string
+	// showing is the lyrics last asked for focus with; key, position, and
+	// positionAt are the track they belong to and where it was when last
+	// reported.
+	showing    *Lyrics
+	key        string
+	position   time.Duration
+	positionAt time.Time
+}
+
+// entry is a track in the cache.
+type entry struct {
+	lyrics  Lyrics
+	found   bool
+	failed  bool
+	loading bool
+	// checked is when the provider last answered.
+	checked time.Time
+}
+
+var _ render.FocusScreen = (*Screen)(nil)
+
+// New returns a lyrics screen fetching from provider, enabled or not.
+func New(clk clock.Clock, provider Provider, enabled bool) *Screen {
+	return &Screen{clk: clock.Or(clk), provider: provider, enabled: enabled, cache: map[string]*entry{}}
+}
+
+// SetEnabled turns the lyrics mode on or off.
+func (s *Screen) SetEnabled(on bool) {
+	s.mu.Lock()
+	changed := s.enabled != on
+	s.enabled = on
+	s.mu.Unlock()
+	if changed {
+		logger.Info("lyrics mode changed", "enabled", on)
+	}
+}
+
+// Enabled reports whether the lyrics mode is on.
+func (s *Screen) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// Name implements render.Screen.
+func (s *Screen) Name() string {
+	return ScreenName
+}
+
+// Focus implements render.FocusScreen. It asks for focus while the mode
+// is on and the playing track's lyrics have loaded, so the artwork shows
+// while they load and for tracks without any.
+func (s *Screen) Focus(state render.FrameState) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.showing = nil
+	if !s.enabled || !state.Status.Playing {
+		return 0
+	}
+	lyrics, ok := s.lookupLocked(state.Status.Track, state.Now)
+	if !ok {
+		return 0
+	}
+	s.showing = &lyrics
+	return focusPriority
+}
+
+// Animating implements render.AnimatedLayer, so the renderer redraws the
+// lyrics as the track plays.
+func (s *Screen) Animating() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.showing != nil
+}
+
+// lookupLocked returns the track's lyrics, if they have loaded, and starts
+// fetching them when they are not cached or are due to be asked for again.
+// Callers must hold s.mu.
+func (s *Screen) lookupLocked(track sonos.TrackInfo, now time.Time) (Lyrics, bool) {
+	key := trackKey(track)
+	if key == "" {
+		return Lyrics{}, false
+	}
+	e, ok := s.cache[key]
+	if !ok {
+		e = &entry{}
+		s.cache[key] = e
+		s.evictLocked()
+	}
+	s.recent = append(slices.DeleteFunc(s.recent, func(k string) bool { return k == key }), key)
+	stale := e.checked.IsZero() ||
+		(e.failed && now.Sub(e.checked) >= retryDelay) ||
+		(!e.found && !e.failed && now.Sub(e.checked) >= missAge)
+	if stale && !e.loading {
+		e.loading = true
+		go s.fetch(key, track)
+	}
+	return e.lyrics, e.found
+}
+
+// evictLocked drops the least recently used tracks beyond cacheSize.
+// Callers must hold s.mu.
+func (s *Screen) evictLocked() {
+	for len(s.cache) > cacheSize && len(s.recent) > 0 {
+		delete(s.cache, s.recent[0])
+		s.recent = s.recent[1:]
+	}
+}
+
+// fetch asks the provider for the lyrics of track and caches the answer
+// under key.
+func (s *Screen) fetch(key string, track sonos.TrackInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	lyrics, err := s.provider.Fetch(ctx, track)
+	switch {
+	case err == nil:
+		logger.Debug("lyrics loaded", "artist", track.Artist, "title", track.Title, "lines", len(lyrics.Lines), "synced", lyrics.Synced)
+	case errors.Is(err, ErrNotFound):
+		logger.Debug("no lyrics for track", "artist", track.Artist, "title", track.Title)
+	default:
+		logger.Warn("lyrics fetch failed", "artist", track.Artist, "title", track.Title, "err", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.cache[key]
+	if !ok {
+		// Evicted while loading.
+		return
+	}
+	e.loading, e.checked = false, s.clk.Now()
+	e.failed = err != nil && !errors.Is(err, ErrNotFound)
+	if err == nil {
+		e.lyrics, e.found = lyrics, len(lyrics.Lines) > 0
+	} else if !e.failed {
+		e.lyrics, e.found = Lyrics{}, false
+	}
+}
+
+// estimateLocked returns how far into the track playback is at now,
+// running on from the last position reported for it. Callers must hold
+// s.mu.
+func (s *Screen) estimateLocked(status sonos.PlaybackStatus, now time.Time) time.Duration {
+	key := trackKey(status.Track)
+	if key != s.key || status.Track.Position != s.position {
+		s.key, s.position, s.positionAt = key, status.Track.Position, now
+	}
+	if !status.Playing {
+		return s.position
+	}
+	return s.position + min(max(now.Sub(s.positionAt), 0), maxLead)
+}
+
+// trackKey identifies a track in the cache, or is empty for one that
+// cannot have lyrics looked up, such as a radio station or the TV.
+func trackKey(track sonos.TrackInfo) string {
+	switch track.Source {
+	case sonos.SourceRadio, sonos.SourceLineIn, sonos.SourceTV:
+		return ""
+	}
+	artist := strings.ToLower(strings.TrimSpace(track.Artist))
+	title := strings.ToLower(strings.TrimSpace(track.Title))
+	if artist == "" || title == "" {
+		return ""
+	}
+	return artist + "\x00" + title + "\x00" + strings.ToLower(strings.TrimSpace(track.Album))
+}
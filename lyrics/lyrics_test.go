@@ -0,0 +1,268 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+func TestParseLRC(t *testing.T) {
+	lines := ParseLRC("[ar:Someone]\n[offset:+500]\n[00:12.30]First\r\n[00:05.5][01:02.123]Chorus\nno timestamp\n[00:20.00]\n")
+	want := []Line{
+		{At: 5 * time.Second, Text: "Chorus"},
+		{At: 11800 * time.Millisecond, Text: "First"},
+		{At: 19500 * time.Millisecond, Text: ""},
+		{At: 61623 * time.Millisecond, Text: "Chorus"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("ParseLRC = %+v, want %+v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	lines := ParsePlain("\n\nOne\nTwo\n\n\nThree\n\n")
+	want := []string{"One", "Two", "", "Three"}
+	if len(lines) != len(want) {
+		t.Fatalf("ParsePlain = %+v, want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i].Text != want[i] {
+			t.Fatalf("line %d = %q, want %q", i, lines[i].Text, want[i])
+		}
+	}
+}
+
+func TestLRCLibFallsBackToSearch(t *testing.T) {
+	var gets, searches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/api/get":
+			gets++
+			if query.Get("album_name") != "Live" || query.Get("duration") != "200" {
+				t.Errorf("get query = %v", query)
+			}
+			http.NotFound(w, r)
+		case "/api/search":
+			searches++
+			if query.Get("artist_name") != "Band" || query.Get("track_name") != "Song" || query.Has("album_name") {
+				t.Errorf("search query = %v", query)
+			}
+			w.Write([]byte(`[
+				{"duration": 260, "syncedLyrics": "[00:01.00]Wrong recording"},
+				{"duration": 201, "plainLyrics": null, "syncedLyrics": "[00:01.00]Hello\n[00:03.00]World"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	lyrics, err := NewLRCLib(server.URL+"/api/").Fetch(context.Background(), sonos.TrackInfo{Artist: "Band", Title: "Song", Album: "Live", Duration: 200 * time.Second})
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	if !lyrics.Synced || len(lyrics.Lines) != 2 || lyrics.Lines[1] != (Line{At: 3 * time.Second, Text: "World"}) {
+		t.Fatalf("Fetch = %+v, want the matching recording's synced lyrics", lyrics)
+	}
+	if gets != 1 || searches != 1 {
+		t.Fatalf("gets %d, searches %d; want one of each", gets, searches)
+	}
+}
+
+func TestLRCLibInstrumentalIsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"instrumental": true, "plainLyrics": null, "syncedLyrics": null}`))
+	}))
+	defer server.Close()
+	_, err := NewLRCLib(server.URL).Fetch(context.Background(), sonos.TrackInfo{Artist: "Band", Title: "Intro"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Fetch = %v, want ErrNotFound", err)
+	}
+}
+
+// fakeProvider answers from a table and reports each fetch.
+type fakeProvider struct {
+	lyrics  map[string]Lyrics
+	err     error
+	fetched chan string
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context, track sonos.TrackInfo) (Lyrics, error) {
+	defer func() { p.fetched <- track.Title }()
+	if p.err != nil {
+		return Lyrics{}, p.err
+	}
+	lyrics, ok := p.lyrics[track.Title]
+	if !ok {
+		return Lyrics{}, ErrNotFound
+	}
+	return lyrics, nil
+}
+
+// waitFocus polls s until its focus for state is want.
+func waitFocus(t *testing.T, s *Screen, state render.FrameState, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.Focus(state) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("focus = %d, want %d", s.Focus(state), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScreenFocusesOnTracksWithLyrics(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	provider := &fakeProvider{
+		lyrics:  map[string]Lyrics{"Song": {Lines: []Line{{Text: "La la"}}}},
+		fetched: make(chan string, 10),
+	}
+	s := New(clk, provider, false)
+	song := render.FrameState{Now: clk.Now(), Status: sonos.PlaybackStatus{Playing: true, Track: sonos.TrackInfo{Artist: "Band", Title: "Song"}}}
+	if s.Focus(song) != 0 {
+		t.Fatal("a disabled screen asked for focus")
+	}
+
+	s.SetEnabled(true)
+	if s.Focus(song) != 0 {
+		t.Fatal("focus asked for before the lyrics loaded")
+	}
+	<-provider.fetched
+	waitFocus(t, s, song, focusPriority)
+	if !s.Animating() {
+		t.Fatal("screen with focus is not animating")
+	}
+
+	// A track without lyrics falls back to the art, and is not asked for
+	// again until missAge has passed.
+	instrumental := song
+	instrumental.Status.Track.Title = "Intro"
+	s.Focus(instrumental)
+	<-provider.fetched
+	for range 3 {
+		if s.Focus(instrumental) != 0 || s.Animating() {
+			t.Fatal("focus asked for a track without lyrics")
+		}
+	}
+	select {
+	case title := <-provider.fetched:
+		t.Fatalf("fetched %q again", title)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(missAge)
+	instrumental.Now = clk.Now()
+	s.Focus(instrumental)
+	if title := <-provider.fetched; title != "Intro" {
+		t.Fatalf("fetched %q, want the track without lyrics again", title)
+	}
+
+	// Paused tracks and radio keep the art.
+	paused := song
+	paused.Status.Playing = false
+	radio := song
+	radio.Status.Track.Source = sonos.SourceRadio
+	if s.Focus(paused) != 0 || s.Focus(radio) != 0 {
+		t.Fatal("focus asked for while paused or on the radio")
+	}
+}
+
+func TestScreenRetriesFailedFetches(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	provider := &fakeProvider{err: errors.New("offline"), fetched: make(chan string, 10)}
+	s := New(clk, provider, true)
+	state := render.FrameState{Now: clk.Now(), Status: sonos.PlaybackStatus{Playing: true, Track: sonos.TrackInfo{Artist: "Band", Title: "Song"}}}
+	s.Focus(state)
+	<-provider.fetched
+	waitFocus(t, s, state, 0)
+	select {
+	case <-provider.fetched:
+		t.Fatal("failed fetch retried at once")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(retryDelay)
+	state.Now = clk.Now()
+	s.Focus(state)
+	<-provider.fetched
+}
+
+func TestDrawHighlightsTheLineBeingSung(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	provider := &fakeProvider{
+		lyrics: map[string]Lyrics{"Song": {Synced: true, Lines: []Line{
+			{At: 0, Text: "One"},
+			{At: 10 * time.Second, Text: "Two"},
+			{At: 20 * time.Second, Text: "Three"},
+		}}},
+		fetched: make(chan string, 10),
+	}
+	s := New(clk, provider, true)
+	palette := theme.DefaultPalette
+	state := render.FrameState{Palette: palette, Now: clk.Now(), Status: sonos.PlaybackStatus{Playing: true, Track: sonos.TrackInfo{Artist: "Band", Title: "Song", Position: 12 * time.Second}}}
+	s.Focus(state)
+	<-provider.fetched
+	waitFocus(t, s, state, focusPriority)
+
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := s.Draw(frame, state); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	// "Two" is in the middle rows in the text color, and the lines around
+	// it are dimmed.
+	rowOf := func(col [4]uint8) (first, last int) {
+		first, last = -1, -1
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				c := frame.RGBAAt(x, y)
+				if [4]uint8{c.R, c.G, c.B, c.A} == col {
+					if first < 0 {
+						first = y
+					}
+					last = y
+				}
+			}
+		}
+		return first, last
+	}
+	first, last := rowOf([4]uint8{palette.Text.R, palette.Text.G, palette.Text.B, palette.Text.A})
+	if first < 24 || last > 40 {
+		t.Fatalf("line being sung drawn in rows %d..%d, want the middle of the panel", first, last)
+	}
+	dim := render.ScaleColor(palette.Text, otherLines)
+	if first, _ := rowOf([4]uint8{dim.R, dim.G, dim.B, dim.A}); first < 0 || first >= 24 {
+		t.Fatalf("dimmed lines start at row %d, want above the line being sung", first)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	rows, starts := layout(text.Medium, []Line{{Text: "a short line"}, {}, {Text: "Supercalifragilistic"}}, 40)
+	if len(starts) != 3 || starts[2] != starts[1]+1 || rows[starts[1]] != "" {
+		t.Fatalf("layout = %q, starts %v", rows, starts)
+	}
+	for _, row := range rows {
+		if text.Medium.Measure(row) > 40 {
+			t.Fatalf("row %q is wider than 40 pixels", row)
+		}
+	}
+	if len(rows)-starts[2] < 2 {
+		t.Fatalf("long word not split: %q", rows[starts[2]:])
+	}
+}
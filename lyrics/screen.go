@@ -0,0 +1,129 @@
+package lyrics
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+const (
+	// lineScroll is how long synced lyrics take to scroll to the next line.
+	lineScroll = 300 * time.Millisecond
+	// plainSpeed is how fast plain lyrics scroll when the track's length is
+	// unknown, in pixels per second at scale 1.
+	plainSpeed = 4
+	// otherLines is how bright lines other than the one being sung are, in
+	// percent.
+	otherLines = 40
+)
+
+// Draw implements render.Screen. Synced lyrics keep the line being sung in
+// the middle of the panel, in the text color, between the dimmed lines
+// around it, easing up as each new line starts. Plain lyrics are centered
+// when they fit and otherwise scroll up over the length of the track. Long
+// lines wrap onto rows of their own.
+func (s *Screen) Draw(frame *image.RGBA, state render.FrameState) error {
+	s.mu.Lock()
+	lyrics := s.showing
+	position := s.estimateLocked(state.Status, state.Now)
+	s.mu.Unlock()
+	if lyrics == nil {
+		return nil
+	}
+
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	font := text.Medium
+	rowHeight := (font.Height() + 2) * scale
+	rows, starts := layout(font, lyrics.Lines, bounds.Dx()/scale-2)
+	content := len(rows) * rowHeight
+	bright, dim := state.Palette.Text, render.ScaleColor(state.Palette.Text, otherLines)
+
+	if !lyrics.Synced {
+		top := bounds.Min.Y + (bounds.Dy()-content)/2
+		if content > bounds.Dy() {
+			// The lyrics run from the top of the panel at the start of the
+			// track to the bottom at its end.
+			travel := content - bounds.Dy() + 2*scale
+			var offset int
+			if duration := state.Status.Track.Duration; duration > 0 {
+				offset = int(float64(travel) * min(float64(position)/float64(duration), 1))
+			} else {
+				offset = int(position.Seconds()*float64(plainSpeed*scale)) % (travel + bounds.Dy())
+			}
+			top = bounds.Min.Y + scale - offset
+		}
+		for i, row := range rows {
+			drawRow(frame, font, top+i*rowHeight, rowHeight, row, bright, scale)
+		}
+		return nil
+	}
+
+	// current is the line being sung, or -1 before the first.
+	current := -1
+	for i, line := range lyrics.Lines {
+		if line.At > position {
+			break
+		}
+		current = i
+	}
+	// The middle of the line being sung, measured from the top of the
+	// lyrics, goes in the middle of the panel, easing over from the line
+	// before it.
+	middleOf := func(i int) float64 {
+		i = max(i, 0)
+		end := len(rows)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		return float64(starts[i]*rowHeight) + float64((end-starts[i])*rowHeight)/2
+	}
+	middle := middleOf(current)
+	if current > 0 {
+		eased := min(float64(position-lyrics.Lines[current].At)/float64(lineScroll), 1)
+		middle = middleOf(current-1) + (middle-middleOf(current-1))*eased
+	}
+	top := bounds.Min.Y + bounds.Dy()/2 - int(middle)
+	for line := range lyrics.Lines {
+		end := len(rows)
+		if line+1 < len(starts) {
+			end = starts[line+1]
+		}
+		col := dim
+		if line == current {
+			col = bright
+		}
+		for row := starts[line]; row < end; row++ {
+			drawRow(frame, font, top+row*rowHeight, rowHeight, rows[row], col, scale)
+		}
+	}
+	return nil
+}
+
+// drawRow draws s centered across frame with its row's top at y, skipping
+// rows entirely off the panel.
+func drawRow(frame *image.RGBA, font *text.Font, y, rowHeight int, s string, col color.RGBA, scale int) {
+	bounds := frame.Bounds()
+	if s == "" || y+rowHeight <= bounds.Min.Y || y >= bounds.Max.Y {
+		return
+	}
+	width := font.Measure(s) * scale
+	font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, y+scale, s, col, scale)
+}
+
+// layout wraps lines into rows no wider than width pixels at scale 1. The
+// rows of lines[i] start at rows[starts[i]]; blank lines take one empty row.
+func layout(font *text.Font, lines []Line, width int) (rows []string, starts []int) {
+	for _, line := range lines {
+		starts = append(starts, len(rows))
+		wrapped := font.Wrap(line.Text, width)
+		if len(wrapped) == 0 {
+			wrapped = []string{""}
+		}
+		rows = append(rows, wrapped...)
+	}
+	return rows, starts
+}
@@ -2,22 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
-	"log"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	"golang.org/x/image/draw"
-
+	"musicDisplay/ambient"
+	"musicDisplay/clock"
+	"musicDisplay/devicecache"
+	"musicDisplay/diagnostics"
+	"musicDisplay/dryrundisplay"
+	"musicDisplay/httpapi"
+	"musicDisplay/logging"
+	"musicDisplay/lyrics"
 	"musicDisplay/matrixdisplay"
+	"musicDisplay/mpris"
+	"musicDisplay/mqttbridge"
+	"musicDisplay/notify"
+	"musicDisplay/powersave"
+	"musicDisplay/render"
+	"musicDisplay/simdisplay"
 	"musicDisplay/sonos"
+	"musicDisplay/spotify"
+	"musicDisplay/theme"
+	"musicDisplay/visualizer"
 )
 
 const (
@@ -26,152 +42,654 @@ const (
 	enrichmentMinimumTotal = 30 * time.Second
 	defaultConfigPath      = "config.json"
 	defaultCallbackPath    = "/sonos/events"
+	defaultIdleTimeout     = 2 * time.Minute
 )
 
-var debugMode bool
+var (
+	debugMode bool
+	// discovery controls every device search; main fills it from the config.
+	discovery = discoverySettings{method: sonos.DiscoverSSDP}
+	logger    = logging.For("main")
+)
 
-func infof(format string, args ...interface{}) {
-	if debugMode {
-		log.Printf("info: "+format, args...)
-	}
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...interface{}) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
 
 func main() {
-	debugFlag := flag.Bool("debug", false, "enable debug logging")
-	displayFlag := flag.Bool("display", false, "enable RGB LED matrix output")
-	displayTestFlag := flag.String("display-test", "", "path to an image to display on the matrix and exit")
-	writeOverlayFlag := flag.Bool("write-overlay", false, "overlay text on an image and write it back to disk; provide text and image path arguments")
-	flag.Parse()
+	if err := runCLI(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fatal(err.Error())
+	}
+}
+
+// runApp implements the run subcommand, the display itself: it finds the
+// configured room, follows it, and draws what it plays.
+func runApp(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay [run] [flags]")
+		fs.PrintDefaults()
+	}
+	debugFlag := fs.Bool("debug", false, "enable debug logging")
+	var displayFlag displayMode
+	fs.Var(&displayFlag, "display", "enable display output: bare -display for the RGB LED matrix, -display=simulator for a browser preview, -display=terminal for ANSI output, -display=dry-run to log frames, or -display=remote to push frames to a -sink")
+	simulatorAddrFlag := fs.String("simulator-addr", simdisplay.DefaultAddr, "listen address for -display=simulator")
+	displayTestFlag := fs.String("display-test", "", "path to an image to display on the matrix and exit (same as the display-test command)")
+	dryRunFlag := fs.Bool("dry-run", false, "log each frame and save it as a PNG instead of driving a display (same as -display=dry-run)")
+	dryRunDirFlag := fs.String("dry-run-dir", "", "directory for -dry-run frames (default: a new temporary directory)")
+	remoteSinkFlag := fs.String("remote-sink", "", "host:port of the frame sink -display=remote pushes frames to, or several separated by commas")
+	gamutPreviewFlag := fs.Bool("gamut-preview", false, "make the simulator, terminal, and dry-run displays show colors as the matrix would at its pwm_bits depth")
+	sinkFlag := fs.String("sink", "", "run as a frame sink on this address: show frames pushed by another instance with -display=remote instead of following Sonos")
+	callbackPortFlag := fs.Int("callback-port", 0, "fixed port for the Sonos event callback server (default: any free port)")
+	callbackBindFlag := fs.String("callback-bind", "", "IP address the event callback server listens on (default: the interface that reaches the speaker)")
+	callbackAdvertiseFlag := fs.String("callback-advertise", "", "host or host:port speakers should send events to, for NAT or containers")
+	discoveryFlag := fs.String("discovery", "", "how to find speakers: ssdp, mdns, or both (default ssdp)")
+	profileFlag := fs.String("profile", "", "apply the named profile from config.json")
+	daemonFlag := fs.Bool("daemon", false, "run as a systemd service: notify readiness, serve a health endpoint, and restart the listener instead of exiting (same as the daemon command)")
+	writeOverlayFlag := fs.Bool("write-overlay", false, "overlay text on an image and write it back to disk; provide text and image path arguments")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
 
 	debugMode = *debugFlag
-	sonos.SetDebugLogging(debugMode)
 
 	if *writeOverlayFlag {
-		if flag.NArg() < 2 {
-			log.Fatalf("-write-overlay requires text and an image path argument")
+		if fs.NArg() < 2 {
+			return errors.New("-write-overlay requires text and an image path argument")
 		}
-		text := flag.Arg(0)
-		imagePath := flag.Arg(1)
-		outputPath, err := generateOverlayImage(text, imagePath)
+		text := fs.Arg(0)
+		imagePath := fs.Arg(1)
+		outputPath, err := generateOverlayImage(text, imagePath, strings.TrimSpace(*profileFlag))
 		if err != nil {
-			log.Fatalf("write overlay: %v", err)
+			return err
 		}
-		fmt.Printf("Overlay image written to %s\n", outputPath)
-		return
+		fmt.Fprintf(stdout, "Overlay image written to %s\n", outputPath)
+		return nil
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	cfg, err := loadConfig(defaultConfigPath)
+	profile := strings.TrimSpace(*profileFlag)
+	cfg, err := loadConfig(defaultConfigPath, profile)
 	if err != nil {
-		log.Printf("warning: %v", err)
+		if profile != "" {
+			return err
+		}
+		logger.Warn(err.Error())
+	}
+	if err := logging.Setup(stderr, loggingOptions(cfg.Logging, debugMode)); err != nil {
+		logger.Warn("logging config ignored", "err", err)
+	}
+	if profile != "" {
+		logger.Debug("using config profile", "profile", profile)
+	}
+	if cfg.Display != "" && !flagWasSet(fs, "display") {
+		// Validated by loadConfig.
+		_ = displayFlag.Set(cfg.Display)
+	}
+	if *dryRunFlag {
+		displayFlag = displayDryRun
+	}
+	method := cfg.Discovery
+	if flagWasSet(fs, "discovery") {
+		method = *discoveryFlag
+	}
+	if err := configureSpeakers(cfg, method); err != nil {
+		return fmt.Errorf("invalid discovery method: %w", err)
+	}
+	callback := callbackSettings(fs, cfg.Callback, *callbackPortFlag, *callbackBindFlag, *callbackAdvertiseFlag)
+	if err := callback.validate(); err != nil {
+		return fmt.Errorf("invalid callback settings: %w", err)
 	}
 
 	targetRoom := strings.TrimSpace(cfg.Room)
 	if targetRoom != "" {
-		infof("filtering to room %q", targetRoom)
+		logger.Debug("filtering to room", "room", targetRoom)
 	}
 
 	var brightness int
 	if cfg.Brightness != nil {
 		brightness = *cfg.Brightness
-		infof("matrix brightness override set to %d", brightness)
+		logger.Debug("matrix brightness override", "brightness", brightness)
+	}
+
+	if addr := strings.TrimSpace(*sinkFlag); addr != "" {
+		if displayFlag == displayNone {
+			displayFlag = displayMatrix
+		}
+		if err := runSink(ctx, stdout, addr, displayFlag, cfg.Matrix.hardware(), brightness, *simulatorAddrFlag, *dryRunDirFlag); err != nil {
+			return fmt.Errorf("frame sink: %w", err)
+		}
+		return nil
+	}
+	remoteSink := cfg.RemoteSink
+	if flagWasSet(fs, "remote-sink") {
+		remoteSink = *remoteSinkFlag
 	}
 
-	idleTimeout := 2 * time.Minute
+	idleTimeout := idleTimeoutFor(cfg)
 	if cfg.IdleTimeoutSeconds != nil {
-		idleTimeout = time.Duration(*cfg.IdleTimeoutSeconds) * time.Second
-		infof("idle timeout override set to %s", idleTimeout)
+		logger.Debug("idle timeout override", "timeout", idleTimeout)
 	}
 
-	discoveryCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
-	devices, err := sonos.Discover(discoveryCtx, discoveryTimeout, targetRoom)
-	cancel()
+	themeSchedule, err := buildThemeSchedule(cfg)
 	if err != nil {
-		log.Fatalf("failed to discover Sonos devices: %v", err)
+		logger.Warn("themes disabled", "err", err)
 	}
-	if len(devices) == 0 {
-		fmt.Println("No Sonos-compatible responders found via SSDP.")
-		return
+	currentTheme := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	specialDays, err := buildSpecialDays(cfg)
+	if err != nil {
+		logger.Warn("special days disabled", "err", err)
 	}
-
-	enrichmentWindow := time.Duration(len(devices)) * enrichmentPerDevice
-	if enrichmentWindow < enrichmentMinimumTotal {
-		enrichmentWindow = enrichmentMinimumTotal
+	scenes, err := buildScenes(cfg)
+	if err != nil {
+		logger.Warn("scenes disabled", "err", err)
 	}
-	enrichmentCtx, cancel := context.WithTimeout(ctx, enrichmentWindow)
-	enriched, enrichmentErr := sonos.EnrichDevices(enrichmentCtx, devices)
-	cancel()
-	if len(enriched) > 0 {
-		devices = enriched
+	brightnessSchedule, err := buildBrightnessSchedule(cfg)
+	if err != nil {
+		logger.Warn("brightness schedule disabled", "err", err)
 	}
-	if enrichmentErr != nil {
-		log.Printf("warning: failed to enrich all devices: %v", enrichmentErr)
+
+	// wait keeps the program up, showing the idle screen, when the room's
+	// speaker is not found, so it recovers once the network is back.
+	wait := targetRoom != "" && cfg.Startup.waits()
+	devices, err := discoverDevices(ctx, targetRoom)
+	if err != nil {
+		if !wait {
+			return fmt.Errorf("discover Sonos devices: %w", err)
+		}
+		logger.Warn("failed to discover Sonos devices", "err", err)
+	}
+	if len(devices) == 0 && !wait {
+		fmt.Fprintf(stdout, "No Sonos-compatible responders found via %s.\n", discovery.method)
+		return nil
 	}
 
 	statuses, targetDevice := sonos.GatherRoomStatuses(ctx, devices, targetRoom)
-	if len(statuses) == 0 {
-		fmt.Println("No Sonos devices found after filtering.")
-		return
+	if len(statuses) == 0 && !wait {
+		fmt.Fprintln(stdout, "No Sonos devices found after filtering.")
+		return nil
 	}
 
 	if debugMode {
-		sonos.PrintRoomStatuses(statuses)
+		_ = sonos.WriteRoomStatuses(stdout, statuses)
 	}
 
 	if targetRoom == "" {
-		return
+		return nil
 	}
 
-	if targetDevice == nil {
-		log.Printf("warning: no device matched room %q for subscription", targetRoom)
-		return
+	if targetDevice == nil && !wait {
+		logger.Warn("no device matched room for subscription", "room", targetRoom)
+		return nil
 	}
 
-	var display *matrixdisplay.Controller
-	needDisplay := *displayFlag || strings.TrimSpace(*displayTestFlag) != ""
-	if needDisplay {
-		ctrl, err := matrixdisplay.NewController(brightness)
+	// hardware describes the panels, and geometry the frame every stage
+	// composes for them.
+	hardware := cfg.Matrix.hardware()
+	geometry := hardware.Geometry()
+	var display outputDisplay
+	// chain is the front of the display chain. Closing it closes every
+	// wrapper and then the backend, so it is the only thing closed here.
+	var chain sonos.Display
+	if displayFlag == displayNone && strings.TrimSpace(*displayTestFlag) != "" {
+		displayFlag = displayMatrix
+	}
+	if displayFlag != displayNone {
+		out, err := openDisplay(stdout, displayFlag, hardware, brightness, *simulatorAddrFlag, *dryRunDirFlag, remoteSink)
 		if err != nil {
-			log.Printf("warning: init matrix display: %v", err)
+			logger.Warn("init display failed", "display", string(displayFlag), "err", err)
 		} else {
-			display = ctrl
-			infof("matrix display initialized")
+			display = out
+			chain = out
+			logger.Debug("display initialized", "display", string(displayFlag))
+			if *gamutPreviewFlag || cfg.GamutPreview {
+				previewGamut(out, hardware)
+			}
 			defer func() {
-				if err := display.Close(); err != nil {
-					log.Printf("warning: close display: %v", err)
+				if err := chain.Close(); err != nil {
+					logger.Warn("close display", "err", err)
 				}
 			}()
 		}
 	} else {
-		infof("matrix display disabled")
+		logger.Debug("display disabled")
+	}
+
+	if themeSchedule != nil {
+		var setter brightnessSetter
+		if display != nil {
+			setter = display
+		}
+		var sceneRules sceneRuleSetter
+		if scenes != nil {
+			sceneRules = scenes
+		}
+		go runThemeScheduler(ctx, clock.Real, themeSchedule, currentTheme, setter, sceneRules)
+	}
+	if brightnessSchedule != nil && display != nil {
+		go runBrightnessScheduler(ctx, clock.Real, brightnessSchedule, display)
 	}
 
 	if display == nil && strings.TrimSpace(*displayTestFlag) != "" {
-		log.Printf("warning: display test requested but matrix initialization failed")
+		logger.Warn("display test requested but display initialization failed")
 	}
 
 	if display != nil && strings.TrimSpace(*displayTestFlag) != "" {
-		if err := showTestImage(ctx, display, strings.TrimSpace(*displayTestFlag)); err != nil {
-			log.Fatalf("display test failed: %v", err)
+		if err := showTestImage(ctx, stdout, display, strings.TrimSpace(*displayTestFlag)); err != nil {
+			return fmt.Errorf("display test: %w", err)
 		}
-		return
+		return nil
 	}
 
-	fmt.Println("Listening for updates. Press Ctrl+C to exit.")
+	fmt.Fprintln(stdout, "Listening for updates. Press Ctrl+C to exit.")
 	opts := sonos.ListenerOptions{
-		Debug:       debugMode,
-		Display:     display,
-		IdleTimeout: idleTimeout,
+		IdleTimeout:   idleTimeout,
+		StateTimeouts: buildStateTimeouts(cfg, idleTimeout),
+		// One cache for the session, kept across room switches.
+		Tracks: sonos.NewTrackCache(0),
+	}
+	if debugMode {
+		opts.OnChange = func(change sonos.RoomChange) {
+			fmt.Fprintf(stdout, "[%s] %s – %s | %s\n", change.Time.Format("15:04:05"), change.Room, change.State, change.Track)
+		}
+	}
+	callback.apply(&opts)
+	opts.ArtStorage = cfg.ArtCache.storage()
+	opts.ArtStorage.Size = geometry.ArtSize()
+	opts.ArtStorage.Processing = cfg.ArtProcessing.processing()
+	opts.ArtStorage.Fetch = cfg.ArtFetch.fetch()
+	go pruneArtCache(ctx, opts.ArtStorage, cfg.ArtCache.limits())
+	if cfg.Placeholder != nil {
+		placeholder, err := cfg.Placeholder.art(geometry.ArtSize(), opts.ArtStorage.Processing)
+		if err != nil {
+			return fmt.Errorf("placeholder art: %w", err)
+		}
+		opts.Placeholder = placeholder
+	}
+	if len(cfg.SilenceMinutes) > 0 {
+		opts.SilenceTimeouts = make(map[sonos.SourceKind]time.Duration, len(cfg.SilenceMinutes))
+		for source, minutes := range cfg.SilenceMinutes {
+			kind, err := sonos.ParseSourceKind(source)
+			if err != nil {
+				continue
+			}
+			opts.SilenceTimeouts[kind] = time.Duration(minutes) * time.Minute
+		}
+	}
+	if cfg.ITunesLookup {
+		opts.ITunes = sonos.NewITunesLookup(cfg.ITunesCountry)
+		logger.Debug("itunes metadata lookup enabled for AirPlay sessions")
+	}
+	var reloadDisplay brightnessSetter
+	if display != nil {
+		reloadDisplay = display
+	}
+	reloader := newConfigReloader(cfg, defaultConfigPath, profile, reloadDisplay)
+	opts.TimeoutUpdates = reloader.timeouts
+	go watchConfig(ctx, defaultConfigPath, profile, configPollInterval, reloader.apply)
+	remote := newRemoteControl(reloader, display, opts.ArtStorage, brightness)
+	var (
+		sink          statusDisplay
+		spotifyClient *spotify.Client
+		fallback      *spotifyFallback
+		scripts       loadedScripts
+	)
+
+	if display != nil {
+		renderOpts := render.Options{ShowProgress: cfg.ProgressBar, ArtPalette: cfg.ArtPalette, Size: displaySize(display), FPS: cfg.FrameRate}
+		if cfg.PowerSave != nil {
+			renderOpts.AdaptiveFPS = cfg.PowerSave.AdaptiveFPS == nil || *cfg.PowerSave.AdaptiveFPS
+		}
+		if cfg.DimLevel != nil {
+			renderOpts.DimLevel = *cfg.DimLevel
+		}
+		if cfg.Ticker != nil {
+			renderOpts.Ticker = render.TickerOptions{
+				Enabled:  true,
+				Rows:     cfg.Ticker.Rows,
+				Position: cfg.Ticker.Position,
+				FPS:      cfg.Ticker.Speed,
+				UpNext:   cfg.Ticker.UpNext,
+			}
+		}
+		if cfg.SourceBadge != nil {
+			renderOpts.SourceBadge = render.SourceBadgeOptions{
+				Enabled: true,
+				Corner:  cfg.SourceBadge.Corner,
+				Size:    cfg.SourceBadge.Size,
+			}
+		}
+		if cfg.PlayMode != nil {
+			renderOpts.PlayMode = render.PlayModeOptions{
+				Enabled: true,
+				Corner:  cfg.PlayMode.Corner,
+				Size:    cfg.PlayMode.Size,
+			}
+		}
+		if cfg.Transition != nil {
+			renderOpts.Transition = render.TransitionOptions{
+				Style:    cfg.Transition.Style,
+				Duration: time.Duration(cfg.Transition.DurationMS) * time.Millisecond,
+				FPS:      cfg.Transition.FPS,
+			}
+		}
+		if cfg.BurnIn != nil {
+			renderOpts.BurnIn = cfg.BurnIn.options()
+		}
+		if cfg.Rotation != nil {
+			renderOpts.Rotation = cfg.Rotation.options()
+		}
+		renderOpts.Idle = render.IdleOptions{Screen: cfg.IdleScreen}
+		if cfg.Clock != nil {
+			renderOpts.Idle.Clock.TwelveHour = cfg.Clock.Format == "12h"
+			if cfg.Clock.Brightness != nil {
+				renderOpts.Idle.Clock.Brightness = *cfg.Clock.Brightness
+			}
+		}
+		if strings.TrimSpace(cfg.IdleAnimation) != "" {
+			frames, err := loadAnimation(cfg.IdleAnimation, matrixdisplay.Geometry{Width: renderOpts.Size.X, Height: renderOpts.Size.Y})
+			if err != nil {
+				logger.Warn("idle animation disabled", "err", err)
+			} else {
+				renderOpts.Idle.Animation = frames
+			}
+		}
+		scripts = loadScripts(cfg)
+		defer scripts.close()
+		for _, s := range scripts.overlays {
+			renderOpts.Layers = append(renderOpts.Layers, s)
+		}
+		if scripts.idle != nil {
+			renderOpts.Idle.Script = scripts.idle
+		}
+		if source, screen := buildWeather(cfg); source != nil {
+			go source.Run(ctx, clock.Real)
+			renderOpts.Screens = append(renderOpts.Screens, screen)
+		}
+		if source, screen := buildCalendar(cfg); source != nil {
+			go source.Run(ctx, clock.Real)
+			renderOpts.Screens = append(renderOpts.Screens, screen)
+		}
+		countdown := buildTimer(cfg, remote)
+		renderOpts.Screens = append(renderOpts.Screens, countdown)
+		remote.attachTimer(countdown)
+		diag := diagnostics.New(clock.Real, 0)
+		renderOpts.Screens = append(renderOpts.Screens, diag)
+		remote.attachDiagnostics(diag)
+		if cfg.Lyrics != nil {
+			screen := lyrics.New(clock.Real, lyrics.NewLRCLib(cfg.Lyrics.URL), cfg.Lyrics.Enabled == nil || *cfg.Lyrics.Enabled)
+			renderOpts.Screens = append(renderOpts.Screens, screen)
+			remote.attachLyrics(screen)
+		}
+		if cfg.Visualizer != nil {
+			screen := visualizer.New(clock.Real, cfg.Visualizer.options(), cfg.Visualizer.Enabled == nil || *cfg.Visualizer.Enabled)
+			go screen.Run(ctx)
+			renderOpts.Screens = append(renderOpts.Screens, screen)
+			remote.attachVisualizer(screen)
+		}
+		notifier := notify.New(clock.Real, renderOpts.Size)
+		renderOpts.Overlays = append(renderOpts.Overlays, notifier)
+		var wifi *diagnostics.WiFiMonitor
+		if cfg.WiFi != nil {
+			wifi = diagnostics.NewWiFiMonitor(clock.Real, cfg.WiFi.options())
+			renderOpts.Overlays = append(renderOpts.Overlays, wifi)
+			remote.attachWiFi(wifi)
+		}
+		if dry, ok := display.(*dryrundisplay.Display); ok {
+			dry.SetLayout(describeLayout(renderOpts))
+		}
+		renderer := render.New(display, currentTheme, renderOpts)
+		renderer.SetLargeText(cfg.LargeText)
+		remote.attachLargeText(renderer)
+		remote.attachNotifier(notifier, renderer.Wake)
+		if wifi != nil {
+			go wifi.Run(ctx, renderer.Wake)
+		}
+		go renderer.Run(ctx)
+		go runSpecialDays(ctx, clock.Real, specialDays, renderer)
+		if scenes != nil {
+			go runScenes(ctx, clock.Real, scenes, renderer)
+		}
+		sink = renderer
 	}
-	if err := sonos.ListenForEvents(ctx, *targetDevice, targetRoom, defaultCallbackPath, opts); err != nil {
-		log.Printf("warning: %v", err)
+	if cfg.Export != nil {
+		export, err := newNowPlayingExport(cfg.Export.Dir, sink)
+		if err != nil {
+			logger.Warn("now-playing export disabled", "err", err)
+		} else {
+			sink = export
+			logger.Debug("exporting now playing", "dir", cfg.Export.Dir)
+		}
+	}
+	if cfg.PowerSave != nil && cfg.PowerSave.Governors != nil {
+		governor := powersave.NewGovernor("", cfg.PowerSave.Governors.Idle, cfg.PowerSave.Governors.Active)
+		defer governor.Restore()
+		sink = &governorTap{governor: governor, out: sink}
+	}
+	if cfg.Ambient != nil {
+		syncer := ambient.NewSyncer(cfg.Ambient.lights()...)
+		go syncer.Run(ctx)
+		sink = &ambientTap{syncer: syncer, out: sink}
+	}
+	if cfg.MQTT != nil {
+		bridge, err := mqttbridge.Connect(mqttOptions(cfg.MQTT), remote)
+		if err != nil {
+			logger.Warn("mqtt disabled", "err", err)
+		} else {
+			defer bridge.Close()
+			if brightness > 0 {
+				bridge.PublishBrightness(brightness)
+			}
+			sink = &mqttTap{bridge: bridge, out: sink}
+			logger.Debug("mqtt bridge connected", "broker", cfg.MQTT.Broker)
+		}
+	}
+	if sink != nil {
+		opts.Display = sink
+		opts.OnStatus = sink.UpdateStatus
+	}
+	if display != nil && cfg.Spotify != nil {
+		spotifyClient = spotify.NewClient(spotify.Credentials{
+			ClientID:     cfg.Spotify.ClientID,
+			ClientSecret: cfg.Spotify.ClientSecret,
+			RefreshToken: cfg.Spotify.RefreshToken,
+		})
+		fallback = newSpotifyFallback(spotifyClient, sink, targetRoom, time.Duration(cfg.Spotify.PollSeconds)*time.Second, geometry.ArtSize(), opts.ArtStorage.Processing)
+		go fallback.Run(ctx)
+		opts.Display = fallback
+		opts.OnStatus = fallback.UpdateStatus
+		logger.Debug("spotify fallback enabled")
+	}
+
+	var device sonos.Device
+	if targetDevice != nil {
+		device = *targetDevice
+	}
+	controls := newTrackControls(device, spotifyClient, fallback)
+	opts.Rediscover = func(ctx context.Context, room string) (sonos.Device, error) {
+		device, err := locateRoom(ctx, room)
+		if err != nil {
+			return sonos.Device{}, err
+		}
+		return *device, nil
+	}
+	opts.OnDevice = controls.setDevice
+	opts.OnHealth = remote.updateHealth
+	opts.OnRoomRenamed = func(from, to string) {
+		reloader.renameRoom(from, to)
+		if fallback != nil {
+			fallback.setRoom(to)
+		}
+	}
+	onStatus := opts.OnStatus
+	opts.OnStatus = func(status sonos.PlaybackStatus) {
+		controls.observe(status)
+		if onStatus != nil {
+			onStatus(status)
+		}
+	}
+	if sim, ok := display.(*simdisplay.Display); ok {
+		sim.SetControls(simulatorControls{trackControls: controls, remote: remote})
+	}
+	if dry, ok := display.(*dryrundisplay.Display); ok {
+		observe := opts.OnStatus
+		opts.OnStatus = func(status sonos.PlaybackStatus) {
+			dry.UpdateStatus(status)
+			observe(status)
+		}
+	}
+	for _, s := range scripts.all() {
+		observe := opts.OnStatus
+		opts.OnStatus = func(status sonos.PlaybackStatus) {
+			observe(status)
+			s.UpdateStatus(status)
+		}
+	}
+	if scenes != nil && display != nil {
+		observe := opts.OnStatus
+		opts.OnStatus = func(status sonos.PlaybackStatus) {
+			observe(status)
+			scenes.UpdateStatus(status)
+		}
+	}
+	if cfg.MPRIS {
+		player, err := mpris.New("walldisplay", "WallDisplay ("+targetRoom+")", controls)
+		if err != nil {
+			logger.Warn("mpris disabled", "err", err)
+		} else {
+			defer player.Close()
+			observe := opts.OnStatus
+			opts.OnStatus = func(status sonos.PlaybackStatus) {
+				observe(status)
+				player.Update(status)
+			}
+			logger.Debug("mpris player registered on the session bus")
+		}
+	}
+	if display != nil {
+		chain = opts.Display
+	}
+	remote.attach(opts.Display, controls)
+	if cfg.API != nil {
+		server, err := httpapi.New(cfg.API.Addr, remote)
+		if err != nil {
+			logger.Warn("control api disabled", "err", err)
+		} else {
+			defer server.Close()
+			logger.Debug("control api listening", "addr", server.Addr())
+		}
+	}
+	onRoom := func(room string, device sonos.Device) {
+		if fallback != nil {
+			fallback.setRoom(room)
+		}
+		controls.setDevice(device)
+	}
+	if targetDevice == nil {
+		if opts.Display != nil {
+			if err := opts.Display.Clear(); err != nil {
+				logger.Warn("show idle screen", "err", err)
+			}
+		}
+		found, room, ok := waitForRoom(ctx, clock.Real, targetRoom, cfg.Startup.retryInterval(), reloader.rooms, *daemonFlag)
+		if !ok {
+			return nil
+		}
+		device, targetRoom = found, room
+		onRoom(room, device)
+	}
+	if *daemonFlag {
+		runDaemon(ctx, cfg.Daemon, device, targetRoom, opts, reloader.rooms, onRoom)
+		return nil
 	}
+	if err := listenRooms(ctx, device, targetRoom, opts, reloader.rooms, onRoom); err != nil {
+		logger.Warn(err.Error())
+	}
+	return nil
+}
+
+// loggingOptions builds the logger settings. -debug lowers the default level
+// to debug; per-package levels from the config still apply.
+func loggingOptions(cfg *LoggingConfig, debug bool) logging.Options {
+	var opts logging.Options
+	if cfg != nil {
+		opts = cfg.options()
+	}
+	if debug {
+		opts.Level = "debug"
+	}
+	return opts
+}
+
+// flagWasSet reports whether the named flag was given on fs's command line.
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// discoverDevices finds Sonos devices with the configured discovery method, or
+// takes them from the config's device list, and fills in their room names.
+// Enrichment failures are logged; the devices found so far are still
+// returned.
+func discoverDevices(ctx context.Context, targetRoom string) ([]sonos.Device, error) {
+	if len(discovery.static) > 0 {
+		return configuredDevices(ctx, discovery.static), nil
+	}
+	if targetRoom != "" && discovery.cachePath != "" {
+		if device, ok := cachedDevice(ctx, discovery.cachePath, targetRoom); ok {
+			return []sonos.Device{device}, nil
+		}
+	}
+	discoveryCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	devices, err := sonos.DiscoverWith(discoveryCtx, discovery.method, discoveryTimeout, targetRoom)
+	cancel()
+	if err != nil || len(devices) == 0 {
+		return devices, err
+	}
+
+	enrichmentWindow := time.Duration(len(devices)) * enrichmentPerDevice
+	if enrichmentWindow < enrichmentMinimumTotal {
+		enrichmentWindow = enrichmentMinimumTotal
+	}
+	enrichmentCtx, cancel := context.WithTimeout(ctx, enrichmentWindow)
+	enriched, enrichmentErr := sonos.EnrichDevices(enrichmentCtx, devices)
+	cancel()
+	if len(enriched) > 0 {
+		devices = enriched
+	}
+	if enrichmentErr != nil {
+		logger.Warn("failed to enrich all devices", "err", enrichmentErr)
+	}
+	if discovery.cachePath != "" {
+		if err := devicecache.Save(discovery.cachePath, devices, time.Now()); err != nil {
+			logger.Warn("save device cache", "err", err)
+		}
+	}
+	return devices, nil
 }
 
-func showTestImage(ctx context.Context, display *matrixdisplay.Controller, path string) error {
-	img, err := loadAndScaleImage(path)
+func showTestImage(ctx context.Context, stdout io.Writer, display outputDisplay, path string) error {
+	size := displaySize(display)
+	geometry := matrixdisplay.Geometry{Width: size.X, Height: size.Y}
+	if frames, err := loadAnimation(path, geometry); err == nil && len(frames) > 1 {
+		fmt.Fprintf(stdout, "Playing %q (%d frames). Press Ctrl+C to exit.\n", path, len(frames))
+		return matrixdisplay.PlayAnimation(ctx, display, frames)
+	}
+
+	img, err := loadAndScaleImage(path, geometry)
 	if err != nil {
 		return err
 	}
@@ -180,14 +698,29 @@ func showTestImage(ctx context.Context, display *matrixdisplay.Controller, path
 		return fmt.Errorf("matrixdisplay: show test image: %w", err)
 	}
 
-	fmt.Printf("Displayed %q on the matrix. Press Ctrl+C to exit.\n", path)
+	fmt.Fprintf(stdout, "Displayed %q. Press Ctrl+C to exit.\n", path)
 	select {
 	case <-ctx.Done():
 		return nil
 	}
 }
 
-func loadAndScaleImage(path string) (image.Image, error) {
+// loadAndScaleImage decodes the image at path and fits it to geometry.
+func loadAndScaleImage(path string, geometry matrixdisplay.Geometry) (image.Image, error) {
+	src, err := loadImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	geometry = geometry.OrDefault()
+	if src.Bounds().Size() == geometry.Bounds().Size() {
+		return src, nil
+	}
+	return matrixdisplay.FitFrame(src, geometry.Width, geometry.Height), nil
+}
+
+// loadImage decodes the image at path.
+func loadImage(path string) (image.Image, error) {
 	if strings.TrimSpace(path) == "" {
 		return nil, fmt.Errorf("matrixdisplay: image path is empty")
 	}
@@ -202,13 +735,5 @@ func loadAndScaleImage(path string) (image.Image, error) {
 	if err != nil {
 		return nil, fmt.Errorf("matrixdisplay: decode image %q: %w", path, err)
 	}
-
-	srcBounds := src.Bounds()
-	if srcBounds.Dx() == matrixdisplay.PanelWidth && srcBounds.Dy() == matrixdisplay.PanelHeight {
-		return src, nil
-	}
-
-	dst := image.NewRGBA(image.Rect(0, 0, matrixdisplay.PanelWidth, matrixdisplay.PanelHeight))
-	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcBounds, draw.Src, nil)
-	return dst, nil
+	return src, nil
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"image"
+
+	"musicDisplay/ambient"
+	"musicDisplay/sonos"
+)
+
+// ambientTap hands each artwork the display chain shows to the ambient
+// light syncer and forwards everything to out, which may be nil when no
+// display is attached. Clearing the display leaves the lights as they are.
+type ambientTap struct {
+	syncer *ambient.Syncer
+	out    statusDisplay
+}
+
+func (t *ambientTap) Show(img image.Image) error {
+	return t.ShowContext(context.Background(), img)
+}
+
+// ShowContext implements sonos.ContextDisplay.
+func (t *ambientTap) ShowContext(ctx context.Context, img image.Image) error {
+	t.syncer.Show(img)
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ShowContext(ctx, t.out, img)
+}
+
+func (t *ambientTap) Clear() error {
+	return t.ClearContext(context.Background())
+}
+
+// ClearContext implements sonos.ContextDisplay.
+func (t *ambientTap) ClearContext(ctx context.Context) error {
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ClearContext(ctx, t.out)
+}
+
+func (t *ambientTap) Close() error {
+	if t.out == nil {
+		return nil
+	}
+	return t.out.Close()
+}
+
+// SetDimmed forwards dimming to the display.
+func (t *ambientTap) SetDimmed(dimmed bool) error {
+	if dimmer, ok := t.out.(sonos.Dimmer); ok {
+		return dimmer.SetDimmed(dimmed)
+	}
+	return nil
+}
+
+func (t *ambientTap) UpdateStatus(status sonos.PlaybackStatus) {
+	if t.out != nil {
+		t.out.UpdateStatus(status)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"musicDisplay/matrixdisplay"
+)
+
+// loadAnimation decodes the GIF at path into frames fitted to geometry. A
+// still GIF yields one frame.
+func loadAnimation(path string, geometry matrixdisplay.Geometry) ([]matrixdisplay.Frame, error) {
+	file, err := os.Open(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("open animation: %w", err)
+	}
+	defer file.Close()
+	frames, err := matrixdisplay.DecodeGIF(file, geometry)
+	if err != nil {
+		return nil, fmt.Errorf("animation %q: %w", path, err)
+	}
+	return frames, nil
+}
+
+// validateIdleAnimation checks that idle_animation decodes, and that the
+// animation idle screen has one to play.
+func validateIdleAnimation(cfg Config) error {
+	if strings.TrimSpace(cfg.IdleAnimation) == "" {
+		if cfg.IdleScreen == "animation" {
+			return fmt.Errorf("idle_screen \"animation\" requires idle_animation")
+		}
+		return nil
+	}
+	_, err := loadAnimation(cfg.IdleAnimation, matrixdisplay.DefaultGeometry)
+	return err
+}
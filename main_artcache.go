@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"musicDisplay/sonos"
+)
+
+// artPruneInterval is how often the disk art cache is checked against its
+// limits after the startup prune.
+const artPruneInterval = time.Hour
+
+// defaultArtGrace is how long art the speaker has not got yet is fetched
+// again while its track plays; AirPlay art often takes several seconds.
+const defaultArtGrace = 30 * time.Second
+
+func (c *ArtCacheConfig) validate() error {
+	switch c.Mode {
+	case "", "disk":
+	case "memory":
+		if strings.TrimSpace(c.Dir) != "" {
+			return fmt.Errorf("dir does not apply to the memory mode")
+		}
+	default:
+		return fmt.Errorf("mode must be \"disk\" or \"memory\", got %q", c.Mode)
+	}
+	for name, value := range map[string]int{"max_size_mb": c.MaxSizeMB, "max_files": c.MaxFiles, "max_age_days": c.MaxAgeDays} {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", name, value)
+		}
+		if value > 0 && c.Mode == "memory" {
+			return fmt.Errorf("%s does not apply to the memory mode", name)
+		}
+	}
+	if c.MemoryEntries < 0 {
+		return fmt.Errorf("memory_entries must not be negative, got %d", c.MemoryEntries)
+	}
+	format, err := sonos.ParseArtFormat(c.Format)
+	if err != nil {
+		return err
+	}
+	if c.Quality != 0 && format != sonos.ArtFormatJPEG {
+		return fmt.Errorf("quality only applies to the jpeg format, not %q", format)
+	}
+	if c.Quality < 0 || c.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100, got %d", c.Quality)
+	}
+	return nil
+}
+
+// storage returns the listener's art storage settings; a nil config keeps
+// PNG files in the default directory.
+func (c *ArtCacheConfig) storage() sonos.ArtStorage {
+	if c == nil {
+		return sonos.ArtStorage{Format: sonos.ArtFormatPNG}
+	}
+	format, err := sonos.ParseArtFormat(c.Format)
+	if err != nil {
+		format = sonos.ArtFormatPNG
+	}
+	return sonos.ArtStorage{
+		Format:        format,
+		Quality:       c.Quality,
+		Dir:           strings.TrimSpace(c.Dir),
+		MemoryOnly:    c.Mode == "memory",
+		MemoryEntries: c.MemoryEntries,
+	}
+}
+
+// limits returns the disk cache bounds; a nil config sets none.
+func (c *ArtCacheConfig) limits() sonos.ArtLimits {
+	if c == nil {
+		return sonos.ArtLimits{}
+	}
+	return sonos.ArtLimits{
+		MaxBytes: int64(c.MaxSizeMB) << 20,
+		MaxFiles: c.MaxFiles,
+		MaxAge:   time.Duration(c.MaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// pruneArtCache keeps the disk art cache within limits, once at startup and
+// then every artPruneInterval, until ctx is canceled.
+func pruneArtCache(ctx context.Context, storage sonos.ArtStorage, limits sonos.ArtLimits) {
+	if storage.MemoryOnly || limits.IsZero() {
+		return
+	}
+	ticker := time.NewTicker(artPruneInterval)
+	defer ticker.Stop()
+	for {
+		result, err := sonos.PruneArt(storage, limits, time.Now())
+		if err != nil {
+			logger.Warn("album art prune failed", "err", err)
+		} else if result.Files > 0 {
+			logger.Debug("album art pruned", "files", result.Files, "bytes", result.Bytes)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processing returns the art processing settings; a nil config keeps the
+// defaults.
+func (c *ArtProcessingConfig) processing() sonos.ArtProcessing {
+	if c == nil {
+		return sonos.ArtProcessing{}
+	}
+	return sonos.ArtProcessing{
+		Scaler:     sonos.ArtScaler(strings.ToLower(strings.TrimSpace(c.Scaler))),
+		Gamma:      c.Gamma,
+		Dither:     sonos.DitherMode(strings.ToLower(strings.TrimSpace(c.Dither))),
+		DitherBits: c.DitherBits,
+	}
+}
+
+func (c *ArtFetchConfig) validate() error {
+	if c.MaxKB < 0 {
+		return fmt.Errorf("max_kb must not be negative, got %d", c.MaxKB)
+	}
+	if c.MaxDimension < 0 {
+		return fmt.Errorf("max_dimension must not be negative, got %d", c.MaxDimension)
+	}
+	if c.Attempts < 0 || c.Attempts > 20 {
+		return fmt.Errorf("attempts must be between 0 and 20, got %d", c.Attempts)
+	}
+	if c.DelayMS < 0 || c.DelayMS > 10000 {
+		return fmt.Errorf("delay_ms must be between 0 and 10000, got %d", c.DelayMS)
+	}
+	if c.GraceSeconds != nil && (*c.GraceSeconds < 0 || *c.GraceSeconds > 600) {
+		return fmt.Errorf("grace_seconds must be between 0 and 600, got %d", *c.GraceSeconds)
+	}
+	return nil
+}
+
+// fetch returns the art download settings; a nil config keeps the defaults.
+func (c *ArtFetchConfig) fetch() sonos.ArtFetch {
+	if c == nil {
+		return sonos.ArtFetch{Grace: defaultArtGrace}
+	}
+	fetch := sonos.ArtFetch{
+		MaxBytes:     int64(c.MaxKB) << 10,
+		MaxDimension: c.MaxDimension,
+		Attempts:     c.Attempts,
+		Delay:        time.Duration(c.DelayMS) * time.Millisecond,
+		Grace:        defaultArtGrace,
+	}
+	if c.GraceSeconds != nil {
+		fetch.Grace = time.Duration(*c.GraceSeconds) * time.Second
+	}
+	return fetch
+}
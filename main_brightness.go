@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/schedule"
+)
+
+// buildBrightnessSchedule converts brightness_schedule into a daily schedule.
+// It returns nil when no schedule is configured.
+func buildBrightnessSchedule(cfg Config) (*schedule.Daily[int], error) {
+	if len(cfg.BrightnessSchedule) == 0 {
+		return nil, nil
+	}
+
+	starts := make([]string, 0, len(cfg.BrightnessSchedule))
+	for start := range cfg.BrightnessSchedule {
+		starts = append(starts, start)
+	}
+	sort.Strings(starts)
+
+	entries := make([]schedule.Entry[int], 0, len(starts))
+	for _, start := range starts {
+		level := cfg.BrightnessSchedule[start]
+		if level < 1 || level > 100 {
+			return nil, fmt.Errorf("brightness_schedule %q must be between 1 and 100, got %d", start, level)
+		}
+		at, err := schedule.ParseTimeOfDay(start)
+		if err != nil {
+			return nil, fmt.Errorf("brightness_schedule %q: %w", start, err)
+		}
+		entries = append(entries, schedule.Entry[int]{Start: at, Value: level})
+	}
+
+	var coords *schedule.Coordinates
+	if cfg.Latitude != nil && cfg.Longitude != nil {
+		coords = &schedule.Coordinates{Latitude: *cfg.Latitude, Longitude: *cfg.Longitude}
+	}
+
+	sched, err := schedule.NewDaily(entries, coords)
+	if err != nil {
+		return nil, err
+	}
+	return &sched, nil
+}
+
+// runBrightnessScheduler applies the scheduled brightness to display at
+// startup and at each change. It blocks until ctx is canceled.
+func runBrightnessScheduler(ctx context.Context, clk clock.Clock, sched *schedule.Daily[int], display brightnessSetter) {
+	if sched == nil || display == nil {
+		return
+	}
+
+	applied := 0
+	schedule.Run(ctx, clk, func(now time.Time) (time.Time, bool) {
+		if level, ok := sched.At(now); ok && level != applied {
+			if err := display.SetBrightness(level); err != nil {
+				logger.Warn("apply scheduled brightness", "err", err)
+			} else {
+				applied = level
+				logger.Debug("scheduled brightness applied", "brightness", level)
+			}
+		}
+		return sched.NextChange(now)
+	})
+}
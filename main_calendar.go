@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"musicDisplay/calendar"
+)
+
+// buildCalendar returns the calendar source and screen for the config, or
+// nils when no calendar section is set.
+func buildCalendar(cfg Config) (*calendar.Source, *calendar.Screen) {
+	if cfg.Calendar == nil {
+		return nil, nil
+	}
+	cc := cfg.Calendar
+	feed := calendar.NewFeed(strings.TrimSpace(cc.URL), cc.Username, cc.Password)
+	source := calendar.NewSource(feed, time.Duration(cc.RefreshMinutes)*time.Minute, time.Duration(cc.Days)*24*time.Hour)
+	screen := calendar.NewScreen(source)
+	screen.TwelveHour = cfg.Clock != nil && cfg.Clock.Format == "12h"
+	return source, screen
+}
+
+// validateCalendar checks the calendar section and that the calendar screen
+// is only chosen when it is configured.
+func validateCalendar(cfg Config) error {
+	if cfg.Calendar == nil {
+		if usesScreen(cfg, calendar.ScreenName) {
+			return fmt.Errorf("the calendar screen requires a calendar section")
+		}
+		return nil
+	}
+	cc := cfg.Calendar
+	raw := strings.TrimSpace(cc.URL)
+	if raw == "" {
+		return fmt.Errorf("calendar: url is required")
+	}
+	// The address is often the secret, so it is left out of the error.
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("calendar: url must be an http, https, or webcal address")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "webcal":
+	default:
+		return fmt.Errorf("calendar: url must be an http, https, or webcal address")
+	}
+	if cc.RefreshMinutes < 0 || (cc.RefreshMinutes > 0 && cc.RefreshMinutes < 5) {
+		return fmt.Errorf("calendar: refresh_minutes must be at least 5, got %d", cc.RefreshMinutes)
+	}
+	if cc.Days < 0 || cc.Days > 366 {
+		return fmt.Errorf("calendar: days must be between 1 and 366, got %d", cc.Days)
+	}
+	return nil
+}
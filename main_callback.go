@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"musicDisplay/sonos"
+)
+
+// callbackSettings merges the -callback-* flags over the config file's
+// callback section. Flags win only when they were given on fs's command line.
+func callbackSettings(fs *flag.FlagSet, cfg *CallbackConfig, port int, bind, advertise string) CallbackConfig {
+	var merged CallbackConfig
+	if cfg != nil {
+		merged = *cfg
+	}
+	if flagWasSet(fs, "callback-port") {
+		merged.Port = port
+	}
+	if flagWasSet(fs, "callback-bind") {
+		merged.Bind = bind
+	}
+	if flagWasSet(fs, "callback-advertise") {
+		merged.Advertise = advertise
+	}
+	return merged
+}
+
+func (c *CallbackConfig) validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 0 and 65535, got %d", c.Port)
+	}
+	bindHost, _, _ := strings.Cut(strings.Trim(strings.TrimSpace(c.Bind), "[]"), "%")
+	if bindHost != "" && net.ParseIP(bindHost) == nil {
+		return fmt.Errorf("bind must be an IP address, got %q", c.Bind)
+	}
+	advertise := strings.TrimSpace(c.Advertise)
+	if advertise == "" {
+		return nil
+	}
+	if host, port, err := net.SplitHostPort(advertise); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 || host == "" {
+			return fmt.Errorf("advertise must be a host or host:port, got %q", c.Advertise)
+		}
+		return nil
+	}
+	if strings.ContainsAny(advertise, "/ ") {
+		return fmt.Errorf("advertise must be a host or host:port, got %q", c.Advertise)
+	}
+	return nil
+}
+
+// apply copies the settings into the listener options.
+func (c CallbackConfig) apply(opts *sonos.ListenerOptions) {
+	opts.CallbackPort = c.Port
+	opts.CallbackBind = strings.TrimSpace(c.Bind)
+	opts.CallbackAdvertise = strings.TrimSpace(c.Advertise)
+}
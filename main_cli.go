@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"musicDisplay/logging"
+)
+
+// command is one subcommand of the program.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands lists the subcommands in the order the usage shows them.
+var commands = []command{
+	{"run", "follow the configured room and draw what it plays (the default)", runApp},
+	{"daemon", "run as a systemd service that restarts the listener instead of exiting", runDaemonCommand},
+	{"discover", "list the speakers on the network", runDiscoverCommand},
+	{"status", "print what each room is playing", runStatusCommand},
+	{"art", "save the artwork of a room's current track", runArtCommand},
+	{"display-test", "show an image on the display until Ctrl+C", runDisplayTestCommand},
+	{"control", "play, pause, stop, or skip in a room", runControlCommand},
+	{"group", "group and ungroup rooms", runGroupCommand},
+	{"overlay", "draw text onto an image", runOverlayCommand},
+}
+
+// runCLI runs the subcommand args names. Without one, or when args start
+// with a flag, it runs the display, so the flags of earlier releases keep
+// working.
+func runCLI(args []string, stdout, stderr io.Writer) error {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		writeUsage(stdout)
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args, stdout, stderr)
+		}
+	}
+	writeUsage(stderr)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// writeUsage lists the subcommands.
+func writeUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: musicDisplay [command] [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-13s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "musicDisplay <command> -h" for a command's flags.`)
+}
+
+// parseArgs parses fs's flags wherever they appear in args and returns the
+// other arguments in order, so "control pause --room Kitchen" and "control
+// --room Kitchen pause" mean the same. ok is false when -h asked for help,
+// which has already been printed.
+func parseArgs(fs *flag.FlagSet, args []string) (rest []string, ok bool, err error) {
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		if fs.NArg() == 0 {
+			return rest, true, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// commandFlags are the flags shared by the subcommands that read the config.
+type commandFlags struct {
+	profile   *string
+	discovery *string
+	debug     *bool
+}
+
+// addCommandFlags registers -profile, -discovery, and -debug on fs.
+func addCommandFlags(fs *flag.FlagSet) commandFlags {
+	return commandFlags{
+		profile:   fs.String("profile", "", "apply the named profile from config.json"),
+		discovery: fs.String("discovery", "", "how to find speakers: ssdp, mdns, or both (default: as configured)"),
+		debug:     fs.Bool("debug", false, "enable debug logging"),
+	}
+}
+
+// load reads config.json, sets up logging on stderr, and configures how
+// speakers are found, as the run command does. A missing or invalid config only warns,
+// unless a profile was asked for.
+func (f commandFlags) load(stderr io.Writer) (Config, error) {
+	profile := strings.TrimSpace(*f.profile)
+	cfg, err := loadConfig(defaultConfigPath, profile)
+	if err != nil {
+		if profile != "" {
+			return Config{}, err
+		}
+		logger.Warn(err.Error())
+	}
+	debugMode = *f.debug
+	if err := logging.Setup(stderr, loggingOptions(cfg.Logging, debugMode)); err != nil {
+		logger.Warn("logging config ignored", "err", err)
+	}
+	method := strings.TrimSpace(*f.discovery)
+	if method == "" {
+		method = cfg.Discovery
+	}
+	if err := configureSpeakers(cfg, method); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
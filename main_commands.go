@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"musicDisplay/simdisplay"
+	"musicDisplay/sonos"
+)
+
+// runDiscoverCommand implements the discover subcommand:
+//
+//	musicDisplay discover [--json]
+//
+// It lists the speakers found, by room, with their addresses and models, as
+// a table or, with --json, as a JSON array.
+func runDiscoverCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay discover [flags]")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	asJSON := fs.Bool("json", false, "print the speakers as JSON")
+	rest, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		return fmt.Errorf("discover: unexpected arguments %q", rest)
+	}
+	if _, err := flags.load(stderr); err != nil {
+		return fmt.Errorf("discover: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	devices, err := discoverDevices(ctx, "")
+	if err != nil {
+		return fmt.Errorf("discover: %w", err)
+	}
+	speakers := make([]sonos.Device, 0, len(devices))
+	for _, device := range devices {
+		if device.IsSonos {
+			speakers = append(speakers, device)
+		}
+	}
+	if len(speakers) == 0 {
+		return fmt.Errorf("discover: no speakers found via %s", discovery.method)
+	}
+	sort.SliceStable(speakers, func(i, j int) bool {
+		return strings.ToLower(sonos.RoomName(speakers[i])) < strings.ToLower(sonos.RoomName(speakers[j]))
+	})
+
+	if *asJSON {
+		out := make([]discoveredSpeaker, 0, len(speakers))
+		for _, device := range speakers {
+			out = append(out, discoveredSpeaker{
+				Room:            sonos.RoomName(device),
+				IP:              device.IP,
+				Model:           device.Metadata.ModelName,
+				ModelNumber:     device.Metadata.ModelNumber,
+				SoftwareVersion: device.Metadata.SoftwareVersion,
+				UUID:            strings.TrimPrefix(device.Metadata.UDN, "uuid:"),
+				Location:        device.Location,
+			})
+		}
+		return writeJSON(stdout, out)
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Room\tIP\tModel")
+	for _, device := range speakers {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", sonos.RoomName(device), device.IP, device.Metadata.ModelName)
+	}
+	return w.Flush()
+}
+
+// discoveredSpeaker is a speaker as discover --json prints it.
+type discoveredSpeaker struct {
+	Room            string `json:"room"`
+	IP              string `json:"ip"`
+	Model           string `json:"model"`
+	ModelNumber     string `json:"model_number,omitempty"`
+	SoftwareVersion string `json:"software_version,omitempty"`
+	UUID            string `json:"uuid,omitempty"`
+	Location        string `json:"location,omitempty"`
+}
+
+// roomStatus is a room's status as status --json prints it.
+type roomStatus struct {
+	Room        string `json:"room"`
+	State       string `json:"state"`
+	Track       string `json:"track"`
+	GroupedWith string `json:"grouped_with,omitempty"`
+	ArtURI      string `json:"art_uri,omitempty"`
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// runStatusCommand implements the status subcommand:
+//
+//	musicDisplay status [--room <room>] [--json]
+//
+// It prints what each room, or only the given one, is playing, as a table
+// or, with --json, as a JSON array.
+func runStatusCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay status [flags]")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	room := fs.String("room", "", "only show this room (default: every room)")
+	asJSON := fs.Bool("json", false, "print the statuses as JSON")
+	rest, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(rest) != 0 {
+		fs.Usage()
+		return fmt.Errorf("status: unexpected arguments %q", rest)
+	}
+	if _, err := flags.load(stderr); err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	target := strings.TrimSpace(*room)
+	devices, err := discoverDevices(ctx, target)
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	statuses, _ := sonos.GatherRoomStatuses(ctx, devices, target)
+	if len(statuses) == 0 {
+		if target != "" {
+			return fmt.Errorf("status: room %q not found", target)
+		}
+		return fmt.Errorf("status: no speakers found via %s", discovery.method)
+	}
+	if *asJSON {
+		out := make([]roomStatus, 0, len(statuses))
+		for _, status := range statuses {
+			out = append(out, roomStatus{
+				Room:        status.Room,
+				State:       status.State,
+				Track:       status.Track,
+				GroupedWith: status.GroupedWith,
+				ArtURI:      status.ArtURI,
+			})
+		}
+		return writeJSON(stdout, out)
+	}
+	return sonos.WriteRoomStatuses(stdout, statuses)
+}
+
+// runArtCommand implements the art subcommand:
+//
+//	musicDisplay art [--room <room>] --out <file.png>
+//
+// It saves the artwork of the room's current track as a PNG, cropped,
+// scaled, and processed as the display would show it.
+func runArtCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("art", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay art [flags] --out <file.png>")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	room := fs.String("room", "", "room whose artwork to save (default: the configured room)")
+	out := fs.String("out", "", "PNG file to write (required)")
+	size := fs.Int("size", 0, "width and height of the image in pixels (default: the artwork size of the configured panels)")
+	rest, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(rest) != 0 || strings.TrimSpace(*out) == "" {
+		fs.Usage()
+		return errors.New("art: want --out <file.png> and no other arguments")
+	}
+	if *size < 0 {
+		return fmt.Errorf("art: size must not be negative, got %d", *size)
+	}
+	cfg, err := flags.load(stderr)
+	if err != nil {
+		return fmt.Errorf("art: %w", err)
+	}
+	target := strings.TrimSpace(*room)
+	if target == "" {
+		target = strings.TrimSpace(cfg.Room)
+	}
+	if target == "" {
+		return errors.New("art: no room given and none configured")
+	}
+	if *size == 0 {
+		*size = cfg.Matrix.hardware().Geometry().ArtSize()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	speakers, err := findSpeakerGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("art: %w", err)
+	}
+	device, name, err := speakers.coordinator(target)
+	if err != nil {
+		return fmt.Errorf("art: %w", err)
+	}
+	data, _, err := sonos.FetchCurrentAlbumArt(ctx, device)
+	if err != nil {
+		return fmt.Errorf("art: %s: %w", name, err)
+	}
+	img, err := sonos.ProcessAlbumArt(data, *size, cfg.ArtProcessing.processing())
+	if err != nil {
+		return fmt.Errorf("art: %s: %w", name, err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("art: %w", err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("art: encode %s: %w", *out, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("art: %w", err)
+	}
+	fmt.Fprintf(stdout, "Saved %s's artwork to %s\n", name, *out)
+	return nil
+}
+
+// runDisplayTestCommand implements the display-test subcommand:
+//
+//	musicDisplay display-test [--display=<backend>] <image>
+//
+// It shows the image, or plays the animated GIF, on the display until
+// interrupted. The display defaults to the configured one, or the matrix.
+func runDisplayTestCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("display-test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay display-test [flags] <image>")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	var mode displayMode
+	fs.Var(&mode, "display", "display to use: matrix, simulator, terminal, dry-run, or remote (default: as configured, or matrix)")
+	simulatorAddr := fs.String("simulator-addr", simdisplay.DefaultAddr, "listen address for --display=simulator")
+	dryRunDir := fs.String("dry-run-dir", "", "directory for --display=dry-run frames (default: a new temporary directory)")
+	remoteSink := fs.String("remote-sink", "", "host:port of the frame sink --display=remote pushes frames to, or several separated by commas (default: as configured)")
+	gamutPreview := fs.Bool("gamut-preview", false, "show colors as the matrix would at its pwm_bits depth")
+	paths, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(paths) != 1 {
+		fs.Usage()
+		return fmt.Errorf("display-test: want exactly one image path, got %d", len(paths))
+	}
+	cfg, err := flags.load(stderr)
+	if err != nil {
+		return fmt.Errorf("display-test: %w", err)
+	}
+	if !flagWasSet(fs, "display") {
+		mode = displayMatrix
+		if cfg.Display != "" {
+			// Validated by loadConfig.
+			_ = mode.Set(cfg.Display)
+		}
+	}
+	if mode == displayNone {
+		return errors.New("display-test: no display selected")
+	}
+	sink := cfg.RemoteSink
+	if flagWasSet(fs, "remote-sink") {
+		sink = *remoteSink
+	}
+	var brightness int
+	if cfg.Brightness != nil {
+		brightness = *cfg.Brightness
+	}
+
+	hardware := cfg.Matrix.hardware()
+	display, err := openDisplay(stdout, mode, hardware, brightness, *simulatorAddr, *dryRunDir, sink)
+	if err != nil {
+		return fmt.Errorf("display-test: open %s display: %w", string(mode), err)
+	}
+	defer func() {
+		if err := display.Close(); err != nil {
+			logger.Warn("close display", "err", err)
+		}
+	}()
+	if *gamutPreview || cfg.GamutPreview {
+		previewGamut(display, hardware)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := showTestImage(ctx, stdout, display, strings.TrimSpace(paths[0])); err != nil {
+		return fmt.Errorf("display-test: %w", err)
+	}
+	return nil
+}
+
+// controlActions maps the control subcommand's actions to the requests that
+// carry them out.
+var controlActions = map[string]struct {
+	run  func(context.Context, sonos.Device) error
+	done string
+}{
+	"play":     {sonos.Play, "playing"},
+	"pause":    {sonos.Pause, "paused"},
+	"stop":     {sonos.Stop, "stopped"},
+	"next":     {sonos.Next, "skipped to the next track"},
+	"previous": {sonos.Previous, "back to the previous track"},
+}
+
+// runControlCommand implements the control subcommand:
+//
+//	musicDisplay control <play|pause|stop|next|previous> [--room <room>]
+//
+// The command goes to the coordinator of the room's group, so it applies to
+// every room playing along.
+func runControlCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("control", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay control [flags] <play|pause|stop|next|previous>")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	room := fs.String("room", "", "room to control (default: the configured room)")
+	rest, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("control: want one of play, pause, stop, next, or previous")
+	}
+	action, known := controlActions[strings.ToLower(rest[0])]
+	if !known {
+		fs.Usage()
+		return fmt.Errorf("control: unknown action %q", rest[0])
+	}
+	cfg, err := flags.load(stderr)
+	if err != nil {
+		return fmt.Errorf("control: %w", err)
+	}
+	target := strings.TrimSpace(*room)
+	if target == "" {
+		target = strings.TrimSpace(cfg.Room)
+	}
+	if target == "" {
+		return errors.New("control: no room given and none configured")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	speakers, err := findSpeakerGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("control: %w", err)
+	}
+	device, name, err := speakers.coordinator(target)
+	if err != nil {
+		return fmt.Errorf("control: %w", err)
+	}
+	if err := action.run(ctx, device); err != nil {
+		return fmt.Errorf("control: %s: %w", name, err)
+	}
+	fmt.Fprintf(stdout, "%s: %s\n", name, action.done)
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"musicDisplay/sonos"
+	"musicDisplay/spotify"
+)
+
+// trackControls backs the simulator's skip and like buttons and the MPRIS
+// player. Skips go to whichever source is on screen; likes save the track to
+// the configured Spotify account; other transport commands go to the room.
+type trackControls struct {
+	spotify  *spotify.Client
+	fallback *spotifyFallback
+
+	mu     sync.Mutex
+	device sonos.Device
+	status sonos.PlaybackStatus
+}
+
+func newTrackControls(device sonos.Device, client *spotify.Client, fallback *spotifyFallback) *trackControls {
+	return &trackControls{device: device, spotify: client, fallback: fallback}
+}
+
+// setDevice points the controls at a new room's coordinator.
+func (c *trackControls) setDevice(device sonos.Device) {
+	c.mu.Lock()
+	c.device = device
+	c.mu.Unlock()
+}
+
+// observe records the listener's latest status so Like knows the track.
+func (c *trackControls) observe(status sonos.PlaybackStatus) {
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+}
+
+// Skip advances the Spotify player while the fallback is showing, and the
+// Sonos queue otherwise.
+func (c *trackControls) Skip(ctx context.Context) error {
+	if _, ok := c.fallback.current(); ok {
+		if err := c.spotify.SkipToNext(ctx); err != nil {
+			return err
+		}
+		logger.Info("skipped Spotify track")
+		return nil
+	}
+
+	c.mu.Lock()
+	device, room := c.device, c.status.Room
+	c.mu.Unlock()
+	if err := sonos.Next(ctx, device); err != nil {
+		return err
+	}
+	logger.Info("skipped track", "room", room)
+	return nil
+}
+
+// Next implements mpris.Handler by skipping.
+func (c *trackControls) Next(ctx context.Context) error {
+	return c.Skip(ctx)
+}
+
+// Previous returns the room to the previous track.
+func (c *trackControls) Previous(ctx context.Context) error {
+	return sonos.Previous(ctx, c.currentDevice())
+}
+
+// Play resumes playback in the room.
+func (c *trackControls) Play(ctx context.Context) error {
+	return sonos.Play(ctx, c.currentDevice())
+}
+
+// Pause pauses playback in the room.
+func (c *trackControls) Pause(ctx context.Context) error {
+	return sonos.Pause(ctx, c.currentDevice())
+}
+
+// Stop stops playback in the room.
+func (c *trackControls) Stop(ctx context.Context) error {
+	return sonos.Stop(ctx, c.currentDevice())
+}
+
+// PlayPause pauses the room while it plays and resumes it otherwise.
+func (c *trackControls) PlayPause(ctx context.Context) error {
+	if c.snapshot().Playing {
+		return c.Pause(ctx)
+	}
+	return c.Play(ctx)
+}
+
+// AdjustVolume changes the room's volume by delta.
+func (c *trackControls) AdjustVolume(ctx context.Context, delta int) (int, error) {
+	return sonos.AdjustVolume(ctx, c.currentDevice(), delta)
+}
+
+// snapshot returns the latest status seen from the listener.
+func (c *trackControls) snapshot() sonos.PlaybackStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+func (c *trackControls) currentDevice() sonos.Device {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.device
+}
+
+// Like saves the track on screen to the Spotify account's Liked Songs. Only
+// Spotify tracks can be saved.
+func (c *trackControls) Like(ctx context.Context) error {
+	if c.spotify == nil {
+		return errors.New("liking tracks needs the spotify section in config.json")
+	}
+
+	var id, title, artist string
+	if pb, ok := c.fallback.current(); ok {
+		id, title, artist = pb.ID, pb.Title, pb.Artist
+	} else {
+		c.mu.Lock()
+		track := c.status.Track
+		c.mu.Unlock()
+		id, _ = spotify.TrackIDFromURI(track.URI)
+		title, artist = track.Title, track.Artist
+	}
+	if id == "" {
+		return errors.New("the current track is not a Spotify track")
+	}
+
+	if err := c.spotify.SaveTrack(ctx, id); err != nil {
+		return err
+	}
+	logger.Info("liked track", "title", title, "artist", artist)
+	return nil
+}
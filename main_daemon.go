@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/sdnotify"
+	"musicDisplay/sonos"
+)
+
+const (
+	defaultHealthAddr       = "127.0.0.1:8066"
+	defaultSelfCheck        = time.Minute
+	daemonRestartInitial    = 2 * time.Second
+	daemonRestartMax        = time.Minute
+	daemonSelfCheckFailures = 2
+)
+
+// runDaemonCommand implements the daemon subcommand, the run command with
+// -daemon.
+func runDaemonCommand(args []string, stdout, stderr io.Writer) error {
+	return runApp(append([]string{"-daemon"}, args...), stdout, stderr)
+}
+
+func (c *DaemonConfig) validate() error {
+	if c.CheckSeconds < 0 || c.CheckSeconds > 3600 {
+		return fmt.Errorf("check_seconds must be between 0 and 3600, got %d", c.CheckSeconds)
+	}
+	if addr := strings.TrimSpace(c.HealthAddr); addr != "" && !strings.EqualFold(addr, "off") {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("health_addr must be host:port or off, got %q", c.HealthAddr)
+		}
+	}
+	return nil
+}
+
+// healthAddr returns where the health endpoint listens, empty when it is
+// off.
+func (c *DaemonConfig) healthAddr() string {
+	if c == nil || strings.TrimSpace(c.HealthAddr) == "" {
+		return defaultHealthAddr
+	}
+	if strings.EqualFold(strings.TrimSpace(c.HealthAddr), "off") {
+		return ""
+	}
+	return strings.TrimSpace(c.HealthAddr)
+}
+
+// checkInterval returns how often the daemon checks the speaker answers.
+func (c *DaemonConfig) checkInterval() time.Duration {
+	if c == nil || c.CheckSeconds == 0 {
+		return defaultSelfCheck
+	}
+	return time.Duration(c.CheckSeconds) * time.Second
+}
+
+// daemon supervises the listener when the program runs as a service: it
+// restarts the listener when it fails, checks that the speaker still
+// answers, and reports its state to systemd and on the health endpoint.
+type daemon struct {
+	clk     clock.Clock
+	started time.Time
+	// locate finds a room's device again before a restart.
+	locate func(ctx context.Context, room string) (*sonos.Device, error)
+
+	mu       sync.Mutex
+	room     string
+	device   sonos.Device
+	health   sonos.ListenerHealth
+	running  bool
+	restarts int
+	lastErr  string
+	// stop cancels the running listener, for the self-check.
+	stop context.CancelFunc
+}
+
+func newDaemon(clk clock.Clock, device sonos.Device, room string) *daemon {
+	return &daemon{clk: clk, started: clk.Now(), locate: locateRoom, device: device, room: room}
+}
+
+// runDaemon runs the listener as a service until ctx is canceled.
+func runDaemon(ctx context.Context, cfg *DaemonConfig, device sonos.Device, room string, opts sonos.ListenerOptions, rooms <-chan string, onRoom func(string, sonos.Device)) {
+	d := newDaemon(clock.Real, device, room)
+
+	onHealth := opts.OnHealth
+	opts.OnHealth = func(health sonos.ListenerHealth) {
+		d.setHealth(health)
+		if onHealth != nil {
+			onHealth(health)
+		}
+	}
+	onDevice := opts.OnDevice
+	opts.OnDevice = func(device sonos.Device) {
+		d.setDevice(device)
+		if onDevice != nil {
+			onDevice(device)
+		}
+	}
+	onRenamed := opts.OnRoomRenamed
+	opts.OnRoomRenamed = func(from, to string) {
+		d.setRoom(to)
+		if onRenamed != nil {
+			onRenamed(from, to)
+		}
+	}
+	switched := func(room string, device sonos.Device) {
+		d.setRoom(room)
+		d.setDevice(device)
+		if onRoom != nil {
+			onRoom(room, device)
+		}
+	}
+
+	if addr := cfg.healthAddr(); addr != "" {
+		server, err := d.serveHealth(addr)
+		if err != nil {
+			logger.Warn("health endpoint disabled", "err", err)
+		} else {
+			defer server.Close()
+			logger.Debug("health endpoint listening", "addr", addr)
+		}
+	}
+	go d.monitor(ctx, cfg.checkInterval())
+
+	notifySystemd(sdnotify.Ready)
+	d.supervise(ctx, func(ctx context.Context, device sonos.Device, room string) error {
+		// After a restart the device may have moved; the controls follow.
+		opts.OnDevice(device)
+		return listenRooms(ctx, device, room, opts, rooms, switched)
+	})
+	notifySystemd(sdnotify.Stopping)
+}
+
+// notifySystemd passes state to systemd, when it started the program.
+func notifySystemd(state string) {
+	if _, err := sdnotify.Notify(state); err != nil {
+		logger.Debug("systemd notification failed", "err", err)
+	}
+}
+
+// supervise runs listen for the current room and device, restarting it
+// with backoff whenever it stops before ctx is canceled. The room's device
+// is found again before each restart, since a speaker that failed is often
+// at a new address.
+func (d *daemon) supervise(ctx context.Context, listen func(context.Context, sonos.Device, string) error) {
+	backoff := daemonRestartInitial
+	for {
+		device, room := d.current()
+		listenCtx, cancel := context.WithCancel(ctx)
+		d.setRunning(true, cancel, nil)
+		notifySystemd(sdnotify.Status("Following " + room))
+		began := d.clk.Now()
+		err := listen(listenCtx, device, room)
+		cancel()
+		if ctx.Err() != nil {
+			d.setRunning(false, nil, nil)
+			return
+		}
+		if err == nil {
+			err = errors.New("speaker stopped answering")
+		}
+		d.setRunning(false, nil, err)
+		if d.clk.Since(began) > daemonRestartMax {
+			backoff = daemonRestartInitial
+		}
+		logger.Warn("listener stopped; restarting", "room", room, "retry_in", backoff, "err", err)
+		notifySystemd(sdnotify.Status(fmt.Sprintf("Listener for %s stopped (%v); restarting", room, err)))
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.clk.After(backoff):
+			}
+			backoff = min(backoff*2, daemonRestartMax)
+			found, err := d.locate(ctx, room)
+			if err == nil {
+				d.setDevice(*found)
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("rediscover room failed", "room", room, "retry_in", backoff, "err", err)
+		}
+	}
+}
+
+// monitor checks every interval that the speaker still answers, stopping
+// the listener so supervise finds it again after daemonSelfCheckFailures
+// failed checks in a row, and keeps systemd's watchdog fed.
+func (d *daemon) monitor(ctx context.Context, interval time.Duration) {
+	check := d.clk.NewTicker(interval)
+	defer check.Stop()
+	var watchdog <-chan time.Time
+	if every, ok := sdnotify.WatchdogInterval(); ok {
+		ticker := d.clk.NewTicker(every / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C()
+	}
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-watchdog:
+			notifySystemd(sdnotify.Watchdog)
+		case <-check.C():
+			device, room := d.current()
+			checkCtx, cancel := context.WithTimeout(ctx, sonos.TimeoutsFor(device).Description)
+			_, err := sonos.ValidateDevice(checkCtx, device)
+			cancel()
+			if err == nil || ctx.Err() != nil {
+				failures = 0
+				continue
+			}
+			failures++
+			logger.Debug("self-check failed", "room", room, "ip", device.IP, "failures", failures, "err", err)
+			if failures >= daemonSelfCheckFailures {
+				failures = 0
+				logger.Warn("speaker not answering; finding it again", "room", room, "ip", device.IP, "err", err)
+				d.stopListener()
+			}
+		}
+	}
+}
+
+func (d *daemon) current() (sonos.Device, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.device, d.room
+}
+
+func (d *daemon) setRoom(room string) {
+	d.mu.Lock()
+	d.room = room
+	d.mu.Unlock()
+}
+
+func (d *daemon) setDevice(device sonos.Device) {
+	d.mu.Lock()
+	d.device = device
+	d.mu.Unlock()
+}
+
+func (d *daemon) setHealth(health sonos.ListenerHealth) {
+	d.mu.Lock()
+	d.health = health
+	d.mu.Unlock()
+}
+
+// setRunning records whether a listener runs, how to stop it, and why the
+// last one stopped.
+func (d *daemon) setRunning(running bool, stop context.CancelFunc, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running, d.stop = running, stop
+	if running {
+		d.health = sonos.ListenerHealth{}
+	}
+	if err != nil {
+		d.restarts++
+		d.lastErr = err.Error()
+	}
+}
+
+func (d *daemon) stopListener() {
+	d.mu.Lock()
+	stop := d.stop
+	d.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// daemonHealth is the body of GET /healthz.
+type daemonHealth struct {
+	// Status is "ok" while subscribed to the speaker's events, "polling"
+	// while it has to poll for them, and "starting" or "restarting" while
+	// the listener is not subscribed.
+	Status        string     `json:"status"`
+	Room          string     `json:"room"`
+	Speaker       string     `json:"speaker,omitempty"`
+	Subscribed    *time.Time `json:"subscribed,omitempty"`
+	LastEvent     *time.Time `json:"last_event,omitempty"`
+	Restarts      int        `json:"restarts"`
+	LastError     string     `json:"last_error,omitempty"`
+	UptimeSeconds float64    `json:"uptime_seconds"`
+}
+
+// snapshot returns the daemon's health and whether it is healthy.
+func (d *daemon) snapshot() (daemonHealth, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := daemonHealth{
+		Room:          d.room,
+		Speaker:       d.health.Speaker,
+		Restarts:      d.restarts,
+		LastError:     d.lastErr,
+		UptimeSeconds: d.clk.Since(d.started).Seconds(),
+	}
+	if !d.health.Subscribed.IsZero() {
+		subscribed := d.health.Subscribed
+		h.Subscribed = &subscribed
+	}
+	if !d.health.LastEvent.IsZero() {
+		last := d.health.LastEvent
+		h.LastEvent = &last
+	}
+	switch {
+	case !d.running:
+		h.Status = "restarting"
+	case d.health.Subscribed.IsZero():
+		h.Status = "starting"
+	case d.health.Polling:
+		h.Status = "polling"
+	default:
+		h.Status = "ok"
+	}
+	// Polling still shows the room's track, so it counts as healthy.
+	return h, h.Status == "ok" || h.Status == "polling"
+}
+
+// serveHealth serves GET /healthz on addr: the daemon's health as JSON,
+// with status 503 while the listener is not subscribed.
+func (d *daemon) serveHealth(addr string) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		health, ok := d.snapshot()
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(health)
+	})
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("health endpoint stopped", "err", err)
+		}
+	}()
+	return server, nil
+}
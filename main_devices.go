@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"musicDisplay/devicecache"
+	"musicDisplay/sonos"
+)
+
+// deviceCacheTimeout bounds checking one remembered speaker. A speaker that
+// answers at all does so well within it.
+const deviceCacheTimeout = 2 * time.Second
+
+// discoverySettings controls how speakers are found.
+type discoverySettings struct {
+	method sonos.DiscoveryMethod
+	// static, when set, replaces discovery entirely.
+	static []DeviceConfig
+	// cachePath is the device cache file; empty disables the cache.
+	cachePath string
+}
+
+// configureSpeakers applies the config's speaker settings: how speakers are
+// found, by method unless the devices are listed, and how long requests to
+// them may take.
+func configureSpeakers(cfg Config, method string) error {
+	parsed, err := sonos.ParseDiscoveryMethod(method)
+	if err != nil {
+		return err
+	}
+	discovery = discoverySettings{
+		method:    parsed,
+		static:    cfg.Devices,
+		cachePath: deviceCachePath(cfg.DeviceCache),
+	}
+	if cfg.RequestTimeouts != nil {
+		sonos.SetTimeouts(cfg.RequestTimeouts.timeouts(), cfg.RequestTimeouts.Slow.timeouts())
+	}
+	return nil
+}
+
+// deviceCachePath resolves the device_cache setting: empty selects the
+// default location and "off" disables the cache.
+func deviceCachePath(setting string) string {
+	setting = strings.TrimSpace(setting)
+	switch {
+	case strings.EqualFold(setting, "off"):
+		return ""
+	case setting != "":
+		return setting
+	}
+	path, err := devicecache.DefaultPath()
+	if err != nil {
+		logger.Debug("device cache disabled", "err", err)
+		return ""
+	}
+	return path
+}
+
+// cachedDevice looks for room among the remembered speakers and returns the
+// first one that still answers as that room.
+func cachedDevice(ctx context.Context, path, room string) (sonos.Device, bool) {
+	entries, err := devicecache.Load(path)
+	if err != nil {
+		logger.Warn("device cache ignored", "err", err)
+		return sonos.Device{}, false
+	}
+	for _, entry := range entries {
+		if !strings.EqualFold(strings.TrimSpace(entry.Room), strings.TrimSpace(room)) {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, deviceCacheTimeout)
+		device, err := sonos.ValidateDevice(checkCtx, entry.Device())
+		cancel()
+		if err != nil {
+			logger.Debug("cached device stale", "room", room, "location", entry.Location, "err", err)
+			continue
+		}
+		if !strings.EqualFold(sonos.RoomName(device), strings.TrimSpace(room)) {
+			continue
+		}
+		logger.Debug("using cached device", "room", room, "location", device.Location)
+		return device, true
+	}
+	return sonos.Device{}, false
+}
+
+// configuredDevices builds the devices listed in the config. A speaker whose
+// description cannot be fetched is still used under its configured room, since
+// it may simply be asleep.
+func configuredDevices(ctx context.Context, configs []DeviceConfig) []sonos.Device {
+	devices := make([]sonos.Device, 0, len(configs))
+	for _, cfg := range configs {
+		device, err := sonos.NewStaticDevice(ctx, cfg.Room, cfg.IP, cfg.Port)
+		if err != nil {
+			logger.Warn("configured device description unavailable", "ip", cfg.IP, "room", cfg.Room, "err", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
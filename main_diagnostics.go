@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+
+	"musicDisplay/simdisplay"
+)
+
+// simulatorControls adds the diagnostics toggle to the track controls on
+// the simulator's preview page.
+type simulatorControls struct {
+	*trackControls
+	remote *remoteControl
+}
+
+var _ simdisplay.DiagnosticsControls = simulatorControls{}
+
+// ToggleDiagnostics implements simdisplay.DiagnosticsControls.
+func (c simulatorControls) ToggleDiagnostics(ctx context.Context) error {
+	return c.remote.toggleDiagnostics()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"musicDisplay/dryrundisplay"
+	"musicDisplay/framesink"
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/render"
+	"musicDisplay/simdisplay"
+	"musicDisplay/sonos"
+	"musicDisplay/termdisplay"
+)
+
+const (
+	displayNone      = ""
+	displayMatrix    = "matrix"
+	displaySimulator = "simulator"
+	displayTerminal  = "terminal"
+	displayDryRun    = "dry-run"
+	displayRemote    = "remote"
+)
+
+// outputDisplay is implemented by every display backend.
+type outputDisplay interface {
+	sonos.Display
+	SetBrightness(level int) error
+	Close() error
+}
+
+// displayMode is the value of the -display flag. A bare -display selects the
+// LED matrix; -display=<backend> picks another backend.
+type displayMode string
+
+func (m *displayMode) String() string {
+	if m == nil {
+		return ""
+	}
+	return string(*m)
+}
+
+func (m *displayMode) Set(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", displayMatrix:
+		*m = displayMatrix
+	case "false", "":
+		*m = displayNone
+	case displaySimulator:
+		*m = displaySimulator
+	case displayTerminal:
+		*m = displayTerminal
+	case displayDryRun:
+		*m = displayDryRun
+	case displayRemote:
+		*m = displayRemote
+	default:
+		return fmt.Errorf("unknown display %q (want matrix, simulator, terminal, dry-run, or remote)", value)
+	}
+	return nil
+}
+
+// IsBoolFlag lets -display be given without a value.
+func (m *displayMode) IsBoolFlag() bool { return true }
+
+// openDisplay initialises the backend selected by mode, writing what the
+// simulator and terminal backends print to stdout. hw drives the LED
+// matrix and sets the frame geometry of the other backends, so they preview
+// the configured panels; frameDir only applies to dry runs and sinkAddr to
+// the remote backend, which takes its geometry from the sink. sinkAddr may
+// list several sinks, separated by commas, to mirror frames in step.
+func openDisplay(stdout io.Writer, mode displayMode, hw matrixdisplay.MatrixConfig, brightness int, simulatorAddr, frameDir, sinkAddr string) (outputDisplay, error) {
+	switch mode {
+	case displayRemote:
+		var addrs []string
+		for _, addr := range strings.Split(sinkAddr, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+		var client outputDisplay
+		var err error
+		switch len(addrs) {
+		case 0:
+			return nil, fmt.Errorf("the remote display needs a sink address (-remote-sink or remote_sink)")
+		case 1:
+			client, err = framesink.Dial(addrs[0])
+		default:
+			client, err = framesink.DialGroup(addrs)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if brightness > 0 {
+			if err := client.SetBrightness(brightness); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+		return client, nil
+	case displayDryRun:
+		return dryrundisplay.New(frameDir, hw.Geometry(), brightness)
+	case displaySimulator:
+		sim, err := simdisplay.New(simulatorAddr, hw.Geometry(), brightness)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(stdout, "Display simulator running at %s\n", sim.URL())
+		return sim, nil
+	case displayTerminal:
+		term, err := termdisplay.New(stdout, hw.Geometry(), brightness)
+		if err != nil {
+			return nil, err
+		}
+		return term, nil
+	default:
+		ctrl, err := matrixdisplay.NewController(hw, brightness)
+		if err != nil {
+			return nil, err
+		}
+		return ctrl, nil
+	}
+}
+
+// previewGamut makes an emulated display simulate the color depth of the
+// configured matrix. The matrix itself needs no preview, so other backends
+// only log that the option does nothing.
+func previewGamut(display outputDisplay, hw matrixdisplay.MatrixConfig) {
+	previewer, ok := display.(interface{ PreviewGamut(pwmBits int) })
+	if !ok {
+		logger.Info("gamut preview only applies to the simulator, terminal, and dry-run displays")
+		return
+	}
+	previewer.PreviewGamut(hw.PWMBits)
+	logger.Debug("previewing matrix gamut", "pwm_bits", hw.PWMBits)
+}
+
+// displaySize returns the frame size of display, defaulting to a single
+// panel for backends that do not report one.
+func displaySize(display outputDisplay) image.Point {
+	if sized, ok := display.(interface{ Size() (int, int) }); ok {
+		if w, h := sized.Size(); w > 0 && h > 0 {
+			return image.Pt(w, h)
+		}
+	}
+	return matrixdisplay.DefaultGeometry.Bounds().Max
+}
+
+// describeLayout summarises the renderer options for dry-run frame logs.
+func describeLayout(opts render.Options) string {
+	parts := []string{"art"}
+	if opts.ShowProgress {
+		parts = append(parts, "progress")
+	}
+	if opts.Ticker.Enabled {
+		position := opts.Ticker.Position
+		if position == "" {
+			position = render.TickerOverlay
+		}
+		parts = append(parts, "ticker("+position+")")
+	}
+	if opts.Transition.Style != "" && opts.Transition.Style != render.TransitionNone {
+		parts = append(parts, "transition:"+opts.Transition.Style)
+	}
+	if len(opts.Layers) > 0 {
+		parts = append(parts, fmt.Sprintf("layers:%d", len(opts.Layers)))
+	}
+	if opts.Idle.Screen != "" {
+		parts = append(parts, "idle:"+opts.Idle.Screen)
+	}
+	if len(opts.Rotation.Screens) > 0 {
+		parts = append(parts, "rotation:"+strings.Join(opts.Rotation.Screens, ","))
+	}
+	return strings.Join(parts, "+")
+}
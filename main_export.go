@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"musicDisplay/atomicfile"
+	"musicDisplay/sonos"
+)
+
+const (
+	exportJSONName = "now_playing.json"
+	exportTextName = "now_playing.txt"
+	exportArtName  = "art.png"
+)
+
+// nowPlayingExport mirrors what the display shows into a directory, for
+// streaming overlays and status bars: now_playing.json, a one-line
+// now_playing.txt, and the artwork as art.png. It forwards everything to out,
+// which may be nil when no display is attached.
+type nowPlayingExport struct {
+	dir string
+	out statusDisplay
+
+	mu       sync.Mutex
+	lastJSON []byte
+}
+
+// exportedTrack is the JSON written to now_playing.json.
+type exportedTrack struct {
+	Room            string    `json:"room"`
+	State           string    `json:"state"`
+	Playing         bool      `json:"playing"`
+	Title           string    `json:"title"`
+	Artist          string    `json:"artist"`
+	Album           string    `json:"album"`
+	Source          string    `json:"source,omitempty"`
+	Service         string    `json:"service,omitempty"`
+	PositionSeconds float64   `json:"position_seconds"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func newNowPlayingExport(dir string, out statusDisplay) (*nowPlayingExport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("export: create %q: %w", dir, err)
+	}
+	return &nowPlayingExport{dir: dir, out: out}, nil
+}
+
+// Show writes the artwork to art.png and forwards it.
+func (e *nowPlayingExport) Show(img image.Image) error {
+	return e.ShowContext(context.Background(), img)
+}
+
+// ShowContext is Show with ctx passed on to the display. It implements
+// sonos.ContextDisplay.
+func (e *nowPlayingExport) ShowContext(ctx context.Context, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		logger.Warn("export artwork", "err", err)
+	} else if err := e.write(exportArtName, buf.Bytes()); err != nil {
+		logger.Warn("export artwork", "err", err)
+	}
+	if e.out == nil {
+		return nil
+	}
+	return sonos.ShowContext(ctx, e.out, img)
+}
+
+// Clear removes art.png and forwards the idle transition.
+func (e *nowPlayingExport) Clear() error {
+	return e.ClearContext(context.Background())
+}
+
+// ClearContext is Clear with ctx passed on to the display. It implements
+// sonos.ContextDisplay.
+func (e *nowPlayingExport) ClearContext(ctx context.Context) error {
+	if err := os.Remove(filepath.Join(e.dir, exportArtName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("export artwork", "err", err)
+	}
+	if e.out == nil {
+		return nil
+	}
+	return sonos.ClearContext(ctx, e.out)
+}
+
+// Close closes the display. The exported files are left in place.
+func (e *nowPlayingExport) Close() error {
+	if e.out == nil {
+		return nil
+	}
+	return e.out.Close()
+}
+
+// SetDimmed forwards dimming to the display.
+func (e *nowPlayingExport) SetDimmed(dimmed bool) error {
+	if dimmer, ok := e.out.(sonos.Dimmer); ok {
+		return dimmer.SetDimmed(dimmed)
+	}
+	return nil
+}
+
+// UpdateStatus rewrites the track files when anything but the timestamp
+// changed, and forwards the status.
+func (e *nowPlayingExport) UpdateStatus(status sonos.PlaybackStatus) {
+	e.export(status)
+	if e.out != nil {
+		e.out.UpdateStatus(status)
+	}
+}
+
+func (e *nowPlayingExport) export(status sonos.PlaybackStatus) {
+	track := exportedTrack{
+		Room:            status.Room,
+		State:           status.State,
+		Playing:         status.Playing,
+		Title:           status.Track.Title,
+		Artist:          status.Track.Artist,
+		Album:           status.Track.Album,
+		Source:          string(status.Track.Source),
+		Service:         status.Track.Service,
+		PositionSeconds: status.Track.Position.Seconds(),
+		DurationSeconds: status.Track.Duration.Seconds(),
+	}
+	// Compare without the timestamp so identical updates are not rewritten.
+	key, err := json.Marshal(track)
+	if err != nil {
+		logger.Warn("export now playing", "err", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if bytes.Equal(key, e.lastJSON) {
+		return
+	}
+	e.lastJSON = key
+
+	track.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(track, "", "  ")
+	if err != nil {
+		logger.Warn("export now playing", "err", err)
+		return
+	}
+	if err := e.write(exportJSONName, append(data, '\n')); err != nil {
+		logger.Warn("export now playing", "err", err)
+	}
+
+	line := ""
+	if status.Playing {
+		line = formatNowPlaying(status.Track)
+	}
+	if err := e.write(exportTextName, []byte(line+"\n")); err != nil {
+		logger.Warn("export now playing", "err", err)
+	}
+}
+
+// formatNowPlaying returns "Artist – Title", or whichever of the two is known.
+func formatNowPlaying(track sonos.TrackInfo) string {
+	switch {
+	case track.Artist != "" && track.Title != "":
+		return track.Artist + " – " + track.Title
+	case track.Title != "":
+		return track.Title
+	default:
+		return track.Artist
+	}
+}
+
+// write replaces name atomically so readers never see a partial file.
+func (e *nowPlayingExport) write(name string, data []byte) error {
+	if err := atomicfile.Write(filepath.Join(e.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"musicDisplay/sonos"
+)
+
+// runGroupCommand implements the group subcommand:
+//
+//	musicDisplay group join <room> <other room>
+//	musicDisplay group leave <room>
+//
+// join adds room to the group other room belongs to; leave takes room out of
+// its group. Speakers are found as configured in config.json.
+func runGroupCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("group", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay group [flags] join <room> <other room>")
+		fmt.Fprintln(stderr, "       musicDisplay group [flags] leave <room>")
+		fs.PrintDefaults()
+	}
+	flags := addCommandFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 || (args[0] == "join" && len(args) != 3) || (args[0] == "leave" && len(args) != 2) || (args[0] != "join" && args[0] != "leave") {
+		fs.Usage()
+		return errors.New("group: want join <room> <other room> or leave <room>")
+	}
+	if _, err := flags.load(stderr); err != nil {
+		return fmt.Errorf("group: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	speakers, err := findSpeakerGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("group: %w", err)
+	}
+
+	room, ok := speakers.find(args[1])
+	if !ok {
+		return fmt.Errorf("group: room %q not found", args[1])
+	}
+	if args[0] == "leave" {
+		if len(room.group.Members) == 1 {
+			fmt.Fprintf(stdout, "%s is not grouped\n", room.member.Room)
+			return nil
+		}
+		if err := sonos.LeaveGroup(ctx, speakers.device(room.member)); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s left its group\n", room.member.Room)
+		return nil
+	}
+
+	other, ok := speakers.find(args[2])
+	if !ok {
+		return fmt.Errorf("group: room %q not found", args[2])
+	}
+	if room.group.ID == other.group.ID {
+		fmt.Fprintf(stdout, "%s is already grouped with %s\n", room.member.Room, other.member.Room)
+		return nil
+	}
+	coordinator, ok := other.group.CoordinatorMember()
+	if !ok {
+		return fmt.Errorf("group: %s's group has no coordinator", other.member.Room)
+	}
+	if err := sonos.JoinGroup(ctx, speakers.device(room.member), speakers.device(coordinator)); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s joined %s\n", room.member.Room, other.member.Room)
+	return nil
+}
+
+// speakerGroups is the household's group topology with the speakers found.
+type speakerGroups struct {
+	groups  []sonos.ZoneGroup
+	devices []sonos.Device
+}
+
+// groupedRoom is a room and the group it belongs to.
+type groupedRoom struct {
+	member sonos.ZoneMember
+	group  sonos.ZoneGroup
+}
+
+// findSpeakerGroups discovers the speakers and asks the first that answers
+// for the household's groups.
+func findSpeakerGroups(ctx context.Context) (speakerGroups, error) {
+	devices, err := discoverDevices(ctx, "")
+	if err != nil {
+		return speakerGroups{}, fmt.Errorf("discover speakers: %w", err)
+	}
+	if len(devices) == 0 {
+		return speakerGroups{}, fmt.Errorf("no speakers found via %s", discovery.method)
+	}
+	var errs []error
+	for _, device := range devices {
+		groups, err := sonos.ZoneGroups(ctx, device)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return speakerGroups{groups: groups, devices: devices}, nil
+	}
+	return speakerGroups{}, fmt.Errorf("read groups: %w", errors.Join(errs...))
+}
+
+// find returns the speaker named room, ignoring case. A stereo pair or home
+// theater is found by its main speaker.
+func (s speakerGroups) find(room string) (groupedRoom, bool) {
+	room = strings.TrimSpace(room)
+	for _, group := range s.groups {
+		for _, member := range group.Members {
+			if strings.EqualFold(member.Room, room) {
+				return groupedRoom{member: member, group: group}, true
+			}
+		}
+	}
+	return groupedRoom{}, false
+}
+
+// device returns member as discovered, which knows its model and so its
+// timeouts, or as the topology describes it when it was not discovered.
+func (s speakerGroups) device(member sonos.ZoneMember) sonos.Device {
+	for _, device := range s.devices {
+		if strings.EqualFold(device.Metadata.UDN, "uuid:"+member.UUID) {
+			return device
+		}
+	}
+	return member.Device()
+}
+
+// coordinator returns the speaker that plays for room's group, which holds
+// the group's transport and current track, and room's name as the topology
+// spells it.
+func (s speakerGroups) coordinator(room string) (sonos.Device, string, error) {
+	found, ok := s.find(room)
+	if !ok {
+		return sonos.Device{}, "", fmt.Errorf("room %q not found", room)
+	}
+	coordinator, ok := found.group.CoordinatorMember()
+	if !ok {
+		return sonos.Device{}, "", fmt.Errorf("%s's group has no coordinator", found.member.Room)
+	}
+	return s.device(coordinator), found.member.Room, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"image"
+
+	"musicDisplay/mqttbridge"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+// mqttTap reports what the display chain shows to the MQTT bridge and
+// forwards everything to out, which may be nil when no display is attached.
+type mqttTap struct {
+	bridge *mqttbridge.Bridge
+	out    statusDisplay
+}
+
+func (t *mqttTap) Show(img image.Image) error {
+	return t.ShowContext(context.Background(), img)
+}
+
+// ShowContext implements sonos.ContextDisplay.
+func (t *mqttTap) ShowContext(ctx context.Context, img image.Image) error {
+	t.bridge.SetArtAvailable(true, theme.Swatches(theme.ArtColors(img, paletteColors)))
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ShowContext(ctx, t.out, img)
+}
+
+func (t *mqttTap) Clear() error {
+	return t.ClearContext(context.Background())
+}
+
+// ClearContext implements sonos.ContextDisplay.
+func (t *mqttTap) ClearContext(ctx context.Context) error {
+	t.bridge.SetArtAvailable(false, nil)
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ClearContext(ctx, t.out)
+}
+
+// Close closes the display; the bridge has its own lifetime.
+func (t *mqttTap) Close() error {
+	if t.out == nil {
+		return nil
+	}
+	return t.out.Close()
+}
+
+// SetDimmed forwards dimming to the display.
+func (t *mqttTap) SetDimmed(dimmed bool) error {
+	if dimmer, ok := t.out.(sonos.Dimmer); ok {
+		return dimmer.SetDimmed(dimmed)
+	}
+	return nil
+}
+
+func (t *mqttTap) UpdateStatus(status sonos.PlaybackStatus) {
+	t.bridge.UpdateStatus(status)
+	if t.out != nil {
+		t.out.UpdateStatus(status)
+	}
+}
+
+// mqttOptions converts the config section into bridge options.
+func mqttOptions(cfg *MQTTConfig) mqttbridge.Options {
+	opts := mqttbridge.Options{
+		Broker:          cfg.Broker,
+		Username:        cfg.Username,
+		Password:        cfg.Password,
+		ClientID:        cfg.ClientID,
+		TopicPrefix:     cfg.TopicPrefix,
+		DiscoveryPrefix: cfg.DiscoveryPrefix,
+		Discovery:       true,
+	}
+	if cfg.Discovery != nil {
+		opts.Discovery = *cfg.Discovery
+	}
+	return opts
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"image"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"musicDisplay/matrixdisplay"
 	"musicDisplay/overlay"
+	"musicDisplay/theme"
 )
 
 const (
@@ -15,35 +20,149 @@ const (
 	defaultOverlayMargin     = 4
 )
 
-func generateOverlayImage(text, imagePath string) (string, error) {
-	text = strings.TrimSpace(text)
+// overlayRequest describes one text overlay written to disk.
+type overlayRequest struct {
+	text     string
+	input    string
+	output   string
+	options  overlay.TextOptions
+	keepSize bool
+	// geometry is the frame the image is fitted to unless keepSize is set.
+	geometry matrixdisplay.Geometry
+}
+
+// runOverlayCommand implements the overlay subcommand:
+//
+//	musicDisplay overlay --text "..." [--pos top-right] [--out file.png] <image>
+//
+// Flags may come before or after the image path.
+func runOverlayCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("overlay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: musicDisplay overlay --text TEXT [flags] <image>")
+		fs.PrintDefaults()
+	}
+	text := fs.String("text", "", "text to draw (required)")
+	pos := fs.String("pos", overlay.TopRight, "where to draw the text: top-left, top-right, bottom-left, bottom-right, or center")
+	out := fs.String("out", "", "PNG file to write (default: <image>-overlayed.png next to the image)")
+	height := fs.Float64("text-height", defaultOverlayTextHeight, "text height in pixels")
+	margin := fs.Int("margin", defaultOverlayMargin, "pixels between the text and the edges it is placed against")
+	textColor := fs.String("color", "#ffffff", "text color as #rrggbb")
+	keepSize := fs.Bool("keep-size", false, "keep the image's size instead of fitting it to the configured panels")
+	profile := fs.String("profile", "", "apply the named profile from config.json")
+
+	paths, ok, err := parseArgs(fs, args)
+	if !ok {
+		return err
+	}
+	if len(paths) != 1 {
+		fs.Usage()
+		return fmt.Errorf("overlay: want exactly one image path, got %d", len(paths))
+	}
+	col, err := theme.ParseColor(*textColor)
+	if err != nil {
+		return fmt.Errorf("overlay: color: %w", err)
+	}
+	geometry, err := overlayGeometry(strings.TrimSpace(*profile))
+	if err != nil {
+		return fmt.Errorf("overlay: %w", err)
+	}
+
+	output, err := writeOverlay(overlayRequest{
+		text:   *text,
+		input:  paths[0],
+		output: *out,
+		options: overlay.TextOptions{
+			Position: strings.ToLower(strings.TrimSpace(*pos)),
+			Margin:   overlay.Margin{Top: *margin, Right: *margin, Bottom: *margin, Left: *margin},
+			Height:   *height,
+			Color:    col,
+		},
+		keepSize: *keepSize,
+		geometry: geometry,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Overlay image written to %s\n", output)
+	return nil
+}
+
+// generateOverlayImage backs the -write-overlay flag: text in the top-right
+// corner of a PNG fitted to the configured panels, written next to it.
+func generateOverlayImage(text, imagePath, profile string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(strings.TrimSpace(imagePath)), ".png") {
+		return "", fmt.Errorf("overlay: image path must point to a .png file")
+	}
+	geometry, err := overlayGeometry(profile)
+	if err != nil {
+		return "", fmt.Errorf("overlay: %w", err)
+	}
+	return writeOverlay(overlayRequest{
+		text:  text,
+		input: imagePath,
+		options: overlay.TextOptions{
+			Position: overlay.TopRight,
+			Margin:   overlay.Margin{Top: defaultOverlayMargin, Right: defaultOverlayMargin},
+			Height:   defaultOverlayTextHeight,
+		},
+		geometry: geometry,
+	})
+}
+
+// overlayGeometry returns the frame of the panels config.json describes,
+// which overlays are fitted to. A missing or invalid config only warns,
+// unless a profile was asked for.
+func overlayGeometry(profile string) (matrixdisplay.Geometry, error) {
+	cfg, err := loadConfig(defaultConfigPath, profile)
+	if err != nil {
+		if profile != "" {
+			return matrixdisplay.Geometry{}, err
+		}
+		logger.Warn(err.Error())
+	}
+	return cfg.Matrix.hardware().Geometry(), nil
+}
+
+// writeOverlay draws the request's text onto its image and saves the result
+// as a PNG, returning the path written.
+func writeOverlay(req overlayRequest) (string, error) {
+	text := strings.TrimSpace(req.text)
 	if text == "" {
 		return "", fmt.Errorf("overlay: text must not be empty")
 	}
-	imagePath = strings.TrimSpace(imagePath)
+	imagePath := strings.TrimSpace(req.input)
 	if imagePath == "" {
 		return "", fmt.Errorf("overlay: image path must not be empty")
 	}
-	if !strings.EqualFold(filepath.Ext(imagePath), ".png") {
-		return "", fmt.Errorf("overlay: image path must point to a .png file")
+	if !overlay.ValidPosition(req.options.Position) {
+		return "", fmt.Errorf("overlay: position must be top-left, top-right, bottom-left, bottom-right, or center, got %q", req.options.Position)
+	}
+	outputPath := strings.TrimSpace(req.output)
+	if outputPath == "" {
+		outputPath = overlayOutputPath(imagePath)
+	}
+	if !strings.EqualFold(filepath.Ext(outputPath), ".png") {
+		return "", fmt.Errorf("overlay: output path must end in .png, got %q", outputPath)
 	}
 
-	src, err := loadAndScaleImage(imagePath)
+	var src image.Image
+	var err error
+	if req.keepSize {
+		src, err = loadImage(imagePath)
+	} else {
+		src, err = loadAndScaleImage(imagePath, req.geometry)
+	}
 	if err != nil {
 		return "", fmt.Errorf("overlay: load base image: %w", err)
 	}
 
-	margin := overlay.Margin{
-		Top:   defaultOverlayMargin,
-		Right: defaultOverlayMargin,
-	}
-	result, err := overlay.OverlayTopRightText(src, text, margin, defaultOverlayTextHeight)
+	result, err := overlay.OverlayText(src, text, req.options)
 	if err != nil {
 		return "", fmt.Errorf("overlay: apply text overlay: %w", err)
 	}
 
-	outputPath := overlayOutputPath(imagePath)
-
 	file, err := os.Create(outputPath)
 	if err != nil {
 		return "", fmt.Errorf("overlay: create output %q: %w", outputPath, err)
@@ -56,8 +175,10 @@ func generateOverlayImage(text, imagePath string) (string, error) {
 	return outputPath, nil
 }
 
+// overlayOutputPath returns the default output for srcPath: the same name
+// with "-overlayed" added, always as a PNG.
 func overlayOutputPath(srcPath string) string {
 	ext := filepath.Ext(srcPath)
 	base := strings.TrimSuffix(srcPath, ext)
-	return fmt.Sprintf("%s-overlayed%s", base, ext)
+	return fmt.Sprintf("%s-overlayed.png", base)
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+)
+
+func (c *PlaceholderConfig) validate() error {
+	if strings.TrimSpace(c.Image) == "" {
+		return nil
+	}
+	_, err := loadPlaceholderImage(c.Image, 0, sonos.ArtProcessing{})
+	return err
+}
+
+// loadPlaceholderImage reads the image at path and processes it like album
+// art, size pixels square.
+func loadPlaceholderImage(path string, size int, proc sonos.ArtProcessing) (image.Image, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	img, err := sonos.ProcessAlbumArt(data, size, proc)
+	if err != nil {
+		return nil, fmt.Errorf("image %q: %w", path, err)
+	}
+	return img, nil
+}
+
+// art returns the listener's Placeholder function: the configured image,
+// or the track's title or station drawn on a colored square, size pixels
+// square and processed like the album art.
+func (c *PlaceholderConfig) art(size int, proc sonos.ArtProcessing) (func(sonos.TrackInfo) (image.Image, error), error) {
+	if strings.TrimSpace(c.Image) != "" {
+		img, err := loadPlaceholderImage(c.Image, size, proc)
+		if err != nil {
+			return nil, err
+		}
+		return func(sonos.TrackInfo) (image.Image, error) { return img, nil }, nil
+	}
+	return func(track sonos.TrackInfo) (image.Image, error) {
+		return overlay.Placeholder(placeholderText(track), size)
+	}, nil
+}
+
+// placeholderText is what a placeholder shows for track: its title, the
+// stream's description, or where it plays from.
+func placeholderText(track sonos.TrackInfo) string {
+	for _, text := range []string{track.Title, track.StreamInfo, track.Artist, track.SourceLabel()} {
+		if text = strings.TrimSpace(text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"image"
+
+	"musicDisplay/powersave"
+	"musicDisplay/sonos"
+)
+
+// governorTap switches the CPU governor to active while the display chain
+// shows artwork and to idle once it is cleared, forwarding everything to
+// out, which may be nil when no display is attached.
+type governorTap struct {
+	governor *powersave.Governor
+	out      statusDisplay
+}
+
+func (t *governorTap) Show(img image.Image) error {
+	return t.ShowContext(context.Background(), img)
+}
+
+// ShowContext implements sonos.ContextDisplay.
+func (t *governorTap) ShowContext(ctx context.Context, img image.Image) error {
+	t.governor.SetIdle(false)
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ShowContext(ctx, t.out, img)
+}
+
+func (t *governorTap) Clear() error {
+	return t.ClearContext(context.Background())
+}
+
+// ClearContext implements sonos.ContextDisplay.
+func (t *governorTap) ClearContext(ctx context.Context) error {
+	t.governor.SetIdle(true)
+	if t.out == nil {
+		return nil
+	}
+	return sonos.ClearContext(ctx, t.out)
+}
+
+func (t *governorTap) Close() error {
+	if t.out == nil {
+		return nil
+	}
+	return t.out.Close()
+}
+
+// SetDimmed forwards dimming to the display.
+func (t *governorTap) SetDimmed(dimmed bool) error {
+	if dimmer, ok := t.out.(sonos.Dimmer); ok {
+		return dimmer.SetDimmed(dimmed)
+	}
+	return nil
+}
+
+func (t *governorTap) UpdateStatus(status sonos.PlaybackStatus) {
+	if t.out != nil {
+		t.out.UpdateStatus(status)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/sonos"
+)
+
+const configPollInterval = 2 * time.Second
+
+// liveConfig is the part of the configuration that is applied without a
+// restart. Everything else is read once at startup.
+type liveConfig struct {
+	Room       string
+	Brightness int
+	Timeouts   sonos.StateTimeouts
+}
+
+func liveConfigFrom(cfg Config) liveConfig {
+	live := liveConfig{Room: strings.TrimSpace(cfg.Room)}
+	if cfg.Brightness != nil {
+		live.Brightness = *cfg.Brightness
+	}
+	idleTimeout := idleTimeoutFor(cfg)
+	if timeouts := buildStateTimeouts(cfg, idleTimeout); timeouts != nil {
+		live.Timeouts = *timeouts
+	} else {
+		live.Timeouts = sonos.StateTimeouts{
+			Paused:  sonos.StateTimeout{Idle: idleTimeout},
+			Stopped: sonos.StateTimeout{Idle: idleTimeout},
+		}
+	}
+	return live
+}
+
+// idleTimeoutFor returns the configured idle timeout or the default.
+func idleTimeoutFor(cfg Config) time.Duration {
+	if cfg.IdleTimeoutSeconds != nil {
+		return time.Duration(*cfg.IdleTimeoutSeconds) * time.Second
+	}
+	return defaultIdleTimeout
+}
+
+// configStamp identifies a revision of the config file on disk.
+type configStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statConfig(path string) configStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}
+	}
+	return configStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchConfig polls path and calls apply with every revision that loads
+// cleanly with profile applied. Edits that fail validation are logged and
+// skipped, so a typo leaves the running settings in place. It blocks until
+// ctx is canceled.
+func watchConfig(ctx context.Context, path, profile string, interval time.Duration, apply func(Config)) {
+	last := statConfig(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stamp := statConfig(path)
+			if stamp == last {
+				continue
+			}
+			last = stamp
+			cfg, err := loadConfig(path, profile)
+			if err != nil {
+				logger.Warn("config reload skipped", "err", err)
+				continue
+			}
+			apply(cfg)
+		}
+	}
+}
+
+// configReloader pushes reloaded settings to the display and the listener.
+type configReloader struct {
+	// path and profile locate the room setting selectRoom writes.
+	path     string
+	profile  string
+	display  brightnessSetter
+	timeouts chan sonos.StateTimeouts
+	rooms    chan string
+
+	mu      sync.Mutex
+	current liveConfig
+}
+
+func newConfigReloader(cfg Config, path, profile string, display brightnessSetter) *configReloader {
+	return &configReloader{
+		path:     path,
+		profile:  profile,
+		current:  liveConfigFrom(cfg),
+		display:  display,
+		timeouts: make(chan sonos.StateTimeouts, 1),
+		rooms:    make(chan string, 1),
+	}
+}
+
+// apply compares cfg with the running settings and forwards what changed.
+func (r *configReloader) apply(cfg Config) {
+	next := liveConfigFrom(cfg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if next.Brightness > 0 && next.Brightness != r.current.Brightness && r.display != nil {
+		if err := r.display.SetBrightness(next.Brightness); err != nil {
+			logger.Warn("config reload brightness", "err", err)
+		} else {
+			logger.Debug("config reload: brightness applied", "brightness", next.Brightness)
+		}
+	}
+
+	if next.Timeouts != r.current.Timeouts {
+		sendLatest(r.timeouts, next.Timeouts)
+		logger.Debug("config reload: state timeouts updated")
+	}
+
+	if next.Room == "" {
+		// Without a room there is nothing to listen to; keep the current one.
+		next.Room = r.current.Room
+	}
+	if !strings.EqualFold(next.Room, r.current.Room) {
+		sendLatest(r.rooms, next.Room)
+		logger.Debug("config reload: switching room", "room", next.Room)
+	}
+
+	r.current = next
+}
+
+// switchRoom asks the listener to move to room without touching the config
+// file; the next edit to the file's room setting takes over again.
+func (r *configReloader) switchRoom(room string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sendLatest(r.rooms, room)
+}
+
+// selectRoom saves room to the config file and switches to it. The saved
+// room becomes the current setting, so the reload of the file that follows
+// does not switch a second time.
+func (r *configReloader) selectRoom(room string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := saveConfigRoom(r.path, r.profile, room); err != nil {
+		return err
+	}
+	r.current.Room = room
+	sendLatest(r.rooms, room)
+	return nil
+}
+
+// renameRoom follows the configured room being renamed in the Sonos app
+// from from to to, saving the new name to the config file so the room is
+// still found after a restart. Another configured room is left alone.
+func (r *configReloader) renameRoom(from, to string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !strings.EqualFold(strings.TrimSpace(r.current.Room), strings.TrimSpace(from)) {
+		return
+	}
+	r.current.Room = to
+	if err := saveConfigRoom(r.path, r.profile, to); err != nil {
+		logger.Warn("save renamed room", "room", to, "err", err)
+		return
+	}
+	logger.Info("saved renamed room to config", "from", from, "to", to)
+}
+
+// room returns the configured room.
+func (r *configReloader) room() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.Room
+}
+
+// sendLatest replaces any value still waiting in ch with v. ch must have a
+// buffer of one and senders must not race each other.
+func sendLatest[T any](ch chan T, v T) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- v
+}
+
+// locateRoom discovers the device that coordinates room.
+func locateRoom(ctx context.Context, room string) (*sonos.Device, error) {
+	devices, err := discoverDevices(ctx, room)
+	if err != nil {
+		return nil, err
+	}
+	_, device := sonos.GatherRoomStatuses(ctx, devices, room)
+	if device == nil {
+		return nil, fmt.Errorf("no device matched room %q", room)
+	}
+	return device, nil
+}
+
+// listenRooms runs the event listener for room and moves it to another
+// room's device whenever rooms delivers a new name. The REST API, the setup
+// page, MQTT, and config reloads all switch rooms through that channel.
+// onRoom, when set, is called after each successful switch. It blocks until
+// ctx is canceled or the listener fails.
+func listenRooms(ctx context.Context, device sonos.Device, room string, opts sonos.ListenerOptions, rooms <-chan string, onRoom func(string, sonos.Device)) error {
+	// renamed carries the room's new name when it is renamed in the Sonos
+	// app, so a switch to the new name is not taken for another room.
+	renamed := make(chan string, 1)
+	onRenamed := opts.OnRoomRenamed
+	opts.OnRoomRenamed = func(from, to string) {
+		sendLatest(renamed, to)
+		if onRenamed != nil {
+			onRenamed(from, to)
+		}
+	}
+	for {
+		listenCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func(device sonos.Device, room string) {
+			done <- sonos.ListenForEvents(listenCtx, device, room, defaultCallbackPath, opts)
+		}(device, room)
+
+		next, found, err := awaitRoomSwitch(ctx, room, rooms, renamed, done)
+		cancel()
+		if found == nil {
+			return err
+		}
+		// Wait for the old listener to unsubscribe before the new one
+		// subscribes, so its last events cannot land on the new room.
+		if err := <-done; err != nil {
+			logger.Warn("stop listener failed", "room", room, "err", err)
+		}
+		select {
+		case <-renamed:
+		default:
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		device, room = *found, next
+		resetRoomState(ctx, opts, room)
+		if onRoom != nil {
+			onRoom(room, device)
+		}
+		logger.Info("switched room", "room", room)
+	}
+}
+
+// awaitRoomSwitch waits for a room to switch to and discovers its device
+// while the current listener keeps running, so a room that cannot be found
+// leaves the display untouched. It returns a nil device, with the listener's
+// result, when the listener stops first. renamed delivers the current room's
+// new name after a rename.
+func awaitRoomSwitch(ctx context.Context, current string, rooms <-chan string, renamed <-chan string, done <-chan error) (string, *sonos.Device, error) {
+	for {
+		select {
+		case err := <-done:
+			return "", nil, err
+		case name := <-renamed:
+			current = name
+		case next := <-rooms:
+			if strings.EqualFold(next, current) {
+				continue
+			}
+			found, err := locateRoom(ctx, next)
+			if err != nil {
+				logger.Warn("switch room failed; staying on current room", "room", next, "current", current, "err", err)
+				continue
+			}
+			return next, found, nil
+		}
+	}
+}
+
+// resetRoomState drops what is left of the previous room: the display clears
+// its artwork and status consumers see an empty status for the new room
+// until its first event, which the speaker sends as soon as the new
+// subscription is made.
+func resetRoomState(ctx context.Context, opts sonos.ListenerOptions, room string) {
+	if opts.Display != nil {
+		clearCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if err := sonos.ClearContext(clearCtx, opts.Display); err != nil {
+			logger.Warn("clear display for room switch failed", "err", err)
+		}
+		cancel()
+	}
+	if opts.OnStatus != nil {
+		opts.OnStatus(sonos.PlaybackStatus{Room: room})
+	}
+}
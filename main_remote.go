@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/diagnostics"
+	"musicDisplay/httpapi"
+	"musicDisplay/lyrics"
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/notify"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+	"musicDisplay/timer"
+	"musicDisplay/visualizer"
+)
+
+// remoteControl carries out commands from the control API and MQTT against
+// the running listener and display.
+type remoteControl struct {
+	reloader *configReloader
+	// output is the panel; nil when running without -display.
+	output outputDisplay
+	// artStorage is where Art looks for album art the listener cached.
+	artStorage sonos.ArtStorage
+
+	mu sync.Mutex
+	// display is the listener's display chain and controls its transport
+	// controls. Both are attached once the chain has been built.
+	display    sonos.Display
+	controls   *trackControls
+	brightness int
+	// largeText is the renderer, once there is one.
+	largeText largeTextToggle
+	// countdown is the timer shown over the renderer, once there is one.
+	countdown *timer.Timer
+	// diagnostics is the troubleshooting screen, once there is one.
+	diagnostics *diagnostics.Screen
+	// notifier shows notifications over the renderer, once there is one,
+	// and wake has the renderer draw a new one at once.
+	notifier *notify.Notifier
+	wake     func()
+	// wifi watches the Wi-Fi signal when its badge is enabled.
+	wifi *diagnostics.WiFiMonitor
+	// lyrics is the lyrics screen when lyrics are configured.
+	lyrics *lyrics.Screen
+	// visualizer is the visualizer screen when it is configured.
+	visualizer *visualizer.Screen
+}
+
+// largeTextToggle turns the renderer's large-text mode on and off.
+type largeTextToggle interface {
+	SetLargeText(on bool)
+	LargeText() bool
+}
+
+func newRemoteControl(reloader *configReloader, output outputDisplay, artStorage sonos.ArtStorage, brightness int) *remoteControl {
+	return &remoteControl{reloader: reloader, output: output, artStorage: artStorage, brightness: brightness}
+}
+
+// attachLargeText lets the remote toggle the renderer's large-text mode.
+func (c *remoteControl) attachLargeText(toggle largeTextToggle) {
+	c.mu.Lock()
+	c.largeText = toggle
+	c.mu.Unlock()
+}
+
+// attachTimer lets the remote start and stop the display's timer.
+func (c *remoteControl) attachTimer(countdown *timer.Timer) {
+	c.mu.Lock()
+	c.countdown = countdown
+	c.mu.Unlock()
+}
+
+// attachDiagnostics lets the remote show the diagnostics screen and feed
+// it the listener's health.
+func (c *remoteControl) attachDiagnostics(screen *diagnostics.Screen) {
+	c.mu.Lock()
+	c.diagnostics = screen
+	c.mu.Unlock()
+}
+
+// attachNotifier lets the remote show notifications through notifier,
+// calling wake after each so the renderer draws it.
+func (c *remoteControl) attachNotifier(notifier *notify.Notifier, wake func()) {
+	c.mu.Lock()
+	c.notifier, c.wake = notifier, wake
+	c.mu.Unlock()
+}
+
+// attachWiFi lets the remote report the Wi-Fi signal in its status.
+func (c *remoteControl) attachWiFi(wifi *diagnostics.WiFiMonitor) {
+	c.mu.Lock()
+	c.wifi = wifi
+	c.mu.Unlock()
+}
+
+// attachLyrics lets the remote turn the lyrics mode on and off.
+func (c *remoteControl) attachLyrics(screen *lyrics.Screen) {
+	c.mu.Lock()
+	c.lyrics = screen
+	c.mu.Unlock()
+}
+
+// attachVisualizer lets the remote turn the visualizer on and off.
+func (c *remoteControl) attachVisualizer(screen *visualizer.Screen) {
+	c.mu.Lock()
+	c.visualizer = screen
+	c.mu.Unlock()
+}
+
+// attach connects the remote to the finished display chain.
+func (c *remoteControl) attach(display sonos.Display, controls *trackControls) {
+	c.mu.Lock()
+	c.display = display
+	c.controls = controls
+	c.mu.Unlock()
+}
+
+func (c *remoteControl) Status() httpapi.Status {
+	c.mu.Lock()
+	controls, brightness, toggle, countdown, wifi := c.controls, c.brightness, c.largeText, c.countdown, c.wifi
+	lyricsScreen, visualizerScreen := c.lyrics, c.visualizer
+	c.mu.Unlock()
+	var status sonos.PlaybackStatus
+	if controls != nil {
+		status = controls.snapshot()
+	}
+	largeText := toggle != nil && toggle.LargeText()
+	var timerStatus *httpapi.Timer
+	if countdown != nil {
+		if t := countdown.Status(time.Now()); t.Mode != timer.ModeOff {
+			timerStatus = &httpapi.Timer{Mode: t.Mode, RemainingSeconds: t.Remaining.Seconds(), ElapsedSeconds: t.Elapsed.Seconds()}
+		}
+	}
+	var wifiDBm *int
+	if wifi != nil {
+		if signal, ok := wifi.Signal(); ok {
+			wifiDBm = &signal.DBm
+		}
+	}
+	return httpapi.Status{
+		Room:            status.Room,
+		State:           status.State,
+		Playing:         status.Playing,
+		Title:           status.Track.Title,
+		Artist:          status.Track.Artist,
+		Album:           status.Track.Album,
+		Source:          string(status.Track.Source),
+		Service:         status.Track.Service,
+		PositionSeconds: status.Track.Position.Seconds(),
+		DurationSeconds: status.Track.Duration.Seconds(),
+		Brightness:      brightness,
+		LargeText:       largeText,
+		Lyrics:          lyricsScreen != nil && lyricsScreen.Enabled(),
+		Visualizer:      visualizerScreen != nil && visualizerScreen.Enabled(),
+		Art:             httpapi.ArtPath(status.ArtKey),
+		Timer:           timerStatus,
+		WiFiDBm:         wifiDBm,
+	}
+}
+
+func (c *remoteControl) Clear() error {
+	display := c.chain()
+	if display == nil {
+		return httpapi.ErrNoDisplay
+	}
+	return display.Clear()
+}
+
+func (c *remoteControl) SetBrightness(level int) error {
+	if c.output == nil {
+		return httpapi.ErrNoDisplay
+	}
+	if err := c.output.SetBrightness(level); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.brightness = level
+	c.mu.Unlock()
+	return nil
+}
+
+// SetLargeText turns the renderer's large-text mode on or off.
+func (c *remoteControl) SetLargeText(on bool) error {
+	c.mu.Lock()
+	toggle := c.largeText
+	c.mu.Unlock()
+	if toggle == nil {
+		return httpapi.ErrNoDisplay
+	}
+	toggle.SetLargeText(on)
+	return nil
+}
+
+// SetDiagnostics shows or hides the diagnostics screen.
+func (c *remoteControl) SetDiagnostics(on bool) error {
+	screen := c.diagnosticsScreen()
+	if screen == nil {
+		return httpapi.ErrNoDisplay
+	}
+	if on {
+		screen.Show()
+	} else {
+		screen.Hide()
+	}
+	return nil
+}
+
+// SetLyrics turns the lyrics mode on or off.
+func (c *remoteControl) SetLyrics(on bool) error {
+	c.mu.Lock()
+	screen := c.lyrics
+	c.mu.Unlock()
+	if screen == nil {
+		if c.output == nil {
+			return httpapi.ErrNoDisplay
+		}
+		return fmt.Errorf("%w: lyrics need a lyrics section in the config", httpapi.ErrNotFound)
+	}
+	screen.SetEnabled(on)
+	return nil
+}
+
+// SetVisualizer turns the visualizer on or off.
+func (c *remoteControl) SetVisualizer(on bool) error {
+	c.mu.Lock()
+	screen := c.visualizer
+	c.mu.Unlock()
+	if screen == nil {
+		if c.output == nil {
+			return httpapi.ErrNoDisplay
+		}
+		return fmt.Errorf("%w: the visualizer needs a visualizer section in the config", httpapi.ErrNotFound)
+	}
+	screen.SetEnabled(on)
+	return nil
+}
+
+// toggleDiagnostics shows the diagnostics screen, or hides it when it is up.
+func (c *remoteControl) toggleDiagnostics() error {
+	screen := c.diagnosticsScreen()
+	if screen == nil {
+		return httpapi.ErrNoDisplay
+	}
+	screen.Toggle()
+	return nil
+}
+
+// updateHealth passes the listener's health on to the diagnostics screen.
+func (c *remoteControl) updateHealth(health sonos.ListenerHealth) {
+	if screen := c.diagnosticsScreen(); screen != nil {
+		screen.Update(health)
+	}
+}
+
+func (c *remoteControl) diagnosticsScreen() *diagnostics.Screen {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diagnostics
+}
+
+// Notify scrolls text across the display over whatever it shows.
+func (c *remoteControl) Notify(text string, d time.Duration, priority int) error {
+	c.mu.Lock()
+	notifier, wake := c.notifier, c.wake
+	c.mu.Unlock()
+	if notifier == nil {
+		return httpapi.ErrNoDisplay
+	}
+	err := notifier.Push(notify.Notification{Text: text, Duration: d, Priority: priority})
+	if errors.Is(err, notify.ErrQueueFull) {
+		return fmt.Errorf("%w: %w", httpapi.ErrBusy, err)
+	}
+	if err != nil {
+		return err
+	}
+	wake()
+	return nil
+}
+
+// StartTimer starts a countdown of d over the display.
+func (c *remoteControl) StartTimer(d time.Duration) error {
+	countdown := c.timer()
+	if countdown == nil {
+		return httpapi.ErrNoDisplay
+	}
+	return countdown.Start(d)
+}
+
+// StartStopwatch starts the stopwatch over the display.
+func (c *remoteControl) StartStopwatch() error {
+	countdown := c.timer()
+	if countdown == nil {
+		return httpapi.ErrNoDisplay
+	}
+	countdown.StartStopwatch()
+	return nil
+}
+
+// StopTimer stops the countdown or stopwatch.
+func (c *remoteControl) StopTimer() error {
+	countdown := c.timer()
+	if countdown == nil {
+		return httpapi.ErrNoDisplay
+	}
+	countdown.Stop()
+	return nil
+}
+
+func (c *remoteControl) timer() *timer.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.countdown
+}
+
+func (c *remoteControl) SwitchRoom(room string) error {
+	c.reloader.switchRoom(room)
+	return nil
+}
+
+// ShowImage shows img until the next track change, scaled to the panel.
+func (c *remoteControl) ShowImage(img image.Image) error {
+	display := c.chain()
+	if display == nil {
+		return httpapi.ErrNoDisplay
+	}
+	size := displaySize(c.output)
+	return display.Show(matrixdisplay.FitFrame(img, size.X, size.Y))
+}
+
+// Art serves album art the listener has already fetched; remote displays
+// share it rather than each asking the speaker.
+func (c *remoteControl) Art(signature string) (image.Image, error) {
+	img, err := sonos.CachedAlbumArt(signature, c.artStorage)
+	if errors.Is(err, sonos.ErrArtNotCached) {
+		return nil, httpapi.ErrNotFound
+	}
+	return img, err
+}
+
+// CurrentArtURL resolves the current track's album art against the room's
+// speaker, for the API to pass through.
+func (c *remoteControl) CurrentArtURL() (string, error) {
+	c.mu.Lock()
+	controls := c.controls
+	c.mu.Unlock()
+	if controls == nil {
+		return "", httpapi.ErrNotFound
+	}
+	track := controls.snapshot().Track
+	if strings.TrimSpace(track.AlbumArtURI) == "" {
+		return "", httpapi.ErrNotFound
+	}
+	return sonos.AlbumArtURL(controls.currentDevice(), track.AlbumArtURI)
+}
+
+// PurgeArt empties the album art cache in memory and on disk.
+func (c *remoteControl) PurgeArt() (httpapi.ArtPurge, error) {
+	result, err := sonos.PurgeArt(c.artStorage)
+	return httpapi.ArtPurge{Files: result.Files, Bytes: result.Bytes}, err
+}
+
+// paletteColors is how many album art colors GET /api/palette and the MQTT
+// state report.
+const paletteColors = 5
+
+// Palette extracts the colors of the current track's cached album art.
+func (c *remoteControl) Palette() (httpapi.Palette, error) {
+	c.mu.Lock()
+	controls := c.controls
+	c.mu.Unlock()
+	if controls == nil {
+		return httpapi.Palette{}, httpapi.ErrNotFound
+	}
+	key := controls.snapshot().ArtKey
+	img, err := c.Art(key)
+	if err != nil {
+		return httpapi.Palette{}, err
+	}
+	return httpapi.Palette{Art: httpapi.ArtPath(key), Colors: theme.Swatches(theme.ArtColors(img, paletteColors))}, nil
+}
+
+// PlayClip plays clip on the current room's speaker over its music.
+func (c *remoteControl) PlayClip(ctx context.Context, clip httpapi.Clip) error {
+	c.mu.Lock()
+	controls := c.controls
+	c.mu.Unlock()
+	if controls == nil {
+		return errors.New("no room connected yet")
+	}
+	err := sonos.PlayAudioClip(ctx, controls.currentDevice(), sonos.AudioClip{URL: clip.URL, Volume: clip.Volume, Priority: clip.Priority})
+	if errors.Is(err, sonos.ErrAudioClipUnsupported) {
+		return fmt.Errorf("%w: %w", httpapi.ErrUnsupported, err)
+	}
+	return err
+}
+
+// Favorites lists the household's Sonos favorites, asking the current
+// room's speaker.
+func (c *remoteControl) Favorites(ctx context.Context) ([]httpapi.Favorite, error) {
+	favorites, err := c.browseFavorites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]httpapi.Favorite, 0, len(favorites))
+	for i, favorite := range favorites {
+		list = append(list, httpapi.Favorite{Index: i, Title: favorite.Title, Description: favorite.Description})
+	}
+	return list, nil
+}
+
+// PlayFavorite starts the favorite numbered index in the current room.
+func (c *remoteControl) PlayFavorite(ctx context.Context, index int) error {
+	favorites, err := c.browseFavorites(ctx)
+	if err != nil {
+		return err
+	}
+	if index >= len(favorites) {
+		return fmt.Errorf("%w: favorite %d, there are %d", httpapi.ErrNotFound, index, len(favorites))
+	}
+	c.mu.Lock()
+	controls := c.controls
+	c.mu.Unlock()
+	favorite := favorites[index]
+	if err := sonos.PlayFavorite(ctx, controls.currentDevice(), favorite); err != nil {
+		return err
+	}
+	logger.Info("playing favorite", "title", favorite.Title)
+	return nil
+}
+
+// browseFavorites lists the favorites from the current room's speaker.
+func (c *remoteControl) browseFavorites(ctx context.Context) ([]sonos.Item, error) {
+	c.mu.Lock()
+	controls := c.controls
+	c.mu.Unlock()
+	if controls == nil {
+		return nil, errors.New("no room connected yet")
+	}
+	return sonos.BrowseFavorites(ctx, controls.currentDevice())
+}
+
+func (c *remoteControl) chain() sonos.Display {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.display
+}
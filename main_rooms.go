@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"musicDisplay/atomicfile"
+	"musicDisplay/httpapi"
+	"musicDisplay/sonos"
+)
+
+// Rooms lists every room on the network with what it is playing, for the
+// setup page. A stereo pair or bonded surround set is listed once.
+func (c *remoteControl) Rooms(ctx context.Context) ([]httpapi.Room, error) {
+	devices, err := discoverDevices(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	current := c.reloader.room()
+	if status := c.Status(); status.Room != "" {
+		current = status.Room
+	}
+	statuses, _ := sonos.GatherRoomStatuses(ctx, devices, "")
+	seen := make(map[string]bool, len(statuses))
+	rooms := make([]httpapi.Room, 0, len(statuses))
+	for _, status := range statuses {
+		key := strings.ToLower(status.Room)
+		if status.Room == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		rooms = append(rooms, httpapi.Room{
+			Name:    status.Room,
+			State:   status.State,
+			Track:   status.Track,
+			Current: strings.EqualFold(status.Room, current),
+		})
+	}
+	return rooms, nil
+}
+
+// SelectRoom saves room to the config file and switches to it.
+func (c *remoteControl) SelectRoom(room string) error {
+	return c.reloader.selectRoom(room)
+}
+
+// saveConfigRoom sets the room in the config file at path, creating the file
+// if needed. The room goes into profile when that profile sets one, since it
+// would override the top-level value, and at the top level otherwise. The
+// other settings are kept, though the file is rewritten with its keys sorted.
+func saveConfigRoom(path, profile, room string) error {
+	settings := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("save config: read %q: %w", path, err)
+	case len(bytes.TrimSpace(data)) > 0:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("save config: parse %q: %w", path, err)
+		}
+	}
+	value, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	if saved, err := setProfileRoom(settings, profile, value); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	} else if !saved {
+		settings["room"] = value
+	}
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	out = append(out, '\n')
+	if err := atomicfile.Write(path, out, 0o644); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	return nil
+}
+
+// setProfileRoom replaces the room of profile in settings, reporting whether
+// the profile had one to replace.
+func setProfileRoom(settings map[string]json.RawMessage, profile string, room json.RawMessage) (bool, error) {
+	if profile == "" || settings["profiles"] == nil {
+		return false, nil
+	}
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(settings["profiles"], &profiles); err != nil {
+		return false, fmt.Errorf("parse profiles: %w", err)
+	}
+	overrides, ok := profiles[profile]
+	if !ok || overrides["room"] == nil {
+		return false, nil
+	}
+	overrides["room"] = room
+	encoded, err := json.Marshal(profiles)
+	if err != nil {
+		return false, err
+	}
+	settings["profiles"] = encoded
+	return true, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/scene"
+	"musicDisplay/schedule"
+)
+
+type screenSetter interface {
+	SetScreen(screen string)
+}
+
+// buildScenes parses the configured scene rules into a manager, or returns
+// nil when neither the configuration nor any theme has scene rules.
+func buildScenes(cfg Config) (*scene.Manager, error) {
+	if len(cfg.Scenes) == 0 && !themesHaveScenes(cfg) {
+		return nil, nil
+	}
+	rules, err := parseSceneRules(cfg.Scenes)
+	if err != nil {
+		return nil, err
+	}
+	var coords *schedule.Coordinates
+	if cfg.Latitude != nil && cfg.Longitude != nil {
+		coords = &schedule.Coordinates{Latitude: *cfg.Latitude, Longitude: *cfg.Longitude}
+	}
+	return scene.NewManager(rules, coords)
+}
+
+// parseSceneRules parses configured scenes into rules.
+func parseSceneRules(scenes []SceneConfig) ([]scene.Rule, error) {
+	rules := make([]scene.Rule, 0, len(scenes))
+	for i, sc := range scenes {
+		rule, err := scene.Parse(sc.When, sc.Show)
+		if err != nil {
+			return nil, fmt.Errorf("scene %d: %w", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// themesHaveScenes reports whether any theme sets its own scene rules.
+func themesHaveScenes(cfg Config) bool {
+	for _, tc := range cfg.Themes {
+		if len(tc.Scenes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runScenes switches target to the screen chosen by the scene rules until
+// ctx is canceled.
+func runScenes(ctx context.Context, clk clock.Clock, scenes *scene.Manager, target screenSetter) {
+	if scenes == nil || target == nil {
+		return
+	}
+	scenes.Run(ctx, clk, func(show string, rule *scene.Rule) {
+		if rule == nil {
+			logger.Debug("scene ended")
+		} else {
+			logger.Debug("scene active", "when", rule.When, "show", show)
+		}
+		target.SetScreen(sceneScreen(show))
+	})
+}
+
+// sceneScreen maps a rule's screen onto the renderer's idle screens.
+func sceneScreen(show string) string {
+	switch show {
+	case scene.ShowClock:
+		return render.IdleClock
+	case scene.ShowBlank:
+		return render.IdleBlank
+	case scene.ShowAnimation:
+		return render.IdleAnimation
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"musicDisplay/script"
+)
+
+const (
+	scriptScreenOverlay = "overlay"
+	scriptScreenIdle    = "idle"
+)
+
+// loadedScripts are the Lua scripts from the config, split by where they
+// draw.
+type loadedScripts struct {
+	overlays []*script.Script
+	idle     *script.Script
+}
+
+// all returns every loaded script.
+func (l loadedScripts) all() []*script.Script {
+	scripts := append([]*script.Script(nil), l.overlays...)
+	if l.idle != nil {
+		scripts = append(scripts, l.idle)
+	}
+	return scripts
+}
+
+// close releases every loaded script.
+func (l loadedScripts) close() {
+	for _, s := range l.all() {
+		s.Close()
+	}
+}
+
+// loadScripts loads the configured scripts. A script that fails to load is
+// skipped with a warning so the others still run.
+func loadScripts(cfg Config) loadedScripts {
+	var loaded loadedScripts
+	for _, sc := range cfg.Scripts {
+		s, err := script.Load(strings.TrimSpace(sc.Path))
+		if err != nil {
+			logger.Warn("script disabled", "path", sc.Path, "err", err)
+			continue
+		}
+		if scriptScreen(sc) == scriptScreenIdle {
+			loaded.idle = s
+		} else {
+			loaded.overlays = append(loaded.overlays, s)
+		}
+		logger.Debug("script loaded", "path", sc.Path, "screen", scriptScreen(sc))
+	}
+	return loaded
+}
+
+// validateScripts checks the script entries and that each script loads.
+func validateScripts(cfg Config) error {
+	idle := 0
+	for i, sc := range cfg.Scripts {
+		if strings.TrimSpace(sc.Path) == "" {
+			return fmt.Errorf("scripts[%d]: path is required", i)
+		}
+		switch scriptScreen(sc) {
+		case scriptScreenOverlay:
+		case scriptScreenIdle:
+			idle++
+		default:
+			return fmt.Errorf("scripts[%d]: screen must be \"overlay\" or \"idle\", got %q", i, sc.Screen)
+		}
+		s, err := script.Load(strings.TrimSpace(sc.Path))
+		if err != nil {
+			return fmt.Errorf("scripts[%d]: %w", i, err)
+		}
+		s.Close()
+	}
+	if idle > 1 {
+		return fmt.Errorf("scripts: only one script may use the idle screen, got %d", idle)
+	}
+	if cfg.IdleScreen == "script" && idle == 0 {
+		return fmt.Errorf("idle_screen \"script\" requires a script with screen \"idle\"")
+	}
+	return nil
+}
+
+func scriptScreen(sc ScriptConfig) string {
+	screen := strings.ToLower(strings.TrimSpace(sc.Screen))
+	if screen == "" {
+		return scriptScreenOverlay
+	}
+	return screen
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"musicDisplay/framesink"
+	"musicDisplay/matrixdisplay"
+)
+
+// runSink drives the display selected by mode with frames pushed by another
+// instance, until ctx is canceled. It does no discovery of its own, which
+// suits a small board that only has to keep the matrix lit.
+func runSink(ctx context.Context, stdout io.Writer, addr string, mode displayMode, hw matrixdisplay.MatrixConfig, brightness int, simulatorAddr, frameDir string) error {
+	if mode == displayRemote {
+		return fmt.Errorf("a frame sink cannot forward to another sink")
+	}
+	display, err := openDisplay(stdout, mode, hw, brightness, simulatorAddr, frameDir, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := display.Close(); err != nil {
+			logger.Warn("close display", "err", err)
+		}
+	}()
+	size := displaySize(display)
+	server, err := framesink.Listen(addr, display, matrixdisplay.Geometry{Width: size.X, Height: size.Y})
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	fmt.Fprintf(stdout, "Frame sink listening on %s. Press Ctrl+C to exit.\n", server.Addr())
+	<-ctx.Done()
+	return nil
+}
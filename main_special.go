@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/schedule"
+)
+
+type specialSetter interface {
+	SetSpecial(special *render.Special)
+}
+
+// specialDay pairs a parsed date rule with the screen it enables.
+type specialDay struct {
+	rule    schedule.DateRule
+	special render.Special
+}
+
+// buildSpecialDays parses the configured special days. Earlier entries win
+// when several match the same date.
+func buildSpecialDays(cfg Config) ([]specialDay, error) {
+	days := make([]specialDay, 0, len(cfg.SpecialDays))
+	for i, sc := range cfg.SpecialDays {
+		name := strings.TrimSpace(sc.Name)
+		if name == "" {
+			name = fmt.Sprintf("special day %d", i+1)
+		}
+		rule, err := schedule.ParseDateRule(sc.Dates)
+		if err != nil {
+			return nil, fmt.Errorf("special day %q dates: %w", name, err)
+		}
+
+		special := render.Special{
+			Name:    name,
+			Banner:  strings.TrimSpace(sc.Banner),
+			Overlay: sc.Overlay,
+		}
+		switch sc.Scene {
+		case render.SceneNone, render.SceneSnow, render.SceneConfetti:
+			special.Scene = sc.Scene
+		default:
+			return nil, fmt.Errorf("special day %q scene must be \"snow\" or \"confetti\", got %q", name, sc.Scene)
+		}
+		if special.Banner == "" && special.Scene == render.SceneNone {
+			return nil, fmt.Errorf("special day %q needs a banner or a scene", name)
+		}
+		if special.Overlay && special.Banner == "" {
+			return nil, fmt.Errorf("special day %q overlay needs a banner", name)
+		}
+		if err := parseThemeColor(sc.Color, &special.Color); err != nil {
+			return nil, fmt.Errorf("special day %q color: %w", name, err)
+		}
+		days = append(days, specialDay{rule: rule, special: special})
+	}
+	return days, nil
+}
+
+// activeSpecial returns the first special day matching now, or nil.
+func activeSpecial(days []specialDay, now time.Time) *render.Special {
+	for i := range days {
+		if days[i].rule.Matches(now) {
+			special := days[i].special
+			return &special
+		}
+	}
+	return nil
+}
+
+// runSpecialDays updates target with the special screen for the current date,
+// re-evaluating after each midnight. It blocks until ctx is canceled.
+func runSpecialDays(ctx context.Context, clk clock.Clock, days []specialDay, target specialSetter) {
+	if len(days) == 0 || target == nil {
+		return
+	}
+
+	activeName := ""
+	schedule.Run(ctx, clk, func(now time.Time) (time.Time, bool) {
+		special := activeSpecial(days, now)
+		name := ""
+		if special != nil {
+			name = special.Name
+		}
+		if name != activeName {
+			activeName = name
+			target.SetSpecial(special)
+			if special != nil {
+				logger.Debug("special day active", "name", name)
+			} else {
+				logger.Debug("special day ended")
+			}
+		}
+		return schedule.NextMidnight(now), true
+	})
+}
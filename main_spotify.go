@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+	"musicDisplay/spotify"
+)
+
+const (
+	defaultSpotifyPoll   = 10 * time.Second
+	spotifyRequestWindow = 8 * time.Second
+)
+
+// statusDisplay is a display that also accepts playback status updates, such
+// as render.Renderer.
+type statusDisplay interface {
+	sonos.Display
+	UpdateStatus(sonos.PlaybackStatus)
+}
+
+// spotifyFallback sits between the Sonos listener and the renderer. While the
+// room is idle it polls the Spotify account and shows whatever is playing
+// there, badged so it is clear the music is not coming from Sonos. Sonos
+// playback always takes precedence.
+type spotifyFallback struct {
+	client *spotify.Client
+	out    statusDisplay
+	poll   time.Duration
+	// artSize is the edge of the square frames the fallback draws, and
+	// artProcessing how their artwork is scaled.
+	artSize       int
+	artProcessing sonos.ArtProcessing
+
+	mu          sync.Mutex
+	room        string
+	sonosActive bool
+	showing     bool
+	itemID      string
+	playback    spotify.Playback
+	sampledAt   time.Time
+}
+
+func newSpotifyFallback(client *spotify.Client, out statusDisplay, room string, poll time.Duration, artSize int, artProcessing sonos.ArtProcessing) *spotifyFallback {
+	if poll <= 0 {
+		poll = defaultSpotifyPoll
+	}
+	return &spotifyFallback{client: client, out: out, room: room, poll: poll, artSize: artSize, artProcessing: artProcessing}
+}
+
+// Show forwards Sonos artwork and suspends the fallback.
+func (f *spotifyFallback) Show(img image.Image) error {
+	return f.ShowContext(context.Background(), img)
+}
+
+// ShowContext is Show with ctx passed on to the display. It implements
+// sonos.ContextDisplay.
+func (f *spotifyFallback) ShowContext(ctx context.Context, img image.Image) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sonosActive = true
+	f.showing = false
+	f.itemID = ""
+	return sonos.ShowContext(ctx, f.out, img)
+}
+
+// Clear is called when the Sonos room goes idle. The idle screen is shown
+// until the next poll finds something playing on Spotify.
+func (f *spotifyFallback) Clear() error {
+	return f.ClearContext(context.Background())
+}
+
+// ClearContext is Clear with ctx passed on to the display. It implements
+// sonos.ContextDisplay.
+func (f *spotifyFallback) ClearContext(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sonosActive = false
+	if f.showing {
+		return nil
+	}
+	return sonos.ClearContext(ctx, f.out)
+}
+
+// Close closes the display.
+func (f *spotifyFallback) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.out.Close()
+}
+
+// setRoom changes the Sonos room whose own playback is left to the listener.
+func (f *spotifyFallback) setRoom(room string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.room = room
+}
+
+// current returns the Spotify playback on screen. It is nil-safe so callers
+// need not check whether the fallback is configured.
+func (f *spotifyFallback) current() (spotify.Playback, bool) {
+	if f == nil {
+		return spotify.Playback{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.playback, f.showing
+}
+
+// SetDimmed forwards the listener's dimming to the renderer.
+func (f *spotifyFallback) SetDimmed(dimmed bool) error {
+	if dimmer, ok := f.out.(sonos.Dimmer); ok {
+		return dimmer.SetDimmed(dimmed)
+	}
+	return nil
+}
+
+// UpdateStatus forwards Sonos status, except idle updates that would otherwise
+// overwrite the Spotify track while the fallback is on screen.
+func (f *spotifyFallback) UpdateStatus(status sonos.PlaybackStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if status.Playing {
+		f.sonosActive = true
+		f.showing = false
+		f.itemID = ""
+	} else if f.showing {
+		return
+	}
+	f.out.UpdateStatus(status)
+}
+
+// Run polls Spotify while the room is idle and keeps the progress of a shown
+// Spotify track moving. It blocks until ctx is canceled.
+func (f *spotifyFallback) Run(ctx context.Context) {
+	pollTimer := time.NewTimer(0)
+	defer pollTimer.Stop()
+	progress := time.NewTicker(time.Second)
+	defer progress.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pollTimer.C:
+			f.check(ctx)
+			pollTimer.Reset(f.poll)
+		case <-progress.C:
+			f.mu.Lock()
+			if f.showing {
+				f.publish()
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+// check polls the account once and switches the fallback on or off.
+func (f *spotifyFallback) check(ctx context.Context) {
+	f.mu.Lock()
+	active := f.sonosActive
+	currentID := f.itemID
+	room := f.room
+	f.mu.Unlock()
+	if active {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, spotifyRequestWindow)
+	defer cancel()
+	pb, ok, err := f.client.CurrentPlayback(reqCtx)
+	if err != nil {
+		logger.Warn("spotify fallback", "err", err)
+		return
+	}
+	if !ok || !pb.Playing || strings.EqualFold(strings.TrimSpace(pb.Device), room) {
+		// Playback on the Sonos room itself is reported by the listener.
+		f.stop()
+		return
+	}
+
+	var art image.Image
+	if pb.ID != currentID {
+		art = f.loadArt(reqCtx, pb)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sonosActive {
+		return
+	}
+	if art != nil {
+		if err := f.out.Show(art); err != nil {
+			logger.Warn("spotify fallback display", "err", err)
+			return
+		}
+		f.itemID = pb.ID
+		logger.Debug("showing Spotify playback", "device", pb.Device, "artist", pb.Artist, "title", pb.Title)
+	}
+	f.showing = true
+	f.playback = pb
+	f.sampledAt = time.Now()
+	f.publish()
+}
+
+// stop returns to the idle screen if the fallback was showing.
+func (f *spotifyFallback) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.showing || f.sonosActive {
+		return
+	}
+	f.showing = false
+	f.itemID = ""
+	if err := f.out.Clear(); err != nil {
+		logger.Warn("spotify fallback clear", "err", err)
+	}
+}
+
+// loadArt downloads and badges the artwork for pb, falling back to a blank
+// badged frame so the track still shows without art.
+func (f *spotifyFallback) loadArt(ctx context.Context, pb spotify.Playback) image.Image {
+	frame := image.NewRGBA(image.Rect(0, 0, f.artSize, f.artSize))
+	draw.Draw(frame, frame.Bounds(), image.Black, image.Point{}, draw.Src)
+	if pb.ArtURL != "" {
+		data, err := f.client.FetchImage(ctx, pb.ArtURL)
+		if err == nil {
+			var art image.Image
+			art, err = sonos.ProcessAlbumArt(data, f.artSize, f.artProcessing)
+			if err == nil {
+				draw.Draw(frame, frame.Bounds(), art, art.Bounds().Min, draw.Src)
+			}
+		}
+		if err != nil {
+			logger.Warn("spotify album art", "err", err)
+		}
+	}
+	overlay.SpotifyBadge(frame)
+	return frame
+}
+
+// publish sends the Spotify track, with an interpolated position, to the
+// renderer. Callers must hold f.mu.
+func (f *spotifyFallback) publish() {
+	track := sonos.TrackInfo{
+		Title:    f.playback.Title,
+		Artist:   f.playback.Artist,
+		Album:    f.playback.Album,
+		State:    "PLAYING",
+		Position: f.playback.Position + time.Since(f.sampledAt),
+		Duration: f.playback.Duration,
+	}
+	if track.Duration > 0 && track.Position > track.Duration {
+		track.Position = track.Duration
+	}
+	f.out.UpdateStatus(sonos.PlaybackStatus{
+		Room:    "Spotify: " + f.playback.Device,
+		State:   "PLAYING",
+		Track:   track,
+		Playing: true,
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/sdnotify"
+	"musicDisplay/sonos"
+)
+
+// defaultStartupRetry is how often a room not found at startup is looked
+// for again.
+const defaultStartupRetry = 30 * time.Second
+
+func (c *StartupConfig) validate() error {
+	if c.RetrySeconds < 0 || c.RetrySeconds > 3600 {
+		return fmt.Errorf("retry_seconds must be between 0 and 3600, got %d", c.RetrySeconds)
+	}
+	return nil
+}
+
+// waits reports whether startup waits for a room that was not found.
+func (c *StartupConfig) waits() bool {
+	return c != nil && c.WaitForSpeakers
+}
+
+// retryInterval returns how often a room not found yet is looked for.
+func (c *StartupConfig) retryInterval() time.Duration {
+	if c == nil || c.RetrySeconds == 0 {
+		return defaultStartupRetry
+	}
+	return time.Duration(c.RetrySeconds) * time.Second
+}
+
+// waitForRoom looks for room every interval until one of its speakers
+// answers, as after a router reboot, and returns its device and the room.
+// A room switch on rooms changes the room looked for. Under systemd the
+// service is reported ready while it waits, and its watchdog is fed from
+// its own goroutine, so a slow search does not count as a hang. ok is
+// false once ctx is canceled.
+func waitForRoom(ctx context.Context, clk clock.Clock, room string, interval time.Duration, rooms <-chan string, systemd bool) (device sonos.Device, found string, ok bool) {
+	if systemd {
+		notifySystemd(sdnotify.Ready)
+		notifySystemd(sdnotify.Status("Waiting for " + room))
+		if every, ok := sdnotify.WatchdogInterval(); ok {
+			done := make(chan struct{})
+			defer close(done)
+			go feedWatchdog(clk, every/2, done)
+		}
+	}
+	logger.Warn("room not found; looking again until it is", "room", room, "retry_in", interval)
+	retry := clk.NewTimer(interval)
+	defer retry.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return sonos.Device{}, "", false
+		case next := <-rooms:
+			room = next
+			logger.Info("waiting for another room", "room", room)
+			if systemd {
+				notifySystemd(sdnotify.Status("Waiting for " + room))
+			}
+		case <-retry.C():
+		}
+		located, err := locateRoom(ctx, room)
+		if err == nil {
+			logger.Info("room found", "room", room, "ip", located.IP)
+			return *located, room, true
+		}
+		if ctx.Err() != nil {
+			return sonos.Device{}, "", false
+		}
+		logger.Debug("room still not found", "room", room, "retry_in", interval, "err", err)
+		retry.Reset(interval)
+	}
+}
+
+// feedWatchdog pings the systemd watchdog every interval until done is
+// closed.
+func feedWatchdog(clk clock.Clock, interval time.Duration, done <-chan struct{}) {
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+			notifySystemd(sdnotify.Watchdog)
+		}
+	}
+}
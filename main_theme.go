@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/scene"
+	"musicDisplay/schedule"
+	"musicDisplay/theme"
+)
+
+type brightnessSetter interface {
+	SetBrightness(level int) error
+}
+
+type sceneRuleSetter interface {
+	SetRules(rules []scene.Rule) error
+}
+
+// buildThemeSchedule converts the configured themes into a schedule. It
+// returns nil when no themes are configured.
+func buildThemeSchedule(cfg Config) (*theme.Schedule, error) {
+	if len(cfg.Themes) == 0 {
+		return nil, nil
+	}
+
+	// Once any theme has its own scenes, every theme carries the rules to
+	// switch to, falling back to the top-level ones.
+	var defaultScenes []scene.Rule
+	withScenes := themesHaveScenes(cfg)
+	if withScenes {
+		rules, err := parseSceneRules(cfg.Scenes)
+		if err != nil {
+			return nil, err
+		}
+		defaultScenes = rules
+	}
+
+	var coords *schedule.Coordinates
+	if cfg.Latitude != nil && cfg.Longitude != nil {
+		coords = &schedule.Coordinates{Latitude: *cfg.Latitude, Longitude: *cfg.Longitude}
+	}
+
+	variants := make([]theme.Variant, 0, len(cfg.Themes))
+	for i, tc := range cfg.Themes {
+		name := strings.TrimSpace(tc.Name)
+		if name == "" {
+			name = fmt.Sprintf("theme %d", i+1)
+		}
+		start, err := schedule.ParseTimeOfDay(tc.Start)
+		if err != nil {
+			return nil, fmt.Errorf("theme %q start: %w", name, err)
+		}
+
+		palette := theme.DefaultPalette
+		if err := parseThemeColor(tc.TextColor, &palette.Text); err != nil {
+			return nil, fmt.Errorf("theme %q text_color: %w", name, err)
+		}
+		if err := parseThemeColor(tc.AccentColor, &palette.Accent); err != nil {
+			return nil, fmt.Errorf("theme %q accent_color: %w", name, err)
+		}
+		if err := parseThemeColor(tc.BackgroundColor, &palette.Background); err != nil {
+			return nil, fmt.Errorf("theme %q background_color: %w", name, err)
+		}
+
+		t := theme.Theme{Name: name, Palette: palette, IdleScreen: tc.IdleScreen}
+		if tc.Brightness != nil {
+			t.Brightness = *tc.Brightness
+		}
+		if withScenes {
+			t.Scenes = defaultScenes
+			if len(tc.Scenes) > 0 {
+				rules, err := parseSceneRules(tc.Scenes)
+				if err != nil {
+					return nil, fmt.Errorf("theme %q %w", name, err)
+				}
+				for _, rule := range rules {
+					if rule.Solar() && coords == nil {
+						return nil, fmt.Errorf("theme %q scenes: sunrise and sunset need latitude and longitude", name)
+					}
+				}
+				t.Scenes = rules
+			}
+		}
+		variants = append(variants, theme.Variant{Theme: t, Start: start})
+	}
+
+	sched, err := theme.NewSchedule(variants, coords)
+	if err != nil {
+		return nil, err
+	}
+	return &sched, nil
+}
+
+// parseThemeColor overwrites dst with value when value is non-empty.
+func parseThemeColor(value string, dst *color.RGBA) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	parsed, err := theme.ParseColor(value)
+	if err != nil {
+		return err
+	}
+	*dst = parsed
+	return nil
+}
+
+// runThemeScheduler keeps current in sync with the schedule, applying theme
+// brightness to display and theme scene rules to scenes whenever the active
+// theme changes. It blocks until ctx is canceled.
+func runThemeScheduler(ctx context.Context, clk clock.Clock, sched *theme.Schedule, current *theme.Current, display brightnessSetter, scenes sceneRuleSetter) {
+	if sched == nil {
+		return
+	}
+
+	var applied theme.Theme
+	first := true
+	schedule.Run(ctx, clk, func(now time.Time) (time.Time, bool) {
+		active := sched.Active(now)
+		if first || !active.Equal(applied) {
+			first = false
+			applied = active
+			current.Store(active)
+			logger.Debug("theme active", "theme", active.Name)
+			if display != nil && active.Brightness > 0 {
+				if err := display.SetBrightness(active.Brightness); err != nil {
+					logger.Warn("apply theme brightness", "err", err)
+				}
+			}
+			if scenes != nil && active.Scenes != nil {
+				if err := scenes.SetRules(active.Scenes); err != nil {
+					logger.Warn("apply theme scenes", "err", err)
+				}
+			}
+		}
+		return sched.NextChange(now)
+	})
+}
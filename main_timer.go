@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/httpapi"
+	"musicDisplay/timer"
+)
+
+// chimeTimeout bounds playing the chime when a timer runs out.
+const chimeTimeout = 10 * time.Second
+
+// buildTimer returns the countdown timer and stopwatch shown over the
+// display, sounding the configured chime on remote's room when a countdown
+// runs out.
+func buildTimer(cfg Config, remote *remoteControl) *timer.Timer {
+	tc := cfg.Timer
+	if tc == nil {
+		tc = &TimerConfig{}
+	}
+	var onDone func()
+	if tc.Chime {
+		clip := httpapi.Clip{URL: tc.ChimeURL, Volume: tc.ChimeVolume, Priority: "high"}
+		onDone = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), chimeTimeout)
+			defer cancel()
+			if err := remote.PlayClip(ctx, clip); err != nil {
+				logger.Warn("play timer chime", "err", err)
+			}
+		}
+	}
+	return timer.New(clock.Real, time.Duration(tc.AlarmSeconds)*time.Second, onDone)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"musicDisplay/weather"
+)
+
+// weatherCoordinates returns the weather section's location, falling back
+// to the top-level latitude and longitude.
+func weatherCoordinates(cfg Config) (float64, float64, bool) {
+	wc := cfg.Weather
+	switch {
+	case wc.Latitude != nil && wc.Longitude != nil:
+		return *wc.Latitude, *wc.Longitude, true
+	case cfg.Latitude != nil && cfg.Longitude != nil:
+		return *cfg.Latitude, *cfg.Longitude, true
+	}
+	return 0, 0, false
+}
+
+// buildWeather returns the weather source and screen for the config, or nils
+// when no weather section is set.
+func buildWeather(cfg Config) (*weather.Source, *weather.Screen) {
+	if cfg.Weather == nil {
+		return nil, nil
+	}
+	wc := cfg.Weather
+	latitude, longitude, _ := weatherCoordinates(cfg)
+	var provider weather.Provider
+	switch weatherProvider(wc) {
+	case weather.ProviderOpenWeatherMap:
+		provider = weather.NewOpenWeatherMap(strings.TrimSpace(wc.APIKey), latitude, longitude, wc.Units)
+	default:
+		provider = weather.NewOpenMeteo(latitude, longitude, wc.Units)
+	}
+	source := weather.NewSource(provider, time.Duration(wc.RefreshMinutes)*time.Minute)
+	return source, weather.NewScreen(source)
+}
+
+// validateWeather checks the weather section and that the weather screen is
+// only chosen when it is configured.
+func validateWeather(cfg Config) error {
+	if cfg.Weather == nil {
+		if usesScreen(cfg, weather.ScreenName) {
+			return fmt.Errorf("the weather screen requires a weather section")
+		}
+		return nil
+	}
+	wc := cfg.Weather
+	switch weatherProvider(wc) {
+	case weather.ProviderOpenMeteo:
+	case weather.ProviderOpenWeatherMap:
+		if strings.TrimSpace(wc.APIKey) == "" {
+			return fmt.Errorf("weather: provider %q requires api_key", weather.ProviderOpenWeatherMap)
+		}
+	default:
+		return fmt.Errorf("weather: provider must be %q or %q, got %q", weather.ProviderOpenMeteo, weather.ProviderOpenWeatherMap, wc.Provider)
+	}
+	if (wc.Latitude == nil) != (wc.Longitude == nil) {
+		return fmt.Errorf("weather: latitude and longitude must be set together")
+	}
+	latitude, longitude, ok := weatherCoordinates(cfg)
+	if !ok {
+		return fmt.Errorf("weather: latitude and longitude are required, in the weather section or at the top level")
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("weather: latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+	switch wc.Units {
+	case "", weather.Metric, weather.Imperial:
+	default:
+		return fmt.Errorf("weather: units must be %q or %q, got %q", weather.Metric, weather.Imperial, wc.Units)
+	}
+	if wc.RefreshMinutes < 0 || (wc.RefreshMinutes > 0 && wc.RefreshMinutes < 5) {
+		return fmt.Errorf("weather: refresh_minutes must be at least 5, got %d", wc.RefreshMinutes)
+	}
+	return nil
+}
+
+func weatherProvider(wc *WeatherConfig) string {
+	provider := strings.ToLower(strings.TrimSpace(wc.Provider))
+	if provider == "" {
+		return weather.ProviderOpenMeteo
+	}
+	return provider
+}
+
+// usesScreen reports whether the idle screen, a theme, or the rotation
+// chooses the screen called name.
+func usesScreen(cfg Config, name string) bool {
+	if cfg.IdleScreen == name {
+		return true
+	}
+	for _, t := range cfg.Themes {
+		if t.IdleScreen == name {
+			return true
+		}
+	}
+	return cfg.Rotation != nil && slices.Contains(cfg.Rotation.Screens, name)
+}
@@ -0,0 +1,124 @@
+package matrixdisplay
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+const (
+	// maxAnimationFrames bounds the frames kept from one GIF; every frame is
+	// held fitted to the display, so long clips would use a lot of memory.
+	maxAnimationFrames = 500
+	// minFrameDelay is the shortest frame delay honored. Like browsers,
+	// GIFs asking for less get defaultFrameDelay instead.
+	minFrameDelay     = 20 * time.Millisecond
+	defaultFrameDelay = 100 * time.Millisecond
+)
+
+// Frame is one image of an animation and how long it stays on screen.
+type Frame struct {
+	Image *image.RGBA
+	Delay time.Duration
+}
+
+// DecodeGIF decodes every frame of a GIF, applies each frame's disposal so
+// partial frames composite correctly, and fits the results to geometry. A
+// still GIF yields a single frame.
+func DecodeGIF(r io.Reader, geometry Geometry) ([]Frame, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("matrixdisplay: decode gif: %w", err)
+	}
+	if len(g.Image) == 0 {
+		return nil, fmt.Errorf("matrixdisplay: gif has no frames")
+	}
+	if len(g.Image) > maxAnimationFrames {
+		return nil, fmt.Errorf("matrixdisplay: gif has %d frames, at most %d are supported", len(g.Image), maxAnimationFrames)
+	}
+
+	screen := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if screen.Empty() {
+		screen = image.Rectangle{}
+		for _, img := range g.Image {
+			screen = screen.Union(img.Bounds())
+		}
+	}
+	geometry = geometry.OrDefault()
+	canvas := image.NewRGBA(screen)
+	frames := make([]Frame, 0, len(g.Image))
+	for i, img := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(screen)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		frames = append(frames, Frame{
+			Image: FitFrame(canvas, geometry.Width, geometry.Height),
+			Delay: gifDelay(g.Delay, i),
+		})
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, nil
+}
+
+// gifDelay returns frame i's delay; GIF delays are in hundredths of a second.
+func gifDelay(delays []int, i int) time.Duration {
+	if i >= len(delays) {
+		return defaultFrameDelay
+	}
+	delay := time.Duration(delays[i]) * 10 * time.Millisecond
+	if delay < minFrameDelay {
+		return defaultFrameDelay
+	}
+	return delay
+}
+
+// PlayAnimation shows frames on out in a loop, each for its delay, until ctx
+// is canceled. A single frame is shown once and held. It returns nil when
+// ctx ends and the first error from out otherwise.
+func PlayAnimation(ctx context.Context, out interface{ Show(image.Image) error }, frames []Frame) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("matrixdisplay: animation has no frames")
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for i := 0; ; i = (i + 1) % len(frames) {
+		if err := out.Show(frames[i].Image); err != nil {
+			return fmt.Errorf("matrixdisplay: show animation frame %d: %w", i, err)
+		}
+		if len(frames) == 1 {
+			<-ctx.Done()
+			return nil
+		}
+		timer.Reset(frames[i].Delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// ShowAnimation plays frames on the matrix until ctx is canceled. See
+// PlayAnimation.
+func (c *Controller) ShowAnimation(ctx context.Context, frames []Frame) error {
+	return PlayAnimation(ctx, c, frames)
+}
@@ -0,0 +1,82 @@
+package matrixdisplay
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestDecodeGIFCompositesFrames(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}, color.RGBA{B: 0xff, A: 0xff}}
+	full := image.NewPaletted(image.Rect(0, 0, 8, 8), pal)
+	for i := range full.Pix {
+		full.Pix[i] = 1
+	}
+	// The second frame only repaints one corner; the rest must stay red.
+	corner := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+	for i := range corner.Pix {
+		corner.Pix[i] = 2
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{full, corner},
+		Delay:    []int{5, 0},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+		Config:   image.Config{Width: 8, Height: 8, ColorModel: pal},
+	})
+	if err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+
+	frames, err := DecodeGIF(&buf, Geometry{Width: 16, Height: 16})
+	if err != nil {
+		t.Fatalf("DecodeGIF: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if frames[0].Delay != 50*time.Millisecond || frames[1].Delay != defaultFrameDelay {
+		t.Fatalf("delays = %v, %v; want 50ms and the default for 0", frames[0].Delay, frames[1].Delay)
+	}
+	second := frames[1].Image
+	if second.Bounds().Dx() != 16 {
+		t.Fatalf("frame width %d, want it fitted to 16", second.Bounds().Dx())
+	}
+	if got := second.RGBAAt(0, 0); got.B != 0xff {
+		t.Fatalf("corner = %v, want blue", got)
+	}
+	if got := second.RGBAAt(12, 12); got.R != 0xff {
+		t.Fatalf("rest of frame = %v, want the first frame's red kept", got)
+	}
+}
+
+type countingDisplay struct {
+	shown  int
+	cancel context.CancelFunc
+}
+
+func (d *countingDisplay) Show(image.Image) error {
+	d.shown++
+	if d.shown == 5 {
+		d.cancel()
+	}
+	return nil
+}
+
+func TestPlayAnimationLoopsUntilCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &countingDisplay{cancel: cancel}
+	frame := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	frames := []Frame{{Image: frame, Delay: time.Millisecond}, {Image: frame, Delay: time.Millisecond}}
+	if err := PlayAnimation(ctx, out, frames); err != nil {
+		t.Fatalf("PlayAnimation: %v", err)
+	}
+	if out.shown != 5 {
+		t.Fatalf("showed %d frames, want the loop to run until canceled after 5", out.shown)
+	}
+}
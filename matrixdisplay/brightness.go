@@ -0,0 +1,73 @@
+package matrixdisplay
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// maxPanelBrightness bounds the multipliers of MatrixConfig.PanelBrightness.
+const maxPanelBrightness = 2
+
+// ApplyBrightness returns a copy of img with every channel scaled to level
+// percent (1..100). Levels outside that range leave the colors unchanged.
+func ApplyBrightness(img image.Image, level int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	if level <= 0 || level >= 100 {
+		return dst
+	}
+
+	var table [256]uint8
+	for i := range table {
+		table[i] = uint8(i * level / 100)
+	}
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i] = table[dst.Pix[i]]
+		dst.Pix[i+1] = table[dst.Pix[i+1]]
+		dst.Pix[i+2] = table[dst.Pix[i+2]]
+	}
+	return dst
+}
+
+// validatePanelBrightness checks that c.PanelBrightness, when set, has a
+// usable multiplier for every panel of the chains.
+func (c MatrixConfig) validatePanelBrightness() error {
+	if len(c.PanelBrightness) == 0 {
+		return nil
+	}
+	if want := c.ChainLength * c.Parallel; len(c.PanelBrightness) != want {
+		return fmt.Errorf("matrixdisplay: panel brightness must list all %d panels of the chains, got %d", want, len(c.PanelBrightness))
+	}
+	for i, gain := range c.PanelBrightness {
+		if gain <= 0 || gain > maxPanelBrightness {
+			return fmt.Errorf("matrixdisplay: panel %d: brightness must be above 0 and at most %d, got %g", i, maxPanelBrightness, gain)
+		}
+	}
+	return nil
+}
+
+// CompensatePanels scales each panel's slot of canvas, the driver canvas
+// returned by MapPanels, by its PanelBrightness multiplier, in place. It
+// expects defaults to have been applied.
+func (c MatrixConfig) CompensatePanels(canvas *image.RGBA) {
+	for i, gain := range c.PanelBrightness {
+		if gain == 1 {
+			continue
+		}
+		var table [256]uint8
+		for v := range table {
+			table[v] = uint8(min(float64(v)*gain+0.5, 255))
+		}
+		slot := c.panelSlot(i).Intersect(canvas.Bounds())
+		for y := slot.Min.Y; y < slot.Max.Y; y++ {
+			row := canvas.Pix[canvas.PixOffset(slot.Min.X, y):canvas.PixOffset(slot.Max.X, y)]
+			for x := 0; x < len(row); x += 4 {
+				row[x] = table[row[x]]
+				row[x+1] = table[row[x+1]]
+				row[x+2] = table[row[x+2]]
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package matrixdisplay
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ScanProgressive = "progressive"
+	ScanInterlaced  = "interlaced"
+
+	defaultHardwareMapping = "adafruit-hat-pwm"
+	defaultPWMBits         = 11
+	defaultPWMLSBNanos     = 130
+)
+
+// MatrixConfig describes the attached panel hardware. Zero fields take the
+// defaults for a single 64x64 panel on an Adafruit RGB Matrix Bonnet.
+type MatrixConfig struct {
+	// Rows and Cols are the dimensions of a single panel.
+	Rows int
+	Cols int
+	// ChainLength is the number of panels daisy-chained on each output.
+	ChainLength int
+	// Parallel is the number of parallel chains (1..3).
+	Parallel int
+	// HardwareMapping names the GPIO mapping, e.g. "regular",
+	// "adafruit-hat", or "adafruit-hat-pwm".
+	HardwareMapping string
+	// PWMBits trades color depth (1..11) for refresh rate.
+	PWMBits int
+	// PWMLSBNanoseconds is the on-time of the lowest PWM bit.
+	PWMLSBNanoseconds int
+	// Slowdown is the GPIO slowdown factor needed by faster Pis. The Go
+	// bindings cannot pass it to the driver, so it is accepted for
+	// completeness but has no effect.
+	Slowdown int
+	// ScanMode is ScanProgressive or ScanInterlaced.
+	ScanMode string
+	// Panels, when set, arranges the chained panels into a video wall, one
+	// placement per panel, so a single composed frame spans them. Without it
+	// the panels sit side by side in chain order.
+	Panels []PanelPlacement
+	// PanelBrightness, when set, multiplies the brightness of each panel, in
+	// chain order, to even out panels from different batches. 1 leaves a
+	// panel unchanged; values up to 2 lift a dim panel, clipping at full
+	// brightness.
+	PanelBrightness []float64
+}
+
+// WithDefaults returns c with zero fields filled in.
+func (c MatrixConfig) WithDefaults() MatrixConfig {
+	if c.Rows <= 0 {
+		c.Rows = PanelHeight
+	}
+	if c.Cols <= 0 {
+		c.Cols = PanelWidth
+	}
+	if c.ChainLength <= 0 {
+		c.ChainLength = 1
+	}
+	if c.Parallel <= 0 {
+		c.Parallel = 1
+	}
+	if strings.TrimSpace(c.HardwareMapping) == "" {
+		c.HardwareMapping = defaultHardwareMapping
+	}
+	if c.PWMBits <= 0 {
+		c.PWMBits = defaultPWMBits
+	}
+	if c.PWMLSBNanoseconds <= 0 {
+		c.PWMLSBNanoseconds = defaultPWMLSBNanos
+	}
+	if c.ScanMode == "" {
+		c.ScanMode = ScanProgressive
+	}
+	return c
+}
+
+// Validate reports settings the driver cannot use. It expects defaults to
+// have been applied.
+func (c MatrixConfig) Validate() error {
+	switch c.Rows {
+	case 8, 16, 32, 64:
+	default:
+		return fmt.Errorf("matrixdisplay: rows must be 8, 16, 32, or 64, got %d", c.Rows)
+	}
+	if c.Cols < 8 || c.Cols > 128 {
+		return fmt.Errorf("matrixdisplay: cols must be between 8 and 128, got %d", c.Cols)
+	}
+	if c.ChainLength > 8 {
+		return fmt.Errorf("matrixdisplay: chain length must be between 1 and 8, got %d", c.ChainLength)
+	}
+	if c.Parallel > 3 {
+		return fmt.Errorf("matrixdisplay: parallel must be between 1 and 3, got %d", c.Parallel)
+	}
+	if c.PWMBits > 11 {
+		return fmt.Errorf("matrixdisplay: pwm bits must be between 1 and 11, got %d", c.PWMBits)
+	}
+	if c.Slowdown < 0 || c.Slowdown > 4 {
+		return fmt.Errorf("matrixdisplay: slowdown must be between 0 and 4, got %d", c.Slowdown)
+	}
+	switch c.ScanMode {
+	case ScanProgressive, ScanInterlaced:
+	default:
+		return fmt.Errorf("matrixdisplay: scan mode must be %q or %q, got %q", ScanProgressive, ScanInterlaced, c.ScanMode)
+	}
+	if err := c.validatePanels(); err != nil {
+		return err
+	}
+	return c.validatePanelBrightness()
+}
+
+// Size returns the pixel dimensions of the whole display surface: the frame
+// the panels span when Panels is set, or the chains side by side otherwise.
+func (c MatrixConfig) Size() (width, height int) {
+	c = c.WithDefaults()
+	if len(c.Panels) > 0 {
+		return c.wallSize()
+	}
+	return c.chainSize()
+}
+
+// Geometry returns the display surface of c as a Geometry.
+func (c MatrixConfig) Geometry() Geometry {
+	width, height := c.Size()
+	return Geometry{Width: width, Height: height}
+}
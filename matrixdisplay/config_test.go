@@ -0,0 +1,135 @@
+package matrixdisplay
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestMatrixConfigDefaultsAndSize(t *testing.T) {
+	cfg := MatrixConfig{}.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+	if w, h := cfg.Size(); w != 64 || h != 64 {
+		t.Fatalf("default size = %dx%d, want 64x64", w, h)
+	}
+	chained := MatrixConfig{Rows: 64, Cols: 64, ChainLength: 2}
+	if w, h := chained.Size(); w != 128 || h != 64 {
+		t.Fatalf("chained size = %dx%d, want 128x64", w, h)
+	}
+	for _, bad := range []MatrixConfig{{Rows: 48}, {Parallel: 4}, {ScanMode: "zigzag"}, {PWMBits: 12}} {
+		if err := bad.WithDefaults().Validate(); err == nil {
+			t.Fatalf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestFitFrameCentersArtOnWidePanels(t *testing.T) {
+	art := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(art, art.Bounds(), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+
+	wide := FitFrame(art, 128, 64)
+	if got := wide.RGBAAt(10, 32); got != (color.RGBA{A: 255}) {
+		t.Fatalf("left margin = %+v, want black", got)
+	}
+	if got := wide.RGBAAt(64, 32); got.R != 255 {
+		t.Fatalf("center = %+v, want art", got)
+	}
+
+	small := FitFrame(art, 32, 32)
+	if small.Bounds().Dx() != 32 || small.RGBAAt(0, 0).R != 255 || small.RGBAAt(31, 31).R != 255 {
+		t.Fatalf("32x32 frame not fully covered by art")
+	}
+}
+
+func TestMapPanelsSpansFrameAcrossWall(t *testing.T) {
+	cfg := MatrixConfig{
+		Rows: 8, Cols: 16, ChainLength: 2,
+		Panels: []PanelPlacement{{X: 0, Y: 0}, {X: 0, Y: 8, Rotate: 180}},
+	}.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if w, h := cfg.Size(); w != 16 || h != 16 {
+		t.Fatalf("wall size = %dx%d, want 16x16", w, h)
+	}
+	frame := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	marker := color.RGBA{G: 255, A: 255}
+	frame.SetRGBA(1, 2, marker)
+	frame.SetRGBA(3, 12, marker)
+
+	canvas := cfg.MapPanels(frame)
+	if b := canvas.Bounds(); b.Dx() != 32 || b.Dy() != 8 {
+		t.Fatalf("canvas = %v, want 32x8", b)
+	}
+	if canvas.RGBAAt(1, 2) != marker {
+		t.Fatal("upright panel marker missing")
+	}
+	// Frame pixel 3,12 is 3,4 within the upside-down panel, which shows it
+	// at 15-3,7-4 of its chain slot.
+	if canvas.RGBAAt(16+12, 3) != marker {
+		t.Fatal("rotated panel marker not turned back")
+	}
+
+	sideways := MatrixConfig{Rows: 8, Cols: 16, Panels: []PanelPlacement{{Rotate: 90}}}.WithDefaults()
+	if w, h := sideways.Size(); w != 8 || h != 16 {
+		t.Fatalf("sideways size = %dx%d, want 8x16", w, h)
+	}
+	upright := image.NewRGBA(image.Rect(0, 0, 8, 16))
+	upright.SetRGBA(7, 0, marker)
+	if sideways.MapPanels(upright).RGBAAt(0, 0) != marker {
+		t.Fatal("top-right of the frame should reach the first pixel of a panel turned clockwise")
+	}
+
+	for _, bad := range []MatrixConfig{
+		{ChainLength: 2, Panels: []PanelPlacement{{}}},
+		{ChainLength: 2, Panels: []PanelPlacement{{}, {X: 32}}},
+		{Panels: []PanelPlacement{{Rotate: 45}}},
+	} {
+		if err := bad.WithDefaults().Validate(); err == nil {
+			t.Fatalf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCompensatePanelsScalesEachPanel(t *testing.T) {
+	cfg := MatrixConfig{Rows: 8, Cols: 8, ChainLength: 2, PanelBrightness: []float64{1, 0.5}}.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 100, B: 10, A: 255}), image.Point{}, draw.Src)
+	cfg.CompensatePanels(canvas)
+	if got := canvas.RGBAAt(3, 3); got != (color.RGBA{R: 200, G: 100, B: 10, A: 255}) {
+		t.Fatalf("first panel = %+v, want unchanged", got)
+	}
+	if got := canvas.RGBAAt(12, 3); got != (color.RGBA{R: 100, G: 50, B: 5, A: 255}) {
+		t.Fatalf("second panel = %+v, want halved", got)
+	}
+
+	for _, bad := range []MatrixConfig{
+		{ChainLength: 2, PanelBrightness: []float64{1}},
+		{PanelBrightness: []float64{0}},
+		{PanelBrightness: []float64{2.5}},
+	} {
+		if err := bad.WithDefaults().Validate(); err == nil {
+			t.Fatalf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestGeometryFollowsMatrixConfig(t *testing.T) {
+	wide := MatrixConfig{ChainLength: 2}.Geometry()
+	if wide != (Geometry{Width: 128, Height: 64}) || wide.ArtSize() != 64 {
+		t.Fatalf("chained geometry = %+v, art %d; want 128x64, art 64", wide, wide.ArtSize())
+	}
+	small := MatrixConfig{Rows: 32, Cols: 32}.Geometry()
+	if small.ArtSize() != 32 || small.Bounds() != image.Rect(0, 0, 32, 32) {
+		t.Fatalf("small geometry = %+v, want 32x32", small)
+	}
+	if (Geometry{}).OrDefault() != DefaultGeometry || (Geometry{}).ArtSize() != PanelWidth {
+		t.Fatal("zero geometry should mean a single default panel")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"sync"
 
 	rgbmatrix "github.com/mcuadros/go-rpi-rgb-led-matrix"
 )
@@ -14,27 +15,57 @@ import (
 const defaultBrightness = 60
 
 // Controller manages a HUB75 RGB LED matrix and provides helpers to display
-// 64x64 images on the panel.
+// images on the panel. Frames that do not match the configured size are
+// scaled to fit.
+//
+// The Go bindings do not expose the driver's runtime brightness control, so the
+// panel runs at full hardware brightness and the configured level is applied by
+// scaling pixel values before each render.
 type Controller struct {
 	matrix rgbmatrix.Matrix
 	canvas *rgbmatrix.Canvas
+	cfg    MatrixConfig
+
+	width  int
+	height int
+
+	mu         sync.Mutex
+	brightness int
+	frame      *image.RGBA
+	// rendered is the hash of the frame on the panel, valid while
+	// hasRendered is set.
+	rendered    uint64
+	hasRendered bool
+	closed      bool
 }
 
-// NewController initializes the LED matrix and clears the display. Call Close
-// when finished to release resources.
-func NewController(brightness int) (*Controller, error) {
+// NewController initializes the LED matrix described by cfg and clears the
+// display. Call Close when finished to release resources.
+func NewController(cfg MatrixConfig, brightness int) (*Controller, error) {
 	if brightness <= 0 || brightness > 100 {
 		brightness = defaultBrightness
 	}
+	cfg = cfg.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Slowdown > 0 {
+		logger.Warn("gpio slowdown ignored: the matrix bindings cannot set it", "slowdown", cfg.Slowdown)
+	}
 
 	config := rgbmatrix.DefaultConfig
-	config.Rows = PanelHeight
-	config.Cols = PanelWidth
-	config.ChainLength = 1
-	config.Parallel = 1
-	config.Brightness = brightness
-	// Force the GPIO mapping expected by the Adafruit RGB Matrix Bonnet.
-	config.HardwareMapping = "adafruit-hat-pwm"
+	config.Rows = cfg.Rows
+	config.Cols = cfg.Cols
+	config.ChainLength = cfg.ChainLength
+	config.Parallel = cfg.Parallel
+	config.PWMBits = cfg.PWMBits
+	config.PWMLSBNanoseconds = cfg.PWMLSBNanoseconds
+	config.Brightness = 100
+	config.HardwareMapping = cfg.HardwareMapping
+	config.ScanMode = rgbmatrix.Progressive
+	if cfg.ScanMode == ScanInterlaced {
+		config.ScanMode = rgbmatrix.Interlaced
+	}
 
 	matrix, err := rgbmatrix.NewRGBLedMatrix(&config)
 	if err != nil {
@@ -43,38 +74,54 @@ func NewController(brightness int) (*Controller, error) {
 
 	canvas := rgbmatrix.NewCanvas(matrix)
 
+	width, height := cfg.Size()
 	ctrl := &Controller{
-		matrix: matrix,
-		canvas: canvas,
+		matrix:     matrix,
+		canvas:     canvas,
+		cfg:        cfg,
+		width:      width,
+		height:     height,
+		brightness: brightness,
 	}
 
 	if err := ctrl.Clear(); err != nil {
 		_ = ctrl.Close()
 		return nil, err
 	}
+	logger.Debug("matrix initialized", "width", width, "height", height, "mapping", cfg.HardwareMapping)
 
 	return ctrl, nil
 }
 
-// Show renders the supplied 64x64 image on the matrix.
+// Show renders the supplied image on the matrix, scaling it to fit when its
+// size differs from the configured geometry.
 func (c *Controller) Show(img image.Image) error {
 	if img == nil {
 		return fmt.Errorf("matrixdisplay: nil image")
 	}
-	bounds := img.Bounds()
-	if bounds.Dx() != PanelWidth || bounds.Dy() != PanelHeight {
-		return fmt.Errorf("matrixdisplay: image dimensions must be %dx%d, got %dx%d", PanelWidth, PanelHeight, bounds.Dx(), bounds.Dy())
+	if img.Bounds().Empty() {
+		return fmt.Errorf("matrixdisplay: empty image")
 	}
+	frame := FitFrame(img, c.width, c.height)
 
-	draw.Draw(c.canvas, c.canvas.Bounds(), img, bounds.Min, draw.Src)
-	if err := c.canvas.Render(); err != nil {
-		return fmt.Errorf("matrixdisplay: render image: %w", err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
 	}
-	return nil
+	c.frame = frame
+	return c.render()
 }
 
 // Clear turns off all pixels on the matrix.
 func (c *Controller) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.frame = nil
+	c.hasRendered = false
 	draw.Draw(c.canvas, c.canvas.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
 	if err := c.canvas.Render(); err != nil {
 		return fmt.Errorf("matrixdisplay: clear display: %w", err)
@@ -82,7 +129,61 @@ func (c *Controller) Clear() error {
 	return nil
 }
 
-// Close clears the display and releases the underlying resources.
+// SetBrightness changes the panel brightness (1..100) and redraws the current
+// frame at the new level.
+func (c *Controller) SetBrightness(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("matrixdisplay: brightness must be between 1 and 100, got %d", level)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if level == c.brightness {
+		return nil
+	}
+	c.brightness = level
+	if c.frame == nil {
+		return nil
+	}
+	return c.render()
+}
+
+// render draws the current frame at the current brightness. It skips the
+// panel update when the result matches what is already shown, which is common
+// when repeated events redraw the same artwork. Callers must hold c.mu.
+func (c *Controller) render() error {
+	scaled := ApplyBrightness(c.frame, c.brightness)
+	hash := FrameHash(scaled)
+	if c.hasRendered && hash == c.rendered {
+		return nil
+	}
+	canvas := c.cfg.MapPanels(scaled)
+	c.cfg.CompensatePanels(canvas)
+	draw.Draw(c.canvas, c.canvas.Bounds(), canvas, image.Point{}, draw.Src)
+	if err := c.canvas.Render(); err != nil {
+		c.hasRendered = false
+		return fmt.Errorf("matrixdisplay: render image: %w", err)
+	}
+	c.rendered, c.hasRendered = hash, true
+	return nil
+}
+
+// Close clears the display and releases the underlying resources. Later
+// calls do nothing, and drawing afterwards fails with ErrClosed rather than
+// touching the freed driver.
 func (c *Controller) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
 	return c.canvas.Close()
 }
+
+// Size returns the pixel dimensions of the display surface.
+func (c *Controller) Size() (width, height int) {
+	return c.width, c.height
+}
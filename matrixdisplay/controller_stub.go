@@ -11,7 +11,7 @@ import (
 type Controller struct{}
 
 // NewController always returns an error on unsupported platforms.
-func NewController(int) (*Controller, error) {
+func NewController(MatrixConfig, int) (*Controller, error) {
 	return nil, errors.New("matrixdisplay: RGB LED matrix output is only supported on linux")
 }
 
@@ -25,7 +25,17 @@ func (c *Controller) Clear() error {
 	return errors.New("matrixdisplay: clear not supported on this platform")
 }
 
+// SetBrightness is a no-op that reports the unsupported platform.
+func (c *Controller) SetBrightness(int) error {
+	return errors.New("matrixdisplay: set brightness not supported on this platform")
+}
+
 // Close is a no-op that reports the unsupported platform.
 func (c *Controller) Close() error {
 	return errors.New("matrixdisplay: close not supported on this platform")
 }
+
+// Size reports the default panel size.
+func (c *Controller) Size() (width, height int) {
+	return PanelWidth, PanelHeight
+}
@@ -1,6 +1,46 @@
 package matrixdisplay
 
+import (
+	"errors"
+	"image"
+)
+
 const (
 	PanelWidth  = 64
 	PanelHeight = 64
 )
+
+// ErrClosed is returned when a closed controller is asked to draw.
+var ErrClosed = errors.New("matrixdisplay: controller closed")
+
+// Geometry is the pixel size of the display surface frames are composed for.
+// Album art processing, the renderer, and every output backend take their
+// size from the same Geometry, so 32x32 panels and 128x64 chains need no
+// special cases.
+type Geometry struct {
+	Width, Height int
+}
+
+// DefaultGeometry is a single 64x64 panel.
+var DefaultGeometry = Geometry{Width: PanelWidth, Height: PanelHeight}
+
+// OrDefault returns g, or DefaultGeometry when g has no area.
+func (g Geometry) OrDefault() Geometry {
+	if g.Width <= 0 || g.Height <= 0 {
+		return DefaultGeometry
+	}
+	return g
+}
+
+// Bounds returns the frame rectangle of g at the origin.
+func (g Geometry) Bounds() image.Rectangle {
+	g = g.OrDefault()
+	return image.Rect(0, 0, g.Width, g.Height)
+}
+
+// ArtSize returns the edge of square album art for g: its shorter side, so
+// art fills a square panel and the full height of a wide chain.
+func (g Geometry) ArtSize() int {
+	g = g.OrDefault()
+	return min(g.Width, g.Height)
+}
@@ -0,0 +1,33 @@
+package matrixdisplay
+
+import (
+	"image"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// FitFrame copies img into a width x height frame. Images of a different size
+// are scaled to fit while keeping their aspect ratio and centered on black,
+// so square artwork works on smaller panels and wider chains.
+func FitFrame(img image.Image, width, height int) *image.RGBA {
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		draw.Draw(frame, frame.Bounds(), img, bounds.Min, draw.Src)
+		return frame
+	}
+
+	draw.Draw(frame, frame.Bounds(), image.Black, image.Point{}, draw.Src)
+	if bounds.Empty() {
+		return frame
+	}
+	w, h := width, bounds.Dy()*width/bounds.Dx()
+	if h > height {
+		w, h = bounds.Dx()*height/bounds.Dy(), height
+	}
+	x0 := (width - w) / 2
+	y0 := (height - h) / 2
+	xdraw.ApproxBiLinear.Scale(frame, image.Rect(x0, y0, x0+w, y0+h), img, bounds, xdraw.Src, nil)
+	return frame
+}
@@ -0,0 +1,25 @@
+package matrixdisplay
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"image"
+)
+
+// FrameHash returns a hash of frame's size and pixels. Two frames with the
+// same hash look the same on the panel, which lets callers skip redundant
+// renders.
+func FrameHash(frame *image.RGBA) uint64 {
+	h := fnv.New64a()
+	bounds := frame.Bounds()
+	var size [8]byte
+	binary.LittleEndian.PutUint32(size[:4], uint32(bounds.Dx()))
+	binary.LittleEndian.PutUint32(size[4:], uint32(bounds.Dy()))
+	h.Write(size[:])
+	rowBytes := bounds.Dx() * 4
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		start := frame.PixOffset(bounds.Min.X, y)
+		h.Write(frame.Pix[start : start+rowBytes])
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,30 @@
+package matrixdisplay
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFrameHash(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	b := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if FrameHash(a) != FrameHash(b) {
+		t.Fatalf("identical frames hash differently")
+	}
+
+	b.SetRGBA(10, 20, color.RGBA{G: 1, A: 255})
+	if FrameHash(a) == FrameHash(b) {
+		t.Fatalf("frames differing in one pixel hash the same")
+	}
+
+	wide := image.NewRGBA(image.Rect(0, 0, 128, 32))
+	if FrameHash(a) == FrameHash(wide) {
+		t.Fatalf("frames of different sizes hash the same")
+	}
+
+	sub := image.NewRGBA(image.Rect(0, 0, 128, 64)).SubImage(image.Rect(64, 0, 128, 64)).(*image.RGBA)
+	if FrameHash(a) != FrameHash(sub) {
+		t.Fatalf("sub-image hash differs from an equal standalone frame")
+	}
+}
@@ -0,0 +1,65 @@
+package matrixdisplay
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// SimulateGamut returns a copy of img as the matrix shows it at the given
+// brightness (1..100) with pwmBits of color depth, for previewing on a
+// monitor. Like the controller it scales every channel by brightness; like
+// the driver it then maps each channel through the CIE 1931 lightness curve
+// and truncates it to one of 2^pwmBits duty levels. The result is mapped back
+// to lightness so the monitor shows what the eye sees: near-black shades
+// collapse and gradients band exactly as they will on the panel. pwmBits
+// outside 1..11 uses the driver default.
+func SimulateGamut(img image.Image, pwmBits, brightness int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	table := gamutTable(pwmBits, brightness)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i] = table[dst.Pix[i]]
+		dst.Pix[i+1] = table[dst.Pix[i+1]]
+		dst.Pix[i+2] = table[dst.Pix[i+2]]
+	}
+	return dst
+}
+
+// gamutTable maps every channel value to its simulated appearance.
+func gamutTable(pwmBits, brightness int) [256]uint8 {
+	if pwmBits < 1 || pwmBits > 11 {
+		pwmBits = defaultPWMBits
+	}
+	if brightness <= 0 || brightness > 100 {
+		brightness = 100
+	}
+	levels := float64(int(1)<<pwmBits - 1)
+	var table [256]uint8
+	for c := range table {
+		scaled := c * brightness / 100
+		duty := math.Floor(levels * cieLuminance(float64(scaled)*100/255))
+		lightness := cieLightness(duty / levels)
+		table[c] = uint8(math.Round(lightness * 255 / 100))
+	}
+	return table
+}
+
+// cieLuminance converts CIE lightness (0..100) to relative luminance (0..1),
+// the correction the driver applies before PWM.
+func cieLuminance(lightness float64) float64 {
+	if lightness <= 8 {
+		return lightness / 902.3
+	}
+	return math.Pow((lightness+16)/116, 3)
+}
+
+// cieLightness is the inverse of cieLuminance.
+func cieLightness(luminance float64) float64 {
+	if luminance*902.3 <= 8 {
+		return luminance * 902.3
+	}
+	return 116*math.Cbrt(luminance) - 16
+}
@@ -0,0 +1,31 @@
+package matrixdisplay
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSimulateGamutMatchesPanelDepth(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 128, B: 2, A: 0xff})
+	img.SetRGBA(1, 0, color.RGBA{R: 20, G: 21, B: 22, A: 0xff})
+
+	full := SimulateGamut(img, 11, 100)
+	if got := full.RGBAAt(0, 0); got.R != 255 || got.G < 126 || got.G > 130 {
+		t.Fatalf("11-bit full brightness = %v, want colors kept", got)
+	}
+
+	low := SimulateGamut(img, 3, 100)
+	if got := low.RGBAAt(0, 0); got.B != 0 {
+		t.Fatalf("3-bit near-black blue = %d, want it crushed to 0", got.B)
+	}
+	if got := low.RGBAAt(1, 0); got.R != got.G || got.G != got.B {
+		t.Fatalf("3-bit dark greys = %v, want them banded into one level", got)
+	}
+
+	dim := SimulateGamut(img, 11, 50)
+	if got := dim.RGBAAt(0, 0).R; got < 125 || got > 129 {
+		t.Fatalf("half brightness white = %d, want about 127", got)
+	}
+}
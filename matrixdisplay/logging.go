@@ -0,0 +1,5 @@
+package matrixdisplay
+
+import "musicDisplay/logging"
+
+var logger = logging.For("matrixdisplay")
@@ -0,0 +1,120 @@
+package matrixdisplay
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// PanelPlacement positions one physical panel of a video wall within the
+// composed frame. Panels are listed in driver order: along the first chain,
+// then along the next parallel chain.
+type PanelPlacement struct {
+	// X and Y are the top-left corner of the part of the frame the panel
+	// shows.
+	X, Y int
+	// Rotate is how far the panel is mounted turned clockwise, in degrees
+	// (0, 90, 180, or 270). Its part of the frame is turned back so the
+	// picture stays upright; at 90 and 270 it is Rows wide and Cols tall.
+	Rotate int
+}
+
+// region returns the part of the frame shown by a rows x cols panel at p.
+func (p PanelPlacement) region(rows, cols int) image.Rectangle {
+	w, h := cols, rows
+	if p.Rotate == 90 || p.Rotate == 270 {
+		w, h = rows, cols
+	}
+	return image.Rect(p.X, p.Y, p.X+w, p.Y+h)
+}
+
+// validatePanels checks that c.Panels, when set, places every panel of the
+// chains once, inside the frame and without overlapping.
+func (c MatrixConfig) validatePanels() error {
+	if len(c.Panels) == 0 {
+		return nil
+	}
+	if want := c.ChainLength * c.Parallel; len(c.Panels) != want {
+		return fmt.Errorf("matrixdisplay: panels must place all %d panels of the chains, got %d", want, len(c.Panels))
+	}
+	for i, p := range c.Panels {
+		switch p.Rotate {
+		case 0, 90, 180, 270:
+		default:
+			return fmt.Errorf("matrixdisplay: panel %d: rotate must be 0, 90, 180, or 270, got %d", i, p.Rotate)
+		}
+		if p.X < 0 || p.Y < 0 {
+			return fmt.Errorf("matrixdisplay: panel %d: position must not be negative, got %d,%d", i, p.X, p.Y)
+		}
+		r := p.region(c.Rows, c.Cols)
+		for j, q := range c.Panels[:i] {
+			if r.Overlaps(q.region(c.Rows, c.Cols)) {
+				return fmt.Errorf("matrixdisplay: panel %d overlaps panel %d", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// chainSize returns the size of the driver's canvas: the panels side by
+// side in chain order, one row per parallel chain.
+func (c MatrixConfig) chainSize() (width, height int) {
+	return c.Cols * c.ChainLength, c.Rows * c.Parallel
+}
+
+// panelSlot returns where the i-th panel, in chain order, sits on the
+// driver canvas.
+func (c MatrixConfig) panelSlot(i int) image.Rectangle {
+	origin := image.Pt((i%c.ChainLength)*c.Cols, (i/c.ChainLength)*c.Rows)
+	return image.Rect(0, 0, c.Cols, c.Rows).Add(origin)
+}
+
+// wallSize returns the size of the frame covered by c.Panels.
+func (c MatrixConfig) wallSize() (width, height int) {
+	var bounds image.Rectangle
+	for _, p := range c.Panels {
+		bounds = bounds.Union(p.region(c.Rows, c.Cols))
+	}
+	return bounds.Max.X, bounds.Max.Y
+}
+
+// MapPanels cuts frame, sized as Size reports, into the panels placed by
+// c.Panels and returns the driver canvas image with each panel's part in its
+// chain slot. Without placements frame is returned as it is. It expects
+// defaults to have been applied.
+func (c MatrixConfig) MapPanels(frame *image.RGBA) *image.RGBA {
+	if len(c.Panels) == 0 {
+		return frame
+	}
+	width, height := c.chainSize()
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, p := range c.Panels {
+		slot := c.panelSlot(i).Min
+		src := p.region(c.Rows, c.Cols).Add(frame.Bounds().Min)
+		if p.Rotate == 0 {
+			draw.Draw(canvas, c.panelSlot(i), frame, src.Min, draw.Src)
+			continue
+		}
+		for y := 0; y < c.Rows; y++ {
+			for x := 0; x < c.Cols; x++ {
+				sx, sy := rotateBack(p.Rotate, x, y, c.Cols, c.Rows)
+				canvas.SetRGBA(slot.X+x, slot.Y+y, frame.RGBAAt(src.Min.X+sx, src.Min.Y+sy))
+			}
+		}
+	}
+	return canvas
+}
+
+// rotateBack returns the frame position, relative to a panel's region, of
+// pixel x,y of a cols x rows panel mounted turned clockwise by degrees.
+func rotateBack(degrees, x, y, cols, rows int) (int, int) {
+	switch degrees {
+	case 90:
+		return rows - 1 - y, x
+	case 180:
+		return cols - 1 - x, rows - 1 - y
+	case 270:
+		return y, cols - 1 - x
+	}
+	return x, y
+}
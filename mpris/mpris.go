@@ -0,0 +1,285 @@
+// Package mpris publishes the displayed room as an MPRIS media player on the
+// D-Bus session bus, so desktop tools such as playerctl can see and control
+// what the wall is showing.
+package mpris
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"musicDisplay/sonos"
+)
+
+const (
+	objectPath      = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootInterface   = "org.mpris.MediaPlayer2"
+	playerInterface = "org.mpris.MediaPlayer2.Player"
+	busNamePrefix   = "org.mpris.MediaPlayer2."
+	noTrack         = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+
+	// commandTimeout bounds each transport command sent on behalf of a
+	// D-Bus caller.
+	commandTimeout = 5 * time.Second
+)
+
+// Playback status values defined by the MPRIS specification.
+const (
+	StatusPlaying = "Playing"
+	StatusPaused  = "Paused"
+	StatusStopped = "Stopped"
+)
+
+// Handler carries out transport commands received over D-Bus.
+type Handler interface {
+	Play(ctx context.Context) error
+	Pause(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Next(ctx context.Context) error
+	Previous(ctx context.Context) error
+}
+
+// Server is an MPRIS player registered on the session bus.
+type Server struct {
+	conn    *dbus.Conn
+	props   *prop.Properties
+	handler Handler
+
+	mu     sync.Mutex
+	status string
+}
+
+// New connects to the session bus and registers the player as
+// org.mpris.MediaPlayer2.<name>.
+func New(name, identity string, handler Handler) (*Server, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("mpris: connect session bus: %w", err)
+	}
+	s, err := newServer(conn, name, identity, handler)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func newServer(conn *dbus.Conn, name, identity string, handler Handler) (*Server, error) {
+	s := &Server{conn: conn, handler: handler, status: StatusStopped}
+
+	if err := conn.Export(root{identity: identity}, objectPath, rootInterface); err != nil {
+		return nil, fmt.Errorf("mpris: export %s: %w", rootInterface, err)
+	}
+	if err := conn.ExportWithMap(player{s}, playerMethodNames, objectPath, playerInterface); err != nil {
+		return nil, fmt.Errorf("mpris: export %s: %w", playerInterface, err)
+	}
+
+	props, err := prop.Export(conn, objectPath, prop.Map{
+		rootInterface: {
+			"CanQuit":             {Value: false, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Emit: prop.EmitConst},
+			"Identity":            {Value: identity, Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{}, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitConst},
+		},
+		playerInterface: {
+			"PlaybackStatus": {Value: StatusStopped, Emit: prop.EmitTrue},
+			"LoopStatus":     {Value: "None", Emit: prop.EmitTrue},
+			"Rate":           {Value: 1.0, Emit: prop.EmitTrue},
+			"Shuffle":        {Value: false, Emit: prop.EmitTrue},
+			"Metadata":       {Value: Metadata(sonos.PlaybackStatus{}), Emit: prop.EmitTrue},
+			"Volume":         {Value: 1.0, Emit: prop.EmitTrue},
+			// Position changes continuously, so per the specification it is
+			// polled rather than announced.
+			"Position":      {Value: int64(0), Emit: prop.EmitFalse},
+			"MinimumRate":   {Value: 1.0, Emit: prop.EmitConst},
+			"MaximumRate":   {Value: 1.0, Emit: prop.EmitConst},
+			"CanGoNext":     {Value: true, Emit: prop.EmitConst},
+			"CanGoPrevious": {Value: true, Emit: prop.EmitConst},
+			"CanPlay":       {Value: true, Emit: prop.EmitConst},
+			"CanPause":      {Value: true, Emit: prop.EmitConst},
+			"CanSeek":       {Value: false, Emit: prop.EmitConst},
+			"CanControl":    {Value: true, Emit: prop.EmitConst},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mpris: export properties: %w", err)
+	}
+	s.props = props
+
+	node := &introspect.Node{
+		Name: string(objectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{Name: rootInterface, Methods: introspect.Methods(root{}), Properties: props.Introspection(rootInterface)},
+			{Name: playerInterface, Methods: playerMethods(), Properties: props.Introspection(playerInterface)},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, fmt.Errorf("mpris: export introspection: %w", err)
+	}
+
+	reply, err := conn.RequestName(busNamePrefix+name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("mpris: request name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("mpris: bus name %s%s is already taken", busNamePrefix, name)
+	}
+	return s, nil
+}
+
+// Update publishes the room's playback status.
+func (s *Server) Update(status sonos.PlaybackStatus) {
+	playback := PlaybackStatus(status)
+	metadata := Metadata(status)
+
+	s.mu.Lock()
+	s.status = playback
+	s.mu.Unlock()
+
+	s.props.SetMust(playerInterface, "Position", status.Track.Position.Microseconds())
+	if current, ok := s.props.GetMust(playerInterface, "PlaybackStatus").(string); !ok || current != playback {
+		s.props.SetMust(playerInterface, "PlaybackStatus", playback)
+	}
+	if !sameMetadata(s.props.GetMust(playerInterface, "Metadata"), metadata) {
+		s.props.SetMust(playerInterface, "Metadata", metadata)
+	}
+}
+
+// Close releases the bus name and disconnects.
+func (s *Server) Close() error {
+	return s.conn.Close()
+}
+
+// PlaybackStatus maps a status to the MPRIS PlaybackStatus value.
+func PlaybackStatus(status sonos.PlaybackStatus) string {
+	switch {
+	case status.Playing:
+		return StatusPlaying
+	case strings.EqualFold(status.State, "Paused"), strings.EqualFold(status.State, "PAUSED_PLAYBACK"):
+		return StatusPaused
+	default:
+		return StatusStopped
+	}
+}
+
+// Metadata builds the MPRIS Metadata map for the status's track.
+func Metadata(status sonos.PlaybackStatus) map[string]dbus.Variant {
+	track := status.Track
+	if track.Title == "" && track.Artist == "" && track.Album == "" {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(noTrack)}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(track.Title + "\x00" + track.Artist + "\x00" + track.Album + "\x00" + track.URI))
+	metadata := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath(fmt.Sprintf("/org/mpris/MediaPlayer2/Track/%x", h.Sum64()))),
+		"xesam:title":   dbus.MakeVariant(track.Title),
+		"xesam:album":   dbus.MakeVariant(track.Album),
+	}
+	if track.Artist != "" {
+		metadata["xesam:artist"] = dbus.MakeVariant([]string{track.Artist})
+	}
+	if track.Duration > 0 {
+		metadata["mpris:length"] = dbus.MakeVariant(track.Duration.Microseconds())
+	}
+	if art := track.AlbumArtURI; strings.HasPrefix(art, "http://") || strings.HasPrefix(art, "https://") {
+		metadata["mpris:artUrl"] = dbus.MakeVariant(art)
+	}
+	return metadata
+}
+
+func sameMetadata(current interface{}, next map[string]dbus.Variant) bool {
+	prev, ok := current.(map[string]dbus.Variant)
+	if !ok || len(prev) != len(next) {
+		return false
+	}
+	for key, value := range next {
+		if other, ok := prev[key]; !ok || other.String() != value.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// run executes a transport command with a timeout and converts its error for
+// the D-Bus caller.
+func (s *Server) run(command func(Handler, context.Context) error) *dbus.Error {
+	if s.handler == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	if err := command(s.handler, ctx); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// root implements org.mpris.MediaPlayer2.
+type root struct {
+	identity string
+}
+
+func (root) Raise() *dbus.Error { return nil }
+func (root) Quit() *dbus.Error  { return nil }
+
+// playerMethodNames maps Go method names to their D-Bus names where the two
+// differ.
+var playerMethodNames = map[string]string{"SeekBy": "Seek"}
+
+// playerMethods returns the introspection data for player under its D-Bus
+// method names.
+func playerMethods() []introspect.Method {
+	methods := introspect.Methods(player{})
+	for i, m := range methods {
+		if name, ok := playerMethodNames[m.Name]; ok {
+			methods[i].Name = name
+		}
+	}
+	return methods
+}
+
+// player implements org.mpris.MediaPlayer2.Player.
+type player struct {
+	s *Server
+}
+
+func (p player) Next() *dbus.Error     { return p.s.run(Handler.Next) }
+func (p player) Previous() *dbus.Error { return p.s.run(Handler.Previous) }
+func (p player) Pause() *dbus.Error    { return p.s.run(Handler.Pause) }
+func (p player) Stop() *dbus.Error     { return p.s.run(Handler.Stop) }
+func (p player) Play() *dbus.Error     { return p.s.run(Handler.Play) }
+
+func (p player) PlayPause() *dbus.Error {
+	p.s.mu.Lock()
+	playing := p.s.status == StatusPlaying
+	p.s.mu.Unlock()
+	if playing {
+		return p.Pause()
+	}
+	return p.Play()
+}
+
+// SeekBy implements Seek; the Go name avoids clashing with io.Seeker.
+func (player) SeekBy(offset int64) *dbus.Error {
+	return nil
+}
+
+func (player) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	return nil
+}
+
+func (player) OpenUri(uri string) *dbus.Error {
+	return dbus.MakeFailedError(fmt.Errorf("mpris: opening URIs is not supported"))
+}
@@ -0,0 +1,128 @@
+package mpris
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"musicDisplay/sonos"
+)
+
+func TestMetadata(t *testing.T) {
+	status := sonos.PlaybackStatus{
+		Room:    "Living Room",
+		Playing: true,
+		Track: sonos.TrackInfo{
+			Title:       "Song",
+			Artist:      "Band",
+			Album:       "Record",
+			AlbumArtURI: "http://192.168.1.10:1400/getaa?s=1",
+			Duration:    3 * time.Minute,
+		},
+	}
+	metadata := Metadata(status)
+	if got := metadata["xesam:title"].Value(); got != "Song" {
+		t.Fatalf("title = %v", got)
+	}
+	if got, ok := metadata["xesam:artist"].Value().([]string); !ok || len(got) != 1 || got[0] != "Band" {
+		t.Fatalf("artist = %v", metadata["xesam:artist"].Value())
+	}
+	if got := metadata["mpris:length"].Value(); got != int64(180_000_000) {
+		t.Fatalf("length = %v", got)
+	}
+	if _, ok := metadata["mpris:artUrl"]; !ok {
+		t.Fatalf("artUrl missing")
+	}
+	if id := metadata["mpris:trackid"].Value(); id == noTrack {
+		t.Fatalf("trackid = NoTrack for a known track")
+	}
+
+	empty := Metadata(sonos.PlaybackStatus{})
+	if id := empty["mpris:trackid"].Value(); id != noTrack || len(empty) != 1 {
+		t.Fatalf("empty metadata = %v", empty)
+	}
+}
+
+func TestPlaybackStatus(t *testing.T) {
+	cases := []struct {
+		status sonos.PlaybackStatus
+		want   string
+	}{
+		{sonos.PlaybackStatus{State: "Playing", Playing: true}, StatusPlaying},
+		{sonos.PlaybackStatus{State: "Paused"}, StatusPaused},
+		{sonos.PlaybackStatus{State: "Stopped"}, StatusStopped},
+		{sonos.PlaybackStatus{State: "No Media"}, StatusStopped},
+	}
+	for _, tc := range cases {
+		if got := PlaybackStatus(tc.status); got != tc.want {
+			t.Fatalf("PlaybackStatus(%q) = %q, want %q", tc.status.State, got, tc.want)
+		}
+	}
+}
+
+// recordingHandler records the calls the server makes, which come from
+// godbus's goroutines.
+type recordingHandler struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (h *recordingHandler) record(call string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, call)
+	return nil
+}
+
+func (h *recordingHandler) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.calls...)
+}
+
+func (h *recordingHandler) Play(context.Context) error     { return h.record("play") }
+func (h *recordingHandler) Pause(context.Context) error    { return h.record("pause") }
+func (h *recordingHandler) Stop(context.Context) error     { return h.record("stop") }
+func (h *recordingHandler) Next(context.Context) error     { return h.record("next") }
+func (h *recordingHandler) Previous(context.Context) error { return h.record("previous") }
+
+func TestServerOnSessionBus(t *testing.T) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Skipf("no session bus: %v", err)
+	}
+	defer conn.Close()
+
+	handler := &recordingHandler{}
+	server, err := New("walldisplay_test", "WallDisplay test", handler)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer server.Close()
+
+	server.Update(sonos.PlaybackStatus{State: "Playing", Playing: true, Track: sonos.TrackInfo{Title: "Song"}})
+
+	obj := conn.Object(busNamePrefix+"walldisplay_test", objectPath)
+	status, err := obj.GetProperty(playerInterface + ".PlaybackStatus")
+	if err != nil {
+		t.Fatalf("get PlaybackStatus: %v", err)
+	}
+	if status.Value() != StatusPlaying {
+		t.Fatalf("PlaybackStatus = %v", status.Value())
+	}
+	for _, method := range []string{"PlayPause", "Next", "Seek"} {
+		args := []interface{}{}
+		if method == "Seek" {
+			args = append(args, int64(1000))
+		}
+		if call := obj.Call(playerInterface+"."+method, 0, args...); call.Err != nil {
+			t.Fatalf("%s error: %v", method, call.Err)
+		}
+	}
+	if calls := handler.recorded(); len(calls) != 2 || calls[0] != "pause" || calls[1] != "next" {
+		t.Fatalf("handler calls = %v, want [pause next]", calls)
+	}
+}
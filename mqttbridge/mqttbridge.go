@@ -0,0 +1,402 @@
+// Package mqttbridge publishes the display's state to an MQTT broker and
+// accepts commands from it. It also announces itself through Home Assistant
+// MQTT discovery so the display shows up as a device without manual setup.
+package mqttbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"musicDisplay/logging"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+var logger = logging.For("mqttbridge")
+
+const (
+	DefaultTopicPrefix     = "walldisplay"
+	DefaultDiscoveryPrefix = "homeassistant"
+	DefaultClientID        = "walldisplay"
+
+	connectTimeout = 10 * time.Second
+	publishQoS     = 1
+)
+
+// Options configures the bridge.
+type Options struct {
+	// Broker is the broker URL, e.g. "tcp://homeassistant.local:1883".
+	Broker   string
+	Username string
+	Password string
+	ClientID string
+	// TopicPrefix is prepended to every state and command topic.
+	TopicPrefix string
+	// DiscoveryPrefix is Home Assistant's discovery prefix. Discovery is
+	// skipped when Discovery is false.
+	DiscoveryPrefix string
+	Discovery       bool
+}
+
+func (o Options) withDefaults() Options {
+	if strings.TrimSpace(o.ClientID) == "" {
+		o.ClientID = DefaultClientID
+	}
+	if strings.TrimSpace(o.TopicPrefix) == "" {
+		o.TopicPrefix = DefaultTopicPrefix
+	}
+	o.TopicPrefix = strings.TrimRight(o.TopicPrefix, "/")
+	if strings.TrimSpace(o.DiscoveryPrefix) == "" {
+		o.DiscoveryPrefix = DefaultDiscoveryPrefix
+	}
+	return o
+}
+
+// Commands carries out commands received on the command topics.
+type Commands interface {
+	Clear() error
+	SetBrightness(level int) error
+	SwitchRoom(room string) error
+	SetLargeText(on bool) error
+	// Notify scrolls text across the display, once when d is zero and
+	// otherwise for d.
+	Notify(text string, d time.Duration, priority int) error
+}
+
+// Topics are the MQTT topics used by a bridge.
+type Topics struct {
+	State         string
+	Availability  string
+	Brightness    string
+	BrightnessSet string
+	ClearSet      string
+	RoomSet       string
+	LargeTextSet  string
+	Notify        string
+}
+
+// TopicsFor returns the topics under prefix.
+func TopicsFor(prefix string) Topics {
+	return Topics{
+		State:         prefix + "/state",
+		Availability:  prefix + "/availability",
+		Brightness:    prefix + "/brightness",
+		BrightnessSet: prefix + "/brightness/set",
+		ClearSet:      prefix + "/clear/set",
+		RoomSet:       prefix + "/room/set",
+		LargeTextSet:  prefix + "/large_text/set",
+		Notify:        prefix + "/notify",
+	}
+}
+
+// State is the JSON published on the state topic.
+type State struct {
+	Room         string `json:"room"`
+	State        string `json:"state"`
+	Playing      bool   `json:"playing"`
+	Title        string `json:"title"`
+	Artist       string `json:"artist"`
+	Album        string `json:"album"`
+	ArtAvailable bool   `json:"art_available"`
+	LargeText    bool   `json:"large_text"`
+	// Colors are the artwork's most prominent colors while it is on
+	// screen, for lights elsewhere in the room to match.
+	Colors []theme.Swatch `json:"colors,omitempty"`
+}
+
+// Bridge is a connected MQTT bridge.
+type Bridge struct {
+	client   mqtt.Client
+	opts     Options
+	topics   Topics
+	commands Commands
+
+	mu         sync.Mutex
+	state      State
+	lastState  []byte
+	brightness int
+}
+
+// Connect connects to the broker, subscribes to the command topics, and
+// publishes discovery payloads. The connection is re-established
+// automatically if it drops.
+func Connect(opts Options, commands Commands) (*Bridge, error) {
+	opts = opts.withDefaults()
+	if strings.TrimSpace(opts.Broker) == "" {
+		return nil, errors.New("mqttbridge: broker is required")
+	}
+	b := &Bridge{opts: opts, topics: TopicsFor(opts.TopicPrefix), commands: commands}
+
+	clientOpts := mqtt.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(connectTimeout).
+		SetWill(b.topics.Availability, "offline", publishQoS, true).
+		SetOnConnectHandler(b.onConnect)
+	b.client = mqtt.NewClient(clientOpts)
+
+	token := b.client.Connect()
+	if !token.WaitTimeout(connectTimeout) {
+		b.client.Disconnect(0)
+		return nil, fmt.Errorf("mqttbridge: connect to %s: timed out", opts.Broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("mqttbridge: connect to %s: %w", opts.Broker, err)
+	}
+	return b, nil
+}
+
+// onConnect runs after every (re)connect: it subscribes, announces the
+// device, and republishes the retained state.
+func (b *Bridge) onConnect(client mqtt.Client) {
+	for _, topic := range []string{b.topics.BrightnessSet, b.topics.ClearSet, b.topics.RoomSet, b.topics.LargeTextSet, b.topics.Notify} {
+		client.Subscribe(topic, publishQoS, func(_ mqtt.Client, msg mqtt.Message) {
+			if err := b.handle(topic, msg.Payload()); err != nil {
+				logger.Warn("mqtt command failed", "topic", topic, "err", err)
+			}
+		})
+	}
+	if b.opts.Discovery {
+		for topic, payload := range DiscoveryPayloads(b.opts) {
+			client.Publish(topic, publishQoS, true, payload)
+		}
+	}
+	client.Publish(b.topics.Availability, publishQoS, true, "online")
+
+	b.mu.Lock()
+	state, brightness := b.lastState, b.brightness
+	b.mu.Unlock()
+	if state != nil {
+		client.Publish(b.topics.State, publishQoS, true, state)
+	}
+	if brightness > 0 {
+		client.Publish(b.topics.Brightness, publishQoS, true, strconv.Itoa(brightness))
+	}
+}
+
+// handle dispatches a command message.
+func (b *Bridge) handle(topic string, payload []byte) error {
+	value := strings.TrimSpace(string(payload))
+	switch topic {
+	case b.topics.BrightnessSet:
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 1 || level > 100 {
+			return fmt.Errorf("brightness must be a number between 1 and 100, got %q", value)
+		}
+		if err := b.commands.SetBrightness(level); err != nil {
+			return err
+		}
+		b.PublishBrightness(level)
+		return nil
+	case b.topics.ClearSet:
+		return b.commands.Clear()
+	case b.topics.RoomSet:
+		if value == "" {
+			return errors.New("room must not be empty")
+		}
+		return b.commands.SwitchRoom(value)
+	case b.topics.LargeTextSet:
+		var on bool
+		switch strings.ToUpper(value) {
+		case "ON":
+			on = true
+		case "OFF":
+		default:
+			return fmt.Errorf("large text must be ON or OFF, got %q", value)
+		}
+		if err := b.commands.SetLargeText(on); err != nil {
+			return err
+		}
+		b.mu.Lock()
+		b.state.LargeText = on
+		b.mu.Unlock()
+		b.publishState()
+		return nil
+	case b.topics.Notify:
+		// The payload is the text to scroll once, or JSON with how long to
+		// keep it up and its priority.
+		var note struct {
+			Text     string  `json:"text"`
+			Seconds  float64 `json:"seconds"`
+			Priority int     `json:"priority"`
+		}
+		note.Text = value
+		if strings.HasPrefix(value, "{") {
+			note.Text = ""
+			if err := json.Unmarshal([]byte(value), &note); err != nil {
+				return fmt.Errorf("notification: %w", err)
+			}
+		}
+		if strings.TrimSpace(note.Text) == "" {
+			return errors.New("notification text must not be empty")
+		}
+		if note.Seconds < 0 {
+			return fmt.Errorf("notification seconds must not be negative, got %v", note.Seconds)
+		}
+		return b.commands.Notify(note.Text, time.Duration(note.Seconds*float64(time.Second)), note.Priority)
+	}
+	return fmt.Errorf("unknown topic")
+}
+
+// UpdateStatus publishes the room's status when it differs from the last one
+// sent. Position is left out so progress ticks do not flood the broker.
+func (b *Bridge) UpdateStatus(status sonos.PlaybackStatus) {
+	b.mu.Lock()
+	b.state.Room = status.Room
+	b.state.State = status.State
+	b.state.Playing = status.Playing
+	b.state.Title = status.Track.Title
+	b.state.Artist = status.Track.Artist
+	b.state.Album = status.Track.Album
+	b.mu.Unlock()
+	b.publishState()
+}
+
+// SetArtAvailable records whether artwork is on screen and, while it is,
+// its most prominent colors.
+func (b *Bridge) SetArtAvailable(available bool, colors []theme.Swatch) {
+	if !available {
+		colors = nil
+	}
+	b.mu.Lock()
+	b.state.ArtAvailable = available
+	b.state.Colors = colors
+	b.mu.Unlock()
+	b.publishState()
+}
+
+// PublishBrightness publishes the panel brightness.
+func (b *Bridge) PublishBrightness(level int) {
+	b.mu.Lock()
+	b.brightness = level
+	b.mu.Unlock()
+	b.client.Publish(b.topics.Brightness, publishQoS, true, strconv.Itoa(level))
+}
+
+func (b *Bridge) publishState() {
+	b.mu.Lock()
+	payload, err := json.Marshal(b.state)
+	if err != nil || string(payload) == string(b.lastState) {
+		b.mu.Unlock()
+		return
+	}
+	b.lastState = payload
+	b.mu.Unlock()
+	b.client.Publish(b.topics.State, publishQoS, true, payload)
+}
+
+// Close marks the display offline and disconnects.
+func (b *Bridge) Close() {
+	token := b.client.Publish(b.topics.Availability, publishQoS, true, "offline")
+	token.WaitTimeout(2 * time.Second)
+	b.client.Disconnect(250)
+}
+
+// DiscoveryPayloads returns the retained Home Assistant discovery messages
+// keyed by topic.
+func DiscoveryPayloads(opts Options) map[string][]byte {
+	opts = opts.withDefaults()
+	topics := TopicsFor(opts.TopicPrefix)
+	node := sanitizeID(opts.ClientID)
+	device := map[string]interface{}{
+		"identifiers":  []string{"walldisplay_" + node},
+		"name":         "WallDisplay",
+		"model":        "Sonos album art display",
+		"manufacturer": "WallDisplay",
+	}
+	base := func(name, object string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":               name,
+			"unique_id":          node + "_" + object,
+			"object_id":          node + "_" + object,
+			"device":             device,
+			"availability_topic": topics.Availability,
+		}
+	}
+
+	entities := map[string]map[string]interface{}{}
+
+	nowPlaying := base("Now playing", "now_playing")
+	nowPlaying["state_topic"] = topics.State
+	nowPlaying["value_template"] = "{{ value_json.artist ~ ' – ' ~ value_json.title if value_json.title else 'Idle' }}"
+	nowPlaying["json_attributes_topic"] = topics.State
+	nowPlaying["icon"] = "mdi:music"
+	entities["sensor/"+node+"/now_playing"] = nowPlaying
+
+	playing := base("Playing", "playing")
+	playing["state_topic"] = topics.State
+	playing["value_template"] = "{{ 'ON' if value_json.playing else 'OFF' }}"
+	entities["binary_sensor/"+node+"/playing"] = playing
+
+	art := base("Album art", "art")
+	art["state_topic"] = topics.State
+	art["value_template"] = "{{ 'ON' if value_json.art_available else 'OFF' }}"
+	art["icon"] = "mdi:image"
+	entities["binary_sensor/"+node+"/art"] = art
+
+	brightness := base("Brightness", "brightness")
+	brightness["state_topic"] = topics.Brightness
+	brightness["command_topic"] = topics.BrightnessSet
+	brightness["min"] = 1
+	brightness["max"] = 100
+	brightness["icon"] = "mdi:brightness-6"
+	entities["number/"+node+"/brightness"] = brightness
+
+	clear := base("Clear display", "clear")
+	clear["command_topic"] = topics.ClearSet
+	clear["payload_press"] = "clear"
+	entities["button/"+node+"/clear"] = clear
+
+	largeText := base("Large text", "large_text")
+	largeText["state_topic"] = topics.State
+	largeText["value_template"] = "{{ 'ON' if value_json.large_text else 'OFF' }}"
+	largeText["command_topic"] = topics.LargeTextSet
+	largeText["icon"] = "mdi:format-size"
+	entities["switch/"+node+"/large_text"] = largeText
+
+	notify := base("Notification", "notify")
+	notify["command_topic"] = topics.Notify
+	notify["icon"] = "mdi:message-text"
+	entities["notify/"+node+"/notify"] = notify
+
+	room := base("Room", "room")
+	room["state_topic"] = topics.State
+	room["value_template"] = "{{ value_json.room }}"
+	room["command_topic"] = topics.RoomSet
+	room["icon"] = "mdi:speaker"
+	entities["text/"+node+"/room"] = room
+
+	payloads := make(map[string][]byte, len(entities))
+	for path, entity := range entities {
+		data, err := json.Marshal(entity)
+		if err != nil {
+			continue
+		}
+		payloads[opts.DiscoveryPrefix+"/"+path+"/config"] = data
+	}
+	return payloads
+}
+
+// sanitizeID keeps the characters Home Assistant accepts in node IDs.
+func sanitizeID(id string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
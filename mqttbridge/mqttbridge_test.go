@@ -0,0 +1,133 @@
+package mqttbridge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"musicDisplay/theme"
+)
+
+type fakeCommands struct {
+	cleared    bool
+	brightness int
+	room       string
+	largeText  bool
+	notified   string
+	notifyFor  time.Duration
+}
+
+func (f *fakeCommands) Clear() error { f.cleared = true; return nil }
+func (f *fakeCommands) SetBrightness(level int) error {
+	f.brightness = level
+	return nil
+}
+func (f *fakeCommands) SwitchRoom(room string) error {
+	f.room = room
+	return nil
+}
+
+func (f *fakeCommands) SetLargeText(on bool) error {
+	f.largeText = on
+	return nil
+}
+
+func (f *fakeCommands) Notify(text string, d time.Duration, priority int) error {
+	f.notified, f.notifyFor = text, d
+	return nil
+}
+
+func TestHandleCommands(t *testing.T) {
+	commands := &fakeCommands{}
+	b := &Bridge{
+		// An unconnected client drops publishes, which is all these tests need.
+		client:   mqtt.NewClient(mqtt.NewClientOptions()),
+		topics:   TopicsFor("wall"),
+		commands: commands,
+	}
+
+	if err := b.handle("wall/brightness/set", []byte(" 35 ")); err != nil || commands.brightness != 35 {
+		t.Fatalf("brightness: err %v, level %d", err, commands.brightness)
+	}
+	if err := b.handle("wall/brightness/set", []byte("bright")); err == nil {
+		t.Fatalf("expected an error for a non-numeric brightness")
+	}
+	if err := b.handle("wall/clear/set", []byte("clear")); err != nil || !commands.cleared {
+		t.Fatalf("clear: err %v, cleared %v", err, commands.cleared)
+	}
+	if err := b.handle("wall/room/set", []byte("Kitchen")); err != nil || commands.room != "Kitchen" {
+		t.Fatalf("room: err %v, room %q", err, commands.room)
+	}
+	if err := b.handle("wall/room/set", []byte("  ")); err == nil {
+		t.Fatalf("expected an error for an empty room")
+	}
+	if err := b.handle("wall/large_text/set", []byte("ON")); err != nil || !commands.largeText || !b.state.LargeText {
+		t.Fatalf("large text: err %v, on %v, state %v", err, commands.largeText, b.state.LargeText)
+	}
+	if err := b.handle("wall/large_text/set", []byte("maybe")); err == nil {
+		t.Fatalf("expected an error for a large text payload other than ON or OFF")
+	}
+	if err := b.handle("wall/notify", []byte("Door open")); err != nil || commands.notified != "Door open" || commands.notifyFor != 0 {
+		t.Fatalf("notify: err %v, text %q for %s", err, commands.notified, commands.notifyFor)
+	}
+	if err := b.handle("wall/notify", []byte(`{"text": "Laundry done", "seconds": 20}`)); err != nil || commands.notified != "Laundry done" || commands.notifyFor != 20*time.Second {
+		t.Fatalf("notify JSON: err %v, text %q for %s", err, commands.notified, commands.notifyFor)
+	}
+	if err := b.handle("wall/notify", []byte(`{"seconds": 20}`)); err == nil {
+		t.Fatalf("expected an error for a notification without text")
+	}
+}
+
+func TestStateCarriesArtColors(t *testing.T) {
+	b := &Bridge{client: mqtt.NewClient(mqtt.NewClientOptions()), topics: TopicsFor("wall")}
+	b.SetArtAvailable(true, []theme.Swatch{{Hex: "#2040c0", RGB: [3]int{0x20, 0x40, 0xc0}}})
+	var state map[string]any
+	if err := json.Unmarshal(b.lastState, &state); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	colors, ok := state["colors"].([]any)
+	if !ok || len(colors) != 1 || colors[0].(map[string]any)["hex"] != "#2040c0" {
+		t.Fatalf("state colors = %v, want the art's color", state["colors"])
+	}
+
+	b.SetArtAvailable(false, []theme.Swatch{{Hex: "#2040c0"}})
+	state = nil
+	if err := json.Unmarshal(b.lastState, &state); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if _, ok := state["colors"]; ok || state["art_available"] != false {
+		t.Fatalf("state without art = %v, want no colors", state)
+	}
+}
+
+func TestDiscoveryPayloads(t *testing.T) {
+	payloads := DiscoveryPayloads(Options{ClientID: "Living Room", TopicPrefix: "wall/"})
+	if len(payloads) != 8 {
+		t.Fatalf("got %d discovery payloads, want 8", len(payloads))
+	}
+
+	data, ok := payloads["homeassistant/number/living_room/brightness/config"]
+	if !ok {
+		t.Fatalf("brightness entity missing; topics: %v", keys(payloads))
+	}
+	var entity map[string]interface{}
+	if err := json.Unmarshal(data, &entity); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if entity["command_topic"] != "wall/brightness/set" || entity["state_topic"] != "wall/brightness" {
+		t.Fatalf("brightness topics = %v / %v", entity["command_topic"], entity["state_topic"])
+	}
+	if entity["availability_topic"] != "wall/availability" || entity["unique_id"] != "living_room_brightness" {
+		t.Fatalf("unexpected entity: %v", entity)
+	}
+}
+
+func keys(m map[string][]byte) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
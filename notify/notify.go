@@ -0,0 +1,226 @@
+// Package notify shows short text notifications in a band scrolling across
+// the display, over whatever it shows.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+var logger = logging.For("notify")
+
+const (
+	// MaxText is the longest notification text Push accepts, in characters.
+	MaxText = 200
+	// MaxDuration is the longest a notification may stay up.
+	MaxDuration = 10 * time.Minute
+	// MaxQueue is how many notifications may wait behind the one showing.
+	MaxQueue = 10
+
+	// scrollSpeed is how fast the text scrolls, in pixels per second at
+	// scale 1.
+	scrollSpeed = 24
+	// scrollGap is the space between repeats of text that keeps scrolling,
+	// in pixels at scale 1.
+	scrollGap = 16
+	// overlayPriority places notifications above every other overlay.
+	overlayPriority = 100
+)
+
+// ErrQueueFull is returned by Push when MaxQueue notifications are already
+// waiting.
+var ErrQueueFull = errors.New("notify: too many notifications waiting")
+
+// Notification is a message to show over the display.
+type Notification struct {
+	Text string
+	// Duration keeps the notification up for that long, scrolling the text
+	// again and again when it does not fit. Zero scrolls it across once.
+	Duration time.Duration
+	// Priority orders waiting notifications, highest first. One with a
+	// higher priority than the notification showing interrupts it, and the
+	// interrupted one shows again afterwards.
+	Priority int
+}
+
+// Notifier queues notifications and draws the current one. It implements
+// render.Overlay.
+type Notifier struct {
+	clk   clock.Clock
+	size  image.Point
+	font  *text.Font
+	scale int
+
+	mu      sync.Mutex
+	current *Notification
+	start   time.Time
+	end     time.Time
+	queue   []Notification
+}
+
+var _ render.Overlay = (*Notifier)(nil)
+
+// New returns a notifier drawing onto frames of size.
+func New(clk clock.Clock, size image.Point) *Notifier {
+	return &Notifier{
+		clk:   clock.Or(clk),
+		size:  size,
+		font:  text.Medium,
+		scale: max(min(size.X, size.Y)/64, 1),
+	}
+}
+
+// Push shows note once the notifications ahead of it have been shown, or
+// right away when it outranks the one showing.
+func (n *Notifier) Push(note Notification) error {
+	note.Text = strings.Join(strings.Fields(note.Text), " ")
+	switch {
+	case note.Text == "":
+		return errors.New("notify: text is empty")
+	case utf8.RuneCountInString(note.Text) > MaxText:
+		return fmt.Errorf("notify: text is longer than %d characters", MaxText)
+	case note.Duration < 0 || note.Duration > MaxDuration:
+		return fmt.Errorf("notify: duration must be between 0 and %s, got %s", MaxDuration, note.Duration)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.clk.Now()
+	n.advanceLocked(now)
+	if len(n.queue) >= MaxQueue {
+		return ErrQueueFull
+	}
+	if n.current == nil {
+		n.startLocked(note, now)
+		return nil
+	}
+	if note.Priority > n.current.Priority {
+		// The interrupted notification shows again, from the start, ahead
+		// of the others of its priority.
+		n.enqueueLocked(*n.current, true)
+		n.startLocked(note, now)
+		return nil
+	}
+	n.enqueueLocked(note, false)
+	return nil
+}
+
+// enqueueLocked adds note to the queue behind those of higher priority and,
+// unless first is set, those of the same priority. Callers must hold n.mu.
+func (n *Notifier) enqueueLocked(note Notification, first bool) {
+	i := sort.Search(len(n.queue), func(i int) bool {
+		if first {
+			return n.queue[i].Priority <= note.Priority
+		}
+		return n.queue[i].Priority < note.Priority
+	})
+	n.queue = append(n.queue[:i], append([]Notification{note}, n.queue[i:]...)...)
+}
+
+// Clear drops the notification showing and every one waiting.
+func (n *Notifier) Clear() {
+	n.mu.Lock()
+	n.current, n.queue = nil, nil
+	n.mu.Unlock()
+}
+
+// Showing returns the text of the notification showing at now, if any.
+func (n *Notifier) Showing(now time.Time) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.advanceLocked(now)
+	if n.current == nil {
+		return "", false
+	}
+	return n.current.Text, true
+}
+
+// startLocked puts note on screen from now. Callers must hold n.mu.
+func (n *Notifier) startLocked(note Notification, now time.Time) {
+	n.current, n.start = &note, now
+	n.end = now.Add(note.Duration)
+	if note.Duration == 0 {
+		distance := n.size.X + n.font.Measure(note.Text)*n.scale
+		n.end = now.Add(time.Duration(distance) * time.Second / time.Duration(scrollSpeed*n.scale))
+	}
+	logger.Info("notification shown", "text", note.Text)
+}
+
+// advanceLocked moves on to the next notification once the current one has
+// ended. Callers must hold n.mu.
+func (n *Notifier) advanceLocked(now time.Time) {
+	for n.current != nil && !now.Before(n.end) {
+		n.current = nil
+		if len(n.queue) > 0 {
+			next := n.queue[0]
+			n.queue = n.queue[1:]
+			n.startLocked(next, n.end)
+		}
+	}
+}
+
+// Priority implements render.Overlay.
+func (n *Notifier) Priority() int {
+	return overlayPriority
+}
+
+// Visible implements render.Overlay: it reports whether a notification
+// is showing.
+func (n *Notifier) Visible() bool {
+	_, ok := n.Showing(n.clk.Now())
+	return ok
+}
+
+// Animating implements render.AnimatedLayer, so the renderer redraws the
+// notification as it scrolls.
+func (n *Notifier) Animating() bool {
+	return n.Visible()
+}
+
+// Draw implements render.Layer: a band across the middle of the frame with
+// the notification's text scrolling through it from the right. Text that
+// fits and stays up for a Duration holds still in the middle instead.
+func (n *Notifier) Draw(frame *image.RGBA, state render.FrameState) error {
+	n.mu.Lock()
+	n.advanceLocked(state.Now)
+	current, start := n.current, n.start
+	n.mu.Unlock()
+	if current == nil {
+		return nil
+	}
+
+	bounds := frame.Bounds()
+	scale := n.scale
+	height := (n.font.Height() + 4) * scale
+	top := bounds.Min.Y + (bounds.Dy()-height)/2
+	band := image.Rect(bounds.Min.X, top, bounds.Max.X, top+height)
+	draw.Draw(frame, band, image.NewUniform(state.Palette.Background), image.Point{}, draw.Src)
+	accent := image.NewUniform(state.Palette.Accent)
+	draw.Draw(frame, image.Rect(band.Min.X, band.Min.Y, band.Max.X, band.Min.Y+scale), accent, image.Point{}, draw.Src)
+	draw.Draw(frame, image.Rect(band.Min.X, band.Max.Y-scale, band.Max.X, band.Max.Y), accent, image.Point{}, draw.Src)
+
+	width := n.font.Measure(current.Text) * scale
+	y := band.Min.Y + 2*scale
+	if current.Duration > 0 && width <= bounds.Dx()-2*scale {
+		n.font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, y, current.Text, state.Palette.Text, scale)
+		return nil
+	}
+	offset := int(state.Now.Sub(start).Seconds() * float64(scrollSpeed*scale))
+	if current.Duration > 0 {
+		// Text that keeps scrolling wraps around with a gap.
+		offset %= width + scrollGap*scale + bounds.Dx()
+	}
+	n.font.Draw(frame, bounds.Max.X-offset, y, current.Text, state.Palette.Text, scale)
+	return nil
+}
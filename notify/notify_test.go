@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/theme"
+)
+
+func TestScrollOnceThenNext(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	n := New(clk, image.Pt(64, 64))
+	if n.Animating() {
+		t.Fatal("a new notifier should have nothing to show")
+	}
+	if err := n.Push(Notification{Text: "  Door\nopen "}); err != nil {
+		t.Fatalf("Push error: %v", err)
+	}
+	if err := n.Push(Notification{Text: "Next", Duration: 5 * time.Second}); err != nil {
+		t.Fatalf("Push error: %v", err)
+	}
+	if got, ok := n.Showing(clk.Now()); !ok || got != "Door open" {
+		t.Fatalf("showing %q, %v; want the first notification", got, ok)
+	}
+
+	// "Door open" is 9 characters of 6 pixels less the trailing space; it
+	// scrolls across 64+53 pixels at 24 a second.
+	clk.Advance(4 * time.Second)
+	if got, _ := n.Showing(clk.Now()); got != "Door open" {
+		t.Fatalf("showing %q after 4s, want the first still scrolling", got)
+	}
+	clk.Advance(time.Second)
+	if got, _ := n.Showing(clk.Now()); got != "Next" {
+		t.Fatalf("showing %q after 5s, want the next notification", got)
+	}
+	clk.Advance(5 * time.Second)
+	if n.Animating() {
+		t.Fatal("notifications still showing after the last ran out")
+	}
+}
+
+func TestHigherPriorityReplacesAndQueuesAhead(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	n := New(clk, image.Pt(64, 64))
+	for _, note := range []Notification{
+		{Text: "low", Duration: time.Minute},
+		{Text: "also low", Duration: time.Minute},
+		{Text: "medium", Duration: time.Minute, Priority: 1},
+		{Text: "urgent", Duration: time.Minute, Priority: 5},
+	} {
+		if err := n.Push(note); err != nil {
+			t.Fatalf("Push(%q) error: %v", note.Text, err)
+		}
+	}
+	for _, want := range []string{"urgent", "medium", "low", "also low"} {
+		if got, _ := n.Showing(clk.Now()); got != want {
+			t.Fatalf("showing %q, want %q", got, want)
+		}
+		clk.Advance(time.Minute)
+	}
+	if n.Animating() {
+		t.Fatal("notifications still showing after the queue ran out")
+	}
+}
+
+func TestPushValidates(t *testing.T) {
+	n := New(clock.NewFake(time.Now()), image.Pt(64, 64))
+	for _, note := range []Notification{
+		{Text: " "},
+		{Text: strings.Repeat("x", MaxText+1)},
+		{Text: "hi", Duration: MaxDuration + time.Second},
+	} {
+		if err := n.Push(note); err == nil {
+			t.Errorf("Push(%+v) accepted", note)
+		}
+	}
+	// One shows and MaxQueue wait.
+	for i := 0; i <= MaxQueue; i++ {
+		if err := n.Push(Notification{Text: "hi", Duration: time.Minute}); err != nil {
+			t.Fatalf("Push %d error: %v", i, err)
+		}
+	}
+	if err := n.Push(Notification{Text: "hi"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Push on a full queue = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	n := New(clk, image.Pt(64, 64))
+	palette := theme.DefaultPalette
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := n.Push(Notification{Text: "Hi", Duration: time.Minute}); err != nil {
+		t.Fatalf("Push error: %v", err)
+	}
+	if err := n.Draw(frame, render.FrameState{Palette: palette, Now: clk.Now()}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	// The band runs across the middle, edged in the accent color, with the
+	// short text held still in its center.
+	top := (64 - (n.font.Height() + 4)) / 2
+	if frame.RGBAAt(0, top) != palette.Accent || frame.RGBAAt(0, top-1) != (image.NewRGBA(image.Rect(0, 0, 1, 1)).RGBAAt(0, 0)) {
+		t.Fatalf("band edge not at row %d", top)
+	}
+	lit := 0
+	for x := 0; x < 64; x++ {
+		if frame.RGBAAt(x, top+2+2) == palette.Text {
+			lit++
+		}
+	}
+	if lit == 0 {
+		t.Fatal("text not drawn in the band")
+	}
+}
@@ -0,0 +1,55 @@
+package overlay
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// SpotifyGreen is the brand color used for the Spotify source badge.
+var SpotifyGreen = color.RGBA{R: 0x1d, G: 0xb9, B: 0x54, A: 0xff}
+
+// spotifyMark is a 7x7 rendering of the Spotify logo: a disc crossed by three
+// arcs. 'G' pixels take the badge color, 'k' pixels are dark, '.' is left
+// untouched.
+var spotifyMark = []string{
+	"..GGG..",
+	".GGGGG.",
+	"GkkkkkG",
+	"GGGGGGG",
+	"GGkkkGG",
+	".GGGGG.",
+	"..GkG..",
+}
+
+// SpotifyBadge stamps a small Spotify logo into the top-left corner of dst to
+// mark artwork that comes from a Spotify account rather than a Sonos room.
+func SpotifyBadge(dst draw.Image) {
+	Badge(dst, spotifyMark, SpotifyGreen)
+}
+
+// Badge stamps mark into the top-left corner of dst, one pixel in from the
+// edges. 'G' pixels are painted col, 'k' pixels black, and any other rune
+// leaves the underlying pixel visible.
+func Badge(dst draw.Image, mark []string, col color.Color) {
+	if dst == nil {
+		return
+	}
+	bounds := dst.Bounds()
+	origin := bounds.Min.Add(image.Pt(1, 1))
+	dark := color.RGBA{A: 0xff}
+	for y, row := range mark {
+		for x, r := range row {
+			p := origin.Add(image.Pt(x, y))
+			if !p.In(bounds) {
+				continue
+			}
+			switch r {
+			case 'G':
+				dst.Set(p.X, p.Y, col)
+			case 'k':
+				dst.Set(p.X, p.Y, dark)
+			}
+		}
+	}
+}
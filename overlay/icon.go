@@ -0,0 +1,128 @@
+package overlay
+
+import (
+	"embed"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Icons available from Icon. Each is an 8x8 PNG under icons/.
+const (
+	IconSpotify = "spotify"
+	IconRadio   = "radio"
+	IconAirPlay = "airplay"
+	IconTV      = "tv"
+	// IconWiFi marks a weak Wi-Fi signal.
+	IconWiFi = "wifi"
+	// Play mode indicators.
+	IconShuffle   = "shuffle"
+	IconRepeat    = "repeat"
+	IconRepeatOne = "repeat-one"
+	IconCrossfade = "crossfade"
+)
+
+// Corners StampIcon can place an icon in.
+const (
+	TopLeft     = "top-left"
+	TopRight    = "top-right"
+	BottomLeft  = "bottom-left"
+	BottomRight = "bottom-right"
+)
+
+// IconSize is the native size of the embedded icons. StampIcon also draws
+// them at LargeIconSize for bigger panels.
+const (
+	IconSize      = 8
+	LargeIconSize = 12
+)
+
+//go:embed icons/*.png
+var iconFiles embed.FS
+
+var (
+	iconMu    sync.Mutex
+	iconCache = map[string]image.Image{}
+)
+
+// Icon returns the embedded icon called name.
+func Icon(name string) (image.Image, error) {
+	iconMu.Lock()
+	defer iconMu.Unlock()
+	if img, ok := iconCache[name]; ok {
+		return img, nil
+	}
+	file, err := iconFiles.Open("icons/" + name + ".png")
+	if err != nil {
+		return nil, fmt.Errorf("overlay: unknown icon %q", name)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("overlay: decode icon %q: %w", name, err)
+	}
+	iconCache[name] = img
+	return img, nil
+}
+
+// ValidCorner reports whether corner names one of the four corners.
+func ValidCorner(corner string) bool {
+	switch corner {
+	case TopLeft, TopRight, BottomLeft, BottomRight:
+		return true
+	}
+	return false
+}
+
+// StampIcon draws icon, scaled to size pixels square, in corner of dst on a
+// dark backing square one pixel larger on each side, so it stays legible on
+// bright artwork. The backing sits one pixel in from the edges. An unknown
+// corner means TopLeft and a size below IconSize means IconSize.
+func StampIcon(dst draw.Image, icon image.Image, corner string, size int) {
+	if icon == nil {
+		return
+	}
+	StampIcons(dst, []image.Image{icon}, corner, size)
+}
+
+// StampIcons draws icons in a row from corner toward the middle of dst, one
+// pixel apart on a shared dark backing, as StampIcon draws one.
+func StampIcons(dst draw.Image, icons []image.Image, corner string, size int) {
+	if dst == nil || len(icons) == 0 {
+		return
+	}
+	if size < IconSize {
+		size = IconSize
+	}
+	bounds := dst.Bounds()
+	box := image.Rect(0, 0, len(icons)*(size+1)+1, size+2)
+	var origin image.Point
+	switch corner {
+	case TopRight:
+		origin = image.Pt(bounds.Max.X-box.Dx()-1, bounds.Min.Y+1)
+	case BottomLeft:
+		origin = image.Pt(bounds.Min.X+1, bounds.Max.Y-box.Dy()-1)
+	case BottomRight:
+		origin = image.Pt(bounds.Max.X-box.Dx()-1, bounds.Max.Y-box.Dy()-1)
+	default:
+		origin = bounds.Min.Add(image.Pt(1, 1))
+	}
+	draw.Draw(dst, box.Add(origin).Intersect(bounds), image.NewUniform(color.RGBA{A: 0xd0}), image.Point{}, draw.Over)
+
+	for i, icon := range icons {
+		if icon == nil {
+			continue
+		}
+		target := image.Rect(0, 0, size, size).Add(origin.Add(image.Pt(1+i*(size+1), 1)))
+		if icon.Bounds().Dx() == size && icon.Bounds().Dy() == size {
+			draw.Draw(dst, target, icon, icon.Bounds().Min, draw.Over)
+			continue
+		}
+		xdraw.NearestNeighbor.Scale(dst, target, icon, icon.Bounds(), draw.Over, nil)
+	}
+}
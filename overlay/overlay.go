@@ -15,8 +15,31 @@ import (
 
 // Margin describes the pixel padding to keep between the rendered text and the edges of the image.
 type Margin struct {
-	Top   int
-	Right int
+	Top    int
+	Right  int
+	Bottom int
+	Left   int
+}
+
+// Center places overlay text in the middle of the image. The corner
+// positions are TopLeft, TopRight, BottomLeft, and BottomRight.
+const Center = "center"
+
+// TextOptions places and styles text drawn by OverlayText.
+type TextOptions struct {
+	// Position is a corner or Center (default TopRight).
+	Position string
+	// Margin is kept between the text and the edges it is placed against.
+	Margin Margin
+	// Height is the text height in pixels.
+	Height float64
+	// Color defaults to white.
+	Color color.Color
+}
+
+// ValidPosition reports whether position is a corner or Center.
+func ValidPosition(position string) bool {
+	return position == Center || ValidCorner(position)
 }
 
 var (
@@ -41,22 +64,38 @@ func loadFont() (*opentype.Font, error) {
 	return regularFont, nil
 }
 
-// OverlayTopRightText places text in the top-right corner of a 64x64 image using the provided margin and text height.
+// OverlayTopRightText places text in the top-right corner of an image of any size using the provided margin and text height.
 // The original image is left unchanged; a copy with the overlay applied is returned instead.
 func OverlayTopRightText(src image.Image, text string, margin Margin, textHeight float64) (*image.RGBA, error) {
+	return OverlayText(src, text, TextOptions{Position: TopRight, Margin: margin, Height: textHeight})
+}
+
+// OverlayText draws text onto a copy of src at opts.Position. Text that does
+// not fit is clipped at the far edge. The original image is left unchanged.
+func OverlayText(src image.Image, text string, opts TextOptions) (*image.RGBA, error) {
 	if src == nil {
 		return nil, fmt.Errorf("nil source image")
 	}
-	if textHeight <= 0 {
+	if opts.Height <= 0 {
 		return nil, fmt.Errorf("text height must be positive")
 	}
-	if margin.Top < 0 || margin.Right < 0 {
+	margin := opts.Margin
+	if margin.Top < 0 || margin.Right < 0 || margin.Bottom < 0 || margin.Left < 0 {
 		return nil, fmt.Errorf("margin values must be non-negative")
 	}
+	if opts.Position == "" {
+		opts.Position = TopRight
+	}
+	if !ValidPosition(opts.Position) {
+		return nil, fmt.Errorf("unknown text position %q", opts.Position)
+	}
+	if opts.Color == nil {
+		opts.Color = color.White
+	}
 
 	bounds := src.Bounds()
-	if bounds.Dx() != 64 || bounds.Dy() != 64 {
-		return nil, fmt.Errorf("expected 64x64 image, got %dx%d", bounds.Dx(), bounds.Dy())
+	if bounds.Empty() {
+		return nil, fmt.Errorf("empty source image")
 	}
 
 	dst := image.NewRGBA(bounds)
@@ -66,22 +105,11 @@ func OverlayTopRightText(src image.Image, text string, margin Margin, textHeight
 		return dst, nil
 	}
 
-	fontParsed, err := loadFont()
+	face, err := newFace(opts.Height)
 	if err != nil {
 		return nil, err
 	}
-
-	face, err := opentype.NewFace(fontParsed, &opentype.FaceOptions{
-		Size:    textHeight,
-		DPI:     72,
-		Hinting: font.HintingFull,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("create font face: %w", err)
-	}
-	if closer, ok := face.(interface{ Close() error }); ok {
-		defer closer.Close()
-	}
+	defer face.Close()
 
 	measureDrawer := font.Drawer{Face: face}
 	textWidth := measureDrawer.MeasureString(text).Ceil()
@@ -89,13 +117,28 @@ func OverlayTopRightText(src image.Image, text string, margin Margin, textHeight
 		return dst, nil
 	}
 
-	x := bounds.Max.X - margin.Right - textWidth
+	metrics := face.Metrics()
+	ascent, descent := metrics.Ascent.Round(), metrics.Descent.Round()
+	var x, baseline int
+	switch opts.Position {
+	case TopLeft, BottomLeft:
+		x = bounds.Min.X + margin.Left
+	case TopRight, BottomRight:
+		x = bounds.Max.X - margin.Right - textWidth
+	default:
+		x = bounds.Min.X + (bounds.Dx()-textWidth)/2
+	}
+	switch opts.Position {
+	case TopLeft, TopRight:
+		baseline = bounds.Min.Y + margin.Top + ascent
+	case BottomLeft, BottomRight:
+		baseline = bounds.Max.Y - margin.Bottom - descent
+	default:
+		baseline = bounds.Min.Y + (bounds.Dy()-ascent-descent)/2 + ascent
+	}
 	if x < bounds.Min.X {
 		x = bounds.Min.X
 	}
-
-	metrics := face.Metrics()
-	baseline := bounds.Min.Y + margin.Top + metrics.Ascent.Round()
 	if baseline > bounds.Max.Y {
 		baseline = bounds.Max.Y
 	}
@@ -113,11 +156,65 @@ func OverlayTopRightText(src image.Image, text string, margin Margin, textHeight
 	drawer.DrawString(text)
 	thresholdAlpha(mask, 0x80)
 
-	draw.DrawMask(dst, bounds, image.NewUniform(color.White), image.Point{}, mask, bounds.Min, draw.Over)
+	draw.DrawMask(dst, bounds, image.NewUniform(opts.Color), image.Point{}, mask, bounds.Min, draw.Over)
+
+	return dst, nil
+}
+
+// TextLine renders text on a transparent strip just wide enough to hold it,
+// using the embedded font at textHeight pixels. Glyph edges are thresholded so
+// they stay crisp on low-resolution LED panels. The strip is meant to be
+// positioned by the caller, for example when scrolling a marquee.
+func TextLine(text string, textHeight float64, col color.Color) (*image.RGBA, error) {
+	if textHeight <= 0 {
+		return nil, fmt.Errorf("text height must be positive")
+	}
+	face, err := newFace(textHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	width := font.MeasureString(face, text).Ceil()
+	if width <= 0 || height <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, height)), nil
+	}
+
+	bounds := image.Rect(0, 0, width, height)
+	mask := image.NewAlpha(bounds)
+	drawer := &font.Drawer{
+		Dst:  mask,
+		Src:  image.NewUniform(color.Opaque),
+		Face: face,
+		Dot:  fixed.Point26_6{X: 0, Y: metrics.Ascent},
+	}
+	drawer.DrawString(text)
+	thresholdAlpha(mask, 0x80)
 
+	dst := image.NewRGBA(bounds)
+	draw.DrawMask(dst, bounds, image.NewUniform(col), image.Point{}, mask, image.Point{}, draw.Over)
 	return dst, nil
 }
 
+func newFace(textHeight float64) (font.Face, error) {
+	fontParsed, err := loadFont()
+	if err != nil {
+		return nil, err
+	}
+
+	face, err := opentype.NewFace(fontParsed, &opentype.FaceOptions{
+		Size:    textHeight,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create font face: %w", err)
+	}
+	return face, nil
+}
+
 func thresholdAlpha(img *image.Alpha, threshold uint8) {
 	if img == nil {
 		return
@@ -0,0 +1,106 @@
+package overlay
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/image/font"
+)
+
+const (
+	placeholderTextHeight = 11
+	placeholderMargin     = 3
+)
+
+// placeholderBackgrounds are the muted colors placeholders are drawn on. The
+// text picks one, so the same station always gets the same color.
+var placeholderBackgrounds = []color.RGBA{
+	{R: 0x6a, G: 0x1b, B: 0x4d, A: 0xff},
+	{R: 0x1b, G: 0x4d, B: 0x6a, A: 0xff},
+	{R: 0x2e, G: 0x5e, B: 0x1b, A: 0xff},
+	{R: 0x6a, G: 0x3d, B: 0x1b, A: 0xff},
+	{R: 0x3d, G: 0x1b, B: 0x6a, A: 0xff},
+	{R: 0x1b, G: 0x5e, B: 0x55, A: 0xff},
+}
+
+// Placeholder renders artwork for tracks that have none: text, such as the
+// title or station name, word-wrapped and centered in white on a colored
+// square of size pixels. Lines that do not fit are dropped from the bottom.
+func Placeholder(text string, size int) (*image.RGBA, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("placeholder size must be positive")
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(PlaceholderColor(text)), image.Point{}, draw.Src)
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return dst, nil
+	}
+	face, err := newFace(placeholderTextHeight)
+	if err != nil {
+		return nil, err
+	}
+	lines := wrapText(face, text, size-2*placeholderMargin)
+	face.Close()
+
+	lineHeight := 0
+	strips := make([]*image.RGBA, 0, len(lines))
+	for _, line := range lines {
+		strip, err := TextLine(line, placeholderTextHeight, color.White)
+		if err != nil {
+			return nil, err
+		}
+		lineHeight = strip.Bounds().Dy()
+		strips = append(strips, strip)
+	}
+	if maxLines := (size - 2*placeholderMargin) / max(lineHeight, 1); len(strips) > maxLines {
+		strips = strips[:maxLines]
+	}
+
+	y := (size - lineHeight*len(strips)) / 2
+	for _, strip := range strips {
+		x := (size - strip.Bounds().Dx()) / 2
+		if x < placeholderMargin {
+			x = placeholderMargin
+		}
+		r := image.Rect(x, y, x+strip.Bounds().Dx(), y+lineHeight).Intersect(dst.Bounds())
+		draw.Draw(dst, r, strip, image.Point{}, draw.Over)
+		y += lineHeight
+	}
+	return dst, nil
+}
+
+// PlaceholderColor is the background Placeholder uses for text.
+func PlaceholderColor(text string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(strings.TrimSpace(text))))
+	return placeholderBackgrounds[h.Sum32()%uint32(len(placeholderBackgrounds))]
+}
+
+// wrapText breaks text into lines no wider than width. A word wider than
+// width gets a line of its own and is clipped when drawn.
+func wrapText(face font.Face, text string, width int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current = candidate
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,47 @@
+package overlay
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// ProgressBar draws a one-pixel-high bar along the bottom row of dst. The
+// first fraction of the row is painted with fill and the remainder with track.
+// A nil track leaves the unplayed part of the row untouched.
+func ProgressBar(dst draw.Image, fraction float64, fill, track color.Color) {
+	if dst == nil {
+		return
+	}
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	bounds := dst.Bounds()
+	if bounds.Empty() {
+		return
+	}
+	y := bounds.Max.Y - 1
+	filled := ProgressPixels(bounds.Dx(), fraction)
+
+	if filled > 0 {
+		draw.Draw(dst, image.Rect(bounds.Min.X, y, bounds.Min.X+filled, y+1), image.NewUniform(fill), image.Point{}, draw.Src)
+	}
+	if track != nil && filled < bounds.Dx() {
+		draw.Draw(dst, image.Rect(bounds.Min.X+filled, y, bounds.Max.X, y+1), image.NewUniform(track), image.Point{}, draw.Src)
+	}
+}
+
+// ProgressPixels returns how many of width pixels a bar at fraction fills.
+func ProgressPixels(width int, fraction float64) int {
+	if fraction <= 0 || width <= 0 {
+		return 0
+	}
+	if fraction >= 1 {
+		return width
+	}
+	return int(fraction*float64(width) + 0.5)
+}
@@ -0,0 +1,136 @@
+// Package powersave hints the CPU frequency governor with what the display
+// is doing, so a Raspberry Pi on a battery or UPS idles at its lowest clock
+// while nothing plays and speeds up again for the music.
+package powersave
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"musicDisplay/logging"
+)
+
+var logger = logging.For("powersave")
+
+const (
+	// DefaultDir holds the CPUs' cpufreq settings on Linux.
+	DefaultDir = "/sys/devices/system/cpu"
+	// DefaultIdle and DefaultActive are the governors used while the room
+	// is idle and while it plays.
+	DefaultIdle   = "powersave"
+	DefaultActive = "ondemand"
+)
+
+// Governors are the cpufreq governors Linux offers; a kernel may build
+// only some of them.
+var Governors = []string{"conservative", "ondemand", "performance", "powersave", "schedutil", "userspace"}
+
+// ValidGovernor reports whether name is one of Governors, or empty for the
+// default.
+func ValidGovernor(name string) bool {
+	return name == "" || slices.Contains(Governors, name)
+}
+
+// Governor switches every CPU's cpufreq governor between an idle and an
+// active one. Changing governors needs root; when a change fails it is
+// logged once and the governors are left alone from then on.
+type Governor struct {
+	dir    string
+	idle   string
+	active string
+
+	mu      sync.Mutex
+	current string
+	failed  bool
+	// original is each CPU's governor before the first change, by the path
+	// of its scaling_governor file.
+	original map[string]string
+}
+
+// NewGovernor returns a governor switching the CPUs under dir (DefaultDir
+// when empty) to idle or active (DefaultIdle and DefaultActive when
+// empty). Nothing changes until SetIdle is called.
+func NewGovernor(dir, idle, active string) *Governor {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if idle == "" {
+		idle = DefaultIdle
+	}
+	if active == "" {
+		active = DefaultActive
+	}
+	return &Governor{dir: dir, idle: idle, active: active}
+}
+
+// SetIdle switches the CPUs to the idle governor, or the active one.
+func (g *Governor) SetIdle(idle bool) {
+	name := g.active
+	if idle {
+		name = g.idle
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failed || name == g.current {
+		return
+	}
+	if err := g.set(name); err != nil {
+		g.failed = true
+		logger.Warn("cpu governor hints disabled", "err", err)
+		return
+	}
+	g.current = name
+	logger.Debug("cpu governor changed", "governor", name)
+}
+
+// Restore puts back the governors the CPUs had before the first change.
+func (g *Governor) Restore() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for path, name := range g.original {
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			logger.Warn("restore cpu governor", "path", path, "err", err)
+		}
+	}
+	g.original = nil
+	g.current = ""
+}
+
+// set writes name to every CPU's governor. Callers must hold g.mu.
+func (g *Governor) set(name string) error {
+	paths, err := filepath.Glob(filepath.Join(g.dir, "cpu[0-9]*", "cpufreq", "scaling_governor"))
+	if err != nil {
+		return fmt.Errorf("powersave: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("powersave: no cpufreq governors under %s", g.dir)
+	}
+	for _, path := range paths {
+		available, err := os.ReadFile(filepath.Join(filepath.Dir(path), "scaling_available_governors"))
+		if err == nil && !slices.Contains(strings.Fields(string(available)), name) {
+			return fmt.Errorf("powersave: %s: governor %q not available, only %s", path, name, strings.TrimSpace(string(available)))
+		}
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("powersave: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				return fmt.Errorf("powersave: %s: run as root to change governors: %w", path, err)
+			}
+			return fmt.Errorf("powersave: %w", err)
+		}
+		if g.original == nil {
+			g.original = map[string]string{}
+		}
+		if _, ok := g.original[path]; !ok {
+			g.original[path] = strings.TrimSpace(string(before))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package powersave
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCPUs lays out a cpufreq tree with cpus CPUs on governor.
+func fakeCPUs(t *testing.T, cpus int, governor string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i < cpus; i++ {
+		cpufreq := filepath.Join(dir, fmt.Sprintf("cpu%d", i), "cpufreq")
+		if err := os.MkdirAll(cpufreq, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(cpufreq, "scaling_governor"), []byte(governor+"\n"), 0o644)
+		os.WriteFile(filepath.Join(cpufreq, "scaling_available_governors"), []byte("conservative ondemand userspace powersave performance schedutil\n"), 0o644)
+	}
+	// Not a CPU.
+	os.MkdirAll(filepath.Join(dir, "cpuidle"), 0o755)
+	return dir
+}
+
+func governors(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, _ := filepath.Glob(filepath.Join(dir, "cpu[0-9]*", "cpufreq", "scaling_governor"))
+	var names []string
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, strings.TrimSpace(string(b)))
+	}
+	return names
+}
+
+func TestGovernorSwitchesAndRestores(t *testing.T) {
+	dir := fakeCPUs(t, 4, "schedutil")
+	g := NewGovernor(dir, "", "")
+
+	g.SetIdle(true)
+	if got := strings.Join(governors(t, dir), " "); got != "powersave powersave powersave powersave" {
+		t.Fatalf("idle governors = %s", got)
+	}
+	g.SetIdle(false)
+	if got := strings.Join(governors(t, dir), " "); got != "ondemand ondemand ondemand ondemand" {
+		t.Fatalf("active governors = %s", got)
+	}
+	g.Restore()
+	if got := strings.Join(governors(t, dir), " "); got != "schedutil schedutil schedutil schedutil" {
+		t.Fatalf("restored governors = %s", got)
+	}
+}
+
+func TestGovernorGivesUpWhenUnavailable(t *testing.T) {
+	dir := fakeCPUs(t, 2, "ondemand")
+	os.WriteFile(filepath.Join(dir, "cpu0", "cpufreq", "scaling_available_governors"), []byte("ondemand performance\n"), 0o644)
+	g := NewGovernor(dir, "", "")
+
+	g.SetIdle(true)
+	if got := strings.Join(governors(t, dir), " "); got != "ondemand ondemand" {
+		t.Fatalf("governors = %s, want them left alone", got)
+	}
+	if !g.failed {
+		t.Fatal("failure not remembered")
+	}
+
+	g = NewGovernor(t.TempDir(), "", "")
+	g.SetIdle(true)
+	if !g.failed {
+		t.Fatal("no cpufreq tree did not fail")
+	}
+}
+
+func TestValidGovernor(t *testing.T) {
+	for name, want := range map[string]bool{"": true, "powersave": true, "schedutil": true, "turbo": false} {
+		if got := ValidGovernor(name); got != want {
+			t.Errorf("ValidGovernor(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"image"
+
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+)
+
+// SourceBadgeOptions configures the icon marking where the current track
+// plays from: Spotify, radio, AirPlay, or TV. Music from the library and
+// other services gets no badge.
+type SourceBadgeOptions struct {
+	Enabled bool
+	// Corner is one of the overlay corner names (default overlay.TopLeft).
+	Corner string
+	// Size is the icon size in pixels, overlay.IconSize (default) or
+	// overlay.LargeIconSize.
+	Size int
+}
+
+func (o SourceBadgeOptions) withDefaults() SourceBadgeOptions {
+	if !overlay.ValidCorner(o.Corner) {
+		o.Corner = overlay.TopLeft
+	}
+	if o.Size != overlay.LargeIconSize {
+		o.Size = overlay.IconSize
+	}
+	return o
+}
+
+// sourceIcon names the badge icon for track, or "" for none.
+func sourceIcon(track sonos.TrackInfo) string {
+	if track.Service == "Spotify" {
+		return overlay.IconSpotify
+	}
+	switch track.Source {
+	case sonos.SourceRadio:
+		return overlay.IconRadio
+	case sonos.SourceAirPlay:
+		return overlay.IconAirPlay
+	case sonos.SourceTV:
+		return overlay.IconTV
+	}
+	return ""
+}
+
+// stampSourceBadge draws the badge for track onto frame, if it has one.
+func stampSourceBadge(frame *image.RGBA, track sonos.TrackInfo, opts SourceBadgeOptions) error {
+	name := sourceIcon(track)
+	if name == "" {
+		return nil
+	}
+	icon, err := overlay.Icon(name)
+	if err != nil {
+		return err
+	}
+	overlay.StampIcon(frame, icon, opts.Corner, opts.Size)
+	return nil
+}
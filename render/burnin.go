@@ -0,0 +1,173 @@
+package render
+
+import (
+	"image"
+	"math"
+	"time"
+)
+
+const (
+	defaultBlackDuration = 2 * time.Second
+	defaultBrightnessMin = 70
+
+	// minBurnInStep is the shortest wait between burn-in updates, which
+	// bounds how often brightness cycling redraws a static frame.
+	minBurnInStep = time.Second
+)
+
+// driftOffsets is the square of positions drift steps through.
+var driftOffsets = [...]image.Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+
+// BurnInOptions configures burn-in protection for frames that stay on screen
+// for a long time. Each mechanism is off while its interval or period is
+// zero.
+type BurnInOptions struct {
+	// DriftInterval moves the whole frame by one pixel this often, around
+	// a 2x2 square, so static edges do not wear the same LEDs.
+	DriftInterval time.Duration
+	// BlackInterval shows a fully black frame for BlackDuration (default
+	// 2s) at the end of each interval.
+	BlackInterval time.Duration
+	BlackDuration time.Duration
+	// BrightnessPeriod cycles brightness smoothly down to BrightnessMin
+	// percent (default 70) and back over this period.
+	BrightnessPeriod time.Duration
+	BrightnessMin    int
+}
+
+func (o BurnInOptions) withDefaults() BurnInOptions {
+	if o.BlackDuration <= 0 {
+		o.BlackDuration = defaultBlackDuration
+	}
+	if o.BlackInterval > 0 && o.BlackDuration >= o.BlackInterval {
+		o.BlackDuration = o.BlackInterval / 2
+	}
+	if o.BrightnessMin <= 0 || o.BrightnessMin > 100 {
+		o.BrightnessMin = defaultBrightnessMin
+	}
+	return o
+}
+
+// enabled reports whether any mechanism is on.
+func (o BurnInOptions) enabled() bool {
+	return o.DriftInterval > 0 || o.BlackInterval > 0 || o.BrightnessPeriod > 0
+}
+
+// burnInState applies burn-in protection relative to start, which is set
+// from the renderer's clock the first time it is consulted.
+type burnInState struct {
+	opts  BurnInOptions
+	start time.Time
+}
+
+// elapsed returns how long protection has run at now, starting the count
+// on the first call.
+func (b *burnInState) elapsed(now time.Time) time.Duration {
+	if b.start.IsZero() {
+		b.start = now
+	}
+	return now.Sub(b.start)
+}
+
+// black reports whether now falls in a black-frame window.
+func (b *burnInState) black(now time.Time) bool {
+	if b.opts.BlackInterval <= 0 {
+		return false
+	}
+	return b.elapsed(now)%b.opts.BlackInterval >= b.opts.BlackInterval-b.opts.BlackDuration
+}
+
+// offset returns the drift offset in effect at now.
+func (b *burnInState) offset(now time.Time) image.Point {
+	if b.opts.DriftInterval <= 0 {
+		return image.Point{}
+	}
+	step := int(b.elapsed(now) / b.opts.DriftInterval)
+	return driftOffsets[step%len(driftOffsets)]
+}
+
+// brightness returns the brightness percentage in effect at now, starting at
+// full brightness.
+func (b *burnInState) brightness(now time.Time) int {
+	if b.opts.BrightnessPeriod <= 0 {
+		return 100
+	}
+	phase := float64(b.elapsed(now)%b.opts.BrightnessPeriod) / float64(b.opts.BrightnessPeriod)
+	swing := float64(100 - b.opts.BrightnessMin)
+	return b.opts.BrightnessMin + int(math.Round(swing*(1+math.Cos(2*math.Pi*phase))/2))
+}
+
+// wait returns how long after now the protected frame next changes, or 0
+// when burn-in protection is off.
+func (b *burnInState) wait(now time.Time) time.Duration {
+	if !b.opts.enabled() {
+		return 0
+	}
+	elapsed := b.elapsed(now)
+	var wait time.Duration
+	consider := func(d time.Duration) {
+		if d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+	if b.opts.DriftInterval > 0 {
+		consider(b.opts.DriftInterval - elapsed%b.opts.DriftInterval)
+	}
+	if b.opts.BlackInterval > 0 {
+		into := elapsed % b.opts.BlackInterval
+		if blackAt := b.opts.BlackInterval - b.opts.BlackDuration; into < blackAt {
+			consider(blackAt - into)
+		} else {
+			consider(b.opts.BlackInterval - into)
+		}
+	}
+	if b.opts.BrightnessPeriod > 0 && b.opts.BrightnessMin < 100 {
+		// One percent of brightness changes at most this often.
+		consider(b.opts.BrightnessPeriod / time.Duration(2*(100-b.opts.BrightnessMin)))
+	}
+	return max(wait, minBurnInStep)
+}
+
+// apply protects frame in place for now.
+func (b *burnInState) apply(frame *image.RGBA, now time.Time) {
+	if !b.opts.enabled() {
+		return
+	}
+	if b.black(now) {
+		dimFrame(frame, 0)
+		return
+	}
+	if offset := b.offset(now); offset != (image.Point{}) {
+		shiftFrame(frame, offset)
+	}
+	if level := b.brightness(now); level < 100 {
+		dimFrame(frame, level)
+	}
+}
+
+// shiftFrame moves frame's pixels right by offset.X and down by offset.Y,
+// filling the uncovered edges with black.
+func shiftFrame(frame *image.RGBA, offset image.Point) {
+	w, h := frame.Rect.Dx(), frame.Rect.Dy()
+	if offset.X >= w || offset.Y >= h {
+		dimFrame(frame, 0)
+		return
+	}
+	for y := h - 1; y >= 0; y-- {
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+w*4]
+		if y >= offset.Y {
+			src := frame.Pix[(y-offset.Y)*frame.Stride:]
+			copy(row[offset.X*4:], src[:(w-offset.X)*4])
+			clearPixels(row[:offset.X*4])
+		} else {
+			clearPixels(row)
+		}
+	}
+}
+
+// clearPixels sets the RGBA pixels in pix to opaque black.
+func clearPixels(pix []uint8) {
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = 0, 0, 0, 0xff
+	}
+}
@@ -0,0 +1,137 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/render/text"
+	"musicDisplay/theme"
+)
+
+const (
+	// IdleBlank turns the panel off once the listener reports idle.
+	IdleBlank = "blank"
+	// IdleClock shows a large clock once the listener reports idle.
+	IdleClock = "clock"
+	// IdleAnimation loops IdleOptions.Animation once the listener reports
+	// idle, or blanks the panel when no animation is loaded.
+	IdleAnimation = "animation"
+	// IdleScript shows IdleOptions.Script once the listener reports idle, or
+	// blanks the panel when no script is loaded.
+	IdleScript = "script"
+
+	defaultClockBrightness = 40
+	// maxClockHeight is the tallest the clock digits are drawn.
+	maxClockHeight = 24
+)
+
+// IdleOptions selects what the renderer shows when nothing is playing.
+type IdleOptions struct {
+	// Screen is IdleBlank (default), IdleClock, IdleAnimation, or
+	// IdleScript. The active theme's IdleScreen, when set, takes precedence.
+	Screen string
+	Clock  ClockOptions
+	// Animation holds the frames of the idle animation, already fitted to
+	// Options.Size.
+	Animation []matrixdisplay.Frame
+	// Script draws the idle screen over the theme background. It is redrawn
+	// every minute, on status updates, and at Options.FPS while it is an
+	// animated layer.
+	Script Layer
+}
+
+// ClockOptions configures the idle clock.
+type ClockOptions struct {
+	// TwelveHour renders "3:04" with an AM/PM marker instead of "15:04".
+	TwelveHour bool
+	// Brightness is the clock's intensity as a percentage of the theme text
+	// color (1..100, default 40) so the idle screen stays unobtrusive.
+	Brightness int
+}
+
+func (o IdleOptions) withDefaults() IdleOptions {
+	if o.Screen != IdleClock && o.Screen != IdleAnimation && o.Screen != IdleScript {
+		o.Screen = IdleBlank
+	}
+	if o.Clock.Brightness <= 0 || o.Clock.Brightness > 100 {
+		o.Clock.Brightness = defaultClockBrightness
+	}
+	return o
+}
+
+// clockLabel returns the text shown for now and, in 12-hour mode, the AM/PM
+// marker.
+func clockLabel(now time.Time, opts ClockOptions) (string, string) {
+	if !opts.TwelveHour {
+		return now.Format("15:04"), ""
+	}
+	return now.Format("3:04"), now.Format("PM")
+}
+
+// drawClock renders a large centered clock into frame.
+func drawClock(frame *image.RGBA, now time.Time, opts ClockOptions, palette theme.Palette) error {
+	bounds := frame.Bounds()
+	draw.Draw(frame, bounds, image.NewUniform(palette.Background), image.Point{}, draw.Src)
+
+	textColor := ScaleColor(palette.Text, opts.Brightness)
+	label, marker := clockLabel(now, opts)
+
+	scale := fitScale(text.Medium, label, bounds.Dx()-4, maxClockHeight)
+	digits := text.Medium.Render(label, textColor, scale).SubImage(image.Rect(0, 0, text.Medium.Measure(label)*scale, text.Medium.Ascent()*scale)).(*image.RGBA)
+
+	var suffix *image.RGBA
+	if marker != "" {
+		suffix = text.Small.Render(marker, textColor, 1)
+	}
+
+	totalH := digits.Bounds().Dy()
+	if suffix != nil {
+		totalH += suffix.Bounds().Dy() + 1
+	}
+	y := bounds.Min.Y + (bounds.Dy()-totalH)/2
+	blitCentered(frame, digits, y)
+	if suffix != nil {
+		blitCentered(frame, suffix, y+digits.Bounds().Dy()+1)
+	}
+	return nil
+}
+
+// fitScale returns the largest whole scale, at least 1, at which s in font
+// fits in maxWidth by maxHeight pixels.
+func fitScale(font *text.Font, s string, maxWidth, maxHeight int) int {
+	scale := 1
+	for next := 2; font.Measure(s)*next <= maxWidth && font.Ascent()*next <= maxHeight; next++ {
+		scale = next
+	}
+	return scale
+}
+
+func blitCentered(frame *image.RGBA, src *image.RGBA, y int) {
+	bounds := frame.Bounds()
+	w := src.Bounds().Dx()
+	x := bounds.Min.X + (bounds.Dx()-w)/2
+	dst := image.Rect(x, y, x+w, y+src.Bounds().Dy())
+	draw.Draw(frame, dst.Intersect(bounds), src, dst.Intersect(bounds).Min.Sub(dst.Min), draw.Over)
+}
+
+// ScaleColor returns c at percent of its intensity, or c itself from 100
+// up.
+func ScaleColor(c color.RGBA, percent int) color.RGBA {
+	if percent >= 100 {
+		return c
+	}
+	return color.RGBA{
+		R: uint8(int(c.R) * percent / 100),
+		G: uint8(int(c.G) * percent / 100),
+		B: uint8(int(c.B) * percent / 100),
+		A: 0xff,
+	}
+}
+
+// untilNextMinute returns how long until the wall clock next changes minute.
+func untilNextMinute(now time.Time) time.Duration {
+	return now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+// NowPlayingFrame composes the frame the display shows while track plays,
+// with the decorations opts enables over art, so other programs, such as an
+// e-paper dashboard or a terminal widget, can draw the same picture without
+// running the app. art is used at its own size; a nil art draws the
+// decorations over a black frame of opts.Size.
+//
+// The frame is a still: a ticker is drawn at the start of its text, and
+// transitions, burn-in protection, and rotation are left out. Colors come
+// from theme.DefaultPalette, or from the artwork with opts.ArtPalette. A
+// decoration that cannot be drawn is logged and left out.
+func NowPlayingFrame(track sonos.TrackInfo, art image.Image, opts Options) image.Image {
+	opts.Transition = TransitionOptions{}
+	opts.BurnIn = BurnInOptions{}
+	opts.Rotation = RotationOptions{}
+	opts.Screens = nil
+	if art == nil {
+		size := opts.Size
+		if size.X <= 0 || size.Y <= 0 {
+			size = image.Pt(defaultFrameSize, defaultFrameSize)
+		}
+		art = image.NewRGBA(image.Rectangle{Max: size})
+	}
+
+	capture := &frameCapture{}
+	r := New(capture, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), opts)
+	r.UpdateStatus(sonos.PlaybackStatus{
+		State:   "Playing",
+		Track:   track,
+		Playing: true,
+	})
+	if err := r.Show(art); err != nil {
+		logger.Warn("compose now playing frame", "err", err)
+	}
+	if capture.frame == nil {
+		// Nothing could be drawn over the art; show it as it is.
+		frame := image.NewRGBA(image.Rectangle{Max: art.Bounds().Size()})
+		draw.Draw(frame, frame.Rect, art, art.Bounds().Min, draw.Src)
+		return frame
+	}
+	return capture.frame
+}
+
+// frameCapture is a display that keeps a copy of the last frame shown.
+type frameCapture struct {
+	frame *image.RGBA
+}
+
+func (c *frameCapture) Show(img image.Image) error {
+	frame := image.NewRGBA(image.Rectangle{Max: img.Bounds().Size()})
+	draw.Draw(frame, frame.Rect, img, img.Bounds().Min, draw.Src)
+	c.frame = frame
+	return nil
+}
+
+func (c *frameCapture) Clear() error { return nil }
+
+func (c *frameCapture) Close() error { return nil }
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+// ScreenLargeText names the large-text screen, which SetLargeText shows in
+// place of everything else.
+const ScreenLargeText = "large_text"
+
+// largeTextState holds the three scrolling lines of the large-text screen:
+// the title across the top half, then the artist and the playback state.
+type largeTextState struct {
+	title, artist, state tickerState
+}
+
+// draw fills frame with status in text as large as the frame allows. Lines
+// too wide for the frame scroll like the ticker.
+func (l *largeTextState) draw(frame *image.RGBA, status sonos.PlaybackStatus, palette theme.Palette) error {
+	bounds := frame.Bounds()
+	draw.Draw(frame, bounds, image.NewUniform(palette.Background), image.Point{}, draw.Src)
+	titleRows := bounds.Dy() / 2
+	lineRows := bounds.Dy() / 4
+	title, artist, state := largeTextLines(status)
+	bands := []struct {
+		line  *tickerState
+		label string
+		band  image.Rectangle
+	}{
+		{&l.title, title, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Min.Y+titleRows)},
+		{&l.artist, artist, image.Rect(bounds.Min.X, bounds.Min.Y+titleRows, bounds.Max.X, bounds.Min.Y+titleRows+lineRows)},
+		{&l.state, state, image.Rect(bounds.Min.X, bounds.Max.Y-lineRows, bounds.Max.X, bounds.Max.Y)},
+	}
+	for i, b := range bands {
+		col := palette.Text
+		if i == len(bands)-1 {
+			col = palette.Accent
+		}
+		if b.label != b.line.text {
+			b.line.offset = 0
+		}
+		if err := b.line.prepare(b.label, b.band.Dy(), col, bounds); err != nil {
+			return err
+		}
+		b.line.drawText(frame, b.band)
+	}
+	return nil
+}
+
+// scrolls reports whether any line is too wide to fit.
+func (l *largeTextState) scrolls() bool {
+	return l.title.scrolls() || l.artist.scrolls() || l.state.scrolls()
+}
+
+// scroll moves every line on by pixels.
+func (l *largeTextState) scroll(pixels float64) {
+	l.title.scroll(pixels)
+	l.artist.scroll(pixels)
+	l.state.scroll(pixels)
+}
+
+// largeTextLines returns the title, artist, and state lines for status.
+// Without a title the top line falls back like the ticker, to the artist,
+// the stream, or the source; without a state it reads "Idle".
+func largeTextLines(status sonos.PlaybackStatus) (title, artist, state string) {
+	title = strings.TrimSpace(status.Track.Title)
+	if title != "" {
+		artist = strings.TrimSpace(status.Track.Artist)
+	} else {
+		title = tickerText(status.Track)
+	}
+	state = strings.TrimSpace(status.State)
+	if state == "" {
+		state = "Idle"
+	}
+	return title, artist, state
+}
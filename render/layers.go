@@ -0,0 +1,103 @@
+package render
+
+import (
+	"image"
+	"sort"
+	"time"
+
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+// Layer draws one element over the now-playing frame. The renderer
+// composites the built-in decorations (ticker, banner, progress bar, source
+// badge) and then Options.Layers, in order, into a single frame per update,
+// so features never hand frames to the output themselves.
+type Layer interface {
+	Draw(frame *image.RGBA, state FrameState) error
+}
+
+// AnimatedLayer is a Layer whose drawing changes over time. While any layer
+// reports Animating, the render loop produces frames at Options.FPS.
+type AnimatedLayer interface {
+	Layer
+	Animating() bool
+}
+
+// Overlay is a Layer drawn over every frame, whatever it shows: the
+// artwork, idle and focus screens, large text, and special days. It is for
+// notices such as notifications and warnings. The renderer draws an
+// overlay only while it is Visible, and draws those in order of rising
+// Priority, so the highest ends up on top. An overlay that moves also
+// implements AnimatedLayer. Overlays change on their own, so their owner
+// calls Renderer.Wake to have a change drawn.
+type Overlay interface {
+	Layer
+	Priority() int
+	Visible() bool
+}
+
+// FrameState is what layers draw from.
+type FrameState struct {
+	Status  sonos.PlaybackStatus
+	Palette theme.Palette
+	// Now is the time the frame is composed for.
+	Now time.Time
+}
+
+// LayerFunc adapts a function to Layer.
+type LayerFunc func(frame *image.RGBA, state FrameState) error
+
+// Draw calls f.
+func (f LayerFunc) Draw(frame *image.RGBA, state FrameState) error {
+	return f(frame, state)
+}
+
+// layersAnimating reports whether any of layers is animated and moving.
+func layersAnimating(layers []Layer) bool {
+	for _, layer := range layers {
+		if animated, ok := layer.(AnimatedLayer); ok && animated.Animating() {
+			return true
+		}
+	}
+	return false
+}
+
+// sortOverlays returns overlays in drawing order, lowest Priority first.
+func sortOverlays(overlays []Overlay) []Overlay {
+	sorted := append([]Overlay(nil), overlays...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+	return sorted
+}
+
+// overlaysAnimating reports whether a visible overlay moves. Callers must
+// hold r.mu.
+func (r *Renderer) overlaysAnimating() bool {
+	for _, overlay := range r.opts.Overlays {
+		if animated, ok := overlay.(AnimatedLayer); ok && overlay.Visible() && animated.Animating() {
+			return true
+		}
+	}
+	return false
+}
+
+// drawOverlays draws the visible overlays over frame and reports whether
+// any of them moves. Callers must hold r.mu.
+func (r *Renderer) drawOverlays(frame *image.RGBA) (bool, error) {
+	moving := false
+	state := r.frameState()
+	for _, overlay := range r.opts.Overlays {
+		if !overlay.Visible() {
+			continue
+		}
+		if err := overlay.Draw(frame, state); err != nil {
+			return moving, err
+		}
+		if animated, ok := overlay.(AnimatedLayer); ok && animated.Animating() {
+			moving = true
+		}
+	}
+	return moving, nil
+}
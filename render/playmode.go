@@ -0,0 +1,73 @@
+package render
+
+import (
+	"image"
+	"strings"
+
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+)
+
+// PlayModeOptions configures the small icons showing that the room
+// shuffles, repeats, or crossfades. Nothing is drawn in normal play.
+type PlayModeOptions struct {
+	Enabled bool
+	// Corner is one of the overlay corner names (default overlay.TopRight,
+	// clear of the source badge).
+	Corner string
+	// Size is the icon size in pixels, overlay.IconSize (default) or
+	// overlay.LargeIconSize.
+	Size int
+}
+
+func (o PlayModeOptions) withDefaults() PlayModeOptions {
+	if !overlay.ValidCorner(o.Corner) {
+		o.Corner = overlay.TopRight
+	}
+	if o.Size != overlay.LargeIconSize {
+		o.Size = overlay.IconSize
+	}
+	return o
+}
+
+// playModeIcons names the indicator icons for status, in drawing order.
+func playModeIcons(status sonos.PlaybackStatus) []string {
+	var names []string
+	if status.PlayMode.Shuffle() {
+		names = append(names, overlay.IconShuffle)
+	}
+	switch {
+	case status.PlayMode.RepeatOne():
+		names = append(names, overlay.IconRepeatOne)
+	case status.PlayMode.RepeatAll():
+		names = append(names, overlay.IconRepeat)
+	}
+	if status.Crossfade {
+		names = append(names, overlay.IconCrossfade)
+	}
+	return names
+}
+
+// playModeKey identifies the indicators drawn for status, to tell when they
+// change.
+func playModeKey(status sonos.PlaybackStatus) string {
+	return strings.Join(playModeIcons(status), ",")
+}
+
+// stampPlayMode draws the indicators for status onto frame, if any.
+func stampPlayMode(frame *image.RGBA, status sonos.PlaybackStatus, opts PlayModeOptions) error {
+	names := playModeIcons(status)
+	if len(names) == 0 {
+		return nil
+	}
+	icons := make([]image.Image, 0, len(names))
+	for _, name := range names {
+		icon, err := overlay.Icon(name)
+		if err != nil {
+			return err
+		}
+		icons = append(icons, icon)
+	}
+	overlay.StampIcons(frame, icons, opts.Corner, opts.Size)
+	return nil
+}
@@ -0,0 +1,894 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"time"
+
+	"musicDisplay/logging"
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+var logger = logging.For("render")
+
+// Options selects which decorations the renderer draws over album art.
+type Options struct {
+	// ShowProgress draws a progress bar along the bottom row while the track
+	// length is known.
+	ShowProgress bool
+	// Ticker enables the scrolling "Artist – Title" band at the bottom of the
+	// frame.
+	Ticker TickerOptions
+	// SourceBadge marks Spotify, radio, AirPlay, and TV tracks with a small
+	// icon in a corner of the art.
+	SourceBadge SourceBadgeOptions
+	// PlayMode marks shuffle, repeat, and crossfade with small icons in a
+	// corner of the art.
+	PlayMode PlayModeOptions
+	// ArtPalette colors the progress bar, ticker, and idle clock from the
+	// current artwork instead of the theme. The idle screen keeps the colors
+	// of the last artwork shown.
+	ArtPalette bool
+	// Idle selects the screen shown after the listener clears the display.
+	Idle IdleOptions
+	// Transition animates the change from one artwork to the next.
+	Transition TransitionOptions
+	// Size is the frame size used for screens drawn without artwork. It
+	// defaults to 64x64.
+	Size image.Point
+	// DimLevel is the brightness, in percent, of frames drawn while the
+	// display is dimmed (default 30).
+	DimLevel int
+	// OutputTimeout bounds how long a frame may take to reach the output
+	// display when it is a sonos.ContextDisplay (default 5 seconds).
+	OutputTimeout time.Duration
+	// FPS is the frame rate of the render loop while something on screen
+	// moves (default 30). Scrolling and scenes move by elapsed time, so it
+	// only changes smoothness, not speed.
+	FPS int
+	// AdaptiveFPS drops the frame rate to StaticFPS while something on
+	// screen should move but the frames have not changed for staticAfter,
+	// such as a visualizer in a quiet room, and goes back to FPS with the
+	// next frame that differs.
+	AdaptiveFPS bool
+	// Layers are drawn over the artwork after the built-in decorations.
+	Layers []Layer
+	// Overlays are drawn over every frame, whatever it shows, while they
+	// are visible.
+	Overlays []Overlay
+	// BurnIn shifts, blanks, and dims frames over time so static screens
+	// wear the panel evenly.
+	BurnIn BurnInOptions
+	// Screens are extra full-frame screens, shown by name wherever an idle
+	// screen can be chosen.
+	Screens []Screen
+	// Rotation cycles through screens on a timer.
+	Rotation RotationOptions
+}
+
+var errClosed = errors.New("render: renderer closed")
+
+const (
+	defaultFrameSize = 64
+	defaultDimLevel  = 30
+	defaultFPS       = 30
+	// StaticFPS is the frame rate AdaptiveFPS drops to.
+	StaticFPS = 1
+	// staticAfter is how long frames must go unchanged before AdaptiveFPS
+	// drops the frame rate.
+	staticAfter = 2 * time.Second
+
+	// maxFrameStep caps how far animations move in one frame, so a stalled
+	// output does not make them jump.
+	maxFrameStep = 250 * time.Millisecond
+
+	defaultOutputTimeout = 5 * time.Second
+)
+
+// Renderer composes album art with playback decorations and forwards the
+// finished frame to an output display. It implements sonos.Display so the
+// listener can hand it artwork directly, and UpdateStatus can be used as
+// sonos.ListenerOptions.OnStatus. Animated decorations are advanced by Run.
+//
+// Frames are double-buffered: each one is composed in whichever of two
+// buffers is not on screen, and a frame identical to the one on screen is
+// not sent again. Outputs must therefore copy a frame they keep after Show
+// returns; every backend in this repository does.
+type Renderer struct {
+	out   sonos.Display
+	theme *theme.Current
+	opts  Options
+
+	mu      sync.Mutex
+	art     image.Image
+	status  sonos.PlaybackStatus
+	drawn   bool
+	lastBar barState
+	badge   string
+	mode    string
+	colors  []color.RGBA
+	ticker  tickerState
+	idle    bool
+	dimmed  bool
+	special *Special
+	// screen is the idle screen a scene rule shows in place of the art, or
+	// "" to follow playback. active is the screen last drawn in place of
+	// the art, so a change from rotation or focus can be noticed.
+	screen string
+	active string
+	// largeText replaces every screen with the track in large text.
+	largeText bool
+	large     largeTextState
+	scene     sceneState
+	banner    tickerState
+	frame     int
+	// shown is the last frame handed to the output, which a transition
+	// starts from. buffers are the two frames composition alternates
+	// between.
+	shown   *image.RGBA
+	buffers [2]*image.RGBA
+	// changed is when a frame that differed from the one on screen was
+	// last handed to the output.
+	changed    time.Time
+	transition transitionState
+	burnIn     burnInState
+	// overlaid is set while the frame on screen carries a moving overlay,
+	// so it is redrawn once the overlay stops.
+	overlaid bool
+	wake     chan struct{}
+	now      func() time.Time
+	closed   bool
+}
+
+type barState struct {
+	pixels int
+	fill   color.RGBA
+	track  color.RGBA
+}
+
+// New returns a renderer that draws onto out using colors from current.
+func New(out sonos.Display, current *theme.Current, opts Options) *Renderer {
+	opts.Ticker = opts.Ticker.withDefaults()
+	opts.SourceBadge = opts.SourceBadge.withDefaults()
+	opts.PlayMode = opts.PlayMode.withDefaults()
+	opts.Idle = opts.Idle.withDefaults()
+	opts.Transition = opts.Transition.withDefaults()
+	if opts.Size.X <= 0 || opts.Size.Y <= 0 {
+		opts.Size = image.Pt(defaultFrameSize, defaultFrameSize)
+	}
+	if opts.DimLevel <= 0 || opts.DimLevel > 100 {
+		opts.DimLevel = defaultDimLevel
+	}
+	if opts.OutputTimeout <= 0 {
+		opts.OutputTimeout = defaultOutputTimeout
+	}
+	if opts.FPS <= 0 {
+		opts.FPS = defaultFPS
+	}
+	opts.BurnIn = opts.BurnIn.withDefaults()
+	opts.Rotation = opts.Rotation.withDefaults()
+	opts.Overlays = sortOverlays(opts.Overlays)
+	return &Renderer{
+		out:    out,
+		theme:  current,
+		opts:   opts,
+		burnIn: burnInState{opts: opts.BurnIn},
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// Show replaces the album art and redraws the frame.
+func (r *Renderer) Show(img image.Image) error {
+	return r.ShowContext(context.Background(), img)
+}
+
+// ShowContext is Show with the frame handed to the output under ctx. It
+// implements sonos.ContextDisplay.
+func (r *Renderer) ShowContext(ctx context.Context, img image.Image) error {
+	if img == nil {
+		return fmt.Errorf("render: nil image")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := img != r.art
+	r.art = img
+	r.idle = false
+	r.ticker.offset = 0
+	r.syncScreen()
+	if changed && r.active == "" {
+		r.transition.begin(r.shown, img.Bounds().Size(), r.now(), r.opts.Transition)
+	}
+	if r.opts.ArtPalette {
+		r.colors = theme.ArtColors(img, 3)
+	}
+	var err error
+	if r.active != "" {
+		err = r.drawIdle(ctx)
+	} else {
+		err = r.redraw(ctx)
+	}
+	r.signal()
+	return err
+}
+
+// Clear removes the album art and switches to the idle screen, which either
+// blanks the output or shows the clock.
+func (r *Renderer) Clear() error {
+	return r.ClearContext(context.Background())
+}
+
+// ClearContext is Clear with the idle screen handed to the output under ctx.
+// It implements sonos.ContextDisplay.
+func (r *Renderer) ClearContext(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.art = nil
+	r.drawn = false
+	r.idle = true
+	r.dimmed = false
+	r.frame = 0
+	r.transition = transitionState{}
+	r.active = r.override()
+	err := r.drawIdle(ctx)
+	r.signal()
+	return err
+}
+
+// UpdateStatus records the latest playback status and redraws when the
+// visible decorations change.
+func (r *Renderer) UpdateStatus(status sonos.PlaybackStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+	if r.syncScreen() && !r.closed {
+		r.refresh(context.Background())
+		r.signal()
+		return
+	}
+	if (r.showingLargeText() || r.showingScript() || r.customScreen() != nil) && !r.closed {
+		if err := r.drawIdle(context.Background()); err != nil {
+			logger.Warn("render idle script", "err", err)
+		}
+		r.signal()
+		return
+	}
+	if !r.showingArt() || r.closed {
+		return
+	}
+
+	textChanged := r.opts.Ticker.Enabled && statusTickerText(status, r.opts.Ticker.UpNext) != r.ticker.text
+	barChanged := r.opts.ShowProgress && r.barState() != r.lastBar
+	badgeChanged := r.opts.SourceBadge.Enabled && sourceIcon(status.Track) != r.badge
+	modeChanged := r.opts.PlayMode.Enabled && playModeKey(status) != r.mode
+	if r.drawn && !textChanged && !barChanged && !badgeChanged && !modeChanged {
+		return
+	}
+	if textChanged {
+		r.ticker.offset = 0
+	}
+	if err := r.redraw(context.Background()); err != nil {
+		logger.Warn("render status", "err", err)
+	}
+	r.signal()
+}
+
+// SetDimmed dims or restores the now-playing frame. It implements
+// sonos.Dimmer; the idle screen has its own brightness and ignores it.
+func (r *Renderer) SetDimmed(dimmed bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dimmed == dimmed {
+		return nil
+	}
+	r.dimmed = dimmed
+	if !r.showingArt() {
+		return nil
+	}
+	return r.redraw(context.Background())
+}
+
+// SetSpecial activates a date-specific screen, or restores the normal screens
+// when special is nil.
+func (r *Renderer) SetSpecial(special *Special) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.special = special
+	r.banner.offset = 0
+	var err error
+	switch {
+	case r.closed:
+	case r.showingArt():
+		err = r.redraw(context.Background())
+	case r.showingIdle():
+		err = r.drawIdle(context.Background())
+	}
+	if err != nil {
+		logger.Warn("render special screen", "err", err)
+	}
+	r.signal()
+}
+
+// SetScreen shows the idle screen screen (IdleBlank, IdleClock,
+// IdleAnimation, IdleScript, or a registered Screen) whatever is playing, as
+// chosen by a scene rule. An empty screen returns to following playback. A
+// special day's screen still takes precedence.
+func (r *Renderer) SetScreen(screen string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if screen == r.screen {
+		return
+	}
+	r.screen = screen
+	r.frame = 0
+	r.transition = transitionState{}
+	r.active = r.override()
+	var err error
+	switch {
+	case r.closed:
+	case r.showingArt():
+		r.drawn = false
+		err = r.redraw(context.Background())
+	case r.showingIdle():
+		err = r.drawIdle(context.Background())
+	}
+	if err != nil {
+		logger.Warn("render scene screen", "err", err)
+	}
+	r.signal()
+}
+
+// SetLargeText turns the large-text mode on or off. While it is on, the
+// track title, artist, and playback state fill the panel in large scrolling
+// text in place of the art and every other screen but one asking for
+// focus, for viewers who cannot make out artwork from across the room.
+func (r *Renderer) SetLargeText(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if on == r.largeText {
+		return
+	}
+	r.largeText = on
+	r.large = largeTextState{}
+	r.active = r.override()
+	r.frame = 0
+	r.transition = transitionState{}
+	var err error
+	switch {
+	case r.closed:
+	case r.showingArt():
+		r.drawn = false
+		err = r.redraw(context.Background())
+	case r.showingIdle():
+		err = r.drawIdle(context.Background())
+	}
+	if err != nil {
+		logger.Warn("render large text", "err", err)
+	}
+	r.signal()
+}
+
+// Wake redraws the frame on screen and resumes the render loop, so a change
+// the renderer cannot see, such as a new notification in an overlay, shows
+// at once.
+func (r *Renderer) Wake() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.refresh(context.Background())
+	r.signal()
+}
+
+// LargeText reports whether the large-text mode is on.
+func (r *Renderer) LargeText() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.largeText
+}
+
+// Close stops drawing and closes the output display. Run returns once the
+// renderer is closed; later calls do nothing.
+func (r *Renderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.signal()
+	return r.out.Close()
+}
+
+// Run is the render loop. Until ctx is canceled it produces a frame every
+// 1/FPS while something on screen moves (the ticker, a banner, a special
+// scene, a transition, or an animated layer), keeps the idle clock current,
+// and plays the idle animation. When nothing moves it sleeps until the next
+// change, waking only to move burn-in protection on.
+func (r *Renderer) Run(ctx context.Context) {
+	interval := r.frameInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := time.Now()
+
+	for {
+		r.mu.Lock()
+		closed := r.closed
+		if r.overlaid && !r.overlaysAnimating() && !closed {
+			r.refresh(ctx)
+		}
+		animating := r.animating()
+		wait := r.idleWait()
+		refresh := r.refreshWait()
+		screen := r.screenWait()
+		next := r.frameInterval()
+		r.mu.Unlock()
+
+		if closed {
+			return
+		}
+		if next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+
+		if !animating {
+			if !r.waitIdle(ctx, wait, refresh, screen) {
+				return
+			}
+			last = time.Now()
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+			// Something changed; pick the frame rate again rather than
+			// wait out a slow tick.
+		case now := <-ticker.C:
+			step := min(now.Sub(last), maxFrameStep)
+			last = now
+			r.mu.Lock()
+			if r.animating() {
+				r.advance(ctx, step)
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// waitIdle blocks until Run has something to do. When wait is positive it
+// also redraws the idle screen after wait: the clock at each minute
+// boundary, or the animation's next frame. When refresh is positive it
+// redraws whatever is on screen after refresh so burn-in protection moves
+// on. When screen is positive it checks after screen whether the rotation
+// or a screen asking for focus changed what to show, redrawing only if so.
+// It returns false once ctx is done.
+func (r *Renderer) waitIdle(ctx context.Context, wait, refresh, screen time.Duration) bool {
+	var tick, refreshTick, screenTick <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		tick = timer.C
+	}
+	if refresh > 0 {
+		timer := time.NewTimer(refresh)
+		defer timer.Stop()
+		refreshTick = timer.C
+	}
+	if screen > 0 {
+		timer := time.NewTimer(screen)
+		defer timer.Stop()
+		screenTick = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-r.wake:
+	case <-refreshTick:
+		r.mu.Lock()
+		if !r.closed {
+			r.refresh(ctx)
+		}
+		r.mu.Unlock()
+	case <-screenTick:
+		r.mu.Lock()
+		if r.syncScreen() && !r.closed {
+			r.refresh(ctx)
+		}
+		r.mu.Unlock()
+	case <-tick:
+		r.mu.Lock()
+		if r.showingAnimation() && !r.closed {
+			r.frame = (r.frame + 1) % len(r.opts.Idle.Animation)
+			if err := r.drawIdle(ctx); err != nil {
+				logger.Warn("render idle animation", "err", err)
+			}
+		} else if (r.showingClock() || r.showingScript() || r.customScreen() != nil) && !r.closed {
+			if err := r.drawIdle(ctx); err != nil {
+				logger.Warn("render idle screen", "err", err)
+			}
+		}
+		r.mu.Unlock()
+	}
+	return true
+}
+
+// refreshWait returns how long until burn-in protection changes the frame
+// on screen, or 0 when it is off or the panel is blank. Callers must hold
+// r.mu.
+func (r *Renderer) refreshWait() time.Duration {
+	if r.shown == nil {
+		return 0
+	}
+	return r.burnIn.wait(r.now())
+}
+
+// refresh redraws the screen on display without moving anything on,
+// switching screens first if the rotation or focus moved on. Callers must
+// hold r.mu.
+func (r *Renderer) refresh(ctx context.Context) {
+	r.syncScreen()
+	var err error
+	switch {
+	case r.showingArt():
+		err = r.redraw(ctx)
+	case r.showingIdle():
+		err = r.drawIdle(ctx)
+	}
+	if err != nil {
+		logger.Warn("render burn-in refresh", "err", err)
+	}
+}
+
+// signal wakes Run so it can re-evaluate whether animation is needed.
+func (r *Renderer) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// frameInterval is the time between animation frames: the transition's
+// frame rate while one runs, StaticFPS while AdaptiveFPS finds the frames
+// unchanged, Options.FPS otherwise. Callers must hold r.mu.
+func (r *Renderer) frameInterval() time.Duration {
+	if r.transition.active() {
+		return time.Second / time.Duration(r.opts.Transition.FPS)
+	}
+	if r.opts.AdaptiveFPS && !r.changed.IsZero() && r.now().Sub(r.changed) >= staticAfter {
+		return time.Second / StaticFPS
+	}
+	return time.Second / time.Duration(r.opts.FPS)
+}
+
+// animating reports whether the current frame moves. Callers must hold r.mu.
+func (r *Renderer) animating() bool {
+	if r.overlaysAnimating() && (r.shown != nil || r.showingIdle()) {
+		return true
+	}
+	if r.showingArt() {
+		return r.transition.active() || (r.opts.Ticker.Enabled && r.ticker.scrolls()) || (r.overlayingBanner() && r.banner.scrolls()) || layersAnimating(r.opts.Layers)
+	}
+	if screen := r.customScreen(); screen != nil {
+		animated, ok := screen.(AnimatedLayer)
+		return ok && animated.Animating()
+	}
+	if r.showingLargeText() {
+		return r.large.scrolls()
+	}
+	if r.showingScript() {
+		return layersAnimating([]Layer{r.opts.Idle.Script})
+	}
+	return r.showingIdle() && r.special != nil && (r.scene.animated() || r.banner.scrolls())
+}
+
+// advance moves animated elements on by step and redraws. Text scrolls at
+// the ticker speed and scenes at sceneStepsPerSecond. Callers must hold
+// r.mu.
+func (r *Renderer) advance(ctx context.Context, step time.Duration) {
+	r.syncScreen()
+	pixels := step.Seconds() * float64(r.opts.Ticker.FPS)
+	if r.showingArt() {
+		// Scrolling text holds still until the new artwork is in place.
+		if !r.transition.active() {
+			r.ticker.scroll(pixels)
+			r.banner.scroll(pixels)
+		}
+		if err := r.redraw(ctx); err != nil {
+			logger.Warn("render ticker frame", "err", err)
+		}
+		return
+	}
+	if r.showingLargeText() {
+		r.large.scroll(pixels)
+	}
+	r.scene.run(step.Seconds() * sceneStepsPerSecond)
+	r.banner.scroll(pixels)
+	if err := r.drawIdle(ctx); err != nil {
+		logger.Warn("render idle frame", "err", err)
+	}
+}
+
+// overlayingBanner reports whether a special banner is drawn over the art.
+// Callers must hold r.mu.
+func (r *Renderer) overlayingBanner() bool {
+	return r.special != nil && r.special.Overlay && r.special.Banner != ""
+}
+
+// showingArt reports whether the now-playing frame is on screen. Callers
+// must hold r.mu.
+func (r *Renderer) showingArt() bool {
+	return r.art != nil && r.override() == ""
+}
+
+// showingLargeText reports whether the large-text screen is on screen.
+// Callers must hold r.mu.
+func (r *Renderer) showingLargeText() bool {
+	return r.largeText && r.override() == ScreenLargeText
+}
+
+// showingIdle reports whether an idle screen is on screen, either because
+// nothing is playing or because a scene rule, the rotation, or a screen
+// asking for focus chose one. Callers must hold r.mu.
+func (r *Renderer) showingIdle() bool {
+	return r.idle || r.override() != ""
+}
+
+// idleScreen returns the screen chosen in place of the art, else the idle
+// screen for the active theme, falling back to the configured one. Callers
+// must hold r.mu.
+func (r *Renderer) idleScreen() string {
+	if screen := r.override(); screen != "" {
+		return screen
+	}
+	if screen := r.theme.Load().IdleScreen; screen != "" {
+		return screen
+	}
+	return r.opts.Idle.Screen
+}
+
+// showingClock reports whether the idle clock is on screen. Callers must hold
+// r.mu.
+func (r *Renderer) showingClock() bool {
+	return r.showingIdle() && r.special == nil && r.idleScreen() == IdleClock
+}
+
+// showingAnimation reports whether the idle animation is on screen. Callers
+// must hold r.mu.
+func (r *Renderer) showingAnimation() bool {
+	return r.showingIdle() && r.special == nil && r.idleScreen() == IdleAnimation && len(r.opts.Idle.Animation) > 0
+}
+
+// showingScript reports whether the idle script is on screen. Callers must
+// hold r.mu.
+func (r *Renderer) showingScript() bool {
+	return r.showingIdle() && r.special == nil && r.idleScreen() == IdleScript && r.opts.Idle.Script != nil
+}
+
+// idleWait returns how long until the idle screen next changes on its own,
+// or 0 when it is static. Callers must hold r.mu.
+func (r *Renderer) idleWait() time.Duration {
+	switch {
+	case r.showingAnimation():
+		return r.opts.Idle.Animation[r.frame%len(r.opts.Idle.Animation)].Delay
+	case r.showingClock(), r.showingScript(), r.customScreen() != nil:
+		return untilNextMinute(r.now())
+	}
+	return 0
+}
+
+// drawIdle shows the idle screen, which is the special screen while one is
+// active. Callers must hold r.mu.
+func (r *Renderer) drawIdle(ctx context.Context) error {
+	if screen := r.customScreen(); screen != nil {
+		frame := r.canvas(r.opts.Size)
+		state := r.frameState()
+		draw.Draw(frame, frame.Bounds(), image.NewUniform(state.Palette.Background), image.Point{}, draw.Src)
+		if err := screen.Draw(frame, state); err != nil {
+			return fmt.Errorf("render: screen %s: %w", screen.Name(), err)
+		}
+		return r.show(ctx, frame)
+	}
+	if r.showingLargeText() {
+		frame := r.canvas(r.opts.Size)
+		if err := r.large.draw(frame, r.status, r.palette()); err != nil {
+			return err
+		}
+		return r.show(ctx, frame)
+	}
+	if r.special != nil {
+		frame := r.canvas(r.opts.Size)
+		r.scene.reset(r.special.Scene, r.opts.Size)
+		if err := drawSpecial(frame, &r.scene, &r.banner, r.special, r.palette()); err != nil {
+			return err
+		}
+		return r.show(ctx, frame)
+	}
+	if r.showingAnimation() {
+		frame := r.canvas(r.opts.Size)
+		current := r.opts.Idle.Animation[r.frame%len(r.opts.Idle.Animation)].Image
+		draw.Draw(frame, frame.Bounds(), current, current.Bounds().Min, draw.Src)
+		return r.show(ctx, frame)
+	}
+	if r.showingScript() {
+		frame := r.canvas(r.opts.Size)
+		palette := r.palette()
+		draw.Draw(frame, frame.Bounds(), image.NewUniform(palette.Background), image.Point{}, draw.Src)
+		if err := r.opts.Idle.Script.Draw(frame, FrameState{Status: r.status, Palette: palette, Now: r.now()}); err != nil {
+			return err
+		}
+		return r.show(ctx, frame)
+	}
+	if r.idleScreen() != IdleClock {
+		if r.overlaysAnimating() {
+			// A moving overlay, such as a notification, still shows over
+			// the blank screen; static ones wait for something to show.
+			frame := r.canvas(r.opts.Size)
+			draw.Draw(frame, frame.Bounds(), image.Black, image.Point{}, draw.Src)
+			return r.show(ctx, frame)
+		}
+		if r.closed {
+			return errClosed
+		}
+		r.overlaid = false
+		ctx, cancel := context.WithTimeout(ctx, r.opts.OutputTimeout)
+		defer cancel()
+		r.shown = nil
+		return sonos.ClearContext(ctx, r.out)
+	}
+	frame := r.canvas(r.opts.Size)
+	if err := drawClock(frame, r.now(), r.opts.Idle.Clock, r.palette()); err != nil {
+		return err
+	}
+	return r.show(ctx, frame)
+}
+
+// palette is the theme's palette, recolored from the artwork when
+// ArtPalette is set. Callers must hold r.mu.
+func (r *Renderer) palette() theme.Palette {
+	return r.theme.Palette().WithArtColors(r.colors)
+}
+
+// canvas returns the buffer to compose the next frame in: whichever of the
+// two buffers is not on screen, reallocated when size changed. Callers must
+// hold r.mu.
+func (r *Renderer) canvas(size image.Point) *image.RGBA {
+	i := 0
+	if r.buffers[0] != nil && r.buffers[0] == r.shown {
+		i = 1
+	}
+	if r.buffers[i] == nil || r.buffers[i].Rect.Size() != size {
+		r.buffers[i] = image.NewRGBA(image.Rectangle{Max: size})
+	}
+	return r.buffers[i]
+}
+
+// show applies burn-in protection to frame and hands it to the output,
+// giving up after OutputTimeout, unless it matches the frame already on
+// screen. Callers must hold r.mu.
+func (r *Renderer) show(ctx context.Context, frame *image.RGBA) error {
+	if r.closed {
+		return errClosed
+	}
+	overlaid, err := r.drawOverlays(frame)
+	if err != nil {
+		return err
+	}
+	r.overlaid = overlaid
+	r.burnIn.apply(frame, r.now())
+	if r.shown != nil && r.shown.Rect == frame.Rect && bytes.Equal(r.shown.Pix, frame.Pix) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.OutputTimeout)
+	defer cancel()
+	if err := sonos.ShowContext(ctx, r.out, frame); err != nil {
+		return err
+	}
+	r.shown = frame
+	r.changed = r.now()
+	return nil
+}
+
+// redraw composes and shows the current frame. Callers must hold r.mu.
+func (r *Renderer) redraw(ctx context.Context) error {
+	bounds := r.art.Bounds()
+	frame := r.canvas(bounds.Size())
+	palette := r.palette()
+
+	if r.opts.Ticker.Enabled {
+		if err := r.ticker.prepare(statusTickerText(r.status, r.opts.Ticker.UpNext), r.opts.Ticker.Rows, palette.Text, frame.Bounds()); err != nil {
+			return err
+		}
+		r.ticker.compose(frame, r.art, r.opts.Ticker, palette)
+	} else {
+		draw.Draw(frame, frame.Bounds(), r.art, bounds.Min, draw.Src)
+	}
+
+	if r.overlayingBanner() {
+		if err := overlayBanner(frame, &r.banner, r.special, r.opts.Ticker.Rows, palette); err != nil {
+			return err
+		}
+	}
+
+	bar := r.barState()
+	if r.opts.ShowProgress && r.status.Progress() >= 0 {
+		overlay.ProgressBar(frame, r.status.Progress(), bar.fill, bar.track)
+	}
+
+	badge := ""
+	if r.opts.SourceBadge.Enabled {
+		if err := stampSourceBadge(frame, r.status.Track, r.opts.SourceBadge); err != nil {
+			return err
+		}
+		badge = sourceIcon(r.status.Track)
+	}
+
+	mode := ""
+	if r.opts.PlayMode.Enabled {
+		if err := stampPlayMode(frame, r.status, r.opts.PlayMode); err != nil {
+			return err
+		}
+		mode = playModeKey(r.status)
+	}
+
+	state := FrameState{Status: r.status, Palette: palette, Now: r.now()}
+	for _, layer := range r.opts.Layers {
+		if err := layer.Draw(frame, state); err != nil {
+			return err
+		}
+	}
+
+	if r.dimmed {
+		dimFrame(frame, r.opts.DimLevel)
+	}
+
+	if err := r.show(ctx, r.transition.apply(frame, r.now(), r.opts.Transition)); err != nil {
+		return err
+	}
+	r.drawn = true
+	r.lastBar = bar
+	r.badge = badge
+	r.mode = mode
+	return nil
+}
+
+func (r *Renderer) barState() barState {
+	palette := r.palette()
+	state := barState{
+		fill:  palette.Accent,
+		track: dim(palette.Accent),
+	}
+	if progress := r.status.Progress(); progress >= 0 && r.art != nil {
+		state.pixels = overlay.ProgressPixels(r.art.Bounds().Dx(), progress)
+	} else {
+		state.pixels = -1
+	}
+	return state
+}
+
+// dimFrame scales every pixel of frame to percent of its intensity.
+func dimFrame(frame *image.RGBA, percent int) {
+	for i := 0; i < len(frame.Pix); i += 4 {
+		frame.Pix[i] = uint8(int(frame.Pix[i]) * percent / 100)
+		frame.Pix[i+1] = uint8(int(frame.Pix[i+1]) * percent / 100)
+		frame.Pix[i+2] = uint8(int(frame.Pix[i+2]) * percent / 100)
+	}
+}
+
+// dim returns c at a quarter of its intensity, used for the unplayed part of
+// the progress bar.
+func dim(c color.RGBA) color.RGBA {
+	return color.RGBA{R: c.R / 4, G: c.G / 4, B: c.B / 4, A: 0xff}
+}
@@ -0,0 +1,1067 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"musicDisplay/matrixdisplay"
+	"musicDisplay/overlay"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+type recordingDisplay struct {
+	frames  []image.Image
+	cleared int
+	closed  int
+}
+
+func (d *recordingDisplay) Show(img image.Image) error {
+	d.frames = append(d.frames, img)
+	return nil
+}
+
+func (d *recordingDisplay) Clear() error {
+	d.cleared++
+	return nil
+}
+
+func (d *recordingDisplay) Close() error {
+	d.closed++
+	return nil
+}
+
+func (d *recordingDisplay) last() *image.RGBA {
+	return d.frames[len(d.frames)-1].(*image.RGBA)
+}
+
+func solidArt(c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestRendererDrawsProgressBar(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{ShowProgress: true})
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{
+		Playing: true,
+		Track:   sonos.TrackInfo{Position: time.Minute, Duration: 2 * time.Minute},
+	})
+
+	frame := out.last()
+	accent := theme.DefaultPalette.Accent
+	if got := frame.RGBAAt(10, 63); got != accent {
+		t.Fatalf("played pixel = %+v, want accent %+v", got, accent)
+	}
+	if got := frame.RGBAAt(50, 63); got == accent || got == (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("unplayed pixel = %+v, want dimmed track color", got)
+	}
+	if got := frame.RGBAAt(10, 62); got != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("art pixel above bar = %+v, want white", got)
+	}
+
+	frames := len(out.frames)
+	r.UpdateStatus(sonos.PlaybackStatus{
+		Playing: true,
+		Track:   sonos.TrackInfo{Position: time.Minute + 100*time.Millisecond, Duration: 2 * time.Minute},
+	})
+	if len(out.frames) != frames {
+		t.Fatal("expected no redraw when the bar did not move")
+	}
+}
+
+func TestTickerScrollsLongText(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{Ticker: TickerOptions{Enabled: true}})
+
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Artist: "A Very Long Artist Name", Title: "An Even Longer Song Title"}})
+	if !r.animating() {
+		t.Fatal("expected long ticker text to scroll")
+	}
+
+	before := out.last()
+	r.mu.Lock()
+	r.ticker.advance()
+	if err := r.redraw(context.Background()); err != nil {
+		t.Fatalf("redraw error: %v", err)
+	}
+	r.mu.Unlock()
+	after := out.last()
+
+	if bandEqual(before, after, 64-defaultTickerRows) {
+		t.Fatal("expected ticker band to change after advancing")
+	}
+	if !rowsEqual(before, after, 0, 64-defaultTickerRows) {
+		t.Fatal("expected art above the ticker band to stay unchanged")
+	}
+}
+
+func TestTickerShortTextIsStatic(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{Ticker: TickerOptions{Enabled: true}})
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Title: "Hi"}})
+	if r.animating() {
+		t.Fatal("expected short text to stay static")
+	}
+}
+
+func TestTickerShowsUpNext(t *testing.T) {
+	status := sonos.PlaybackStatus{
+		Track:     sonos.TrackInfo{Artist: "Artist", Title: "Now"},
+		NextTrack: sonos.TrackInfo{Artist: "Other", Title: "Later"},
+	}
+	if got := statusTickerText(status, false); got != "Artist – Now" {
+		t.Fatalf("without up next = %q", got)
+	}
+	if got := statusTickerText(status, true); got != "Artist – Now   Up Next: Other – Later" {
+		t.Fatalf("with up next = %q", got)
+	}
+	status.NextTrack = sonos.TrackInfo{}
+	if got := statusTickerText(status, true); got != "Artist – Now" {
+		t.Fatalf("empty queue = %q", got)
+	}
+
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{Ticker: TickerOptions{Enabled: true, UpNext: true}})
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Title: "Hi"}})
+	frames := len(out.frames)
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Title: "Hi"}, NextTrack: sonos.TrackInfo{Title: "Next"}})
+	if len(out.frames) == frames {
+		t.Fatal("expected a redraw when the next track changed")
+	}
+}
+
+func TestSourceBadge(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{SourceBadge: SourceBadgeOptions{Enabled: true, Corner: "bottom-right"}})
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if err := r.Show(solidArt(white)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Title: "Song", Source: sonos.SourceMusic}})
+	if got := out.last().RGBAAt(58, 58); got != white {
+		t.Fatalf("library track has a badge: pixel %v", got)
+	}
+
+	frames := len(out.frames)
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Title: "Station", Source: sonos.SourceRadio}})
+	if len(out.frames) == frames {
+		t.Fatal("expected a redraw when the source changed")
+	}
+	frame := out.last()
+	if frame.RGBAAt(58, 58) == white || frame.RGBAAt(1, 1) != white {
+		t.Fatal("expected the radio badge in the bottom-right corner only")
+	}
+	if !rowsEqual(frame, solidArt(white), 0, 50) {
+		t.Fatal("expected the art above the badge to stay unchanged")
+	}
+}
+
+func TestPlayModeIcons(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{PlayMode: PlayModeOptions{Enabled: true}})
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if err := r.Show(solidArt(white)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	track := sonos.TrackInfo{Title: "Song"}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: track, PlayMode: sonos.PlayModeNormal})
+	if !rowsEqual(out.last(), solidArt(white), 0, 64) {
+		t.Fatal("normal play has indicators")
+	}
+
+	// Shuffle with repeat: two icons on one backing, 19 pixels wide, one
+	// pixel in from the top-right corner.
+	r.UpdateStatus(sonos.PlaybackStatus{Track: track, PlayMode: sonos.PlayModeShuffle})
+	frame := out.last()
+	if frame.RGBAAt(45, 1) == white || frame.RGBAAt(62, 1) == white || frame.RGBAAt(43, 1) != white || frame.RGBAAt(63, 1) != white {
+		t.Fatal("expected two indicators in the top-right corner")
+	}
+	if !rowsEqual(frame, solidArt(white), 11, 64) {
+		t.Fatal("expected the art below the indicators to stay unchanged")
+	}
+
+	r.UpdateStatus(sonos.PlaybackStatus{Track: track, PlayMode: sonos.PlayModeRepeatOne, Crossfade: true})
+	if got := playModeIcons(r.status); len(got) != 2 || got[0] != overlay.IconRepeatOne || got[1] != overlay.IconCrossfade {
+		t.Fatalf("icons = %v, want repeat-one and crossfade", got)
+	}
+
+	r.UpdateStatus(sonos.PlaybackStatus{Track: track, PlayMode: sonos.PlayModeRepeatAll})
+	frame = out.last()
+	if frame.RGBAAt(45, 1) != white || frame.RGBAAt(55, 1) == white {
+		t.Fatal("expected a single indicator after shuffle was turned off")
+	}
+}
+
+func TestNowPlayingFrameMatchesRenderer(t *testing.T) {
+	opts := Options{
+		ShowProgress: true,
+		Ticker:       TickerOptions{Enabled: true},
+		SourceBadge:  SourceBadgeOptions{Enabled: true},
+	}
+	track := sonos.TrackInfo{Title: "Song", Artist: "Band", Source: sonos.SourceRadio, Position: time.Minute, Duration: 2 * time.Minute}
+	art := solidArt(color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff})
+
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), opts)
+	r.UpdateStatus(sonos.PlaybackStatus{State: "Playing", Track: track, Playing: true})
+	if err := r.Show(art); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+
+	frame, ok := NowPlayingFrame(track, art, opts).(*image.RGBA)
+	if !ok || !rowsEqual(frame, out.last(), 0, 64) {
+		t.Fatal("NowPlayingFrame differs from the renderer's frame")
+	}
+	if art.RGBAAt(0, 63) != (color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}) {
+		t.Fatal("NowPlayingFrame drew onto the artwork")
+	}
+
+	blank := NowPlayingFrame(track, nil, Options{ShowProgress: true})
+	if blank.Bounds().Size() != image.Pt(64, 64) {
+		t.Fatalf("frame without art is %v, want 64x64", blank.Bounds())
+	}
+	if got := blank.(*image.RGBA).RGBAAt(0, 63); got != theme.DefaultPalette.Accent {
+		t.Fatalf("progress pixel without art = %v, want the accent color", got)
+	}
+}
+
+func TestArtPaletteColorsProgressBar(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{ShowProgress: true, ArtPalette: true})
+	red := color.RGBA{R: 0xe0, G: 0x10, B: 0x10, A: 0xff}
+	if err := r.Show(solidArt(red)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Playing: true, Track: sonos.TrackInfo{Position: time.Minute, Duration: 2 * time.Minute}})
+	got := out.last().RGBAAt(0, 63)
+	if got == theme.DefaultPalette.Accent || got.R <= got.G {
+		t.Fatalf("progress bar pixel = %v, want the red of the artwork", got)
+	}
+}
+
+func bandEqual(a, b *image.RGBA, fromRow int) bool {
+	return rowsEqual(a, b, fromRow, a.Bounds().Dy())
+}
+
+func rowsEqual(a, b *image.RGBA, from, to int) bool {
+	for y := from; y < to; y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestClearShowsIdleClock(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleClock}})
+	r.now = func() time.Time { return time.Date(2024, 5, 1, 21, 7, 30, 0, time.UTC) }
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 0 {
+		t.Fatalf("output cleared %d times, want clock frame instead", out.cleared)
+	}
+	if len(out.frames) != 1 {
+		t.Fatalf("frames = %d, want 1", len(out.frames))
+	}
+
+	frame := out.last()
+	if frame.Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Fatalf("clock frame bounds = %v, want 64x64", frame.Bounds())
+	}
+	var lit int
+	var brightest uint8
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			p := frame.RGBAAt(x, y)
+			if p.R > 0 {
+				lit++
+			}
+			if p.R > brightest {
+				brightest = p.R
+			}
+		}
+	}
+	if lit < 40 {
+		t.Fatalf("clock lit %d pixels, want visible digits", lit)
+	}
+	if want := uint8(0xff * defaultClockBrightness / 100); brightest > want {
+		t.Fatalf("brightest clock pixel = %d, want dimmed to at most %d", brightest, want)
+	}
+	if !r.showingClock() {
+		t.Fatalf("showingClock = false after Clear")
+	}
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if r.showingClock() {
+		t.Fatalf("showingClock = true after new art")
+	}
+}
+
+func TestRendererPlaysIdleAnimation(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	frames := []matrixdisplay.Frame{
+		{Image: solidArt(color.RGBA{R: 0xff, A: 0xff}), Delay: 50 * time.Millisecond},
+		{Image: solidArt(color.RGBA{B: 0xff, A: 0xff}), Delay: time.Second},
+	}
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleAnimation, Animation: frames}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 0 || out.last().RGBAAt(10, 10).R != 0xff {
+		t.Fatalf("idle screen did not show the first animation frame")
+	}
+	if wait := r.idleWait(); wait != 50*time.Millisecond {
+		t.Fatalf("idleWait = %v, want the first frame's delay", wait)
+	}
+	<-r.wake // drain Clear's signal so waitIdle waits for the frame delay
+	if !r.waitIdle(context.Background(), r.idleWait(), 0, 0) {
+		t.Fatal("waitIdle returned false")
+	}
+	if out.last().RGBAAt(10, 10).B != 0xff {
+		t.Fatalf("idle screen did not advance to the second frame")
+	}
+	if wait := r.idleWait(); wait != time.Second {
+		t.Fatalf("idleWait = %v, want the second frame's delay", wait)
+	}
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if r.showingAnimation() || r.idleWait() != 0 {
+		t.Fatalf("animation still playing after new art")
+	}
+}
+
+func TestRendererTransitionsBetweenArtwork(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{Transition: TransitionOptions{Style: TransitionCrossfade, Duration: time.Second}})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if r.transition.active() {
+		t.Fatal("first artwork started a transition, want it shown at once")
+	}
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if !r.animating() || r.frameInterval() != time.Second/defaultTransitionFPS {
+		t.Fatal("new artwork did not start a transition at its frame rate")
+	}
+	if got := out.last().RGBAAt(5, 5).R; got != 0 {
+		t.Fatalf("transition start = %d, want the old artwork", got)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	r.advance(context.Background(), time.Second/sceneStepsPerSecond)
+	if got := out.last().RGBAAt(5, 5).R; got < 120 || got > 135 {
+		t.Fatalf("halfway through crossfade = %d, want about 128", got)
+	}
+
+	now = now.Add(time.Second)
+	r.advance(context.Background(), time.Second/sceneStepsPerSecond)
+	if got := out.last().RGBAAt(5, 5).R; got != 0xff || r.animating() {
+		t.Fatalf("after transition = %d, animating %v; want the new artwork and no animation", got, r.animating())
+	}
+}
+
+func TestBlendTransitionStyles(t *testing.T) {
+	from, to := solidArt(color.Black), solidArt(color.White)
+	wipe := blendTransition(from, to, 0.25, TransitionWipe)
+	if wipe.RGBAAt(10, 0).R != 0xff || wipe.RGBAAt(20, 0).R != 0 {
+		t.Fatal("wipe should show the new artwork left of the edge and the old to the right")
+	}
+	slide := blendTransition(from, to, 0.5, TransitionSlide)
+	if slide.RGBAAt(10, 0).R != 0 || slide.RGBAAt(40, 0).R != 0xff {
+		t.Fatal("slide should push the old artwork left with the new one following")
+	}
+}
+
+func TestIdleScriptRedrawsOnStatus(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	script := LayerFunc(func(frame *image.RGBA, state FrameState) error {
+		if state.Status.Playing {
+			frame.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+		}
+		return nil
+	})
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleScript, Script: script}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 0 || len(out.frames) != 1 {
+		t.Fatalf("cleared=%d frames=%d, want one script frame", out.cleared, len(out.frames))
+	}
+	if r.idleWait() <= 0 {
+		t.Fatalf("idleWait = %v, want a minute refresh for the script", r.idleWait())
+	}
+
+	r.UpdateStatus(sonos.PlaybackStatus{Playing: true})
+	if len(out.frames) != 2 {
+		t.Fatalf("frames = %d after a status update, want the script redrawn", len(out.frames))
+	}
+	if got := out.last().RGBAAt(0, 0); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("script pixel = %v, want red", got)
+	}
+}
+
+func TestThemeIdleScreenOverridesOptions(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette, IdleScreen: IdleBlank})
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleClock}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 1 || len(out.frames) != 0 {
+		t.Fatalf("cleared=%d frames=%d, want blank idle screen", out.cleared, len(out.frames))
+	}
+}
+
+func TestSetScreenReplacesArtUntilReset(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{ShowProgress: true})
+	r.now = func() time.Time { return time.Date(2024, 5, 1, 21, 7, 30, 0, time.UTC) }
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.SetScreen(IdleClock)
+	if !r.showingClock() || r.showingArt() {
+		t.Fatalf("showingClock=%v showingArt=%v, want the clock over the art", r.showingClock(), r.showingArt())
+	}
+	if got := out.last().RGBAAt(32, 2); got == (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("clock frame still shows the art")
+	}
+
+	frames := len(out.frames)
+	r.UpdateStatus(sonos.PlaybackStatus{Playing: true, Track: sonos.TrackInfo{Position: time.Minute, Duration: 2 * time.Minute}})
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if len(out.frames) != frames {
+		t.Fatalf("frames = %d after status and art updates, want the clock left alone at %d", len(out.frames), frames)
+	}
+
+	r.SetScreen(IdleBlank)
+	if out.cleared != 1 {
+		t.Fatalf("cleared = %d, want the blank screen", out.cleared)
+	}
+
+	r.SetScreen("")
+	if !r.showingArt() {
+		t.Fatalf("showingArt = false after resetting the screen")
+	}
+	if got := out.last().RGBAAt(32, 2); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("art pixel = %v after resetting the screen, want white", got)
+	}
+}
+
+func TestClockLabel(t *testing.T) {
+	now := time.Date(2024, 5, 1, 21, 7, 0, 0, time.UTC)
+	if label, marker := clockLabel(now, ClockOptions{}); label != "21:07" || marker != "" {
+		t.Fatalf("24h label = %q %q, want \"21:07\"", label, marker)
+	}
+	if label, marker := clockLabel(now, ClockOptions{TwelveHour: true}); label != "9:07" || marker != "PM" {
+		t.Fatalf("12h label = %q %q, want \"9:07\" \"PM\"", label, marker)
+	}
+	if got := untilNextMinute(now.Add(45 * time.Second)); got != 15*time.Second {
+		t.Fatalf("untilNextMinute = %s, want 15s", got)
+	}
+}
+
+func TestSpecialReplacesIdleScreenAndAnimates(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleClock}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	banner := color.RGBA{R: 0xff, A: 0xff}
+	r.SetSpecial(&Special{Name: "birthday", Banner: "Hi", Color: banner, Scene: SceneSnow})
+	if r.showingClock() {
+		t.Fatalf("showingClock = true while special is active")
+	}
+	if !r.animating() {
+		t.Fatalf("animating = false for snow scene")
+	}
+
+	first := out.last()
+	var bannerPixels int
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if p := first.RGBAAt(x, y); p.R > 0x80 && p.G == 0 && p.B == 0 {
+				bannerPixels++
+			}
+		}
+	}
+	if bannerPixels == 0 {
+		t.Fatalf("special frame has no banner pixels")
+	}
+
+	r.mu.Lock()
+	r.advance(context.Background(), time.Second/sceneStepsPerSecond)
+	r.mu.Unlock()
+	if rowsEqual(first, out.last(), 0, 64) {
+		t.Fatalf("snow scene did not move between frames")
+	}
+
+	r.SetSpecial(nil)
+	if !r.showingClock() {
+		t.Fatalf("showingClock = false after special ended")
+	}
+}
+
+func TestSpecialBannerOverlaysArt(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{})
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.SetSpecial(&Special{Banner: "Party", Overlay: true})
+
+	frame := out.last()
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if got := frame.RGBAAt(0, 0); got == white {
+		t.Fatalf("top band pixel = %+v, want shaded banner band", got)
+	}
+	if got := frame.RGBAAt(0, 32); got != white {
+		t.Fatalf("art pixel = %+v, want untouched art", got)
+	}
+}
+
+func TestSetDimmedScalesFrameUntilCleared(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{DimLevel: 50})
+
+	if err := r.Show(solidArt(color.RGBA{R: 200, G: 100, B: 50, A: 0xff})); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if err := r.SetDimmed(true); err != nil {
+		t.Fatalf("SetDimmed error: %v", err)
+	}
+	if got := out.last().RGBAAt(5, 5); got != (color.RGBA{R: 100, G: 50, B: 25, A: 0xff}) {
+		t.Fatalf("dimmed pixel = %+v, want half intensity", got)
+	}
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if err := r.Show(solidArt(color.RGBA{R: 200, A: 0xff})); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if got := out.last().RGBAAt(5, 5); got.R != 200 {
+		t.Fatalf("pixel after idle = %+v, want full brightness", got)
+	}
+}
+
+// stalledDisplay is a network-style display that never finishes a frame.
+type stalledDisplay struct{ recordingDisplay }
+
+func (d *stalledDisplay) ShowContext(ctx context.Context, img image.Image) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (d *stalledDisplay) ClearContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestShowGivesUpOnStalledOutput(t *testing.T) {
+	out := &stalledDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{OutputTimeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	err := r.Show(solidArt(color.White))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Show error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Show blocked for %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.ClearContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ClearContext error = %v, want canceled", err)
+	}
+}
+
+func TestCloseStopsRunAndClosesOutput(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{})
+	done := make(chan struct{})
+	go func() {
+		r.Run(context.Background())
+		close(done)
+	}()
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("second Close error: %v", err)
+	}
+	if out.closed != 1 {
+		t.Fatalf("output closed %d times, want 1", out.closed)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after Close")
+	}
+	if err := r.Show(solidArt(color.White)); !errors.Is(err, errClosed) {
+		t.Fatalf("Show after Close error = %v, want errClosed", err)
+	}
+	if len(out.frames) != 0 {
+		t.Fatalf("output received %d frames after Close", len(out.frames))
+	}
+}
+
+type blinkLayer struct{ on bool }
+
+func (l *blinkLayer) Draw(frame *image.RGBA, state FrameState) error {
+	if l.on {
+		frame.SetRGBA(0, 0, state.Palette.Accent)
+	}
+	return nil
+}
+
+func (l *blinkLayer) Animating() bool { return l.on }
+
+func TestRendererCompositesLayersAndSkipsUnchangedFrames(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	blink := &blinkLayer{}
+	r := New(out, current, Options{Layers: []Layer{blink}})
+
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if r.animating() {
+		t.Fatal("idle layer made the frame animate")
+	}
+	first := out.last()
+
+	r.mu.Lock()
+	if err := r.redraw(context.Background()); err != nil {
+		t.Fatalf("redraw error: %v", err)
+	}
+	r.mu.Unlock()
+	if len(out.frames) != 1 {
+		t.Fatalf("output got %d frames, want an unchanged redraw skipped", len(out.frames))
+	}
+
+	blink.on = true
+	if !r.animating() {
+		t.Fatal("animated layer did not start the render loop")
+	}
+	r.mu.Lock()
+	r.advance(context.Background(), time.Second/defaultFPS)
+	r.mu.Unlock()
+	second := out.last()
+	if len(out.frames) != 2 || second == first {
+		t.Fatalf("output got %d frames, want the layer's frame in the other buffer", len(out.frames))
+	}
+	if second.RGBAAt(0, 0) != theme.DefaultPalette.Accent {
+		t.Fatal("layer was not drawn over the artwork")
+	}
+	if first.RGBAAt(0, 0) != (color.RGBA{A: 0xff}) {
+		t.Fatal("composing the second frame overwrote the one on screen")
+	}
+}
+
+func TestAdaptiveFPSSlowsUnchangedAnimation(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	blink := &blinkLayer{on: true}
+	r := New(out, current, Options{Layers: []Layer{blink}, AdaptiveFPS: true})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	full := time.Second / defaultFPS
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if got := r.frameInterval(); got != full {
+		t.Fatalf("interval after a new frame = %v, want %v", got, full)
+	}
+	// The layer asks for animation but draws the same frame.
+	now = now.Add(staticAfter)
+	r.advance(context.Background(), full)
+	if got := r.frameInterval(); got != time.Second/StaticFPS {
+		t.Fatalf("interval with unchanged frames = %v, want %v", got, time.Second/StaticFPS)
+	}
+	palette := theme.DefaultPalette
+	palette.Accent = color.RGBA{R: 0x12, A: 0xff}
+	current.Store(theme.Theme{Palette: palette})
+	r.advance(context.Background(), time.Second)
+	if got := r.frameInterval(); got != full {
+		t.Fatalf("interval after the frame changed = %v, want %v", got, full)
+	}
+}
+
+type markOverlay struct {
+	blinkLayer
+	priority int
+	col      color.RGBA
+}
+
+func (o *markOverlay) Draw(frame *image.RGBA, state FrameState) error {
+	frame.SetRGBA(0, 0, o.col)
+	return nil
+}
+
+func (o *markOverlay) Priority() int { return o.priority }
+func (o *markOverlay) Visible() bool { return o.on }
+
+func TestOverlaysDrawOverBlankIdleInPriorityOrder(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	top := &markOverlay{priority: 10, col: color.RGBA{R: 0xff, A: 0xff}}
+	bottom := &markOverlay{priority: 1, col: color.RGBA{G: 0xff, A: 0xff}}
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleBlank}, Overlays: []Overlay{top, bottom}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 1 || len(out.frames) != 0 {
+		t.Fatalf("cleared %d, frames %d; want the blank screen", out.cleared, len(out.frames))
+	}
+
+	top.on, bottom.on = true, true
+	r.Wake()
+	if len(out.frames) != 1 || out.last().RGBAAt(0, 0) != top.col {
+		t.Fatalf("frames %d; want the higher-priority overlay drawn on top of the blank screen", len(out.frames))
+	}
+	r.mu.Lock()
+	animating := r.animating()
+	r.mu.Unlock()
+	if !animating {
+		t.Fatal("an active overlay did not start the render loop")
+	}
+
+	// Run takes the overlay off the screen once it ends.
+	top.on, bottom.on = false, false
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mu.Lock()
+		cleared := out.cleared
+		r.mu.Unlock()
+		if cleared == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("blank screen not restored after the overlay ended")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+type staticOverlay struct{ visible bool }
+
+func (o *staticOverlay) Draw(frame *image.RGBA, state FrameState) error {
+	frame.SetRGBA(63, 0, state.Palette.Accent)
+	return nil
+}
+func (o *staticOverlay) Priority() int { return 0 }
+func (o *staticOverlay) Visible() bool { return o.visible }
+
+func TestStaticOverlayWaitsForAFrameAndDoesNotAnimate(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	badge := &staticOverlay{visible: true}
+	r := New(out, current, Options{Idle: IdleOptions{Screen: IdleBlank}, Overlays: []Overlay{badge}})
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	if out.cleared != 1 || len(out.frames) != 0 {
+		t.Fatalf("cleared %d, frames %d; want a static overlay to leave the blank screen dark", out.cleared, len(out.frames))
+	}
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if out.last().RGBAAt(63, 0) != theme.DefaultPalette.Accent {
+		t.Fatal("static overlay not drawn over the artwork")
+	}
+	r.mu.Lock()
+	animating := r.animating()
+	r.mu.Unlock()
+	if animating {
+		t.Fatal("a static overlay started the render loop")
+	}
+
+	badge.visible = false
+	r.Wake()
+	if out.last().RGBAAt(63, 0) == theme.DefaultPalette.Accent {
+		t.Fatal("Wake did not take the overlay off the frame")
+	}
+}
+
+func TestTickerScrollsByElapsedTime(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{Ticker: TickerOptions{Enabled: true, FPS: 20}})
+	if err := r.Show(solidArt(color.Black)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Track: sonos.TrackInfo{Artist: "A Very Long Artist Name", Title: "An Even Longer Song Title"}})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i < 40; i++ {
+		r.advance(context.Background(), 25*time.Millisecond)
+	}
+	if r.ticker.offset != 20 {
+		t.Fatalf("ticker offset after one second = %d, want the 20 pixel/s speed whatever the frame rate", r.ticker.offset)
+	}
+}
+
+func TestBurnInDriftsBlanksAndDims(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := burnInState{opts: BurnInOptions{
+		DriftInterval:    time.Minute,
+		BlackInterval:    time.Hour,
+		BrightnessPeriod: 10 * time.Minute,
+		BrightnessMin:    50,
+	}.withDefaults(), start: start}
+
+	if got := b.offset(start.Add(90 * time.Second)); got != image.Pt(1, 0) {
+		t.Fatalf("offset after 90s = %v, want (1,0)", got)
+	}
+	if got := b.offset(start.Add(4 * time.Minute)); got != (image.Point{}) {
+		t.Fatalf("offset after 4m = %v, want back at (0,0)", got)
+	}
+	if got := b.brightness(start); got != 100 {
+		t.Fatalf("brightness at start = %d, want 100", got)
+	}
+	if got := b.brightness(start.Add(5 * time.Minute)); got != 50 {
+		t.Fatalf("brightness at half period = %d, want 50", got)
+	}
+	if b.black(start.Add(59*time.Minute)) || !b.black(start.Add(time.Hour-time.Second)) {
+		t.Fatalf("black frame should cover only the last %s of each hour", defaultBlackDuration)
+	}
+	if got := b.wait(start.Add(90 * time.Second)); got != 6*time.Second {
+		t.Fatalf("wait = %v, want the brightness step", got)
+	}
+
+	frame := solidArt(color.White)
+	frame.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	b.apply(frame, start.Add(150*time.Second))
+	if got := frame.RGBAAt(1, 1); got.R == 0 || got.G != 0 {
+		t.Fatalf("pixel (1,1) = %v, want the red corner moved onto it", got)
+	}
+	if got := frame.RGBAAt(1, 0); got != (color.RGBA{A: 0xff}) {
+		t.Fatalf("uncovered top row = %v, want black", got)
+	}
+	if got := frame.RGBAAt(10, 10); got.R >= 0xff || got.R == 0 {
+		t.Fatalf("pixel (10,10) = %v, want dimmed white", got)
+	}
+}
+
+func TestRendererRefreshesStaticFrameForBurnIn(t *testing.T) {
+	out := &recordingDisplay{}
+	r := New(out, theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette}), Options{BurnIn: BurnInOptions{DriftInterval: time.Minute}})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	r.now = func() time.Time { return now }
+
+	art := solidArt(color.Black)
+	art.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	if err := r.Show(art); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wait := r.refreshWait(); wait != time.Minute {
+		t.Fatalf("refreshWait = %v, want the drift interval", wait)
+	}
+	now = start.Add(time.Minute)
+	r.refresh(context.Background())
+	if len(out.frames) != 2 {
+		t.Fatalf("frames = %d, want the drifted frame sent", len(out.frames))
+	}
+	if got := out.last().RGBAAt(1, 0); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("pixel (1,0) = %v, want the art moved one pixel right", got)
+	}
+	r.refresh(context.Background())
+	if len(out.frames) != 2 {
+		t.Fatalf("frames = %d, want no resend before the next drift", len(out.frames))
+	}
+}
+
+type focusScreen struct {
+	Screen
+	priority int
+}
+
+func (s *focusScreen) Focus(FrameState) int { return s.priority }
+
+func TestRotationCyclesScreensUntilOneWantsFocus(t *testing.T) {
+	fill := func(c color.RGBA) func(*image.RGBA, FrameState) error {
+		return func(frame *image.RGBA, _ FrameState) error {
+			draw.Draw(frame, frame.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+			return nil
+		}
+	}
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	alert := &focusScreen{Screen: NewScreen("alert", fill(blue))}
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{
+		Screens:  []Screen{NewScreen("red", fill(red)), alert},
+		Rotation: RotationOptions{Screens: []string{ScreenArt, "red"}, Interval: 10 * time.Second},
+	})
+	now := time.Unix(20000, 0)
+	r.now = func() time.Time { return now }
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if got := out.last().RGBAAt(32, 32); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("pixel = %v, want the art first", got)
+	}
+
+	now = now.Add(5 * time.Second)
+	r.mu.Lock()
+	if wait := r.screenWait(); wait != time.Second {
+		t.Fatalf("screenWait = %v, want the focus poll", wait)
+	}
+	now = now.Add(5 * time.Second)
+	r.refresh(context.Background())
+	r.mu.Unlock()
+	if got := out.last().RGBAAt(32, 32); got != red {
+		t.Fatalf("pixel = %v after the interval, want the red screen", got)
+	}
+
+	r.mu.Lock()
+	alert.priority = 1
+	r.refresh(context.Background())
+	r.mu.Unlock()
+	if got := out.last().RGBAAt(32, 32); got != blue {
+		t.Fatalf("pixel = %v, want the screen asking for focus", got)
+	}
+	r.SetLargeText(true)
+	r.SetSpecial(&Special{Name: "birthday", Banner: "Happy birthday"})
+	if got := out.last().RGBAAt(32, 32); got != blue {
+		t.Fatalf("pixel = %v with large text and a special day, want the screen asking for focus", got)
+	}
+	r.SetSpecial(nil)
+	r.SetLargeText(false)
+
+	alert.priority = 0
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear error: %v", err)
+	}
+	now = now.Add(10 * time.Second)
+	r.mu.Lock()
+	r.refresh(context.Background())
+	r.mu.Unlock()
+	if got := out.last().RGBAAt(32, 32); got != red {
+		t.Fatalf("pixel = %v while idle, want the rotation to skip the art", got)
+	}
+}
+
+func TestLargeTextReplacesArtUntilTurnedOff(t *testing.T) {
+	out := &recordingDisplay{}
+	current := theme.NewCurrent(theme.Theme{Palette: theme.DefaultPalette})
+	r := New(out, current, Options{})
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+	if err := r.Show(solidArt(color.White)); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	r.UpdateStatus(sonos.PlaybackStatus{Playing: true, State: "Playing", Track: sonos.TrackInfo{Title: "Bohemian Rhapsody", Artist: "Queen"}})
+	r.SetLargeText(true)
+	if !r.LargeText() || r.showingArt() {
+		t.Fatalf("LargeText=%v showingArt=%v, want the large text over the art", r.LargeText(), r.showingArt())
+	}
+	frame := out.last()
+	var top, bottom int
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			switch {
+			case frame.RGBAAt(x, y) != white:
+			case y < 32:
+				top++
+			default:
+				bottom++
+			}
+		}
+	}
+	if top == 0 || bottom == 0 {
+		t.Fatalf("lit pixels top=%d bottom=%d, want the title above the artist", top, bottom)
+	}
+	if top+bottom > 64*64/2 {
+		t.Fatalf("%d white pixels, want text rather than the art", top+bottom)
+	}
+
+	r.mu.Lock()
+	animating := r.animating()
+	r.mu.Unlock()
+	if !animating {
+		t.Fatal("long title does not scroll")
+	}
+
+	r.SetLargeText(false)
+	if !r.showingArt() {
+		t.Fatal("showingArt = false after turning large text off")
+	}
+	if got := out.last().RGBAAt(32, 32); got != white {
+		t.Fatalf("pixel = %v after turning large text off, want the art", got)
+	}
+}
@@ -0,0 +1,206 @@
+package render
+
+import (
+	"image"
+	"time"
+)
+
+const (
+	// ScreenArt names the now-playing screen in a rotation.
+	ScreenArt = "art"
+
+	// RotateAlways rotates whether or not music is playing.
+	RotateAlways = "always"
+	// RotatePlaying rotates only while there is artwork to show.
+	RotatePlaying = "playing"
+	// RotateIdle rotates only once the listener reports idle.
+	RotateIdle = "idle"
+
+	defaultRotationInterval = 30 * time.Second
+	// focusPoll is how often screens are asked whether they want focus.
+	focusPoll = time.Second
+)
+
+// Screen is a full-frame view the renderer can show in place of the
+// artwork, such as weather or a dashboard. Screens are registered in
+// Options.Screens and shown by name, like the built-in IdleClock, by the
+// idle screen setting, a rotation, a scene rule, or by asking for focus.
+// Screens that implement AnimatedLayer's Animating are redrawn at
+// Options.FPS while it reports true, and every minute otherwise.
+//
+// The screens in this repository are laid out for a 64x64 panel and scale
+// every measure by the whole number of times 64 fits the frame's shorter
+// side, so a 128x128 wall doubles them and a 128x64 one keeps the 64x64
+// sizes with more room across.
+type Screen interface {
+	// Name identifies the screen in settings. It must not clash with the
+	// built-in screen names.
+	Name() string
+	// Draw renders the screen into frame, which is already filled with the
+	// theme background.
+	Draw(frame *image.RGBA, state FrameState) error
+}
+
+// FocusScreen is a Screen that can ask to be shown, such as a running
+// kitchen timer. While any screen reports a priority above zero, the one
+// with the highest is shown in place of everything else: the artwork, the
+// rotation, scene rules, special days, and the large-text mode.
+type FocusScreen interface {
+	Screen
+	Focus(state FrameState) int
+}
+
+// RotationOptions cycles the display through several screens.
+type RotationOptions struct {
+	// Screens lists the screens in order by name: ScreenArt, the idle
+	// screens (IdleClock, IdleAnimation, IdleScript), or a registered
+	// Screen. ScreenArt is skipped while nothing is playing.
+	Screens []string
+	// Interval is how long each screen is shown (default 30s). Screens
+	// change on multiples of Interval by the wall clock.
+	Interval time.Duration
+	// When is RotateAlways (default), RotatePlaying, or RotateIdle.
+	When string
+}
+
+func (o RotationOptions) withDefaults() RotationOptions {
+	if o.Interval <= 0 {
+		o.Interval = defaultRotationInterval
+	}
+	if o.When != RotatePlaying && o.When != RotateIdle {
+		o.When = RotateAlways
+	}
+	return o
+}
+
+// screenFunc adapts a name and a draw function to Screen.
+type screenFunc struct {
+	name string
+	draw func(frame *image.RGBA, state FrameState) error
+}
+
+func (s screenFunc) Name() string { return s.name }
+
+func (s screenFunc) Draw(frame *image.RGBA, state FrameState) error { return s.draw(frame, state) }
+
+// NewScreen returns a Screen called name that draws with draw.
+func NewScreen(name string, draw func(frame *image.RGBA, state FrameState) error) Screen {
+	return screenFunc{name: name, draw: draw}
+}
+
+// override returns the screen shown in place of what playback would show:
+// a screen asking for focus, the large-text screen, a scene rule's screen,
+// or the rotation's current screen. It returns "" when playback decides.
+// Callers must hold r.mu.
+func (r *Renderer) override() string {
+	if name := r.focused(); name != "" {
+		return name
+	}
+	if r.largeText {
+		return ScreenLargeText
+	}
+	if r.screen != "" {
+		return r.screen
+	}
+	return r.rotating()
+}
+
+// focused returns the name of the registered screen with the highest focus
+// priority, or "" when none wants focus. Callers must hold r.mu.
+func (r *Renderer) focused() string {
+	best, name := 0, ""
+	for _, screen := range r.opts.Screens {
+		focus, ok := screen.(FocusScreen)
+		if !ok {
+			continue
+		}
+		if priority := focus.Focus(r.frameState()); priority > best {
+			best, name = priority, screen.Name()
+		}
+	}
+	return name
+}
+
+// rotating returns the rotation's screen at the current time, or "" for the
+// artwork or when the rotation does not apply. Callers must hold r.mu.
+func (r *Renderer) rotating() string {
+	rotation := r.opts.Rotation
+	switch {
+	case len(rotation.Screens) == 0:
+		return ""
+	case rotation.When == RotatePlaying && r.art == nil:
+		return ""
+	case rotation.When == RotateIdle && !r.idle:
+		return ""
+	}
+	eligible := make([]string, 0, len(rotation.Screens))
+	for _, name := range rotation.Screens {
+		if name != ScreenArt || r.art != nil {
+			eligible = append(eligible, name)
+		}
+	}
+	if len(eligible) == 0 {
+		return ""
+	}
+	step := int(r.now().UnixNano() / int64(rotation.Interval) % int64(len(eligible)))
+	if name := eligible[step]; name != ScreenArt {
+		return name
+	}
+	return ""
+}
+
+// screenWait returns how long until the screen to show may change on its
+// own, or 0 when only an update can change it. Callers must hold r.mu.
+func (r *Renderer) screenWait() time.Duration {
+	var wait time.Duration
+	if len(r.opts.Rotation.Screens) > 1 {
+		interval := r.opts.Rotation.Interval
+		wait = interval - time.Duration(r.now().UnixNano()%int64(interval))
+	}
+	for _, screen := range r.opts.Screens {
+		if _, ok := screen.(FocusScreen); ok && (wait == 0 || focusPoll < wait) {
+			wait = focusPoll
+			break
+		}
+	}
+	return wait
+}
+
+// syncScreen notes a change of the screen shown in place of the art and
+// resets per-screen state so the new one starts afresh. It reports whether
+// the screen changed. Callers must hold r.mu.
+func (r *Renderer) syncScreen() bool {
+	active := r.override()
+	if active == r.active {
+		return false
+	}
+	r.active = active
+	r.drawn = false
+	r.frame = 0
+	r.transition = transitionState{}
+	return true
+}
+
+// customScreen returns the registered screen on display, or nil. Only a
+// screen asking for focus shows over a special day. Callers must hold r.mu.
+func (r *Renderer) customScreen() Screen {
+	if !r.showingIdle() {
+		return nil
+	}
+	name := r.idleScreen()
+	if r.special != nil && name != r.focused() {
+		return nil
+	}
+	for _, screen := range r.opts.Screens {
+		if screen.Name() == name {
+			return screen
+		}
+	}
+	return nil
+}
+
+// frameState is the state layers and screens draw from. Callers must hold
+// r.mu.
+func (r *Renderer) frameState() FrameState {
+	return FrameState{Status: r.status, Palette: r.palette(), Now: r.now()}
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+
+	"musicDisplay/theme"
+)
+
+const (
+	// SceneNone shows only the banner text on the idle screen.
+	SceneNone = ""
+	// SceneSnow animates slowly falling white flakes behind the banner.
+	SceneSnow = "snow"
+	// SceneConfetti animates falling multicolored confetti behind the banner.
+	SceneConfetti = "confetti"
+
+	bannerRows     = 12
+	sceneParticles = 40
+	// sceneStepsPerSecond is how many particle steps a scene takes each
+	// second; particle speeds are in pixels per step.
+	sceneStepsPerSecond = 20
+)
+
+// Special describes a date-specific screen, such as a birthday banner or a
+// holiday animation, that replaces the normal idle screen while active.
+type Special struct {
+	Name string
+	// Banner is the text shown on the idle screen and, with Overlay, along
+	// the top of the artwork.
+	Banner string
+	// Color is the banner text color. The zero value uses the theme accent.
+	Color color.RGBA
+	// Scene is SceneNone, SceneSnow, or SceneConfetti.
+	Scene string
+	// Overlay also draws the banner over album art while music is playing.
+	Overlay bool
+}
+
+func (s *Special) bannerColor(palette theme.Palette) color.RGBA {
+	if s.Color == (color.RGBA{}) {
+		return palette.Accent
+	}
+	return s.Color
+}
+
+type particle struct {
+	x, y  float64
+	speed float64
+	drift float64
+	color color.RGBA
+}
+
+// sceneState animates the particles of a special scene.
+type sceneState struct {
+	kind      string
+	particles []particle
+	rng       *rand.Rand
+	size      image.Point
+}
+
+var confettiColors = []color.RGBA{
+	{R: 0xff, G: 0x40, B: 0x40, A: 0xff},
+	{R: 0xff, G: 0xd0, B: 0x20, A: 0xff},
+	{R: 0x40, G: 0xd0, B: 0x60, A: 0xff},
+	{R: 0x40, G: 0x90, B: 0xff, A: 0xff},
+	{R: 0xd0, G: 0x50, B: 0xff, A: 0xff},
+}
+
+// reset seeds the particles for kind. It is a no-op when the scene is
+// unchanged so animation continues smoothly across redraws.
+func (s *sceneState) reset(kind string, size image.Point) {
+	if s.kind == kind && s.size == size && s.particles != nil {
+		return
+	}
+	s.kind = kind
+	s.size = size
+	s.particles = nil
+	if kind != SceneSnow && kind != SceneConfetti {
+		return
+	}
+	s.rng = rand.New(rand.NewSource(1))
+	s.particles = make([]particle, sceneParticles)
+	for i := range s.particles {
+		s.particles[i] = s.spawn(s.rng.Float64() * float64(size.Y))
+	}
+}
+
+func (s *sceneState) spawn(y float64) particle {
+	p := particle{
+		x: s.rng.Float64() * float64(s.size.X),
+		y: y,
+	}
+	if s.kind == SceneSnow {
+		p.speed = 0.15 + s.rng.Float64()*0.25
+		p.drift = (s.rng.Float64() - 0.5) * 0.2
+		p.color = color.RGBA{R: 0xc0, G: 0xc0, B: 0xd0, A: 0xff}
+	} else {
+		p.speed = 0.3 + s.rng.Float64()*0.4
+		p.drift = (s.rng.Float64() - 0.5) * 0.4
+		p.color = confettiColors[s.rng.Intn(len(confettiColors))]
+	}
+	return p
+}
+
+func (s *sceneState) animated() bool {
+	return len(s.particles) > 0
+}
+
+// run moves every particle by steps, which may be fractional.
+func (s *sceneState) run(steps float64) {
+	for i := range s.particles {
+		p := &s.particles[i]
+		p.y += p.speed * steps
+		p.x += p.drift * steps
+		if p.x < 0 {
+			p.x += float64(s.size.X)
+		} else if p.x >= float64(s.size.X) {
+			p.x -= float64(s.size.X)
+		}
+		if p.y >= float64(s.size.Y) {
+			*p = s.spawn(0)
+		}
+	}
+}
+
+func (s *sceneState) draw(frame *image.RGBA) {
+	for _, p := range s.particles {
+		frame.SetRGBA(int(p.x), int(p.y), p.color)
+	}
+}
+
+// drawSpecial renders the idle screen for special: background, scene
+// particles, and the banner text across the middle of the frame.
+func drawSpecial(frame *image.RGBA, scene *sceneState, banner *tickerState, special *Special, palette theme.Palette) error {
+	bounds := frame.Bounds()
+	draw.Draw(frame, bounds, image.NewUniform(palette.Background), image.Point{}, draw.Src)
+	scene.draw(frame)
+
+	if err := banner.prepare(special.Banner, bannerRows, special.bannerColor(palette), bounds); err != nil {
+		return err
+	}
+	y := bounds.Min.Y + (bounds.Dy()-bannerRows)/2
+	banner.drawText(frame, image.Rect(bounds.Min.X, y, bounds.Max.X, y+bannerRows))
+	return nil
+}
+
+// overlayBanner draws special's banner in a shaded band along the top of a
+// composed now-playing frame.
+func overlayBanner(frame *image.RGBA, banner *tickerState, special *Special, rows int, palette theme.Palette) error {
+	bounds := frame.Bounds()
+	band := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Min.Y+rows)
+	if err := banner.prepare(special.Banner, rows, special.bannerColor(palette), bounds); err != nil {
+		return err
+	}
+	shadeBand(frame, band, palette)
+	banner.drawText(frame, band)
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package text draws text with small bitmap fonts. At the sizes an LED
+// matrix can show, outline fonts antialias into blurry smudges; these fonts
+// light whole pixels, so a 64-pixel panel can show a dozen crisp characters
+// per line.
+//
+// Two fonts are provided: Small, 3x5 uppercase, and Medium, 5x7 with
+// lowercase and a one-pixel descender. Glyphs are proportional and pairs are
+// kerned automatically where their shapes leave room, e.g. "T." and "LT".
+// Accented letters are drawn without their accents; Covers reports whether
+// a string can be drawn without falling back to '?'.
+package text
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Font is a bitmap font.
+type Font struct {
+	// ascent is the height of capitals and digits, descent the rows below
+	// the baseline used by g, j, p, q, and y.
+	ascent  int
+	descent int
+	// upper draws lowercase letters as capitals.
+	upper  bool
+	glyphs map[rune]*glyph
+}
+
+type glyph struct {
+	width int
+	// rows holds the lit pixels, ascent+descent rows of width columns.
+	rows [][]bool
+	// left and right are the blank columns before and after the ink in
+	// each row, used for kerning. Blank rows hold width.
+	left, right []int
+	// blank is set for glyphs without ink, such as space, which are never
+	// kerned.
+	blank bool
+}
+
+const (
+	// spacing is the blank columns between glyphs before kerning.
+	spacing = 1
+	// maxKern is the most a pair is pulled together.
+	maxKern = 1
+	// fallback is drawn for runes the font does not cover.
+	fallback = '?'
+)
+
+var (
+	// Small is a 3x5 uppercase font for labels such as AM/PM markers.
+	Small = newFont(5, 0, true, small3x5)
+	// Medium is a 5x7 font with lowercase for tickers, banners, and the
+	// clock.
+	Medium = newFont(7, 1, false, medium5x7)
+)
+
+// substitutes maps typographic punctuation common in track metadata onto
+// characters the fonts draw.
+var substitutes = strings.NewReplacer(
+	"–", "-", "—", "-", "‘", "'", "’", "'", "“", "\"", "”", "\"", "…", "...", "×", "x",
+)
+
+// newFont parses glyph definitions: rows separated by spaces, '#' for a lit
+// pixel. Glyphs with fewer rows than ascent+descent are padded below.
+func newFont(ascent, descent int, upper bool, defs map[rune]string) *Font {
+	f := &Font{ascent: ascent, descent: descent, upper: upper, glyphs: make(map[rune]*glyph, len(defs))}
+	height := ascent + descent
+	for r, def := range defs {
+		lines := strings.Fields(def)
+		g := &glyph{width: len(lines[0]), rows: make([][]bool, height), left: make([]int, height), right: make([]int, height), blank: true}
+		for y := range height {
+			g.rows[y] = make([]bool, g.width)
+			g.left[y], g.right[y] = g.width, g.width
+			if y >= len(lines) {
+				continue
+			}
+			for x, c := range lines[y] {
+				if c != '#' {
+					continue
+				}
+				g.rows[y][x] = true
+				g.blank = false
+				g.left[y] = min(g.left[y], x)
+				g.right[y] = min(g.right[y], g.width-1-x)
+			}
+		}
+		f.glyphs[r] = g
+	}
+	return f
+}
+
+// Height is the height of a line in pixels at scale 1, including the
+// descender.
+func (f *Font) Height() int {
+	return f.ascent + f.descent
+}
+
+// Ascent is the height of capitals and digits at scale 1.
+func (f *Font) Ascent() int {
+	return f.ascent
+}
+
+// normalize applies typographic substitutions and strips accents.
+func normalize(s string) string {
+	s = substitutes.Replace(s)
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lookup returns the glyph for r and whether the font has one.
+func (f *Font) lookup(r rune) (*glyph, bool) {
+	if f.upper {
+		r = unicode.ToUpper(r)
+	}
+	if g, ok := f.glyphs[r]; ok {
+		return g, true
+	}
+	return f.glyphs[fallback], false
+}
+
+// Covers reports whether every character of s has a glyph, after accents
+// are stripped.
+func (f *Font) Covers(s string) bool {
+	for _, r := range normalize(s) {
+		if _, ok := f.lookup(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// layout returns the glyphs of s and the x position of each at scale 1.
+func (f *Font) layout(s string) ([]*glyph, []int, int) {
+	var glyphs []*glyph
+	var xs []int
+	x := 0
+	var prev *glyph
+	for _, r := range normalize(s) {
+		g, _ := f.lookup(r)
+		if prev != nil {
+			x += prev.width + spacing - kern(prev, g)
+		}
+		glyphs = append(glyphs, g)
+		xs = append(xs, x)
+		prev = g
+	}
+	if prev == nil {
+		return nil, nil, 0
+	}
+	return glyphs, xs, x + prev.width
+}
+
+// kern returns how many columns b can move towards a without their ink
+// touching, even diagonally, up to maxKern.
+func kern(a, b *glyph) int {
+	if a.blank || b.blank {
+		return 0
+	}
+	clearance := a.width + b.width
+	for y := range a.rows {
+		for d := -1; d <= 1; d++ {
+			if y+d < 0 || y+d >= len(b.rows) {
+				continue
+			}
+			clearance = min(clearance, a.right[y]+b.left[y+d])
+		}
+	}
+	return min(clearance, maxKern)
+}
+
+// Measure returns the width of s in pixels at scale 1.
+func (f *Font) Measure(s string) int {
+	_, _, width := f.layout(s)
+	return width
+}
+
+// Draw draws s into dst with its top-left corner at (x, y), each font pixel
+// scale pixels square, and returns the width drawn. Pixels outside dst are
+// clipped.
+func (f *Font) Draw(dst *image.RGBA, x, y int, s string, col color.RGBA, scale int) int {
+	scale = max(scale, 1)
+	glyphs, xs, width := f.layout(s)
+	bounds := dst.Bounds()
+	for i, g := range glyphs {
+		for row, pixels := range g.rows {
+			for column, lit := range pixels {
+				if !lit {
+					continue
+				}
+				px := x + (xs[i]+column)*scale
+				py := y + row*scale
+				cell := image.Rect(px, py, px+scale, py+scale).Intersect(bounds)
+				for cy := cell.Min.Y; cy < cell.Max.Y; cy++ {
+					for cx := cell.Min.X; cx < cell.Max.X; cx++ {
+						dst.SetRGBA(cx, cy, col)
+					}
+				}
+			}
+		}
+	}
+	return width * scale
+}
+
+// Render draws s onto a transparent strip exactly as wide as the text and
+// Height()*scale tall.
+func (f *Font) Render(s string, col color.RGBA, scale int) *image.RGBA {
+	scale = max(scale, 1)
+	strip := image.NewRGBA(image.Rect(0, 0, max(f.Measure(s)*scale, 1), f.Height()*scale))
+	f.Draw(strip, 0, 0, s, col, scale)
+	return strip
+}
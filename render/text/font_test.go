@@ -0,0 +1,126 @@
+package text
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestGlyphTablesAreWellFormed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		defs   map[rune]string
+		height int
+	}{
+		"small":  {small3x5, 5},
+		"medium": {medium5x7, 8},
+	} {
+		for r := rune(' '); r <= '~'; r++ {
+			if name == "small" && r >= 'a' && r <= 'z' {
+				continue
+			}
+			def, ok := tc.defs[r]
+			if !ok {
+				t.Fatalf("%s font has no glyph for %q", name, r)
+			}
+			rows := strings.Fields(def)
+			if len(rows) > tc.height {
+				t.Fatalf("%s glyph %q has %d rows, want at most %d", name, r, len(rows), tc.height)
+			}
+			for _, row := range rows {
+				if len(row) != len(rows[0]) {
+					t.Fatalf("%s glyph %q has ragged rows %q", name, r, def)
+				}
+			}
+		}
+	}
+}
+
+func TestMeasureKernsAndSpaces(t *testing.T) {
+	if got := Medium.Measure("H"); got != 5 {
+		t.Fatalf("Measure(H) = %d, want 5", got)
+	}
+	if got := Medium.Measure("HH"); got != 11 {
+		t.Fatalf("Measure(HH) = %d, want two glyphs and a column between", got)
+	}
+	if got := Medium.Measure("T."); got != 6 {
+		t.Fatalf("Measure(T.) = %d, want the period tucked under the T", got)
+	}
+	if got := Medium.Measure("H H"); got != 5+1+3+1+5 {
+		t.Fatalf("Measure(H H) = %d, want an unkerned space", got)
+	}
+	if got := Medium.Measure(""); got != 0 {
+		t.Fatalf("Measure(\"\") = %d, want 0", got)
+	}
+	if got, want := Small.Measure("pm"), Small.Measure("PM"); got != want {
+		t.Fatalf("Small.Measure(pm) = %d, want the capitals' %d", got, want)
+	}
+}
+
+func TestDrawLightsWholePixels(t *testing.T) {
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	frame := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	if w := Medium.Draw(frame, 1, 1, "I", white, 2); w != 6 {
+		t.Fatalf("Draw width = %d, want 6 at scale 2", w)
+	}
+	for _, p := range []image.Point{{1, 1}, {6, 2}, {3, 13}, {4, 14}} {
+		if got := frame.RGBAAt(p.X, p.Y); got != white {
+			t.Fatalf("pixel %v = %v, want lit", p, got)
+		}
+	}
+	for _, p := range []image.Point{{1, 3}, {7, 1}, {0, 0}} {
+		if got := frame.RGBAAt(p.X, p.Y); got != (color.RGBA{}) {
+			t.Fatalf("pixel %v = %v, want untouched", p, got)
+		}
+	}
+	for _, c := range frame.Pix {
+		if c != 0 && c != 0xff {
+			t.Fatalf("found partial intensity %d, want no antialiasing", c)
+		}
+	}
+
+	strip := Medium.Render("Hey", white, 1)
+	if strip.Bounds() != image.Rect(0, 0, Medium.Measure("Hey"), 8) {
+		t.Fatalf("Render bounds = %v", strip.Bounds())
+	}
+	descender := false
+	for x := 0; x < strip.Bounds().Dx(); x++ {
+		descender = descender || strip.RGBAAt(x, 7) == white
+	}
+	if !descender {
+		t.Fatal("descender of y not drawn in the last row")
+	}
+}
+
+func TestCoversAccentsAndPunctuation(t *testing.T) {
+	if !Medium.Covers("Beyoncé – Déjà Vu’s “Hit”…") {
+		t.Fatal("accented letters and typographic punctuation should be covered")
+	}
+	if got, want := Medium.Measure("é"), Medium.Measure("e"); got != want {
+		t.Fatalf("Measure(é) = %d, want the width of e (%d)", got, want)
+	}
+	if Medium.Covers("坂本龍一") {
+		t.Fatal("CJK text reported as covered")
+	}
+	if got, want := Medium.Measure("龍"), Medium.Measure("?"); got != want {
+		t.Fatalf("uncovered rune width = %d, want the fallback's %d", got, want)
+	}
+}
+
+func TestWrapSplitsLongWords(t *testing.T) {
+	lines := Medium.Wrap("a short line and Supercalifragilistic", 40)
+	for _, line := range lines {
+		if w := Medium.Measure(line); w > 40 {
+			t.Fatalf("line %q is %d pixels wide", line, w)
+		}
+	}
+	if joined := strings.Join(lines, " "); strings.ReplaceAll(joined, " ", "") != "ashortlineandSupercalifragilistic" {
+		t.Fatalf("lines = %q, want every letter kept", lines)
+	}
+	if got := Medium.Wrap("Lunch", 40); len(got) != 1 || got[0] != "Lunch" {
+		t.Fatalf("short text = %q", got)
+	}
+	if got := Medium.Wrap("   ", 40); len(got) != 0 {
+		t.Fatalf("blank text = %q, want no lines", got)
+	}
+}
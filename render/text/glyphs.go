@@ -0,0 +1,177 @@
+package text
+
+// small3x5 holds the Small font's glyphs, five rows each. Lowercase letters
+// are drawn with the capitals.
+var small3x5 = map[rune]string{
+	' ':  ".. .. .. .. ..",
+	'!':  "# # # . #",
+	'"':  "#.# #.# ... ... ...",
+	'#':  "#.# ### #.# ### #.#",
+	'$':  ".## ##. .#. .## ##.",
+	'%':  "#.. ..# .#. #.. ..#",
+	'&':  ".#. #.# .#. #.# .##",
+	'\'': "# # . . .",
+	'(':  ".# #. #. #. .#",
+	')':  "#. .# .# .# #.",
+	'*':  "... #.# .#. #.# ...",
+	'+':  "... .#. ### .#. ...",
+	',':  ".. .. .. .# #.",
+	'-':  "... ... ### ... ...",
+	'.':  ". . . . #",
+	'/':  "..# ..# .#. #.. #..",
+	'0':  "### #.# #.# #.# ###",
+	'1':  ".#. ##. .#. .#. ###",
+	'2':  "### ..# ### #.. ###",
+	'3':  "### ..# .## ..# ###",
+	'4':  "#.# #.# ### ..# ..#",
+	'5':  "### #.. ### ..# ###",
+	'6':  "### #.. ### #.# ###",
+	'7':  "### ..# .#. .#. .#.",
+	'8':  "### #.# ### #.# ###",
+	'9':  "### #.# ### ..# ###",
+	':':  ". # . # .",
+	';':  ".. .# .. .# #.",
+	'<':  "..# .#. #.. .#. ..#",
+	'=':  "... ### ... ### ...",
+	'>':  "#.. .#. ..# .#. #..",
+	'?':  "### ..# .## ... .#.",
+	'@':  "### #.# #.# #.. .##",
+	'A':  ".#. #.# ### #.# #.#",
+	'B':  "##. #.# ##. #.# ##.",
+	'C':  ".## #.. #.. #.. .##",
+	'D':  "##. #.# #.# #.# ##.",
+	'E':  "### #.. ##. #.. ###",
+	'F':  "### #.. ##. #.. #..",
+	'G':  ".## #.. #.# #.# .##",
+	'H':  "#.# #.# ### #.# #.#",
+	'I':  "### .#. .#. .#. ###",
+	'J':  "..# ..# ..# #.# .#.",
+	'K':  "#.# #.# ##. #.# #.#",
+	'L':  "#.. #.. #.. #.. ###",
+	'M':  "#...# ##.## #.#.# #...# #...#",
+	'N':  "#..# ##.# #.## #..# #..#",
+	'O':  ".#. #.# #.# #.# .#.",
+	'P':  "##. #.# ##. #.. #..",
+	'Q':  ".#. #.# #.# ##. .##",
+	'R':  "##. #.# ##. #.# #.#",
+	'S':  ".## #.. .#. ..# ##.",
+	'T':  "### .#. .#. .#. .#.",
+	'U':  "#.# #.# #.# #.# ###",
+	'V':  "#.# #.# #.# #.# .#.",
+	'W':  "#...# #...# #.#.# ##.## #...#",
+	'X':  "#.# #.# .#. #.# #.#",
+	'Y':  "#.# #.# .#. .#. .#.",
+	'Z':  "### ..# .#. #.. ###",
+	'[':  "## #. #. #. ##",
+	'\\': "#.. #.. .#. ..# ..#",
+	']':  "## .# .# .# ##",
+	'^':  ".#. #.# ... ... ...",
+	'_':  "... ... ... ... ###",
+	'`':  "#. .# .. .. ..",
+	'{':  ".## .#. ##. .#. .##",
+	'|':  "# # # # #",
+	'}':  "##. .#. .## .#. ##.",
+	'~':  "... ##. .## ... ...",
+	'°':  "## ##",
+}
+
+// medium5x7 holds the Medium font's glyphs: seven rows down to the
+// baseline, plus an eighth for descenders.
+var medium5x7 = map[rune]string{
+	' ':  "... ... ... ... ... ... ...",
+	'!':  "# # # # # . #",
+	'"':  "#.# #.# ... ... ... ... ...",
+	'#':  ".#.#. .#.#. ##### .#.#. ##### .#.#. .#.#.",
+	'$':  "..#.. .#### #.#.. .###. ..#.# ####. ..#..",
+	'%':  "##... ##..# ...#. ..#.. .#... #..## ...##",
+	'&':  ".##.. #..#. #.#.. .#... #.#.# #..#. .##.#",
+	'\'': "# # . . . . .",
+	'(':  ".# #. #. #. #. #. .#",
+	')':  "#. .# .# .# .# .# #.",
+	'*':  "..... ..#.. #.#.# .###. #.#.# ..#.. .....",
+	'+':  "..... ..#.. ..#.. ##### ..#.. ..#.. .....",
+	',':  ".. .. .. .. .# .# #.",
+	'-':  ".... .... .... #### .... .... ....",
+	'.':  ". . . . . . #",
+	'/':  "....# ...#. ...#. ..#.. .#... .#... #....",
+	'0':  ".###. #...# #..## #.#.# ##..# #...# .###.",
+	'1':  ".#. ##. .#. .#. .#. .#. ###",
+	'2':  ".###. #...# ....# ...#. ..#.. .#... #####",
+	'3':  "####. ....# ....# .###. ....# ....# ####.",
+	'4':  "...#. ..##. .#.#. #..#. ##### ...#. ...#.",
+	'5':  "##### #.... ####. ....# ....# #...# .###.",
+	'6':  "..##. .#... #.... ####. #...# #...# .###.",
+	'7':  "##### ....# ...#. ..#.. .#... .#... .#...",
+	'8':  ".###. #...# #...# .###. #...# #...# .###.",
+	'9':  ".###. #...# #...# .#### ....# ...#. .##..",
+	':':  ". . # . . # .",
+	';':  ".. .. .# .. .. .# #.",
+	'<':  "...# ..#. .#.. #... .#.. ..#. ...#",
+	'=':  "..... ..... ##### ..... ##### ..... .....",
+	'>':  "#... .#.. ..#. ...# ..#. .#.. #...",
+	'?':  ".###. #...# ....# ...#. ..#.. ..... ..#..",
+	'@':  ".###. #...# #.### #.#.# #.### #.... .####",
+	'A':  ".###. #...# #...# ##### #...# #...# #...#",
+	'B':  "####. #...# #...# ####. #...# #...# ####.",
+	'C':  ".###. #...# #.... #.... #.... #...# .###.",
+	'D':  "####. #...# #...# #...# #...# #...# ####.",
+	'E':  "##### #.... #.... ####. #.... #.... #####",
+	'F':  "##### #.... #.... ####. #.... #.... #....",
+	'G':  ".###. #...# #.... #.### #...# #...# .####",
+	'H':  "#...# #...# #...# ##### #...# #...# #...#",
+	'I':  "### .#. .#. .#. .#. .#. ###",
+	'J':  "..### ...#. ...#. ...#. ...#. #..#. .##..",
+	'K':  "#...# #..#. #.#.. ##... #.#.. #..#. #...#",
+	'L':  "#.... #.... #.... #.... #.... #.... #####",
+	'M':  "#...# ##.## #.#.# #.#.# #...# #...# #...#",
+	'N':  "#...# #...# ##..# #.#.# #..## #...# #...#",
+	'O':  ".###. #...# #...# #...# #...# #...# .###.",
+	'P':  "####. #...# #...# ####. #.... #.... #....",
+	'Q':  ".###. #...# #...# #...# #.#.# #..#. .##.#",
+	'R':  "####. #...# #...# ####. #.#.. #..#. #...#",
+	'S':  ".#### #.... #.... .###. ....# ....# ####.",
+	'T':  "##### ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'U':  "#...# #...# #...# #...# #...# #...# .###.",
+	'V':  "#...# #...# #...# #...# #...# .#.#. ..#..",
+	'W':  "#...# #...# #...# #.#.# #.#.# #.#.# .#.#.",
+	'X':  "#...# #...# .#.#. ..#.. .#.#. #...# #...#",
+	'Y':  "#...# #...# .#.#. ..#.. ..#.. ..#.. ..#..",
+	'Z':  "##### ....# ...#. ..#.. .#... #.... #####",
+	'[':  "## #. #. #. #. #. ##",
+	'\\': "#.... .#... .#... ..#.. ...#. ...#. ....#",
+	']':  "## .# .# .# .# .# ##",
+	'^':  "..#.. .#.#. #...# ..... ..... ..... .....",
+	'_':  "..... ..... ..... ..... ..... ..... #####",
+	'`':  "#. .# .. .. .. .. ..",
+	'a':  "..... ..... .###. ....# .#### #...# .####",
+	'b':  "#.... #.... #.##. ##..# #...# #...# ####.",
+	'c':  "..... ..... .###. #.... #.... #...# .###.",
+	'd':  "....# ....# .##.# #..## #...# #...# .####",
+	'e':  "..... ..... .###. #...# ##### #.... .###.",
+	'f':  "..## .#.. .#.. ###. .#.. .#.. .#..",
+	'g':  "..... ..... .#### #...# #...# .#### ....# .###.",
+	'h':  "#.... #.... #.##. ##..# #...# #...# #...#",
+	'i':  "# . # # # # #",
+	'j':  "..# ... .## ..# ..# ..# ..# ##.",
+	'k':  "#... #... #..# #.#. ##.. #.#. #..#",
+	'l':  "##. .#. .#. .#. .#. .#. ###",
+	'm':  "..... ..... ##.#. #.#.# #.#.# #.#.# #.#.#",
+	'n':  "..... ..... #.##. ##..# #...# #...# #...#",
+	'o':  "..... ..... .###. #...# #...# #...# .###.",
+	'p':  "..... ..... ####. #...# #...# ####. #.... #....",
+	'q':  "..... ..... .#### #...# #...# .#### ....# ....#",
+	'r':  "..... ..... #.##. ##..# #.... #.... #....",
+	's':  "..... ..... .#### #.... .###. ....# ####.",
+	't':  ".#.. .#.. ###. .#.. .#.. .#.. ..##",
+	'u':  "..... ..... #...# #...# #...# #..## .##.#",
+	'v':  "..... ..... #...# #...# #...# .#.#. ..#..",
+	'w':  "..... ..... #...# #...# #.#.# #.#.# .#.#.",
+	'x':  "..... ..... #...# .#.#. ..#.. .#.#. #...#",
+	'y':  "..... ..... #...# #...# #...# .#### ....# .###.",
+	'z':  "..... ..... ##### ...#. ..#.. .#... #####",
+	'{':  "..# .#. .#. #.. .#. .#. ..#",
+	'|':  "# # # # # # #",
+	'}':  "#.. .#. .#. ..# .#. .#. #..",
+	'~':  "..... ..... .#... #.#.# ...#. ..... .....",
+	'°':  ".#. #.# .#.",
+}
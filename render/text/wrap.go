@@ -0,0 +1,40 @@
+package text
+
+import "strings"
+
+// Wrap breaks s into lines no wider than width pixels at scale 1, splitting
+// words that do not fit on a line of their own.
+func (f *Font) Wrap(s string, width int) []string {
+	var out []string
+	line := ""
+	words := strings.Fields(s)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if f.Measure(candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line == "" {
+			// Split a word too long for any line at the last rune that
+			// fits.
+			runes := []rune(word)
+			cut := 1
+			for cut < len(runes) && f.Measure(string(runes[:cut+1])) <= width {
+				cut++
+			}
+			line = string(runes[:cut])
+			words[i] = string(runes[cut:])
+		}
+		i--
+		out = append(out, line)
+		line = ""
+	}
+	if line != "" {
+		out = append(out, line)
+	}
+	return out
+}
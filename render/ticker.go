@@ -0,0 +1,213 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+
+	"musicDisplay/overlay"
+	"musicDisplay/render/text"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+const (
+	// TickerOverlay draws the ticker band on top of the bottom rows of the art.
+	TickerOverlay = "overlay"
+	// TickerBelow shrinks the art so the ticker band sits underneath it.
+	TickerBelow = "below"
+
+	defaultTickerRows = 10
+	defaultTickerFPS  = 20
+	tickerGap         = 16
+)
+
+// TickerOptions configures the scrolling track ticker.
+type TickerOptions struct {
+	Enabled bool
+	// Rows is the height of the ticker band in pixels (8..16, default 10).
+	Rows int
+	// Position is TickerOverlay (default) or TickerBelow.
+	Position string
+	// FPS is the scroll speed in pixels per second (default 20).
+	FPS int
+	// UpNext appends "Up Next: Artist – Title" for the queued track when the
+	// speaker reports one.
+	UpNext bool
+}
+
+func (o TickerOptions) withDefaults() TickerOptions {
+	if o.Rows <= 0 {
+		o.Rows = defaultTickerRows
+	}
+	if o.Rows < 8 {
+		o.Rows = 8
+	}
+	if o.Rows > 16 {
+		o.Rows = 16
+	}
+	if o.Position != TickerBelow {
+		o.Position = TickerOverlay
+	}
+	if o.FPS <= 0 {
+		o.FPS = defaultTickerFPS
+	}
+	return o
+}
+
+type tickerState struct {
+	text      string
+	textColor color.RGBA
+	rows      int
+	strip     *image.RGBA
+	width     int
+	offset    int
+	pending   float64
+}
+
+// prepare renders the text strip when the text or its color changed. rows is
+// the height of the band the text is drawn in.
+func (t *tickerState) prepare(label string, rows int, col color.RGBA, frame image.Rectangle) error {
+	t.width = frame.Dx()
+	if t.strip != nil && label == t.text && col == t.textColor && rows == t.rows {
+		return nil
+	}
+	t.text = label
+	t.textColor = col
+	t.rows = rows
+	t.strip = nil
+	if label == "" {
+		return nil
+	}
+	strip, err := textStrip(label, rows, col)
+	if err != nil {
+		return err
+	}
+	t.strip = strip
+	return nil
+}
+
+// textStrip renders label for a band rows high: in the bitmap font at the
+// largest whole scale that fits, or in the outline font when the bitmap font
+// cannot draw every character, such as for CJK titles.
+func textStrip(label string, rows int, col color.RGBA) (*image.RGBA, error) {
+	if text.Medium.Covers(label) {
+		return text.Medium.Render(label, col, max(rows/text.Medium.Height(), 1)), nil
+	}
+	return overlay.TextLine(label, float64(rows)*0.85, col)
+}
+
+// scrolls reports whether the text is too wide to fit and must move.
+func (t *tickerState) scrolls() bool {
+	return t.strip != nil && t.strip.Bounds().Dx() > t.width
+}
+
+// scroll moves the text on by pixels, carrying fractions over to the next
+// call so slow speeds stay smooth at any frame rate.
+func (t *tickerState) scroll(pixels float64) {
+	t.pending += pixels
+	for ; t.pending >= 1; t.pending-- {
+		t.advance()
+	}
+}
+
+// advance moves the text on by one pixel.
+func (t *tickerState) advance() {
+	if !t.scrolls() {
+		t.offset = 0
+		return
+	}
+	t.offset = (t.offset + 1) % (t.strip.Bounds().Dx() + tickerGap)
+}
+
+// compose draws art and the ticker band into frame.
+func (t *tickerState) compose(frame *image.RGBA, art image.Image, opts TickerOptions, palette theme.Palette) {
+	bounds := frame.Bounds()
+	band := image.Rect(bounds.Min.X, bounds.Max.Y-opts.Rows, bounds.Max.X, bounds.Max.Y)
+
+	if opts.Position == TickerBelow {
+		draw.Draw(frame, bounds, image.NewUniform(palette.Background), image.Point{}, draw.Src)
+		size := bounds.Dy() - opts.Rows
+		x0 := bounds.Min.X + (bounds.Dx()-size)/2
+		target := image.Rect(x0, bounds.Min.Y, x0+size, bounds.Min.Y+size)
+		xdraw.ApproxBiLinear.Scale(frame, target, art, art.Bounds(), xdraw.Src, nil)
+	} else {
+		draw.Draw(frame, bounds, art, art.Bounds().Min, draw.Src)
+		shadeBand(frame, band, palette)
+	}
+	t.drawText(frame, band)
+}
+
+// shadeBand darkens band so text stays legible over artwork.
+func shadeBand(frame *image.RGBA, band image.Rectangle, palette theme.Palette) {
+	shade := palette.Background
+	shade.A = 0xc0
+	shade.R = uint8(uint16(shade.R) * 0xc0 / 0xff)
+	shade.G = uint8(uint16(shade.G) * 0xc0 / 0xff)
+	shade.B = uint8(uint16(shade.B) * 0xc0 / 0xff)
+	draw.Draw(frame, band, image.NewUniform(shade), image.Point{}, draw.Over)
+}
+
+// drawText draws the text strip centered vertically in band, centered
+// horizontally when it fits and scrolled by offset when it does not.
+func (t *tickerState) drawText(frame *image.RGBA, band image.Rectangle) {
+	if t.strip == nil {
+		return
+	}
+	stripW := t.strip.Bounds().Dx()
+	stripH := t.strip.Bounds().Dy()
+	y := band.Min.Y + (band.Dy()-stripH)/2
+
+	if !t.scrolls() {
+		x := band.Min.X + (band.Dx()-stripW)/2
+		dst := image.Rect(x, y, x+stripW, y+stripH)
+		clipped := dst.Intersect(band)
+		draw.Draw(frame, clipped, t.strip, clipped.Min.Sub(dst.Min), draw.Over)
+		return
+	}
+
+	for x := band.Min.X - t.offset; x < band.Max.X; x += stripW + tickerGap {
+		dst := image.Rect(x, y, x+stripW, y+stripH)
+		clipped := dst.Intersect(band)
+		if clipped.Empty() {
+			continue
+		}
+		draw.Draw(frame, clipped, t.strip, clipped.Min.Sub(dst.Min), draw.Over)
+	}
+}
+
+// statusTickerText is the ticker text for status: the current track, followed
+// by the next one when upNext is set and the queue has one.
+func statusTickerText(status sonos.PlaybackStatus, upNext bool) string {
+	text := tickerText(status.Track)
+	if !upNext {
+		return text
+	}
+	next := tickerText(status.NextTrack)
+	if next == "" || text == "" {
+		return text
+	}
+	return text + "   Up Next: " + next
+}
+
+// tickerText formats the track as "Artist – Title", falling back to whatever
+// descriptive field is available and then to the source, such as "TV".
+func tickerText(track sonos.TrackInfo) string {
+	title := strings.TrimSpace(track.Title)
+	artist := strings.TrimSpace(track.Artist)
+	switch {
+	case title != "" && artist != "":
+		return artist + " – " + title
+	case title != "":
+		return title
+	case artist != "":
+		return artist
+	}
+	if stream := strings.TrimSpace(track.StreamInfo); stream != "" {
+		return stream
+	}
+	return track.SourceLabel()
+}
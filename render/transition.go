@@ -0,0 +1,126 @@
+package render
+
+import (
+	"image"
+	"time"
+)
+
+const (
+	// TransitionNone switches artwork instantly. It is the default.
+	TransitionNone = "none"
+	// TransitionCrossfade blends the old artwork into the new one.
+	TransitionCrossfade = "crossfade"
+	// TransitionWipe reveals the new artwork from left to right over the
+	// old one.
+	TransitionWipe = "wipe"
+	// TransitionSlide pushes the old artwork out to the left as the new one
+	// slides in from the right.
+	TransitionSlide = "slide"
+
+	defaultTransitionDuration = 400 * time.Millisecond
+	defaultTransitionFPS      = 30
+)
+
+// TransitionOptions configures the animation played when the artwork
+// changes.
+type TransitionOptions struct {
+	// Style is TransitionNone (default), TransitionCrossfade,
+	// TransitionWipe, or TransitionSlide.
+	Style string
+	// Duration is how long the animation takes (default 400ms).
+	Duration time.Duration
+	// FPS is the animation's frame rate (default 30).
+	FPS int
+}
+
+func (o TransitionOptions) withDefaults() TransitionOptions {
+	switch o.Style {
+	case TransitionCrossfade, TransitionWipe, TransitionSlide:
+	default:
+		o.Style = TransitionNone
+	}
+	if o.Duration <= 0 {
+		o.Duration = defaultTransitionDuration
+	}
+	if o.FPS <= 0 {
+		o.FPS = defaultTransitionFPS
+	}
+	return o
+}
+
+// transitionState tracks a running transition from the frame that was on
+// screen when new artwork arrived.
+type transitionState struct {
+	from  *image.RGBA
+	start time.Time
+}
+
+// active reports whether a transition is running.
+func (t *transitionState) active() bool {
+	return t.from != nil
+}
+
+// begin starts a transition away from the frame on screen, unless there is
+// none of the same size to animate from.
+func (t *transitionState) begin(from *image.RGBA, size image.Point, now time.Time, opts TransitionOptions) {
+	t.from = nil
+	if opts.Style == TransitionNone || from == nil || from.Bounds().Size() != size {
+		return
+	}
+	// The frame on screen is a reused buffer, so keep a copy.
+	t.from = image.NewRGBA(from.Rect)
+	copy(t.from.Pix, from.Pix)
+	t.start = now
+}
+
+// apply returns the frame to show for the finished frame to at now, ending
+// the transition once its duration has passed.
+func (t *transitionState) apply(to *image.RGBA, now time.Time, opts TransitionOptions) *image.RGBA {
+	if t.from == nil {
+		return to
+	}
+	progress := float64(now.Sub(t.start)) / float64(opts.Duration)
+	if progress >= 1 || t.from.Bounds().Size() != to.Bounds().Size() {
+		t.from = nil
+		return to
+	}
+	if progress < 0 {
+		progress = 0
+	}
+	return blendTransition(t.from, to, progress, opts.Style)
+}
+
+// blendTransition draws the frame progress (0..1) of the way from from to
+// to. Both frames have the same size and a zero origin.
+func blendTransition(from, to *image.RGBA, progress float64, style string) *image.RGBA {
+	bounds := to.Bounds()
+	out := image.NewRGBA(bounds)
+	w, h := bounds.Dx(), bounds.Dy()
+	switch style {
+	case TransitionWipe:
+		edge := int(progress * float64(w))
+		for y := 0; y < h; y++ {
+			row := y * out.Stride
+			copy(out.Pix[row:row+edge*4], to.Pix[y*to.Stride:])
+			copy(out.Pix[row+edge*4:row+w*4], from.Pix[y*from.Stride+edge*4:])
+		}
+	case TransitionSlide:
+		shift := int(easeInOut(progress) * float64(w))
+		for y := 0; y < h; y++ {
+			row := y * out.Stride
+			copy(out.Pix[row:row+(w-shift)*4], from.Pix[y*from.Stride+shift*4:])
+			copy(out.Pix[row+(w-shift)*4:row+w*4], to.Pix[y*to.Stride:])
+		}
+	default:
+		weight := int(progress * 256)
+		for i := range out.Pix {
+			out.Pix[i] = uint8((int(from.Pix[i])*(256-weight) + int(to.Pix[i])*weight) >> 8)
+		}
+	}
+	return out
+}
+
+// easeInOut eases progress so slides start and stop gently.
+func easeInOut(progress float64) float64 {
+	return progress * progress * (3 - 2*progress)
+}
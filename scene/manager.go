@@ -0,0 +1,149 @@
+package scene
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/schedule"
+	"musicDisplay/sonos"
+)
+
+// Manager evaluates rules against the latest playback status and reports the
+// screen to show. The first rule whose condition has held for its duration
+// wins; with none, the screen is ShowArt.
+type Manager struct {
+	rules  []Rule
+	coords *schedule.Coordinates
+
+	mu     sync.Mutex
+	status sonos.PlaybackStatus
+	// since records when each rule's condition started holding, or is zero
+	// while it does not.
+	since []time.Time
+	wake  chan struct{}
+}
+
+// NewManager returns a manager for rules. Rules whose time windows use
+// sunrise or sunset require coords.
+func NewManager(rules []Rule, coords *schedule.Coordinates) (*Manager, error) {
+	if err := checkSolar(rules, coords); err != nil {
+		return nil, err
+	}
+	copied := make([]Rule, len(rules))
+	copy(copied, rules)
+	return &Manager{
+		rules:  copied,
+		coords: coords,
+		since:  make([]time.Time, len(rules)),
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// SetRules replaces the rules, as when a theme with its own scenes becomes
+// active, and wakes Run. The new rules' durations count from now.
+func (m *Manager) SetRules(rules []Rule) error {
+	if err := checkSolar(rules, m.coords); err != nil {
+		return err
+	}
+	copied := make([]Rule, len(rules))
+	copy(copied, rules)
+	m.mu.Lock()
+	m.rules = copied
+	m.since = make([]time.Time, len(rules))
+	m.mu.Unlock()
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func checkSolar(rules []Rule, coords *schedule.Coordinates) error {
+	for _, rule := range rules {
+		if rule.Solar() && coords == nil {
+			return errors.New("scene: sunrise and sunset need latitude and longitude")
+		}
+	}
+	return nil
+}
+
+// UpdateStatus records the latest playback status and wakes Run. It can be
+// used as sonos.ListenerOptions.OnStatus.
+func (m *Manager) UpdateStatus(status sonos.PlaybackStatus) {
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Screen returns the screen to show at now and the rule that chose it, or
+// ShowArt and nil when no rule applies. next is when the answer may next
+// change without a status update; ok is false when only a status update can
+// change it.
+func (m *Manager) Screen(now time.Time) (show string, rule *Rule, next time.Time, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	show = ShowArt
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.timed() {
+			next, ok = earliest(next, ok, now.Truncate(time.Minute).Add(time.Minute))
+		}
+		if !r.matches(m.status, now, m.coords) {
+			m.since[i] = time.Time{}
+			continue
+		}
+		if m.since[i].IsZero() {
+			m.since[i] = now
+		}
+		if due := m.since[i].Add(r.For); due.After(now) {
+			next, ok = earliest(next, ok, due)
+			continue
+		}
+		if rule == nil {
+			show, rule = r.Show, r
+		}
+	}
+	return show, rule, next, ok
+}
+
+func earliest(current time.Time, ok bool, candidate time.Time) (time.Time, bool) {
+	if !ok || candidate.Before(current) {
+		return candidate, true
+	}
+	return current, true
+}
+
+// Run calls apply with the screen to show whenever it changes, re-evaluating
+// after each status update and whenever a rule's duration or time window
+// runs out, until ctx is canceled. apply is not called until the first rule
+// applies. A nil clk uses the wall clock.
+func (m *Manager) Run(ctx context.Context, clk clock.Clock, apply func(show string, rule *Rule)) {
+	clk = clock.Or(clk)
+	applied := ShowArt
+	for {
+		now := clk.Now()
+		show, rule, next, ok := m.Screen(now)
+		if show != applied {
+			applied = show
+			apply(show, rule)
+		}
+
+		timer := clk.NewTimer(schedule.Wait(now, next, ok))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-m.wake:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
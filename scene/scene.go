@@ -0,0 +1,255 @@
+// Package scene interprets the scene rules declared in the config. A rule
+// pairs a condition on the playback status and time of day, such as
+// "state==paused for 5m", with the screen to show while it holds, so that
+// behaviour like "show the clock once music has been paused for a while"
+// needs no code changes.
+//
+// A condition is one or more clauses joined by "and", optionally followed by
+// "for <duration>":
+//
+//	state==paused for 5m
+//	source==tv
+//	state==playing and artist!="White Noise"
+//	time in 23:00..sunrise
+//
+// Fields are state (playing, paused, stopped, transitioning), source (music,
+// radio, line_in, tv, airplay), service, room, artist, title, and album,
+// compared with == or != ignoring case. Values containing spaces are double
+// quoted. "time in FROM..TO" matches a daily window whose ends use the same
+// syntax as the brightness schedule.
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"musicDisplay/schedule"
+	"musicDisplay/sonos"
+)
+
+const (
+	// ShowArt shows the now-playing screen as usual.
+	ShowArt = "art"
+	// ShowClock shows the idle clock.
+	ShowClock = "clock"
+	// ShowBlank turns the panel off.
+	ShowBlank = "blank"
+	// ShowAnimation loops the idle animation.
+	ShowAnimation = "animation"
+)
+
+// Rule shows Show once its condition has held for For.
+type Rule struct {
+	// When is the condition as written, kept for logging.
+	When string
+	Show string
+	For  time.Duration
+
+	clauses []clause
+}
+
+type clause struct {
+	field  string
+	negate bool
+	value  string
+	window *schedule.Window
+}
+
+// Equal reports whether r and other are the same rule.
+func (r Rule) Equal(other Rule) bool {
+	return r.When == other.When && r.Show == other.Show && r.For == other.For
+}
+
+// Parse parses a rule's condition and screen.
+func Parse(when, show string) (Rule, error) {
+	rule := Rule{When: strings.TrimSpace(when), Show: strings.ToLower(strings.TrimSpace(show))}
+	switch rule.Show {
+	case ShowArt, ShowClock, ShowBlank, ShowAnimation:
+	default:
+		return Rule{}, fmt.Errorf("scene: show must be art, clock, blank, or animation, got %q", show)
+	}
+
+	tokens, err := tokenize(rule.When)
+	if err != nil {
+		return Rule{}, err
+	}
+	if len(tokens) == 0 {
+		return Rule{}, errors.New("scene: empty condition")
+	}
+	if n := len(tokens); n >= 2 && strings.EqualFold(tokens[n-2], "for") {
+		d, err := time.ParseDuration(tokens[n-1])
+		if err != nil || d <= 0 {
+			return Rule{}, fmt.Errorf("scene: invalid duration %q in %q", tokens[n-1], rule.When)
+		}
+		rule.For = d
+		tokens = tokens[:n-2]
+	}
+
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return Rule{}, fmt.Errorf("scene: incomplete clause %q in %q", strings.Join(tokens, " "), rule.When)
+		}
+		c, err := parseClause(tokens[0], tokens[1], tokens[2])
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w in %q", err, rule.When)
+		}
+		rule.clauses = append(rule.clauses, c)
+		tokens = tokens[3:]
+		if len(tokens) == 0 {
+			break
+		}
+		if !strings.EqualFold(tokens[0], "and") || len(tokens) == 1 {
+			return Rule{}, fmt.Errorf("scene: expected \"and\" before %q in %q", strings.Join(tokens, " "), rule.When)
+		}
+		tokens = tokens[1:]
+	}
+	if len(rule.clauses) == 0 {
+		return Rule{}, fmt.Errorf("scene: condition %q has no clauses", rule.When)
+	}
+	return rule, nil
+}
+
+func parseClause(field, op, value string) (clause, error) {
+	c := clause{field: strings.ToLower(field), value: value}
+	if c.field == "time" {
+		if !strings.EqualFold(op, "in") {
+			return clause{}, fmt.Errorf("scene: time needs \"in FROM..TO\", got %q", op)
+		}
+		from, to, ok := strings.Cut(value, "..")
+		if !ok {
+			return clause{}, fmt.Errorf("scene: time window %q must be FROM..TO", value)
+		}
+		window, err := schedule.ParseWindow(from, to)
+		if err != nil {
+			return clause{}, fmt.Errorf("scene: time window %q: %w", value, err)
+		}
+		c.window = &window
+		return c, nil
+	}
+
+	switch op {
+	case "==":
+	case "!=":
+		c.negate = true
+	default:
+		return clause{}, fmt.Errorf("scene: unknown operator %q (want == or !=)", op)
+	}
+	switch c.field {
+	case "state":
+		c.value = strings.ToLower(value)
+		switch c.value {
+		case "playing", "paused", "stopped", "transitioning":
+		default:
+			return clause{}, fmt.Errorf("scene: state must be playing, paused, stopped, or transitioning, got %q", value)
+		}
+	case "source":
+		kind, err := sonos.ParseSourceKind(value)
+		if err != nil {
+			return clause{}, fmt.Errorf("scene: %w", err)
+		}
+		c.value = string(kind)
+	case "service", "room", "artist", "title", "album":
+	default:
+		return clause{}, fmt.Errorf("scene: unknown field %q", field)
+	}
+	return c, nil
+}
+
+// tokenize splits a condition into words, double-quoted strings, and the
+// == and != operators, which need no surrounding spaces.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == ' ' || s[i] == '\t':
+			i++
+		case s[i] == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("scene: unterminated quote in %q", s)
+			}
+			tokens = append(tokens, s[i+1:i+1+end])
+			i += end + 2
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!="):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		default:
+			start := i
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' && s[i] != '"' && !strings.HasPrefix(s[i:], "==") && !strings.HasPrefix(s[i:], "!=") {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+// Solar reports whether the rule's time windows use sunrise or sunset and so
+// need coordinates.
+func (r Rule) Solar() bool {
+	for _, c := range r.clauses {
+		if c.window != nil && (c.window.From.Solar() || c.window.To.Solar()) {
+			return true
+		}
+	}
+	return false
+}
+
+// timed reports whether the rule depends on the time of day.
+func (r Rule) timed() bool {
+	for _, c := range r.clauses {
+		if c.window != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether every clause holds for status at now.
+func (r Rule) matches(status sonos.PlaybackStatus, now time.Time, coords *schedule.Coordinates) bool {
+	for _, c := range r.clauses {
+		if !c.matches(status, now, coords) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c clause) matches(status sonos.PlaybackStatus, now time.Time, coords *schedule.Coordinates) bool {
+	if c.window != nil {
+		return c.window.Contains(now, coords)
+	}
+	var actual string
+	switch c.field {
+	case "state":
+		actual = stateName(status.State)
+	case "source":
+		actual = string(status.Track.Source)
+	case "service":
+		actual = status.Track.Service
+	case "room":
+		actual = status.Room
+	case "artist":
+		actual = status.Track.Artist
+	case "title":
+		actual = status.Track.Title
+	case "album":
+		actual = status.Track.Album
+	}
+	return strings.EqualFold(strings.TrimSpace(actual), c.value) != c.negate
+}
+
+// stateName maps a formatted transport state onto the names rules use.
+func stateName(state string) string {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case "playing":
+		return "playing"
+	case "paused":
+		return "paused"
+	case "transitioning":
+		return "transitioning"
+	}
+	return "stopped"
+}
@@ -0,0 +1,199 @@
+package scene
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/sonos"
+)
+
+func mustParse(t *testing.T, when, show string) Rule {
+	t.Helper()
+	rule, err := Parse(when, show)
+	if err != nil {
+		t.Fatalf("Parse(%q, %q) error: %v", when, show, err)
+	}
+	return rule
+}
+
+func TestParse(t *testing.T) {
+	rule := mustParse(t, `state==paused and artist != "White Noise" for 5m`, "Clock")
+	if rule.Show != ShowClock || rule.For != 5*time.Minute {
+		t.Fatalf("rule = %+v, want clock for 5m", rule)
+	}
+	if len(rule.clauses) != 2 || rule.clauses[1].value != "White Noise" || !rule.clauses[1].negate {
+		t.Fatalf("clauses = %+v", rule.clauses)
+	}
+
+	if rule := mustParse(t, "time in sunset..07:00", "blank"); !rule.Solar() || !rule.timed() {
+		t.Fatalf("time window rule not solar and timed: %+v", rule)
+	}
+
+	for _, tc := range []struct{ when, show string }{
+		{"state==paused", "banner"},
+		{"", "clock"},
+		{"state==sleeping", "clock"},
+		{"source==vinyl", "clock"},
+		{"mood==calm", "clock"},
+		{"state>paused", "clock"},
+		{"state==paused for soon", "clock"},
+		{"state==paused or source==tv", "clock"},
+		{"state==paused and", "clock"},
+		{`artist=="unterminated`, "clock"},
+		{"time in 22:00", "clock"},
+		{"time == 22:00..07:00", "clock"},
+	} {
+		if _, err := Parse(tc.when, tc.show); err == nil {
+			t.Fatalf("Parse(%q, %q) expected error", tc.when, tc.show)
+		}
+	}
+}
+
+func TestManagerWaitsForDuration(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager, err := NewManager([]Rule{
+		mustParse(t, "source==tv", "blank"),
+		mustParse(t, "state==paused for 5m", "clock"),
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewManager error: %v", err)
+	}
+
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Paused"})
+	show, rule, next, ok := manager.Screen(start)
+	if show != ShowArt || rule != nil {
+		t.Fatalf("screen right after pausing = %q, want art", show)
+	}
+	if !ok || !next.Equal(start.Add(5*time.Minute)) {
+		t.Fatalf("next = %v (ok=%v), want %v", next, ok, start.Add(5*time.Minute))
+	}
+	if show, rule, _, _ := manager.Screen(start.Add(5 * time.Minute)); show != ShowClock || rule.When != "state==paused for 5m" {
+		t.Fatalf("screen after 5m paused = %q, want clock", show)
+	}
+
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Paused", Track: sonos.TrackInfo{Source: sonos.SourceTV}})
+	if show, _, _, _ := manager.Screen(start.Add(6 * time.Minute)); show != ShowBlank {
+		t.Fatalf("screen for paused TV = %q, want the first matching rule's blank", show)
+	}
+
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Playing"})
+	manager.Screen(start.Add(7 * time.Minute))
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Paused"})
+	if show, _, _, _ := manager.Screen(start.Add(8 * time.Minute)); show != ShowArt {
+		t.Fatalf("screen after resuming and pausing again = %q, want art until 5m pass", show)
+	}
+}
+
+func TestManagerTimeWindow(t *testing.T) {
+	manager, err := NewManager([]Rule{mustParse(t, "state==playing and time in 23:00..06:00", "clock")}, nil)
+	if err != nil {
+		t.Fatalf("NewManager error: %v", err)
+	}
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Playing"})
+
+	evening := time.Date(2024, 3, 1, 22, 59, 30, 0, time.UTC)
+	show, _, next, ok := manager.Screen(evening)
+	if show != ShowArt || !ok || !next.Equal(time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Screen(22:59:30) = %q next %v (ok=%v), want art until 23:00", show, next, ok)
+	}
+	if show, _, _, _ := manager.Screen(evening.Add(time.Minute)); show != ShowClock {
+		t.Fatalf("Screen(23:00:30) = %q, want clock", show)
+	}
+
+	if _, err := NewManager([]Rule{mustParse(t, "time in sunset..sunrise", "blank")}, nil); err == nil {
+		t.Fatalf("NewManager with a solar window and no coordinates expected error")
+	}
+}
+
+func TestManagerRunAppliesChanges(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	manager, err := NewManager([]Rule{mustParse(t, "state==paused for 1m", "clock")}, nil)
+	if err != nil {
+		t.Fatalf("NewManager error: %v", err)
+	}
+
+	screens := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.Run(ctx, fake, func(show string, _ *Rule) { screens <- show })
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-screens:
+			if got != want {
+				t.Fatalf("screen = %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no screen change, want %q", want)
+		}
+	}
+	waitArmed := func() {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for fake.Timers() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Run did not arm a timer")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitArmed()
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Paused"})
+	// Run re-arms its timer for the rule's minute once it sees the update.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		manager.mu.Lock()
+		seen := !manager.since[0].IsZero()
+		manager.mu.Unlock()
+		if seen {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Run did not evaluate the status update")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waitArmed()
+	fake.Advance(time.Minute + time.Second)
+	expect(ShowClock)
+
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Playing"})
+	expect(ShowArt)
+}
+
+func TestManagerSetRules(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager, err := NewManager([]Rule{mustParse(t, "state==paused", "clock")}, nil)
+	if err != nil {
+		t.Fatalf("NewManager error: %v", err)
+	}
+	manager.UpdateStatus(sonos.PlaybackStatus{State: "Paused"})
+	if show, _, _, _ := manager.Screen(now); show != ShowClock {
+		t.Fatalf("Screen = %q, want clock", show)
+	}
+
+	if err := manager.SetRules([]Rule{mustParse(t, "state==paused for 1m", "blank")}); err != nil {
+		t.Fatalf("SetRules error: %v", err)
+	}
+	if show, _, _, _ := manager.Screen(now); show != ShowArt {
+		t.Fatalf("Screen right after SetRules = %q, want art", show)
+	}
+	if show, _, _, _ := manager.Screen(now.Add(time.Minute)); show != ShowBlank {
+		t.Fatalf("Screen a minute after SetRules = %q, want blank", show)
+	}
+
+	if err := manager.SetRules([]Rule{mustParse(t, "time in sunset..sunrise", "blank")}); err == nil {
+		t.Fatalf("SetRules with a solar window and no coordinates expected error")
+	}
+}
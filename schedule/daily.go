@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Entry pairs a daily start time with the value that applies from then on.
+type Entry[T any] struct {
+	Start TimeOfDay
+	Value T
+}
+
+// Daily is a repeating daily schedule. Each entry's value applies from its
+// start until the next entry starts, wrapping around midnight, so
+// {"22:00": 10, "07:00": 60} keeps 10 in effect overnight.
+type Daily[T any] struct {
+	entries []Entry[T]
+	coords  *Coordinates
+}
+
+// NewDaily validates the entries and returns a schedule. Entries using sunrise
+// or sunset require coords.
+func NewDaily[T any](entries []Entry[T], coords *Coordinates) (Daily[T], error) {
+	if len(entries) == 0 {
+		return Daily[T]{}, errors.New("schedule: at least one entry is required")
+	}
+	for _, e := range entries {
+		if e.Start.Solar() && coords == nil {
+			return Daily[T]{}, fmt.Errorf("schedule: %s needs latitude and longitude", e.Start)
+		}
+	}
+	copied := make([]Entry[T], len(entries))
+	copy(copied, entries)
+	return Daily[T]{entries: copied, coords: coords}, nil
+}
+
+// At returns the value in effect at now. When no entry can be resolved (for
+// example a sunrise-only schedule during polar night) the first entry's value
+// is returned with ok set to false.
+func (d Daily[T]) At(now time.Time) (value T, ok bool) {
+	if len(d.entries) == 0 {
+		return value, false
+	}
+
+	best := -1
+	var bestStart time.Time
+	for i, e := range d.entries {
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now, now.AddDate(0, 0, 1)} {
+			start, resolved := e.Start.On(day, d.coords)
+			if !resolved || start.After(now) {
+				continue
+			}
+			if best < 0 || start.After(bestStart) {
+				best = i
+				bestStart = start
+			}
+		}
+	}
+	if best < 0 {
+		return d.entries[0].Value, false
+	}
+	return d.entries[best].Value, true
+}
+
+// NextChange returns the next time after now at which an entry starts. ok is
+// false when nothing can be resolved within the next two days.
+func (d Daily[T]) NextChange(now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, e := range d.entries {
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now, now.AddDate(0, 0, 1), now.AddDate(0, 0, 2)} {
+			start, ok := e.Start.On(day, d.coords)
+			if !ok || !start.After(now) {
+				continue
+			}
+			if !found || start.Before(next) {
+				next = start
+				found = true
+			}
+		}
+	}
+	return next, found
+}
+
+// Window is a daily time range such as quiet hours. A window whose end is
+// before its start spans midnight.
+type Window struct {
+	From TimeOfDay
+	To   TimeOfDay
+}
+
+// ParseWindow parses the from and to expressions of a window.
+func ParseWindow(from, to string) (Window, error) {
+	f, err := ParseTimeOfDay(from)
+	if err != nil {
+		return Window{}, err
+	}
+	t, err := ParseTimeOfDay(to)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{From: f, To: t}, nil
+}
+
+// Contains reports whether now falls inside the window.
+func (w Window) Contains(now time.Time, coords *Coordinates) bool {
+	inside, err := NewDaily([]Entry[bool]{{Start: w.From, Value: true}, {Start: w.To, Value: false}}, coords)
+	if err != nil {
+		return false
+	}
+	value, resolved := inside.At(now)
+	return resolved && value
+}
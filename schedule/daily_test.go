@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyWithSolarOffsets(t *testing.T) {
+	london := &Coordinates{Latitude: 51.5074, Longitude: -0.1278}
+	dim := mustParse(t, "sunset-30m")
+	bright := mustParse(t, "07:00")
+	daily, err := NewDaily([]Entry[int]{{Start: dim, Value: 10}, {Start: bright, Value: 60}}, london)
+	if err != nil {
+		t.Fatalf("NewDaily error: %v", err)
+	}
+
+	// Sunset in London on 2024-06-21 is around 20:21 UTC, so dimming starts
+	// around 19:51.
+	at := func(clock string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", "2024-06-21 "+clock)
+		if err != nil {
+			t.Fatalf("parse %q: %v", clock, err)
+		}
+		return ts
+	}
+
+	cases := map[string]int{
+		"06:59": 10,
+		"07:00": 60,
+		"19:40": 60,
+		"20:00": 10,
+		"23:59": 10,
+	}
+	for clock, want := range cases {
+		got, ok := daily.At(at(clock))
+		if !ok || got != want {
+			t.Fatalf("At(%s) = %d (ok=%t), want %d", clock, got, ok, want)
+		}
+	}
+
+	next, ok := daily.NextChange(at("12:00"))
+	if !ok {
+		t.Fatal("expected next change")
+	}
+	if next.Before(at("19:45")) || next.After(at("19:57")) {
+		t.Fatalf("NextChange = %s, want around 19:51", next)
+	}
+}
+
+func TestDailyRequiresCoordinatesForSolarEntries(t *testing.T) {
+	if _, err := NewDaily([]Entry[int]{{Start: mustParse(t, "sunrise"), Value: 1}}, nil); err == nil {
+		t.Fatal("expected error without coordinates")
+	}
+}
+
+func TestWindowSpansMidnight(t *testing.T) {
+	quiet, err := ParseWindow("22:30", "06:45")
+	if err != nil {
+		t.Fatalf("ParseWindow error: %v", err)
+	}
+	for clock, want := range map[string]bool{"22:29": false, "22:30": true, "03:00": true, "06:45": false, "12:00": false} {
+		ts, _ := time.Parse("2006-01-02 15:04", "2024-01-10 "+clock)
+		if got := quiet.Contains(ts, nil); got != want {
+			t.Fatalf("Contains(%s) = %t, want %t", clock, got, want)
+		}
+	}
+}
+
+func mustParse(t *testing.T, expr string) TimeOfDay {
+	t.Helper()
+	tod, err := ParseTimeOfDay(expr)
+	if err != nil {
+		t.Fatalf("ParseTimeOfDay(%q): %v", expr, err)
+	}
+	return tod
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateRule matches calendar days. It is built from a comma-separated list of
+// yearly dates ("12-25"), one-off dates ("2025-06-14"), and inclusive ranges of
+// either form ("12-24..12-26", "2025-07-01..2025-07-14"). Yearly ranges may wrap
+// around the new year ("12-31..01-01").
+type DateRule struct {
+	spans []dateSpan
+}
+
+type dateSpan struct {
+	from, to int
+	yearly   bool
+}
+
+// ParseDateRule parses a date rule expression.
+func ParseDateRule(value string) (DateRule, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DateRule{}, errors.New("schedule: empty date rule")
+	}
+	var rule DateRule
+	for _, part := range strings.Split(value, ",") {
+		span, err := parseDateSpan(strings.TrimSpace(part))
+		if err != nil {
+			return DateRule{}, err
+		}
+		rule.spans = append(rule.spans, span)
+	}
+	return rule, nil
+}
+
+func parseDateSpan(value string) (dateSpan, error) {
+	fromText, toText, isRange := strings.Cut(value, "..")
+	from, yearly, err := parseDateKey(strings.TrimSpace(fromText))
+	if err != nil {
+		return dateSpan{}, err
+	}
+	if !isRange {
+		return dateSpan{from: from, to: from, yearly: yearly}, nil
+	}
+	to, toYearly, err := parseDateKey(strings.TrimSpace(toText))
+	if err != nil {
+		return dateSpan{}, err
+	}
+	if yearly != toYearly {
+		return dateSpan{}, fmt.Errorf("schedule: date range %q mixes yearly and dated ends", value)
+	}
+	if !yearly && to < from {
+		return dateSpan{}, fmt.Errorf("schedule: date range %q ends before it starts", value)
+	}
+	return dateSpan{from: from, to: to, yearly: yearly}, nil
+}
+
+// parseDateKey parses "MM-DD" or "YYYY-MM-DD" into a sortable key.
+func parseDateKey(value string) (int, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return dateKey(t, false), false, nil
+	}
+	// 2024 is a leap year, so "02-29" is accepted as a yearly date.
+	if t, err := time.Parse("2006-01-02", "2024-"+value); err == nil && len(value) == 5 {
+		return dateKey(t, true), true, nil
+	}
+	return 0, false, fmt.Errorf("schedule: invalid date %q (want MM-DD or YYYY-MM-DD)", value)
+}
+
+func dateKey(t time.Time, yearly bool) int {
+	year, month, day := t.Date()
+	key := int(month)*100 + day
+	if !yearly {
+		key += year * 10000
+	}
+	return key
+}
+
+// Matches reports whether the calendar day of t, in t's location, is covered
+// by the rule.
+func (r DateRule) Matches(t time.Time) bool {
+	for _, span := range r.spans {
+		key := dateKey(t, span.yearly)
+		if span.from <= span.to {
+			if key >= span.from && key <= span.to {
+				return true
+			}
+		} else if key >= span.from || key <= span.to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextMidnight returns the start of the calendar day after t, which is when
+// date rules may next change their result.
+func NextMidnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,49 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateRuleMatches(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 15, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		expr string
+		date time.Time
+		want bool
+	}{
+		{"12-25", day(2024, time.December, 25), true},
+		{"12-25", day(2031, time.December, 25), true},
+		{"12-25", day(2024, time.December, 26), false},
+		{"2025-06-14", day(2025, time.June, 14), true},
+		{"2025-06-14", day(2026, time.June, 14), false},
+		{"12-24..12-26", day(2024, time.December, 24), true},
+		{"12-24..12-26", day(2024, time.December, 27), false},
+		{"12-31..01-01", day(2025, time.January, 1), true},
+		{"12-31..01-01", day(2024, time.December, 31), true},
+		{"12-31..01-01", day(2025, time.January, 2), false},
+		{"2025-12-30..2026-01-02", day(2026, time.January, 1), true},
+		{"2025-12-30..2026-01-02", day(2026, time.December, 31), false},
+		{"02-29", day(2024, time.February, 29), true},
+		{"03-14, 10-31", day(2024, time.October, 31), true},
+	}
+	for _, tc := range cases {
+		rule, err := ParseDateRule(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseDateRule(%q) error: %v", tc.expr, err)
+		}
+		if got := rule.Matches(tc.date); got != tc.want {
+			t.Fatalf("%q matches %s = %v, want %v", tc.expr, tc.date.Format("2006-01-02"), got, tc.want)
+		}
+	}
+}
+
+func TestParseDateRuleRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{"", "13-01", "02-30", "12-25..", "2025-01-02..2025-01-01", "12-24..2025-12-26", "christmas"} {
+		if _, err := ParseDateRule(expr); err == nil {
+			t.Fatalf("ParseDateRule(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+// maxWait caps how long Run sleeps so clock adjustments and solar drift are
+// picked up.
+const maxWait = time.Hour
+
+// Run calls step with the current time, then again just after each change
+// step reports, until ctx is canceled. step returns the time of its next
+// change, or ok=false when there is none. A nil clk uses the wall clock.
+func Run(ctx context.Context, clk clock.Clock, step func(now time.Time) (next time.Time, ok bool)) {
+	clk = clock.Or(clk)
+	for {
+		now := clk.Now()
+		next, ok := step(now)
+		timer := clk.NewTimer(Wait(now, next, ok))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+// Wait returns how long to sleep at now before re-checking a schedule whose
+// next change is at next. It waits until a second past the change, between
+// one second and an hour.
+func Wait(now, next time.Time, ok bool) time.Duration {
+	wait := maxWait
+	if ok {
+		wait = next.Sub(now) + time.Second
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	return wait
+}
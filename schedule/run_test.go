@@ -0,0 +1,83 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+func TestRunStepsAtEachChange(t *testing.T) {
+	start := time.Date(2024, 3, 1, 21, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	daily, err := NewDaily([]Entry[int]{
+		{Start: mustParse(t, "22:00"), Value: 10},
+		{Start: mustParse(t, "07:00"), Value: 60},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewDaily error: %v", err)
+	}
+
+	levels := make(chan int, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(ctx, fake, func(now time.Time) (time.Time, bool) {
+			if level, ok := daily.At(now); ok {
+				levels <- level
+			}
+			return daily.NextChange(now)
+		})
+	}()
+
+	expect := func(want int) {
+		t.Helper()
+		select {
+		case got := <-levels:
+			if got != want {
+				t.Fatalf("level = %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no step, want level %d", want)
+		}
+	}
+	waitArmed := func() {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for fake.Timers() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Run did not arm a timer")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	expect(60)
+	waitArmed()
+	fake.Advance(time.Hour + time.Second)
+	expect(10)
+
+	cancel()
+	<-done
+}
+
+func TestWait(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		next time.Time
+		ok   bool
+		want time.Duration
+	}{
+		{now.Add(10 * time.Minute), true, 10*time.Minute + time.Second},
+		{now.Add(-time.Minute), true, time.Second},
+		{now.Add(5 * time.Hour), true, time.Hour},
+		{time.Time{}, false, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := Wait(now, tc.next, tc.ok); got != tc.want {
+			t.Errorf("Wait(%v, %v) = %v, want %v", tc.next, tc.ok, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"math"
+	"time"
+)
+
+// Coordinates identifies a location on Earth in decimal degrees.
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// official zenith for sunrise/sunset, accounting for refraction and the solar disc.
+const sunZenith = 90.833
+
+// Sunrise returns the time the sun rises on the calendar day of date (in
+// date's location). ok is false when the sun does not rise that day, as
+// happens near the poles.
+func Sunrise(date time.Time, coords Coordinates) (time.Time, bool) {
+	return sunEvent(date, coords, true)
+}
+
+// Sunset returns the time the sun sets on the calendar day of date (in date's
+// location). ok is false when the sun does not set that day.
+func Sunset(date time.Time, coords Coordinates) (time.Time, bool) {
+	return sunEvent(date, coords, false)
+}
+
+// sunEvent implements the sunrise/sunset algorithm from the Almanac for
+// Computers (1990), which is accurate to roughly a minute for most latitudes.
+func sunEvent(date time.Time, coords Coordinates, rising bool) (time.Time, bool) {
+	loc := date.Location()
+	year, month, day := date.Date()
+	dayOfYear := float64(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).YearDay())
+
+	lngHour := coords.Longitude / 15
+	var t float64
+	if rising {
+		t = dayOfYear + (6-lngHour)/24
+	} else {
+		t = dayOfYear + (18-lngHour)/24
+	}
+
+	meanAnomaly := 0.9856*t - 3.289
+
+	trueLongitude := meanAnomaly + 1.916*sinDeg(meanAnomaly) + 0.020*sinDeg(2*meanAnomaly) + 282.634
+	trueLongitude = normalize(trueLongitude, 360)
+
+	rightAscension := normalize(radToDeg(math.Atan(0.91764*tanDeg(trueLongitude))), 360)
+	lQuadrant := math.Floor(trueLongitude/90) * 90
+	raQuadrant := math.Floor(rightAscension/90) * 90
+	rightAscension = (rightAscension + lQuadrant - raQuadrant) / 15
+
+	sinDec := 0.39782 * sinDeg(trueLongitude)
+	cosDec := math.Cos(math.Asin(sinDec))
+
+	cosH := (cosDeg(sunZenith) - sinDec*sinDeg(coords.Latitude)) / (cosDec * cosDeg(coords.Latitude))
+	if cosH > 1 || cosH < -1 {
+		return time.Time{}, false
+	}
+
+	var hourAngle float64
+	if rising {
+		hourAngle = 360 - radToDeg(math.Acos(cosH))
+	} else {
+		hourAngle = radToDeg(math.Acos(cosH))
+	}
+	hourAngle /= 15
+
+	localMean := hourAngle + rightAscension - 0.06571*t - 6.622
+	universal := normalize(localMean-lngHour, 24)
+
+	midnightUTC := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	event := midnightUTC.Add(time.Duration(universal * float64(time.Hour))).In(loc)
+
+	// The algorithm works on UTC days; pull the result back onto the requested
+	// local calendar day when the UTC offset pushed it across midnight.
+	localDay := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if event.Before(localDay) {
+		event = event.Add(24 * time.Hour)
+	} else if !event.Before(localDay.AddDate(0, 0, 1)) {
+		event = event.Add(-24 * time.Hour)
+	}
+	return event, true
+}
+
+func normalize(value, limit float64) float64 {
+	value = math.Mod(value, limit)
+	if value < 0 {
+		value += limit
+	}
+	return value
+}
+
+func sinDeg(deg float64) float64 { return math.Sin(degToRad(deg)) }
+func cosDeg(deg float64) float64 { return math.Cos(degToRad(deg)) }
+func tanDeg(deg float64) float64 { return math.Tan(degToRad(deg)) }
+
+func degToRad(deg float64) float64 { return deg * math.Pi / 180 }
+func radToDeg(rad float64) float64 { return rad * 180 / math.Pi }
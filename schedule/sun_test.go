@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunriseSunsetLondonMidsummer(t *testing.T) {
+	london := Coordinates{Latitude: 51.5074, Longitude: -0.1278}
+	date := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+
+	sunrise, ok := Sunrise(date, london)
+	if !ok {
+		t.Fatal("expected sunrise")
+	}
+	assertNear(t, "sunrise", sunrise, time.Date(2024, time.June, 21, 3, 43, 0, 0, time.UTC))
+
+	sunset, ok := Sunset(date, london)
+	if !ok {
+		t.Fatal("expected sunset")
+	}
+	assertNear(t, "sunset", sunset, time.Date(2024, time.June, 21, 20, 21, 0, 0, time.UTC))
+}
+
+func TestSunsetStaysOnLocalDay(t *testing.T) {
+	// Sydney is far enough ahead of UTC that the UTC-based algorithm lands on
+	// the previous calendar day unless it is corrected.
+	sydney := Coordinates{Latitude: -33.8688, Longitude: 151.2093}
+	loc := time.FixedZone("AEST", 10*60*60)
+	date := time.Date(2024, time.June, 21, 0, 0, 0, 0, loc)
+
+	sunset, ok := Sunset(date, sydney)
+	if !ok {
+		t.Fatal("expected sunset")
+	}
+	if y, m, d := sunset.Date(); y != 2024 || m != time.June || d != 21 {
+		t.Fatalf("sunset on %v, want 2024-06-21", sunset)
+	}
+	assertNear(t, "sunset", sunset, time.Date(2024, time.June, 21, 16, 54, 0, 0, loc))
+}
+
+func TestPolarNightHasNoSunrise(t *testing.T) {
+	tromso := Coordinates{Latitude: 69.6492, Longitude: 18.9553}
+	if _, ok := Sunrise(time.Date(2024, time.December, 21, 12, 0, 0, 0, time.UTC), tromso); ok {
+		t.Fatal("expected no sunrise during polar night")
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	cases := map[string]string{
+		"07:30":        "07:30",
+		" 0:05 ":       "00:05",
+		"SUNSET":       "sunset",
+		"sunrise":      "sunrise",
+		"sunset-30m":   "sunset-30m0s",
+		"sunrise + 1h": "sunrise+1h0m0s",
+		"22:00+15m":    "22:00+15m0s",
+	}
+	for input, want := range cases {
+		got, err := ParseTimeOfDay(input)
+		if err != nil {
+			t.Fatalf("ParseTimeOfDay(%q) error: %v", input, err)
+		}
+		if got.String() != want {
+			t.Fatalf("ParseTimeOfDay(%q) = %s, want %s", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "7", "24:00", "12:60", "noon", "12:5", "sunset-30", "-30m", "sunrise+25h"} {
+		if _, err := ParseTimeOfDay(input); err == nil {
+			t.Fatalf("ParseTimeOfDay(%q) expected error", input)
+		}
+	}
+}
+
+func assertNear(t *testing.T, label string, got, want time.Time) {
+	t.Helper()
+	diff := got.Sub(want)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > 5*time.Minute {
+		t.Fatalf("%s = %s, want within 5m of %s", label, got.Format(time.RFC3339), want.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type anchor int
+
+const (
+	anchorClock anchor = iota
+	anchorSunrise
+	anchorSunset
+)
+
+// TimeOfDay is a daily point in time, either a fixed wall-clock time such as
+// "07:30" or a solar event such as "sunset", optionally shifted by an offset
+// ("sunset-30m", "sunrise+1h").
+type TimeOfDay struct {
+	anchor anchor
+	clock  time.Duration
+	offset time.Duration
+}
+
+// ParseTimeOfDay parses "HH:MM" (24-hour clock), "sunrise", or "sunset",
+// each optionally followed by a signed Go duration such as "-30m" or "+1h15m".
+func ParseTimeOfDay(value string) (TimeOfDay, error) {
+	value = strings.ToLower(strings.Join(strings.Fields(value), ""))
+	if value == "" {
+		return TimeOfDay{}, errors.New("schedule: empty time of day")
+	}
+
+	base := value
+	var offset time.Duration
+	if idx := strings.IndexAny(value, "+-"); idx >= 0 {
+		base = value[:idx]
+		d, err := time.ParseDuration(value[idx:])
+		if err != nil {
+			return TimeOfDay{}, fmt.Errorf("schedule: invalid offset in %q: %w", value, err)
+		}
+		if d <= -24*time.Hour || d >= 24*time.Hour {
+			return TimeOfDay{}, fmt.Errorf("schedule: offset in %q must be less than 24h", value)
+		}
+		offset = d
+	}
+
+	t, err := parseAnchor(base)
+	if err != nil {
+		return TimeOfDay{}, err
+	}
+	t.offset = offset
+	return t, nil
+}
+
+func parseAnchor(value string) (TimeOfDay, error) {
+	switch value {
+	case "":
+		return TimeOfDay{}, errors.New("schedule: time of day missing before offset")
+	case "sunrise":
+		return TimeOfDay{anchor: anchorSunrise}, nil
+	case "sunset":
+		return TimeOfDay{anchor: anchorSunset}, nil
+	}
+
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok {
+		return TimeOfDay{}, fmt.Errorf("schedule: invalid time of day %q (want HH:MM, sunrise, or sunset)", value)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return TimeOfDay{}, fmt.Errorf("schedule: invalid hour in %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || len(minutes) != 2 {
+		return TimeOfDay{}, fmt.Errorf("schedule: invalid minute in %q", value)
+	}
+	return TimeOfDay{anchor: anchorClock, clock: time.Duration(h)*time.Hour + time.Duration(m)*time.Minute}, nil
+}
+
+// Solar reports whether the time depends on sunrise or sunset, and therefore
+// needs coordinates to be resolved.
+func (t TimeOfDay) Solar() bool {
+	return t.anchor != anchorClock
+}
+
+// String formats the time of day in the same form accepted by ParseTimeOfDay.
+func (t TimeOfDay) String() string {
+	var base string
+	switch t.anchor {
+	case anchorSunrise:
+		base = "sunrise"
+	case anchorSunset:
+		base = "sunset"
+	default:
+		base = fmt.Sprintf("%02d:%02d", int(t.clock/time.Hour), int(t.clock%time.Hour/time.Minute))
+	}
+	switch {
+	case t.offset > 0:
+		return base + "+" + t.offset.String()
+	case t.offset < 0:
+		return base + t.offset.String()
+	}
+	return base
+}
+
+// On resolves the time of day on the calendar day of date, in date's
+// location. The offset is applied after resolving the anchor, so the result
+// may fall on a neighbouring day. Solar times require coords; ok is false when
+// they are missing or the sun does not rise or set that day.
+func (t TimeOfDay) On(date time.Time, coords *Coordinates) (time.Time, bool) {
+	year, month, day := date.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+
+	var resolved time.Time
+	switch t.anchor {
+	case anchorSunrise, anchorSunset:
+		if coords == nil {
+			return time.Time{}, false
+		}
+		var ok bool
+		if t.anchor == anchorSunrise {
+			resolved, ok = Sunrise(midnight, *coords)
+		} else {
+			resolved, ok = Sunset(midnight, *coords)
+		}
+		if !ok {
+			return time.Time{}, false
+		}
+	default:
+		h := int(t.clock / time.Hour)
+		m := int(t.clock % time.Hour / time.Minute)
+		resolved = time.Date(year, month, day, h, m, 0, 0, date.Location())
+	}
+	return resolved.Add(t.offset), true
+}
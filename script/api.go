@@ -0,0 +1,163 @@
+package script
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"musicDisplay/overlay"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+const (
+	frameType         = "frame"
+	defaultTextHeight = 10
+)
+
+// registerFrame installs the metatable for the frame passed to draw:
+//
+//	frame:width(), frame:height()
+//	frame:fill(color)
+//	frame:pixel(x, y, color)
+//	frame:rect(x, y, w, h, color)
+//	frame:text(text, x, y, color [, height]) -> width
+//
+// Colors are "#rrggbb" strings. Coordinates start at 0 in the top-left
+// corner, and drawing outside the frame is clipped.
+func registerFrame(state *lua.LState) {
+	mt := state.NewTypeMetatable(frameType)
+	state.SetField(mt, "__index", state.SetFuncs(state.NewTable(), map[string]lua.LGFunction{
+		"width":  frameWidth,
+		"height": frameHeight,
+		"fill":   frameFill,
+		"pixel":  framePixel,
+		"rect":   frameRect,
+		"text":   frameText,
+	}))
+}
+
+func newFrame(state *lua.LState, frame *image.RGBA) *lua.LUserData {
+	ud := state.NewUserData()
+	ud.Value = frame
+	state.SetMetatable(ud, state.GetTypeMetatable(frameType))
+	return ud
+}
+
+// checkFrame returns the frame a method was called on. Frames are only
+// valid during the draw call they were passed to.
+func checkFrame(state *lua.LState) *image.RGBA {
+	ud := state.CheckUserData(1)
+	frame, ok := ud.Value.(*image.RGBA)
+	if !ok {
+		state.ArgError(1, "frame is only valid inside draw")
+	}
+	return frame
+}
+
+func checkColor(state *lua.LState, n int) color.RGBA {
+	c, err := theme.ParseColor(state.CheckString(n))
+	if err != nil {
+		state.ArgError(n, err.Error())
+	}
+	return c
+}
+
+func frameWidth(state *lua.LState) int {
+	state.Push(lua.LNumber(checkFrame(state).Bounds().Dx()))
+	return 1
+}
+
+func frameHeight(state *lua.LState) int {
+	state.Push(lua.LNumber(checkFrame(state).Bounds().Dy()))
+	return 1
+}
+
+func frameFill(state *lua.LState) int {
+	frame := checkFrame(state)
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(checkColor(state, 2)), image.Point{}, draw.Src)
+	return 0
+}
+
+func framePixel(state *lua.LState) int {
+	frame := checkFrame(state)
+	frame.SetRGBA(state.CheckInt(2), state.CheckInt(3), checkColor(state, 4))
+	return 0
+}
+
+func frameRect(state *lua.LState) int {
+	frame := checkFrame(state)
+	x, y := state.CheckInt(2), state.CheckInt(3)
+	rect := image.Rect(x, y, x+state.CheckInt(4), y+state.CheckInt(5)).Intersect(frame.Bounds())
+	draw.Draw(frame, rect, image.NewUniform(checkColor(state, 6)), image.Point{}, draw.Src)
+	return 0
+}
+
+func frameText(state *lua.LState) int {
+	frame := checkFrame(state)
+	text := state.CheckString(2)
+	x, y := state.CheckInt(3), state.CheckInt(4)
+	col := checkColor(state, 5)
+	height := float64(state.OptNumber(6, defaultTextHeight))
+	if height <= 0 {
+		state.ArgError(6, "height must be positive")
+	}
+	line, err := overlay.TextLine(text, height, col)
+	if err != nil {
+		state.RaiseError("text: %v", err)
+	}
+	dst := image.Rect(x, y, x+line.Bounds().Dx(), y+line.Bounds().Dy())
+	draw.Draw(frame, dst, line, line.Bounds().Min, draw.Over)
+	state.Push(lua.LNumber(line.Bounds().Dx()))
+	return 1
+}
+
+// stateTable converts the renderer's frame state for draw:
+//
+//	state.status   -- as passed to on_status
+//	state.palette  -- background, text, accent
+//	state.time     -- hour, minute, second, weekday (0 = Sunday), unix
+func stateTable(state *lua.LState, fs render.FrameState) *lua.LTable {
+	palette := state.NewTable()
+	palette.RawSetString("background", lua.LString(hexColor(fs.Palette.Background)))
+	palette.RawSetString("text", lua.LString(hexColor(fs.Palette.Text)))
+	palette.RawSetString("accent", lua.LString(hexColor(fs.Palette.Accent)))
+
+	now := state.NewTable()
+	now.RawSetString("hour", lua.LNumber(fs.Now.Hour()))
+	now.RawSetString("minute", lua.LNumber(fs.Now.Minute()))
+	now.RawSetString("second", lua.LNumber(fs.Now.Second()))
+	now.RawSetString("weekday", lua.LNumber(fs.Now.Weekday()))
+	now.RawSetString("unix", lua.LNumber(fs.Now.Unix()))
+
+	t := state.NewTable()
+	t.RawSetString("status", statusTable(state, fs.Status))
+	t.RawSetString("palette", palette)
+	t.RawSetString("time", now)
+	return t
+}
+
+// statusTable converts a playback status for scripts. position and duration
+// are in seconds; duration is 0 when unknown.
+func statusTable(state *lua.LState, status sonos.PlaybackStatus) *lua.LTable {
+	t := state.NewTable()
+	t.RawSetString("room", lua.LString(status.Room))
+	t.RawSetString("state", lua.LString(status.State))
+	t.RawSetString("playing", lua.LBool(status.Playing))
+	t.RawSetString("title", lua.LString(status.Track.Title))
+	t.RawSetString("artist", lua.LString(status.Track.Artist))
+	t.RawSetString("album", lua.LString(status.Track.Album))
+	t.RawSetString("source", lua.LString(status.Track.Source))
+	t.RawSetString("service", lua.LString(status.Track.Service))
+	t.RawSetString("position", lua.LNumber(status.Track.Position.Seconds()))
+	t.RawSetString("duration", lua.LNumber(status.Track.Duration.Seconds()))
+	return t
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
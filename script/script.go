@@ -0,0 +1,228 @@
+// Package script runs user-supplied Lua scripts as render layers, so custom
+// screens such as sports scores or personal dashboards can be added without
+// rebuilding the program.
+//
+// A script defines a global draw function, called with a frame and the
+// current state each time the renderer composes a frame:
+//
+//	animated = true
+//
+//	function draw(frame, state)
+//	  frame:fill(state.palette.background)
+//	  frame:text(string.format("%02d:%02d", state.time.hour, state.time.minute), 2, 2, "#ffcc00", 12)
+//	end
+//
+// An optional on_status(status) function is called with each playback
+// status update. Setting the global animated to true makes the renderer
+// redraw at its frame rate; otherwise draw runs only when something else
+// changes.
+//
+// Scripts run in a sandbox: only the base, string, table, and math libraries
+// are available, without file or module loading or the metatable functions,
+// string.rep builds at most MaxRepLength bytes, and each call is stopped
+// after CallTimeout. A script that fails is disabled and logged rather than
+// taking the display down with it.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"musicDisplay/logging"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+)
+
+var logger = logging.For("script")
+
+const (
+	// CallTimeout bounds each call into a script, including loading it.
+	CallTimeout = 100 * time.Millisecond
+	// MaxRepLength bounds the string string.rep may build, far more text
+	// than fits on the panel. Longer results raise an error, which disables
+	// the script instead of letting one call allocate without limit.
+	MaxRepLength = 1 << 20
+
+	callStackSize   = 200
+	registryMaxSize = 256 * 1024
+)
+
+// Script is a loaded Lua script. It implements render.AnimatedLayer and is
+// safe for concurrent use.
+type Script struct {
+	// Name identifies the script in logs, usually its file name.
+	Name string
+
+	mu       sync.Mutex
+	state    *lua.LState
+	draw     *lua.LFunction
+	onStatus *lua.LFunction
+	failed   bool
+}
+
+var _ render.AnimatedLayer = (*Script)(nil)
+
+// Load reads and runs the script at path.
+func Load(path string) (*Script, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+	return New(filepath.Base(path), string(source))
+}
+
+// New runs source as a script named name. It fails when the script does not
+// compile, raises an error, or defines no draw function.
+func New(name, source string) (*Script, error) {
+	state := newSandbox()
+	fn, err := state.Load(strings.NewReader(source), name)
+	if err != nil {
+		state.Close()
+		return nil, fmt.Errorf("script: %s: %w", name, err)
+	}
+	s := &Script{Name: name, state: state}
+	if err := s.call(fn); err != nil {
+		state.Close()
+		return nil, fmt.Errorf("script: %s: %w", name, err)
+	}
+
+	draw, ok := state.GetGlobal("draw").(*lua.LFunction)
+	if !ok {
+		state.Close()
+		return nil, fmt.Errorf("script: %s: no draw function", name)
+	}
+	s.draw = draw
+	s.onStatus, _ = state.GetGlobal("on_status").(*lua.LFunction)
+	return s, nil
+}
+
+// newSandbox returns a Lua state with only the libraries scripts may use.
+func newSandbox() *lua.LState {
+	state := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   callStackSize,
+		RegistryMaxSize: registryMaxSize,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+	// The metatable functions would let a script hook the globals table
+	// every call shares, so they go along with file and module loading.
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require", "collectgarbage", "setmetatable", "getmetatable", "rawset"} {
+		state.SetGlobal(name, lua.LNil)
+	}
+	if strlib, ok := state.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		state.SetField(strlib, "rep", state.NewFunction(luaStringRep))
+	}
+	state.SetGlobal("print", state.NewFunction(luaPrint))
+	registerFrame(state)
+	return state
+}
+
+// luaStringRep is string.rep limited to MaxRepLength bytes.
+func luaStringRep(state *lua.LState) int {
+	str := state.CheckString(1)
+	n := state.CheckInt(2)
+	if n <= 0 || str == "" {
+		state.Push(lua.LString(""))
+		return 1
+	}
+	if n > MaxRepLength/len(str) {
+		state.RaiseError("string.rep result longer than %d bytes", MaxRepLength)
+		return 0
+	}
+	state.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// luaPrint sends print output to the debug log.
+func luaPrint(state *lua.LState) int {
+	parts := make([]string, state.GetTop())
+	for i := range parts {
+		parts[i] = state.ToStringMeta(state.Get(i + 1)).String()
+	}
+	logger.Debug("script output", "text", strings.Join(parts, " "))
+	return 0
+}
+
+// Draw calls the script's draw function. Errors disable the script and are
+// logged rather than returned, so a broken script never blanks the frame.
+func (s *Script) Draw(frame *image.RGBA, state render.FrameState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed {
+		return nil
+	}
+	ud := newFrame(s.state, frame)
+	defer func() { ud.Value = nil }()
+	s.fail(s.call(s.draw, ud, stateTable(s.state, state)))
+	return nil
+}
+
+// Animating reports whether the script's animated global is true.
+func (s *Script) Animating() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.failed && lua.LVAsBool(s.state.GetGlobal("animated"))
+}
+
+// UpdateStatus passes status to the script's on_status function, if any. It
+// can be chained into sonos.ListenerOptions.OnStatus.
+func (s *Script) UpdateStatus(status sonos.PlaybackStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed || s.onStatus == nil {
+		return
+	}
+	s.fail(s.call(s.onStatus, statusTable(s.state, status)))
+}
+
+// Close releases the Lua state. The script must not be used afterwards.
+func (s *Script) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.state.Close()
+}
+
+// call runs fn with args under CallTimeout. Callers must hold s.mu unless the
+// script is still being loaded.
+func (s *Script) call(fn *lua.LFunction, args ...lua.LValue) error {
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	s.state.SetContext(ctx)
+	defer s.state.RemoveContext()
+	err := s.state.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("took longer than %s", CallTimeout)
+	}
+	return err
+}
+
+// fail disables the script after err. Callers must hold s.mu.
+func (s *Script) fail(err error) {
+	if err == nil {
+		return
+	}
+	s.failed = true
+	logger.Warn("script disabled", "script", s.Name, "err", err)
+}
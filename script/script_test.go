@@ -0,0 +1,185 @@
+package script
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+func frameState() render.FrameState {
+	return render.FrameState{
+		Status:  sonos.PlaybackStatus{State: "Playing", Playing: true, Track: sonos.TrackInfo{Title: "Song", Artist: "Band"}},
+		Palette: theme.DefaultPalette,
+		Now:     time.Date(2024, 5, 1, 21, 7, 30, 0, time.UTC),
+	}
+}
+
+func TestScriptDrawsWithFrameAPI(t *testing.T) {
+	s, err := New("test.lua", `
+function draw(frame, state)
+  frame:fill("#000080")
+  frame:rect(0, 0, 4, 2, "#ff0000")
+  frame:pixel(frame:width() - 1, frame:height() - 1, "#00ff00")
+  if state.status.artist == "Band" and state.time.hour == 21 then
+    frame:pixel(10, 10, state.palette.text)
+  end
+  local w = frame:text("Hi", 20, 20, "#ffffff", 8)
+  assert(w > 0, "text width")
+end
+`)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer s.Close()
+
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := s.Draw(frame, frameState()); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{1, 1, color.RGBA{R: 0xff, A: 0xff}},
+		{5, 5, color.RGBA{B: 0x80, A: 0xff}},
+		{63, 63, color.RGBA{G: 0xff, A: 0xff}},
+		{10, 10, theme.DefaultPalette.Text},
+	} {
+		if got := frame.RGBAAt(tc.x, tc.y); got != tc.want {
+			t.Fatalf("pixel (%d,%d) = %v, want %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+	if s.Animating() {
+		t.Fatalf("Animating = true without the animated global")
+	}
+}
+
+func TestScriptReceivesStatusAndAnimates(t *testing.T) {
+	s, err := New("status.lua", `
+title = ""
+function on_status(status)
+  title = status.title
+  animated = status.playing
+end
+function draw(frame, state)
+  if title == "Song" then frame:fill("#ffffff") end
+end
+`)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer s.Close()
+
+	s.UpdateStatus(frameState().Status)
+	if !s.Animating() {
+		t.Fatalf("Animating = false after on_status set animated")
+	}
+	frame := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	s.Draw(frame, frameState())
+	if got := frame.RGBAAt(0, 0); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("pixel = %v, want white after on_status saw the title", got)
+	}
+}
+
+func TestScriptSandbox(t *testing.T) {
+	for _, name := range []string{"io", "os", "require", "dofile", "loadfile", "load", "setmetatable", "getmetatable", "rawset"} {
+		if _, err := New("sandbox.lua", "assert("+name+" == nil, '"+name+" available')\nfunction draw() end"); err != nil {
+			t.Fatalf("sandbox exposes %s: %v", name, err)
+		}
+	}
+
+	if _, err := New("nodraw.lua", "x = 1"); err == nil || !strings.Contains(err.Error(), "no draw function") {
+		t.Fatalf("New without draw error = %v, want no draw function", err)
+	}
+	if _, err := New("syntax.lua", "function draw("); err == nil {
+		t.Fatalf("New with a syntax error expected error")
+	}
+	if _, err := New("loop.lua", "while true do end"); err == nil || !strings.Contains(err.Error(), "took longer") {
+		t.Fatalf("New with an endless loop error = %v, want a timeout", err)
+	}
+}
+
+func TestFailingScriptIsDisabled(t *testing.T) {
+	s, err := New("broken.lua", `
+animated = true
+calls = 0
+function draw(frame, state)
+  calls = calls + 1
+  frame:fill("#ff0000")
+  frame:fill("not a color")
+end
+`)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer s.Close()
+
+	frame := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	if err := s.Draw(frame, frameState()); err != nil {
+		t.Fatalf("Draw error = %v, want failures kept from the renderer", err)
+	}
+	if s.Animating() {
+		t.Fatalf("Animating = true after the script failed")
+	}
+	frame = image.NewRGBA(image.Rect(0, 0, 4, 4))
+	s.Draw(frame, frameState())
+	if got := frame.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Fatalf("disabled script still drew %v", got)
+	}
+}
+
+func TestAllocatingScriptIsDisabled(t *testing.T) {
+	s, err := New("alloc.lua", `
+animated = true
+function draw(frame, state)
+  local big = string.rep("x", 1e9)
+  frame:fill("#ff0000")
+end
+`)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer s.Close()
+
+	frame := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	s.Draw(frame, frameState())
+	if s.Animating() {
+		t.Fatalf("Animating = true after the script allocated past the limit")
+	}
+	if got := frame.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Fatalf("script drew %v after string.rep went past the limit", got)
+	}
+
+	small, err := New("rep.lua", `assert(#string.rep("ab", 3) == 6 and ("x"):rep(0) == "")
+function draw() end`)
+	if err != nil {
+		t.Fatalf("string.rep within the limit failed: %v", err)
+	}
+	small.Close()
+}
+
+func TestLoadReadsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clock.lua")
+	if err := os.WriteFile(path, []byte("function draw(frame) frame:fill('#123456') end"), 0o644); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	defer s.Close()
+	if s.Name != "clock.lua" {
+		t.Fatalf("Name = %q, want clock.lua", s.Name)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.lua")); err == nil {
+		t.Fatalf("Load of a missing file expected error")
+	}
+}
@@ -0,0 +1,70 @@
+// Package sdnotify reports the service's state to systemd through the
+// notification socket of a Type=notify unit: readiness, a status line for
+// systemctl status, shutdown, and watchdog keep-alives.
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// States understood by systemd; see sd_notify(3).
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns the state that sets the status line systemctl status
+// shows.
+func Status(text string) string {
+	return "STATUS=" + strings.ReplaceAll(text, "\n", " ")
+}
+
+// Notify sends state to the socket systemd names in $NOTIFY_SOCKET. It
+// reports false, without an error, when there is no socket because the
+// program was not started by systemd as a Type=notify service.
+func Notify(state string) (bool, error) {
+	return notify(os.Getenv("NOTIFY_SOCKET"), state)
+}
+
+func notify(socket, state string) (bool, error) {
+	if socket == "" {
+		return false, nil
+	}
+	// A socket starting with "@" is in the abstract namespace, which the
+	// net package handles.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("sdnotify: dial %s: %w", socket, err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sdnotify: send %q: %w", state, err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the unit's WatchdogSec, within which systemd
+// expects a Watchdog notification, when the watchdog is enabled for this
+// process.
+func WatchdogInterval() (time.Duration, bool) {
+	return watchdogInterval(os.Getenv("WATCHDOG_USEC"), os.Getenv("WATCHDOG_PID"), os.Getpid())
+}
+
+func watchdogInterval(usec, pid string, self int) (time.Duration, bool) {
+	if pid != "" {
+		if p, err := strconv.Atoi(pid); err != nil || p != self {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(usec), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Microsecond, true
+}
@@ -0,0 +1,60 @@
+package sdnotify
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotifySendsState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	sent, err := notify(path, Status("Following Kitchen\nnow"))
+	if err != nil || !sent {
+		t.Fatalf("notify = %v, %v; want sent", sent, err)
+	}
+	buf := make([]byte, 128)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got := string(buf[:n]); got != "STATUS=Following Kitchen now" {
+		t.Fatalf("received %q", got)
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	sent, err := notify("", Ready)
+	if sent || err != nil {
+		t.Fatalf("notify without socket = %v, %v; want false, nil", sent, err)
+	}
+	if _, err := notify(filepath.Join(t.TempDir(), "missing.sock"), Ready); err == nil {
+		t.Fatalf("notify to a missing socket succeeded")
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	for _, tc := range []struct {
+		usec, pid string
+		want      time.Duration
+		ok        bool
+	}{
+		{"30000000", "", 30 * time.Second, true},
+		{"30000000", "42", 30 * time.Second, true},
+		{"30000000", "7", 0, false},
+		{"", "", 0, false},
+		{"soon", "", 0, false},
+	} {
+		got, ok := watchdogInterval(tc.usec, tc.pid, 42)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("watchdogInterval(%q, %q) = %v, %v; want %v, %v", tc.usec, tc.pid, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+// Package simdisplay is a development stand-in for the LED matrix. It keeps
+// the latest frame in memory and serves it over HTTP as a PNG alongside a
+// small auto-refreshing preview page, so renderers can be worked on without
+// the panel hardware.
+package simdisplay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/matrixdisplay"
+)
+
+// DefaultAddr is the listen address used when none is configured.
+const DefaultAddr = "127.0.0.1:8064"
+
+// Controls handles the track buttons and keyboard shortcuts on the preview
+// page.
+type Controls interface {
+	// Skip moves playback to the next track.
+	Skip(ctx context.Context) error
+	// Like saves the current track to the listener's library.
+	Like(ctx context.Context) error
+	// PlayPause toggles playback.
+	PlayPause(ctx context.Context) error
+	// AdjustVolume changes the volume by delta and returns the new volume.
+	AdjustVolume(ctx context.Context, delta int) (int, error)
+}
+
+// DiagnosticsControls is implemented by Controls that can also toggle a
+// diagnostics screen, which the preview page does on a long press of the
+// frame or the d key.
+type DiagnosticsControls interface {
+	Controls
+	// ToggleDiagnostics shows the diagnostics screen, or hides it when it
+	// is up.
+	ToggleDiagnostics(ctx context.Context) error
+}
+
+// brightnessSteps are the levels the b key cycles through.
+var brightnessSteps = []int{100, 75, 50, 25, 10}
+
+// Display implements the same Show/Clear/SetBrightness/Close surface as the
+// matrix controller, rendering into an in-memory frame.
+type Display struct {
+	server   *http.Server
+	listener net.Listener
+
+	geometry matrixdisplay.Geometry
+
+	mu         sync.RWMutex
+	frame      *image.RGBA
+	brightness int
+	gamutBits  int
+	version    uint64
+	controls   Controls
+}
+
+// New starts serving the simulator on addr (DefaultAddr when empty),
+// previewing a display of the given geometry.
+func New(addr string, geometry matrixdisplay.Geometry, brightness int) (*Display, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("simdisplay: listen %s: %w", addr, err)
+	}
+
+	geometry = geometry.OrDefault()
+	d := &Display{
+		listener:   ln,
+		geometry:   geometry,
+		frame:      blankFrame(geometry),
+		brightness: brightness,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.handleIndex)
+	mux.HandleFunc("/frame.png", d.handleFrame)
+	mux.HandleFunc("/api/skip", d.handleControl(Controls.Skip))
+	mux.HandleFunc("/api/like", d.handleControl(Controls.Like))
+	mux.HandleFunc("/api/playpause", d.handleControl(Controls.PlayPause))
+	mux.HandleFunc("/api/diagnostics", d.handleDiagnostics)
+	mux.HandleFunc("/api/volume", d.handleVolume)
+	mux.HandleFunc("/api/brightness", d.handleBrightness)
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		_ = d.server.Serve(ln)
+	}()
+	return d, nil
+}
+
+// Size reports the frame size of the previewed geometry.
+func (d *Display) Size() (int, int) {
+	return d.geometry.Width, d.geometry.Height
+}
+
+// URL returns the address of the preview page.
+func (d *Display) URL() string {
+	return "http://" + d.listener.Addr().String() + "/"
+}
+
+// Show replaces the current frame.
+func (d *Display) Show(img image.Image) error {
+	if img == nil {
+		return errors.New("simdisplay: nil image")
+	}
+	bounds := img.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(frame, frame.Bounds(), img, bounds.Min, draw.Src)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Unchanged frames keep the version so the page does not refetch them.
+	if d.frame != nil && matrixdisplay.FrameHash(d.frame) == matrixdisplay.FrameHash(frame) {
+		return nil
+	}
+	d.frame = frame
+	d.version++
+	return nil
+}
+
+// Clear blanks the frame.
+func (d *Display) Clear() error {
+	d.mu.Lock()
+	d.frame = blankFrame(d.geometry)
+	d.version++
+	d.mu.Unlock()
+	return nil
+}
+
+// SetBrightness changes the brightness applied to the served frame, mirroring
+// the software scaling done by the matrix controller.
+func (d *Display) SetBrightness(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("simdisplay: brightness must be between 1 and 100, got %d", level)
+	}
+	d.mu.Lock()
+	d.brightness = level
+	d.version++
+	d.mu.Unlock()
+	return nil
+}
+
+// PreviewGamut makes the served frame simulate a matrix with pwmBits of
+// color depth, as matrixdisplay.SimulateGamut does; 0 turns it off.
+func (d *Display) PreviewGamut(pwmBits int) {
+	d.mu.Lock()
+	d.gamutBits = pwmBits
+	d.version++
+	d.mu.Unlock()
+}
+
+// SetControls shows skip and like buttons on the preview page that call c.
+func (d *Display) SetControls(c Controls) {
+	d.mu.Lock()
+	d.controls = c
+	d.mu.Unlock()
+}
+
+// Close stops the HTTP server.
+func (d *Display) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return d.server.Shutdown(ctx)
+}
+
+// Frame returns the current frame with brightness applied, and the gamut
+// simulated when PreviewGamut is on, and a version that changes whenever the
+// frame does.
+func (d *Display) Frame() (*image.RGBA, uint64) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.gamutBits > 0 {
+		return matrixdisplay.SimulateGamut(d.frame, d.gamutBits, d.brightness), d.version
+	}
+	return matrixdisplay.ApplyBrightness(d.frame, d.brightness), d.version
+}
+
+func (d *Display) handleFrame(w http.ResponseWriter, r *http.Request) {
+	frame, version := d.Frame()
+	etag := `"` + strconv.FormatUint(version, 10) + `"`
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, frame); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// handleControl serves a POST endpoint that runs action on the configured
+// controls.
+func (d *Display) handleControl(action func(Controls, context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		d.mu.RLock()
+		controls := d.controls
+		d.mu.RUnlock()
+		if controls == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := action(controls, r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDiagnostics serves POST /api/diagnostics, toggling the diagnostics
+// screen when the controls can.
+func (d *Display) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.mu.RLock()
+	controls, ok := d.controls.(DiagnosticsControls)
+	d.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := controls.ToggleDiagnostics(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleVolume serves POST /api/volume?delta=N.
+func (d *Display) handleVolume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.mu.RLock()
+	controls := d.controls
+	d.mu.RUnlock()
+	if controls == nil {
+		http.NotFound(w, r)
+		return
+	}
+	delta, err := strconv.Atoi(r.URL.Query().Get("delta"))
+	if err != nil {
+		http.Error(w, "delta must be an integer", http.StatusBadRequest)
+		return
+	}
+	volume, err := controls.AdjustVolume(r.Context(), delta)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	fmt.Fprintf(w, "Volume %d", volume)
+}
+
+// handleBrightness serves POST /api/brightness, stepping the preview to the
+// next lower brightness and wrapping back to full.
+func (d *Display) handleBrightness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.mu.RLock()
+	current := d.brightness
+	d.mu.RUnlock()
+	level := nextBrightness(current)
+	if err := d.SetBrightness(level); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "Brightness %d", level)
+}
+
+// nextBrightness returns the step after current in brightnessSteps.
+func nextBrightness(current int) int {
+	if current <= 0 {
+		current = 100
+	}
+	for _, step := range brightnessSteps {
+		if step < current {
+			return step
+		}
+	}
+	return brightnessSteps[0]
+}
+
+func (d *Display) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	d.mu.RLock()
+	page := strings.Replace(indexHTML, "{{size}}", fmt.Sprintf("%d×%d", d.geometry.Width, d.geometry.Height), 1)
+	if d.controls != nil {
+		page = strings.Replace(page, `<div id="controls" hidden>`, `<div id="controls">`, 1)
+	}
+	d.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(page))
+}
+
+func blankFrame(geometry matrixdisplay.Geometry) *image.RGBA {
+	frame := image.NewRGBA(geometry.Bounds())
+	draw.Draw(frame, frame.Bounds(), image.Black, image.Point{}, draw.Src)
+	return frame
+}
+
+const indexHTML = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>WallDisplay simulator</title>
+<style>
+  body { background: #111; color: #888; font: 14px sans-serif; display: flex; flex-direction: column; align-items: center; margin-top: 40px; }
+  img { width: 512px; height: auto; image-rendering: pixelated; background: #000; border: 8px solid #222; }
+  button { background: #222; color: #ccc; border: 1px solid #444; border-radius: 4px; padding: 6px 18px; margin: 0 6px; font: inherit; cursor: pointer; }
+</style>
+</head>
+<body>
+<img id="frame" src="frame.png" alt="matrix frame">
+<p>{{size}} matrix preview</p>
+<div id="controls" hidden>
+  <button data-action="skip">Skip ⏭</button>
+  <button data-action="like">Like ♥</button>
+  <p>Keys: space play/pause · n next · ↑/↓ volume ±5 · ←/→ volume ±1 · b brightness · d or hold the frame for diagnostics</p>
+  <p id="result"></p>
+</div>
+<script>
+  const controls = document.getElementById("controls");
+  const result = document.getElementById("result");
+  async function send(action, label) {
+    result.textContent = "";
+    try {
+      const resp = await fetch("api/" + action, { method: "POST" });
+      const text = await resp.text();
+      result.textContent = resp.ok ? (text || label + " sent") : text;
+    } catch (e) {
+      result.textContent = String(e);
+    }
+  }
+  for (const button of document.querySelectorAll("#controls button")) {
+    button.addEventListener("click", () => send(button.dataset.action, button.textContent.trim()));
+  }
+  const keys = {
+    " ": ["playpause", "Play/pause"],
+    "n": ["skip", "Next"],
+    "ArrowUp": ["volume?delta=5", "Volume up"],
+    "ArrowDown": ["volume?delta=-5", "Volume down"],
+    "ArrowRight": ["volume?delta=1", "Volume up"],
+    "ArrowLeft": ["volume?delta=-1", "Volume down"],
+    "b": ["brightness", "Brightness"],
+    "d": ["diagnostics", "Diagnostics"],
+  };
+  document.addEventListener("keydown", (event) => {
+    const key = keys[event.key];
+    if (controls.hidden || !key || event.repeat || event.ctrlKey || event.metaKey || event.altKey) {
+      return;
+    }
+    event.preventDefault();
+    send(key[0], key[1]);
+  });
+
+  const img = document.getElementById("frame");
+  // Holding the frame stands in for a long press of a button on the wall.
+  let hold = 0;
+  img.addEventListener("pointerdown", () => {
+    if (!controls.hidden) {
+      hold = setTimeout(() => send("diagnostics", "Diagnostics"), 800);
+    }
+  });
+  for (const name of ["pointerup", "pointerleave", "pointercancel"]) {
+    img.addEventListener(name, () => clearTimeout(hold));
+  }
+  let etag = "";
+  async function refresh() {
+    try {
+      const resp = await fetch("frame.png", { headers: etag ? { "If-None-Match": etag } : {} });
+      if (resp.status === 200) {
+        etag = resp.headers.get("ETag") || "";
+        const url = URL.createObjectURL(await resp.blob());
+        img.onload = () => URL.revokeObjectURL(url);
+        img.src = url;
+      }
+    } catch (e) {}
+    setTimeout(refresh, 100);
+  }
+  refresh();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,262 @@
+package simdisplay
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"musicDisplay/matrixdisplay"
+)
+
+func TestServesCurrentFrame(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+
+	art := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(art, art.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 100, A: 0xff}), image.Point{}, draw.Src)
+	if err := d.Show(art); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if err := d.SetBrightness(50); err != nil {
+		t.Fatalf("SetBrightness error: %v", err)
+	}
+
+	resp, err := http.Get(d.URL() + "frame.png")
+	if err != nil {
+		t.Fatalf("get frame: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if got := color.RGBAModel.Convert(img.At(10, 10)).(color.RGBA); got != (color.RGBA{R: 100, G: 50, A: 0xff}) {
+		t.Fatalf("pixel = %+v, want brightness-scaled frame", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, d.URL()+"frame.png", nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	cached, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("conditional get: %v", err)
+	}
+	cached.Body.Close()
+	if cached.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional status = %d, want 304", cached.StatusCode)
+	}
+
+	page, err := http.Get(d.URL())
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	body, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	if !strings.Contains(string(body), "frame.png") {
+		t.Fatalf("index page does not reference the frame")
+	}
+}
+
+type fakeControls struct {
+	skips  int
+	volume int
+	err    error
+}
+
+func (f *fakeControls) Skip(ctx context.Context) error {
+	f.skips++
+	return nil
+}
+
+func (f *fakeControls) Like(ctx context.Context) error {
+	return f.err
+}
+
+func (f *fakeControls) PlayPause(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeControls) AdjustVolume(ctx context.Context, delta int) (int, error) {
+	f.volume += delta
+	return f.volume, nil
+}
+
+type fakeDiagnosticsControls struct {
+	fakeControls
+	toggles int
+}
+
+func (f *fakeDiagnosticsControls) ToggleDiagnostics(ctx context.Context) error {
+	f.toggles++
+	return nil
+}
+
+func TestDiagnosticsEndpoint(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+	post := func() int {
+		resp, err := http.Post(d.URL()+"api/diagnostics", "", nil)
+		if err != nil {
+			t.Fatalf("post diagnostics: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	d.SetControls(&fakeControls{})
+	if code := post(); code != http.StatusNotFound {
+		t.Fatalf("diagnostics without support = %d, want 404", code)
+	}
+	controls := &fakeDiagnosticsControls{}
+	d.SetControls(controls)
+	if code := post(); code != http.StatusNoContent || controls.toggles != 1 {
+		t.Fatalf("diagnostics status = %d, toggles = %d", code, controls.toggles)
+	}
+}
+
+func TestControlEndpoints(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+
+	resp, err := http.Post(d.URL()+"api/skip", "", nil)
+	if err != nil {
+		t.Fatalf("post skip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("skip without controls = %d, want 404", resp.StatusCode)
+	}
+
+	controls := &fakeControls{err: errors.New("not a Spotify track")}
+	d.SetControls(controls)
+
+	resp, err = http.Post(d.URL()+"api/skip", "", nil)
+	if err != nil {
+		t.Fatalf("post skip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || controls.skips != 1 {
+		t.Fatalf("skip status = %d, skips = %d", resp.StatusCode, controls.skips)
+	}
+
+	resp, err = http.Post(d.URL()+"api/like", "", nil)
+	if err != nil {
+		t.Fatalf("post like: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || !strings.Contains(string(body), "not a Spotify track") {
+		t.Fatalf("like status = %d body %q", resp.StatusCode, body)
+	}
+
+	page, err := http.Get(d.URL())
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	html, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	if strings.Contains(string(html), `<div id="controls" hidden>`) {
+		t.Fatalf("controls still hidden after SetControls")
+	}
+}
+
+func TestKeyboardEndpoints(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+	controls := &fakeControls{volume: 20}
+	d.SetControls(controls)
+
+	post := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(d.URL()+path, "", nil)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := post("api/volume?delta=-5"); code != http.StatusOK || body != "Volume 15" {
+		t.Fatalf("volume = %d %q", code, body)
+	}
+	if code, _ := post("api/volume?delta=up"); code != http.StatusBadRequest {
+		t.Fatalf("bad delta = %d, want 400", code)
+	}
+	for _, want := range []string{"Brightness 75", "Brightness 50", "Brightness 25", "Brightness 10", "Brightness 100"} {
+		if _, body := post("api/brightness"); body != want {
+			t.Fatalf("brightness step = %q, want %q", body, want)
+		}
+	}
+}
+
+func TestPreviewsConfiguredGeometry(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{Width: 128, Height: 64}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+	if w, h := d.Size(); w != 128 || h != 64 {
+		t.Fatalf("Size = %dx%d, want 128x64", w, h)
+	}
+	if frame, _ := d.Frame(); frame.Bounds().Dx() != 128 || frame.Bounds().Dy() != 64 {
+		t.Fatalf("blank frame = %v, want 128x64", frame.Bounds())
+	}
+	resp, err := http.Get(d.URL())
+	if err != nil {
+		t.Fatalf("get page: %v", err)
+	}
+	defer resp.Body.Close()
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(page), "128×64 matrix preview") {
+		t.Fatal("preview page does not show the configured size")
+	}
+}
+
+func TestPreviewGamutSimulatesPanelDepth(t *testing.T) {
+	d, err := New("127.0.0.1:0", matrixdisplay.Geometry{}, 100)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	defer d.Close()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	img.SetRGBA(0, 0, color.RGBA{R: 3, G: 3, B: 3, A: 0xff})
+	if err := d.Show(img); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	_, before := d.Frame()
+
+	d.PreviewGamut(4)
+	frame, after := d.Frame()
+	if after == before {
+		t.Fatal("version unchanged after PreviewGamut, want the page to refetch")
+	}
+	if got := frame.RGBAAt(0, 0); got.R != 0 {
+		t.Fatalf("near-black pixel = %v, want it crushed at 4 PWM bits", got)
+	}
+	d.PreviewGamut(0)
+	if frame, _ := d.Frame(); frame.RGBAAt(0, 0).R != 3 {
+		t.Fatal("PreviewGamut(0) did not restore the plain preview")
+	}
+}
@@ -2,6 +2,7 @@ package sonos
 
 import (
 	"bytes"
+	"container/list"
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
@@ -9,156 +10,321 @@ import (
 	"fmt"
 	"image"
 	imagedraw "image/draw"
+	"image/jpeg"
 	"image/png"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	_ "image/gif"
-	_ "image/jpeg"
 
-	xdraw "golang.org/x/image/draw"
+	"musicDisplay/atomicfile"
 )
 
+// ArtFormat is how album art is stored in the disk cache.
+type ArtFormat string
+
+const (
+	// ArtFormatPNG stores the processed image as PNG. It is the default.
+	ArtFormatPNG ArtFormat = "png"
+	// ArtFormatJPEG stores the processed image as JPEG, at a fraction of the
+	// size of PNG.
+	ArtFormatJPEG ArtFormat = "jpeg"
+	// ArtFormatOriginal stores the bytes the speaker served, unprocessed, so
+	// nothing is re-encoded and the full resolution is kept.
+	ArtFormatOriginal ArtFormat = "original"
+)
+
+// DefaultJPEGQuality is the JPEG quality used when ArtStorage.Quality is 0.
+const DefaultJPEGQuality = 85
+
+// ParseArtFormat parses "png", "jpeg", or "original". An empty value selects
+// PNG.
+func ParseArtFormat(value string) (ArtFormat, error) {
+	switch format := ArtFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return ArtFormatPNG, nil
+	case "jpg":
+		return ArtFormatJPEG, nil
+	case ArtFormatPNG, ArtFormatJPEG, ArtFormatOriginal:
+		return format, nil
+	}
+	return "", fmt.Errorf("sonos: unknown art format %q (want png, jpeg, or original)", value)
+}
+
+// DefaultArtDir is where album art is cached on disk unless ArtStorage.Dir
+// says otherwise.
+const DefaultArtDir = "art"
+
+// ArtStorage controls how SaveAlbumArt caches artwork. Quality is the JPEG
+// quality, 1-100, and is ignored by the other formats.
+type ArtStorage struct {
+	Format  ArtFormat
+	Quality int
+	// Dir is the disk cache directory, DefaultArtDir when empty. Pointing it
+	// at a tmpfs such as /dev/shm keeps the writes off an SD card.
+	Dir string
+	// MemoryOnly keeps artwork in memory and never touches the disk.
+	MemoryOnly bool
+	// MemoryEntries bounds how many processed images are kept in memory;
+	// 0 means 64.
+	MemoryEntries int
+	// Size is the edge of processed art in pixels, usually the display
+	// geometry's art size; 0 means DefaultArtSize.
+	Size int
+	// Processing tunes the scaling, gamma, and dithering of processed art.
+	Processing ArtProcessing
+	// Fetch limits the size of the art downloaded and of the images decoded.
+	Fetch ArtFetch
+}
+
+func (s ArtStorage) dir() string {
+	if dir := strings.TrimSpace(s.Dir); dir != "" {
+		return dir
+	}
+	return DefaultArtDir
+}
+
+func (s ArtStorage) memoryEntries() int {
+	if s.MemoryEntries > 0 {
+		return s.MemoryEntries
+	}
+	return defaultRecentArtSize
+}
+
+// encode returns the bytes to store for art fetched as data and processed
+// into img, with the file's content type.
+func (s ArtStorage) encode(data []byte, img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch s.Format {
+	case ArtFormatOriginal:
+		return data, http.DetectContentType(data), nil
+	case ArtFormatJPEG:
+		quality := s.Quality
+		if quality <= 0 || quality > 100 {
+			quality = DefaultJPEGQuality
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("encode album art: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	default:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("encode album art: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+}
+
 // SaveAlbumArt retrieves the current track art (when available), returning a
-// 64x64 processed image. When cacheToDisk is true the artwork is persisted
-// under ./art/ so it can be reused by later runs; otherwise the image is kept
-// in-memory only.
-func SaveAlbumArt(ctx context.Context, device Device, room string, track TrackInfo, signature string, cacheToDisk bool) (image.Image, error) {
+// processed image storage.Size pixels square. Recently used images are kept
+// in memory, by signature and by art URI, so replaying a track or album does
+// not fetch it again. When cacheToDisk is true, and storage is not
+// MemoryOnly, the artwork is also persisted under storage's directory, in the
+// format it selects, so it can be reused by later runs.
+func SaveAlbumArt(ctx context.Context, device Device, room string, track TrackInfo, signature string, cacheToDisk bool, storage ArtStorage) (image.Image, error) {
+	return saveAlbumArt(ctx, nil, device, room, track, signature, cacheToDisk, storage)
+}
+
+// saveAlbumArt is SaveAlbumArt with the session's track cache.
+func saveAlbumArt(ctx context.Context, tracks *TrackCache, device Device, room string, track TrackInfo, signature string, cacheToDisk bool, storage ArtStorage) (image.Image, error) {
 	artURI := strings.TrimSpace(track.AlbumArtURI)
 	if artURI == "" {
 		return nil, nil
 	}
+	key := AlbumArtKey(signature)
+	if img, ok := recentArt.get(key); ok {
+		return img, nil
+	}
 
-	if !cacheToDisk {
-		data, err := fetchAlbumArtBytes(ctx, device, artURI)
+	if !cacheToDisk || storage.MemoryOnly {
+		if img, ok := recentArt.getURI(artURI); ok {
+			// Another track with the same cover; keep it under this
+			// track's key too so the control API can serve it.
+			recentArt.add(key, artURI, img, storage.memoryEntries())
+			return img, nil
+		}
+		data, err := fetchAlbumArtBytes(ctx, tracks, device, artURI, storage.Fetch)
+		if err != nil {
+			return nil, err
+		}
+		img, err := ProcessAlbumArt(data, storage.Size, storage.Processing)
 		if err != nil {
 			return nil, err
 		}
-		return processAlbumArt(data)
+		recentArt.add(key, artURI, img, storage.memoryEntries())
+		return img, nil
 	}
 
-	const storedContentType = "image/png"
-	path, err := albumArtPath(room, signature, storedContentType)
+	cached, err := cachedAlbumArtFile(storage.dir(), room, signature)
 	if err != nil {
 		return nil, err
 	}
-
-	if _, err := os.Stat(path); err == nil {
-		file, err := os.Open(path)
-		if err != nil {
-			return nil, fmt.Errorf("open album art file: %w", err)
-		}
-		defer file.Close()
-		img, err := png.Decode(file)
+	if cached != "" {
+		img, err := loadAlbumArtFile(cached, storage)
 		if err != nil {
-			return nil, fmt.Errorf("decode cached album art: %w", err)
+			return nil, err
 		}
+		recentArt.add(key, artURI, img, storage.memoryEntries())
 		return img, nil
-	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("stat album art file: %w", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return nil, fmt.Errorf("create album art directory: %w", err)
+	data, err := fetchAlbumArtBytes(ctx, tracks, device, artURI, storage.Fetch)
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := fetchAlbumArtBytes(ctx, device, artURI)
+	// The file keeps the art before gamma and dithering, which are applied
+	// again whenever it is loaded.
+	img, err := scaleAlbumArt(data, storage.Size, storage.Processing)
 	if err != nil {
 		return nil, err
 	}
-
-	img, err := processAlbumArt(data)
+	stored, contentType, err := storage.encode(data, img)
 	if err != nil {
 		return nil, err
 	}
-
-	file, err := os.Create(path)
+	storage.Processing.adjust(img)
+	path, err := albumArtPath(storage.dir(), room, signature, contentType)
 	if err != nil {
-		return nil, fmt.Errorf("create album art file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
-
-	if err := png.Encode(file, img); err != nil {
-		return nil, fmt.Errorf("encode album art: %w", err)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create album art directory: %w", err)
+	}
+	// A power cut never leaves a truncated image behind.
+	if err := atomicfile.Write(path, stored, 0o644); err != nil {
+		return nil, err
 	}
 
+	recentArt.add(key, artURI, img, storage.memoryEntries())
 	return img, nil
 }
 
-func fetchAlbumArtBytes(ctx context.Context, device Device, artURI string) ([]byte, error) {
-	targetURL, err := resolveAlbumArtURL(device, artURI)
+// cachedAlbumArtFile returns the cached file for room and signature in any
+// format, or "" when there is none.
+func cachedAlbumArtFile(dir, room, signature string) (string, error) {
+	path, err := albumArtPath(dir, room, signature, "")
 	if err != nil {
-		return nil, fmt.Errorf("resolve album art url: %w", err)
+		return "", err
 	}
+	matches, err := filepath.Glob(strings.TrimSuffix(path, filepath.Ext(path)) + ".*")
+	if err != nil || len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0], nil
+}
 
-	artCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(artCtx, http.MethodGet, targetURL, nil)
+// loadAlbumArtFile reads and processes a cached art file, whatever format it
+// was stored in.
+func loadAlbumArtFile(path string, storage ArtStorage) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open album art file: %w", err)
+	}
+	img, err := ProcessAlbumArt(data, storage.Size, storage.Processing)
 	if err != nil {
-		return nil, fmt.Errorf("create album art request: %w", err)
+		return nil, fmt.Errorf("decode cached album art: %w", err)
 	}
+	return img, nil
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	var resp *http.Response
-	var lastErr error
+// fetchAlbumArtBytes downloads the art at artURI, trying again after
+// fetch's delay while the request fails or the speaker answers 404, up to
+// fetch's attempts. Art that is still missing is reported as ErrArtNotFound.
+func fetchAlbumArtBytes(ctx context.Context, tracks *TrackCache, device Device, artURI string, fetch ArtFetch) ([]byte, error) {
+	targetURL, err := tracks.albumArtURL(device, artURI)
+	if err != nil {
+		return nil, fmt.Errorf("resolve album art url: %w", err)
+	}
 
-	for attempt := 0; attempt < 3; attempt++ {
-		resp, err = client.Do(req)
-		if err != nil {
-			lastErr = err
-			time.Sleep(200 * time.Millisecond)
-			continue
+	timeout := TimeoutsFor(device).Art
+	client := &http.Client{Timeout: timeout}
+	attempts := fetch.attempts()
+	for attempt := 1; ; attempt++ {
+		data, retry, err := fetchAlbumArtOnce(ctx, client, targetURL, fetch)
+		if err == nil {
+			return data, nil
 		}
-		if resp.StatusCode == http.StatusOK {
-			break
+		if !retry || attempt >= attempts {
+			if errors.Is(err, ErrArtNotFound) {
+				return nil, fmt.Errorf("%w after %d attempts", ErrArtNotFound, attempt)
+			}
+			return nil, err
 		}
-		if resp.StatusCode == http.StatusNotFound {
-			resp.Body.Close()
-			time.Sleep(200 * time.Millisecond)
-			continue
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("fetch album art: %w", ctx.Err())
+		case <-time.After(fetch.delay()):
 		}
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
-		resp.Body.Close()
-		return nil, fmt.Errorf("album art http status %s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
+}
 
-	if resp == nil {
-		return nil, fmt.Errorf("fetch album art failed: %w", lastErr)
+// fetchAlbumArtOnce makes one request for the art at targetURL. retry
+// reports whether a failure may pass: the request failed or the art is not
+// there yet.
+func fetchAlbumArtOnce(ctx context.Context, client *http.Client, targetURL string, fetch ArtFetch) (data []byte, retry bool, err error) {
+	artCtx, cancel := context.WithTimeout(ctx, client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(artCtx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("create album art request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("fetch album art failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("album art http status 404 after retries")
-		}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, true, ErrArtNotFound
+	default:
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
-		return nil, fmt.Errorf("album art http status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		return nil, false, fmt.Errorf("album art http status %s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err = fetch.read(resp)
 	if err != nil {
-		return nil, fmt.Errorf("read album art body: %w", err)
+		return nil, false, err
+	}
+	if err := fetch.check(data); err != nil {
+		return nil, false, err
 	}
-	return data, nil
+	return data, false, nil
 }
 
-func processAlbumArt(data []byte) (image.Image, error) {
+// DefaultArtSize is the edge of processed album art when ArtStorage.Size is
+// 0: one 64x64 panel.
+const DefaultArtSize = 64
+
+// ProcessAlbumArt decodes artwork, crops it to a centered square, and scales
+// it to size pixels square, DefaultArtSize when size is not positive, as proc
+// directs.
+func ProcessAlbumArt(data []byte, size int, proc ArtProcessing) (image.Image, error) {
+	img, err := scaleAlbumArt(data, size, proc)
+	if err != nil {
+		return nil, err
+	}
+	proc.adjust(img)
+	return img, nil
+}
+
+// scaleAlbumArt is ProcessAlbumArt without the gamma and dithering.
+func scaleAlbumArt(data []byte, size int, proc ArtProcessing) (*image.NRGBA, error) {
+	if size <= 0 {
+		size = DefaultArtSize
+	}
 	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("decode album art: %w", err)
 	}
-
-	img = cropToSquare(img)
-
-	dst := image.NewNRGBA(image.Rect(0, 0, 64, 64))
-	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Over, nil)
-
-	return dst, nil
+	return proc.scale(cropToSquare(img), size), nil
 }
 
 func cropToSquare(img image.Image) image.Image {
@@ -190,7 +356,149 @@ func cropToSquare(img image.Image) image.Image {
 	return dst
 }
 
-func albumArtPath(room, signature, contentType string) (string, error) {
+// AlbumArtKey returns the short key that names the art for a track
+// signature, both in the art directory and in CachedAlbumArt.
+func AlbumArtKey(signature string) string {
+	if signature == "" {
+		return ""
+	}
+	hash := sha1.Sum([]byte(signature))
+	return hex.EncodeToString(hash[:6])
+}
+
+// ErrArtNotCached is returned by CachedAlbumArt for art that has not been
+// fetched, or has since been dropped.
+var ErrArtNotCached = errors.New("sonos: album art not cached")
+
+// defaultRecentArtSize is how many processed images are kept in memory by
+// default. At the default size that is about a megabyte.
+const defaultRecentArtSize = 64
+
+var recentArt = newArtMemory()
+
+// artMemory is a least-recently-used cache of processed album art, keyed by
+// AlbumArtKey. Entries are also found by their art URI, so tracks sharing a
+// cover, such as the tracks of an album, share one download.
+type artMemory struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	byURI   map[string]*list.Element
+	lru     *list.List
+}
+
+type artMemoryEntry struct {
+	key, uri string
+	img      image.Image
+}
+
+func newArtMemory() *artMemory {
+	return &artMemory{
+		entries: make(map[string]*list.Element),
+		byURI:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// add keeps img under key and, when uri is set, under uri, dropping the
+// least recently used images beyond limit.
+func (m *artMemory) add(key, uri string, img image.Image, limit int) {
+	if key == "" || img == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.drop(el)
+	}
+	el := m.lru.PushFront(&artMemoryEntry{key: key, uri: uri, img: img})
+	m.entries[key] = el
+	if uri != "" {
+		m.byURI[uri] = el
+	}
+	for m.lru.Len() > limit {
+		m.drop(m.lru.Back())
+	}
+}
+
+// drop removes el. Callers must hold m.mu.
+func (m *artMemory) drop(el *list.Element) {
+	entry := el.Value.(*artMemoryEntry)
+	m.lru.Remove(el)
+	delete(m.entries, entry.key)
+	if m.byURI[entry.uri] == el {
+		delete(m.byURI, entry.uri)
+	}
+}
+
+// clear drops every image.
+func (m *artMemory) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[string]*list.Element)
+	m.byURI = make(map[string]*list.Element)
+	m.lru.Init()
+}
+
+// get returns the image stored under key and marks it recently used.
+func (m *artMemory) get(key string) (image.Image, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.use(m.entries[key])
+}
+
+// getURI returns the image last stored for the art URI uri.
+func (m *artMemory) getURI(uri string) (image.Image, bool) {
+	if uri == "" {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.use(m.byURI[uri])
+}
+
+func (m *artMemory) use(el *list.Element) (image.Image, bool) {
+	if el == nil {
+		return nil, false
+	}
+	m.lru.MoveToFront(el)
+	return el.Value.(*artMemoryEntry).img, true
+}
+
+// CachedAlbumArt returns processed album art by its AlbumArtKey, from memory
+// or from storage's directory. It never fetches from a speaker.
+func CachedAlbumArt(key string, storage ArtStorage) (image.Image, error) {
+	if !isArtKey(key) {
+		return nil, ErrArtNotCached
+	}
+	if img, ok := recentArt.get(key); ok {
+		return img, nil
+	}
+	if storage.MemoryOnly {
+		return nil, ErrArtNotCached
+	}
+	matches, err := filepath.Glob(filepath.Join(storage.dir(), "*-"+key+".*"))
+	if err != nil || len(matches) == 0 {
+		return nil, ErrArtNotCached
+	}
+	img, err := loadAlbumArtFile(matches[0], storage)
+	if err != nil {
+		return nil, err
+	}
+	recentArt.add(key, "", img, storage.memoryEntries())
+	return img, nil
+}
+
+// isArtKey reports whether key looks like an AlbumArtKey, which keeps
+// arbitrary request paths out of the art directory glob.
+func isArtKey(key string) bool {
+	if len(key) != 12 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+func albumArtPath(dir, room, signature, contentType string) (string, error) {
 	roomSlug := sanitizeForFilename(room)
 	if roomSlug == "" {
 		roomSlug = "room"
@@ -198,11 +506,9 @@ func albumArtPath(room, signature, contentType string) (string, error) {
 	if signature == "" {
 		return "", errors.New("album art signature empty")
 	}
-	hash := sha1.Sum([]byte(signature))
-	hashHex := hex.EncodeToString(hash[:6])
 	ext := extensionFromContentType(contentType)
-	filename := fmt.Sprintf("%s-%s.%s", roomSlug, hashHex, ext)
-	return filepath.Join("art", filename), nil
+	filename := fmt.Sprintf("%s-%s.%s", roomSlug, AlbumArtKey(signature), ext)
+	return filepath.Join(dir, filename), nil
 }
 
 func sanitizeForFilename(value string) string {
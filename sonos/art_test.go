@@ -0,0 +1,225 @@
+package sonos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedAlbumArt(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	key := AlbumArtKey("song|artist")
+	if len(key) != 12 {
+		t.Fatalf("AlbumArtKey = %q, want 12 hex digits", key)
+	}
+	if _, err := CachedAlbumArt(key, ArtStorage{}); !errors.Is(err, ErrArtNotCached) {
+		t.Fatalf("uncached art err = %v, want ErrArtNotCached", err)
+	}
+	if _, err := CachedAlbumArt("../../etc/pa", ArtStorage{}); !errors.Is(err, ErrArtNotCached) {
+		t.Fatalf("bad key err = %v, want ErrArtNotCached", err)
+	}
+
+	path, err := albumArtPath(DefaultArtDir, "Kitchen", "song|artist", "image/png")
+	if err != nil {
+		t.Fatalf("albumArtPath: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(file, image.NewNRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	file.Close()
+
+	img, err := CachedAlbumArt(key, ArtStorage{})
+	if err != nil || img.Bounds().Dx() != 64 {
+		t.Fatalf("disk art = %v, %v", img, err)
+	}
+
+	memKey := AlbumArtKey("memory only")
+	recentArt.add(memKey, "", image.NewNRGBA(image.Rect(0, 0, 8, 8)), defaultRecentArtSize)
+	if img, err := CachedAlbumArt(memKey, ArtStorage{MemoryOnly: true}); err != nil || img.Bounds().Dx() != 8 {
+		t.Fatalf("memory art = %v, %v", img, err)
+	}
+}
+
+func TestSaveAlbumArtFormats(t *testing.T) {
+	var source bytes.Buffer
+	if err := jpeg.Encode(&source, image.NewNRGBA(image.Rect(0, 0, 300, 300)), nil); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(source.Bytes())
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	track := TrackInfo{AlbumArtURI: "/getaa?u=1"}
+
+	cases := []struct {
+		storage ArtStorage
+		ext     string
+		check   func(data []byte) bool
+	}{
+		{ArtStorage{Format: ArtFormatPNG}, ".png", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\x89PNG")) }},
+		{ArtStorage{Format: ArtFormatJPEG, Quality: 50}, ".jpg", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\xff\xd8")) && len(data) < source.Len() }},
+		{ArtStorage{Format: ArtFormatOriginal}, ".jpg", func(data []byte) bool { return bytes.Equal(data, source.Bytes()) }},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.storage.Format), func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			fetches = 0
+			for i := 0; i < 2; i++ {
+				img, err := SaveAlbumArt(context.Background(), device, "Den", track, "sig-"+string(tc.storage.Format), true, tc.storage)
+				if err != nil || img.Bounds().Dx() != 64 {
+					t.Fatalf("SaveAlbumArt = %v, %v", img, err)
+				}
+			}
+			if fetches != 1 {
+				t.Fatalf("fetched %d times, want 1", fetches)
+			}
+			matches, _ := filepath.Glob(filepath.Join("art", "den-*"))
+			if len(matches) != 1 || filepath.Ext(matches[0]) != tc.ext {
+				t.Fatalf("cached files = %v, want one %s", matches, tc.ext)
+			}
+			data, err := os.ReadFile(matches[0])
+			if err != nil || !tc.check(data) {
+				t.Fatalf("unexpected cached bytes (%d, %v)", len(data), err)
+			}
+		})
+	}
+}
+
+func TestParseArtFormat(t *testing.T) {
+	for value, want := range map[string]ArtFormat{"": ArtFormatPNG, "JPG": ArtFormatJPEG, "original": ArtFormatOriginal} {
+		if got, err := ParseArtFormat(value); err != nil || got != want {
+			t.Fatalf("ParseArtFormat(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseArtFormat("webp"); err == nil {
+		t.Fatal("expected error for webp")
+	}
+}
+
+func TestSaveAlbumArtStorageModes(t *testing.T) {
+	var source bytes.Buffer
+	if err := png.Encode(&source, image.NewNRGBA(image.Rect(0, 0, 100, 100))); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(source.Bytes())
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	track := TrackInfo{AlbumArtURI: "/getaa?u=2"}
+	t.Chdir(t.TempDir())
+
+	memory := ArtStorage{MemoryOnly: true}
+	if _, err := SaveAlbumArt(context.Background(), device, "Den", track, "memory mode", true, memory); err != nil {
+		t.Fatalf("SaveAlbumArt memory: %v", err)
+	}
+	if _, err := os.Stat(DefaultArtDir); !os.IsNotExist(err) {
+		t.Fatalf("memory-only storage wrote to disk: %v", err)
+	}
+	if _, err := CachedAlbumArt(AlbumArtKey("memory mode"), memory); err != nil {
+		t.Fatalf("CachedAlbumArt memory: %v", err)
+	}
+
+	tmpfs := ArtStorage{Dir: filepath.Join(t.TempDir(), "shm")}
+	if _, err := SaveAlbumArt(context.Background(), device, "Den", track, "tmpfs mode", true, tmpfs); err != nil {
+		t.Fatalf("SaveAlbumArt dir: %v", err)
+	}
+	entries, err := os.ReadDir(tmpfs.Dir)
+	if err != nil || len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".png" {
+		t.Fatalf("art dir entries = %v, %v; want one png and no temporary files", entries, err)
+	}
+
+	recentArt.add("a", "", image.NewNRGBA(image.Rect(0, 0, 1, 1)), 2)
+	recentArt.add("b", "", image.NewNRGBA(image.Rect(0, 0, 1, 1)), 2)
+	recentArt.add("c", "", image.NewNRGBA(image.Rect(0, 0, 1, 1)), 2)
+	if _, ok := recentArt.get("a"); ok {
+		t.Fatal("memory cache kept more entries than its limit")
+	}
+}
+
+func TestArtMemoryKeepsRecentlyUsed(t *testing.T) {
+	m := newArtMemory()
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	m.add("a", "/getaa?u=a", img, 2)
+	m.add("b", "/getaa?u=b", img, 2)
+	if _, ok := m.get("a"); !ok {
+		t.Fatal("entry a missing")
+	}
+	m.add("c", "/getaa?u=c", img, 2)
+	if _, ok := m.get("b"); ok {
+		t.Fatal("least recently used entry b was kept")
+	}
+	if _, ok := m.getURI("/getaa?u=b"); ok {
+		t.Fatal("evicted entry still found by art URI")
+	}
+	if _, ok := m.get("a"); !ok {
+		t.Fatal("recently used entry a was evicted")
+	}
+	if _, ok := m.getURI("/getaa?u=c"); !ok {
+		t.Fatal("entry c not found by art URI")
+	}
+}
+
+func TestSaveAlbumArtReusesArtByURI(t *testing.T) {
+	var source bytes.Buffer
+	if err := png.Encode(&source, image.NewNRGBA(image.Rect(0, 0, 100, 100))); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(source.Bytes())
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	track := TrackInfo{AlbumArtURI: "/getaa?u=shared-album"}
+
+	for _, signature := range []string{"track one|album", "track two|album", "track one|album"} {
+		if _, err := SaveAlbumArt(context.Background(), device, "Den", track, signature, false, ArtStorage{}); err != nil {
+			t.Fatalf("SaveAlbumArt %q: %v", signature, err)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("fetches = %d, want 1", fetches)
+	}
+	if _, err := CachedAlbumArt(AlbumArtKey("track two|album"), ArtStorage{MemoryOnly: true}); err != nil {
+		t.Fatalf("CachedAlbumArt second track: %v", err)
+	}
+}
+
+func TestProcessAlbumArtSizes(t *testing.T) {
+	var source bytes.Buffer
+	if err := png.Encode(&source, image.NewNRGBA(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatalf("encode source: %v", err)
+	}
+	for size, want := range map[int]int{0: DefaultArtSize, 32: 32, 128: 128} {
+		img, err := ProcessAlbumArt(source.Bytes(), size, ArtProcessing{})
+		if err != nil {
+			t.Fatalf("ProcessAlbumArt(%d): %v", size, err)
+		}
+		if b := img.Bounds(); b.Dx() != want || b.Dy() != want {
+			t.Fatalf("ProcessAlbumArt(%d) = %v, want %dx%d", size, b, want, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package sonos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultMaxArtBytes bounds an album art download when ArtFetch.MaxBytes
+	// is 0. Cover art is rarely more than a few hundred kilobytes, but some
+	// stations serve multi-megabyte originals.
+	DefaultMaxArtBytes = 4 << 20
+	// DefaultMaxArtDimension bounds each side of album art when
+	// ArtFetch.MaxDimension is 0.
+	DefaultMaxArtDimension = 4096
+	// DefaultArtAttempts and DefaultArtDelay are how often, and how far
+	// apart, art is requested before it counts as missing, when
+	// ArtFetch.Attempts and ArtFetch.Delay are 0.
+	DefaultArtAttempts = 3
+	DefaultArtDelay    = 200 * time.Millisecond
+
+	// artRetryInitialDelay and artRetryMaxDelay space the deferred
+	// re-fetches of art not found yet.
+	artRetryInitialDelay = time.Second
+	artRetryMaxDelay     = 8 * time.Second
+)
+
+// ErrArtRejected reports album art refused before decoding: a download over
+// the size limit, data that is not an image, or an image with no pixels or
+// with a side over the dimension limit.
+var ErrArtRejected = errors.New("sonos: album art rejected")
+
+// ErrArtNotFound reports album art the speaker answered 404 for on every
+// attempt. Speakers serve AirPlay art a while after the track starts, so it
+// may still appear.
+var ErrArtNotFound = errors.New("sonos: album art not found")
+
+// ArtFetch limits the album art that is downloaded and decoded, and says how
+// hard to try for art that is not there yet.
+type ArtFetch struct {
+	// MaxBytes is the largest download accepted; 0 means DefaultMaxArtBytes.
+	MaxBytes int64
+	// MaxDimension is the longest side, in pixels, of an image accepted;
+	// 0 means DefaultMaxArtDimension.
+	MaxDimension int
+	// Attempts is how many times art is requested while the request fails
+	// or the speaker answers 404, Delay apart; 0 means DefaultArtAttempts
+	// and DefaultArtDelay.
+	Attempts int
+	Delay    time.Duration
+	// Grace, when positive, keeps the listener re-fetching art that was not
+	// found, with growing delays, while the track plays, until Grace after
+	// the first miss.
+	Grace time.Duration
+}
+
+func (f ArtFetch) attempts() int {
+	if f.Attempts > 0 {
+		return f.Attempts
+	}
+	return DefaultArtAttempts
+}
+
+func (f ArtFetch) delay() time.Duration {
+	if f.Delay > 0 {
+		return f.Delay
+	}
+	return DefaultArtDelay
+}
+
+func (f ArtFetch) maxBytes() int64 {
+	if f.MaxBytes > 0 {
+		return f.MaxBytes
+	}
+	return DefaultMaxArtBytes
+}
+
+func (f ArtFetch) maxDimension() int {
+	if f.MaxDimension > 0 {
+		return f.MaxDimension
+	}
+	return DefaultMaxArtDimension
+}
+
+// read reads the body of an art response, stopping as soon as it is over
+// the size limit.
+func (f ArtFetch) read(resp *http.Response) ([]byte, error) {
+	limit := f.maxBytes()
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes is over the %d byte limit", ErrArtRejected, resp.ContentLength, limit)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read album art body: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: over the %d byte limit", ErrArtRejected, limit)
+	}
+	return data, nil
+}
+
+// check reads only the image header of data, so a corrupt file or one whose
+// dimensions would need a huge allocation is refused before it is decoded.
+func (f ArtFetch) check(data []byte) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrArtRejected, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("%w: %s image is %dx%d", ErrArtRejected, format, cfg.Width, cfg.Height)
+	}
+	if limit := f.maxDimension(); cfg.Width > limit || cfg.Height > limit {
+		return fmt.Errorf("%w: %s image is %dx%d, over the %d pixel limit", ErrArtRejected, format, cfg.Width, cfg.Height, limit)
+	}
+	return nil
+}
+
+// blankArt is the black square shown in place of art that could not be
+// fetched when there is no placeholder.
+func blankArt(size int) image.Image {
+	if size <= 0 {
+		size = DefaultArtSize
+	}
+	return image.NewNRGBA(image.Rect(0, 0, size, size))
+}
@@ -0,0 +1,235 @@
+package sonos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+func TestSaveAlbumArtRejectsOversizedArt(t *testing.T) {
+	var small, wide bytes.Buffer
+	if err := png.Encode(&small, image.NewNRGBA(image.Rect(0, 0, 100, 100))); err != nil {
+		t.Fatalf("encode small: %v", err)
+	}
+	if err := png.Encode(&wide, image.NewNRGBA(image.Rect(0, 0, 600, 10))); err != nil {
+		t.Fatalf("encode wide: %v", err)
+	}
+	bodies := map[string][]byte{
+		"/getaa?u=small":   small.Bytes(),
+		"/getaa?u=wide":    wide.Bytes(),
+		"/getaa?u=corrupt": []byte("<html>not an image</html>"),
+		"/getaa?u=large":   bytes.Repeat([]byte{0}, 4096),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bodies[r.URL.RequestURI()])
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	storage := ArtStorage{Fetch: ArtFetch{MaxBytes: 2048, MaxDimension: 512}}
+
+	for _, tc := range []struct {
+		uri    string
+		reject bool
+	}{
+		{"/getaa?u=small", false},
+		{"/getaa?u=wide", true},
+		{"/getaa?u=corrupt", true},
+		{"/getaa?u=large", true},
+	} {
+		track := TrackInfo{AlbumArtURI: tc.uri}
+		img, err := SaveAlbumArt(context.Background(), device, "Den", track, "limits|"+tc.uri, false, storage)
+		if tc.reject {
+			if !errors.Is(err, ErrArtRejected) || img != nil {
+				t.Fatalf("%s: got %v, %v; want ErrArtRejected", tc.uri, img, err)
+			}
+			continue
+		}
+		if err != nil || img == nil {
+			t.Fatalf("%s: got %v, %v; want the art", tc.uri, img, err)
+		}
+	}
+}
+
+func TestListenForEventsBlanksArtThatFails(t *testing.T) {
+	callbacks := make(chan string, 1)
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "SUBSCRIBE" || r.Method == "UNSUBSCRIBE":
+			if callback := r.Header.Get("CALLBACK"); callback != "" {
+				callbacks <- strings.Trim(callback, "<>")
+			}
+			w.Header().Set("SID", "uuid:1")
+			w.Header().Set("TIMEOUT", "Second-1800")
+		case strings.HasPrefix(r.URL.Path, "/getaa"):
+			w.Write([]byte("corrupt"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer speaker.Close()
+
+	display := &showRecorder{shown: make(chan image.Image, 1)}
+	opts := ListenerOptions{
+		Clock:             clock.NewFake(time.Now()),
+		Display:           display,
+		PollFallbackAfter: -1,
+		ArtStorage:        ArtStorage{MemoryOnly: true, Size: 32},
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	const playing = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-file-cifs://nas/song.mp3&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot; xmlns:upnp=&quot;urn:schemas-upnp-org:metadata-1-0/upnp/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Broken Cover&lt;/dc:title&gt;&lt;upnp:albumArtURI&gt;/getaa?u=broken-cover&lt;/upnp:albumArtURI&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	req, err := http.NewRequest("NOTIFY", <-callbacks, strings.NewReader(playing))
+	if err != nil {
+		t.Fatalf("build notify: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send notify: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case img := <-display.shown:
+		if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+			t.Fatalf("shown %v, want 32x32 blank art", b)
+		}
+		if c := color.NRGBAModel.Convert(img.At(16, 16)).(color.NRGBA); c.R != 0 || c.G != 0 || c.B != 0 {
+			t.Fatalf("shown pixel %v, want black", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("nothing shown for a track whose art is corrupt")
+	}
+}
+
+func TestFetchAlbumArtRetriesMissingArt(t *testing.T) {
+	var art bytes.Buffer
+	if err := png.Encode(&art, image.NewNRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 3 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(art.Bytes())
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+
+	_, err := fetchAlbumArtBytes(context.Background(), nil, device, "/getaa?u=slow", ArtFetch{Attempts: 2, Delay: time.Millisecond})
+	if !errors.Is(err, ErrArtNotFound) || requests.Load() != 2 {
+		t.Fatalf("2 attempts: err %v after %d requests, want ErrArtNotFound after 2", err, requests.Load())
+	}
+	requests.Store(0)
+	data, err := fetchAlbumArtBytes(context.Background(), nil, device, "/getaa?u=slow", ArtFetch{Attempts: 5, Delay: time.Millisecond})
+	if err != nil || !bytes.Equal(data, art.Bytes()) || requests.Load() != 4 {
+		t.Fatalf("5 attempts: err %v after %d requests, want the art on the 4th", err, requests.Load())
+	}
+}
+
+func TestListenForEventsRefetchesArtWithinGrace(t *testing.T) {
+	var cover bytes.Buffer
+	white := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	if err := png.Encode(&cover, white); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var artAvailable atomic.Bool
+	callbacks := make(chan string, 1)
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "SUBSCRIBE" || r.Method == "UNSUBSCRIBE":
+			if callback := r.Header.Get("CALLBACK"); callback != "" {
+				callbacks <- strings.Trim(callback, "<>")
+			}
+			w.Header().Set("SID", "uuid:1")
+			w.Header().Set("TIMEOUT", "Second-1800")
+		case strings.HasPrefix(r.URL.Path, "/getaa") && artAvailable.Load():
+			w.Write(cover.Bytes())
+		case strings.HasPrefix(r.URL.Path, "/getaa"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer speaker.Close()
+
+	clk := clock.NewFake(time.Now())
+	display := &showRecorder{shown: make(chan image.Image, 1)}
+	opts := ListenerOptions{
+		Clock:             clk,
+		Display:           display,
+		PollFallbackAfter: -1,
+		ArtStorage:        ArtStorage{MemoryOnly: true, Size: 16, Fetch: ArtFetch{Attempts: 1, Grace: 30 * time.Second}},
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	// The art URI is new on every run, so art remembered in memory by an
+	// earlier run (go test -count) is not shown in place of the miss.
+	artURI := fmt.Sprintf("/getaa?u=slow-cover-%d", time.Now().UnixNano())
+	playing := `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-sonos-vli:RINCON_1:1,airplay:abc&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot; xmlns:upnp=&quot;urn:schemas-upnp-org:metadata-1-0/upnp/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Slow Cover&lt;/dc:title&gt;&lt;upnp:albumArtURI&gt;` + artURI + `&lt;/upnp:albumArtURI&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	req, err := http.NewRequest("NOTIFY", <-callbacks, strings.NewReader(playing))
+	if err != nil {
+		t.Fatalf("build notify: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send notify: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-display.shown:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("nothing shown for a track whose art is missing")
+	}
+	artAvailable.Store(true)
+	clk.Advance(artRetryInitialDelay)
+	select {
+	case img := <-display.shown:
+		if c := color.NRGBAModel.Convert(img.At(8, 8)).(color.NRGBA); c.R != 0xff {
+			t.Fatalf("shown pixel %v after the re-fetch, want the white cover", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("art not fetched again after it appeared")
+	}
+}
@@ -0,0 +1,200 @@
+package sonos
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// ArtScaler names the resampling filter used to shrink album art.
+type ArtScaler string
+
+const (
+	// ScalerBilinear is fast and a little soft. It is the default.
+	ScalerBilinear ArtScaler = "bilinear"
+	// ScalerCatmullRom is slower but keeps edges and text in artwork crisp
+	// at panel sizes.
+	ScalerCatmullRom ArtScaler = "catmull-rom"
+	// ScalerBox averages the source pixels under each panel pixel. It is
+	// much cheaper than the filters above when shrinking large art, which
+	// matters on single-core boards like the Pi Zero.
+	ScalerBox ArtScaler = "box"
+	// ScalerNearest picks one source pixel per panel pixel. It is the
+	// cheapest and suits pixel art, but aliases photos.
+	ScalerNearest ArtScaler = "nearest"
+)
+
+// DitherMode selects how processed art is reduced to the color depth of the
+// panel.
+type DitherMode string
+
+const (
+	// DitherNone rounds every channel to the nearest level. It is the
+	// default.
+	DitherNone DitherMode = "none"
+	// DitherOrdered adds a fixed 4x4 Bayer pattern, which stays still
+	// between frames and suits flat areas.
+	DitherOrdered DitherMode = "ordered"
+	// DitherFloydSteinberg spreads each pixel's rounding error to its
+	// neighbours, which keeps smooth gradients at low depths.
+	DitherFloydSteinberg DitherMode = "floyd-steinberg"
+)
+
+// DefaultDitherBits is the color depth per channel dithering targets when
+// ArtProcessing.DitherBits is 0.
+const DefaultDitherBits = 5
+
+// ArtProcessing tunes how album art is scaled for LED panels. The zero value
+// scales bilinearly and leaves the colors alone.
+type ArtProcessing struct {
+	Scaler ArtScaler
+	// Custom, when set, replaces the built-in scaler named by Scaler.
+	Custom Scaler
+	// Gamma, when above 0 and not 1, maps every channel through
+	// v^Gamma. LED panels are linear, so a gamma around 2.2 keeps midtones
+	// from looking washed out.
+	Gamma float64
+	// Dither reduces each channel to DitherBits bits (1..8, default
+	// DefaultDitherBits), spreading the rounding error so the low depth of
+	// a fast-refreshing panel shows as fine grain rather than banding.
+	Dither     DitherMode
+	DitherBits int
+}
+
+// ParseArtScaler parses "bilinear", "catmull-rom", "box", or "nearest". An
+// empty value selects bilinear.
+func ParseArtScaler(value string) (ArtScaler, error) {
+	switch scaler := ArtScaler(strings.ToLower(strings.TrimSpace(value))); scaler {
+	case "":
+		return ScalerBilinear, nil
+	case ScalerBilinear, ScalerCatmullRom, ScalerBox, ScalerNearest:
+		return scaler, nil
+	}
+	return "", fmt.Errorf("sonos: unknown art scaler %q (want bilinear, catmull-rom, box, or nearest)", value)
+}
+
+// ParseDitherMode parses "none", "ordered", or "floyd-steinberg". An empty
+// value selects none.
+func ParseDitherMode(value string) (DitherMode, error) {
+	switch mode := DitherMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return DitherNone, nil
+	case DitherNone, DitherOrdered, DitherFloydSteinberg:
+		return mode, nil
+	}
+	return "", fmt.Errorf("sonos: unknown dither mode %q (want none, ordered, or floyd-steinberg)", value)
+}
+
+// Validate reports settings ProcessAlbumArt cannot use.
+func (p ArtProcessing) Validate() error {
+	if _, err := ParseArtScaler(string(p.Scaler)); err != nil {
+		return err
+	}
+	if _, err := ParseDitherMode(string(p.Dither)); err != nil {
+		return err
+	}
+	if p.Gamma < 0 || p.Gamma > 4 {
+		return fmt.Errorf("sonos: gamma must be between 0 and 4, got %g", p.Gamma)
+	}
+	if p.DitherBits < 0 || p.DitherBits > 8 {
+		return fmt.Errorf("sonos: dither bits must be between 1 and 8, got %d", p.DitherBits)
+	}
+	return nil
+}
+
+// scale draws src into a new size x size image with p's scaler.
+func (p ArtProcessing) scale(src image.Image, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scaler := p.Custom
+	if scaler == nil {
+		scaler, _ = LookupScaler(p.Scaler)
+	}
+	scaler.Scale(dst, src)
+	return dst
+}
+
+// adjust applies p's gamma and dithering to img in place.
+func (p ArtProcessing) adjust(img *image.NRGBA) {
+	if p.Gamma > 0 && p.Gamma != 1 {
+		var table [256]uint8
+		for v := range table {
+			table[v] = uint8(math.Round(255 * math.Pow(float64(v)/255, p.Gamma)))
+		}
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i] = table[img.Pix[i]]
+			img.Pix[i+1] = table[img.Pix[i+1]]
+			img.Pix[i+2] = table[img.Pix[i+2]]
+		}
+	}
+
+	bits := p.DitherBits
+	if bits == 0 {
+		bits = DefaultDitherBits
+	}
+	switch p.Dither {
+	case DitherOrdered:
+		ditherOrdered(img, bits)
+	case DitherFloydSteinberg:
+		ditherFloydSteinberg(img, bits)
+	}
+}
+
+// bayer4 is the 4x4 ordered dither matrix.
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// quantize rounds v to the nearest of the levels evenly spaced over 0..255.
+func quantize(v float64, levels int) uint8 {
+	step := 255 / float64(levels-1)
+	q := math.Round(v/step) * step
+	return uint8(math.Max(0, math.Min(255, math.Round(q))))
+}
+
+func ditherOrdered(img *image.NRGBA, bits int) {
+	levels := 1 << bits
+	step := 255 / float64(levels-1)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			offset := ((bayer4[y&3][x&3]+0.5)/16 - 0.5) * step
+			i := img.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				img.Pix[i+c] = quantize(float64(img.Pix[i+c])+offset, levels)
+			}
+		}
+	}
+}
+
+func ditherFloydSteinberg(img *image.NRGBA, bits int) {
+	levels := 1 << bits
+	b := img.Bounds()
+	width := b.Dx()
+	// Two rows of accumulated error per channel: the current and the next.
+	cur := make([]float64, (width+2)*3)
+	next := make([]float64, (width+2)*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := 0; x < width; x++ {
+			i := img.PixOffset(b.Min.X+x, y)
+			for c := 0; c < 3; c++ {
+				e := (x+1)*3 + c
+				want := float64(img.Pix[i+c]) + cur[e]
+				got := quantize(want, levels)
+				img.Pix[i+c] = got
+				diff := want - float64(got)
+				cur[e+3] += diff * 7 / 16
+				next[e-3] += diff * 3 / 16
+				next[e] += diff * 5 / 16
+				next[e+3] += diff * 1 / 16
+			}
+		}
+		cur, next = next, cur
+		for k := range next {
+			next[k] = 0
+		}
+	}
+}
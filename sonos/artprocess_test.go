@@ -0,0 +1,94 @@
+package sonos
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func grayArt(t *testing.T, level uint8) []byte {
+	t.Helper()
+	src := image.NewNRGBA(image.Rect(0, 0, 80, 80))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.NRGBA{R: level, G: level, B: level, A: 0xff}), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArtProcessingGammaAndDither(t *testing.T) {
+	data := grayArt(t, 128)
+
+	img, err := ProcessAlbumArt(data, 16, ArtProcessing{Scaler: ScalerCatmullRom, Gamma: 2.2})
+	if err != nil {
+		t.Fatalf("ProcessAlbumArt: %v", err)
+	}
+	if got := img.(*image.NRGBA).NRGBAAt(8, 8).R; got < 50 || got > 60 {
+		t.Fatalf("gamma 2.2 mid grey = %d, want about 56", got)
+	}
+
+	for _, mode := range []DitherMode{DitherOrdered, DitherFloydSteinberg} {
+		img, err := ProcessAlbumArt(data, 16, ArtProcessing{Dither: mode, DitherBits: 2})
+		if err != nil {
+			t.Fatalf("ProcessAlbumArt %s: %v", mode, err)
+		}
+		nrgba := img.(*image.NRGBA)
+		sum := 0
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				v := nrgba.NRGBAAt(x, y).R
+				if v%85 != 0 {
+					t.Fatalf("%s left level %d, want a 2-bit level", mode, v)
+				}
+				sum += int(v)
+			}
+		}
+		// The grain should average out to the original grey.
+		if mean := sum / 256; mean < 118 || mean > 138 {
+			t.Fatalf("%s mean = %d, want about 128", mode, mean)
+		}
+	}
+
+	for _, bad := range []ArtProcessing{{Scaler: "lanczos"}, {Dither: "random"}, {Gamma: -1}, {DitherBits: 9}} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestSaveAlbumArtStoresArtBeforeGamma(t *testing.T) {
+	data := grayArt(t, 128)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	storage := ArtStorage{Dir: t.TempDir(), Processing: ArtProcessing{Gamma: 2.2}}
+	track := TrackInfo{AlbumArtURI: "/getaa?u=gamma"}
+
+	shown, err := SaveAlbumArt(context.Background(), device, "Den", track, "gamma|track", true, storage)
+	if err != nil {
+		t.Fatalf("SaveAlbumArt: %v", err)
+	}
+	recentArt.clear()
+	path, err := cachedAlbumArtFile(storage.Dir, "Den", "gamma|track")
+	if err != nil || path == "" {
+		t.Fatalf("cached file = %q, %v", path, err)
+	}
+	reloaded, err := loadAlbumArtFile(path, storage)
+	if err != nil {
+		t.Fatalf("loadAlbumArtFile: %v", err)
+	}
+	want := color.NRGBAModel.Convert(shown.At(5, 5))
+	if got := color.NRGBAModel.Convert(reloaded.At(5, 5)); got != want {
+		t.Fatalf("reloaded pixel = %+v, want %+v as first shown", got, want)
+	}
+}
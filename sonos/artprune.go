@@ -0,0 +1,141 @@
+package sonos
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArtLimits bounds the disk art cache. Zero fields are unlimited.
+type ArtLimits struct {
+	MaxBytes int64
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// IsZero reports whether l sets no limit at all.
+func (l ArtLimits) IsZero() bool {
+	return l.MaxBytes <= 0 && l.MaxFiles <= 0 && l.MaxAge <= 0
+}
+
+// PruneResult reports what PruneArt or PurgeArt removed.
+type PruneResult struct {
+	Files int
+	Bytes int64
+}
+
+type artFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// PruneArt removes cached artwork from storage's directory until it is
+// within limits: first files older than MaxAge, then the oldest files until
+// at most MaxFiles remain and they total at most MaxBytes. Only files named
+// like cached art are touched. A missing directory is an empty cache.
+func PruneArt(storage ArtStorage, limits ArtLimits, now time.Time) (PruneResult, error) {
+	var result PruneResult
+	if storage.MemoryOnly || limits.IsZero() {
+		return result, nil
+	}
+	files, err := artFiles(storage.dir())
+	if err != nil {
+		return result, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	var errs []error
+	remaining := len(files)
+	for _, f := range files {
+		expired := limits.MaxAge > 0 && now.Sub(f.modTime) > limits.MaxAge
+		tooMany := limits.MaxFiles > 0 && remaining > limits.MaxFiles
+		tooBig := limits.MaxBytes > 0 && total > limits.MaxBytes
+		if !expired && !tooMany && !tooBig {
+			// Files are oldest first, so the rest are newer and the
+			// count and size only shrink from here.
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		remaining--
+		total -= f.size
+		result.Files++
+		result.Bytes += f.size
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("sonos: prune album art: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// PurgeArt empties the art cache: the processed images kept in memory and,
+// unless storage is MemoryOnly, every cached file in its directory. The
+// result counts the files removed.
+func PurgeArt(storage ArtStorage) (PruneResult, error) {
+	recentArt.clear()
+	var result PruneResult
+	if storage.MemoryOnly {
+		return result, nil
+	}
+	files, err := artFiles(storage.dir())
+	if err != nil {
+		return result, err
+	}
+	var errs []error
+	for _, f := range files {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		result.Files++
+		result.Bytes += f.size
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("sonos: purge album art: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// artFiles lists the cached art in dir, skipping anything else that may
+// live there, such as a half-written temporary file.
+func artFiles(dir string) ([]artFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sonos: list album art: %w", err)
+	}
+	var files []artFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isArtFileName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, artFile{path: filepath.Join(dir, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// isArtFileName reports whether name has the <room>-<key>.<ext> form
+// albumArtPath produces.
+func isArtFileName(name string) bool {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	idx := strings.LastIndex(stem, "-")
+	return idx > 0 && stem != name && isArtKey(stem[idx+1:])
+}
@@ -0,0 +1,66 @@
+package sonos
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneArtEnforcesLimits(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	write := func(signature string, size int, age time.Duration) string {
+		t.Helper()
+		path, err := albumArtPath(dir, "Den", signature, "image/png")
+		if err != nil {
+			t.Fatalf("albumArtPath: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+		return path
+	}
+	ancient := write("ancient", 100, 40*24*time.Hour)
+	old := write("old", 100, 3*time.Hour)
+	recent := write("recent", 100, 2*time.Hour)
+	newest := write("newest", 100, time.Hour)
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	storage := ArtStorage{Dir: dir}
+	result, err := PruneArt(storage, ArtLimits{MaxAge: 30 * 24 * time.Hour, MaxFiles: 3, MaxBytes: 250}, now)
+	if err != nil {
+		t.Fatalf("PruneArt: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != 200 {
+		t.Fatalf("pruned %+v, want 2 files of 200 bytes", result)
+	}
+	for path, want := range map[string]bool{ancient: false, old: false, recent: true, newest: true, other: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Fatalf("%s kept = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+
+	if result, err := PruneArt(ArtStorage{Dir: filepath.Join(dir, "missing")}, ArtLimits{MaxFiles: 1}, now); err != nil || result.Files != 0 {
+		t.Fatalf("missing dir = %+v, %v", result, err)
+	}
+
+	recentArt.add(AlbumArtKey("recent"), "", image.NewNRGBA(image.Rect(0, 0, 1, 1)), defaultRecentArtSize)
+	result, err = PurgeArt(storage)
+	if err != nil || result.Files != 2 {
+		t.Fatalf("PurgeArt = %+v, %v; want 2 files", result, err)
+	}
+	if _, err := CachedAlbumArt(AlbumArtKey("recent"), storage); err != ErrArtNotCached {
+		t.Fatalf("purged art still cached: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("purge removed an unrelated file: %v", err)
+	}
+}
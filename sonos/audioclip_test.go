@@ -0,0 +1,115 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+// audioClipServer starts a TLS websocket server standing in for a speaker's
+// local API and points PlayAudioClip at it. reply answers each command.
+func audioClipServer(t *testing.T, reply func(header map[string]string, body map[string]any) []any) Device {
+	t.Helper()
+	server := httptest.NewTLSServer(websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if r.Header.Get("X-Sonos-Api-Key") != audioClipAPIKey {
+				return errors.New("missing api key")
+			}
+			if len(config.Protocol) != 1 || config.Protocol[0] != audioClipProtocol {
+				return errors.New("missing protocol")
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			var msg []map[string]any
+			if err := websocket.JSON.Receive(conn, &msg); err != nil || len(msg) != 2 {
+				return
+			}
+			header := map[string]string{}
+			for k, v := range msg[0] {
+				header[k], _ = v.(string)
+			}
+			// An unrelated event goes first, as a speaker may send one.
+			_ = websocket.JSON.Send(conn, []any{map[string]any{"namespace": "audioClip:1", "name": "audioClipStatus"}, map[string]any{}})
+			_ = websocket.JSON.Send(conn, reply(header, msg[1]))
+		},
+	})
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	previous := audioClipPort
+	audioClipPort = port
+	t.Cleanup(func() { audioClipPort = previous })
+	return Device{
+		Location: "http://" + host + ":1400/xml/device_description.xml",
+		USN:      "uuid:RINCON_000E58A0123401400::urn:schemas-upnp-org:device:ZonePlayer:1",
+	}
+}
+
+func TestPlayAudioClip(t *testing.T) {
+	var gotHeader map[string]string
+	var gotBody map[string]any
+	device := audioClipServer(t, func(header map[string]string, body map[string]any) []any {
+		gotHeader, gotBody = header, body
+		return []any{map[string]any{"namespace": "audioClip:1", "response": "loadAudioClip", "cmdId": header["cmdId"], "success": true}, map[string]any{"_objectType": "audioClip", "status": "ACTIVE"}}
+	})
+
+	err := PlayAudioClip(context.Background(), device, AudioClip{URL: "http://10.0.0.2/ding.mp3", Volume: 150, Priority: "high"})
+	if err != nil {
+		t.Fatalf("PlayAudioClip error: %v", err)
+	}
+	if gotHeader["command"] != "loadAudioClip" || gotHeader["playerId"] != "RINCON_000E58A0123401400" {
+		t.Fatalf("header = %v", gotHeader)
+	}
+	if gotBody["clipType"] != "CUSTOM" || gotBody["streamUrl"] != "http://10.0.0.2/ding.mp3" || gotBody["volume"] != float64(100) || gotBody["priority"] != ClipPriorityHigh {
+		t.Fatalf("body = %v", gotBody)
+	}
+
+	if err := PlayAudioClip(context.Background(), device, AudioClip{}); err != nil {
+		t.Fatalf("PlayAudioClip chime error: %v", err)
+	}
+	if gotBody["clipType"] != "CHIME" || gotBody["streamUrl"] != nil || gotBody["volume"] != nil || gotBody["priority"] != ClipPriorityLow {
+		t.Fatalf("chime body = %v", gotBody)
+	}
+}
+
+func TestPlayAudioClipReportsRejection(t *testing.T) {
+	code := "ERROR_UNSUPPORTED_NAMESPACE"
+	device := audioClipServer(t, func(header map[string]string, body map[string]any) []any {
+		return []any{map[string]any{"cmdId": header["cmdId"], "success": false}, map[string]any{"_objectType": "globalError", "errorCode": code}}
+	})
+	if err := PlayAudioClip(context.Background(), device, AudioClip{}); !errors.Is(err, ErrAudioClipUnsupported) {
+		t.Fatalf("unsupported namespace error = %v", err)
+	}
+	code = "ERROR_INVALID_PARAMETER"
+	if err := PlayAudioClip(context.Background(), device, AudioClip{}); err == nil || errors.Is(err, ErrAudioClipUnsupported) {
+		t.Fatalf("invalid parameter error = %v", err)
+	}
+}
+
+func TestPlayAudioClipWithoutLocalAPI(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	previous := audioClipPort
+	audioClipPort = port
+	defer func() { audioClipPort = previous }()
+
+	device := Device{IP: "127.0.0.1", Metadata: DeviceMetadata{UDN: "uuid:RINCON_1"}}
+	if err := PlayAudioClip(context.Background(), device, AudioClip{}); !errors.Is(err, ErrAudioClipUnsupported) {
+		t.Fatalf("error = %v, want ErrAudioClipUnsupported", err)
+	}
+	if err := PlayAudioClip(context.Background(), Device{IP: "127.0.0.1"}, AudioClip{}); err == nil {
+		t.Fatal("expected an error without a player id")
+	}
+}
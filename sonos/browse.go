@@ -0,0 +1,196 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+const contentDirectoryService = "urn:schemas-upnp-org:service:ContentDirectory:1"
+
+// Containers of the ContentDirectory service.
+const (
+	// FavoritesID is the container of the household's Sonos favorites.
+	FavoritesID = "FV:2"
+	// QueueID is the container of the room's queue.
+	QueueID = "Q:0"
+)
+
+// browsePage is how many entries one Browse call asks for; the speakers
+// return at most 100.
+const browsePage = 100
+
+// Item is an entry of a ContentDirectory container, such as a favorite or
+// a queued track.
+type Item struct {
+	ID          string
+	Title       string
+	Creator     string
+	Album       string
+	Class       string
+	AlbumArtURI string
+	// URI is what the speaker plays for the item.
+	URI string
+	// Metadata is the DIDL-Lite document to pass along with URI: the
+	// favorite's own metadata for a favorite, empty for other items.
+	Metadata string
+	// Description is the favorite's kind as the Sonos app shows it, such
+	// as "TuneIn Station" or "Spotify Playlist".
+	Description string
+}
+
+// container reports whether the item is a collection, such as a playlist
+// or an album, that plays through the queue rather than directly.
+func (i Item) container() bool {
+	for _, prefix := range []string{"x-rincon-cpcontainer:", "x-rincon-playlist:", "file:///jffs/settings/savedqueues.rsq"} {
+		if strings.HasPrefix(i.URI, prefix) {
+			return true
+		}
+	}
+	meta, err := parseTrackMetadata(sanitizeInvalidEntities(i.Metadata))
+	return err == nil && strings.HasPrefix(strings.TrimSpace(meta.Class), "object.container")
+}
+
+// BrowseFavorites lists the household's Sonos favorites, in the order the
+// Sonos app shows them. Any speaker of the household can be asked.
+func BrowseFavorites(ctx context.Context, device Device) ([]Item, error) {
+	return Browse(ctx, device, FavoritesID)
+}
+
+// BrowseQueue lists the tracks queued in the room coordinated by device.
+func BrowseQueue(ctx context.Context, device Device) ([]Item, error) {
+	return Browse(ctx, device, QueueID)
+}
+
+// Browse lists the items of the ContentDirectory container id, such as
+// FavoritesID or QueueID, a page at a time.
+func Browse(ctx context.Context, device Device, id string) ([]Item, error) {
+	var items []Item
+	for {
+		page, total, err := browseRange(ctx, device, id, len(items), browsePage)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(page) == 0 || len(items) >= total {
+			return items, nil
+		}
+	}
+}
+
+// browseRange lists up to count items of the container id from index
+// start, counting from 0, and returns how many the container holds in all.
+func browseRange(ctx context.Context, device Device, id string, start, count int) ([]Item, int, error) {
+	controlURL, err := contentDirectoryControlURL(device)
+	if err != nil {
+		return nil, 0, err
+	}
+	args := "\n      <ObjectID>" + html.EscapeString(id) + "</ObjectID>" +
+		"\n      <BrowseFlag>BrowseDirectChildren</BrowseFlag>" +
+		"\n      <Filter>*</Filter>" +
+		"\n      <StartingIndex>" + strconv.Itoa(start) + "</StartingIndex>" +
+		"\n      <RequestedCount>" + strconv.Itoa(count) + "</RequestedCount>" +
+		"\n      <SortCriteria></SortCriteria>"
+	body, err := invokeAction(ctx, TimeoutsFor(device).Control, controlURL, contentDirectoryService, "Browse", args)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseBrowseResponse(body)
+}
+
+// parseBrowseResponse returns the items of a Browse response and how many
+// the container holds in all.
+func parseBrowseResponse(body []byte) ([]Item, int, error) {
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				Result       string `xml:"Result"`
+				TotalMatches int    `xml:"TotalMatches"`
+			} `xml:"BrowseResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, 0, fmt.Errorf("sonos: decode browse response: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return nil, 0, errors.New("sonos: empty browse response")
+	}
+	items, err := parseDIDLItems(envelope.Body.Response.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, envelope.Body.Response.TotalMatches, nil
+}
+
+// parseDIDLItems parses the items of a DIDL-Lite document. Items and
+// containers are both listed; favorites of either kind are items.
+func parseDIDLItems(didl string) ([]Item, error) {
+	if strings.TrimSpace(didl) == "" {
+		return nil, nil
+	}
+	type entry struct {
+		ID          string `xml:"id,attr"`
+		Title       string `xml:"title"`
+		Creator     string `xml:"creator"`
+		Album       string `xml:"album"`
+		Class       string `xml:"class"`
+		AlbumArtURI string `xml:"albumArtURI"`
+		Res         string `xml:"res"`
+		ResMD       string `xml:"resMD"`
+		Description string `xml:"description"`
+	}
+	var doc struct {
+		Entries []entry `xml:",any"`
+	}
+	if err := xml.Unmarshal([]byte(sanitizeInvalidEntities(didl)), &doc); err != nil {
+		return nil, fmt.Errorf("sonos: parse browse result: %w", err)
+	}
+	items := make([]Item, 0, len(doc.Entries))
+	for _, e := range doc.Entries {
+		items = append(items, Item{
+			ID:          e.ID,
+			Title:       strings.TrimSpace(e.Title),
+			Creator:     strings.TrimSpace(e.Creator),
+			Album:       strings.TrimSpace(e.Album),
+			Class:       strings.TrimSpace(e.Class),
+			AlbumArtURI: strings.TrimSpace(e.AlbumArtURI),
+			URI:         strings.TrimSpace(e.Res),
+			Metadata:    strings.TrimSpace(e.ResMD),
+			Description: strings.TrimSpace(e.Description),
+		})
+	}
+	return items, nil
+}
+
+// PlayFavorite starts favorite, as listed by BrowseFavorites, in the room
+// coordinated by device. Stations and tracks play directly; playlists and
+// albums replace the room's queue.
+func PlayFavorite(ctx context.Context, device Device, favorite Item) error {
+	if favorite.URI == "" {
+		return fmt.Errorf("sonos: favorite %q has nothing to play", favorite.Title)
+	}
+	if !favorite.container() {
+		if err := SetAVTransportURI(ctx, device, favorite.URI, favorite.Metadata); err != nil {
+			return err
+		}
+		return Play(ctx, device)
+	}
+	queue, err := QueueURI(device)
+	if err != nil {
+		return err
+	}
+	if err := ClearQueue(ctx, device); err != nil {
+		return err
+	}
+	if _, err := AddURIToQueue(ctx, device, favorite.URI, favorite.Metadata, false); err != nil {
+		return err
+	}
+	if err := SetAVTransportURI(ctx, device, queue, ""); err != nil {
+		return err
+	}
+	return Play(ctx, device)
+}
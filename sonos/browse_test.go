@@ -0,0 +1,137 @@
+package sonos
+
+import (
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const favoritesDIDL = `<DIDL-Lite xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:r="urn:schemas-rinconnetworks-com:metadata-1-0/" xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/">` +
+	`<item id="FV:2/5" parentID="FV:2" restricted="false"><dc:title>Radio Paradise</dc:title><upnp:class>object.itemobject.item.sonos-favorite</upnp:class><r:ordinal>0</r:ordinal>` +
+	`<res protocolInfo="x-sonosapi-stream:*:*:*">x-sonosapi-stream:s13606?sid=254&amp;flags=8224&amp;sn=0</res><upnp:albumArtURI>https://cdn-profiles.tunein.com/s13606/images/logoq.png</upnp:albumArtURI><r:type>instantPlay</r:type><r:description>TuneIn Station</r:description>` +
+	`<r:resMD>&lt;DIDL-Lite xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot; xmlns:upnp=&quot;urn:schemas-upnp-org:metadata-1-0/upnp/&quot; xmlns:r=&quot;urn:schemas-rinconnetworks-com:metadata-1-0/&quot; xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot;&gt;&lt;item id=&quot;F00092020s13606&quot; parentID=&quot;L&quot; restricted=&quot;true&quot;&gt;&lt;dc:title&gt;Radio Paradise&lt;/dc:title&gt;&lt;upnp:class&gt;object.item.audioItem.audioBroadcast&lt;/upnp:class&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</r:resMD></item>` +
+	`<item id="FV:2/7" parentID="FV:2" restricted="false"><dc:title>Morning Mix</dc:title><upnp:class>object.itemobject.item.sonos-favorite</upnp:class>` +
+	`<res protocolInfo="x-rincon-cpcontainer:*:*:*">x-rincon-cpcontainer:1006206cspotify%3aplaylist%3a37i9dQZF1DX?sid=9&amp;flags=8300&amp;sn=2</res><r:description>Spotify Playlist</r:description>` +
+	`<r:resMD>&lt;DIDL-Lite xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot; xmlns:upnp=&quot;urn:schemas-upnp-org:metadata-1-0/upnp/&quot; xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot;&gt;&lt;item id=&quot;1006206cspotify%3aplaylist%3a37i9dQZF1DX&quot; parentID=&quot;10fe2664playlists&quot; restricted=&quot;true&quot;&gt;&lt;dc:title&gt;Morning Mix&lt;/dc:title&gt;&lt;upnp:class&gt;object.container.playlistContainer&lt;/upnp:class&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</r:resMD></item>` +
+	`</DIDL-Lite>`
+
+// browseResponse wraps didl in a Browse response.
+func browseResponse(didl string, returned, total int) string {
+	return `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:BrowseResponse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">` +
+		`<Result>` + html.EscapeString(didl) + `</Result><NumberReturned>` + strconv.Itoa(returned) + `</NumberReturned><TotalMatches>` + strconv.Itoa(total) + `</TotalMatches><UpdateID>1</UpdateID>` +
+		`</u:BrowseResponse></s:Body></s:Envelope>`
+}
+
+func TestBrowseFavorites(t *testing.T) {
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/MediaServer/ContentDirectory/Control" {
+			t.Fatalf("path = %q", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		io.WriteString(w, browseResponse(favoritesDIDL, 2, 2))
+	}))
+	defer server.Close()
+
+	favorites, err := BrowseFavorites(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"})
+	if err != nil {
+		t.Fatalf("BrowseFavorites error: %v", err)
+	}
+	if !strings.Contains(gotBody, "<ObjectID>FV:2</ObjectID>") || !strings.Contains(gotBody, "<BrowseFlag>BrowseDirectChildren</BrowseFlag>") {
+		t.Fatalf("unexpected body: %s", gotBody)
+	}
+	if len(favorites) != 2 {
+		t.Fatalf("got %d favorites, want 2", len(favorites))
+	}
+	radio := favorites[0]
+	if radio.Title != "Radio Paradise" || radio.URI != "x-sonosapi-stream:s13606?sid=254&flags=8224&sn=0" || radio.Description != "TuneIn Station" {
+		t.Fatalf("radio = %+v", radio)
+	}
+	if !strings.HasPrefix(radio.Metadata, "<DIDL-Lite") || radio.container() {
+		t.Fatalf("radio metadata = %q", radio.Metadata)
+	}
+	if !favorites[1].container() {
+		t.Fatal("playlist favorite not played through the queue")
+	}
+}
+
+func TestBrowsePages(t *testing.T) {
+	item := func(id string) string {
+		return `<item id="Q:0/` + id + `"><dc:title>Track ` + id + `</dc:title><dc:creator>Artist</dc:creator><upnp:album>Album</upnp:album><res>x-file-cifs://nas/` + id + `.flac</res></item>`
+	}
+	didl := func(items ...string) string {
+		return `<DIDL-Lite xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/">` + strings.Join(items, "") + `</DIDL-Lite>`
+	}
+	start := regexp.MustCompile(`<StartingIndex>(\d+)</StartingIndex>`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "<ObjectID>Q:0</ObjectID>") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+		switch start.FindStringSubmatch(string(body))[1] {
+		case "0":
+			io.WriteString(w, browseResponse(didl(item("1"), item("2")), 2, 3))
+		case "2":
+			io.WriteString(w, browseResponse(didl(item("3")), 1, 3))
+		default:
+			t.Fatalf("unexpected page: %s", body)
+		}
+	}))
+	defer server.Close()
+
+	queue, err := BrowseQueue(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"})
+	if err != nil {
+		t.Fatalf("BrowseQueue error: %v", err)
+	}
+	if len(queue) != 3 || queue[2].Title != "Track 3" || queue[0].Creator != "Artist" || queue[0].Album != "Album" || queue[1].URI != "x-file-cifs://nas/2.flac" {
+		t.Fatalf("queue = %+v", queue)
+	}
+}
+
+func TestPlayFavorite(t *testing.T) {
+	var actions []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := r.Header.Get("SOAPACTION")
+		actions = append(actions, action[strings.Index(action, "#")+1:len(action)-1])
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if strings.Contains(action, "AddURIToQueue") {
+			io.WriteString(w, addToQueueResponse(1))
+		}
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml", Metadata: DeviceMetadata{UDN: "uuid:RINCON_000E58A0123401400"}}
+	favorites, err := parseDIDLItems(favoritesDIDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PlayFavorite(context.Background(), device, favorites[0]); err != nil {
+		t.Fatalf("PlayFavorite error: %v", err)
+	}
+	if strings.Join(actions, ",") != "SetAVTransportURI,Play" {
+		t.Fatalf("station actions = %v", actions)
+	}
+	if !strings.Contains(bodies[0], "<CurrentURI>x-sonosapi-stream:s13606?sid=254&amp;flags=8224&amp;sn=0</CurrentURI>") || !strings.Contains(bodies[0], "&lt;DIDL-Lite") {
+		t.Fatalf("unexpected body: %s", bodies[0])
+	}
+
+	actions, bodies = nil, nil
+	if err := PlayFavorite(context.Background(), device, favorites[1]); err != nil {
+		t.Fatalf("PlayFavorite error: %v", err)
+	}
+	if strings.Join(actions, ",") != "RemoveAllTracksFromQueue,AddURIToQueue,SetAVTransportURI,Play" {
+		t.Fatalf("playlist actions = %v", actions)
+	}
+	if !strings.Contains(bodies[2], "<CurrentURI>x-rincon-queue:RINCON_000E58A0123401400#0</CurrentURI>") {
+		t.Fatalf("unexpected body: %s", bodies[2])
+	}
+}
@@ -0,0 +1,139 @@
+package sonos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	avTransportService      = "urn:schemas-upnp-org:service:AVTransport:1"
+	renderingControlService = "urn:schemas-upnp-org:service:RenderingControl:1"
+)
+
+// Next skips the room coordinated by device to the next track in its queue.
+// Sources without a queue, such as radio or line-in, reject it.
+func Next(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "Next", "")
+	return err
+}
+
+// Previous returns the room coordinated by device to the previous track.
+func Previous(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "Previous", "")
+	return err
+}
+
+// Play resumes playback in the room coordinated by device.
+func Play(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "Play", "\n      <Speed>1</Speed>")
+	return err
+}
+
+// Pause pauses playback in the room coordinated by device.
+func Pause(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "Pause", "")
+	return err
+}
+
+// Stop stops playback in the room coordinated by device.
+func Stop(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "Stop", "")
+	return err
+}
+
+// AdjustVolume changes the speaker volume of device by delta and returns the
+// new volume (0..100).
+func AdjustVolume(ctx context.Context, device Device, delta int) (int, error) {
+	controlURL, err := renderingControlURL(device)
+	if err != nil {
+		return 0, err
+	}
+	args := fmt.Sprintf("\n      <Channel>Master</Channel>\n      <Adjustment>%d</Adjustment>", delta)
+	body, err := callService(ctx, TimeoutsFor(device).Control, controlURL, renderingControlService, "SetRelativeVolume", args)
+	if err != nil {
+		return 0, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				NewVolume int `xml:"NewVolume"`
+			} `xml:"SetRelativeVolumeResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return 0, fmt.Errorf("sonos: decode volume: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return 0, errors.New("sonos: empty volume response")
+	}
+	return envelope.Body.Response.NewVolume, nil
+}
+
+// callAVTransport invokes action on the device's AVTransport service.
+func callAVTransport(ctx context.Context, device Device, action, args string) ([]byte, error) {
+	controlURL, err := avTransportControlURL(device)
+	if err != nil {
+		return nil, err
+	}
+	return callService(ctx, TimeoutsFor(device).Control, controlURL, avTransportService, action, args)
+}
+
+// callService invokes action on the UPnP service at controlURL, giving up
+// after timeout. args holds the action's arguments as XML elements, after
+// InstanceID. It returns the response body.
+func callService(ctx context.Context, timeout time.Duration, controlURL, service, action, args string) ([]byte, error) {
+	return invokeAction(ctx, timeout, controlURL, service, action, "\n      <InstanceID>0</InstanceID>"+args)
+}
+
+// invokeAction posts action with exactly the argument elements in args, for
+// services such as ZoneGroupTopology whose actions take no InstanceID.
+func invokeAction(ctx context.Context, timeout time.Duration, controlURL, service, action, args string) ([]byte, error) {
+	if ctx == nil {
+		return nil, errors.New("sonos: nil context")
+	}
+
+	payload := `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:` + action + ` xmlns:u="` + service + `">` + args + `
+    </u:` + action + `>
+  </s:Body>
+</s:Envelope>`
+
+	logger.Debug("calling service action", "action", action, "url", controlURL)
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, strings.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("sonos: create %s request: %w", action, err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPACTION", `"`+service+`#`+action+`"`)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sonos: %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("sonos: read %s body: %w", action, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		snippet := string(bytes.TrimSpace(body))
+		if len(snippet) > 256 {
+			snippet = snippet[:256]
+		}
+		return nil, fmt.Errorf("sonos: %s http status %s: %s", action, resp.Status, snippet)
+	}
+	return body, nil
+}
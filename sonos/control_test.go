@@ -0,0 +1,69 @@
+package sonos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNextSendsSOAPAction(t *testing.T) {
+	var gotAction, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/MediaRenderer/AVTransport/Control" {
+			t.Fatalf("path = %q", r.URL.Path)
+		}
+		gotAction = r.Header.Get("SOAPACTION")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	if err := Next(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"}); err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	if gotAction != `"urn:schemas-upnp-org:service:AVTransport:1#Next"` {
+		t.Fatalf("SOAPACTION = %q", gotAction)
+	}
+	if !strings.Contains(gotBody, "<u:Next ") || !strings.Contains(gotBody, "<InstanceID>0</InstanceID>") {
+		t.Fatalf("unexpected body: %s", gotBody)
+	}
+}
+
+func TestNextReportsFault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<s:Fault>701</s:Fault>", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := Next(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"})
+	if err == nil || !strings.Contains(err.Error(), "701") {
+		t.Fatalf("Next error = %v, want fault", err)
+	}
+}
+
+func TestAdjustVolume(t *testing.T) {
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/MediaRenderer/RenderingControl/Control" {
+			t.Fatalf("path = %q", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:SetRelativeVolumeResponse xmlns:u="urn:schemas-upnp-org:service:RenderingControl:1"><NewVolume>27</NewVolume></u:SetRelativeVolumeResponse></s:Body></s:Envelope>`)
+	}))
+	defer server.Close()
+
+	volume, err := AdjustVolume(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"}, -3)
+	if err != nil {
+		t.Fatalf("AdjustVolume error: %v", err)
+	}
+	if volume != 27 {
+		t.Fatalf("volume = %d, want 27", volume)
+	}
+	if !strings.Contains(gotBody, "<Adjustment>-3</Adjustment>") {
+		t.Fatalf("unexpected body: %s", gotBody)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net"
 	"net/textproto"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -15,12 +16,16 @@ import (
 
 const (
 	ssdpAddress     = "239.255.255.250:1900"
+	ssdpAddress6    = "[FF02::C]:1900"
 	ssdpSearch      = "urn:schemas-upnp-org:device:ZonePlayer:1"
 	ssdpTimeout     = 250 * time.Millisecond
 	ssdpQuietPeriod = 1 * time.Second
 )
 
-var ssdpUDPAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+var (
+	ssdpUDPAddr   = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+	ssdpIPv6Group = net.ParseIP("ff02::c")
+)
 
 // Device contains basic metadata about a discovered Sonos device.
 type Device struct {
@@ -39,6 +44,11 @@ type Device struct {
 // The context governs the lifetime of the discovery. A zero timeout
 // falls back to a sensible default. If targetRoom is non-empty, discovery
 // stops as soon as a matching device is observed.
+//
+// Searches go out over IPv4 and, on hosts with IPv6 interfaces, to the
+// link-local FF02::C group at the same time. A speaker answering on both is
+// reported once, by its IPv4 answer. An error from one family is only
+// returned when the other found nothing.
 func Discover(ctx context.Context, timeout time.Duration, targetRoom string) ([]Device, error) {
 	if ctx == nil {
 		return nil, errors.New("sonos: nil context")
@@ -49,17 +59,127 @@ func Discover(ctx context.Context, timeout time.Duration, targetRoom string) ([]
 	}
 
 	targetRoomCanonical := canonicalRoomName(targetRoom)
+	// foundTarget reports whether a search stopped on the target room, so
+	// the other search can stop too.
+	foundTarget := func(devices []Device) bool {
+		return targetRoomCanonical != "" && len(devices) == 1 &&
+			devices[0].IsSonos && roomMatchesHeader(devices[0], targetRoomCanonical)
+	}
+
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		devices []Device
+		err     error
+	}
+	v6Result := make(chan result, 1)
+	go func() {
+		devices, err := discoverSSDP6(searchCtx, timeout, targetRoom)
+		if foundTarget(devices) {
+			cancel()
+		}
+		v6Result <- result{devices, err}
+	}()
+	v4Devices, v4Err := discoverSSDP4(searchCtx, timeout, targetRoom)
+	if foundTarget(v4Devices) {
+		cancel()
+	}
+	v6 := <-v6Result
+
+	switch {
+	case foundTarget(v4Devices):
+		return v4Devices, nil
+	case foundTarget(v6.devices):
+		return v6.devices, nil
+	}
+
+	devices := mergeSSDPDevices(v4Devices, v6.devices)
+	if len(devices) == 0 {
+		if errors.Is(v6.err, errNoIPv6) {
+			v6.err = nil
+		}
+		return nil, errors.Join(v4Err, v6.err)
+	}
+	return devices, nil
+}
 
+// errNoIPv6 is returned by discoverSSDP6 on hosts with no IPv6 multicast
+// interface.
+var errNoIPv6 = errors.New("sonos: no IPv6 multicast interface")
+
+func discoverSSDP4(ctx context.Context, timeout time.Duration, targetRoom string) ([]Device, error) {
 	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
 	if err != nil {
 		return nil, fmt.Errorf("sonos: listen UDP: %w", err)
 	}
 	defer conn.Close()
 
-	if err := sendSearchRequests(conn, ssdpUDPAddr); err != nil {
+	if err := sendSearchRequests(conn, ssdpUDPAddr, ssdpAddress); err != nil {
 		return nil, err
 	}
+	return collectSSDPResponses(ctx, conn, timeout, targetRoom)
+}
+
+// discoverSSDP6 searches on every interface with IPv6 multicast, since the
+// link-local group has to be addressed per interface.
+func discoverSSDP6(ctx context.Context, timeout time.Duration, targetRoom string) ([]Device, error) {
+	ifaces := ipv6MulticastInterfaces()
+	if len(ifaces) == 0 {
+		return nil, errNoIPv6
+	}
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6unspecified, Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("sonos: listen UDP6: %w", err)
+	}
+	defer conn.Close()
 
+	var sendErr error
+	sent := false
+	for _, iface := range ifaces {
+		target := &net.UDPAddr{IP: ssdpIPv6Group, Port: 1900, Zone: iface}
+		if err := sendSearchRequests(conn, target, ssdpAddress6); err != nil {
+			sendErr = err
+			continue
+		}
+		sent = true
+	}
+	if !sent {
+		return nil, sendErr
+	}
+	return collectSSDPResponses(ctx, conn, timeout, targetRoom)
+}
+
+// ipv6MulticastInterfaces lists the names of the up, non-loopback interfaces
+// that can multicast and have an IPv6 address.
+func ipv6MulticastInterfaces() []string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() == nil {
+				names = append(names, iface.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// collectSSDPResponses reads search responses from conn until timeout, or
+// until a quiet period once some have arrived.
+func collectSSDPResponses(ctx context.Context, conn *net.UDPConn, timeout time.Duration, targetRoom string) ([]Device, error) {
+	targetRoomCanonical := canonicalRoomName(targetRoom)
 	deadline := time.Now().Add(timeout)
 	buf := make([]byte, 2048)
 	indexByKey := make(map[string]int)
@@ -103,7 +223,8 @@ func Discover(ctx context.Context, timeout time.Duration, targetRoom string) ([]
 			// Ignore malformed responses.
 			continue
 		}
-		device.IP = addr.IP.String()
+		device.IP = hostIP(addr.IP, addr.Zone)
+		device.Location = zonedLocation(device.Location, addr.Zone)
 
 		lastResponse = time.Now()
 
@@ -127,14 +248,62 @@ func Discover(ctx context.Context, timeout time.Duration, targetRoom string) ([]
 		return nil, nil
 	}
 
+	sortDevices(devices)
+	return devices, nil
+}
+
+// mergeSSDPDevices combines search results, keeping the first answer for
+// each USN.
+func mergeSSDPDevices(lists ...[]Device) []Device {
+	seen := make(map[string]struct{})
+	var merged []Device
+	for _, list := range lists {
+		for _, device := range list {
+			key := device.USN
+			if key == "" {
+				key = device.IP
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, device)
+		}
+	}
+	sortDevices(merged)
+	return merged
+}
+
+func sortDevices(devices []Device) {
 	sort.Slice(devices, func(i, j int) bool {
 		if devices[i].IP == devices[j].IP {
 			return devices[i].Location < devices[j].Location
 		}
 		return devices[i].IP < devices[j].IP
 	})
+}
 
-	return devices, nil
+// zonedLocation adds zone to a LOCATION URL whose host is a link-local IPv6
+// address without one; speakers cannot know the zone their answer arrived on.
+func zonedLocation(location, zone string) string {
+	if zone == "" {
+		return location
+	}
+	u, err := url.Parse(strings.TrimSpace(location))
+	if err != nil {
+		return location
+	}
+	ip := net.ParseIP(u.Hostname())
+	if ip == nil || !ip.IsLinkLocalUnicast() || ip.To4() != nil {
+		return location
+	}
+	host := hostIP(ip, zone)
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = "[" + host + "]"
+	}
+	return u.String()
 }
 
 func canonicalRoomName(value string) string {
@@ -185,10 +354,10 @@ func headerRoomCandidates(device Device) []string {
 	return candidates
 }
 
-func sendSearchRequests(conn *net.UDPConn, target *net.UDPAddr) error {
+func sendSearchRequests(conn *net.UDPConn, target *net.UDPAddr, host string) error {
 	message := strings.Join([]string{
 		"M-SEARCH * HTTP/1.1",
-		"HOST: " + ssdpAddress,
+		"HOST: " + host,
 		"MAN: \"ssdp:discover\"",
 		"MX: 1",
 		"ST: " + ssdpSearch,
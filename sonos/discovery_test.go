@@ -70,7 +70,7 @@ func TestSendSearchRequests(t *testing.T) {
 		}
 	}()
 
-	if err := sendSearchRequests(client, listener.LocalAddr().(*net.UDPAddr)); err != nil {
+	if err := sendSearchRequests(client, listener.LocalAddr().(*net.UDPAddr), ssdpAddress); err != nil {
 		t.Fatalf("sendSearchRequests: %v", err)
 	}
 
@@ -103,3 +103,45 @@ func TestRoomMatchesHeader(t *testing.T) {
 		t.Fatal("roomMatchesHeader should not match different room")
 	}
 }
+
+func TestZonedLocation(t *testing.T) {
+	cases := []struct {
+		location, zone, want string
+	}{
+		{"http://[fe80::1]:1400/xml/device_description.xml", "eth0", "http://[fe80::1%25eth0]:1400/xml/device_description.xml"},
+		{"http://[fd00::5]:1400/xml/device_description.xml", "eth0", "http://[fd00::5]:1400/xml/device_description.xml"},
+		{"http://192.168.1.5:1400/xml/device_description.xml", "eth0", "http://192.168.1.5:1400/xml/device_description.xml"},
+		{"http://[fe80::1]:1400/xml/device_description.xml", "", "http://[fe80::1]:1400/xml/device_description.xml"},
+	}
+	for _, tc := range cases {
+		if got := zonedLocation(tc.location, tc.zone); got != tc.want {
+			t.Fatalf("zonedLocation(%q, %q) = %q, want %q", tc.location, tc.zone, got, tc.want)
+		}
+	}
+
+	device := Device{IP: "fe80::1%eth0", Location: zonedLocation(cases[0].location, "eth0")}
+	control, err := avTransportControlURL(device)
+	if err != nil || control != "http://[fe80::1%25eth0]:1400/MediaRenderer/AVTransport/Control" {
+		t.Fatalf("control URL = %q, %v", control, err)
+	}
+	if got := descriptionURL("fd00::5", DefaultPort); got != "http://[fd00::5]:1400/xml/device_description.xml" {
+		t.Fatalf("descriptionURL = %q", got)
+	}
+}
+
+func TestMergeSSDPDevicesPrefersFirstFamily(t *testing.T) {
+	v4 := []Device{{IP: "192.168.1.5", USN: "uuid:RINCON_A::urn"}}
+	v6 := []Device{
+		{IP: "fe80::5%eth0", USN: "uuid:RINCON_A::urn"},
+		{IP: "fd00::6", USN: "uuid:RINCON_B::urn"},
+	}
+	merged := mergeSSDPDevices(v4, v6)
+	if len(merged) != 2 {
+		t.Fatalf("merged %d devices, want 2: %+v", len(merged), merged)
+	}
+	for _, device := range merged {
+		if device.USN == "uuid:RINCON_A::urn" && device.IP != "192.168.1.5" {
+			t.Fatalf("dual-stack speaker kept IPv6 answer %q", device.IP)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Package sonos finds Sonos speakers on the network, reads and controls
+// what their rooms play, and follows a room's playback through UPnP events,
+// fetching its album art along the way.
+//
+// The package prints nothing: ListenForEvents reports through the
+// callbacks in ListenerOptions, GatherRoomStatuses returns its results for
+// WriteRoomStatuses or the caller to show, and diagnostics go to the
+// logger from musicDisplay/logging, which logging.Setup can point at
+// another writer, such as io.Discard, before the package is used.
+package sonos
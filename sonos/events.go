@@ -16,12 +16,27 @@ type Subscription struct {
 	ID       string
 	Timeout  time.Duration
 	EventURL string
+	// requestTimeout bounds renewing and cancelling the subscription,
+	// TimeoutsFor the device's Subscribe.
+	requestTimeout time.Duration
 }
 
 // AVTransportEvent captures the interesting fields from AVTransport event notifications.
 type AVTransportEvent struct {
 	TransportState string
 	Track          TrackInfo
+	// NextTrack is the track queued after Track, from r:NextTrackMetaData.
+	// It is zero when nothing is queued or the source does not say.
+	NextTrack TrackInfo
+	// PlayMode is CurrentPlayMode, or empty when the event does not say.
+	PlayMode PlayMode
+	// Crossfade is CurrentCrossfadeMode, or nil when the event does not
+	// say.
+	Crossfade *bool
+	// MissingMetadata is set when CurrentTrackMetaData was empty or
+	// NOT_IMPLEMENTED, as line-in, some radio streams, and speakers that are
+	// still buffering send it. The listener then polls for the metadata.
+	MissingMetadata bool
 }
 
 // SubscribeAVTransport registers a callback URL to receive AVTransport NOTIFY events.
@@ -30,7 +45,24 @@ func SubscribeAVTransport(ctx context.Context, device Device, callbackURL string
 	if err != nil {
 		return Subscription{}, err
 	}
+	return subscribe(ctx, device, eventURL, "avtransport", callbackURL, timeout)
+}
 
+// SubscribeContentDirectory registers a callback URL to receive
+// ContentDirectory NOTIFY events, which report edits to the queue and the
+// favorites; see ParseQueueUpdateID. RenewAVTransport and
+// UnsubscribeAVTransport manage the subscription.
+func SubscribeContentDirectory(ctx context.Context, device Device, callbackURL string, timeout time.Duration) (Subscription, error) {
+	eventURL, err := contentDirectoryEventURL(device)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return subscribe(ctx, device, eventURL, "contentdirectory", callbackURL, timeout)
+}
+
+// subscribe registers callbackURL for the events of the service at
+// eventURL, named service in errors.
+func subscribe(ctx context.Context, device Device, eventURL, service, callbackURL string, timeout time.Duration) (Subscription, error) {
 	if timeout <= 0 {
 		timeout = 30 * time.Minute
 	}
@@ -43,10 +75,11 @@ func SubscribeAVTransport(ctx context.Context, device Device, callbackURL string
 	req.Header.Set("NT", "upnp:event")
 	req.Header.Set("TIMEOUT", formatUPnPTimeout(timeout))
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	requestTimeout := TimeoutsFor(device).Subscribe
+	client := &http.Client{Timeout: requestTimeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return Subscription{}, fmt.Errorf("sonos: subscribe avtransport: %w", err)
+		return Subscription{}, fmt.Errorf("sonos: subscribe %s: %w", service, err)
 	}
 	defer resp.Body.Close()
 
@@ -65,7 +98,7 @@ func SubscribeAVTransport(ctx context.Context, device Device, callbackURL string
 		negotiated = timeout
 	}
 
-	return Subscription{ID: sid, Timeout: negotiated, EventURL: eventURL}, nil
+	return Subscription{ID: sid, Timeout: negotiated, EventURL: eventURL, requestTimeout: requestTimeout}, nil
 }
 
 // RenewAVTransport refreshes an active AVTransport subscription.
@@ -84,7 +117,7 @@ func RenewAVTransport(ctx context.Context, sub Subscription, timeout time.Durati
 	req.Header.Set("SID", sub.ID)
 	req.Header.Set("TIMEOUT", formatUPnPTimeout(timeout))
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := &http.Client{Timeout: sub.timeout()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("sonos: renew avtransport: %w", err)
@@ -107,7 +140,7 @@ func UnsubscribeAVTransport(ctx context.Context, sub Subscription) error {
 	}
 	req.Header.Set("SID", sub.ID)
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := &http.Client{Timeout: sub.timeout()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("sonos: unsubscribe avtransport: %w", err)
@@ -122,6 +155,14 @@ func UnsubscribeAVTransport(ctx context.Context, sub Subscription) error {
 	return nil
 }
 
+// timeout returns how long renewing or cancelling sub may take.
+func (sub Subscription) timeout() time.Duration {
+	if sub.requestTimeout > 0 {
+		return sub.requestTimeout
+	}
+	return DefaultTimeouts.Subscribe
+}
+
 func formatUPnPTimeout(d time.Duration) string {
 	if d <= 0 {
 		return "Second-0"
@@ -148,6 +189,12 @@ func parseUPnPTimeout(header string) time.Duration {
 
 // ParseAVTransportEvent extracts state and track information from an AVTransport NOTIFY payload.
 func ParseAVTransportEvent(body []byte) (AVTransportEvent, error) {
+	return parseAVTransportEvent(body, nil)
+}
+
+// parseAVTransportEvent is ParseAVTransportEvent with the session's track
+// cache.
+func parseAVTransportEvent(body []byte, tracks *TrackCache) (AVTransportEvent, error) {
 	var event AVTransportEvent
 
 	var props eventPropertySet
@@ -182,17 +229,33 @@ func ParseAVTransportEvent(body []byte) (AVTransportEvent, error) {
 
 	meta := strings.TrimSpace(instance.CurrentTrackMetaData.Value)
 	uri := strings.TrimSpace(instance.CurrentTrackURI.Value)
+	duration := strings.TrimSpace(instance.CurrentTrackDuration.Value)
 
 	if strings.EqualFold(meta, "not_implemented") {
 		meta = ""
 	}
+	event.MissingMetadata = meta == ""
 
 	if meta != "" || uri != "" {
-		info, err := buildTrackInfo(positionInfoResponse{TrackMetaData: meta, TrackURI: uri})
+		info, err := buildTrackInfo(tracks, positionInfoResponse{TrackMetaData: meta, TrackURI: uri, TrackDuration: duration})
 		if err == nil {
 			event.Track = info
 		} else {
-			event.Track.URI = uri
+			event.Track = withSource(TrackInfo{URI: uri, Duration: parseTrackTime(duration)})
+		}
+	}
+
+	event.PlayMode = parsePlayMode(instance.CurrentPlayMode.Value)
+	if mode := instance.CurrentCrossfadeMode; mode != nil {
+		crossfade := strings.TrimSpace(mode.Value) == "1"
+		event.Crossfade = &crossfade
+	}
+
+	nextMeta := strings.TrimSpace(instance.NextTrackMetaData.Value)
+	if nextMeta != "" && !strings.EqualFold(nextMeta, "not_implemented") {
+		next, err := buildTrackInfo(tracks, positionInfoResponse{TrackMetaData: nextMeta, TrackURI: strings.TrimSpace(instance.NextTrackURI.Value)})
+		if err == nil {
+			event.NextTrack = next
 		}
 	}
 
@@ -311,7 +374,8 @@ type eventPropertySet struct {
 }
 
 type eventProperty struct {
-	LastChange innerXML `xml:"LastChange"`
+	LastChange         innerXML `xml:"LastChange"`
+	ContainerUpdateIDs string   `xml:"ContainerUpdateIDs"`
 }
 
 type innerXML struct {
@@ -326,6 +390,13 @@ type avTransportInstance struct {
 	TransportState       avTransportValue `xml:"TransportState"`
 	CurrentTrackMetaData avTransportValue `xml:"CurrentTrackMetaData"`
 	CurrentTrackURI      avTransportValue `xml:"CurrentTrackURI"`
+	CurrentTrackDuration avTransportValue `xml:"CurrentTrackDuration"`
+	NextTrackURI         avTransportValue `xml:"NextTrackURI"`
+	NextTrackMetaData    avTransportValue `xml:"NextTrackMetaData"`
+	CurrentPlayMode      avTransportValue `xml:"CurrentPlayMode"`
+	// CurrentCrossfadeMode is "1" when crossfading is on and "0" when off;
+	// nil when the event does not report it.
+	CurrentCrossfadeMode *avTransportValue `xml:"CurrentCrossfadeMode"`
 }
 
 type avTransportValue struct {
@@ -66,7 +66,57 @@ func TestParseAVTransportEventWithRealPayload(t *testing.T) {
 	if event.Track.URI != "x-sonos-vli:RINCON_F0F6C19DB2C101400:1,airplay:3e4acedc271c488c9f7a78dc0cb819df" {
 		t.Fatalf("Track.URI = %q, want x-sonos-vli:RINCON_F0F6C19DB2C101400:1,airplay:3e4acedc271c488c9f7a78dc0cb819df", event.Track.URI)
 	}
+	if event.NextTrack.Title != "" || event.NextTrack.Artist != "" {
+		t.Fatalf("NextTrack = %+v, want no title for an empty AirPlay queue", event.NextTrack)
+	}
 	if event.Track.AlbumArtURI != "http://192.168.7.119:1400/getaa?v=0&vli=1&u=2507217148" {
 		t.Fatalf("Track.AlbumArtURI = %q, want http://192.168.7.119:1400/getaa?v=0&vli=1&u=2507217148", event.Track.AlbumArtURI)
 	}
+	if event.PlayMode != PlayModeNormal || event.Crossfade == nil || *event.Crossfade {
+		t.Fatalf("PlayMode = %q, Crossfade = %v; want NORMAL without crossfade", event.PlayMode, event.Crossfade)
+	}
+}
+
+func TestParseAVTransportEventPlayMode(t *testing.T) {
+	event, err := ParseAVTransportEvent([]byte(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;CurrentPlayMode val=&quot;SHUFFLE&quot;/&gt;&lt;CurrentCrossfadeMode val=&quot;1&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`))
+	if err != nil {
+		t.Fatalf("ParseAVTransportEvent error: %v", err)
+	}
+	if !event.PlayMode.Shuffle() || !event.PlayMode.RepeatAll() || event.PlayMode.RepeatOne() {
+		t.Fatalf("PlayMode = %q, want shuffle with repeat all", event.PlayMode)
+	}
+	if event.Crossfade == nil || !*event.Crossfade {
+		t.Fatalf("Crossfade = %v, want on", event.Crossfade)
+	}
+
+	event, err = ParseAVTransportEvent([]byte(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`))
+	if err != nil {
+		t.Fatalf("ParseAVTransportEvent error: %v", err)
+	}
+	if event.PlayMode != "" || event.Crossfade != nil {
+		t.Fatalf("PlayMode = %q, Crossfade = %v; want both unreported", event.PlayMode, event.Crossfade)
+	}
+}
+
+func TestParseAVTransportEventNextTrack(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="utf-8"?>
+<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0">
+  <e:property>
+    <LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot; xmlns:r=&quot;urn:schemas-rinconnetworks-com:metadata-1-0/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;First&lt;/dc:title&gt;&lt;dc:creator&gt;Artist&lt;/dc:creator&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-file-cifs://nas/first.flac&quot;/&gt;&lt;r:NextTrackURI val=&quot;x-file-cifs://nas/second.flac&quot;/&gt;&lt;r:NextTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Second&lt;/dc:title&gt;&lt;dc:creator&gt;Other Artist&lt;/dc:creator&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange>
+  </e:property>
+</e:propertyset>`
+
+	event, err := ParseAVTransportEvent([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseAVTransportEvent error: %v", err)
+	}
+	if event.Track.Title != "First" {
+		t.Fatalf("Track.Title = %q, want First", event.Track.Title)
+	}
+	if event.NextTrack.Title != "Second" || event.NextTrack.Artist != "Other Artist" {
+		t.Fatalf("NextTrack = %+v, want Second by Other Artist", event.NextTrack)
+	}
+	if event.NextTrack.URI != "x-file-cifs://nas/second.flac" {
+		t.Fatalf("NextTrack.URI = %q", event.NextTrack.URI)
+	}
 }
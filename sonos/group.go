@@ -0,0 +1,35 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JoinGroup adds the speaker device to the group run by coordinator, which
+// must be a group coordinator: device drops what it was playing and follows
+// coordinator's playback.
+func JoinGroup(ctx context.Context, device, coordinator Device) error {
+	id := strings.TrimPrefix(coordinator.Metadata.UDN, "uuid:")
+	if id == "" {
+		return errors.New("sonos: join group: coordinator UDN unknown")
+	}
+	if sameSpeaker(device, coordinator) {
+		return errors.New("sonos: join group: speaker cannot join itself")
+	}
+	if err := SetAVTransportURI(ctx, device, "x-rincon:"+id, ""); err != nil {
+		return fmt.Errorf("sonos: join group: %w", err)
+	}
+	return nil
+}
+
+// LeaveGroup takes the speaker device out of its group into a group of its
+// own, which is stopped. Leaving the coordinator hands the rest of the group
+// to one of its other members, which keeps playing.
+func LeaveGroup(ctx context.Context, device Device) error {
+	if _, err := callAVTransport(ctx, device, "BecomeCoordinatorOfStandaloneGroup", ""); err != nil {
+		return fmt.Errorf("sonos: leave group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package sonos
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJoinGroup(t *testing.T) {
+	server, actions, bodies := recordActions(t)
+	device := Device{Location: server.URL + "/xml/device_description.xml", Metadata: DeviceMetadata{UDN: "uuid:RINCON_MEMBER01400"}}
+	coordinator := Device{Location: "http://192.0.2.10:1400/xml/device_description.xml", Metadata: DeviceMetadata{UDN: "uuid:RINCON_COORD01400"}}
+	if err := JoinGroup(context.Background(), device, coordinator); err != nil {
+		t.Fatalf("JoinGroup error: %v", err)
+	}
+	if len(*actions) != 1 || (*actions)[0] != "SetAVTransportURI" {
+		t.Fatalf("actions = %v", *actions)
+	}
+	if body := (*bodies)[0]; !strings.Contains(body, "<CurrentURI>x-rincon:RINCON_COORD01400</CurrentURI>") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	if err := JoinGroup(context.Background(), device, device); err == nil {
+		t.Fatalf("expected an error joining a speaker to itself")
+	}
+	if err := JoinGroup(context.Background(), device, Device{}); err == nil {
+		t.Fatalf("expected an error for a coordinator without a UDN")
+	}
+	if len(*actions) != 1 {
+		t.Fatalf("rejected joins reached the speaker: %v", *actions)
+	}
+}
+
+func TestLeaveGroup(t *testing.T) {
+	server, actions, _ := recordActions(t)
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	if err := LeaveGroup(context.Background(), device); err != nil {
+		t.Fatalf("LeaveGroup error: %v", err)
+	}
+	if len(*actions) != 1 || (*actions)[0] != "BecomeCoordinatorOfStandaloneGroup" {
+		t.Fatalf("actions = %v", *actions)
+	}
+}
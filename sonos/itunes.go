@@ -0,0 +1,177 @@
+package sonos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultITunesSearchURL = "https://itunes.apple.com/search"
+	itunesLookupTimeout    = 4 * time.Second
+	itunesCacheLimit       = 256
+)
+
+// ITunesLookup fills in the sparse metadata Sonos reports for AirPlay
+// (x-sonos-vli) sessions by searching the iTunes Search API for the track
+// title. Results, including misses, are cached by search term.
+type ITunesLookup struct {
+	client    *http.Client
+	searchURL string
+	country   string
+
+	mu    sync.Mutex
+	cache map[string]itunesMatch
+}
+
+type itunesMatch struct {
+	found  bool
+	artist string
+	album  string
+	art    string
+}
+
+// NewITunesLookup returns a lookup that searches the given iTunes store
+// country (two-letter code, empty for the API default).
+func NewITunesLookup(country string) *ITunesLookup {
+	return &ITunesLookup{
+		client:    &http.Client{Timeout: itunesLookupTimeout},
+		searchURL: defaultITunesSearchURL,
+		country:   strings.ToLower(strings.TrimSpace(country)),
+		cache:     make(map[string]itunesMatch),
+	}
+}
+
+// Enrich returns track with missing artist, album, and artwork fields filled
+// from iTunes when track is an AirPlay session with a title but incomplete
+// metadata. Fields Sonos already reported are left untouched. Lookup failures
+// are logged at debug level and leave the track unchanged.
+func (l *ITunesLookup) Enrich(ctx context.Context, track TrackInfo) TrackInfo {
+	if l == nil || !isAirPlayTrack(track) {
+		return track
+	}
+	title := strings.TrimSpace(track.Title)
+	if title == "" {
+		return track
+	}
+	if strings.TrimSpace(track.Artist) != "" && strings.TrimSpace(track.Album) != "" && strings.TrimSpace(track.AlbumArtURI) != "" {
+		return track
+	}
+
+	match, err := l.lookup(ctx, title, strings.TrimSpace(track.Artist))
+	if err != nil {
+		logger.Debug("itunes lookup failed", "title", title, "err", err)
+		return track
+	}
+	if !match.found {
+		return track
+	}
+	if strings.TrimSpace(track.Artist) == "" {
+		track.Artist = match.artist
+	}
+	if strings.TrimSpace(track.Album) == "" {
+		track.Album = match.album
+	}
+	if strings.TrimSpace(track.AlbumArtURI) == "" {
+		track.AlbumArtURI = match.art
+	}
+	return track
+}
+
+func (l *ITunesLookup) lookup(ctx context.Context, title, artist string) (itunesMatch, error) {
+	term := strings.TrimSpace(title + " " + artist)
+	key := strings.ToLower(term)
+
+	l.mu.Lock()
+	cached, ok := l.cache[key]
+	l.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	query := url.Values{
+		"term":   {term},
+		"media":  {"music"},
+		"entity": {"song"},
+		"limit":  {"10"},
+	}
+	if l.country != "" {
+		query.Set("country", l.country)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, itunesLookupTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.searchURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return itunesMatch{}, fmt.Errorf("build itunes request: %w", err)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return itunesMatch{}, fmt.Errorf("itunes search: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return itunesMatch{}, fmt.Errorf("itunes search http status %s", resp.Status)
+	}
+
+	var payload itunesSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return itunesMatch{}, fmt.Errorf("decode itunes response: %w", err)
+	}
+	match := payload.best(title, artist)
+
+	l.mu.Lock()
+	if len(l.cache) >= itunesCacheLimit {
+		l.cache = make(map[string]itunesMatch)
+	}
+	l.cache[key] = match
+	l.mu.Unlock()
+	return match, nil
+}
+
+type itunesSearchResponse struct {
+	Results []struct {
+		TrackName      string `json:"trackName"`
+		ArtistName     string `json:"artistName"`
+		CollectionName string `json:"collectionName"`
+		ArtworkURL100  string `json:"artworkUrl100"`
+	} `json:"results"`
+}
+
+// best picks the result whose title (and artist, when known) matches exactly,
+// falling back to the first result with a matching title. Results with a
+// different title are ignored so unrelated tracks are never shown.
+func (r itunesSearchResponse) best(title, artist string) itunesMatch {
+	pick := -1
+	for i, res := range r.Results {
+		if !strings.EqualFold(strings.TrimSpace(res.TrackName), title) {
+			continue
+		}
+		if artist == "" || strings.EqualFold(strings.TrimSpace(res.ArtistName), artist) {
+			pick = i
+			break
+		}
+		if pick < 0 {
+			pick = i
+		}
+	}
+	if pick < 0 {
+		return itunesMatch{}
+	}
+	res := r.Results[pick]
+	return itunesMatch{
+		found:  true,
+		artist: strings.TrimSpace(res.ArtistName),
+		album:  strings.TrimSpace(res.CollectionName),
+		art:    strings.TrimSpace(res.ArtworkURL100),
+	}
+}
+
+func isAirPlayTrack(track TrackInfo) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(track.URI)), "x-sonos-vli:")
+}
@@ -0,0 +1,79 @@
+package sonos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestITunesLookupEnrichesAirPlayTrack(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.URL.Query().Get("term"); got != "Blue Monday" {
+			t.Fatalf("term = %q", got)
+		}
+		if got := r.URL.Query().Get("country"); got != "nl" {
+			t.Fatalf("country = %q", got)
+		}
+		fmt.Fprint(w, `{"resultCount":3,"results":[
+  {"trackName":"Blue Monday '88","artistName":"New Order","collectionName":"Substance","artworkUrl100":"http://art/88.jpg"},
+  {"trackName":"Blue Monday","artistName":"New Order","collectionName":"Power, Corruption & Lies","artworkUrl100":"http://art/pcl.jpg"},
+  {"trackName":"Blue Monday","artistName":"Orkestra","collectionName":"Covers","artworkUrl100":"http://art/cover.jpg"}
+]}`)
+	}))
+	defer server.Close()
+
+	lookup := NewITunesLookup("NL")
+	lookup.searchURL = server.URL
+
+	track := TrackInfo{Title: "Blue Monday", URI: "x-sonos-vli:RINCON_1:2,airplay:abc"}
+	got := lookup.Enrich(context.Background(), track)
+	if got.Artist != "New Order" || got.Album != "Power, Corruption & Lies" || got.AlbumArtURI != "http://art/pcl.jpg" {
+		t.Fatalf("enriched track = %+v", got)
+	}
+
+	lookup.Enrich(context.Background(), track)
+	if requests != 1 {
+		t.Fatalf("requests = %d, want cached result reused", requests)
+	}
+}
+
+func TestITunesLookupSkipsNonAirPlayAndCompleteTracks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("unexpected lookup for %s", r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	lookup := NewITunesLookup("")
+	lookup.searchURL = server.URL
+
+	spotifyTrack := TrackInfo{Title: "Song", URI: "x-sonos-spotify:spotify%3atrack%3a1"}
+	if got := lookup.Enrich(context.Background(), spotifyTrack); got != spotifyTrack {
+		t.Fatalf("non-AirPlay track changed: %+v", got)
+	}
+	complete := TrackInfo{Title: "Song", Artist: "A", Album: "B", AlbumArtURI: "/getaa?x", URI: "x-sonos-vli:1"}
+	if got := lookup.Enrich(context.Background(), complete); got != complete {
+		t.Fatalf("complete track changed: %+v", got)
+	}
+	var nilLookup *ITunesLookup
+	if got := nilLookup.Enrich(context.Background(), spotifyTrack); got != spotifyTrack {
+		t.Fatalf("nil lookup changed track")
+	}
+}
+
+func TestITunesLookupIgnoresUnrelatedResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[{"trackName":"Something Else","artistName":"X","collectionName":"Y"}]}`)
+	}))
+	defer server.Close()
+
+	lookup := NewITunesLookup("")
+	lookup.searchURL = server.URL
+	track := TrackInfo{Title: "Voice Memo", URI: "x-sonos-vli:1"}
+	if got := lookup.Enrich(context.Background(), track); got != track {
+		t.Fatalf("track changed by unrelated result: %+v", got)
+	}
+}
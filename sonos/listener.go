@@ -6,26 +6,292 @@ import (
 	"fmt"
 	"image"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	"musicDisplay/clock"
 )
 
 // Display abstracts the image rendering backend (e.g. an RGB LED matrix).
+// Close releases the backend and is called once, by whoever assembled the
+// display; the listener never closes the display it is given, so it can be
+// restarted on another room with the same one.
 type Display interface {
 	Show(image.Image) error
 	Clear() error
+	Close() error
+}
+
+// ContextDisplay is implemented by displays whose Show and Clear can block,
+// such as displays reached over the network. The listener calls them with a
+// deadline (ListenerOptions.DisplayTimeout) so a stalled backend cannot hold
+// up event handling.
+type ContextDisplay interface {
+	Display
+	ShowContext(ctx context.Context, img image.Image) error
+	ClearContext(ctx context.Context) error
+}
+
+// ShowContext shows img on d, passing ctx on when d is a ContextDisplay.
+// Other displays are called directly once ctx is checked, since they cannot
+// be interrupted.
+func ShowContext(ctx context.Context, d Display, img image.Image) error {
+	if cd, ok := d.(ContextDisplay); ok {
+		return cd.ShowContext(ctx, img)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.Show(img)
+}
+
+// ClearContext clears d, passing ctx on when d is a ContextDisplay.
+func ClearContext(ctx context.Context, d Display) error {
+	if cd, ok := d.(ContextDisplay); ok {
+		return cd.ClearContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.Clear()
+}
+
+// Dimmer is implemented by displays that can dim what they show, such as the
+// renderer. The listener uses it for StateTimeout.Dim.
+type Dimmer interface {
+	SetDimmed(dimmed bool) error
+}
+
+// StateTimeout controls how long the display stays fully lit in one transport
+// state. After Dim the display is dimmed (when it implements Dimmer); after
+// Idle it switches to its idle screen. Zero disables the step.
+type StateTimeout struct {
+	Dim  time.Duration
+	Idle time.Duration
+}
+
+// StateTimeouts holds the timeouts per transport state. Transitioning rooms
+// keep whatever timers are running; every state other than playing and paused
+// counts as stopped.
+type StateTimeouts struct {
+	Playing StateTimeout
+	Paused  StateTimeout
+	Stopped StateTimeout
+}
+
+func (t StateTimeouts) forState(class string) StateTimeout {
+	switch class {
+	case stateClassPlaying:
+		return t.Playing
+	case stateClassPaused:
+		return t.Paused
+	}
+	return t.Stopped
+}
+
+const (
+	stateClassPlaying = "playing"
+	stateClassPaused  = "paused"
+	stateClassStopped = "stopped"
+)
+
+// stateClass groups a formatted transport state for StateTimeouts. It returns
+// "" for transitional states.
+func stateClass(state string) string {
+	switch {
+	case strings.EqualFold(state, "Playing"):
+		return stateClassPlaying
+	case strings.EqualFold(state, "Paused"):
+		return stateClassPaused
+	case strings.EqualFold(state, "Transitioning"):
+		return ""
+	}
+	return stateClassStopped
+}
+
+// RoomChange is a change of a room's playback state or track, with both as
+// GatherRoomStatuses describes them, such as "Playing" and
+// "Artist - Title".
+type RoomChange struct {
+	Time  time.Time
+	Room  string
+	State string
+	Track string
 }
 
 // ListenerOptions customises runtime behaviour for ListenForEvents.
 type ListenerOptions struct {
-	Debug       bool
-	Display     Display
+	Display Display
+	// OnChange, when set, is called whenever the room's playback state or
+	// track changes. The listener itself never writes to stdout.
+	OnChange func(RoomChange)
+	// IdleTimeout is how long a paused or stopped room keeps its artwork
+	// before the display switches to its idle screen. It is only used when
+	// StateTimeouts is nil.
 	IdleTimeout time.Duration
+	// StateTimeouts, when set, replaces IdleTimeout with per-state dim and
+	// idle timeouts.
+	StateTimeouts *StateTimeouts
+	// TimeoutUpdates, when set, delivers replacement timeouts while the
+	// listener runs, e.g. after the config file is edited. The running dim
+	// and idle timers restart with the new values.
+	TimeoutUpdates <-chan StateTimeouts
+	// OnStatus, when set, receives the room's playback status after every
+	// event and on each progress tick while a track is playing.
+	OnStatus func(PlaybackStatus)
+	// ProgressInterval controls how often OnStatus is called with an
+	// interpolated track position during playback. Defaults to one second.
+	ProgressInterval time.Duration
+	// ITunes, when set, fills missing artist, album, and artwork for AirPlay
+	// sessions before they are displayed.
+	ITunes *ITunesLookup
+	// SilenceTimeouts treats a room as idle once it has been playing the same
+	// metadata for the given duration, per source. This keeps sources such as
+	// line-in or TV, which report PLAYING indefinitely, from holding the
+	// display all night.
+	SilenceTimeouts map[SourceKind]time.Duration
+	// Clock drives the listener's timers. Defaults to the wall clock; tests
+	// substitute a fake.
+	Clock clock.Clock
+	// HealthCheckInterval is how long the listener goes without events before
+	// it checks that the subscription is still alive. Defaults to five
+	// minutes.
+	HealthCheckInterval time.Duration
+	// Rediscover, when set, finds the room's device again after its
+	// subscription is lost, e.g. because the speaker rebooted or was given a
+	// new IP address. Without it the listener re-subscribes to the same
+	// address. When the device cannot be subscribed to but its group plays
+	// on under another coordinator, the listener moves to that coordinator.
+	Rediscover func(ctx context.Context, room string) (Device, error)
+	// OnDevice, when set, is called after a reconnect moved the room to a
+	// device at a different address, including a group's new coordinator.
+	OnDevice func(Device)
+	// RoomCheckInterval is how often the listener reads the household's
+	// topology to notice the room being renamed in the Sonos app. Defaults
+	// to one minute; negative disables the check.
+	RoomCheckInterval time.Duration
+	// OnRoomRenamed, when set, is called after the room was renamed. The
+	// listener reports the new name in its statuses and rediscovers the
+	// room by it from then on.
+	OnRoomRenamed func(from, to string)
+	// OnHealth, when set, receives the state of the listener's link to the
+	// speaker whenever it changes: on subscribing, on each event, and when
+	// polling starts or stops.
+	OnHealth func(ListenerHealth)
+	// PollFallbackAfter is how long the listener waits for the first event
+	// after subscribing. Speakers send one straight away, so silence means
+	// the callbacks are blocked (guest VLANs, firewalls) and the listener
+	// switches to polling the speaker instead. Defaults to 15 seconds;
+	// negative disables the fallback.
+	PollFallbackAfter time.Duration
+	// PollInterval is how often the speaker is polled in fallback mode.
+	// Defaults to three seconds.
+	PollInterval time.Duration
+	// DisplayTimeout bounds each Show and Clear on a ContextDisplay.
+	// Defaults to five seconds.
+	DisplayTimeout time.Duration
+	// CallbackPort is the port the event callback server listens on. Zero
+	// picks a free port.
+	CallbackPort int
+	// CallbackBind is the IP address the callback server listens on. By
+	// default it is the local address that routes to the speaker.
+	CallbackBind string
+	// CallbackAdvertise is the host, or host:port, speakers are told to send
+	// events to. Set it when the listener is behind NAT or in a container and
+	// the address it listens on is not reachable from the speaker.
+	CallbackAdvertise string
+	// ArtStorage selects the format of the album art disk cache, which is
+	// used when there is no Display.
+	ArtStorage ArtStorage
+	// Tracks is the session's track cache, so metadata arriving again, from
+	// a poll or from going back in the queue, is not parsed again. It can
+	// be shared across restarts of the listener; without it each run keeps
+	// its own.
+	Tracks *TrackCache
+	// Placeholder, when set, supplies the image shown for a playing track
+	// without album art, or whose art could not be fetched, instead of
+	// leaving the previous track's art on the display. Without it, art that
+	// could not be fetched or was rejected is replaced by a black square.
+	Placeholder func(TrackInfo) (image.Image, error)
+}
+
+const (
+	defaultHealthCheckInterval = 5 * time.Minute
+	defaultRoomCheckInterval   = time.Minute
+	defaultPollFallbackAfter   = 15 * time.Second
+	defaultPollInterval        = 3 * time.Second
+	defaultDisplayTimeout      = 5 * time.Second
+	reconnectInitialBackoff    = 2 * time.Second
+	reconnectMaxBackoff        = time.Minute
+	// trackEndGrace is how long after the expected end of a track the
+	// listener checks the speaker if no event announced the next one.
+	trackEndGrace = 2 * time.Second
+)
+
+// positionSample is a track position fetched for the event numbered event.
+type positionSample struct {
+	event    int
+	elapsed  time.Duration
+	duration time.Duration
+	err      error
+}
+
+// ListenerHealth describes the listener's link to its speaker, for
+// troubleshooting.
+type ListenerHealth struct {
+	Room string
+	// Speaker is the address of the device subscribed to.
+	Speaker string
+	// Callback is the URL the speaker sends events to.
+	Callback string
+	SID      string
+	// Subscribed is when the current subscription was made.
+	Subscribed time.Time
+	// LastEvent is when the speaker last sent an event; it is zero until
+	// the first one.
+	LastEvent time.Time
+	// Polling is set while the listener polls the speaker because no
+	// events arrive.
+	Polling bool
+}
+
+// PlaybackStatus is the listener's current view of a room. Track.Position is
+// interpolated from the last GetPositionInfo sample while playing.
+type PlaybackStatus struct {
+	Room    string
+	State   string
+	Track   TrackInfo
+	Playing bool
+	// ArtKey names the track's album art for CachedAlbumArt, or is empty when
+	// the track has none. The art may still be loading.
+	ArtKey string
+	// NextTrack is the track queued to play after Track, when known.
+	NextTrack TrackInfo
+	// PlayMode is the room's shuffle and repeat setting, or empty until
+	// the speaker reports it.
+	PlayMode PlayMode
+	// Crossfade is set while the room crossfades between tracks.
+	Crossfade bool
+}
+
+// Progress reports the fraction of the track that has been played, or -1 when
+// the track length is unknown.
+func (s PlaybackStatus) Progress() float64 {
+	if s.Track.Duration <= 0 {
+		return -1
+	}
+	fraction := float64(s.Track.Position) / float64(s.Track.Duration)
+	if fraction < 0 {
+		return 0
+	}
+	if fraction > 1 {
+		return 1
+	}
+	return fraction
 }
 
 // ListenForEvents subscribes to AVTransport events for the supplied device and
@@ -35,52 +301,259 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 	if opts.IdleTimeout <= 0 {
 		opts.IdleTimeout = 5 * time.Minute
 	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = time.Second
+	}
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if opts.PollFallbackAfter == 0 {
+		opts.PollFallbackAfter = defaultPollFallbackAfter
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.DisplayTimeout <= 0 {
+		opts.DisplayTimeout = defaultDisplayTimeout
+	}
+	if opts.RoomCheckInterval == 0 {
+		opts.RoomCheckInterval = defaultRoomCheckInterval
+	}
+	timeouts := StateTimeouts{
+		Paused:  StateTimeout{Idle: opts.IdleTimeout},
+		Stopped: StateTimeout{Idle: opts.IdleTimeout},
+	}
+	if opts.StateTimeouts != nil {
+		timeouts = *opts.StateTimeouts
+	}
+	dimmer, _ := opts.Display.(Dimmer)
+	clk := clock.Or(opts.Clock)
+	tracks := opts.Tracks
+	if tracks == nil {
+		tracks = NewTrackCache(0)
+	}
 
-	bindAddr, err := determineLocalCallbackAddr(device)
+	bindAddr, err := callbackBindAddr(device, opts.CallbackBind, opts.CallbackPort)
 	if err != nil {
 		return err
 	}
-	bindAddr.Port = 0
 
 	notifyCh := make(chan AVTransportEvent, 16)
+	// notified receives a value whenever a NOTIFY arrives, so the listener
+	// can tell real callbacks from events synthesised by polling.
+	notified := make(chan struct{}, 1)
+	// queueUpdates receives the queue's update ID from ContentDirectory
+	// events, which arrive on their own path.
+	queueUpdates := make(chan string, 1)
 	serverErrors := make(chan error, 1)
 	lastState := ""
 	lastTrackSignature := ""
+	// roomPlayMode and roomCrossfade are the modes last reported; events
+	// made up from polling do not carry them.
+	var roomPlayMode PlayMode
+	roomCrossfade := false
 	savedArtSignature := ""
-	displayActive := false
+	// artRetry re-fetches art the speaker did not have yet while its track
+	// keeps playing, within ArtStorage.Fetch.Grace of the first miss.
+	var artRetry struct {
+		track     TrackInfo
+		signature string
+		since     time.Time
+		delay     time.Duration
+		timer     clock.Timer
+	}
+	var artRetryCh <-chan time.Time
+	// displayIdle tracks whether the display has been switched to its idle
+	// screen; it starts false so an idle room gets its idle screen too.
+	displayIdle := false
 	cacheToDisk := opts.Display == nil
-	var idleTimer *time.Timer
-	var idleTimerCh <-chan time.Time
+	// currentClass is the state class the dim and idle timers were started
+	// for; dimmed records whether the display was dimmed by the dim timer.
+	currentClass := ""
+	dimmed := false
+	var idleTimer, dimTimer clock.Timer
+	var idleTimerCh, dimTimerCh <-chan time.Time
+	// silenceSignature is the playing track the silence timer is measuring;
+	// silent is set once that track has been unchanged for too long.
+	silenceSignature := ""
+	silent := false
+	var silenceTimer clock.Timer
+	var silenceCh <-chan time.Time
 
-	stopIdleTimer := func() {
-		if idleTimer != nil {
-			if !idleTimer.Stop() {
-				select {
-				case <-idleTimer.C:
-				default:
-				}
+	var status PlaybackStatus
+	var positionSampledAt time.Time
+	var progressTicker clock.Ticker
+	var progressCh <-chan time.Time
+
+	currentStatus := func() PlaybackStatus {
+		current := status
+		if current.Playing && !positionSampledAt.IsZero() {
+			current.Track.Position += clk.Since(positionSampledAt)
+			if current.Track.Duration > 0 && current.Track.Position > current.Track.Duration {
+				current.Track.Position = current.Track.Duration
 			}
-			idleTimer = nil
-			idleTimerCh = nil
 		}
+		return current
 	}
 
-	startIdleTimer := func() {
-		if opts.Display == nil || opts.IdleTimeout <= 0 {
+	publishStatus := func() {
+		if opts.OnStatus != nil {
+			opts.OnStatus(currentStatus())
+		}
+	}
+
+	stopProgressTicker := func() {
+		if progressTicker != nil {
+			progressTicker.Stop()
+			progressTicker = nil
+			progressCh = nil
+		}
+	}
+	defer stopProgressTicker()
+
+	startProgressTicker := func() {
+		if opts.OnStatus == nil || progressTicker != nil {
 			return
 		}
-		if idleTimer == nil {
-			idleTimer = time.NewTimer(opts.IdleTimeout)
-			idleTimerCh = idleTimer.C
+		progressTicker = clk.NewTicker(opts.ProgressInterval)
+		progressCh = progressTicker.C()
+	}
+
+	// The track position is fetched off the event path, so a slow speaker
+	// does not hold up events. One fetch runs at a time; samples taken for
+	// an earlier event are dropped and fetched again for the latest, so a
+	// burst of events costs one extra request.
+	positions := make(chan positionSample, 1)
+	positionEvent := 0
+	fetchingPosition := false
+	fetchPosition := func() {
+		if fetchingPosition {
 			return
 		}
-		if !idleTimer.Stop() {
+		fetchingPosition = true
+		event, target := positionEvent, device
+		go func() {
+			posCtx, posCancel := context.WithTimeout(ctx, TimeoutsFor(target).Poll)
+			elapsed, duration, err := FetchPosition(posCtx, target)
+			posCancel()
 			select {
-			case <-idleTimer.C:
-			default:
+			case positions <- positionSample{event: event, elapsed: elapsed, duration: duration, err: err}:
+			case <-ctx.Done():
 			}
+		}()
+	}
+
+	stopStateTimers := func() {
+		if idleTimer != nil {
+			idleTimer.Stop()
+			idleTimer = nil
+			idleTimerCh = nil
+		}
+		if dimTimer != nil {
+			dimTimer.Stop()
+			dimTimer = nil
+			dimTimerCh = nil
+		}
+	}
+	defer stopStateTimers()
+
+	showArt := func(img image.Image) error {
+		showCtx, cancel := context.WithTimeout(ctx, opts.DisplayTimeout)
+		defer cancel()
+		return ShowContext(showCtx, opts.Display, img)
+	}
+	scheduleArtRetry := func(track TrackInfo, signature string) {
+		grace := opts.ArtStorage.Fetch.Grace
+		if grace <= 0 {
+			return
+		}
+		now := clk.Now()
+		if artRetry.signature != signature {
+			artRetry.track, artRetry.signature, artRetry.since, artRetry.delay = track, signature, now, artRetryInitialDelay
+		} else {
+			artRetry.delay = min(artRetry.delay*2, artRetryMaxDelay)
+		}
+		if artRetry.timer != nil {
+			artRetry.timer.Stop()
+		}
+		artRetry.timer, artRetryCh = nil, nil
+		if now.Add(artRetry.delay).Sub(artRetry.since) > grace {
+			logger.Debug("album art still missing after grace period", "room", room, "grace", grace)
+			return
+		}
+		artRetry.timer = clk.NewTimer(artRetry.delay)
+		artRetryCh = artRetry.timer.C()
+	}
+	clearDisplay := func() error {
+		clearCtx, cancel := context.WithTimeout(ctx, opts.DisplayTimeout)
+		defer cancel()
+		return ClearContext(clearCtx, opts.Display)
+	}
+
+	setDimmed := func(on bool) {
+		if dimmer == nil || dimmed == on {
+			return
+		}
+		if err := dimmer.SetDimmed(on); err != nil {
+			logger.Warn("dim display failed", "err", err)
+			return
+		}
+		dimmed = on
+	}
+
+	// enterStateClass restarts the dim and idle timers when the room moves
+	// into a different state class, or when trackChanged so new artwork gets
+	// the full timeout.
+	enterStateClass := func(class string, trackChanged bool) {
+		if class == "" || (class == currentClass && !trackChanged) {
+			return
+		}
+		currentClass = class
+		stopStateTimers()
+		if opts.Display == nil {
+			return
+		}
+		timeout := timeouts.forState(class)
+		setDimmed(false)
+		if dimmer != nil && timeout.Dim > 0 && (timeout.Idle <= 0 || timeout.Dim < timeout.Idle) {
+			dimTimer = clk.NewTimer(timeout.Dim)
+			dimTimerCh = dimTimer.C()
+		}
+		if timeout.Idle > 0 {
+			idleTimer = clk.NewTimer(timeout.Idle)
+			idleTimerCh = idleTimer.C()
+		}
+	}
+
+	stopSilenceTimer := func() {
+		if silenceTimer != nil {
+			silenceTimer.Stop()
+			silenceTimer = nil
+			silenceCh = nil
+		}
+	}
+	defer stopSilenceTimer()
+
+	// trackEndTimer fires shortly after the playing track should have
+	// ended, so a delayed or dropped NOTIFY for the next track does not
+	// leave the old one on the display.
+	var trackEndTimer clock.Timer
+	var trackEndCh <-chan time.Time
+	stopTrackEndTimer := func() {
+		if trackEndTimer != nil {
+			trackEndTimer.Stop()
+			trackEndTimer = nil
+			trackEndCh = nil
+		}
+	}
+	defer stopTrackEndTimer()
+	armTrackEndTimer := func(remaining time.Duration) {
+		stopTrackEndTimer()
+		if remaining < 0 {
+			remaining = 0
 		}
-		idleTimer.Reset(opts.IdleTimeout)
+		trackEndTimer = clk.NewTimer(remaining + trackEndGrace)
+		trackEndCh = trackEndTimer.C()
 	}
 
 	mux := http.NewServeMux()
@@ -92,19 +565,44 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 		body, err := io.ReadAll(r.Body)
 		r.Body.Close()
 		if err != nil {
-			log.Printf("warning: read event body: %v", err)
+			logger.Warn("read event body failed", "err", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		event, err := ParseAVTransportEvent(body)
+		select {
+		case notified <- struct{}{}:
+		default:
+		}
+		event, err := parseAVTransportEvent(body, tracks)
 		if err != nil {
-			log.Printf("warning: parse event: %v", err)
-			log.Printf("warning: event payload: %s", string(body))
+			logger.Warn("parse event failed", "err", err, "payload", string(body))
 		} else {
 			select {
 			case notifyCh <- event:
 			default:
-				log.Printf("warning: dropping event for %s (channel full)", room)
+				logger.Warn("dropping event: channel full", "room", room)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	queuePath := strings.TrimRight(callbackPath, "/") + "/queue"
+	mux.HandleFunc(queuePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "NOTIFY" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if id, ok, err := ParseQueueUpdateID(body); err != nil {
+			logger.Debug("parse queue event failed", "err", err)
+		} else if ok {
+			select {
+			case queueUpdates <- id:
+			default:
 			}
 		}
 		w.WriteHeader(http.StatusOK)
@@ -121,13 +619,16 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 	if !ok || addr == nil {
 		return fmt.Errorf("listen callback address: unexpected address type %T", listener.Addr())
 	}
-	host := net.JoinHostPort(addr.IP.String(), strconv.Itoa(addr.Port))
+	host, err := callbackHost(device, addr, opts.CallbackAdvertise)
+	if err != nil {
+		return err
+	}
 	callbackURL := &url.URL{
 		Scheme: "http",
 		Host:   host,
 		Path:   callbackPath,
 	}
-	logInfo("info: callback listening on %s", callbackURL.String())
+	logger.Debug("callback listening", "url", callbackURL.String())
 
 	go func() {
 		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -135,25 +636,281 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 		}
 	}()
 
-	subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	subCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Subscribe)
 	subscription, err := SubscribeAVTransport(subCtx, device, callbackURL.String(), 30*time.Minute)
 	cancel()
 	if err != nil {
 		_ = server.Shutdown(context.Background())
 		return err
 	}
-	logInfo("info: subscribed to AVTransport events with SID %s", subscription.ID)
+	logger.Debug("subscribed to AVTransport events", "sid", subscription.ID)
+
+	// queueSubscription reports queue edits, so the next track shown in
+	// the status follows changes made from the Sonos app. Only the status
+	// shows the next track, and a speaker without the events still works,
+	// so failures are not fatal.
+	queueCallbackURL := &url.URL{Scheme: "http", Host: host, Path: queuePath}
+	var queueSubscription Subscription
+	lastQueueUpdate := ""
+	subscribeQueue := func() {
+		if opts.OnStatus == nil {
+			return
+		}
+		queueSubscription, lastQueueUpdate = Subscription{}, ""
+		subCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Subscribe)
+		sub, err := SubscribeContentDirectory(subCtx, device, queueCallbackURL.String(), 30*time.Minute)
+		cancel()
+		if err != nil {
+			logger.Debug("queue events unavailable", "room", room, "err", err)
+			return
+		}
+		queueSubscription = sub
+	}
+	renewQueue := func() {
+		if opts.OnStatus == nil {
+			return
+		}
+		if queueSubscription.ID == "" {
+			subscribeQueue()
+			return
+		}
+		renewCtx, cancel := context.WithTimeout(ctx, queueSubscription.timeout())
+		_, err := RenewAVTransport(renewCtx, queueSubscription, queueSubscription.Timeout)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			logger.Debug("renew queue subscription failed; subscribing again", "room", room, "err", err)
+			subscribeQueue()
+		}
+	}
+	subscribeQueue()
 
-	var renewTicker *time.Ticker
+	health := ListenerHealth{Room: room, Speaker: device.IP, Callback: callbackURL.String(), SID: subscription.ID, Subscribed: clk.Now()}
+	reportHealth := func() {
+		if opts.OnHealth != nil {
+			opts.OnHealth(health)
+		}
+	}
+	reportHealth()
+
+	// peers are the other members of the device's group. If the device
+	// disappears while its group plays on, they name the new coordinator.
+	// The same topology tells the listener when its room has been renamed:
+	// named is the device the name was last read for, and nameSeen that
+	// name. After following a group to another room's coordinator the
+	// device's name is not the room's, so only a change of the name seen
+	// for the room's own speaker counts.
+	var peers []Device
+	var named Device
+	nameSeen := ""
+	refreshPeers := func() {
+		topoCtx, topoCancel := context.WithTimeout(ctx, TimeoutsFor(device).Poll)
+		found, name, err := groupPeers(topoCtx, device)
+		topoCancel()
+		if err != nil {
+			logger.Debug("zone group topology unavailable", "room", room, "err", err)
+			return
+		}
+		peers = found
+		previousName := nameSeen
+		sameDevice := sameSpeaker(named, device)
+		named, nameSeen = device, name
+		if name == "" || !sameDevice || previousName == "" || name == previousName {
+			return
+		}
+		if canonicalRoomName(previousName) != canonicalRoomName(room) {
+			return
+		}
+		logger.Info("room renamed", "from", room, "to", name)
+		previous := room
+		room = name
+		health.Room = name
+		reportHealth()
+		device.Metadata.RoomName = name
+		status.Room = name
+		publishStatus()
+		if opts.OnRoomRenamed != nil {
+			opts.OnRoomRenamed(previous, name)
+		}
+	}
+	refreshPeers()
+
+	var roomCheckCh <-chan time.Time
+	if opts.RoomCheckInterval > 0 {
+		roomCheck := clk.NewTicker(opts.RoomCheckInterval)
+		defer roomCheck.Stop()
+		roomCheckCh = roomCheck.C()
+	}
+
+	var renewTicker clock.Ticker
 	var renew <-chan time.Time
-	if subscription.Timeout > 0 {
-		interval := subscription.Timeout / 2
+	// scheduleRenew renews the subscription at half its timeout.
+	scheduleRenew := func(timeout time.Duration) {
+		if timeout <= 0 {
+			return
+		}
+		interval := timeout / 2
 		if interval < time.Minute {
 			interval = time.Minute
 		}
-		renewTicker = time.NewTicker(interval)
-		renew = renewTicker.C
-		defer renewTicker.Stop()
+		if renewTicker == nil {
+			renewTicker = clk.NewTicker(interval)
+			renew = renewTicker.C()
+			return
+		}
+		renewTicker.Reset(interval)
+	}
+	scheduleRenew(subscription.Timeout)
+	defer func() {
+		if renewTicker != nil {
+			renewTicker.Stop()
+		}
+	}()
+
+	// healthTimer fires after HealthCheckInterval without events. A quiet
+	// room is normal, so it only triggers a renewal to prove the
+	// subscription still exists; a speaker that rebooted has forgotten it.
+	healthTimer := clk.NewTimer(opts.HealthCheckInterval)
+	defer healthTimer.Stop()
+	resetHealthTimer := func() {
+		healthTimer.Reset(opts.HealthCheckInterval)
+	}
+
+	// firstEventTimer fires when no event followed the subscription within
+	// PollFallbackAfter; polling then stands in for the callbacks until one
+	// arrives.
+	var firstEventTimer clock.Timer
+	var firstEventCh <-chan time.Time
+	var pollTicker clock.Ticker
+	var pollCh <-chan time.Time
+	lastPolled := ""
+	armFirstEvent := func() {
+		if opts.PollFallbackAfter < 0 || pollTicker != nil {
+			return
+		}
+		if firstEventTimer == nil {
+			firstEventTimer = clk.NewTimer(opts.PollFallbackAfter)
+			firstEventCh = firstEventTimer.C()
+			return
+		}
+		firstEventTimer.Reset(opts.PollFallbackAfter)
+	}
+	stopPolling := func() {
+		if firstEventTimer != nil {
+			firstEventTimer.Stop()
+		}
+		if pollTicker != nil {
+			pollTicker.Stop()
+			pollTicker = nil
+			pollCh = nil
+		}
+	}
+	defer stopPolling()
+	// poll queries the speaker and queues an event when the state or the
+	// track changed since the last poll.
+	poll := func() {
+		pollCtx, pollCancel := context.WithTimeout(ctx, TimeoutsFor(device).Poll)
+		track, err := nowPlaying(pollCtx, tracks, device)
+		pollCancel()
+		if err != nil {
+			logger.Debug("poll now playing failed", "room", room, "err", err)
+			return
+		}
+		key := track.State + "|" + trackSignature(track, "")
+		if key == lastPolled {
+			return
+		}
+		lastPolled = key
+		select {
+		case notifyCh <- AVTransportEvent{TransportState: track.State, Track: track}:
+		default:
+		}
+	}
+	armFirstEvent()
+
+	// reconnect replaces a lost subscription, rediscovering the room's
+	// device first when possible. It retries with backoff and reports false
+	// only when ctx is canceled.
+	reconnect := func() bool {
+		backoff := reconnectInitialBackoff
+		for attempt := 1; ; attempt++ {
+			next := device
+			if opts.Rediscover != nil {
+				found, err := opts.Rediscover(ctx, room)
+				if err != nil {
+					logger.Warn("rediscover room failed", "room", room, "attempt", attempt, "err", err)
+				} else {
+					next = found
+				}
+			}
+			subCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(next).Subscribe)
+			sub, err := SubscribeAVTransport(subCtx, next, callbackURL.String(), 30*time.Minute)
+			cancel()
+			if err != nil && len(peers) > 0 {
+				// The room's speaker is gone; follow its group if the
+				// group carried on under another coordinator.
+				findCtx, findCancel := context.WithTimeout(ctx, 5*time.Second)
+				coordinator, findErr := groupCoordinator(findCtx, peers, device)
+				findCancel()
+				if findErr != nil {
+					logger.Debug("group failover unavailable", "room", room, "err", findErr)
+				} else {
+					logger.Info("room's speaker lost; following its group to the new coordinator",
+						"room", room, "coordinator", RoomName(coordinator), "ip", coordinator.IP)
+					next = coordinator
+					subCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(next).Subscribe)
+					sub, err = SubscribeAVTransport(subCtx, next, callbackURL.String(), 30*time.Minute)
+					cancel()
+				}
+			}
+			if err == nil {
+				moved := next.Location != device.Location || next.IP != device.IP
+				device = next
+				subscription = sub
+				health.Speaker, health.SID, health.Subscribed = device.IP, sub.ID, clk.Now()
+				reportHealth()
+				scheduleRenew(sub.Timeout)
+				armFirstEvent()
+				logger.Info("resubscribed to AVTransport events", "room", room, "ip", device.IP, "sid", sub.ID)
+				subscribeQueue()
+				refreshPeers()
+				if moved && opts.OnDevice != nil {
+					opts.OnDevice(device)
+				}
+				return true
+			}
+			logger.Warn("resubscribe failed", "room", room, "attempt", attempt, "retry_in", backoff, "err", err)
+			select {
+			case <-ctx.Done():
+				return false
+			case <-clk.After(backoff):
+			}
+			backoff *= 2
+			if backoff > reconnectMaxBackoff {
+				backoff = reconnectMaxBackoff
+			}
+		}
+	}
+
+	// renewOrReconnect renews the subscription, reconnecting when the
+	// speaker no longer knows it or cannot be reached.
+	renewOrReconnect := func() {
+		renewCtx, renewCancel := context.WithTimeout(ctx, subscription.timeout())
+		newTimeout, err := RenewAVTransport(renewCtx, subscription, subscription.Timeout)
+		renewCancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("renew subscription failed; reconnecting", "room", room, "err", err)
+			reconnect()
+			return
+		}
+		if newTimeout > 0 {
+			subscription.Timeout = newTimeout
+			scheduleRenew(newTimeout)
+		}
+		renewQueue()
+		refreshPeers()
 	}
 
 	for {
@@ -162,14 +919,26 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 			_ = server.Shutdown(shutdownCtx)
 			shutdownCancel()
-			unsubscribeCtx, unsubscribeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			unsubscribeCtx, unsubscribeCancel := context.WithTimeout(context.Background(), subscription.timeout())
 			err := UnsubscribeAVTransport(unsubscribeCtx, subscription)
 			unsubscribeCancel()
 			if err != nil {
-				log.Printf("warning: unsubscribe failed: %v", err)
+				logger.Warn("unsubscribe failed", "err", err)
+			}
+			if queueSubscription.ID != "" {
+				unsubscribeCtx, unsubscribeCancel := context.WithTimeout(context.Background(), queueSubscription.timeout())
+				_ = UnsubscribeAVTransport(unsubscribeCtx, queueSubscription)
+				unsubscribeCancel()
 			}
 			return nil
 		case ev := <-notifyCh:
+			resetHealthTimer()
+			if ev.MissingMetadata && strings.EqualFold(strings.TrimSpace(ev.TransportState), "PLAYING") {
+				fillCtx, fillCancel := context.WithTimeout(ctx, TimeoutsFor(device).Control)
+				ev.Track = fillMissingMetadata(fillCtx, tracks, device, ev.Track)
+				fillCancel()
+			}
+			ev.Track = opts.ITunes.Enrich(ctx, ev.Track)
 			state := formatStateDisplay(ev.TransportState)
 			if state == "" {
 				state = "Unknown"
@@ -183,21 +952,70 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 			}
 			signature := trackSignature(ev.Track, display)
 			stateChanged := state != lastState || signature != lastTrackSignature
-			shouldPrint := opts.Debug && stateChanged
 			needArt := signature != "" && signature != savedArtSignature
 			idleState := display == "(idle)" || strings.EqualFold(state, "No Media") || strings.EqualFold(state, "Stopped")
 			isPlaying := strings.EqualFold(state, "Playing")
 
-			if isPlaying {
-				stopIdleTimer()
+			if !isPlaying {
+				silenceSignature = ""
+				silent = false
+				stopSilenceTimer()
+			} else if signature != silenceSignature {
+				silenceSignature = signature
+				silent = false
+				stopSilenceTimer()
+				if timeout := opts.SilenceTimeouts[ClassifySource(ev.Track.URI)]; timeout > 0 {
+					silenceTimer = clk.NewTimer(timeout)
+					silenceCh = silenceTimer.C()
+				}
+			}
+			if silent {
+				// Unchanged metadata past the silence timeout counts as idle.
+				isPlaying = false
+				needArt = false
+			}
+
+			if silent {
+				enterStateClass(stateClassStopped, false)
 			} else {
-				startIdleTimer()
+				enterStateClass(stateClass(state), needArt)
 			}
 
-			if opts.Debug {
-				logDebug("debug: event room=%s state=%s display=%s sig=%s stateChanged=%t shouldPrint=%t needArt=%t idle=%t timerActive=%t", room, state, display, signature, stateChanged, shouldPrint, needArt, idleState, idleTimer != nil)
+			if ev.PlayMode != "" {
+				roomPlayMode = ev.PlayMode
+			}
+			if ev.Crossfade != nil {
+				roomCrossfade = *ev.Crossfade
+			}
+			if opts.OnStatus != nil {
+				previous := currentStatus()
+				status = PlaybackStatus{Room: room, State: state, Track: ev.Track, Playing: isPlaying, NextTrack: ev.NextTrack, PlayMode: roomPlayMode, Crossfade: roomCrossfade}
+				if strings.TrimSpace(ev.Track.AlbumArtURI) != "" {
+					status.ArtKey = AlbumArtKey(signature)
+				}
+				// Until the new sample arrives, the same track keeps its
+				// estimated position.
+				positionSampledAt = time.Time{}
+				if trackSignature(previous.Track, "") == trackSignature(ev.Track, "") && previous.Track.Position > 0 {
+					status.Track.Position = previous.Track.Position
+					positionSampledAt = clk.Now()
+				}
+				positionEvent++
+				fetchPosition()
+				if isPlaying && status.Track.Duration > 0 {
+					startProgressTicker()
+				} else {
+					stopProgressTicker()
+				}
+				stopTrackEndTimer()
+				publishStatus()
 			}
 
+			logger.Debug("event",
+				"room", room, "state", state, "display", display, "sig", signature,
+				"stateChanged", stateChanged, "needArt", needArt,
+				"idle", idleState, "class", currentClass, "idleTimer", idleTimer != nil, "dimTimer", dimTimer != nil)
+
 			if !stateChanged && !needArt {
 				continue
 			}
@@ -205,52 +1023,206 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 				lastState = state
 				lastTrackSignature = signature
 			}
-			if shouldPrint {
-				fmt.Printf("[%s] %s – %s | %s\n", time.Now().Format("15:04:05"), room, state, display)
+			if stateChanged && opts.OnChange != nil {
+				opts.OnChange(RoomChange{Time: clk.Now(), Room: room, State: state, Track: display})
 			}
 			if needArt {
-				img, err := SaveAlbumArt(ctx, device, room, ev.Track, signature, cacheToDisk)
+				img, err := saveAlbumArt(ctx, tracks, device, room, ev.Track, signature, cacheToDisk, opts.ArtStorage)
 				if err != nil {
-					log.Printf("warning: album art: %v", err)
-				} else if img != nil {
-					savedArtSignature = signature
+					logger.Warn("album art failed", "err", err)
+				}
+				if errors.Is(err, ErrArtNotFound) && isPlaying {
+					scheduleArtRetry(ev.Track, signature)
+				}
+				if img == nil && !idleState && opts.Placeholder != nil {
+					if placeholder, phErr := opts.Placeholder(ev.Track); phErr != nil {
+						logger.Warn("placeholder art failed", "err", phErr)
+					} else {
+						img = placeholder
+					}
+				}
+				if img == nil && err != nil && !idleState {
+					// Without a placeholder, blank art still replaces the
+					// previous track's, which would otherwise stay up
+					// under the new title.
+					img = blankArt(opts.ArtStorage.Size)
+				}
+				if img != nil {
+					// After a failed fetch the placeholder stands in
+					// until a later event fetches the real art.
+					if err == nil {
+						savedArtSignature = signature
+					}
 					if opts.Display != nil {
-						if err := opts.Display.Show(img); err != nil {
-							log.Printf("warning: update display: %v", err)
+						if err := showArt(img); err != nil {
+							logger.Warn("update display failed", "err", err)
 						} else {
-							displayActive = true
+							displayIdle = false
 						}
 					}
 				}
 			}
-		case <-idleTimerCh:
-			stopIdleTimer()
-			if opts.Display != nil && displayActive {
-				if err := opts.Display.Clear(); err != nil {
-					log.Printf("warning: clear display after idle timeout: %v", err)
+		case <-artRetryCh:
+			artRetry.timer, artRetryCh = nil, nil
+			if artRetry.signature != lastTrackSignature || !strings.EqualFold(lastState, "Playing") || silent || savedArtSignature == artRetry.signature {
+				continue
+			}
+			img, err := saveAlbumArt(ctx, tracks, device, room, artRetry.track, artRetry.signature, cacheToDisk, opts.ArtStorage)
+			if errors.Is(err, ErrArtNotFound) {
+				scheduleArtRetry(artRetry.track, artRetry.signature)
+				continue
+			}
+			if err != nil {
+				logger.Warn("album art failed", "err", err)
+				continue
+			}
+			if img == nil {
+				continue
+			}
+			logger.Debug("album art appeared after a miss", "room", room, "after", clk.Since(artRetry.since))
+			savedArtSignature = artRetry.signature
+			if opts.Display != nil {
+				if err := showArt(img); err != nil {
+					logger.Warn("update display failed", "err", err)
+				} else {
+					displayIdle = false
 				}
-				displayActive = false
 			}
-			savedArtSignature = ""
-			if opts.Debug {
-				logDebug("debug: idle timeout reached; display cleared for room %s", room)
+		case id := <-queueUpdates:
+			// The first event after subscribing only reports the current
+			// update ID.
+			first := lastQueueUpdate == ""
+			if id == lastQueueUpdate {
+				continue
 			}
-		case <-renew:
-			renewCtx, renewCancel := context.WithTimeout(context.Background(), 5*time.Second)
-			newTimeout, err := RenewAVTransport(renewCtx, subscription, subscription.Timeout)
-			renewCancel()
+			lastQueueUpdate = id
+			if first || status.Room == "" {
+				continue
+			}
+			nextCtx, nextCancel := context.WithTimeout(ctx, TimeoutsFor(device).Control)
+			next, err := NextQueuedTrack(nextCtx, device)
+			nextCancel()
+			if err != nil {
+				logger.Debug("next track refresh failed", "room", room, "err", err)
+				continue
+			}
+			if trackSignature(next, "") == trackSignature(status.NextTrack, "") {
+				continue
+			}
+			logger.Debug("queue edited; next track refreshed", "room", room, "next", formatTrackDisplay(next))
+			status.NextTrack = next
+			publishStatus()
+		case sample := <-positions:
+			fetchingPosition = false
+			if sample.event != positionEvent {
+				fetchPosition()
+				continue
+			}
+			if sample.err != nil {
+				logger.Debug("position fetch failed", "err", sample.err)
+				continue
+			}
+			status.Track.Position = sample.elapsed
+			if sample.duration > 0 {
+				status.Track.Duration = sample.duration
+			}
+			positionSampledAt = clk.Now()
+			if status.Playing && status.Track.Duration > 0 {
+				startProgressTicker()
+				armTrackEndTimer(status.Track.Duration - status.Track.Position)
+			}
+			publishStatus()
+		case <-progressCh:
+			publishStatus()
+		case <-trackEndCh:
+			trackEndTimer = nil
+			trackEndCh = nil
+			// Ask the speaker directly; its answer goes through the usual
+			// event handling when the track or state moved on.
+			endCtx, endCancel := context.WithTimeout(ctx, TimeoutsFor(device).Poll)
+			track, err := nowPlaying(endCtx, tracks, device)
+			endCancel()
 			if err != nil {
-				log.Printf("warning: renew subscription failed: %v", err)
+				logger.Debug("end of track poll failed", "room", room, "err", err)
 				continue
 			}
-			if newTimeout > 0 {
-				subscription.Timeout = newTimeout
-				interval := newTimeout / 2
-				if interval < time.Minute {
-					interval = time.Minute
+			if formatStateDisplay(track.State) == status.State && trackSignature(track, "") == trackSignature(status.Track, "") {
+				continue
+			}
+			logger.Debug("track ended without an event; refreshing", "room", room)
+			select {
+			case notifyCh <- AVTransportEvent{TransportState: track.State, Track: track}:
+			default:
+			}
+		case next := <-opts.TimeoutUpdates:
+			timeouts = next
+			if !displayIdle {
+				enterStateClass(currentClass, true)
+			}
+			logger.Debug("state timeouts updated", "room", room)
+		case <-dimTimerCh:
+			dimTimer = nil
+			dimTimerCh = nil
+			if !displayIdle {
+				setDimmed(true)
+			}
+			logger.Debug("dim timeout reached", "room", room, "class", currentClass)
+		case <-idleTimerCh:
+			idleTimer = nil
+			idleTimerCh = nil
+			dimmed = false
+			if opts.Display != nil && !displayIdle {
+				if err := clearDisplay(); err != nil {
+					logger.Warn("clear display after idle timeout failed", "err", err)
+				}
+				displayIdle = true
+			}
+			savedArtSignature = ""
+			logger.Debug("idle timeout reached; display switched to idle screen", "room", room)
+		case <-silenceCh:
+			stopSilenceTimer()
+			silent = true
+			if opts.Display != nil && !displayIdle {
+				if err := clearDisplay(); err != nil {
+					logger.Warn("clear display after silence timeout failed", "err", err)
 				}
-				renewTicker.Reset(interval)
+				displayIdle = true
 			}
+			savedArtSignature = ""
+			status.Playing = false
+			stopProgressTicker()
+			publishStatus()
+			logger.Debug("metadata unchanged past silence timeout; treating room as idle", "room", room)
+		case <-notified:
+			if firstEventTimer != nil {
+				firstEventTimer.Stop()
+			}
+			if pollTicker != nil {
+				stopPolling()
+				lastPolled = ""
+				logger.Info("events arriving; polling stopped", "room", room)
+			}
+			health.LastEvent, health.Polling = clk.Now(), false
+			reportHealth()
+		case <-firstEventCh:
+			logger.Warn("no events received since subscribing; polling the speaker instead",
+				"room", room, "after", opts.PollFallbackAfter, "interval", opts.PollInterval,
+				"callback", callbackURL.String())
+			pollTicker = clk.NewTicker(opts.PollInterval)
+			pollCh = pollTicker.C()
+			health.Polling = true
+			reportHealth()
+			poll()
+		case <-pollCh:
+			poll()
+		case <-renew:
+			renewOrReconnect()
+		case <-roomCheckCh:
+			refreshPeers()
+		case <-healthTimer.C():
+			logger.Debug("no events; checking subscription", "room", room, "after", opts.HealthCheckInterval)
+			renewOrReconnect()
+			healthTimer.Reset(opts.HealthCheckInterval)
 		case err := <-serverErrors:
 			_ = server.Shutdown(context.Background())
 			return fmt.Errorf("callback server error: %w", err)
@@ -258,18 +1230,57 @@ func ListenForEvents(ctx context.Context, device Device, room, callbackPath stri
 	}
 }
 
+// callbackBindAddr returns the address the callback server listens on: bind
+// when given, otherwise the local address that routes to device.
+func callbackBindAddr(device Device, bind string, port int) (*net.TCPAddr, error) {
+	if bind = strings.TrimSpace(bind); bind != "" {
+		// A link-local IPv6 address needs its zone, as in fe80::1%eth0.
+		host, zone, _ := strings.Cut(strings.Trim(bind, "[]"), "%")
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("callback bind address %q is not an IP address", bind)
+		}
+		return &net.TCPAddr{IP: ip, Zone: zone, Port: port}, nil
+	}
+	addr, err := determineLocalCallbackAddr(device)
+	if err != nil {
+		return nil, err
+	}
+	addr.Port = port
+	return addr, nil
+}
+
+// callbackHost returns the host:port speakers send events to. advertise, when
+// set, wins; a bare host keeps the listening port. A server listening on all
+// interfaces advertises the local address that routes to device. IPv6 hosts
+// are bracketed, and the zone of a link-local address is left out since it
+// only means something on this host.
+func callbackHost(device Device, listening *net.TCPAddr, advertise string) (string, error) {
+	port := strconv.Itoa(listening.Port)
+	if advertise = strings.TrimSpace(advertise); advertise != "" {
+		if _, _, err := net.SplitHostPort(advertise); err == nil {
+			return advertise, nil
+		}
+		return net.JoinHostPort(strings.Trim(advertise, "[]"), port), nil
+	}
+	ip := listening.IP
+	if ip == nil || ip.IsUnspecified() {
+		detected, err := determineLocalCallbackAddr(device)
+		if err != nil {
+			return "", err
+		}
+		ip = detected.IP
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
 func determineLocalCallbackAddr(device Device) (*net.TCPAddr, error) {
 	remoteIP := strings.TrimSpace(device.IP)
-	remotePort := "1400"
+	remotePort := devicePort(device)
 
-	if location := strings.TrimSpace(device.Location); location != "" {
-		if u, err := url.Parse(location); err == nil {
-			if host := u.Hostname(); host != "" && remoteIP == "" {
-				remoteIP = host
-			}
-			if port := u.Port(); port != "" {
-				remotePort = port
-			}
+	if remoteIP == "" {
+		if base, err := deviceBaseURL(device); err == nil {
+			remoteIP = base.Hostname()
 		}
 	}
 
@@ -0,0 +1,576 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+func TestStateTimeoutsForState(t *testing.T) {
+	timeouts := StateTimeouts{
+		Paused:  StateTimeout{Dim: time.Minute, Idle: 10 * time.Minute},
+		Stopped: StateTimeout{Idle: 2 * time.Minute},
+	}
+	cases := map[string]StateTimeout{
+		"Playing":  {},
+		"Paused":   timeouts.Paused,
+		"Stopped":  timeouts.Stopped,
+		"No Media": timeouts.Stopped,
+		"Unknown":  timeouts.Stopped,
+	}
+	for state, want := range cases {
+		if got := timeouts.forState(stateClass(state)); got != want {
+			t.Fatalf("timeouts for %q = %+v, want %+v", state, got, want)
+		}
+	}
+	if class := stateClass("Transitioning"); class != "" {
+		t.Fatalf("Transitioning class = %q, want none", class)
+	}
+}
+
+// fakeSpeaker answers AVTransport subscription requests. After a reboot it
+// no longer recognises the subscriptions it handed out.
+func fakeSpeaker(t *testing.T, sid string, rebooted func() bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "SUBSCRIBE" && r.Header.Get("SID") != "" && rebooted():
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case r.Method == "SUBSCRIBE" || r.Method == "UNSUBSCRIBE":
+			w.Header().Set("SID", sid)
+			w.Header().Set("TIMEOUT", "Second-1800")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListenForEventsReconnectsAfterSpeakerReboot(t *testing.T) {
+	old := fakeSpeaker(t, "uuid:old", func() bool { return true })
+	moved := fakeSpeaker(t, "uuid:new", func() bool { return false })
+	oldDevice := Device{IP: "127.0.0.1", Location: old.URL + "/xml/device_description.xml"}
+	newDevice := Device{IP: "127.0.0.1", Location: moved.URL + "/xml/device_description.xml"}
+
+	fake := clock.NewFake(time.Now())
+	devices := make(chan Device, 1)
+	opts := ListenerOptions{
+		Clock:               fake,
+		HealthCheckInterval: time.Minute,
+		Rediscover: func(ctx context.Context, room string) (Device, error) {
+			if room != "Kitchen" {
+				t.Errorf("rediscovered room %q, want Kitchen", room)
+			}
+			return newDevice, nil
+		},
+		OnDevice: func(d Device) { devices <- d },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, oldDevice, "Kitchen", "/events", opts)
+	}()
+	// The health check and renewal timers are armed once subscribed.
+	waitForTimers(t, fake, 2)
+	fake.Advance(time.Minute)
+
+	select {
+	case got := <-devices:
+		if got.Location != newDevice.Location {
+			t.Fatalf("OnDevice location = %q, want %q", got.Location, newDevice.Location)
+		}
+	case err := <-done:
+		t.Fatalf("listener returned before reconnecting: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("listener did not reconnect")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("listener error: %v", err)
+	}
+}
+
+func waitForTimers(t *testing.T, fake *clock.Fake, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for fake.Timers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers armed, want %d", fake.Timers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type clearRecorder struct {
+	cleared chan struct{}
+}
+
+func (d *clearRecorder) Show(image.Image) error { return nil }
+func (d *clearRecorder) Close() error           { return nil }
+
+func (d *clearRecorder) Clear() error {
+	select {
+	case d.cleared <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func TestListenForEventsIdleTimeoutUsesClock(t *testing.T) {
+	callbacks := make(chan string, 1)
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "SUBSCRIBE" && r.Method != "UNSUBSCRIBE" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if callback := r.Header.Get("CALLBACK"); callback != "" {
+			callbacks <- strings.Trim(callback, "<>")
+		}
+		w.Header().Set("SID", "uuid:1")
+		w.Header().Set("TIMEOUT", "Second-1800")
+	}))
+	defer speaker.Close()
+
+	fake := clock.NewFake(time.Now())
+	display := &clearRecorder{cleared: make(chan struct{}, 1)}
+	opts := ListenerOptions{
+		Clock:         fake,
+		Display:       display,
+		StateTimeouts: &StateTimeouts{Stopped: StateTimeout{Idle: time.Minute}},
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	const stopped = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;STOPPED&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	req, err := http.NewRequest("NOTIFY", <-callbacks, strings.NewReader(stopped))
+	if err != nil {
+		t.Fatalf("build notify: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send notify: %v", err)
+	}
+	resp.Body.Close()
+
+	// Health check, renewal, and the idle timer for the stopped room.
+	waitForTimers(t, fake, 3)
+	fake.Advance(59 * time.Second)
+	select {
+	case <-display.cleared:
+		t.Fatalf("display cleared before the idle timeout")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.Advance(time.Second)
+	select {
+	case <-display.cleared:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("display not cleared after the idle timeout")
+	}
+}
+
+func TestListenForEventsPollsWhenNoEventsArrive(t *testing.T) {
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "SUBSCRIBE", "UNSUBSCRIBE":
+			w.Header().Set("SID", "uuid:1")
+			w.Header().Set("TIMEOUT", "Second-1800")
+			return
+		case http.MethodPost:
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		action := r.Header.Get("SOAPACTION")
+		switch {
+		case strings.Contains(action, "GetPositionInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><Track>1</Track><TrackDuration>0:03:30</TrackDuration><RelTime>0:00:10</RelTime><TrackMetaData>&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/"&gt;&lt;item id="1"&gt;&lt;dc:title&gt;Polled Song&lt;/dc:title&gt;&lt;dc:creator&gt;Band&lt;/dc:creator&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</TrackMetaData><TrackURI>x-file-cifs://nas/song.mp3</TrackURI></u:GetPositionInfoResponse></s:Body></s:Envelope>`)
+		case strings.Contains(action, "GetTransportInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetTransportInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentTransportState>PLAYING</CurrentTransportState></u:GetTransportInfoResponse></s:Body></s:Envelope>`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer speaker.Close()
+
+	fake := clock.NewFake(time.Now())
+	statuses := make(chan PlaybackStatus, 16)
+	health := make(chan ListenerHealth, 4)
+	changes := make(chan RoomChange, 4)
+	opts := ListenerOptions{
+		Clock:             fake,
+		PollFallbackAfter: 10 * time.Second,
+		PollInterval:      2 * time.Second,
+		OnStatus:          func(s PlaybackStatus) { statuses <- s },
+		OnHealth:          func(h ListenerHealth) { health <- h },
+		OnChange:          func(c RoomChange) { changes <- c },
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	// Health check, renewal, and the first-event watchdog.
+	waitForTimers(t, fake, 3)
+	if h := <-health; h.SID != "uuid:1" || h.Speaker != "127.0.0.1" || h.Polling || !h.Subscribed.Equal(fake.Now()) {
+		t.Fatalf("health after subscribing = %+v", h)
+	}
+	fake.Advance(9 * time.Second)
+	select {
+	case s := <-statuses:
+		t.Fatalf("status %+v published before the fallback", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.Advance(time.Second)
+	select {
+	case s := <-statuses:
+		if s.Track.Title != "Polled Song" || !s.Playing {
+			t.Fatalf("polled status = %+v, want Polled Song playing", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no status after falling back to polling")
+	}
+	if h := <-health; !h.Polling || !h.LastEvent.IsZero() {
+		t.Fatalf("health while polling = %+v", h)
+	}
+	if c := <-changes; c.Room != "Kitchen" || c.State != "Playing" || c.Track != "Band - Polled Song" || !c.Time.Equal(fake.Now()) {
+		t.Fatalf("change = %+v, want Kitchen playing Band - Polled Song", c)
+	}
+}
+
+func TestListenForEventsRefreshesAtTrackEnd(t *testing.T) {
+	var nextTrack atomic.Bool
+	callbacks := make(chan string, 1)
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "SUBSCRIBE", "UNSUBSCRIBE":
+			if callback := r.Header.Get("CALLBACK"); callback != "" {
+				callbacks <- strings.Trim(callback, "<>")
+			}
+			w.Header().Set("SID", "uuid:1")
+			w.Header().Set("TIMEOUT", "Second-1800")
+			return
+		case http.MethodPost:
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		title, uri := "First", "x-file-cifs://nas/first.mp3"
+		if nextTrack.Load() {
+			title, uri = "Second", "x-file-cifs://nas/second.mp3"
+		}
+		action := r.Header.Get("SOAPACTION")
+		switch {
+		case strings.Contains(action, "GetPositionInfo"):
+			fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><TrackDuration>0:03:30</TrackDuration><RelTime>0:03:25</RelTime><TrackMetaData>&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/"&gt;&lt;item id="1"&gt;&lt;dc:title&gt;%s&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</TrackMetaData><TrackURI>%s</TrackURI></u:GetPositionInfoResponse></s:Body></s:Envelope>`, title, uri)
+		case strings.Contains(action, "GetTransportInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetTransportInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentTransportState>PLAYING</CurrentTransportState></u:GetTransportInfoResponse></s:Body></s:Envelope>`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer speaker.Close()
+
+	fake := clock.NewFake(time.Now())
+	statuses := make(chan PlaybackStatus, 64)
+	opts := ListenerOptions{
+		Clock:             fake,
+		PollFallbackAfter: -1,
+		OnStatus:          func(s PlaybackStatus) { statuses <- s },
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	const playing = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-file-cifs://nas/first.mp3&quot;/&gt;&lt;CurrentTrackDuration val=&quot;0:03:30&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;First&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	req, err := http.NewRequest("NOTIFY", <-callbacks, strings.NewReader(playing))
+	if err != nil {
+		t.Fatalf("build notify: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send notify: %v", err)
+	}
+	resp.Body.Close()
+
+	// The event's status comes first, then the one with the fetched
+	// position, which arms the end-of-track timer.
+	for sampled := false; !sampled; {
+		select {
+		case s := <-statuses:
+			if s.Track.Title != "First" {
+				t.Fatalf("status = %+v, want First", s)
+			}
+			sampled = s.Track.Position == 3*time.Minute+25*time.Second
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no status with the first track's position")
+		}
+	}
+
+	// The speaker moves on without sending an event. Five seconds were left,
+	// plus the grace period.
+	nextTrack.Store(true)
+	fake.Advance(5*time.Second + trackEndGrace)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-statuses:
+			if s.Track.Title == "Second" {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("display not refreshed after the track ended")
+		}
+	}
+}
+
+func TestListenForEventsDoesNotWaitForPosition(t *testing.T) {
+	callbacks := make(chan string, 1)
+	release := make(chan struct{})
+	var positionCalls atomic.Int32
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "SUBSCRIBE", "UNSUBSCRIBE":
+			if callback := r.Header.Get("CALLBACK"); callback != "" {
+				callbacks <- strings.Trim(callback, "<>")
+			}
+			w.Header().Set("SID", "uuid:1")
+			w.Header().Set("TIMEOUT", "Second-1800")
+			return
+		case http.MethodPost:
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.Contains(r.Header.Get("SOAPACTION"), "GetPositionInfo") {
+			// The speaker is too slow to answer while the events arrive.
+			positionCalls.Add(1)
+			<-release
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer speaker.Close()
+	defer close(release)
+
+	statuses := make(chan PlaybackStatus, 16)
+	opts := ListenerOptions{
+		Clock:             clock.NewFake(time.Now()),
+		PollFallbackAfter: -1,
+		OnStatus:          func(s PlaybackStatus) { statuses <- s },
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	callback := <-callbacks
+	for _, title := range []string{"First", "Second", "Third"} {
+		event := `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-file-cifs://nas/` + title + `.mp3&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;` + title + `&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+		req, err := http.NewRequest("NOTIFY", callback, strings.NewReader(event))
+		if err != nil {
+			t.Fatalf("build notify: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("send notify: %v", err)
+		}
+		resp.Body.Close()
+		select {
+		case s := <-statuses:
+			if s.Track.Title != title {
+				t.Fatalf("status = %+v, want %s", s, title)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no status for %s while the position fetch hangs", title)
+		}
+	}
+	if calls := positionCalls.Load(); calls != 1 {
+		t.Fatalf("%d position fetches in flight, want 1", calls)
+	}
+}
+
+type showRecorder struct {
+	shown chan image.Image
+}
+
+func (d *showRecorder) Show(img image.Image) error {
+	d.shown <- img
+	return nil
+}
+func (d *showRecorder) Clear() error { return nil }
+func (d *showRecorder) Close() error { return nil }
+
+func TestListenForEventsShowsPlaceholderWithoutArt(t *testing.T) {
+	callbacks := make(chan string, 1)
+	speaker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "SUBSCRIBE" && r.Method != "UNSUBSCRIBE" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if callback := r.Header.Get("CALLBACK"); callback != "" {
+			callbacks <- strings.Trim(callback, "<>")
+		}
+		w.Header().Set("SID", "uuid:1")
+		w.Header().Set("TIMEOUT", "Second-1800")
+	}))
+	defer speaker.Close()
+
+	placeholder := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	var placeholderFor TrackInfo
+	display := &showRecorder{shown: make(chan image.Image, 1)}
+	opts := ListenerOptions{
+		Clock:             clock.NewFake(time.Now()),
+		Display:           display,
+		PollFallbackAfter: -1,
+		Placeholder: func(track TrackInfo) (image.Image, error) {
+			placeholderFor = track
+			return placeholder, nil
+		},
+	}
+	device := Device{IP: "127.0.0.1", Location: speaker.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	const playing = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-rincon-mp3radio://radio.example/live&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Radio Example&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	req, err := http.NewRequest("NOTIFY", <-callbacks, strings.NewReader(playing))
+	if err != nil {
+		t.Fatalf("build notify: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send notify: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case img := <-display.shown:
+		if img != placeholder || placeholderFor.Title != "Radio Example" {
+			t.Fatalf("shown %p for %+v, want the placeholder for Radio Example", img, placeholderFor)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no placeholder shown for a track without art")
+	}
+}
+
+func TestShowContextChecksDeadlineForPlainDisplays(t *testing.T) {
+	display := &clearRecorder{cleared: make(chan struct{}, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ClearContext(ctx, display); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ClearContext error = %v, want canceled", err)
+	}
+	select {
+	case <-display.cleared:
+		t.Fatalf("display cleared after the context was canceled")
+	default:
+	}
+	if err := ClearContext(context.Background(), display); err != nil {
+		t.Fatalf("ClearContext error: %v", err)
+	}
+	if len(display.cleared) != 1 {
+		t.Fatalf("display not cleared")
+	}
+}
+
+func TestCallbackAddresses(t *testing.T) {
+	device := Device{IP: "127.0.0.1"}
+	bind, err := callbackBindAddr(device, "0.0.0.0", 3400)
+	if err != nil {
+		t.Fatalf("callbackBindAddr error: %v", err)
+	}
+	if !bind.IP.IsUnspecified() || bind.Port != 3400 {
+		t.Fatalf("bind address = %v, want 0.0.0.0:3400", bind)
+	}
+	if _, err := callbackBindAddr(device, "eth0", 0); err == nil {
+		t.Fatalf("callbackBindAddr accepted an interface name")
+	}
+	bind, err = callbackBindAddr(device, "[fe80::1%eth0]", 3400)
+	if err != nil || bind.Zone != "eth0" || bind.String() != "[fe80::1%eth0]:3400" {
+		t.Fatalf("callbackBindAddr(fe80::1%%eth0) = %v, %v", bind, err)
+	}
+
+	listening := &net.TCPAddr{IP: net.IPv4zero, Port: 3400}
+	cases := map[string]string{
+		"":                   "127.0.0.1:3400",
+		"192.168.1.20":       "192.168.1.20:3400",
+		"wall.example:13400": "wall.example:13400",
+		"fd00::20":           "[fd00::20]:3400",
+		"[fd00::20]:13400":   "[fd00::20]:13400",
+	}
+	for advertise, want := range cases {
+		got, err := callbackHost(device, listening, advertise)
+		if err != nil {
+			t.Fatalf("callbackHost(%q) error: %v", advertise, err)
+		}
+		if got != want {
+			t.Fatalf("callbackHost(%q) = %q, want %q", advertise, got, want)
+		}
+	}
+
+	zoned := &net.TCPAddr{IP: net.ParseIP("fe80::20"), Zone: "eth0", Port: 3400}
+	if got, err := callbackHost(device, zoned, ""); err != nil || got != "[fe80::20]:3400" {
+		t.Fatalf("callbackHost(link-local) = %q, %v", got, err)
+	}
+}
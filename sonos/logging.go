@@ -1,29 +1,7 @@
 package sonos
 
-import (
-	"log"
-	"sync/atomic"
-)
+import "musicDisplay/logging"
 
-var debugLogging atomic.Bool
-
-// SetDebugLogging enables or disables verbose logging inside the sonos package.
-func SetDebugLogging(enabled bool) {
-	debugLogging.Store(enabled)
-}
-
-func isDebugLogging() bool {
-	return debugLogging.Load()
-}
-
-func logDebug(format string, args ...interface{}) {
-	if isDebugLogging() {
-		log.Printf(format, args...)
-	}
-}
-
-func logInfo(format string, args ...interface{}) {
-	if isDebugLogging() {
-		log.Printf(format, args...)
-	}
-}
+// logger is the sonos package logger. Its level can be set separately from
+// the rest of the program through the logging config.
+var logger = logging.For("sonos")
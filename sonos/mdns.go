@@ -0,0 +1,304 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	mdnsAddress = "224.0.0.251:5353"
+	mdnsService = "_sonos._tcp.local."
+)
+
+var mdnsUDPAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// DiscoveryMethod selects how Discover looks for speakers.
+type DiscoveryMethod string
+
+const (
+	// DiscoverSSDP sends UPnP M-SEARCH requests. It is the default.
+	DiscoverSSDP DiscoveryMethod = "ssdp"
+	// DiscoverMDNS browses _sonos._tcp over multicast DNS, for networks that
+	// filter SSDP but pass Bonjour.
+	DiscoverMDNS DiscoveryMethod = "mdns"
+	// DiscoverBoth runs SSDP and mDNS together and merges the results.
+	DiscoverBoth DiscoveryMethod = "both"
+)
+
+// ParseDiscoveryMethod parses "ssdp", "mdns", or "both". An empty value
+// selects SSDP.
+func ParseDiscoveryMethod(value string) (DiscoveryMethod, error) {
+	switch method := DiscoveryMethod(strings.ToLower(strings.TrimSpace(value))); method {
+	case "":
+		return DiscoverSSDP, nil
+	case DiscoverSSDP, DiscoverMDNS, DiscoverBoth:
+		return method, nil
+	}
+	return "", fmt.Errorf("sonos: unknown discovery method %q (want ssdp, mdns, or both)", value)
+}
+
+// DiscoverWith finds speakers using method. With DiscoverBoth the two
+// searches run side by side and a device found by both is reported once,
+// preferring the SSDP answer since it carries the full UPnP headers. An error
+// from one search is only returned when the other found nothing.
+func DiscoverWith(ctx context.Context, method DiscoveryMethod, timeout time.Duration, targetRoom string) ([]Device, error) {
+	switch method {
+	case "", DiscoverSSDP:
+		return Discover(ctx, timeout, targetRoom)
+	case DiscoverMDNS:
+		return DiscoverMDNSDevices(ctx, timeout, targetRoom)
+	case DiscoverBoth:
+	default:
+		return nil, fmt.Errorf("sonos: unknown discovery method %q", method)
+	}
+
+	type result struct {
+		devices []Device
+		err     error
+	}
+	mdnsResult := make(chan result, 1)
+	go func() {
+		devices, err := DiscoverMDNSDevices(ctx, timeout, targetRoom)
+		mdnsResult <- result{devices, err}
+	}()
+	ssdpDevices, ssdpErr := Discover(ctx, timeout, targetRoom)
+	mdns := <-mdnsResult
+
+	devices := mergeDevices(ssdpDevices, mdns.devices)
+	if len(devices) == 0 {
+		return nil, errors.Join(ssdpErr, mdns.err)
+	}
+	return devices, nil
+}
+
+// mergeDevices combines discovery results, keeping the first device seen at
+// each IP address.
+func mergeDevices(lists ...[]Device) []Device {
+	seen := make(map[string]struct{})
+	var merged []Device
+	for _, list := range lists {
+		for _, device := range list {
+			key := device.IP
+			if key == "" {
+				key = device.Location
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, device)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].IP < merged[j].IP })
+	return merged
+}
+
+// DiscoverMDNSDevices browses for _sonos._tcp services with multicast DNS.
+// Devices are built from the service instance names, which Sonos sets to
+// "RINCON_<id>@<room>", and point at the UPnP description on DefaultPort;
+// the SRV record advertises the HTTPS API port, which is no use here. If
+// targetRoom is non-empty, discovery stops once that room answers.
+func DiscoverMDNSDevices(ctx context.Context, timeout time.Duration, targetRoom string) ([]Device, error) {
+	if ctx == nil {
+		return nil, errors.New("sonos: nil context")
+	}
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	targetRoomCanonical := canonicalRoomName(targetRoom)
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("sonos: listen UDP: %w", err)
+	}
+	defer conn.Close()
+
+	query, err := mdnsQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(ssdpTimeout)); err != nil {
+		return nil, fmt.Errorf("sonos: set write deadline: %w", err)
+	}
+	if _, err := conn.WriteToUDP(query, mdnsUDPAddr); err != nil {
+		return nil, fmt.Errorf("sonos: write mDNS query: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 9000)
+	records := newMDNSRecords()
+	lastResponse := time.Time{}
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		readDeadline := time.Now().Add(ssdpTimeout)
+		if readDeadline.After(deadline) {
+			readDeadline = deadline
+		}
+		if err := conn.SetReadDeadline(readDeadline); err != nil {
+			return nil, fmt.Errorf("sonos: set read deadline: %w", err)
+		}
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if !lastResponse.IsZero() && time.Since(lastResponse) >= ssdpQuietPeriod {
+					break
+				}
+				continue
+			}
+			return nil, fmt.Errorf("sonos: read mDNS response: %w", err)
+		}
+		if err := records.add(buf[:n], addr.IP); err != nil {
+			// Ignore malformed responses.
+			continue
+		}
+		lastResponse = time.Now()
+		if targetRoomCanonical != "" {
+			for _, device := range records.devices() {
+				if roomMatchesHeader(device, targetRoomCanonical) {
+					return []Device{device}, nil
+				}
+			}
+		}
+	}
+	devices := records.devices()
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	return devices, nil
+}
+
+// mdnsQuery builds a PTR question for the Sonos service. The query goes out
+// from an ephemeral port, so responders answer by unicast.
+func mdnsQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(mdnsService)
+	if err != nil {
+		return nil, fmt.Errorf("sonos: mDNS name: %w", err)
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("sonos: pack mDNS query: %w", err)
+	}
+	return packed, nil
+}
+
+// mdnsRecords accumulates the records of every response, since the PTR,
+// SRV, TXT, and address records for one speaker may arrive separately.
+type mdnsRecords struct {
+	instances []string
+	hosts     map[string]string
+	txt       map[string][]string
+	addrs     map[string]net.IP
+	senders   map[string]net.IP
+}
+
+func newMDNSRecords() *mdnsRecords {
+	return &mdnsRecords{
+		hosts:   make(map[string]string),
+		txt:     make(map[string][]string),
+		addrs:   make(map[string]net.IP),
+		senders: make(map[string]net.IP),
+	}
+}
+
+// add records the answers and additional records of one response.
+func (r *mdnsRecords) add(packet []byte, from net.IP) error {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil {
+		return fmt.Errorf("sonos: parse mDNS response: %w", err)
+	}
+	resources := append(msg.Answers, msg.Additionals...)
+	for _, res := range resources {
+		name := strings.ToLower(res.Header.Name.String())
+		switch body := res.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name != mdnsService {
+				continue
+			}
+			instance := body.PTR.String()
+			if _, ok := r.senders[instance]; !ok {
+				r.instances = append(r.instances, instance)
+				r.senders[instance] = from
+			}
+		case *dnsmessage.SRVResource:
+			r.hosts[name] = strings.ToLower(body.Target.String())
+		case *dnsmessage.TXTResource:
+			r.txt[name] = body.TXT
+		case *dnsmessage.AResource:
+			r.addrs[name] = net.IP(body.A[:])
+		}
+	}
+	return nil
+}
+
+// devices converts the service instances seen so far into devices.
+func (r *mdnsRecords) devices() []Device {
+	devices := make([]Device, 0, len(r.instances))
+	for _, instance := range r.instances {
+		key := strings.ToLower(instance)
+		ip := r.senders[instance]
+		if addr, ok := r.addrs[r.hosts[key]]; ok {
+			ip = addr
+		}
+		if ip == nil {
+			continue
+		}
+		label := strings.TrimSuffix(instance, "."+mdnsService)
+		id, room, _ := strings.Cut(unescapeDNSLabel(label), "@")
+		headers := map[string]string{}
+		if room != "" {
+			headers["ROOMNAME"] = room
+		}
+		for _, entry := range r.txt[key] {
+			if k, v, ok := strings.Cut(entry, "="); ok {
+				headers["MDNS-"+strings.ToUpper(k)] = v
+			}
+		}
+		device := Device{
+			IP:       ip.String(),
+			Location: descriptionURL(ip.String(), DefaultPort),
+			Server:   "Sonos (mDNS)",
+			ST:       ssdpSearch,
+			Headers:  headers,
+			IsSonos:  true,
+		}
+		if strings.HasPrefix(strings.ToUpper(id), "RINCON_") {
+			device.USN = "uuid:" + id + "::" + ssdpSearch
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].IP < devices[j].IP })
+	return devices
+}
+
+// unescapeDNSLabel undoes the \DDD and \X escapes dnsmessage applies to
+// names, so rooms with spaces or punctuation read naturally.
+func unescapeDNSLabel(label string) string {
+	var b strings.Builder
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if c != '\\' || i+1 >= len(label) {
+			b.WriteByte(c)
+			continue
+		}
+		if i+3 < len(label) {
+			if n, err := strconv.Atoi(label[i+1 : i+4]); err == nil && n < 256 {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(label[i+1])
+		i++
+	}
+	return b.String()
+}
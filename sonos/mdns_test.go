@@ -0,0 +1,94 @@
+package sonos
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func mdnsResponse(t *testing.T, instance, host string, ip [4]byte) []byte {
+	t.Helper()
+	name := func(s string) dnsmessage.Name {
+		n, err := dnsmessage.NewName(s)
+		if err != nil {
+			t.Fatalf("NewName(%q): %v", s, err)
+		}
+		return n
+	}
+	header := func(n string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name(n), Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header(mdnsService, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: name(instance)}},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: header(instance, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: name(host), Port: 1443}},
+			{Header: header(instance, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"vers=3", "hhid=Sonos_abc"}}},
+			{Header: header(host, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: ip}},
+		},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	return packed
+}
+
+func TestMDNSRecordsBuildDevices(t *testing.T) {
+	records := newMDNSRecords()
+	packet := mdnsResponse(t, "RINCON_000E58AAAA01400@Living Room."+mdnsService, "sonos-aaaa.local.", [4]byte{192, 168, 1, 23})
+	if err := records.add(packet, net.IPv4(192, 168, 1, 99)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := records.add([]byte("not dns"), nil); err == nil {
+		t.Fatalf("add accepted a malformed packet")
+	}
+
+	devices := records.devices()
+	if len(devices) != 1 {
+		t.Fatalf("got %d devices, want 1", len(devices))
+	}
+	device := devices[0]
+	if device.IP != "192.168.1.23" {
+		t.Fatalf("IP = %q, want the A record address", device.IP)
+	}
+	if want := "http://192.168.1.23:1400/xml/device_description.xml"; device.Location != want {
+		t.Fatalf("Location = %q, want %q", device.Location, want)
+	}
+	if want := "uuid:RINCON_000E58AAAA01400::" + ssdpSearch; device.USN != want {
+		t.Fatalf("USN = %q, want %q", device.USN, want)
+	}
+	if !roomMatchesHeader(device, "living room") {
+		t.Fatalf("room not matched from headers %v", device.Headers)
+	}
+	if device.Headers["MDNS-HHID"] != "Sonos_abc" {
+		t.Fatalf("TXT records not kept: %v", device.Headers)
+	}
+}
+
+func TestMergeDevicesPrefersFirstList(t *testing.T) {
+	ssdp := []Device{{IP: "192.168.1.23", Server: "Linux UPnP/1.0 Sonos"}}
+	mdns := []Device{{IP: "192.168.1.23", Server: "Sonos (mDNS)"}, {IP: "192.168.1.10"}}
+	merged := mergeDevices(ssdp, mdns)
+	if len(merged) != 2 {
+		t.Fatalf("merged %d devices, want 2", len(merged))
+	}
+	if merged[0].IP != "192.168.1.10" || merged[1].Server != ssdp[0].Server {
+		t.Fatalf("merged = %+v", merged)
+	}
+}
+
+func TestParseDiscoveryMethod(t *testing.T) {
+	for input, want := range map[string]DiscoveryMethod{"": DiscoverSSDP, "mDNS": DiscoverMDNS, " both ": DiscoverBoth} {
+		got, err := ParseDiscoveryMethod(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseDiscoveryMethod(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseDiscoveryMethod("upnp"); err == nil {
+		t.Fatalf("ParseDiscoveryMethod accepted upnp")
+	}
+}
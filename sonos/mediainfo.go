@@ -0,0 +1,90 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MediaInfo describes what a room has loaded as a whole, as opposed to the
+// current track: for a radio station the station, for a queue the queue.
+type MediaInfo struct {
+	// URI is the loaded source, e.g. "x-sonosapi-stream:s12345?sid=254".
+	URI string
+	// Title is the source's name from its metadata, such as the station
+	// name. It is empty when the speaker does not know it.
+	Title       string
+	AlbumArtURI string
+}
+
+// CurrentMedia asks device what it has loaded (GetMediaInfo).
+func CurrentMedia(ctx context.Context, device Device) (MediaInfo, error) {
+	body, err := callAVTransport(ctx, device, "GetMediaInfo", "")
+	if err != nil {
+		return MediaInfo{}, err
+	}
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				CurrentURI         string `xml:"CurrentURI"`
+				CurrentURIMetaData string `xml:"CurrentURIMetaData"`
+			} `xml:"GetMediaInfoResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return MediaInfo{}, fmt.Errorf("sonos: decode media info: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return MediaInfo{}, errors.New("sonos: empty media info response")
+	}
+	media := MediaInfo{URI: strings.TrimSpace(envelope.Body.Response.CurrentURI)}
+	meta := strings.TrimSpace(envelope.Body.Response.CurrentURIMetaData)
+	if meta == "" || strings.EqualFold(meta, "not_implemented") {
+		return media, nil
+	}
+	info, err := trackInfoFromMetadata(meta)
+	if err != nil {
+		return media, err
+	}
+	media.Title = info.Title
+	media.AlbumArtURI = info.AlbumArtURI
+	return media, nil
+}
+
+// hasMetadata reports whether track carries anything to show besides its URI.
+func hasMetadata(track TrackInfo) bool {
+	return strings.TrimSpace(track.Title) != "" || strings.TrimSpace(track.Artist) != "" || strings.TrimSpace(track.StreamInfo) != ""
+}
+
+// fillMissingMetadata completes a playing track whose event carried no
+// metadata: first from GetPositionInfo, which some sources answer even when
+// their events do not, then from GetMediaInfo, which names radio stations.
+// The track is returned unchanged when neither helps.
+func fillMissingMetadata(ctx context.Context, tracks *TrackCache, device Device, track TrackInfo) TrackInfo {
+	if polled, err := nowPlaying(ctx, tracks, device); err != nil {
+		logger.Debug("metadata poll failed", "err", err)
+	} else if hasMetadata(polled) {
+		polled.State = track.State
+		if polled.Duration == 0 {
+			polled.Duration = track.Duration
+		}
+		return polled
+	}
+	media, err := CurrentMedia(ctx, device)
+	if err != nil {
+		logger.Debug("media info poll failed", "err", err)
+		return track
+	}
+	if media.Title != "" {
+		track.Title = media.Title
+	}
+	if track.AlbumArtURI == "" {
+		track.AlbumArtURI = media.AlbumArtURI
+	}
+	if track.URI == "" {
+		track = withSource(TrackInfo{Title: track.Title, AlbumArtURI: track.AlbumArtURI, URI: media.URI, Duration: track.Duration})
+	}
+	return track
+}
@@ -0,0 +1,50 @@
+package sonos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFillMissingMetadataUsesMediaInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(payload), "GetPositionInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><TrackMetaData>NOT_IMPLEMENTED</TrackMetaData><TrackURI>x-sonosapi-stream:s1234?sid=254</TrackURI></u:GetPositionInfoResponse></s:Body></s:Envelope>`)
+		case strings.Contains(string(payload), "GetTransportInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetTransportInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentTransportState>PLAYING</CurrentTransportState></u:GetTransportInfoResponse></s:Body></s:Envelope>`)
+		case strings.Contains(string(payload), "GetMediaInfo"):
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetMediaInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentURI>x-sonosapi-stream:s1234?sid=254</CurrentURI><CurrentURIMetaData>&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/"&gt;&lt;item&gt;&lt;dc:title&gt;Jazz FM&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</CurrentURIMetaData></u:GetMediaInfoResponse></s:Body></s:Envelope>`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+
+	media, err := CurrentMedia(context.Background(), device)
+	if err != nil || media.Title != "Jazz FM" || media.URI != "x-sonosapi-stream:s1234?sid=254" {
+		t.Fatalf("CurrentMedia = %+v, %v", media, err)
+	}
+
+	track := fillMissingMetadata(context.Background(), nil, device, withSource(TrackInfo{URI: "x-sonosapi-stream:s1234?sid=254"}))
+	if track.Title != "Jazz FM" || track.Service != "TuneIn" {
+		t.Fatalf("filled track = %+v, want the station name", track)
+	}
+}
+
+func TestParseAVTransportEventMissingMetadata(t *testing.T) {
+	const body = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;NOT_IMPLEMENTED&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-rincon-stream:RINCON_1&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`
+	event, err := ParseAVTransportEvent([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseAVTransportEvent error: %v", err)
+	}
+	if !event.MissingMetadata || event.Track.URI != "x-rincon-stream:RINCON_1" {
+		t.Fatalf("event = %+v, want missing metadata with the URI kept", event)
+	}
+}
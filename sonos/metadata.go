@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 )
 
 // DeviceMetadata holds fields extracted from the UPnP device description XML.
@@ -21,6 +22,8 @@ type DeviceMetadata struct {
 	ModelNumber     string
 	SerialNumber    string
 	SoftwareVersion string
+	// UDN is the unique device name, "uuid:RINCON_..." on Sonos speakers.
+	UDN string
 }
 
 // enrichMetadata pulls the device description XML and updates metadata fields on the Device.
@@ -34,7 +37,7 @@ func enrichMetadata(ctx context.Context, device Device) (Device, error) {
 		return device, fmt.Errorf("sonos: create metadata request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: TimeoutsFor(device).Description}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -126,6 +129,8 @@ func parseDeviceDescription(body []byte) (DeviceMetadata, error) {
 				meta.SerialNumber = value
 			case "softwareVersion":
 				meta.SoftwareVersion = value
+			case "UDN":
+				meta.UDN = value
 			}
 		}
 	}
@@ -169,7 +174,7 @@ func EnrichDevices(ctx context.Context, devices []Device) ([]Device, error) {
 			return enriched, ctx.Err()
 		}
 
-		localCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		localCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Description)
 		updated, err := enrichMetadata(localCtx, device)
 		cancel()
 		if err != nil {
@@ -183,3 +188,61 @@ func EnrichDevices(ctx context.Context, devices []Device) ([]Device, error) {
 
 	return enriched, firstErr
 }
+
+// ValidateDevice checks that a remembered device is still a Sonos speaker at
+// its address, fetching its description. When both the device and the
+// description carry an identity, they must match, so an address now used by a
+// different speaker is rejected.
+func ValidateDevice(ctx context.Context, device Device) (Device, error) {
+	if device.Location == "" {
+		return device, errors.New("sonos: validate device: location unknown")
+	}
+	enriched, err := enrichMetadata(ctx, device)
+	if err != nil {
+		return device, err
+	}
+	if !enriched.IsSonos {
+		return device, fmt.Errorf("sonos: validate device: %s is not a Sonos speaker", device.Location)
+	}
+	if udn := enriched.Metadata.UDN; device.USN != "" && udn != "" && !strings.HasPrefix(device.USN, udn) {
+		return device, fmt.Errorf("sonos: validate device: %s is now %s, not %s", device.Location, udn, device.USN)
+	}
+	return enriched, nil
+}
+
+// NewStaticDevice builds a device for a speaker whose address is known, so
+// discovery can be skipped. port 0 means DefaultPort. The description XML is
+// fetched for the metadata; when that fails the device is still returned,
+// named after room, together with the error.
+func NewStaticDevice(ctx context.Context, room, host string, port int) (Device, error) {
+	host = strings.Trim(strings.TrimSpace(host), "[]")
+	if host == "" {
+		return Device{}, errors.New("sonos: static device address is empty")
+	}
+	portText := DefaultPort
+	if port > 0 {
+		portText = strconv.Itoa(port)
+	}
+	device := Device{
+		IP:       host,
+		Location: descriptionURL(host, portText),
+		Server:   "static",
+		Headers:  map[string]string{},
+		IsSonos:  true,
+	}
+	if room = strings.TrimSpace(room); room != "" {
+		device.Headers["ROOMNAME"] = room
+	}
+
+	localCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Description)
+	defer cancel()
+	enriched, err := enrichMetadata(localCtx, device)
+	if err != nil {
+		return device, err
+	}
+	if room != "" {
+		// The configured name wins over the one the speaker reports.
+		enriched.Metadata.RoomName = room
+	}
+	return enriched, nil
+}
@@ -2,6 +2,7 @@ package sonos
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -24,6 +25,7 @@ const sonosXML = `<?xml version="1.0" encoding="utf-8"?>
     <modelNumber>S13</modelNumber>
     <serialNumber>RINCON_12345</serialNumber>
     <softwareVersion>65.1-123456</softwareVersion>
+    <UDN>uuid:RINCON_12345</UDN>
     <deviceList>
       <device>
         <deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType>
@@ -166,3 +168,61 @@ func TestEnrichDevicesUsesContextDeadline(t *testing.T) {
 		t.Fatalf("expected context deadline error")
 	}
 }
+
+func TestNewStaticDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xml/device_description.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(sonosXML))
+	}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+
+	device, err := NewStaticDevice(context.Background(), "", addr.IP.String(), addr.Port)
+	if err != nil {
+		t.Fatalf("NewStaticDevice error: %v", err)
+	}
+	if device.Location != server.URL+"/xml/device_description.xml" {
+		t.Fatalf("Location = %q", device.Location)
+	}
+	if got := deriveRoomName(device); got != "Kitchen" {
+		t.Fatalf("room = %q, want the described Kitchen", got)
+	}
+
+	server.Close()
+	device, err = NewStaticDevice(context.Background(), "Den", addr.IP.String(), addr.Port)
+	if err == nil {
+		t.Fatalf("NewStaticDevice succeeded without a description")
+	}
+	if !device.IsSonos || deriveRoomName(device) != "Den" {
+		t.Fatalf("unreachable device = %+v, want the configured room", device)
+	}
+	if _, err := NewStaticDevice(context.Background(), "Den", " ", 0); err == nil {
+		t.Fatalf("NewStaticDevice accepted an empty address")
+	}
+}
+
+func TestValidateDeviceChecksIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sonosXML))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	same := Device{Location: server.URL + "/xml/device_description.xml", USN: "uuid:RINCON_12345::urn:schemas-upnp-org:device:ZonePlayer:1"}
+	got, err := ValidateDevice(ctx, same)
+	if err != nil {
+		t.Fatalf("ValidateDevice error: %v", err)
+	}
+	if got.Metadata.UDN != "uuid:RINCON_12345" || !got.IsSonos {
+		t.Fatalf("validated device = %+v", got)
+	}
+
+	other := same
+	other.USN = "uuid:RINCON_99999::urn:schemas-upnp-org:device:ZonePlayer:1"
+	if _, err := ValidateDevice(ctx, other); err == nil {
+		t.Fatalf("ValidateDevice accepted a different speaker at the address")
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"fmt"
 	"html"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,10 +24,31 @@ type TrackInfo struct {
 	URI         string
 	State       string
 	AlbumArtURI string
+	// Position is how far into the track playback was when the track was
+	// queried (GetPositionInfo RelTime). It is zero for event-derived tracks.
+	Position time.Duration
+	// Duration is the track length, or zero for streams and unknown lengths.
+	Duration time.Duration
+	// Source classifies the track URI (radio, line-in, TV, AirPlay, ...).
+	Source SourceKind
+	// Service is the music service the URI names, such as "Spotify", or "".
+	Service string
+	// Class is the item's upnp:class, e.g. "object.item.audioItem.musicTrack"
+	// or "object.item.audioItem.audioBook", so callers can treat item types
+	// this package does not special-case differently.
+	Class string
+	// RawDIDL is the track's DIDL-Lite metadata as unescaped XML, for fields
+	// TrackInfo does not carry. It is empty when the speaker sent none.
+	RawDIDL string
 }
 
 // NowPlaying queries a Sonos device for the currently playing track metadata.
 func NowPlaying(ctx context.Context, device Device) (TrackInfo, error) {
+	return nowPlaying(ctx, nil, device)
+}
+
+// nowPlaying is NowPlaying with the session's track cache.
+func nowPlaying(ctx context.Context, tracks *TrackCache, device Device) (TrackInfo, error) {
 	if ctx == nil {
 		return TrackInfo{}, errors.New("sonos: nil context")
 	}
@@ -38,8 +59,8 @@ func NowPlaying(ctx context.Context, device Device) (TrackInfo, error) {
 	}
 
 	payload := buildGetPositionInfoPayload()
-	logDebug("debug: querying now playing at %s", controlURL)
-	client := &http.Client{Timeout: 5 * time.Second}
+	logger.Debug("querying now playing", "url", controlURL)
+	client := &http.Client{Timeout: TimeoutsFor(device).Control}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, bytes.NewReader(payload))
 	if err != nil {
 		return TrackInfo{}, fmt.Errorf("sonos: create now playing request: %w", err)
@@ -71,18 +92,59 @@ func NowPlaying(ctx context.Context, device Device) (TrackInfo, error) {
 		return TrackInfo{}, err
 	}
 
-	info, err := buildTrackInfo(position)
+	info, err := buildTrackInfo(tracks, position)
 	if err != nil {
 		return TrackInfo{}, err
 	}
 	if state, err := fetchTransportState(ctx, client, controlURL); err != nil {
-		logDebug("debug: transport state fetch failed: %v", err)
+		logger.Debug("transport state fetch failed", "err", err)
 	} else {
 		info.State = state
 	}
 	return info, nil
 }
 
+// FetchPosition queries how far playback has progressed through the current
+// track and how long the track is. Either value is zero when the device does
+// not report it (for example on radio streams).
+func FetchPosition(ctx context.Context, device Device) (elapsed, duration time.Duration, err error) {
+	body, err := callAVTransport(ctx, device, "GetPositionInfo", "")
+	if err != nil {
+		return 0, 0, err
+	}
+	position, err := parsePositionInfoResponse(body)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseTrackTime(position.RelTime), parseTrackTime(position.TrackDuration), nil
+}
+
+// parseTrackTime converts the H:MM:SS values used by AVTransport into a
+// duration. Unknown values such as NOT_IMPLEMENTED yield zero.
+func parseTrackTime(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if idx := strings.Index(value, "."); idx >= 0 {
+		value = value[:idx]
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var total time.Duration
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total += time.Duration(n) * units[i]
+	}
+	return total
+}
+
 func buildGetPositionInfoPayload() []byte {
 	const payload = `<?xml version="1.0" encoding="utf-8"?>
 <s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
@@ -155,6 +217,8 @@ type positionInfoBody struct {
 type positionInfoResponse struct {
 	TrackMetaData string `xml:"TrackMetaData"`
 	TrackURI      string `xml:"TrackURI"`
+	TrackDuration string `xml:"TrackDuration"`
+	RelTime       string `xml:"RelTime"`
 }
 
 type soapFault struct {
@@ -241,18 +305,32 @@ type didlItem struct {
 	ProgramTitle string
 	RadioShow    string
 	AlbumArtURI  string
+	Class        string
 }
 
-func buildTrackInfo(resp positionInfoResponse) (TrackInfo, error) {
+// buildTrackInfo turns a GetPositionInfo response into a TrackInfo, taking
+// the parsed metadata from tracks when the same metadata was seen before.
+func buildTrackInfo(tracks *TrackCache, resp positionInfoResponse) (TrackInfo, error) {
 	info := TrackInfo{
-		URI: strings.TrimSpace(resp.TrackURI),
+		URI:      strings.TrimSpace(resp.TrackURI),
+		Position: parseTrackTime(resp.RelTime),
+		Duration: parseTrackTime(resp.TrackDuration),
 	}
-
 	meta := strings.TrimSpace(resp.TrackMetaData)
 	if meta == "" {
-		return info, nil
+		return withSource(info), nil
+	}
+	parsed, err := tracks.parse(info.URI, meta, trackInfoFromMetadata)
+	if err != nil {
+		return withSource(info), err
 	}
+	parsed.URI, parsed.Position, parsed.Duration = info.URI, info.Position, info.Duration
+	return withSource(parsed), nil
+}
 
+// trackInfoFromMetadata parses the DIDL-Lite metadata of a track.
+func trackInfoFromMetadata(meta string) (TrackInfo, error) {
+	var info TrackInfo
 	decoded := sanitizeInvalidEntities(html.UnescapeString(meta))
 	item, err := parseTrackMetadata(decoded)
 	if err != nil {
@@ -264,6 +342,8 @@ func buildTrackInfo(resp positionInfoResponse) (TrackInfo, error) {
 	info.Album = strings.TrimSpace(item.Album)
 	info.StreamInfo = strings.TrimSpace(item.StreamInfo)
 	info.AlbumArtURI = strings.TrimSpace(item.AlbumArtURI)
+	info.Class = strings.TrimSpace(item.Class)
+	info.RawDIDL = decoded
 
 	if info.Title == "" {
 		if strings.TrimSpace(item.ProgramTitle) != "" {
@@ -280,6 +360,7 @@ func buildTrackInfo(resp positionInfoResponse) (TrackInfo, error) {
 
 // FetchCurrentAlbumArt downloads the album artwork for the track currently playing on the device.
 // The returned byte slice contains the raw image data and contentType reports the HTTP Content-Type header, if any.
+// Downloads over DefaultMaxArtBytes are refused with ErrArtRejected.
 func FetchCurrentAlbumArt(ctx context.Context, device Device) ([]byte, string, error) {
 	if ctx == nil {
 		return nil, "", errors.New("sonos: nil context")
@@ -304,7 +385,7 @@ func FetchCurrentAlbumArt(ctx context.Context, device Device) ([]byte, string, e
 		return nil, "", fmt.Errorf("sonos: create album art request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: TimeoutsFor(device).Art}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("sonos: fetch album art: %w", err)
@@ -316,9 +397,9 @@ func FetchCurrentAlbumArt(ctx context.Context, device Device) ([]byte, string, e
 		return nil, "", fmt.Errorf("sonos: album art http status %s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := ArtFetch{}.read(resp)
 	if err != nil {
-		return nil, "", fmt.Errorf("sonos: read album art body: %w", err)
+		return nil, "", err
 	}
 
 	return data, resp.Header.Get("Content-Type"), nil
@@ -348,26 +429,15 @@ func resolveAlbumArtURL(device Device, artURI string) (string, error) {
 }
 
 func albumArtBaseURL(device Device) (*url.URL, error) {
-	if loc := strings.TrimSpace(device.Location); loc != "" {
-		base, err := url.Parse(loc)
-		if err == nil && base.Scheme != "" && base.Host != "" {
-			base.Path = "/"
-			base.RawQuery = ""
-			base.Fragment = ""
-			return base, nil
-		}
+	base, err := deviceBaseURL(device)
+	if err == nil {
+		return base, nil
 	}
-
+	// A malformed location still leaves the IP address to fall back on.
 	if ip := strings.TrimSpace(device.IP); ip != "" {
-		host := net.JoinHostPort(ip, "1400")
-		base, err := url.Parse("http://" + host)
-		if err != nil {
-			return nil, fmt.Errorf("sonos: construct album art base url: %w", err)
-		}
-		return base, nil
+		return deviceBaseURL(Device{IP: ip})
 	}
-
-	return nil, errors.New("sonos: album art base url unavailable")
+	return nil, fmt.Errorf("sonos: album art base url unavailable: %w", err)
 }
 
 func parseTrackMetadata(xmlString string) (didlItem, error) {
@@ -428,6 +498,8 @@ func parseTrackMetadata(xmlString string) (didlItem, error) {
 					item.Album = value
 				case "albumArtURI":
 					item.AlbumArtURI = value
+				case "class":
+					item.Class = value
 				}
 			case "urn:schemas-rinconnetworks-com:metadata-1-0/":
 				switch field.Local {
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNowPlayingTrack(t *testing.T) {
@@ -86,6 +87,55 @@ func TestNowPlayingTrack(t *testing.T) {
 	if got, want := info.AlbumArtURI, "/art.jpg"; got != want {
 		t.Fatalf("AlbumArtURI = %q, want %q", got, want)
 	}
+	if got, want := info.Duration, 3*time.Minute+30*time.Second; got != want {
+		t.Fatalf("Duration = %s, want %s", got, want)
+	}
+}
+
+func TestFetchPosition(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1">
+      <Track>1</Track>
+      <TrackDuration>0:04:05</TrackDuration>
+      <TrackMetaData></TrackMetaData>
+      <TrackURI>x-sonos-spotify:spotify%3atrack%3a123</TrackURI>
+      <RelTime>0:01:02</RelTime>
+    </u:GetPositionInfoResponse>
+  </s:Body>
+</s:Envelope>`
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	elapsed, duration, err := FetchPosition(context.Background(), Device{Location: server.URL + "/xml/device_description.xml"})
+	if err != nil {
+		t.Fatalf("FetchPosition error: %v", err)
+	}
+	if want := time.Minute + 2*time.Second; elapsed != want {
+		t.Fatalf("elapsed = %s, want %s", elapsed, want)
+	}
+	if want := 4*time.Minute + 5*time.Second; duration != want {
+		t.Fatalf("duration = %s, want %s", duration, want)
+	}
+}
+
+func TestParseTrackTime(t *testing.T) {
+	cases := map[string]time.Duration{
+		"0:03:30":         3*time.Minute + 30*time.Second,
+		"1:00:01":         time.Hour + time.Second,
+		"0:00:05.250":     5 * time.Second,
+		"NOT_IMPLEMENTED": 0,
+		"":                0,
+		"3:30":            0,
+	}
+	for input, want := range cases {
+		if got := parseTrackTime(input); got != want {
+			t.Fatalf("parseTrackTime(%q) = %s, want %s", input, got, want)
+		}
+	}
 }
 
 func TestNowPlayingFault(t *testing.T) {
@@ -124,10 +174,10 @@ func TestNowPlayingFault(t *testing.T) {
 
 func TestBuildTrackInfoParsesMetadata(t *testing.T) {
 	meta := positionInfoResponse{
-		TrackMetaData: `&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:r="urn:schemas-rinconnetworks-com:metadata-1-0/"&gt;&lt;item&gt;&lt;dc:title&gt;Unit Test Song&lt;/dc:title&gt;&lt;dc:creator&gt;Tester&lt;/dc:creator&gt;&lt;upnp:albumArtURI&gt;/cover.png&lt;/upnp:albumArtURI&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;`,
+		TrackMetaData: `&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:r="urn:schemas-rinconnetworks-com:metadata-1-0/"&gt;&lt;item&gt;&lt;dc:title&gt;Unit Test Song&lt;/dc:title&gt;&lt;dc:creator&gt;Tester&lt;/dc:creator&gt;&lt;upnp:albumArtURI&gt;/cover.png&lt;/upnp:albumArtURI&gt;&lt;upnp:class&gt;object.item.audioItem.audioBook&lt;/upnp:class&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;`,
 	}
 
-	info, err := buildTrackInfo(meta)
+	info, err := buildTrackInfo(nil, meta)
 	if err != nil {
 		t.Fatalf("buildTrackInfo error: %v", err)
 	}
@@ -140,6 +190,12 @@ func TestBuildTrackInfoParsesMetadata(t *testing.T) {
 	if info.AlbumArtURI != "/cover.png" {
 		t.Fatalf("AlbumArtURI = %q, want /cover.png", info.AlbumArtURI)
 	}
+	if info.Class != "object.item.audioItem.audioBook" {
+		t.Fatalf("Class = %q, want object.item.audioItem.audioBook", info.Class)
+	}
+	if !strings.HasPrefix(info.RawDIDL, "<DIDL-Lite") || !strings.Contains(info.RawDIDL, "<dc:title>Unit Test Song</dc:title>") {
+		t.Fatalf("RawDIDL = %q, want the unescaped DIDL-Lite document", info.RawDIDL)
+	}
 }
 
 func TestBuildTrackInfoRepairsInvalidEntities(t *testing.T) {
@@ -147,7 +203,7 @@ func TestBuildTrackInfoRepairsInvalidEntities(t *testing.T) {
 		TrackMetaData: `&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"&gt;&lt;item&gt;&lt;dc:title&gt;Song &amp;amp; Dance&lt;/dc:title&gt;&lt;upnp:album&gt;Club &vli Nights&lt;/upnp:album&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;`,
 	}
 
-	info, err := buildTrackInfo(meta)
+	info, err := buildTrackInfo(nil, meta)
 	if err != nil {
 		t.Fatalf("buildTrackInfo error: %v", err)
 	}
@@ -0,0 +1,39 @@
+package sonos
+
+import "strings"
+
+// PlayMode is a room's play mode as the speaker names it, combining shuffle
+// and repeat.
+type PlayMode string
+
+// Play modes of the AVTransport service.
+const (
+	PlayModeNormal           PlayMode = "NORMAL"
+	PlayModeRepeatAll        PlayMode = "REPEAT_ALL"
+	PlayModeRepeatOne        PlayMode = "REPEAT_ONE"
+	PlayModeShuffleNoRepeat  PlayMode = "SHUFFLE_NOREPEAT"
+	PlayModeShuffle          PlayMode = "SHUFFLE"
+	PlayModeShuffleRepeatOne PlayMode = "SHUFFLE_REPEAT_ONE"
+)
+
+// parsePlayMode normalises a play mode from the speaker; unknown values are
+// kept as sent.
+func parsePlayMode(raw string) PlayMode {
+	return PlayMode(strings.ToUpper(strings.TrimSpace(raw)))
+}
+
+// Shuffle reports whether the queue plays in random order.
+func (m PlayMode) Shuffle() bool {
+	return strings.HasPrefix(string(m), "SHUFFLE")
+}
+
+// RepeatAll reports whether the queue starts over after its last track.
+// Sonos names shuffle with repeat plain SHUFFLE.
+func (m PlayMode) RepeatAll() bool {
+	return m == PlayModeRepeatAll || m == PlayModeShuffle
+}
+
+// RepeatOne reports whether the current track repeats.
+func (m PlayMode) RepeatOne() bool {
+	return m == PlayModeRepeatOne || m == PlayModeShuffleRepeatOne
+}
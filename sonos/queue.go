@@ -0,0 +1,164 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultClass is the UPnP class Metadata.DIDL uses when none is set.
+const DefaultClass = "object.item.audioItem.musicTrack"
+
+// Metadata describes what SetAVTransportURI or AddURIToQueue plays, for the
+// speaker and the Sonos app to show.
+type Metadata struct {
+	// ID and ParentID identify the item with its music service, such as
+	// "10032020spotify%3atrack%3a…"; both default to "-1".
+	ID       string
+	ParentID string
+	Title    string
+	Creator  string
+	Album    string
+	// AlbumArtURI is the artwork's URL.
+	AlbumArtURI string
+	// Class is the item's UPnP class (DefaultClass when empty), such as
+	// "object.container.playlistContainer" for a playlist.
+	Class string
+	// Desc names the music service account that plays the item, such as
+	// "SA_RINCON2311_X_#Svc2311-0-Token" for Spotify. Items the speaker can
+	// fetch itself, such as files on a share or plain web streams, need
+	// none.
+	Desc string
+}
+
+// DIDL returns m as the DIDL-Lite document the AVTransport actions take.
+func (m Metadata) DIDL() string {
+	text := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	or := func(s, fallback string) string {
+		if strings.TrimSpace(s) == "" {
+			return fallback
+		}
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:r="urn:schemas-rinconnetworks-com:metadata-1-0/" xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/">`)
+	b.WriteString(`<item id="` + text(or(m.ID, "-1")) + `" parentID="` + text(or(m.ParentID, "-1")) + `" restricted="true">`)
+	if m.Title != "" {
+		b.WriteString("<dc:title>" + text(m.Title) + "</dc:title>")
+	}
+	if m.Creator != "" {
+		b.WriteString("<dc:creator>" + text(m.Creator) + "</dc:creator>")
+	}
+	if m.Album != "" {
+		b.WriteString("<upnp:album>" + text(m.Album) + "</upnp:album>")
+	}
+	if m.AlbumArtURI != "" {
+		b.WriteString("<upnp:albumArtURI>" + text(m.AlbumArtURI) + "</upnp:albumArtURI>")
+	}
+	b.WriteString("<upnp:class>" + text(or(m.Class, DefaultClass)) + "</upnp:class>")
+	if m.Desc != "" {
+		b.WriteString(`<desc id="cdudn" nameSpace="urn:schemas-rinconnetworks-com:metadata-1-0/">` + text(m.Desc) + "</desc>")
+	}
+	b.WriteString("</item></DIDL-Lite>")
+	return b.String()
+}
+
+// SetAVTransportURI points the room coordinated by device at uri, described
+// by the DIDL-Lite document metadata, which may be empty. Playback starts
+// with Play.
+func SetAVTransportURI(ctx context.Context, device Device, uri, metadata string) error {
+	args := "\n      <CurrentURI>" + html.EscapeString(uri) + "</CurrentURI>" +
+		"\n      <CurrentURIMetaData>" + html.EscapeString(metadata) + "</CurrentURIMetaData>"
+	_, err := callAVTransport(ctx, device, "SetAVTransportURI", args)
+	return err
+}
+
+// QueueURI returns the URI that plays the queue of the room coordinated by
+// device, for SetAVTransportURI.
+func QueueURI(device Device) (string, error) {
+	id := strings.TrimPrefix(device.Metadata.UDN, "uuid:")
+	if id == "" {
+		return "", errors.New("sonos: device UDN unknown, cannot address its queue")
+	}
+	return "x-rincon-queue:" + id + "#0", nil
+}
+
+// AddURIToQueue adds uri, described by the DIDL-Lite document metadata, to
+// the queue of the room coordinated by device: at the end, or after the
+// current track when next is set. A container, such as a playlist, adds all
+// its tracks. It returns the queue position, from 1, of the first track
+// added.
+func AddURIToQueue(ctx context.Context, device Device, uri, metadata string, next bool) (int, error) {
+	asNext := "0"
+	if next {
+		asNext = "1"
+	}
+	args := "\n      <EnqueuedURI>" + html.EscapeString(uri) + "</EnqueuedURI>" +
+		"\n      <EnqueuedURIMetaData>" + html.EscapeString(metadata) + "</EnqueuedURIMetaData>" +
+		"\n      <DesiredFirstTrackNumberEnqueued>0</DesiredFirstTrackNumberEnqueued>" +
+		"\n      <EnqueueAsNext>" + asNext + "</EnqueueAsNext>"
+	body, err := callAVTransport(ctx, device, "AddURIToQueue", args)
+	if err != nil {
+		return 0, err
+	}
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				FirstTrackNumberEnqueued int `xml:"FirstTrackNumberEnqueued"`
+			} `xml:"AddURIToQueueResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return 0, fmt.Errorf("sonos: decode queue response: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return 0, errors.New("sonos: empty queue response")
+	}
+	return envelope.Body.Response.FirstTrackNumberEnqueued, nil
+}
+
+// ClearQueue removes every track from the queue of the room coordinated by
+// device.
+func ClearQueue(ctx context.Context, device Device) error {
+	_, err := callAVTransport(ctx, device, "RemoveAllTracksFromQueue", "")
+	return err
+}
+
+// Seek moves the current track of the room coordinated by device to
+// position, whole seconds from its start.
+func Seek(ctx context.Context, device Device, position time.Duration) error {
+	if position < 0 {
+		return fmt.Errorf("sonos: seek to %v: position must not be negative", position)
+	}
+	return seek(ctx, device, "REL_TIME", formatTrackTime(position))
+}
+
+// SeekTrack moves the room coordinated by device to track n of its queue,
+// counted from 1. The room must be playing its queue.
+func SeekTrack(ctx context.Context, device Device, n int) error {
+	if n < 1 {
+		return fmt.Errorf("sonos: seek to track %d: tracks are counted from 1", n)
+	}
+	return seek(ctx, device, "TRACK_NR", strconv.Itoa(n))
+}
+
+func seek(ctx context.Context, device Device, unit, target string) error {
+	args := "\n      <Unit>" + unit + "</Unit>\n      <Target>" + target + "</Target>"
+	_, err := callAVTransport(ctx, device, "Seek", args)
+	return err
+}
+
+// formatTrackTime formats d as the H:MM:SS the AVTransport service uses.
+func formatTrackTime(d time.Duration) string {
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
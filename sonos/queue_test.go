@@ -0,0 +1,129 @@
+package sonos
+
+import (
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addToQueueResponse is the AddURIToQueue response for tracks added at
+// position first.
+func addToQueueResponse(first int) string {
+	return `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:AddURIToQueueResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1">` +
+		`<FirstTrackNumberEnqueued>` + strconv.Itoa(first) + `</FirstTrackNumberEnqueued><NumTracksAdded>1</NumTracksAdded><NewQueueLength>` + strconv.Itoa(first) + `</NewQueueLength>` +
+		`</u:AddURIToQueueResponse></s:Body></s:Envelope>`
+}
+
+// recordActions serves AVTransport and records each action with its body.
+func recordActions(t *testing.T) (*httptest.Server, *[]string, *[]string) {
+	t.Helper()
+	var actions, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/MediaRenderer/AVTransport/Control" {
+			t.Fatalf("path = %q", r.URL.Path)
+		}
+		action := r.Header.Get("SOAPACTION")
+		actions = append(actions, action[strings.Index(action, "#")+1:len(action)-1])
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if strings.Contains(action, "AddURIToQueue") {
+			io.WriteString(w, addToQueueResponse(7))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &actions, &bodies
+}
+
+func TestMetadataDIDL(t *testing.T) {
+	didl := Metadata{Title: "Rock & Roll", Creator: "Band", Album: "<Live>", Desc: "SA_RINCON2311_X_#Svc2311-0-Token"}.DIDL()
+	for _, want := range []string{
+		`<item id="-1" parentID="-1" restricted="true">`,
+		`<dc:title>Rock &amp; Roll</dc:title>`,
+		`<upnp:album>&lt;Live&gt;</upnp:album>`,
+		`<upnp:class>object.item.audioItem.musicTrack</upnp:class>`,
+		`<desc id="cdudn" nameSpace="urn:schemas-rinconnetworks-com:metadata-1-0/">SA_RINCON2311_X_#Svc2311-0-Token</desc>`,
+	} {
+		if !strings.Contains(didl, want) {
+			t.Fatalf("DIDL lacks %s:\n%s", want, didl)
+		}
+	}
+	if strings.Contains(didl, "albumArtURI") {
+		t.Fatalf("DIDL has empty fields:\n%s", didl)
+	}
+	track, err := parseTrackMetadata(didl)
+	if err != nil || track.Title != "Rock & Roll" || track.Creator != "Band" || track.Album != "<Live>" {
+		t.Fatalf("DIDL parses back as %+v, %v", track, err)
+	}
+}
+
+func TestSetAVTransportURI(t *testing.T) {
+	server, actions, bodies := recordActions(t)
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	meta := Metadata{Title: "Morning Radio", Class: "object.item.audioItem.audioBroadcast"}.DIDL()
+	if err := SetAVTransportURI(context.Background(), device, "x-rincon-mp3radio://radio.example/stream?a=1&b=2", meta); err != nil {
+		t.Fatalf("SetAVTransportURI error: %v", err)
+	}
+	if (*actions)[0] != "SetAVTransportURI" {
+		t.Fatalf("actions = %v", *actions)
+	}
+	body := (*bodies)[0]
+	if !strings.Contains(body, "<CurrentURI>x-rincon-mp3radio://radio.example/stream?a=1&amp;b=2</CurrentURI>") || !strings.Contains(body, "<CurrentURIMetaData>"+html.EscapeString(meta)+"</CurrentURIMetaData>") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestQueueActions(t *testing.T) {
+	server, actions, bodies := recordActions(t)
+	device := Device{Location: server.URL + "/xml/device_description.xml", Metadata: DeviceMetadata{UDN: "uuid:RINCON_000E58A0123401400"}}
+	ctx := context.Background()
+
+	if err := ClearQueue(ctx, device); err != nil {
+		t.Fatalf("ClearQueue error: %v", err)
+	}
+	first, err := AddURIToQueue(ctx, device, "x-file-cifs://nas/music/song.flac", "", true)
+	if err != nil || first != 7 {
+		t.Fatalf("AddURIToQueue = %d, %v, want 7", first, err)
+	}
+	if !strings.Contains((*bodies)[1], "<EnqueuedURI>x-file-cifs://nas/music/song.flac</EnqueuedURI>") || !strings.Contains((*bodies)[1], "<EnqueueAsNext>1</EnqueueAsNext>") {
+		t.Fatalf("unexpected body: %s", (*bodies)[1])
+	}
+	if err := SeekTrack(ctx, device, 7); err != nil {
+		t.Fatalf("SeekTrack error: %v", err)
+	}
+	if err := Seek(ctx, device, 1*time.Hour+2*time.Minute+3500*time.Millisecond); err != nil {
+		t.Fatalf("Seek error: %v", err)
+	}
+	if got := strings.Join(*actions, ","); got != "RemoveAllTracksFromQueue,AddURIToQueue,Seek,Seek" {
+		t.Fatalf("actions = %s", got)
+	}
+	if !strings.Contains((*bodies)[2], "<Unit>TRACK_NR</Unit>\n      <Target>7</Target>") {
+		t.Fatalf("unexpected body: %s", (*bodies)[2])
+	}
+	if !strings.Contains((*bodies)[3], "<Unit>REL_TIME</Unit>\n      <Target>1:02:03</Target>") {
+		t.Fatalf("unexpected body: %s", (*bodies)[3])
+	}
+
+	if err := Seek(ctx, device, -time.Second); err == nil {
+		t.Fatal("negative seek accepted")
+	}
+	if err := SeekTrack(ctx, device, 0); err == nil {
+		t.Fatal("track 0 accepted")
+	}
+	if len(*actions) != 4 {
+		t.Fatalf("invalid seeks reached the speaker: %v", *actions)
+	}
+
+	queue, err := QueueURI(device)
+	if err != nil || queue != "x-rincon-queue:RINCON_000E58A0123401400#0" {
+		t.Fatalf("QueueURI = %q, %v", queue, err)
+	}
+	if _, err := QueueURI(Device{}); err == nil {
+		t.Fatal("QueueURI without a UDN succeeded")
+	}
+}
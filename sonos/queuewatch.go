@@ -0,0 +1,133 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseQueueUpdateID returns the queue's update ID from a ContentDirectory
+// NOTIFY payload. The ID changes whenever the queue is edited; ok is false
+// when the event is about other containers only.
+func ParseQueueUpdateID(body []byte) (id string, ok bool, err error) {
+	var props eventPropertySet
+	if err := xml.Unmarshal(body, &props); err != nil {
+		return "", false, fmt.Errorf("sonos: decode contentdirectory event: %w", err)
+	}
+	for _, p := range props.Properties {
+		// ContainerUpdateIDs pairs containers with their update IDs:
+		// "Q:0,12,FV:2,3".
+		fields := strings.Split(strings.TrimSpace(p.ContainerUpdateIDs), ",")
+		for i := 0; i+1 < len(fields); i += 2 {
+			if strings.TrimSpace(fields[i]) == QueueID {
+				return strings.TrimSpace(fields[i+1]), true, nil
+			}
+		}
+	}
+	return "", false, nil
+}
+
+// NextQueuedTrack reads the track the room coordinated by device plays
+// after the current one from its queue. The track is zero when the room is
+// not playing its queue, the queue ends with the current track, or shuffle
+// leaves the order to the speaker.
+func NextQueuedTrack(ctx context.Context, device Device) (TrackInfo, error) {
+	media, err := CurrentMedia(ctx, device)
+	if err != nil {
+		return TrackInfo{}, err
+	}
+	if !strings.HasPrefix(media.URI, "x-rincon-queue:") {
+		return TrackInfo{}, nil
+	}
+	mode, err := playMode(ctx, device)
+	if err != nil {
+		return TrackInfo{}, err
+	}
+	if mode.Shuffle() {
+		return TrackInfo{}, nil
+	}
+	current, err := currentTrackNumber(ctx, device)
+	if err != nil {
+		return TrackInfo{}, err
+	}
+	if mode.RepeatOne() {
+		current--
+	}
+	// Track numbers count from 1 and browse indexes from 0, so the next
+	// track's index is the current track's number.
+	items, total, err := browseRange(ctx, device, QueueID, current, 1)
+	if err != nil {
+		return TrackInfo{}, err
+	}
+	if len(items) == 0 && mode.RepeatAll() && total > 0 {
+		items, _, err = browseRange(ctx, device, QueueID, 0, 1)
+		if err != nil {
+			return TrackInfo{}, err
+		}
+	}
+	if len(items) == 0 {
+		return TrackInfo{}, nil
+	}
+	item := items[0]
+	return TrackInfo{
+		Title:       item.Title,
+		Artist:      item.Creator,
+		Album:       item.Album,
+		URI:         item.URI,
+		AlbumArtURI: item.AlbumArtURI,
+		Class:       item.Class,
+		Source:      ClassifySource(item.URI),
+	}, nil
+}
+
+// playMode returns the room's play mode (GetTransportSettings).
+func playMode(ctx context.Context, device Device) (PlayMode, error) {
+	body, err := callAVTransport(ctx, device, "GetTransportSettings", "")
+	if err != nil {
+		return "", err
+	}
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				PlayMode string `xml:"PlayMode"`
+			} `xml:"GetTransportSettingsResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return "", fmt.Errorf("sonos: decode transport settings: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return "", errors.New("sonos: empty transport settings response")
+	}
+	return parsePlayMode(envelope.Body.Response.PlayMode), nil
+}
+
+// currentTrackNumber returns the queue position, from 1, of the track the
+// room is playing (GetPositionInfo Track).
+func currentTrackNumber(ctx context.Context, device Device) (int, error) {
+	body, err := callAVTransport(ctx, device, "GetPositionInfo", "")
+	if err != nil {
+		return 0, err
+	}
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				Track string `xml:"Track"`
+			} `xml:"GetPositionInfoResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return 0, fmt.Errorf("sonos: decode position info: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return 0, errors.New("sonos: empty position info response")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(envelope.Body.Response.Track))
+	if err != nil {
+		return 0, fmt.Errorf("sonos: parse track number: %w", err)
+	}
+	return n, nil
+}
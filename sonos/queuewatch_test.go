@@ -0,0 +1,184 @@
+package sonos
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+func TestParseQueueUpdateID(t *testing.T) {
+	const event = `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><SystemUpdateID>88</SystemUpdateID></e:property><e:property><ContainerUpdateIDs>FV:2,31,Q:0,12</ContainerUpdateIDs></e:property></e:propertyset>`
+	id, ok, err := ParseQueueUpdateID([]byte(event))
+	if err != nil || !ok || id != "12" {
+		t.Fatalf("ParseQueueUpdateID = %q, %v, %v; want 12", id, ok, err)
+	}
+	id, ok, err = ParseQueueUpdateID([]byte(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><ContainerUpdateIDs>FV:2,31</ContainerUpdateIDs></e:property></e:propertyset>`))
+	if err != nil || ok {
+		t.Fatalf("favorites-only event = %q, %v, %v; want no queue ID", id, ok, err)
+	}
+}
+
+// queueSpeaker answers the actions NextQueuedTrack makes for a queue of
+// titles, playing track current in mode.
+func queueSpeaker(t *testing.T, mode string, current *int, titles *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "SUBSCRIBE", "UNSUBSCRIBE":
+			w.Header().Set("SID", "uuid:"+strings.TrimPrefix(r.URL.Path, "/"))
+			w.Header().Set("TIMEOUT", "Second-1800")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		action := r.Header.Get("SOAPACTION")
+		envelope := func(inner string) {
+			fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+inner+`</s:Body></s:Envelope>`)
+		}
+		switch {
+		case strings.Contains(action, "GetMediaInfo"):
+			envelope(`<u:GetMediaInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentURI>x-rincon-queue:RINCON_1#0</CurrentURI><CurrentURIMetaData></CurrentURIMetaData></u:GetMediaInfoResponse>`)
+		case strings.Contains(action, "GetTransportSettings"):
+			envelope(`<u:GetTransportSettingsResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><PlayMode>` + mode + `</PlayMode></u:GetTransportSettingsResponse>`)
+		case strings.Contains(action, "GetPositionInfo"):
+			envelope(fmt.Sprintf(`<u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><Track>%d</Track></u:GetPositionInfoResponse>`, *current))
+		case strings.Contains(action, "Browse"):
+			var start int
+			fmt.Sscanf(string(body[strings.Index(string(body), "<StartingIndex>")+len("<StartingIndex>"):]), "%d", &start)
+			didl := `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`
+			returned := 0
+			if start < len(*titles) {
+				didl += `<item id="Q:0/` + fmt.Sprint(start+1) + `"><dc:title>` + (*titles)[start] + `</dc:title><dc:creator>Band</dc:creator><res>x-file-cifs://nas/` + fmt.Sprint(start+1) + `.flac</res></item>`
+				returned = 1
+			}
+			didl += `</DIDL-Lite>`
+			envelope(fmt.Sprintf(`<u:BrowseResponse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1"><Result>%s</Result><NumberReturned>%d</NumberReturned><TotalMatches>%d</TotalMatches></u:BrowseResponse>`, html.EscapeString(didl), returned, len(*titles)))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNextQueuedTrack(t *testing.T) {
+	titles := []string{"One", "Two", "Three"}
+	for _, tc := range []struct {
+		mode    string
+		current int
+		want    string
+	}{
+		{"NORMAL", 1, "Two"},
+		{"NORMAL", 3, ""},
+		{"REPEAT_ALL", 3, "One"},
+		{"REPEAT_ONE", 2, "Two"},
+		{"SHUFFLE", 1, ""},
+	} {
+		current := tc.current
+		server := queueSpeaker(t, tc.mode, &current, &titles)
+		device := Device{Location: server.URL + "/xml/device_description.xml"}
+		next, err := NextQueuedTrack(context.Background(), device)
+		if err != nil {
+			t.Fatalf("%s at %d: NextQueuedTrack error: %v", tc.mode, tc.current, err)
+		}
+		if next.Title != tc.want {
+			t.Fatalf("%s at %d: next = %q, want %q", tc.mode, tc.current, next.Title, tc.want)
+		}
+		if tc.want != "" && next.Artist != "Band" {
+			t.Fatalf("next artist = %q, want Band", next.Artist)
+		}
+	}
+}
+
+func TestListenForEventsRefreshesNextTrackWhenQueueEdited(t *testing.T) {
+	current := 1
+	titles := []string{"One", "Two"}
+	speaker := queueSpeaker(t, "NORMAL", &current, &titles)
+	callbacks := make(chan string, 2)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if callback := r.Header.Get("CALLBACK"); callback != "" {
+			callbacks <- r.URL.Path + " " + strings.Trim(callback, "<>")
+		}
+		speaker.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer subscriber.Close()
+
+	statuses := make(chan PlaybackStatus, 16)
+	opts := ListenerOptions{
+		Clock:             clock.NewFake(time.Now()),
+		PollFallbackAfter: -1,
+		OnStatus:          func(s PlaybackStatus) { statuses <- s },
+	}
+	device := Device{IP: "127.0.0.1", Location: subscriber.URL + "/xml/device_description.xml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, device, "Kitchen", "/events", opts)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("listener error: %v", err)
+		}
+	}()
+
+	urls := map[string]string{}
+	for range 2 {
+		select {
+		case c := <-callbacks:
+			path, callback, _ := strings.Cut(c, " ")
+			urls[path] = callback
+		case <-time.After(5 * time.Second):
+			t.Fatalf("subscriptions = %v, want AVTransport and ContentDirectory", urls)
+		}
+	}
+	avCallback, queueCallback := urls["/MediaRenderer/AVTransport/Event"], urls["/MediaServer/ContentDirectory/Event"]
+	if avCallback == "" || !strings.HasSuffix(queueCallback, "/events/queue") {
+		t.Fatalf("callbacks = %v", urls)
+	}
+	notify := func(callback, body string) {
+		t.Helper()
+		req, err := http.NewRequest("NOTIFY", callback, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("build notify: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("send notify: %v", err)
+		}
+		resp.Body.Close()
+	}
+	queueEvent := func(id int) string {
+		return fmt.Sprintf(`<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><ContainerUpdateIDs>Q:0,%d</ContainerUpdateIDs></e:property></e:propertyset>`, id)
+	}
+	notify(queueCallback, queueEvent(1))
+	notify(avCallback, `<e:propertyset xmlns:e="urn:schemas-upnp-org:event-1-0"><e:property><LastChange>&lt;Event xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/AVT/&quot; xmlns:r=&quot;urn:schemas-rinconnetworks-com:metadata-1-0/&quot;&gt;&lt;InstanceID val=&quot;0&quot;&gt;&lt;TransportState val=&quot;PLAYING&quot;/&gt;&lt;CurrentTrackURI val=&quot;x-file-cifs://nas/1.flac&quot;/&gt;&lt;CurrentTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;One&lt;/dc:title&gt;&lt;dc:creator&gt;Band&lt;/dc:creator&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;r:NextTrackURI val=&quot;x-file-cifs://nas/2.flac&quot;/&gt;&lt;r:NextTrackMetaData val=&quot;&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Two&lt;/dc:title&gt;&lt;dc:creator&gt;Band&lt;/dc:creator&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;&quot;/&gt;&lt;/InstanceID&gt;&lt;/Event&gt;</LastChange></e:property></e:propertyset>`)
+	waitForNext := func(want string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case s := <-statuses:
+				if s.NextTrack.Title == want {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("no status with next track %q", want)
+			}
+		}
+	}
+	waitForNext("Two")
+
+	// A track is inserted after the current one from the Sonos app.
+	titles = []string{"One", "Inserted", "Two"}
+	notify(queueCallback, queueEvent(2))
+	waitForNext("Inserted")
+}
@@ -0,0 +1,246 @@
+package sonos
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Scaler resizes album art. Scale fills all of dst with src, stretched to
+// dst's bounds. Implementations are used from one goroutine at a time.
+type Scaler interface {
+	Scale(dst *image.NRGBA, src image.Image)
+}
+
+// LookupScaler returns the built-in Scaler called name: ScalerBilinear,
+// ScalerCatmullRom, ScalerBox, or ScalerNearest.
+func LookupScaler(name ArtScaler) (Scaler, bool) {
+	switch name {
+	case "", ScalerBilinear:
+		return xdrawScaler{xdraw.ApproxBiLinear}, true
+	case ScalerCatmullRom:
+		return xdrawScaler{xdraw.CatmullRom}, true
+	case ScalerBox:
+		return BoxScaler{}, true
+	case ScalerNearest:
+		return NearestScaler{}, true
+	}
+	return nil, false
+}
+
+// xdrawScaler adapts a golang.org/x/image/draw filter.
+type xdrawScaler struct {
+	scaler xdraw.Scaler
+}
+
+func (s xdrawScaler) Scale(dst *image.NRGBA, src image.Image) {
+	s.scaler.Scale(dst, dst.Bounds(), src, src.Bounds(), xdraw.Over, nil)
+}
+
+// BoxScaler averages every source pixel under each destination pixel. When
+// shrinking large artwork to a panel it is as smooth as the filtering
+// scalers at a fraction of their cost: each source pixel is read once,
+// straight from the buffers of the image types decoders produce, and JPEG
+// art is averaged before its conversion to RGB.
+type BoxScaler struct{}
+
+// Scale implements Scaler.
+func (BoxScaler) Scale(dst *image.NRGBA, src image.Image) {
+	sb, db := src.Bounds(), dst.Bounds()
+	if sb.Empty() || db.Empty() {
+		return
+	}
+	dw, dh := db.Dx(), db.Dy()
+	set := func(i int, c color.NRGBA) {
+		dst.SetNRGBA(db.Min.X+i%dw, db.Min.Y+i/dw, c)
+	}
+	switch img := src.(type) {
+	case *image.YCbCr:
+		cw, ch := chromaSize(img)
+		lum := boxPlane(img.Y[img.YOffset(sb.Min.X, sb.Min.Y):], img.YStride, sb.Dx(), sb.Dy(), 1, dw, dh)
+		ci := img.COffset(sb.Min.X, sb.Min.Y)
+		cb := boxPlane(img.Cb[ci:], img.CStride, cw, ch, 1, dw, dh)
+		cr := boxPlane(img.Cr[ci:], img.CStride, cw, ch, 1, dw, dh)
+		for i := range lum {
+			r, g, b := color.YCbCrToRGB(lum[i], cb[i], cr[i])
+			set(i, color.NRGBA{R: r, G: g, B: b, A: 0xff})
+		}
+		return
+	case *image.Gray:
+		lum := boxPlane(img.Pix[img.PixOffset(sb.Min.X, sb.Min.Y):], img.Stride, sb.Dx(), sb.Dy(), 1, dw, dh)
+		for i, v := range lum {
+			set(i, color.NRGBA{R: v, G: v, B: v, A: 0xff})
+		}
+		return
+	case *image.NRGBA:
+		if img.Opaque() {
+			// Opaque NRGBA pixels are already premultiplied.
+			src = &image.RGBA{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect}
+		}
+	}
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(sb)
+		draw.Draw(rgba, sb, src, sb.Min, draw.Src)
+	}
+	// Averaging premultiplied channels keeps transparent pixels from
+	// darkening their neighbours.
+	pix := boxPlane(rgba.Pix[rgba.PixOffset(sb.Min.X, sb.Min.Y):], rgba.Stride, sb.Dx(), sb.Dy(), 4, dw, dh)
+	for i := 0; i < dw*dh; i++ {
+		p := pix[i*4:]
+		set(i, unpremultiply(uint64(p[0]), uint64(p[1]), uint64(p[2]), uint64(p[3])))
+	}
+}
+
+// chromaSize returns the dimensions of img's Cb and Cr planes.
+func chromaSize(img *image.YCbCr) (w, h int) {
+	dx, dy := 1, 1
+	switch img.SubsampleRatio {
+	case image.YCbCrSubsampleRatio422:
+		dx = 2
+	case image.YCbCrSubsampleRatio420:
+		dx, dy = 2, 2
+	case image.YCbCrSubsampleRatio440:
+		dy = 2
+	case image.YCbCrSubsampleRatio411:
+		dx = 4
+	case image.YCbCrSubsampleRatio410:
+		dx, dy = 4, 2
+	}
+	r := img.Rect
+	return (r.Max.X+dx-1)/dx - r.Min.X/dx, (r.Max.Y+dy-1)/dy - r.Min.Y/dy
+}
+
+// span returns the source range [lo, hi) that destination index i of n
+// covers in a source of the given size. It is never empty, so enlarging
+// repeats source pixels.
+func span(i, n, size int) (lo, hi int) {
+	lo, hi = i*size/n, (i+1)*size/n
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// boxPlane averages a w x h plane of interleaved 8-bit channels down to
+// dw x dh pixels. The sums are 32-bit, which is ample as long as no
+// destination pixel covers more than 16 million source pixels.
+func boxPlane(pix []uint8, stride, w, h, channels, dw, dh int) []uint8 {
+	out := make([]uint8, dw*dh*channels)
+	sums := make([]uint32, dw*channels)
+	cols := make([][2]int, dw)
+	for dx := range cols {
+		cols[dx][0], cols[dx][1] = span(dx, dw, w)
+	}
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := span(dy, dh, h)
+		clear(sums)
+		for y := y0; y < y1; y++ {
+			row := pix[y*stride:]
+			for dx, col := range cols {
+				x0, x1 := col[0], col[1]
+				if channels == 1 {
+					var sum uint32
+					for _, v := range row[x0:x1] {
+						sum += uint32(v)
+					}
+					sums[dx] += sum
+					continue
+				}
+				var r, g, b, a uint32
+				for i := x0 * 4; i < x1*4; i += 4 {
+					r += uint32(row[i])
+					g += uint32(row[i+1])
+					b += uint32(row[i+2])
+					a += uint32(row[i+3])
+				}
+				s := sums[dx*4 : dx*4+4]
+				s[0] += r
+				s[1] += g
+				s[2] += b
+				s[3] += a
+			}
+		}
+		for dx, col := range cols {
+			n := uint32((y1 - y0) * (col[1] - col[0]))
+			for c := 0; c < channels; c++ {
+				out[(dy*dw+dx)*channels+c] = uint8((sums[dx*channels+c] + n/2) / n)
+			}
+		}
+	}
+	return out
+}
+
+// NearestScaler copies the source pixel under the centre of each destination
+// pixel. It is the cheapest scaler and keeps pixel art hard-edged, but
+// aliases detailed photos.
+type NearestScaler struct{}
+
+// Scale implements Scaler.
+func (NearestScaler) Scale(dst *image.NRGBA, src image.Image) {
+	sb, db := src.Bounds(), dst.Bounds()
+	if sb.Empty() || db.Empty() {
+		return
+	}
+	read := pixelReader(src)
+	dw, dh := db.Dx(), db.Dy()
+	for dy := 0; dy < dh; dy++ {
+		sy := sb.Min.Y + (2*dy+1)*sb.Dy()/(2*dh)
+		for dx := 0; dx < dw; dx++ {
+			sx := sb.Min.X + (2*dx+1)*sb.Dx()/(2*dw)
+			r, g, b, a := read(sx, sy)
+			dst.SetNRGBA(db.Min.X+dx, db.Min.Y+dy, unpremultiply(uint64(r), uint64(g), uint64(b), uint64(a)))
+		}
+	}
+}
+
+// pixelReader returns a function reading 8-bit premultiplied pixels of src,
+// directly from the buffers of the types image decoders produce.
+func pixelReader(src image.Image) func(x, y int) (r, g, b, a uint8) {
+	switch img := src.(type) {
+	case *image.YCbCr:
+		return func(x, y int) (uint8, uint8, uint8, uint8) {
+			yi, ci := img.YOffset(x, y), img.COffset(x, y)
+			r, g, b := color.YCbCrToRGB(img.Y[yi], img.Cb[ci], img.Cr[ci])
+			return r, g, b, 0xff
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint8, uint8, uint8, uint8) {
+			p := img.Pix[img.PixOffset(x, y):]
+			return p[0], p[1], p[2], p[3]
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint8, uint8, uint8, uint8) {
+			p := img.Pix[img.PixOffset(x, y):]
+			a := uint16(p[3])
+			return uint8(uint16(p[0]) * a / 0xff), uint8(uint16(p[1]) * a / 0xff), uint8(uint16(p[2]) * a / 0xff), p[3]
+		}
+	case *image.Gray:
+		return func(x, y int) (uint8, uint8, uint8, uint8) {
+			v := img.Pix[img.PixOffset(x, y)]
+			return v, v, v, 0xff
+		}
+	}
+	return func(x, y int) (uint8, uint8, uint8, uint8) {
+		r, g, b, a := src.At(x, y).RGBA()
+		return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)
+	}
+}
+
+// unpremultiply converts averaged premultiplied channels to color.NRGBA.
+func unpremultiply(r, g, b, a uint64) color.NRGBA {
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	if a == 0xff {
+		return color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xff}
+	}
+	return color.NRGBA{
+		R: uint8(min(r*0xff/a, 0xff)),
+		G: uint8(min(g*0xff/a, 0xff)),
+		B: uint8(min(b*0xff/a, 0xff)),
+		A: uint8(a),
+	}
+}
@@ -0,0 +1,95 @@
+package sonos
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestBoxScalerAveragesBlocks(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			v := uint8(0)
+			if (x+y)%2 == 0 {
+				v = 200
+			}
+			src.SetRGBA(x, y, color.RGBA{R: v, G: 0xff - v, B: 40, A: 0xff})
+		}
+	}
+	src.SetRGBA(3, 0, color.RGBA{})
+	src.SetRGBA(3, 1, color.RGBA{})
+
+	dst := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	BoxScaler{}.Scale(dst, src)
+	if got, want := dst.NRGBAAt(0, 0), (color.NRGBA{R: 100, G: 155, B: 40, A: 0xff}); got != want {
+		t.Fatalf("left pixel = %v, want the average %v", got, want)
+	}
+	// The right block is half transparent; its color must not darken.
+	if got := dst.NRGBAAt(1, 0); got.A < 0x7f || got.A > 0x80 || got.B < 39 || got.B > 40 {
+		t.Fatalf("right pixel = %v, want half-alpha with unpremultiplied color", got)
+	}
+}
+
+func TestScalersFillDestination(t *testing.T) {
+	src := testPhoto(300, 200)
+	for _, name := range []ArtScaler{ScalerBilinear, ScalerCatmullRom, ScalerBox, ScalerNearest} {
+		scaler, ok := LookupScaler(name)
+		if !ok {
+			t.Fatalf("LookupScaler(%q) not found", name)
+		}
+		for _, size := range []int{16, 64, 400} {
+			dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+			scaler.Scale(dst, src)
+			for i := 3; i < len(dst.Pix); i += 4 {
+				if dst.Pix[i] != 0xff {
+					t.Fatalf("%s to %d: pixel %d alpha %d, want every pixel opaque", name, size, i/4, dst.Pix[i])
+				}
+			}
+		}
+	}
+	if _, ok := LookupScaler("lanczos"); ok {
+		t.Fatal("LookupScaler(lanczos) found a scaler")
+	}
+}
+
+type countingScaler struct{ calls int }
+
+func (s *countingScaler) Scale(dst *image.NRGBA, src image.Image) { s.calls++ }
+
+func TestArtProcessingUsesCustomScaler(t *testing.T) {
+	custom := &countingScaler{}
+	ArtProcessing{Scaler: ScalerCatmullRom, Custom: custom}.scale(testPhoto(10, 10), 4)
+	if custom.calls != 1 {
+		t.Fatalf("custom scaler called %d times, want 1", custom.calls)
+	}
+}
+
+// testPhoto returns noisy JPEG-like art, the common input for the scalers.
+func testPhoto(w, h int) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	rng := rand.New(rand.NewSource(1))
+	for i := range img.Y {
+		img.Y[i] = uint8(rng.Intn(256))
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(rng.Intn(256))
+		img.Cr[i] = uint8(rng.Intn(256))
+	}
+	return img
+}
+
+// Compare with: go test ./sonos -run '^$' -bench Scaler
+func BenchmarkScaler(b *testing.B) {
+	src := testPhoto(600, 600)
+	for _, name := range []ArtScaler{ScalerBilinear, ScalerCatmullRom, ScalerBox, ScalerNearest} {
+		scaler, _ := LookupScaler(name)
+		b.Run(string(name), func(b *testing.B) {
+			dst := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+			for i := 0; i < b.N; i++ {
+				scaler.Scale(dst, src)
+			}
+		})
+	}
+}
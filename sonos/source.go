@@ -0,0 +1,138 @@
+package sonos
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SourceKind classifies where a room's audio comes from, based on the track
+// URI scheme Sonos reports.
+type SourceKind string
+
+const (
+	SourceUnknown SourceKind = ""
+	// SourceMusic covers streaming services and local library tracks.
+	SourceMusic SourceKind = "music"
+	// SourceRadio covers internet radio streams.
+	SourceRadio SourceKind = "radio"
+	// SourceLineIn is the analog or optical line-in of a player.
+	SourceLineIn SourceKind = "line_in"
+	// SourceTV is the home-theater input of a soundbar.
+	SourceTV SourceKind = "tv"
+	// SourceAirPlay is an AirPlay session.
+	SourceAirPlay SourceKind = "airplay"
+)
+
+// sourcePrefixes maps URI scheme prefixes to their source. Longer, more
+// specific prefixes come first.
+var sourcePrefixes = []struct {
+	prefix string
+	kind   SourceKind
+}{
+	{"x-sonos-htastream:", SourceTV},
+	{"x-rincon-stream:", SourceLineIn},
+	{"x-sonos-vli:", SourceAirPlay},
+	{"x-sonosapi-stream:", SourceRadio},
+	{"x-sonosapi-radio:", SourceRadio},
+	{"x-sonosapi-hls:", SourceRadio},
+	{"x-rincon-mp3radio:", SourceRadio},
+	{"aac:", SourceRadio},
+	{"hls-radio:", SourceRadio},
+	{"x-sonos-spotify:", SourceMusic},
+	{"x-sonos-http:", SourceMusic},
+	{"x-sonosapi-hls-static:", SourceMusic},
+	{"x-file-cifs:", SourceMusic},
+	{"x-rincon-playlist:", SourceMusic},
+	{"http:", SourceMusic},
+	{"https:", SourceMusic},
+}
+
+// ClassifySource returns the source kind for a track URI.
+func ClassifySource(uri string) SourceKind {
+	uri = strings.ToLower(strings.TrimSpace(uri))
+	if uri == "" {
+		return SourceUnknown
+	}
+	for _, p := range sourcePrefixes {
+		if strings.HasPrefix(uri, p.prefix) {
+			return p.kind
+		}
+	}
+	return SourceUnknown
+}
+
+// Label is the short name of the source shown on the display, or "" for
+// SourceMusic and SourceUnknown, which show the track itself.
+func (k SourceKind) Label() string {
+	switch k {
+	case SourceRadio:
+		return "Radio"
+	case SourceLineIn:
+		return "Line-In"
+	case SourceTV:
+		return "TV"
+	case SourceAirPlay:
+		return "AirPlay"
+	}
+	return ""
+}
+
+// serviceNames maps the sid query parameter of Sonos service URIs to the
+// music service's name.
+var serviceNames = map[string]string{
+	"2":   "Deezer",
+	"9":   "Spotify",
+	"12":  "Spotify",
+	"160": "SoundCloud",
+	"174": "TIDAL",
+	"201": "Amazon Music",
+	"204": "Apple Music",
+	"254": "TuneIn",
+	"284": "YouTube Music",
+	"303": "Sonos Radio",
+}
+
+// ServiceName returns the music service a track URI plays from, such as
+// "Spotify" or "TuneIn", or "" when the URI does not name one.
+func ServiceName(uri string) string {
+	uri = strings.TrimSpace(uri)
+	if strings.HasPrefix(strings.ToLower(uri), "x-sonos-spotify:") {
+		return "Spotify"
+	}
+	_, query, ok := strings.Cut(uri, "?")
+	if !ok {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	return serviceNames[values.Get("sid")]
+}
+
+// SourceLabel names where the track comes from for a display badge: the
+// music service when the URI names one, otherwise the source label.
+func (t TrackInfo) SourceLabel() string {
+	if t.Service != "" {
+		return t.Service
+	}
+	return t.Source.Label()
+}
+
+// withSource fills in the source fields derived from the track URI.
+func withSource(info TrackInfo) TrackInfo {
+	info.Source = ClassifySource(info.URI)
+	info.Service = ServiceName(info.URI)
+	return info
+}
+
+// ParseSourceKind validates a source kind name as used in configuration.
+func ParseSourceKind(value string) (SourceKind, error) {
+	kind := SourceKind(strings.ToLower(strings.TrimSpace(value)))
+	switch kind {
+	case SourceMusic, SourceRadio, SourceLineIn, SourceTV, SourceAirPlay:
+		return kind, nil
+	}
+	return SourceUnknown, fmt.Errorf("sonos: unknown source %q (want music, radio, line_in, tv, or airplay)", value)
+}
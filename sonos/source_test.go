@@ -0,0 +1,59 @@
+package sonos
+
+import "testing"
+
+func TestClassifySource(t *testing.T) {
+	cases := map[string]SourceKind{
+		"x-sonos-htastream:RINCON_000E58:spdif":                 SourceTV,
+		"x-rincon-stream:RINCON_000E58A0B1C201400":              SourceLineIn,
+		"x-sonos-vli:RINCON_1:2,airplay:abc":                    SourceAirPlay,
+		"x-sonosapi-stream:s12345?sid=254&flags=8224&sn=0":      SourceRadio,
+		"x-rincon-mp3radio://stream.example.com/live":           SourceRadio,
+		"X-Sonos-Spotify:spotify%3atrack%3a123":                 SourceMusic,
+		"x-file-cifs://nas/music/track.flac":                    SourceMusic,
+		"x-sonosapi-hls-static:ALkSOiG?sid=201&flags=8232&sn=5": SourceMusic,
+		"": SourceUnknown,
+		"x-rincon-queue:RINCON_000E58A0B1C201400#0": SourceUnknown,
+	}
+	for uri, want := range cases {
+		if got := ClassifySource(uri); got != want {
+			t.Fatalf("ClassifySource(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+func TestParseSourceKind(t *testing.T) {
+	if kind, err := ParseSourceKind(" TV "); err != nil || kind != SourceTV {
+		t.Fatalf("ParseSourceKind(TV) = %q, %v", kind, err)
+	}
+	if _, err := ParseSourceKind("vinyl"); err == nil {
+		t.Fatalf("ParseSourceKind(vinyl) succeeded, want error")
+	}
+}
+
+func TestServiceNameAndSourceLabel(t *testing.T) {
+	cases := map[string]string{
+		"x-sonos-spotify:spotify%3atrack%3a123?sid=12&flags=8224": "Spotify",
+		"x-sonosapi-stream:s12345?sid=254&flags=8224&sn=0":        "TuneIn",
+		"x-sonosapi-hls-static:ALkSOiG?sid=201&flags=8232&sn=5":   "Amazon Music",
+		"x-sonosapi-hls-static:ALkSOiG?sid=99999":                 "",
+		"x-file-cifs://nas/music/track.flac":                      "",
+	}
+	for uri, want := range cases {
+		if got := ServiceName(uri); got != want {
+			t.Fatalf("ServiceName(%q) = %q, want %q", uri, got, want)
+		}
+	}
+
+	tv := withSource(TrackInfo{URI: "x-sonos-htastream:RINCON_000E58:spdif"})
+	if tv.Source != SourceTV || tv.SourceLabel() != "TV" {
+		t.Fatalf("TV track = %+v, label %q", tv, tv.SourceLabel())
+	}
+	if got := formatTrackDisplay(tv); got != "TV" || shouldSkipDisplay(got) {
+		t.Fatalf("formatTrackDisplay(TV) = %q, want an unskipped TV label", got)
+	}
+	radio := withSource(TrackInfo{URI: "x-sonosapi-stream:s1?sid=254"})
+	if radio.SourceLabel() != "TuneIn" {
+		t.Fatalf("radio label = %q, want the service name", radio.SourceLabel())
+	}
+}
@@ -3,9 +3,8 @@ package sonos
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"strings"
-	"time"
 )
 
 // RoomStatus represents the playback state of a Sonos room.
@@ -13,6 +12,14 @@ type RoomStatus struct {
 	Room  string
 	State string
 	Track string
+	// GroupedWith is the room that coordinates the group this room plays
+	// in, when that is another room. State then reads "Grouped with" it and
+	// Track is the coordinator's, since a group member has no transport of
+	// its own.
+	GroupedWith string
+	// ArtURI is the absolute URL of the current track's album art, empty
+	// when it has none.
+	ArtURI string
 }
 
 // GatherRoomStatuses collects the playback status for each discovered device. If
@@ -22,11 +29,16 @@ func GatherRoomStatuses(ctx context.Context, devices []Device, targetRoom string
 	statuses := make([]RoomStatus, 0, len(devices))
 
 	var targetDevice *Device
+	var groups []ZoneGroup
+	topologyRead := false
+	// byCoordinator keeps the status of each coordinator queried, by UUID,
+	// for the rooms grouped with it.
+	byCoordinator := make(map[string]RoomStatus)
 
 	for i := range devices {
 		device := devices[i]
 		if !device.IsSonos {
-			log.Printf("ignoring non-Sonos responder at %s (%s)", device.IP, device.Server)
+			logger.Info("ignoring non-Sonos responder", "ip", device.IP, "server", device.Server)
 			continue
 		}
 
@@ -39,14 +51,85 @@ func GatherRoomStatuses(ctx context.Context, devices []Device, targetRoom string
 			targetDevice = &devices[i]
 		}
 
-		statuses = append(statuses, buildRoomStatus(ctx, device, room))
+		if !topologyRead {
+			groups = readGroups(ctx, device)
+			topologyRead = true
+		}
+		coordinator, grouped := coordinatorOf(groups, device)
+		if !grouped {
+			id := strings.TrimPrefix(device.Metadata.UDN, "uuid:")
+			status, ok := byCoordinator[id]
+			if !ok || id == "" {
+				status = buildRoomStatus(ctx, device, room)
+			}
+			if id != "" {
+				byCoordinator[id] = status
+			}
+			status.Room = room
+			statuses = append(statuses, status)
+			continue
+		}
+		coordinatorStatus, ok := byCoordinator[coordinator.UUID]
+		if !ok {
+			coordinatorStatus = buildRoomStatus(ctx, discoveredDevice(devices, coordinator), coordinator.Room)
+			byCoordinator[coordinator.UUID] = coordinatorStatus
+		}
+		statuses = append(statuses, RoomStatus{
+			Room:        room,
+			State:       "Grouped with " + coordinator.Room,
+			Track:       coordinatorStatus.Track,
+			GroupedWith: coordinator.Room,
+			ArtURI:      coordinatorStatus.ArtURI,
+		})
 	}
 
 	return statuses, targetDevice
 }
 
-// PrintRoomStatuses renders the collected statuses in a table format.
-func PrintRoomStatuses(statuses []RoomStatus) {
+// readGroups asks device for the household's groups, returning none when it
+// cannot say.
+func readGroups(ctx context.Context, device Device) []ZoneGroup {
+	topologyCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Control)
+	defer cancel()
+	groups, err := ZoneGroups(topologyCtx, device)
+	if err != nil {
+		logger.Debug("group topology unavailable, rooms shown ungrouped", "err", err)
+		return nil
+	}
+	return groups
+}
+
+// coordinatorOf returns the coordinator of device's group when that is
+// another speaker.
+func coordinatorOf(groups []ZoneGroup, device Device) (ZoneMember, bool) {
+	for _, group := range groups {
+		self, ok := findMember(group, device)
+		if !ok {
+			continue
+		}
+		coordinator, ok := group.CoordinatorMember()
+		if !ok || coordinator.UUID == self.UUID {
+			return ZoneMember{}, false
+		}
+		return coordinator, true
+	}
+	return ZoneMember{}, false
+}
+
+// discoveredDevice returns member as discovered, which knows its model, or
+// as the topology describes it otherwise.
+func discoveredDevice(devices []Device, member ZoneMember) Device {
+	device := member.Device()
+	for _, discovered := range devices {
+		if sameSpeaker(discovered, device) {
+			return discovered
+		}
+	}
+	return device
+}
+
+// WriteRoomStatuses writes the collected statuses to w as a table.
+func WriteRoomStatuses(w io.Writer, statuses []RoomStatus) error {
 	roomColumnWidth := len("Room")
 	stateColumnWidth := len("State")
 	for _, status := range statuses {
@@ -58,20 +141,23 @@ func PrintRoomStatuses(statuses []RoomStatus) {
 		}
 	}
 
-	fmt.Printf("%-*s  %-*s  %s\n", roomColumnWidth, "Room", stateColumnWidth, "State", "Now Playing")
-	fmt.Printf("%s  %s  %s\n", strings.Repeat("-", roomColumnWidth), strings.Repeat("-", stateColumnWidth), strings.Repeat("-", len("Now Playing")))
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s  %-*s  %s\n", roomColumnWidth, "Room", stateColumnWidth, "State", "Now Playing")
+	fmt.Fprintf(&b, "%s  %s  %s\n", strings.Repeat("-", roomColumnWidth), strings.Repeat("-", stateColumnWidth), strings.Repeat("-", len("Now Playing")))
 	for _, status := range statuses {
-		fmt.Printf("%-*s  %-*s  %s\n", roomColumnWidth, status.Room, stateColumnWidth, status.State, status.Track)
+		fmt.Fprintf(&b, "%-*s  %-*s  %s\n", roomColumnWidth, status.Room, stateColumnWidth, status.State, status.Track)
 	}
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
 func buildRoomStatus(ctx context.Context, device Device, room string) RoomStatus {
-	playbackCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	playbackCtx, cancel := context.WithTimeout(ctx, TimeoutsFor(device).Control)
 	defer cancel()
 
 	info, err := NowPlaying(playbackCtx, device)
 	if err != nil {
-		log.Printf("warning: now playing for %s: %v", room, err)
+		logger.Warn("now playing query failed", "room", room, "err", err)
 		return RoomStatus{
 			Room:  room,
 			State: "Unavailable",
@@ -90,12 +176,38 @@ func buildRoomStatus(ctx context.Context, device Device, room string) RoomStatus
 	}
 
 	return RoomStatus{
-		Room:  room,
-		State: state,
-		Track: track,
+		Room:   room,
+		State:  state,
+		Track:  track,
+		ArtURI: absoluteArtURI(device, info.AlbumArtURI),
 	}
 }
 
+// absoluteArtURI resolves artURI, which speakers usually give relative to
+// themselves, keeping it as given when it cannot be resolved.
+func absoluteArtURI(device Device, artURI string) string {
+	if strings.TrimSpace(artURI) == "" {
+		return ""
+	}
+	resolved, err := resolveAlbumArtURL(device, artURI)
+	if err != nil {
+		return strings.TrimSpace(artURI)
+	}
+	return resolved
+}
+
+// AlbumArtURL resolves artURI, as a track's metadata gives it, to the
+// absolute address of the art, usually on device.
+func AlbumArtURL(device Device, artURI string) (string, error) {
+	return resolveAlbumArtURL(device, artURI)
+}
+
+// RoomName returns the room a device belongs to, from its description when
+// it has been fetched and from the discovery headers otherwise.
+func RoomName(device Device) string {
+	return deriveRoomName(device)
+}
+
 func deriveRoomName(device Device) string {
 	if room := strings.TrimSpace(device.Metadata.RoomName); room != "" {
 		return room
@@ -140,6 +252,11 @@ func formatTrackDisplay(info TrackInfo) string {
 	if strings.TrimSpace(info.StreamInfo) != "" {
 		return strings.TrimSpace(info.StreamInfo)
 	}
+	// Inputs and services without metadata show their source instead of the
+	// raw x-sonos URI, which would otherwise be skipped.
+	if label := info.SourceLabel(); label != "" {
+		return label
+	}
 	if strings.TrimSpace(info.URI) != "" {
 		return strings.TrimSpace(info.URI)
 	}
@@ -0,0 +1,81 @@
+package sonos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteRoomStatuses(t *testing.T) {
+	var b strings.Builder
+	err := WriteRoomStatuses(&b, []RoomStatus{
+		{Room: "Kitchen", State: "Playing", Track: "Band - Song"},
+		{Room: "Living Room", State: "Paused", Track: "(idle)"},
+	})
+	if err != nil {
+		t.Fatalf("WriteRoomStatuses error: %v", err)
+	}
+	want := "Room         State    Now Playing\n" +
+		"-----------  -------  -----------\n" +
+		"Kitchen      Playing  Band - Song\n" +
+		"Living Room  Paused   (idle)\n"
+	if b.String() != want {
+		t.Fatalf("table =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestGatherRoomStatusesLabelsGroupMembers(t *testing.T) {
+	var state string
+	var memberQueries atomic.Int32
+	speaker := func(coordinator bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			action := r.Header.Get("SOAPACTION")
+			switch {
+			case strings.Contains(action, "GetZoneGroupState"):
+				fmt.Fprint(w, zoneGroupStateResponse(state))
+			case !coordinator:
+				memberQueries.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+			case strings.Contains(action, "GetPositionInfo"):
+				fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><TrackMetaData>&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"&gt;&lt;item id="1"&gt;&lt;dc:title&gt;Song&lt;/dc:title&gt;&lt;dc:creator&gt;Band&lt;/dc:creator&gt;&lt;upnp:albumArtURI&gt;/getaa?s=1&amp;amp;u=song&lt;/upnp:albumArtURI&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;</TrackMetaData><TrackURI>x-file-cifs://nas/song.mp3</TrackURI></u:GetPositionInfoResponse></s:Body></s:Envelope>`)
+			case strings.Contains(action, "GetTransportInfo"):
+				fmt.Fprint(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetTransportInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><CurrentTransportState>PLAYING</CurrentTransportState></u:GetTransportInfoResponse></s:Body></s:Envelope>`)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+	}
+	kitchen := speaker(true)
+	defer kitchen.Close()
+	den := speaker(false)
+	defer den.Close()
+	state = `<ZoneGroups><ZoneGroup Coordinator="RINCON_K" ID="RINCON_K:1">` +
+		`<ZoneGroupMember UUID="RINCON_D" Location="` + den.URL + `/xml/device_description.xml" ZoneName="Den"/>` +
+		`<ZoneGroupMember UUID="RINCON_K" Location="` + kitchen.URL + `/xml/device_description.xml" ZoneName="Kitchen"/>` +
+		`</ZoneGroup></ZoneGroups>`
+
+	devices := []Device{
+		{Location: den.URL + "/xml/device_description.xml", IsSonos: true, Metadata: DeviceMetadata{RoomName: "Den", UDN: "uuid:RINCON_D"}},
+		{Location: kitchen.URL + "/xml/device_description.xml", IsSonos: true, Metadata: DeviceMetadata{RoomName: "Kitchen", UDN: "uuid:RINCON_K"}},
+	}
+	statuses, _ := GatherRoomStatuses(context.Background(), devices, "")
+	want := []RoomStatus{
+		{Room: "Den", State: "Grouped with Kitchen", Track: "Band - Song", GroupedWith: "Kitchen", ArtURI: kitchen.URL + "/getaa?s=1&u=song"},
+		{Room: "Kitchen", State: "Playing", Track: "Band - Song", ArtURI: kitchen.URL + "/getaa?s=1&u=song"},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses = %+v, want %+v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("status %d = %+v, want %+v", i, statuses[i], want[i])
+		}
+	}
+	if n := memberQueries.Load(); n != 0 {
+		t.Fatalf("group member was queried for its transport %d times", n)
+	}
+}
@@ -0,0 +1,97 @@
+package sonos
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeouts bounds the requests made to a speaker, by kind.
+type Timeouts struct {
+	// Control bounds each control action, such as reading what plays or
+	// skipping a track.
+	Control time.Duration
+	// Subscribe bounds subscribing to events and renewing and cancelling
+	// the subscription.
+	Subscribe time.Duration
+	// Poll bounds the reads the listener makes between events: the track
+	// position, the group topology, and polling while events are blocked.
+	Poll time.Duration
+	// Description bounds fetching a device's description.
+	Description time.Duration
+	// Art bounds fetching a track's album art.
+	Art time.Duration
+}
+
+// DefaultTimeouts suit current players.
+var DefaultTimeouts = Timeouts{
+	Control:     5 * time.Second,
+	Subscribe:   5 * time.Second,
+	Poll:        3 * time.Second,
+	Description: 10 * time.Second,
+	Art:         10 * time.Second,
+}
+
+// SlowTimeouts suit the oldest players, which only run S1 and can take well
+// over five seconds to answer while they boot or index the music library.
+var SlowTimeouts = Timeouts{
+	Control:     20 * time.Second,
+	Subscribe:   20 * time.Second,
+	Poll:        10 * time.Second,
+	Description: 30 * time.Second,
+	Art:         30 * time.Second,
+}
+
+// slowModels are the model numbers that get the slow timeouts: the
+// ZonePlayers ZP80, ZP90 (Connect), ZP100, and ZP120 (Connect:Amp), and
+// the first Play:5.
+var slowModels = []string{"ZP80", "ZP90", "ZP100", "ZP120", "S5"}
+
+var timeouts = struct {
+	sync.Mutex
+	normal, slow Timeouts
+}{normal: DefaultTimeouts, slow: SlowTimeouts}
+
+// SetTimeouts replaces the timeouts used for current players and for the
+// slow S1 models. Zero fields keep DefaultTimeouts and SlowTimeouts.
+func SetTimeouts(normal, slow Timeouts) {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	timeouts.normal = normal.withDefaults(DefaultTimeouts)
+	timeouts.slow = slow.withDefaults(SlowTimeouts)
+}
+
+// TimeoutsFor returns the timeouts for requests to device: the slow ones
+// when its model is one of the oldest players. Devices whose description
+// has not been read get the normal ones.
+func TimeoutsFor(device Device) Timeouts {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	if IsSlowModel(device.Metadata.ModelNumber) {
+		return timeouts.slow
+	}
+	return timeouts.normal
+}
+
+// IsSlowModel reports whether model, a device's model number, is one of the
+// oldest players that get the slow timeouts.
+func IsSlowModel(model string) bool {
+	return slices.Contains(slowModels, strings.ToUpper(strings.TrimSpace(model)))
+}
+
+func (t Timeouts) withDefaults(defaults Timeouts) Timeouts {
+	or := func(d, fallback time.Duration) time.Duration {
+		if d <= 0 {
+			return fallback
+		}
+		return d
+	}
+	return Timeouts{
+		Control:     or(t.Control, defaults.Control),
+		Subscribe:   or(t.Subscribe, defaults.Subscribe),
+		Poll:        or(t.Poll, defaults.Poll),
+		Description: or(t.Description, defaults.Description),
+		Art:         or(t.Art, defaults.Art),
+	}
+}
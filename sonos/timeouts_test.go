@@ -0,0 +1,60 @@
+package sonos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsForSlowModels(t *testing.T) {
+	device := Device{Metadata: DeviceMetadata{ModelNumber: "ZP90"}}
+	if got := TimeoutsFor(device); got != SlowTimeouts {
+		t.Fatalf("TimeoutsFor(ZP90) = %+v, want %+v", got, SlowTimeouts)
+	}
+	device.Metadata.ModelNumber = "S12"
+	if got := TimeoutsFor(device); got != DefaultTimeouts {
+		t.Fatalf("TimeoutsFor(S12) = %+v, want %+v", got, DefaultTimeouts)
+	}
+	if got := TimeoutsFor(Device{}); got != DefaultTimeouts {
+		t.Fatalf("TimeoutsFor(unknown) = %+v, want %+v", got, DefaultTimeouts)
+	}
+}
+
+func TestSetTimeoutsKeepsDefaultsForZeroFields(t *testing.T) {
+	t.Cleanup(func() { SetTimeouts(DefaultTimeouts, SlowTimeouts) })
+	SetTimeouts(Timeouts{Control: time.Second}, Timeouts{Art: time.Minute})
+
+	normal := TimeoutsFor(Device{})
+	if normal.Control != time.Second {
+		t.Fatalf("Control = %v, want 1s", normal.Control)
+	}
+	if normal.Art != DefaultTimeouts.Art || normal.Subscribe != DefaultTimeouts.Subscribe {
+		t.Fatalf("unset fields = %+v, want defaults", normal)
+	}
+	slow := TimeoutsFor(Device{Metadata: DeviceMetadata{ModelNumber: "ZP120"}})
+	if slow.Art != time.Minute || slow.Control != SlowTimeouts.Control {
+		t.Fatalf("slow = %+v, want Art 1m and default Control", slow)
+	}
+}
+
+func TestSlowModelGetsLongerControlTimeout(t *testing.T) {
+	t.Cleanup(func() { SetTimeouts(DefaultTimeouts, SlowTimeouts) })
+	SetTimeouts(Timeouts{Control: 20 * time.Millisecond}, Timeouts{Control: 2 * time.Second})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:PlayResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"/></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	device := Device{Location: server.URL + "/xml/device_description.xml"}
+	if _, err := callAVTransport(context.Background(), device, "Play", ""); err == nil {
+		t.Fatalf("expected a normal speaker to time out")
+	}
+	device.Metadata.ModelNumber = "ZP80"
+	if _, err := callAVTransport(context.Background(), device, "Play", ""); err != nil {
+		t.Fatalf("slow speaker call failed: %v", err)
+	}
+}
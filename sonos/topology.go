@@ -0,0 +1,190 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const zoneGroupTopologyService = "urn:schemas-upnp-org:service:ZoneGroupTopology:1"
+
+// ZoneGroup is a set of speakers playing together. Coordinator is the UUID
+// of the member that runs the group's playback.
+type ZoneGroup struct {
+	ID          string
+	Coordinator string
+	Members     []ZoneMember
+}
+
+// ZoneMember is one speaker of a ZoneGroup.
+type ZoneMember struct {
+	UUID     string
+	Room     string
+	Location string
+}
+
+// Device returns the member as a Device that can be subscribed to.
+func (m ZoneMember) Device() Device {
+	device := Device{
+		Location: m.Location,
+		IsSonos:  true,
+		Metadata: DeviceMetadata{RoomName: m.Room},
+	}
+	if m.UUID != "" {
+		device.Metadata.UDN = "uuid:" + m.UUID
+	}
+	if u, err := url.Parse(m.Location); err == nil {
+		device.IP = u.Hostname()
+	}
+	return device
+}
+
+// CoordinatorMember returns the group's coordinator.
+func (g ZoneGroup) CoordinatorMember() (ZoneMember, bool) {
+	for _, member := range g.Members {
+		if member.UUID == g.Coordinator {
+			return member, true
+		}
+	}
+	return ZoneMember{}, false
+}
+
+// ZoneGroups asks device for the household's group topology.
+func ZoneGroups(ctx context.Context, device Device) ([]ZoneGroup, error) {
+	controlURL, err := zoneGroupTopologyControlURL(device)
+	if err != nil {
+		return nil, err
+	}
+	body, err := invokeAction(ctx, TimeoutsFor(device).Control, controlURL, zoneGroupTopologyService, "GetZoneGroupState", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response *struct {
+				State string `xml:"ZoneGroupState"`
+			} `xml:"GetZoneGroupStateResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("sonos: decode zone group state: %w", err)
+	}
+	if envelope.Body.Response == nil {
+		return nil, errors.New("sonos: empty zone group state response")
+	}
+	return parseZoneGroupState(envelope.Body.Response.State)
+}
+
+type zoneGroupXML struct {
+	ID          string `xml:"ID,attr"`
+	Coordinator string `xml:"Coordinator,attr"`
+	Members     []struct {
+		UUID      string `xml:"UUID,attr"`
+		Location  string `xml:"Location,attr"`
+		ZoneName  string `xml:"ZoneName,attr"`
+		Invisible string `xml:"Invisible,attr"`
+	} `xml:"ZoneGroupMember"`
+}
+
+// parseZoneGroupState parses the ZoneGroupState document. Newer firmware
+// wraps the ZoneGroups element in a ZoneGroupState root; older firmware
+// returns ZoneGroups on its own. Invisible members, such as the satellites
+// and subwoofers of a home theater, are left out.
+func parseZoneGroupState(state string) ([]ZoneGroup, error) {
+	var doc struct {
+		Wrapped []zoneGroupXML `xml:"ZoneGroups>ZoneGroup"`
+		Direct  []zoneGroupXML `xml:"ZoneGroup"`
+	}
+	if err := xml.Unmarshal([]byte(strings.TrimSpace(state)), &doc); err != nil {
+		return nil, fmt.Errorf("sonos: parse zone group state: %w", err)
+	}
+	var groups []ZoneGroup
+	for _, raw := range append(doc.Wrapped, doc.Direct...) {
+		group := ZoneGroup{ID: raw.ID, Coordinator: raw.Coordinator}
+		for _, m := range raw.Members {
+			if m.Invisible == "1" {
+				continue
+			}
+			group.Members = append(group.Members, ZoneMember{UUID: m.UUID, Room: m.ZoneName, Location: m.Location})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// groupPeers returns the other visible members of device's group, which can
+// tell the listener where the group went if device disappears, and the room
+// name the household currently gives device, which changes when the room is
+// renamed in the Sonos app. The name is empty when device is not listed.
+func groupPeers(ctx context.Context, device Device) ([]Device, string, error) {
+	groups, err := ZoneGroups(ctx, device)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, group := range groups {
+		self, ok := findMember(group, device)
+		if !ok {
+			continue
+		}
+		var peers []Device
+		for _, member := range group.Members {
+			if !sameSpeaker(member.Device(), device) {
+				peers = append(peers, member.Device())
+			}
+		}
+		return peers, strings.TrimSpace(self.Room), nil
+	}
+	return nil, "", nil
+}
+
+// groupCoordinator asks peers, in order, which speaker now coordinates their
+// group, skipping answers that still name lost. It returns the first new
+// coordinator found.
+func groupCoordinator(ctx context.Context, peers []Device, lost Device) (Device, error) {
+	var errs []error
+	for _, peer := range peers {
+		groups, err := ZoneGroups(ctx, peer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, group := range groups {
+			if _, ok := findMember(group, peer); !ok {
+				continue
+			}
+			coordinator, ok := group.CoordinatorMember()
+			if !ok || sameSpeaker(coordinator.Device(), lost) {
+				continue
+			}
+			return coordinator.Device(), nil
+		}
+	}
+	if len(errs) > 0 {
+		return Device{}, fmt.Errorf("sonos: find group coordinator: %w", errors.Join(errs...))
+	}
+	return Device{}, errors.New("sonos: find group coordinator: group has no other coordinator")
+}
+
+func findMember(group ZoneGroup, device Device) (ZoneMember, bool) {
+	for _, member := range group.Members {
+		if sameSpeaker(member.Device(), device) {
+			return member, true
+		}
+	}
+	return ZoneMember{}, false
+}
+
+// sameSpeaker reports whether a and b are the same speaker, by UDN when both
+// know it and by UPnP address otherwise.
+func sameSpeaker(a, b Device) bool {
+	if a.Metadata.UDN != "" && b.Metadata.UDN != "" {
+		return strings.EqualFold(a.Metadata.UDN, b.Metadata.UDN)
+	}
+	baseA, errA := deviceBaseURL(a)
+	baseB, errB := deviceBaseURL(b)
+	return errA == nil && errB == nil && baseA.Host == baseB.Host
+}
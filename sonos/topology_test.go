@@ -0,0 +1,207 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+)
+
+func zoneGroupStateResponse(state string) string {
+	return `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetZoneGroupStateResponse xmlns:u="urn:schemas-upnp-org:service:ZoneGroupTopology:1"><ZoneGroupState>` +
+		html.EscapeString(state) + `</ZoneGroupState></u:GetZoneGroupStateResponse></s:Body></s:Envelope>`
+}
+
+func TestParseZoneGroupState(t *testing.T) {
+	const wrapped = `<ZoneGroupState><ZoneGroups>
+<ZoneGroup Coordinator="RINCON_A" ID="RINCON_A:1">
+  <ZoneGroupMember UUID="RINCON_A" Location="http://10.0.0.2:1400/xml/device_description.xml" ZoneName="Kitchen"/>
+  <ZoneGroupMember UUID="RINCON_B" Location="http://10.0.0.3:1400/xml/device_description.xml" ZoneName="Den"/>
+  <ZoneGroupMember UUID="RINCON_SUB" Location="http://10.0.0.4:1400/xml/device_description.xml" ZoneName="Den" Invisible="1"/>
+</ZoneGroup>
+<ZoneGroup Coordinator="RINCON_C" ID="RINCON_C:7">
+  <ZoneGroupMember UUID="RINCON_C" Location="http://10.0.0.5:1400/xml/device_description.xml" ZoneName="Office"/>
+</ZoneGroup>
+</ZoneGroups></ZoneGroupState>`
+
+	groups, err := parseZoneGroupState(wrapped)
+	if err != nil {
+		t.Fatalf("parseZoneGroupState: %v", err)
+	}
+	if len(groups) != 2 || len(groups[0].Members) != 2 {
+		t.Fatalf("groups = %+v, want two groups with the invisible member dropped", groups)
+	}
+	coordinator, ok := groups[0].CoordinatorMember()
+	if !ok || coordinator.Room != "Kitchen" {
+		t.Fatalf("coordinator = %+v, %v", coordinator, ok)
+	}
+	device := coordinator.Device()
+	if device.IP != "10.0.0.2" || device.Metadata.UDN != "uuid:RINCON_A" || RoomName(device) != "Kitchen" {
+		t.Fatalf("coordinator device = %+v", device)
+	}
+
+	legacy := `<ZoneGroups><ZoneGroup Coordinator="RINCON_C" ID="x"><ZoneGroupMember UUID="RINCON_C" Location="http://10.0.0.5:1400/xml/device_description.xml" ZoneName="Office"/></ZoneGroup></ZoneGroups>`
+	groups, err = parseZoneGroupState(legacy)
+	if err != nil || len(groups) != 1 || groups[0].Members[0].Room != "Office" {
+		t.Fatalf("legacy state = %+v, %v", groups, err)
+	}
+}
+
+// groupSpeaker is a fake speaker that takes subscriptions and reports the
+// zone group topology returned by state until it goes offline.
+type groupSpeaker struct {
+	server  *httptest.Server
+	offline atomic.Bool
+	state   atomic.Value
+}
+
+func newGroupSpeaker(t *testing.T, sid string) *groupSpeaker {
+	t.Helper()
+	s := &groupSpeaker{}
+	s.state.Store("")
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.offline.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch {
+		case r.Method == "SUBSCRIBE" || r.Method == "UNSUBSCRIBE":
+			w.Header().Set("SID", sid)
+			w.Header().Set("TIMEOUT", "Second-1800")
+		case r.URL.Path == "/ZoneGroupTopology/Control":
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), "InstanceID") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			io.WriteString(w, zoneGroupStateResponse(s.state.Load().(string)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *groupSpeaker) device() Device {
+	return Device{IP: "127.0.0.1", Location: s.location()}
+}
+
+func (s *groupSpeaker) location() string {
+	return s.server.URL + "/xml/device_description.xml"
+}
+
+func TestListenForEventsFollowsGroupToNewCoordinator(t *testing.T) {
+	kitchen := newGroupSpeaker(t, "uuid:kitchen")
+	den := newGroupSpeaker(t, "uuid:den")
+	group := func(coordinator string) string {
+		return fmt.Sprintf(`<ZoneGroupState><ZoneGroups><ZoneGroup Coordinator="%s" ID="g:1">`+
+			`<ZoneGroupMember UUID="RINCON_K" Location="%s" ZoneName="Kitchen"/>`+
+			`<ZoneGroupMember UUID="RINCON_D" Location="%s" ZoneName="Den"/>`+
+			`</ZoneGroup></ZoneGroups></ZoneGroupState>`, coordinator, kitchen.location(), den.location())
+	}
+	kitchen.state.Store(group("RINCON_K"))
+	den.state.Store(group("RINCON_K"))
+
+	fake := clock.NewFake(time.Now())
+	devices := make(chan Device, 1)
+	opts := ListenerOptions{
+		Clock:               fake,
+		HealthCheckInterval: time.Minute,
+		Rediscover: func(ctx context.Context, room string) (Device, error) {
+			return Device{}, errors.New("no device matched room")
+		},
+		OnDevice: func(d Device) { devices <- d },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, kitchen.device(), "Kitchen", "/events", opts)
+	}()
+	waitForTimers(t, fake, 2)
+
+	// The kitchen speaker drops off and the den takes over the group.
+	kitchen.offline.Store(true)
+	den.state.Store(group("RINCON_D"))
+	fake.Advance(time.Minute)
+
+	select {
+	case got := <-devices:
+		if got.Location != den.location() || RoomName(got) != "Den" {
+			t.Fatalf("OnDevice = %+v, want the den coordinator", got)
+		}
+	case err := <-done:
+		t.Fatalf("listener returned before failing over: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("listener did not fail over to the new coordinator")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("listener error: %v", err)
+	}
+}
+
+func TestListenForEventsFollowsRoomRename(t *testing.T) {
+	speaker := newGroupSpeaker(t, "uuid:kitchen")
+	state := func(room string) string {
+		return fmt.Sprintf(`<ZoneGroupState><ZoneGroups><ZoneGroup Coordinator="RINCON_K" ID="g:1">`+
+			`<ZoneGroupMember UUID="RINCON_K" Location="%s" ZoneName="%s"/>`+
+			`</ZoneGroup></ZoneGroups></ZoneGroupState>`, speaker.location(), room)
+	}
+	speaker.state.Store(state("Kitchen"))
+
+	fake := clock.NewFake(time.Now())
+	renames := make(chan [2]string, 1)
+	statuses := make(chan PlaybackStatus, 4)
+	opts := ListenerOptions{
+		Clock:             fake,
+		PollFallbackAfter: -1,
+		RoomCheckInterval: time.Minute,
+		OnRoomRenamed:     func(from, to string) { renames <- [2]string{from, to} },
+		OnStatus:          func(s PlaybackStatus) { statuses <- s },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenForEvents(ctx, speaker.device(), "kitchen", "/events", opts)
+	}()
+	// Health check, renewal, and the room check.
+	waitForTimers(t, fake, 3)
+	fake.Advance(time.Minute)
+	select {
+	case got := <-renames:
+		t.Fatalf("renamed %v while the name was unchanged", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	speaker.state.Store(state("Cooking"))
+	fake.Advance(time.Minute)
+	select {
+	case got := <-renames:
+		if got != [2]string{"kitchen", "Cooking"} {
+			t.Fatalf("rename = %v, want kitchen to Cooking", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("rename not noticed")
+	}
+	if got := <-statuses; got.Room != "Cooking" {
+		t.Fatalf("status room = %q, want the new name", got.Room)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("listener error: %v", err)
+	}
+}
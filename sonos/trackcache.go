@@ -0,0 +1,121 @@
+package sonos
+
+import "sync"
+
+// defaultTrackCacheSize bounds a session's track cache. A long evening of
+// listening stays well inside it.
+const defaultTrackCacheSize = 512
+
+// TrackCache keeps the track metadata parsed for each track URI, and the
+// artwork URLs resolved for them, for one listening session. Entries are
+// checked against the raw metadata, so streams whose metadata changes under
+// one URI still update. The oldest entries are dropped once limit is
+// reached. A nil *TrackCache caches nothing.
+type TrackCache struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[string]trackEntry
+	order   []string
+	artURLs map[string]string
+}
+
+type trackEntry struct {
+	metadata string
+	info     TrackInfo
+}
+
+// NewTrackCache returns a cache holding up to limit tracks, or a default
+// number when limit is not positive.
+func NewTrackCache(limit int) *TrackCache {
+	if limit <= 0 {
+		limit = defaultTrackCacheSize
+	}
+	return &TrackCache{
+		limit:   limit,
+		entries: make(map[string]trackEntry),
+		artURLs: make(map[string]string),
+	}
+}
+
+// Lookup returns the metadata last parsed for uri, so callers can recognise
+// a track they have seen before. Position, duration, and state are not
+// cached and are left zero.
+func (c *TrackCache) Lookup(uri string) (TrackInfo, bool) {
+	if c == nil {
+		return TrackInfo{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[uri]
+	if !ok {
+		return TrackInfo{}, false
+	}
+	info := entry.info
+	info.URI = uri
+	return withSource(info), true
+}
+
+// Len reports how many tracks are cached.
+func (c *TrackCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// parse returns the track metadata for uri, calling parse only when the
+// cached entry came from different metadata.
+func (c *TrackCache) parse(uri, metadata string, parse func(string) (TrackInfo, error)) (TrackInfo, error) {
+	if c == nil || uri == "" {
+		return parse(metadata)
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[uri]
+	c.mu.Unlock()
+	if ok && entry.metadata == metadata {
+		return entry.info, nil
+	}
+
+	info, err := parse(metadata)
+	if err != nil {
+		return info, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[uri]; !ok {
+		c.order = append(c.order, uri)
+		for len(c.order) > c.limit {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.entries[uri] = trackEntry{metadata: metadata, info: info}
+	return info, nil
+}
+
+// albumArtURL resolves artURI against device once per device address.
+func (c *TrackCache) albumArtURL(device Device, artURI string) (string, error) {
+	if c == nil {
+		return resolveAlbumArtURL(device, artURI)
+	}
+	key := device.Location + "|" + device.IP + "|" + artURI
+	c.mu.Lock()
+	resolved, ok := c.artURLs[key]
+	c.mu.Unlock()
+	if ok {
+		return resolved, nil
+	}
+	resolved, err := resolveAlbumArtURL(device, artURI)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.artURLs) >= c.limit {
+		c.artURLs = make(map[string]string)
+	}
+	c.artURLs[key] = resolved
+	return resolved, nil
+}
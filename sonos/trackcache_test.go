@@ -0,0 +1,126 @@
+package sonos
+
+import (
+	"errors"
+	"testing"
+)
+
+func countingParser(calls *int) func(string) (TrackInfo, error) {
+	return func(meta string) (TrackInfo, error) {
+		*calls++
+		return TrackInfo{Title: meta}, nil
+	}
+}
+
+func TestTrackCacheSkipsRepeatedMetadata(t *testing.T) {
+	cache := NewTrackCache(4)
+	calls := 0
+	parse := countingParser(&calls)
+
+	for i := 0; i < 3; i++ {
+		info, err := cache.parse("x-file:song", "Song", parse)
+		if err != nil || info.Title != "Song" {
+			t.Fatalf("parse = %+v, %v", info, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("parser called %d times, want 1", calls)
+	}
+
+	info, err := cache.parse("x-file:song", "Song (Live)", parse)
+	if err != nil || info.Title != "Song (Live)" || calls != 2 {
+		t.Fatalf("changed metadata: info %+v, err %v, calls %d", info, err, calls)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", cache.Len())
+	}
+}
+
+func TestTrackCacheSkipsEmptyURIAndErrors(t *testing.T) {
+	cache := NewTrackCache(4)
+	calls := 0
+	parse := countingParser(&calls)
+	cache.parse("", "Song", parse)
+	cache.parse("", "Song", parse)
+	if calls != 2 || cache.Len() != 0 {
+		t.Fatalf("empty URI: calls %d, Len %d; want 2, 0", calls, cache.Len())
+	}
+
+	failing := func(string) (TrackInfo, error) { return TrackInfo{}, errors.New("bad") }
+	if _, err := cache.parse("x-file:bad", "<", failing); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if _, ok := cache.Lookup("x-file:bad"); ok {
+		t.Fatal("failed parse was cached")
+	}
+}
+
+func TestTrackCacheEvictsOldest(t *testing.T) {
+	cache := NewTrackCache(2)
+	calls := 0
+	parse := countingParser(&calls)
+	cache.parse("a", "A", parse)
+	cache.parse("b", "B", parse)
+	cache.parse("c", "C", parse)
+
+	if _, ok := cache.Lookup("a"); ok {
+		t.Fatal("oldest entry was not evicted")
+	}
+	info, ok := cache.Lookup("c")
+	if !ok || info.Title != "C" || info.URI != "c" {
+		t.Fatalf("Lookup(c) = %+v, %v", info, ok)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", cache.Len())
+	}
+}
+
+func TestBuildTrackInfoKeepsPositionFresh(t *testing.T) {
+	meta := `&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/"&gt;&lt;item&gt;&lt;dc:title&gt;Cached&lt;/dc:title&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;`
+	tracks := NewTrackCache(4)
+	first, err := buildTrackInfo(tracks, positionInfoResponse{TrackURI: "x-file:cached", TrackMetaData: meta, RelTime: "0:00:10"})
+	if err != nil {
+		t.Fatalf("buildTrackInfo error: %v", err)
+	}
+	second, err := buildTrackInfo(tracks, positionInfoResponse{TrackURI: "x-file:cached", TrackMetaData: meta, RelTime: "0:00:20"})
+	if err != nil {
+		t.Fatalf("buildTrackInfo error: %v", err)
+	}
+	if first.Title != "Cached" || second.Title != "Cached" {
+		t.Fatalf("titles = %q, %q", first.Title, second.Title)
+	}
+	if first.Position == second.Position {
+		t.Fatalf("position was cached: %v", second.Position)
+	}
+	if _, ok := tracks.Lookup("x-file:cached"); !ok {
+		t.Fatal("track missing from session cache")
+	}
+}
+
+func TestNilTrackCacheParsesEveryTime(t *testing.T) {
+	var cache *TrackCache
+	calls := 0
+	parse := countingParser(&calls)
+	cache.parse("x-file:song", "Song", parse)
+	cache.parse("x-file:song", "Song", parse)
+	if calls != 2 || cache.Len() != 0 {
+		t.Fatalf("nil cache: calls %d, Len %d; want 2, 0", calls, cache.Len())
+	}
+	if got, err := cache.albumArtURL(Device{IP: "10.0.0.5"}, "/getaa?u=1"); err != nil || got != "http://10.0.0.5:1400/getaa?u=1" {
+		t.Fatalf("albumArtURL = %q, %v", got, err)
+	}
+}
+
+func TestTrackCacheMemoizesArtURL(t *testing.T) {
+	cache := NewTrackCache(4)
+	device := Device{IP: "127.0.0.1", Location: "http://127.0.0.1:38211/xml/device_description.xml"}
+	got, err := cache.albumArtURL(device, "/getaa?u=1")
+	if err != nil || got != "http://127.0.0.1:38211/getaa?u=1" {
+		t.Fatalf("albumArtURL = %q, %v", got, err)
+	}
+	other := Device{IP: "10.0.0.5"}
+	got, err = cache.albumArtURL(other, "/getaa?u=1")
+	if err != nil || got != "http://10.0.0.5:1400/getaa?u=1" {
+		t.Fatalf("albumArtURL for second device = %q, %v", got, err)
+	}
+}
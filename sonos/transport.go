@@ -3,10 +3,16 @@ package sonos
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 )
 
+// DefaultPort is the port Sonos speakers serve UPnP on. It is only assumed
+// when a device has no LOCATION URL; otherwise the port comes from there, so
+// compatible responders and test servers on other ports work too.
+const DefaultPort = "1400"
+
 func avTransportControlURL(device Device) (string, error) {
 	return avTransportURL(device, "Control")
 }
@@ -15,20 +21,94 @@ func avTransportEventURL(device Device) (string, error) {
 	return avTransportURL(device, "Event")
 }
 
-func avTransportURL(device Device, suffix string) (string, error) {
-	if strings.TrimSpace(device.Location) == "" {
-		return "", errors.New("sonos: device location is empty")
+func renderingControlURL(device Device) (string, error) {
+	return mediaRendererURL(device, "RenderingControl/Control")
+}
+
+func zoneGroupTopologyControlURL(device Device) (string, error) {
+	baseURL, err := deviceBaseURL(device)
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimRight(baseURL.String(), "/") + "/ZoneGroupTopology/Control", nil
+}
+
+func contentDirectoryControlURL(device Device) (string, error) {
+	return contentDirectoryURL(device, "Control")
+}
+
+func contentDirectoryEventURL(device Device) (string, error) {
+	return contentDirectoryURL(device, "Event")
+}
 
-	baseURL, err := url.Parse(device.Location)
+func contentDirectoryURL(device Device, suffix string) (string, error) {
+	baseURL, err := deviceBaseURL(device)
 	if err != nil {
-		return "", fmt.Errorf("sonos: parse device location: %w", err)
+		return "", err
 	}
+	return strings.TrimRight(baseURL.String(), "/") + "/MediaServer/ContentDirectory/" + suffix, nil
+}
 
-	baseURL.Path = ""
-	baseURL.RawPath = ""
-	baseURL.RawQuery = ""
-	baseURL.Fragment = ""
+func avTransportURL(device Device, suffix string) (string, error) {
+	return mediaRendererURL(device, "AVTransport/"+suffix)
+}
 
-	return strings.TrimRight(baseURL.String(), "/") + "/MediaRenderer/AVTransport/" + suffix, nil
+// mediaRendererURL resolves a MediaRenderer service path against the device's
+// base URL.
+func mediaRendererURL(device Device, path string) (string, error) {
+	baseURL, err := deviceBaseURL(device)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(baseURL.String(), "/") + "/MediaRenderer/" + path, nil
+}
+
+// deviceBaseURL returns the scheme and host the device serves UPnP on: those
+// of its LOCATION URL, or its IP address on DefaultPort when the location is
+// unknown.
+func deviceBaseURL(device Device) (*url.URL, error) {
+	if loc := strings.TrimSpace(device.Location); loc != "" {
+		base, err := url.Parse(loc)
+		if err != nil {
+			return nil, fmt.Errorf("sonos: parse device location: %w", err)
+		}
+		if base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("sonos: device location %q is not an absolute URL", loc)
+		}
+		return &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/"}, nil
+	}
+	if ip := strings.TrimSpace(device.IP); ip != "" {
+		return &url.URL{Scheme: "http", Host: net.JoinHostPort(ip, DefaultPort), Path: "/"}, nil
+	}
+	return nil, errors.New("sonos: device location is empty")
+}
+
+// devicePort returns the port the device serves UPnP on.
+func devicePort(device Device) string {
+	if base, err := deviceBaseURL(device); err == nil {
+		if port := base.Port(); port != "" {
+			return port
+		}
+		if base.Scheme == "https" {
+			return "443"
+		}
+		return "80"
+	}
+	return DefaultPort
+}
+
+// descriptionURL returns the UPnP description URL of a speaker at host and
+// port. IPv6 hosts are bracketed and their zones escaped.
+func descriptionURL(host, port string) string {
+	u := &url.URL{Scheme: "http", Host: net.JoinHostPort(host, port), Path: "/xml/device_description.xml"}
+	return u.String()
+}
+
+// hostIP returns ip as a host for URLs and dialing, keeping the zone of a
+// link-local IPv6 address, without which it cannot be reached.
+func hostIP(ip net.IP, zone string) string {
+	if zone != "" && ip.To4() == nil && ip.IsLinkLocalUnicast() {
+		return ip.String() + "%" + zone
+	}
+	return ip.String()
 }
@@ -0,0 +1,47 @@
+package sonos
+
+import "testing"
+
+func TestDeviceURLsFollowLocationPort(t *testing.T) {
+	cases := []struct {
+		device  Device
+		control string
+		art     string
+		port    string
+	}{
+		{
+			device:  Device{IP: "192.168.1.23", Location: "http://192.168.1.23:1400/xml/device_description.xml"},
+			control: "http://192.168.1.23:1400/MediaRenderer/AVTransport/Control",
+			art:     "http://192.168.1.23:1400/getaa?u=1",
+			port:    "1400",
+		},
+		{
+			device:  Device{IP: "127.0.0.1", Location: "http://127.0.0.1:38211/xml/device_description.xml"},
+			control: "http://127.0.0.1:38211/MediaRenderer/AVTransport/Control",
+			art:     "http://127.0.0.1:38211/getaa?u=1",
+			port:    "38211",
+		},
+		{
+			device:  Device{IP: "10.0.0.5"},
+			control: "http://10.0.0.5:1400/MediaRenderer/AVTransport/Control",
+			art:     "http://10.0.0.5:1400/getaa?u=1",
+			port:    "1400",
+		},
+	}
+	for _, tc := range cases {
+		control, err := avTransportControlURL(tc.device)
+		if err != nil || control != tc.control {
+			t.Fatalf("control URL for %+v = %q, %v; want %q", tc.device, control, err, tc.control)
+		}
+		art, err := resolveAlbumArtURL(tc.device, "/getaa?u=1")
+		if err != nil || art != tc.art {
+			t.Fatalf("art URL for %+v = %q, %v; want %q", tc.device, art, err, tc.art)
+		}
+		if port := devicePort(tc.device); port != tc.port {
+			t.Fatalf("port for %+v = %q, want %q", tc.device, port, tc.port)
+		}
+	}
+	if _, err := avTransportControlURL(Device{}); err == nil {
+		t.Fatalf("control URL resolved for a device without address")
+	}
+}
@@ -0,0 +1,360 @@
+// Package spotify reads the listening state of a Spotify account through the
+// Web API so it can be shown when no Sonos room is playing.
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenURL = "https://accounts.spotify.com/api/token"
+	defaultAPIURL   = "https://api.spotify.com/v1"
+
+	// minArtSize is the smallest artwork edge worth downloading for a 64x64
+	// panel.
+	minArtSize = 64
+)
+
+// Credentials authorise the client using the refresh-token flow. The refresh
+// token must have been granted the user-read-playback-state scope, plus
+// user-modify-playback-state and user-library-modify for SkipToNext and
+// SaveTrack.
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+}
+
+// Playback is the account's current player state.
+type Playback struct {
+	// ID identifies the track or episode; it changes whenever the item does.
+	ID      string
+	Playing bool
+	// Device is the name of the Spotify Connect device playing the item.
+	Device   string
+	Title    string
+	Artist   string
+	Album    string
+	ArtURL   string
+	Position time.Duration
+	Duration time.Duration
+}
+
+// Client queries the Spotify Web API, refreshing its access token as needed.
+type Client struct {
+	creds      Credentials
+	httpClient *http.Client
+	tokenURL   string
+	apiURL     string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewClient returns a client for creds.
+func NewClient(creds Credentials) *Client {
+	return &Client{
+		creds:      creds,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		tokenURL:   defaultTokenURL,
+		apiURL:     defaultAPIURL,
+	}
+}
+
+// CurrentPlayback returns the account's player state. ok is false when no
+// device is active.
+func (c *Client) CurrentPlayback(ctx context.Context) (Playback, bool, error) {
+	resp, err := c.get(ctx, c.apiURL+"/me/player?additional_types=episode")
+	if err != nil {
+		return Playback{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return Playback{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Playback{}, false, statusError("player", resp)
+	}
+
+	var state playerState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return Playback{}, false, fmt.Errorf("spotify: decode player state: %w", err)
+	}
+	if state.Item == nil {
+		return Playback{}, false, nil
+	}
+	return state.playback(), true, nil
+}
+
+// FetchImage downloads artwork from url.
+func (c *Client) FetchImage(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("spotify: build image request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("spotify: fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("image", resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("spotify: read image: %w", err)
+	}
+	return data, nil
+}
+
+// SkipToNext skips the account's active player to the next item.
+func (c *Client) SkipToNext(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodPost, c.apiURL+"/me/player/next")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return statusError("skip", resp)
+	}
+	return nil
+}
+
+// SaveTrack adds the track with the given ID to the account's Liked Songs.
+func (c *Client) SaveTrack(ctx context.Context, id string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("spotify: save track: empty track id")
+	}
+	resp, err := c.do(ctx, http.MethodPut, c.apiURL+"/me/tracks?ids="+url.QueryEscape(id))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return statusError("save track", resp)
+	}
+	return nil
+}
+
+// TrackIDFromURI extracts the Spotify track ID from a Spotify URI or from a
+// Sonos track URI wrapping one, such as
+// "x-sonos-spotify:spotify%3atrack%3a4uLU6hMCjMI75M1A2tKUQC?sid=9".
+func TrackIDFromURI(uri string) (string, bool) {
+	decoded, err := url.QueryUnescape(uri)
+	if err != nil {
+		decoded = uri
+	}
+	lower := strings.ToLower(decoded)
+	idx := strings.Index(lower, "spotify:track:")
+	if idx < 0 {
+		return "", false
+	}
+	id := decoded[idx+len("spotify:track:"):]
+	if end := strings.IndexAny(id, "?&#:/"); end >= 0 {
+		id = id[:end]
+	}
+	if id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// get performs an authorised GET request.
+func (c *Client) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, endpoint)
+}
+
+// do performs an authorised API request, refreshing the token and retrying
+// once if the API rejects it.
+func (c *Client) do(ctx context.Context, method, endpoint string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := c.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("spotify: build request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("spotify: request %s: %w", endpoint, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			c.invalidate()
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func (c *Client) invalidate() {
+	c.mu.Lock()
+	c.accessToken = ""
+	c.mu.Unlock()
+}
+
+// token returns a valid access token, exchanging the refresh token when the
+// cached one is missing or about to expire.
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != "" && time.Until(c.expiresAt) > 30*time.Second {
+		return c.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.creds.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("spotify: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.creds.ClientID, c.creds.ClientSecret)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("spotify: refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("token", resp)
+	}
+
+	var payload struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("spotify: decode token: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return "", errors.New("spotify: token response missing access_token")
+	}
+	c.accessToken = payload.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(payload.ExpiresIn) * time.Second)
+	if payload.RefreshToken != "" {
+		c.creds.RefreshToken = payload.RefreshToken
+	}
+	return c.accessToken, nil
+}
+
+func statusError(what string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := fmt.Sprintf("spotify: %s http status %s", what, resp.Status)
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		msg += " (retry after " + retry + "s)"
+	}
+	if text := strings.TrimSpace(string(body)); text != "" {
+		msg += ": " + text
+	}
+	return errors.New(msg)
+}
+
+type playerState struct {
+	Device struct {
+		Name string `json:"name"`
+	} `json:"device"`
+	ProgressMS int   `json:"progress_ms"`
+	IsPlaying  bool  `json:"is_playing"`
+	Item       *item `json:"item"`
+}
+
+type item struct {
+	ID         string `json:"id"`
+	URI        string `json:"uri"`
+	Name       string `json:"name"`
+	DurationMS int    `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album *collection `json:"album"`
+	Show  *collection `json:"show"`
+}
+
+type collection struct {
+	Name   string     `json:"name"`
+	Images []artImage `json:"images"`
+}
+
+type artImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+func (s playerState) playback() Playback {
+	pb := Playback{
+		ID:       s.Item.ID,
+		Playing:  s.IsPlaying,
+		Device:   s.Device.Name,
+		Title:    s.Item.Name,
+		Position: time.Duration(s.ProgressMS) * time.Millisecond,
+		Duration: time.Duration(s.Item.DurationMS) * time.Millisecond,
+	}
+	if pb.ID == "" {
+		pb.ID = s.Item.URI
+	}
+
+	artists := make([]string, 0, len(s.Item.Artists))
+	for _, a := range s.Item.Artists {
+		if name := strings.TrimSpace(a.Name); name != "" {
+			artists = append(artists, name)
+		}
+	}
+	pb.Artist = strings.Join(artists, ", ")
+
+	source := s.Item.Album
+	if source == nil {
+		// Podcast episodes carry their artwork and name on the show.
+		source = s.Item.Show
+		if source != nil && pb.Artist == "" {
+			pb.Artist = source.Name
+		}
+	}
+	if source != nil {
+		pb.Album = source.Name
+		pb.ArtURL = pickImage(source.Images)
+	}
+	return pb
+}
+
+// pickImage returns the smallest image that still covers the panel, or the
+// largest available one.
+func pickImage(images []artImage) string {
+	best := -1
+	for i, img := range images {
+		if best < 0 {
+			best = i
+			continue
+		}
+		cur := images[best]
+		switch {
+		case img.Width >= minArtSize && (cur.Width < minArtSize || img.Width < cur.Width):
+			best = i
+		case cur.Width < minArtSize && img.Width > cur.Width:
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return images[best].URL
+}
@@ -0,0 +1,147 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, api http.HandlerFunc) (*Client, *int) {
+	t.Helper()
+	tokenRequests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "id" || secret != "secret" {
+			t.Fatalf("unexpected basic auth %q/%q", id, secret)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "refresh_token" {
+			t.Fatalf("grant_type = %q", got)
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, tokenRequests)
+	})
+	mux.HandleFunc("/v1/", api)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewClient(Credentials{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh"})
+	client.tokenURL = server.URL + "/token"
+	client.apiURL = server.URL + "/v1"
+	return client, &tokenRequests
+}
+
+func TestCurrentPlayback(t *testing.T) {
+	client, tokens := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Fatalf("Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{
+  "device": {"name": "Pato's iPhone"},
+  "progress_ms": 61500,
+  "is_playing": true,
+  "item": {
+    "id": "abc",
+    "name": "My Song",
+    "duration_ms": 210000,
+    "artists": [{"name": "One"}, {"name": "Two"}],
+    "album": {"name": "My Album", "images": [
+      {"url": "http://img/640", "width": 640, "height": 640},
+      {"url": "http://img/300", "width": 300, "height": 300},
+      {"url": "http://img/64", "width": 64, "height": 64}
+    ]}
+  }
+}`)
+	})
+
+	pb, ok, err := client.CurrentPlayback(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("CurrentPlayback = ok %v, err %v", ok, err)
+	}
+	want := Playback{
+		ID:       "abc",
+		Playing:  true,
+		Device:   "Pato's iPhone",
+		Title:    "My Song",
+		Artist:   "One, Two",
+		Album:    "My Album",
+		ArtURL:   "http://img/64",
+		Position: 61500 * time.Millisecond,
+		Duration: 210 * time.Second,
+	}
+	if pb != want {
+		t.Fatalf("playback = %+v, want %+v", pb, want)
+	}
+
+	if _, _, err := client.CurrentPlayback(context.Background()); err != nil {
+		t.Fatalf("second CurrentPlayback error: %v", err)
+	}
+	if *tokens != 1 {
+		t.Fatalf("token requests = %d, want cached token reused", *tokens)
+	}
+}
+
+func TestCurrentPlaybackNothingActive(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if _, ok, err := client.CurrentPlayback(context.Background()); err != nil || ok {
+		t.Fatalf("CurrentPlayback = ok %v, err %v; want nothing active", ok, err)
+	}
+}
+
+func TestCurrentPlaybackRefreshesRejectedToken(t *testing.T) {
+	client, tokens := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"is_playing": false, "item": {"uri": "spotify:episode:1", "name": "Ep", "show": {"name": "Pod", "images": [{"url": "http://img/show", "width": 300}]}}}`)
+	})
+
+	pb, ok, err := client.CurrentPlayback(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("CurrentPlayback = ok %v, err %v", ok, err)
+	}
+	if *tokens != 2 {
+		t.Fatalf("token requests = %d, want refresh after 401", *tokens)
+	}
+	if pb.ID != "spotify:episode:1" || pb.Artist != "Pod" || pb.ArtURL != "http://img/show" || pb.Playing {
+		t.Fatalf("episode playback = %+v", pb)
+	}
+}
+
+func TestSaveTrack(t *testing.T) {
+	var gotMethod, gotPath, gotIDs string
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotIDs = r.Method, r.URL.Path, r.URL.Query().Get("ids")
+	})
+
+	if err := client.SaveTrack(context.Background(), "4uLU6hMCjMI75M1A2tKUQC"); err != nil {
+		t.Fatalf("SaveTrack error: %v", err)
+	}
+	if gotMethod != http.MethodPut || gotPath != "/v1/me/tracks" || gotIDs != "4uLU6hMCjMI75M1A2tKUQC" {
+		t.Fatalf("request = %s %s ids=%q", gotMethod, gotPath, gotIDs)
+	}
+}
+
+func TestTrackIDFromURI(t *testing.T) {
+	cases := map[string]string{
+		"spotify:track:4uLU6hMCjMI75M1A2tKUQC":                                      "4uLU6hMCjMI75M1A2tKUQC",
+		"x-sonos-spotify:spotify%3atrack%3a4uLU6hMCjMI75M1A2tKUQC?sid=9&flags=8224": "4uLU6hMCjMI75M1A2tKUQC",
+		"x-rincon-mp3radio://example.com/stream":                                    "",
+		"spotify:episode:512ojhOuo1ktJprKbVcKyQ":                                    "",
+	}
+	for uri, want := range cases {
+		got, ok := TrackIDFromURI(uri)
+		if got != want || ok != (want != "") {
+			t.Fatalf("TrackIDFromURI(%q) = %q, %v; want %q", uri, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package termdisplay renders frames to an ANSI terminal using 24-bit colors
+// and half-block characters, so the whole pipeline can run over SSH without
+// the panel hardware. Each character cell shows two vertically stacked
+// pixels: the upper one as the foreground of "▀" and the lower one as the
+// background.
+package termdisplay
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"strings"
+	"sync"
+
+	"musicDisplay/matrixdisplay"
+)
+
+const (
+	escClearScreen = "\x1b[2J"
+	escReset       = "\x1b[0m"
+	escSaveCursor  = "\x1b7"
+	escRestore     = "\x1b8"
+	escHideCursor  = "\x1b[?25l"
+	escShowCursor  = "\x1b[?25h"
+	escResetScroll = "\x1b[r"
+)
+
+// Display draws frames at the top of the terminal and confines regular
+// program output to a scrolling region underneath.
+type Display struct {
+	geometry matrixdisplay.Geometry
+
+	mu         sync.Mutex
+	out        io.Writer
+	frame      *image.RGBA
+	brightness int
+	gamutBits  int
+	rows       []string
+}
+
+// New prepares the terminal behind out for drawing frames of the given
+// geometry.
+func New(out io.Writer, geometry matrixdisplay.Geometry, brightness int) (*Display, error) {
+	if out == nil {
+		return nil, errors.New("termdisplay: nil writer")
+	}
+	geometry = geometry.OrDefault()
+	d := &Display{
+		geometry:   geometry,
+		out:        out,
+		frame:      image.NewRGBA(geometry.Bounds()),
+		brightness: brightness,
+	}
+	frameRows := (geometry.Height + 1) / 2
+	// Reserve the frame rows plus a spacer; output scrolls below them.
+	setup := escClearScreen + fmt.Sprintf("\x1b[%d;r", frameRows+2) + fmt.Sprintf("\x1b[%d;1H", frameRows+2)
+	if _, err := io.WriteString(out, setup); err != nil {
+		return nil, fmt.Errorf("termdisplay: init terminal: %w", err)
+	}
+	return d, d.render()
+}
+
+// Size reports the frame size of the display's geometry.
+func (d *Display) Size() (int, int) {
+	return d.geometry.Width, d.geometry.Height
+}
+
+// Show draws img.
+func (d *Display) Show(img image.Image) error {
+	if img == nil {
+		return errors.New("termdisplay: nil image")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	bounds := img.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(frame, frame.Bounds(), img, bounds.Min, draw.Src)
+	d.frame = frame
+	return d.renderLocked()
+}
+
+// Clear blanks the frame.
+func (d *Display) Clear() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.frame = image.NewRGBA(d.frame.Bounds())
+	return d.renderLocked()
+}
+
+// SetBrightness scales the drawn colors to level percent, like the matrix
+// controller.
+func (d *Display) SetBrightness(level int) error {
+	if level < 1 || level > 100 {
+		return fmt.Errorf("termdisplay: brightness must be between 1 and 100, got %d", level)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.brightness = level
+	return d.renderLocked()
+}
+
+// PreviewGamut makes frames drawn from now on simulate a matrix with pwmBits
+// of color depth, as matrixdisplay.SimulateGamut does; 0 turns it off.
+func (d *Display) PreviewGamut(pwmBits int) {
+	d.mu.Lock()
+	d.gamutBits = pwmBits
+	d.mu.Unlock()
+}
+
+// Close restores the terminal's scrolling region, colors, and cursor.
+func (d *Display) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err := io.WriteString(d.out, escResetScroll+escReset+escShowCursor)
+	return err
+}
+
+func (d *Display) render() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.renderLocked()
+}
+
+// renderLocked redraws the rows that changed since the last frame. Callers
+// must hold d.mu.
+func (d *Display) renderLocked() error {
+	var frame *image.RGBA
+	if d.gamutBits > 0 {
+		frame = matrixdisplay.SimulateGamut(d.frame, d.gamutBits, d.brightness)
+	} else {
+		frame = matrixdisplay.ApplyBrightness(d.frame, d.brightness)
+	}
+	rows := encodeRows(frame)
+
+	var b strings.Builder
+	for i, row := range rows {
+		if i < len(d.rows) && d.rows[i] == row {
+			continue
+		}
+		fmt.Fprintf(&b, "\x1b[%d;1H%s", i+1, row)
+	}
+	d.rows = rows
+	if b.Len() == 0 {
+		return nil
+	}
+
+	w := bufio.NewWriter(d.out)
+	_, _ = w.WriteString(escSaveCursor + escHideCursor)
+	_, _ = w.WriteString(b.String())
+	_, _ = w.WriteString(escReset + escRestore + escShowCursor)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("termdisplay: write frame: %w", err)
+	}
+	return nil
+}
+
+// encodeRows converts frame into one escape-coded string per character row,
+// emitting color changes only where consecutive cells differ.
+func encodeRows(frame *image.RGBA) []string {
+	bounds := frame.Bounds()
+	rows := make([]string, 0, (bounds.Dy()+1)/2)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		var b strings.Builder
+		var lastFg, lastBg color.RGBA
+		first := true
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			fg := frame.RGBAAt(x, y)
+			bg := color.RGBA{}
+			if y+1 < bounds.Max.Y {
+				bg = frame.RGBAAt(x, y+1)
+			}
+			if first || fg != lastFg {
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm", fg.R, fg.G, fg.B)
+			}
+			if first || bg != lastBg {
+				fmt.Fprintf(&b, "\x1b[48;2;%d;%d;%dm", bg.R, bg.G, bg.B)
+			}
+			b.WriteString("▀")
+			lastFg, lastBg, first = fg, bg, false
+		}
+		b.WriteString(escReset)
+		rows = append(rows, b.String())
+	}
+	return rows
+}
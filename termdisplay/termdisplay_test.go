@@ -0,0 +1,56 @@
+package termdisplay
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"musicDisplay/matrixdisplay"
+)
+
+func TestShowWritesHalfBlocksAndOnlyChangedRows(t *testing.T) {
+	var out bytes.Buffer
+	d, err := New(&out, matrixdisplay.Geometry{}, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if !strings.Contains(out.String(), "\x1b[34;r") {
+		t.Fatalf("setup does not reserve a scroll region below the frame: %q", out.String()[:40])
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+	img.SetRGBA(0, 1, color.RGBA{B: 255, A: 255})
+	out.Reset()
+	if err := d.Show(img); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "\x1b[1;1H\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀") {
+		t.Fatalf("first cell not encoded as red over blue: %q", got[:80])
+	}
+	if strings.Contains(got, "\x1b[2;1H") {
+		t.Fatalf("unchanged second row was redrawn")
+	}
+	if n := strings.Count(got, "▀"); n != 64 {
+		t.Fatalf("drew %d cells, want one changed row of 64", n)
+	}
+
+	out.Reset()
+	if err := d.Show(img); err != nil {
+		t.Fatalf("Show error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("identical frame wrote %d bytes", out.Len())
+	}
+
+	out.Reset()
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if !strings.Contains(out.String(), "\x1b[r") {
+		t.Fatalf("Close did not reset scroll region")
+	}
+}
@@ -0,0 +1,185 @@
+package theme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+const (
+	// artSamples bounds how many pixels ArtColors looks at, so large
+	// artwork costs no more than a 64x64 panel frame.
+	artSamples = 4096
+	// minColorDistance keeps the extracted colors visibly different.
+	minColorDistance = 64
+	// minAccentLuma is the darkest an accent taken from artwork may be, so
+	// the progress bar and text stay visible on a black panel.
+	minAccentLuma = 110
+)
+
+// ArtColors returns up to n of the most prominent colors of img, most
+// prominent first. Colors are ranked by how much of the image they cover,
+// weighted towards saturated ones, so a vivid detail can outrank a large grey
+// area. Near-black, near-white, and transparent pixels are ignored; artwork
+// made only of those yields no colors.
+func ArtColors(img image.Image, n int) []color.RGBA {
+	if img == nil || n <= 0 {
+		return nil
+	}
+	bounds := img.Bounds()
+	step := 1
+	for (bounds.Dx()/step)*(bounds.Dy()/step) > artSamples {
+		step++
+	}
+
+	type bucket struct {
+		r, g, b, count int
+	}
+	buckets := map[int]*bucket{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			hi, lo := maxMin(c)
+			if hi < 40 || lo > 225 {
+				continue
+			}
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.r += int(c.R)
+			bk.g += int(c.G)
+			bk.b += int(c.B)
+			bk.count++
+		}
+	}
+
+	type candidate struct {
+		color color.RGBA
+		score float64
+	}
+	candidates := make([]candidate, 0, len(buckets))
+	for _, bk := range buckets {
+		c := color.RGBA{R: uint8(bk.r / bk.count), G: uint8(bk.g / bk.count), B: uint8(bk.b / bk.count), A: 0xff}
+		candidates = append(candidates, candidate{color: c, score: float64(bk.count) * (0.25 + saturation(c))})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return rgbKey(candidates[i].color) < rgbKey(candidates[j].color)
+	})
+
+	var colors []color.RGBA
+	for _, cand := range candidates {
+		if len(colors) == n {
+			break
+		}
+		distinct := true
+		for _, picked := range colors {
+			if colorDistance(cand.color, picked) < minColorDistance {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			colors = append(colors, cand.color)
+		}
+	}
+	return colors
+}
+
+// Swatch is a color as integrations such as smart bulbs take it: as a
+// "#rrggbb" string and as red, green, and blue from 0 to 255.
+type Swatch struct {
+	Hex string `json:"hex"`
+	RGB [3]int `json:"rgb"`
+}
+
+// Swatches returns colors as swatches, in the same order.
+func Swatches(colors []color.RGBA) []Swatch {
+	swatches := make([]Swatch, 0, len(colors))
+	for _, c := range colors {
+		swatches = append(swatches, Swatch{
+			Hex: fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B),
+			RGB: [3]int{int(c.R), int(c.G), int(c.B)},
+		})
+	}
+	return swatches
+}
+
+// WithArtColors returns p with its accent and text colors taken from colors,
+// as returned by ArtColors: the first becomes the accent and the second, if
+// any, the text. Both are lightened until they show up on a dark panel. The
+// background is kept, and so is p itself when colors is empty.
+func (p Palette) WithArtColors(colors []color.RGBA) Palette {
+	if len(colors) == 0 {
+		return p
+	}
+	p.Accent = brighten(colors[0], minAccentLuma)
+	if len(colors) > 1 {
+		p.Text = brighten(colors[1], 2*minAccentLuma)
+	}
+	return p
+}
+
+func maxMin(c color.NRGBA) (uint8, uint8) {
+	hi, lo := c.R, c.R
+	for _, v := range []uint8{c.G, c.B} {
+		if v > hi {
+			hi = v
+		}
+		if v < lo {
+			lo = v
+		}
+	}
+	return hi, lo
+}
+
+func saturation(c color.RGBA) float64 {
+	hi, lo := maxMin(color.NRGBA(c))
+	if hi == 0 {
+		return 0
+	}
+	return float64(hi-lo) / float64(hi)
+}
+
+func luma(c color.RGBA) int {
+	return (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+}
+
+// brighten mixes c with white until its luma reaches at least min.
+func brighten(c color.RGBA, min int) color.RGBA {
+	if min > 255 {
+		min = 255
+	}
+	lift := func(v uint8) uint8 {
+		return v + uint8((255-int(v)+7)/8)
+	}
+	for luma(c) < min {
+		c.R, c.G, c.B = lift(c.R), lift(c.G), lift(c.B)
+	}
+	return c
+}
+
+func colorDistance(a, b color.RGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return abs(dr) + abs(dg) + abs(db)
+}
+
+func rgbKey(c color.RGBA) int {
+	return int(c.R)<<16 | int(c.G)<<8 | int(c.B)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,54 @@
+package theme
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestArtColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 0x20, G: 0x40, B: 0xc0, A: 0xff}), image.Point{}, draw.Src)
+	// A smaller red patch, and black and white areas that do not count.
+	draw.Draw(img, image.Rect(0, 0, 32, 16), image.NewUniform(color.RGBA{R: 0xe0, G: 0x10, B: 0x10, A: 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 48, 64, 64), image.Black, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(48, 16, 64, 48), image.White, image.Point{}, draw.Src)
+
+	colors := ArtColors(img, 3)
+	if len(colors) != 2 {
+		t.Fatalf("ArtColors = %v, want blue and red only", colors)
+	}
+	if colors[0] != (color.RGBA{R: 0x20, G: 0x40, B: 0xc0, A: 0xff}) || colors[1] != (color.RGBA{R: 0xe0, G: 0x10, B: 0x10, A: 0xff}) {
+		t.Fatalf("ArtColors = %v, want blue then red", colors)
+	}
+
+	if got := ArtColors(image.NewRGBA(image.Rect(0, 0, 8, 8)), 3); len(got) != 0 {
+		t.Fatalf("transparent art colors = %v", got)
+	}
+}
+
+func TestWithArtColors(t *testing.T) {
+	if got := DefaultPalette.WithArtColors(nil); got != DefaultPalette {
+		t.Fatalf("empty colors changed the palette: %+v", got)
+	}
+	dark := color.RGBA{R: 0x30, G: 0x00, B: 0x00, A: 0xff}
+	got := DefaultPalette.WithArtColors([]color.RGBA{dark, {R: 0x00, G: 0x80, B: 0x00, A: 0xff}})
+	if luma(got.Accent) < minAccentLuma || got.Accent.R <= got.Accent.G {
+		t.Fatalf("accent %v is not a brightened red", got.Accent)
+	}
+	if got.Text.G <= got.Text.R || got.Background != DefaultPalette.Background {
+		t.Fatalf("palette = %+v, want green text and the theme background", got)
+	}
+}
+
+func TestSwatches(t *testing.T) {
+	got := Swatches([]color.RGBA{{R: 0x20, G: 0x40, B: 0xc0, A: 0xff}, {R: 0xff, A: 0xff}})
+	want := []Swatch{{Hex: "#2040c0", RGB: [3]int{0x20, 0x40, 0xc0}}, {Hex: "#ff0000", RGB: [3]int{0xff, 0, 0}}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Swatches = %+v, want %+v", got, want)
+	}
+	if got := Swatches(nil); got == nil || len(got) != 0 {
+		t.Fatalf("Swatches(nil) = %#v, want an empty list", got)
+	}
+}
@@ -0,0 +1,148 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/scene"
+	"musicDisplay/schedule"
+)
+
+// Palette holds the colors renderers use for text and decorations drawn on top
+// of, or instead of, album artwork.
+type Palette struct {
+	Text       color.RGBA
+	Accent     color.RGBA
+	Background color.RGBA
+}
+
+// DefaultPalette is used when no theme configures colors.
+var DefaultPalette = Palette{
+	Text:       color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Accent:     color.RGBA{R: 0x1d, G: 0xb9, B: 0x54, A: 0xff},
+	Background: color.RGBA{A: 0xff},
+}
+
+// Theme is a named combination of palette, panel brightness, and scene
+// rules. A zero Brightness leaves the current brightness untouched, an empty
+// IdleScreen keeps the renderer's configured idle screen, and nil Scenes
+// keep the scene rules in effect.
+type Theme struct {
+	Name       string
+	Brightness int
+	Palette    Palette
+	IdleScreen string
+	Scenes     []scene.Rule
+}
+
+// Equal reports whether t and other look and behave the same, so a switch
+// between them needs nothing applied.
+func (t Theme) Equal(other Theme) bool {
+	return t.Name == other.Name && t.Brightness == other.Brightness && t.Palette == other.Palette &&
+		t.IdleScreen == other.IdleScreen && (t.Scenes == nil) == (other.Scenes == nil) &&
+		slices.EqualFunc(t.Scenes, other.Scenes, scene.Rule.Equal)
+}
+
+// Variant pairs a theme with the daily time it becomes active.
+type Variant struct {
+	Theme Theme
+	Start schedule.TimeOfDay
+}
+
+// Schedule picks the active theme variant for a point in time. Each variant
+// stays active from its start until the next variant's start, wrapping around
+// midnight.
+type Schedule struct {
+	daily schedule.Daily[Theme]
+}
+
+// NewSchedule validates the variants and returns a schedule. Solar start times
+// require coords.
+func NewSchedule(variants []Variant, coords *schedule.Coordinates) (Schedule, error) {
+	if len(variants) == 0 {
+		return Schedule{}, errors.New("theme: schedule needs at least one theme")
+	}
+	entries := make([]schedule.Entry[Theme], 0, len(variants))
+	for _, v := range variants {
+		if v.Start.Solar() && coords == nil {
+			return Schedule{}, fmt.Errorf("theme: %q starts at %s but no coordinates are configured", v.Theme.Name, v.Start)
+		}
+		entries = append(entries, schedule.Entry[Theme]{Start: v.Start, Value: v.Theme})
+	}
+	daily, err := schedule.NewDaily(entries, coords)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("theme: %w", err)
+	}
+	return Schedule{daily: daily}, nil
+}
+
+// Active returns the theme in effect at now.
+func (s Schedule) Active(now time.Time) Theme {
+	active, ok := s.daily.At(now)
+	if !ok && active.Equal(Theme{}) {
+		return Theme{Palette: DefaultPalette}
+	}
+	return active
+}
+
+// NextChange returns the next time after now at which a variant starts. ok is
+// false when no start time can be resolved within the next two days.
+func (s Schedule) NextChange(now time.Time) (time.Time, bool) {
+	return s.daily.NextChange(now)
+}
+
+// Current holds the active theme so renderers can read it while a scheduler
+// swaps it in the background.
+type Current struct {
+	mu    sync.RWMutex
+	theme Theme
+}
+
+// NewCurrent returns a holder initialised with t.
+func NewCurrent(t Theme) *Current {
+	return &Current{theme: t}
+}
+
+// Load returns the active theme.
+func (c *Current) Load() Theme {
+	if c == nil {
+		return Theme{Palette: DefaultPalette}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.theme
+}
+
+// Store replaces the active theme.
+func (c *Current) Store(t Theme) {
+	c.mu.Lock()
+	c.theme = t
+	c.mu.Unlock()
+}
+
+// Palette returns the active theme's palette.
+func (c *Current) Palette() Palette {
+	return c.Load().Palette
+}
+
+// ParseColor parses a "#rrggbb" or "#rgb" hex color.
+func ParseColor(value string) (color.RGBA, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(raw) == 3 {
+		raw = string([]byte{raw[0], raw[0], raw[1], raw[1], raw[2], raw[2]})
+	}
+	if len(raw) != 6 {
+		return color.RGBA{}, fmt.Errorf("theme: invalid color %q (want #rrggbb)", value)
+	}
+	n, err := strconv.ParseUint(raw, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("theme: invalid color %q: %w", value, err)
+	}
+	return color.RGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 0xff}, nil
+}
@@ -0,0 +1,74 @@
+package theme
+
+import (
+	"testing"
+	"time"
+
+	"musicDisplay/schedule"
+)
+
+func TestScheduleActiveWrapsAroundMidnight(t *testing.T) {
+	day := mustVariant(t, "day", "07:00")
+	night := mustVariant(t, "night", "22:00")
+	sched, err := NewSchedule([]Variant{day, night}, nil)
+	if err != nil {
+		t.Fatalf("NewSchedule error: %v", err)
+	}
+
+	cases := []struct {
+		clock string
+		want  string
+	}{
+		{"06:59", "night"},
+		{"07:00", "day"},
+		{"21:59", "day"},
+		{"22:00", "night"},
+		{"23:30", "night"},
+	}
+	for _, tc := range cases {
+		now, _ := time.Parse("2006-01-02 15:04", "2024-03-10 "+tc.clock)
+		if got := sched.Active(now).Name; got != tc.want {
+			t.Fatalf("Active(%s) = %q, want %q", tc.clock, got, tc.want)
+		}
+	}
+
+	now, _ := time.Parse("2006-01-02 15:04", "2024-03-10 23:30")
+	next, ok := sched.NextChange(now)
+	if !ok {
+		t.Fatal("expected a next change")
+	}
+	if want, _ := time.Parse("2006-01-02 15:04", "2024-03-11 07:00"); !next.Equal(want) {
+		t.Fatalf("NextChange = %s, want %s", next, want)
+	}
+}
+
+func TestNewScheduleRequiresCoordinatesForSolarStarts(t *testing.T) {
+	if _, err := NewSchedule([]Variant{mustVariant(t, "night", "sunset")}, nil); err == nil {
+		t.Fatal("expected error for sunset theme without coordinates")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#1DB954")
+	if err != nil {
+		t.Fatalf("ParseColor error: %v", err)
+	}
+	if c.R != 0x1d || c.G != 0xb9 || c.B != 0x54 || c.A != 0xff {
+		t.Fatalf("ParseColor = %+v", c)
+	}
+	if c, err := ParseColor("#fff"); err != nil || c.R != 0xff || c.B != 0xff {
+		t.Fatalf("ParseColor short form = %+v, %v", c, err)
+	}
+	if _, err := ParseColor("blue"); err == nil {
+		t.Fatal("expected error for named color")
+	}
+}
+
+func mustVariant(t *testing.T, name, start string) Variant {
+	t.Helper()
+	tod, err := schedule.ParseTimeOfDay(start)
+	if err != nil {
+		t.Fatalf("ParseTimeOfDay(%q): %v", start, err)
+	}
+	return Variant{Theme: Theme{Name: name}, Start: tod}
+}
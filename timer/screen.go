@@ -0,0 +1,76 @@
+package timer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+// ScreenName is the name the timer screen is shown by.
+const ScreenName = "timer"
+
+// flashPeriod is how long each phase of a finished timer's flashing lasts.
+const flashPeriod = 500 * time.Millisecond
+
+// Draw implements render.Screen: a label on top, the time left or elapsed
+// in large digits, and for a countdown a bar that empties as it runs. A
+// finished countdown flashes the whole panel in the accent color.
+func (t *Timer) Draw(frame *image.RGBA, state render.FrameState) error {
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	status := t.Status(state.Now)
+	font := text.Medium
+	fg, accent := state.Palette.Text, state.Palette.Accent
+
+	label, digits := "", ""
+	switch status.Mode {
+	case ModeCountdown:
+		label, digits = "Timer", formatDuration(status.Remaining)
+	case ModeStopwatch:
+		label, digits = "Stopwatch", formatDuration(status.Elapsed)
+	case ModeDone:
+		label, digits = "Time's up", formatDuration(0)
+		if state.Now.Sub(t.start.Add(status.Duration))/flashPeriod%2 == 0 {
+			draw.Draw(frame, bounds, image.NewUniform(accent), image.Point{}, draw.Src)
+			fg, accent = state.Palette.Background, state.Palette.Background
+		}
+	default:
+		return nil
+	}
+
+	width := font.Measure(label) * scale
+	font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, bounds.Min.Y+6*scale, label, accent, scale)
+
+	digitScale := 2 * scale
+	if font.Measure(digits)*digitScale > bounds.Dx()-2*scale {
+		digitScale = scale
+	}
+	width = font.Measure(digits) * digitScale
+	font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, bounds.Min.Y+(bounds.Dy()-font.Ascent()*digitScale)/2, digits, fg, digitScale)
+
+	if status.Mode == ModeCountdown {
+		bar := image.Rect(bounds.Min.X+4*scale, bounds.Max.Y-10*scale, bounds.Max.X-4*scale, bounds.Max.Y-8*scale)
+		filled := bar
+		filled.Max.X = min(bar.Min.X+int(float64(bar.Dx())*float64(status.Remaining)/float64(status.Duration)), bar.Max.X)
+		// The emptied part is the accent at a quarter, like the track
+		// progress bar.
+		track := color.RGBA{R: accent.R / 4, G: accent.G / 4, B: accent.B / 4, A: 0xff}
+		draw.Draw(frame, bar, image.NewUniform(track), image.Point{}, draw.Src)
+		draw.Draw(frame, filled, image.NewUniform(accent), image.Point{}, draw.Src)
+	}
+	return nil
+}
+
+// formatDuration formats d as "4:05", or "1:04:05" from an hour up.
+func formatDuration(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,187 @@
+// Package timer runs a kitchen countdown timer and a stopwatch and draws
+// them as a screen that takes over the display while either runs.
+package timer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/render"
+)
+
+var logger = logging.For("timer")
+
+const (
+	// ModeOff means neither the timer nor the stopwatch is running.
+	ModeOff = "off"
+	// ModeCountdown means the timer is counting down.
+	ModeCountdown = "countdown"
+	// ModeDone means the timer has run out and the screen is flashing.
+	ModeDone = "done"
+	// ModeStopwatch means the stopwatch is counting up.
+	ModeStopwatch = "stopwatch"
+
+	// DefaultAlarm is how long a finished timer flashes before the display
+	// returns to what it showed before.
+	DefaultAlarm = 15 * time.Second
+	// MaxDuration is the longest countdown Start accepts.
+	MaxDuration = 24 * time.Hour
+	// MaxStopwatch is how long the stopwatch runs before it stops itself,
+	// so a forgotten one does not hold the display for good.
+	MaxStopwatch = 24 * time.Hour
+
+	// focusPriority is the screen's focus priority while it runs.
+	focusPriority = 100
+)
+
+// Status is what the timer is doing at a moment.
+type Status struct {
+	Mode string
+	// Remaining is the time left on the countdown, rounded up to the
+	// second, and Duration the countdown's full length.
+	Remaining time.Duration
+	Duration  time.Duration
+	// Elapsed is the time on the stopwatch.
+	Elapsed time.Duration
+}
+
+// Timer is a countdown timer and stopwatch; starting one replaces the
+// other. It implements render.FocusScreen, asking for focus while either
+// runs and while a finished countdown flashes.
+type Timer struct {
+	clk    clock.Clock
+	alarm  time.Duration
+	onDone func()
+
+	mu       sync.Mutex
+	mode     string
+	start    time.Time
+	duration time.Duration
+	// cancel stops the goroutine waiting for the running countdown.
+	cancel chan struct{}
+}
+
+var _ render.FocusScreen = (*Timer)(nil)
+
+// New returns a stopped timer. A finished countdown flashes for alarm
+// (DefaultAlarm when zero), and onDone, when set, is called from its own
+// goroutine as it runs out, such as to sound a chime.
+func New(clk clock.Clock, alarm time.Duration, onDone func()) *Timer {
+	if alarm <= 0 {
+		alarm = DefaultAlarm
+	}
+	return &Timer{clk: clock.Or(clk), alarm: alarm, onDone: onDone, mode: ModeOff}
+}
+
+// Start starts a countdown of d, replacing a running countdown or
+// stopwatch.
+func (t *Timer) Start(d time.Duration) error {
+	if d < time.Second || d > MaxDuration {
+		return fmt.Errorf("timer: duration must be between 1s and %s, got %s", MaxDuration, d)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked()
+	t.mode, t.start, t.duration = ModeCountdown, t.clk.Now(), d
+	cancel := make(chan struct{})
+	t.cancel = cancel
+	timer := t.clk.NewTimer(d)
+	go func() {
+		select {
+		case <-cancel:
+			timer.Stop()
+		case <-timer.C():
+			t.finish(cancel)
+		}
+	}()
+	logger.Info("timer started", "duration", d)
+	return nil
+}
+
+// StartStopwatch starts the stopwatch from zero, replacing a running
+// countdown or stopwatch.
+func (t *Timer) StartStopwatch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked()
+	t.mode, t.start, t.duration = ModeStopwatch, t.clk.Now(), 0
+	logger.Info("stopwatch started")
+}
+
+// Stop stops the countdown or stopwatch and silences a finished timer.
+func (t *Timer) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLocked()
+}
+
+func (t *Timer) stopLocked() {
+	if t.cancel != nil {
+		close(t.cancel)
+		t.cancel = nil
+	}
+	t.mode = ModeOff
+}
+
+// finish runs as the countdown started with cancel runs out, unless it has
+// been replaced since.
+func (t *Timer) finish(cancel chan struct{}) {
+	t.mu.Lock()
+	current := t.cancel == cancel
+	if current {
+		t.cancel = nil
+	}
+	t.mu.Unlock()
+	if !current {
+		return
+	}
+	logger.Info("timer done")
+	if t.onDone != nil {
+		t.onDone()
+	}
+}
+
+// Status returns what the timer is doing at now.
+func (t *Timer) Status(now time.Time) Status {
+	t.mu.Lock()
+	mode, start, duration := t.mode, t.start, t.duration
+	t.mu.Unlock()
+	elapsed := max(now.Sub(start), 0)
+	switch mode {
+	case ModeCountdown:
+		if elapsed < duration {
+			remaining := (duration - elapsed + time.Second - 1).Truncate(time.Second)
+			return Status{Mode: ModeCountdown, Remaining: remaining, Duration: duration}
+		}
+		if elapsed < duration+t.alarm {
+			return Status{Mode: ModeDone, Duration: duration}
+		}
+	case ModeStopwatch:
+		if elapsed < MaxStopwatch {
+			return Status{Mode: ModeStopwatch, Elapsed: elapsed}
+		}
+	}
+	return Status{Mode: ModeOff}
+}
+
+// Name implements render.Screen.
+func (t *Timer) Name() string {
+	return ScreenName
+}
+
+// Focus implements render.FocusScreen.
+func (t *Timer) Focus(state render.FrameState) int {
+	if t.Status(state.Now).Mode == ModeOff {
+		return 0
+	}
+	return focusPriority
+}
+
+// Animating implements render.AnimatedLayer, so the renderer redraws the
+// seconds and the flashing as they change.
+func (t *Timer) Animating() bool {
+	return t.Status(t.clk.Now()).Mode != ModeOff
+}
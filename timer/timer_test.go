@@ -0,0 +1,132 @@
+package timer
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/theme"
+)
+
+// waitForTimers waits until the countdown goroutine has started waiting.
+func waitForTimers(t *testing.T, clk *clock.Fake, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for clk.Timers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clock has %d timers, want %d", clk.Timers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCountdownRunsOutAndFlashes(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	done := make(chan struct{}, 1)
+	tm := New(clk, 10*time.Second, func() { done <- struct{}{} })
+	if err := tm.Start(90 * time.Second); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	state := render.FrameState{Now: clk.Now()}
+	if tm.Focus(state) == 0 || !tm.Animating() {
+		t.Fatal("a running timer should ask for focus")
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	if got := tm.Status(clk.Now()); got.Mode != ModeCountdown || got.Remaining != 90*time.Second {
+		t.Fatalf("status = %+v, want 1:30 left", got)
+	}
+	clk.Advance(89 * time.Second)
+	if got := tm.Status(clk.Now()); got.Remaining != time.Second {
+		t.Fatalf("status = %+v, want 0:01 left", got)
+	}
+	clk.Advance(500 * time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("onDone not called")
+	}
+	if got := tm.Status(clk.Now()); got.Mode != ModeDone {
+		t.Fatalf("status = %+v, want done", got)
+	}
+	clk.Advance(10 * time.Second)
+	if got := tm.Status(clk.Now()); got.Mode != ModeOff || tm.Focus(render.FrameState{Now: clk.Now()}) != 0 {
+		t.Fatalf("status = %+v, want off once the alarm ends", got)
+	}
+}
+
+func TestStopAndReplaceCancelCountdown(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	done := make(chan struct{}, 2)
+	tm := New(clk, 0, func() { done <- struct{}{} })
+	if err := tm.Start(time.Minute); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	tm.StartStopwatch()
+	waitForTimers(t, clk, 0)
+	clk.Advance(2 * time.Minute)
+	if got := tm.Status(clk.Now()); got.Mode != ModeStopwatch || got.Elapsed != 2*time.Minute {
+		t.Fatalf("status = %+v, want the stopwatch at 2:00", got)
+	}
+	tm.Stop()
+	if got := tm.Status(clk.Now()); got.Mode != ModeOff {
+		t.Fatalf("status = %+v, want off", got)
+	}
+	select {
+	case <-done:
+		t.Fatal("onDone called for a replaced countdown")
+	default:
+	}
+
+	for _, d := range []time.Duration{0, 500 * time.Millisecond, 25 * time.Hour} {
+		if err := tm.Start(d); err == nil {
+			t.Errorf("Start(%s) succeeded", d)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	tm := New(clk, 0, nil)
+	palette := theme.DefaultPalette
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	count := func(c any) int {
+		n := 0
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				if frame.RGBAAt(x, y) == c {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	if err := tm.Start(75 * time.Minute); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	if err := tm.Draw(frame, render.FrameState{Palette: palette, Now: clk.Now()}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	if count(palette.Text) == 0 || frame.RGBAAt(32, 55) != palette.Accent {
+		t.Fatal("countdown draws no digits or no full bar")
+	}
+
+	clk.Advance(75 * time.Minute)
+	frame = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	if err := tm.Draw(frame, render.FrameState{Palette: palette, Now: clk.Now()}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	if count(palette.Accent) < 64*64/2 {
+		t.Fatal("finished timer does not flash the panel")
+	}
+
+	if got := formatDuration(75*time.Minute + 5*time.Second); got != "1:15:05" {
+		t.Errorf("formatDuration = %q", got)
+	}
+	if got := formatDuration(65 * time.Second); got != "1:05" {
+		t.Errorf("formatDuration = %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package visualizer
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft transforms x in place with the radix-2 Cooley-Tukey algorithm. Its
+// length must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
+
+// spectrum returns the level of each of bands frequency bands in samples,
+// taken at sampleRate, in decibels relative to a full-scale sine. The bands
+// are spaced evenly on a log scale from minFrequency to maxFrequency, as
+// the ear hears pitch. len(samples) must be a power of two.
+func spectrum(samples []float64, bands, sampleRate int) []float64 {
+	n := len(samples)
+	x := make([]complex128, n)
+	for i, v := range samples {
+		// A Hann window keeps loud bins from smearing across the others.
+		hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		x[i] = complex(v*hann, 0)
+	}
+	fft(x)
+
+	levels := make([]float64, bands)
+	binWidth := float64(sampleRate) / float64(n)
+	ratio := math.Pow(maxFrequency/minFrequency, 1/float64(bands))
+	low := minFrequency
+	for band := range levels {
+		high := low * ratio
+		first := max(int(low/binWidth), 1)
+		last := min(max(int(high/binWidth), first), n/2-1)
+		peak := 0.0
+		for bin := first; bin <= last; bin++ {
+			peak = max(peak, cmplx.Abs(x[bin]))
+		}
+		// A full-scale sine peaks at n/4 through the Hann window.
+		levels[band] = 20 * math.Log10(max(peak/(float64(n)/4), 1e-9))
+		low = high
+	}
+	return levels
+}
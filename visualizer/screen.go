@@ -0,0 +1,113 @@
+package visualizer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"musicDisplay/render"
+)
+
+const (
+	// dynamicRange is the span of levels the bars show, in dB below the
+	// ceiling.
+	dynamicRange = 45
+	// minCeiling keeps a quiet room from being amplified into noise, in dB.
+	minCeiling = -50
+	// ceilingFall is how fast the ceiling sinks after the music gets
+	// quieter, in dB per second.
+	ceilingFall = 3
+	// barFall and peakFall are how fast the bars and the peak marks above
+	// them drop, in panel heights per second.
+	barFall  = 1.5
+	peakFall = 0.4
+	// minWaveGain keeps the waveform of a quiet room flat instead of
+	// amplifying its noise to full height.
+	minWaveGain = 0.05
+)
+
+// Draw implements render.Screen: spectrum bars in the accent color with a
+// peak mark in the text color above each, or the waveform across the
+// middle of the panel. The bars fill the panel's width, so a wide panel
+// gets more of them, and neither style draws anything until a full window
+// of audio has arrived.
+func (s *Screen) Draw(frame *image.RGBA, state render.FrameState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < windowSize {
+		return nil
+	}
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	elapsed := 0.0
+	if !s.drawn.IsZero() {
+		elapsed = min(max(state.Now.Sub(s.drawn).Seconds(), 0), 0.25)
+	}
+	s.drawn = state.Now
+	if s.opts.Style == StyleWave {
+		drawWave(frame, s.samples, state.Palette.Accent, scale)
+		return nil
+	}
+	s.drawBars(frame, state, scale, elapsed)
+	return nil
+}
+
+// drawBars updates the bars from the latest spectrum, elapsed seconds after
+// they were last drawn, and draws them. Callers must hold s.mu.
+func (s *Screen) drawBars(frame *image.RGBA, state render.FrameState, scale int, elapsed float64) {
+	bounds := frame.Bounds()
+	width, gap := 3*scale, scale
+	count := max((bounds.Dx()+gap)/(width+gap), 1)
+	levels := spectrum(s.samples, count, sampleRate)
+	if len(s.levels) != count {
+		s.levels, s.peaks = make([]float64, count), make([]float64, count)
+	}
+
+	loudest := math.Inf(-1)
+	for _, level := range levels {
+		loudest = max(loudest, level)
+	}
+	s.ceiling = max(loudest, s.ceiling-ceilingFall*elapsed, minCeiling)
+
+	accent := image.NewUniform(state.Palette.Accent)
+	peak := image.NewUniform(state.Palette.Text)
+	left := bounds.Min.X + (bounds.Dx()-(count*(width+gap)-gap))/2
+	for i, level := range levels {
+		value := min(max((level-(s.ceiling-dynamicRange))/dynamicRange, 0), 1)
+		s.levels[i] = max(value, s.levels[i]-barFall*elapsed)
+		s.peaks[i] = max(s.levels[i], s.peaks[i]-peakFall*elapsed)
+
+		x := left + i*(width+gap)
+		height := int(s.levels[i] * float64(bounds.Dy()-2*scale))
+		draw.Draw(frame, image.Rect(x, bounds.Max.Y-height, x+width, bounds.Max.Y), accent, image.Point{}, draw.Src)
+		top := bounds.Max.Y - int(s.peaks[i]*float64(bounds.Dy()-2*scale)) - scale
+		draw.Draw(frame, image.Rect(x, top, x+width, top+scale), peak, image.Point{}, draw.Src)
+	}
+}
+
+// drawWave draws samples as a line across the middle of frame, scaled so
+// the loudest fills its height.
+func drawWave(frame *image.RGBA, samples []float64, col color.RGBA, scale int) {
+	bounds := frame.Bounds()
+	gain := minWaveGain
+	for _, v := range samples {
+		gain = max(gain, math.Abs(v))
+	}
+	middle := bounds.Min.Y + bounds.Dy()/2
+	amplitude := float64(bounds.Dy()/2 - scale)
+	fill := image.NewUniform(col)
+	previous := middle
+	for x := bounds.Min.X; x < bounds.Max.X; x += scale {
+		v := samples[(x-bounds.Min.X)*len(samples)/bounds.Dx()]
+		y := middle - int(v/gain*amplitude)
+		// Join each column to the one before, so steep edges stay
+		// connected.
+		top, bottom := min(y, previous), max(y, previous)
+		if x == bounds.Min.X {
+			top, bottom = y, y
+		}
+		draw.Draw(frame, image.Rect(x, top, x+scale, bottom+scale).Intersect(bounds), fill, image.Point{}, draw.Src)
+		previous = y
+	}
+}
@@ -0,0 +1,278 @@
+// Package visualizer draws the sound in the room, picked up by a USB or
+// other ALSA microphone, as spectrum bars or a waveform in place of the
+// artwork while music plays. Audio is captured with arecord from
+// alsa-utils, so the microphone is only opened while the visualizer is on.
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+	"musicDisplay/render"
+)
+
+var logger = logging.For("visualizer")
+
+const (
+	// ScreenName is the name the visualizer screen is shown by.
+	ScreenName = "visualizer"
+
+	// Styles the visualizer draws in.
+	StyleBars = "bars"
+	StyleWave = "wave"
+
+	// DefaultDevice is the ALSA capture device used when none is set.
+	DefaultDevice = "default"
+
+	// sampleRate is the capture rate, enough for the frequencies shown.
+	sampleRate = 22050
+	// windowSize is how many of the latest samples are analysed per frame,
+	// about 46ms.
+	windowSize = 1024
+	// readSize is how many samples are read from the microphone at a time.
+	readSize = 256
+	// minFrequency and maxFrequency bound the spectrum bars, in Hz.
+	minFrequency = 40.0
+	maxFrequency = 10000.0
+
+	// retryDelay is how soon capture starts again after it failed, such as
+	// while the microphone is unplugged.
+	retryDelay = 30 * time.Second
+	// staleAfter is how long after the last samples arrived the artwork
+	// shows again.
+	staleAfter = time.Second
+
+	// focusPriority is the screen's focus priority while music plays with
+	// the visualizer on: above the music but below lyrics, diagnostics,
+	// and a running kitchen timer.
+	focusPriority = 15
+)
+
+// ValidStyle reports whether style names a style, or is empty for the
+// default.
+func ValidStyle(style string) bool {
+	switch style {
+	case "", StyleBars, StyleWave:
+		return true
+	}
+	return false
+}
+
+// Options configures a Screen.
+type Options struct {
+	// Device is the ALSA capture device, such as "plughw:1,0" for the
+	// first USB microphone (DefaultDevice when empty).
+	Device string
+	// Style is StyleBars (default) or StyleWave.
+	Style string
+}
+
+// Screen is the visualizer screen. It implements render.FocusScreen: while
+// it is on and music plays, it asks for focus as long as the microphone
+// delivers sound.
+type Screen struct {
+	clk  clock.Clock
+	opts Options
+	// command starts the capture, writing raw signed 16-bit little-endian
+	// mono samples at sampleRate to its output; tests replace it.
+	command func(ctx context.Context) *exec.Cmd
+	// changed wakes Run when the visualizer is turned on or off.
+	changed chan struct{}
+
+	mu      sync.Mutex
+	enabled bool
+	showing bool
+	// samples are the latest windowSize samples, oldest first, from -1 to
+	// 1; received is when the last arrived.
+	samples  []float64
+	received time.Time
+	// levels and peaks are the bars as last drawn, from 0 to 1; ceiling is
+	// the loudest level heard lately, in dB, which the bars are scaled to.
+	levels  []float64
+	peaks   []float64
+	ceiling float64
+	drawn   time.Time
+}
+
+var _ render.FocusScreen = (*Screen)(nil)
+
+// New returns a visualizer screen, on or off. It captures nothing until Run
+// is called.
+func New(clk clock.Clock, opts Options, enabled bool) *Screen {
+	if opts.Device == "" {
+		opts.Device = DefaultDevice
+	}
+	if !ValidStyle(opts.Style) || opts.Style == "" {
+		opts.Style = StyleBars
+	}
+	device := opts.Device
+	return &Screen{
+		clk:  clock.Or(clk),
+		opts: opts,
+		command: func(ctx context.Context) *exec.Cmd {
+			return exec.CommandContext(ctx, "arecord", "-q", "-D", device, "-t", "raw", "-f", "S16_LE", "-c", "1", "-r", strconv.Itoa(sampleRate))
+		},
+		changed: make(chan struct{}, 1),
+		enabled: enabled,
+	}
+}
+
+// SetEnabled turns the visualizer on or off, opening or closing the
+// microphone.
+func (s *Screen) SetEnabled(on bool) {
+	s.mu.Lock()
+	changed := s.enabled != on
+	s.enabled = on
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+	logger.Info("visualizer changed", "enabled", on)
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Enabled reports whether the visualizer is on.
+func (s *Screen) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// Name implements render.Screen.
+func (s *Screen) Name() string {
+	return ScreenName
+}
+
+// Focus implements render.FocusScreen. It asks for focus while the
+// visualizer is on, music plays, and sound has arrived within staleAfter,
+// so the artwork shows while the microphone is missing.
+func (s *Screen) Focus(state render.FrameState) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.showing = s.enabled && state.Status.Playing && !s.received.IsZero() && state.Now.Sub(s.received) < staleAfter
+	if !s.showing {
+		return 0
+	}
+	return focusPriority
+}
+
+// Animating implements render.AnimatedLayer, so the renderer redraws the
+// visualizer as the sound changes.
+func (s *Screen) Animating() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.showing
+}
+
+// Run captures from the microphone while the visualizer is on, until ctx
+// is done. Capture that fails is started again after retryDelay.
+func (s *Screen) Run(ctx context.Context) {
+	for {
+		if !s.Enabled() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.changed:
+				continue
+			}
+		}
+		captureCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- s.capture(captureCtx) }()
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		case <-s.changed:
+			cancel()
+			<-done
+			continue
+		case err := <-done:
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("microphone capture stopped", "device", s.opts.Device, "err", err, "retry", retryDelay)
+		}
+		timer := s.clk.NewTimer(retryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.changed:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
+
+// capture runs the capture command and feeds its samples to the screen
+// until it exits or ctx is done.
+func (s *Screen) capture(ctx context.Context) error {
+	cmd := s.command(ctx)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("visualizer: capture: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("visualizer: start capture: %w", err)
+	}
+	logger.Info("microphone capture started", "device", s.opts.Device)
+	readErr := s.read(out)
+	waitErr := cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case waitErr != nil:
+		return fmt.Errorf("visualizer: capture: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	case readErr != nil && !errors.Is(readErr, io.EOF):
+		return fmt.Errorf("visualizer: read capture: %w", readErr)
+	}
+	return errors.New("visualizer: capture ended")
+}
+
+// read feeds the samples in r to the screen until r ends.
+func (s *Screen) read(r io.Reader) error {
+	buf := make([]byte, 2*readSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n >= 2 {
+			samples := make([]float64, n/2)
+			for i := range samples {
+				samples[i] = float64(int16(binary.LittleEndian.Uint16(buf[2*i:]))) / 32768
+			}
+			s.push(samples)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// push adds samples to the window.
+func (s *Screen) push(samples []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, samples...)
+	if extra := len(s.samples) - windowSize; extra > 0 {
+		s.samples = append(s.samples[:0], s.samples[extra:]...)
+	}
+	s.received = s.clk.Now()
+}
@@ -0,0 +1,165 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"math"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/sonos"
+	"musicDisplay/theme"
+)
+
+// sine returns n samples of a full-scale sine at frequency, as arecord
+// writes them.
+func sine(frequency float64, n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		v := int16(32767 * math.Sin(2*math.Pi*frequency*float64(i)/sampleRate))
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func TestSpectrumFindsTheTone(t *testing.T) {
+	samples := make([]float64, windowSize)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 1000 * float64(i) / sampleRate)
+	}
+	levels := spectrum(samples, 16, sampleRate)
+	loudest := 0
+	for i, level := range levels {
+		if level > levels[loudest] {
+			loudest = i
+		}
+	}
+	// 1 kHz falls in band 9 of 16 between 40 Hz and 10 kHz.
+	ratio := math.Pow(maxFrequency/minFrequency, 1.0/16)
+	low := minFrequency * math.Pow(ratio, float64(loudest))
+	if low > 1000 || low*ratio < 1000 {
+		t.Fatalf("loudest band %d covers %.0f-%.0f Hz, want 1 kHz", loudest, low, low*ratio)
+	}
+	if levels[loudest] < -3 || levels[loudest] > 1 {
+		t.Fatalf("full-scale tone at %.1f dB, want about 0", levels[loudest])
+	}
+	if levels[0] > -40 {
+		t.Fatalf("lowest band at %.1f dB, want it quiet", levels[0])
+	}
+}
+
+func TestFocusFollowsSound(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+	s := New(clk, Options{}, true)
+	playing := render.FrameState{Now: clk.Now(), Status: sonos.PlaybackStatus{Playing: true}}
+	if s.Focus(playing) != 0 {
+		t.Fatal("focus asked for before any sound arrived")
+	}
+	if err := s.read(bytes.NewReader(sine(440, windowSize))); err == nil {
+		t.Fatal("read did not report the end of the capture")
+	}
+	if s.Focus(playing) != focusPriority || !s.Animating() {
+		t.Fatal("no focus with sound arriving")
+	}
+	paused := playing
+	paused.Status.Playing = false
+	if s.Focus(paused) != 0 {
+		t.Fatal("focus asked for while paused")
+	}
+	clk.Advance(staleAfter)
+	playing.Now = clk.Now()
+	if s.Focus(playing) != 0 {
+		t.Fatal("focus kept after the microphone went quiet")
+	}
+	s.SetEnabled(false)
+	s.push(make([]float64, readSize))
+	if s.Focus(playing) != 0 {
+		t.Fatal("focus asked for while off")
+	}
+}
+
+func TestDrawStyles(t *testing.T) {
+	for _, style := range []string{StyleBars, StyleWave} {
+		clk := clock.NewFake(time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC))
+		s := New(clk, Options{Style: style}, true)
+		s.read(bytes.NewReader(sine(440, windowSize)))
+		frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		state := render.FrameState{Palette: theme.DefaultPalette, Now: clk.Now()}
+		if err := s.Draw(frame, state); err != nil {
+			t.Fatalf("%s: Draw error: %v", style, err)
+		}
+		lit := 0
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				if frame.RGBAAt(x, y) == theme.DefaultPalette.Accent {
+					lit++
+				}
+			}
+		}
+		if lit == 0 {
+			t.Fatalf("%s: nothing drawn in the accent color", style)
+		}
+	}
+}
+
+// TestCaptureHelper stands in for arecord when run by
+// TestRunCapturesWhileEnabled.
+func TestCaptureHelper(t *testing.T) {
+	if os.Getenv("VISUALIZER_CAPTURE_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	for {
+		os.Stdout.Write(sine(440, readSize))
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunCapturesWhileEnabled(t *testing.T) {
+	s := New(clock.Real, Options{}, false)
+	started := make(chan struct{}, 10)
+	s.command = func(ctx context.Context) *exec.Cmd {
+		started <- struct{}{}
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestCaptureHelper$")
+		cmd.Env = append(os.Environ(), "VISUALIZER_CAPTURE_HELPER=1")
+		return cmd
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("capture started while off")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.SetEnabled(true)
+	<-started
+	state := render.FrameState{Status: sonos.PlaybackStatus{Playing: true}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state.Now = time.Now()
+		if s.Focus(state) == focusPriority {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no sound captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop the capture")
+	}
+}
@@ -0,0 +1,213 @@
+package weather
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// iconSize is the width and height of the condition icons at scale 1.
+const iconSize = 16
+
+// iconColors maps the letters in icon rows onto colors; '.' is transparent.
+var iconColors = map[rune]color.RGBA{
+	'Y': {R: 0xff, G: 0xc8, B: 0x20, A: 0xff},
+	'M': {R: 0xf0, G: 0xe8, B: 0xb0, A: 0xff},
+	'W': {R: 0xe0, G: 0xe0, B: 0xe8, A: 0xff},
+	'G': {R: 0x80, G: 0x80, B: 0x90, A: 0xff},
+	'B': {R: 0x40, G: 0x90, B: 0xff, A: 0xff},
+	'C': {R: 0xc0, G: 0xf0, B: 0xff, A: 0xff},
+}
+
+// Icon layers, sixteen rows of sixteen pixels; rows not listed are blank.
+// Conditions stack several, such as a cloud over rain.
+var (
+	sunIcon = []string{
+		"................",
+		".......Y........",
+		"..Y....Y....Y...",
+		"...Y.......Y....",
+		"......YYY.......",
+		".....YYYYY......",
+		"....YYYYYYY.....",
+		".YY.YYYYYYY.YY..",
+		"....YYYYYYY.....",
+		".....YYYYY......",
+		"......YYY.......",
+		"...Y.......Y....",
+		"..Y....Y....Y...",
+		".......Y........",
+	}
+	moonIcon = []string{
+		"................",
+		"................",
+		".....MMMM.......",
+		"...MMMM.........",
+		"..MMMM..........",
+		"..MMM...........",
+		".MMMM...........",
+		".MMMM...........",
+		".MMMM...........",
+		".MMMMM..........",
+		"..MMMMM.....M...",
+		"..MMMMMMMMMMM...",
+		"....MMMMMMMM....",
+		"......MMMM......",
+	}
+	smallSunIcon = []string{
+		"....Y...........",
+		".Y.....Y........",
+		"...YYY..........",
+		"..YYYYY.........",
+		"Y.YYYYY.Y.......",
+		"..YYYYY.........",
+		"...YYY..........",
+		".Y.....Y........",
+		"....Y...........",
+	}
+	smallMoonIcon = []string{
+		"................",
+		"...MMM..........",
+		"..MM............",
+		".MM.............",
+		".MM.............",
+		".MMM...M........",
+		"..MMMMMM........",
+		"...MMMM.........",
+	}
+	cloudIcon = []string{
+		"................",
+		"................",
+		"................",
+		"................",
+		"......WWWW......",
+		".....WWWWWW.....",
+		"..WWWWWWWWWW....",
+		".WWWWWWWWWWWWW..",
+		"WWWWWWWWWWWWWWW.",
+		"WWWWWWWWWWWWWWW.",
+		".WWWWWWWWWWWWW..",
+	}
+	darkCloudIcon = recolor(cloudIcon, 'W', 'G')
+	rainIcon      = []string{
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"...B....B....B..",
+		"..B....B....B...",
+		"................",
+		".B....B....B....",
+	}
+	snowIcon = []string{
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"..C....C....C...",
+		".CCC..CCC..CCC..",
+		"..C....C....C...",
+	}
+	boltIcon = []string{
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		"........YY......",
+		".......YY.......",
+		"......YYYY......",
+		"........Y.......",
+		".......Y........",
+		"......Y.........",
+	}
+	fogIcon = []string{
+		"................",
+		"................",
+		"................",
+		"................",
+		"................",
+		".GGGGGGGGGGGG...",
+		"................",
+		"...GGGGGGGGGGGG.",
+		"................",
+		".GGGGGGGGGGGG...",
+		"................",
+		"...GGGGGGGGGGGG.",
+	}
+)
+
+// iconLayers returns the layers drawn, bottom first, for condition.
+func iconLayers(condition Condition, day bool) [][]string {
+	sun, smallSun := sunIcon, smallSunIcon
+	if !day {
+		sun, smallSun = moonIcon, smallMoonIcon
+	}
+	switch condition {
+	case Clear:
+		return [][]string{sun}
+	case PartlyCloudy:
+		return [][]string{smallSun, cloudIcon}
+	case Fog:
+		return [][]string{fogIcon}
+	case Rain:
+		return [][]string{cloudIcon, rainIcon}
+	case Snow:
+		return [][]string{cloudIcon, snowIcon}
+	case Thunder:
+		return [][]string{darkCloudIcon, boltIcon}
+	}
+	return [][]string{cloudIcon}
+}
+
+// drawIcon draws condition's icon into dst with its top-left corner at
+// (x, y), each icon pixel scale pixels square.
+func drawIcon(dst *image.RGBA, x, y int, condition Condition, day bool, scale int) {
+	bounds := dst.Bounds()
+	for _, layer := range iconLayers(condition, day) {
+		for row, line := range layer {
+			for column, c := range line {
+				col, ok := iconColors[c]
+				if !ok {
+					continue
+				}
+				px, py := x+column*scale, y+row*scale
+				cell := image.Rect(px, py, px+scale, py+scale).Intersect(bounds)
+				for cy := cell.Min.Y; cy < cell.Max.Y; cy++ {
+					for cx := cell.Min.X; cx < cell.Max.X; cx++ {
+						dst.SetRGBA(cx, cy, col)
+					}
+				}
+			}
+		}
+	}
+}
+
+func recolor(rows []string, from, to rune) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = strings.ReplaceAll(row, string(from), string(to))
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package weather
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"musicDisplay/render"
+	"musicDisplay/render/text"
+)
+
+// ScreenName is the name the weather screen is shown by, such as in
+// idle_screen or a rotation.
+const ScreenName = "weather"
+
+// Screen draws the latest report from a Source: the condition icon and the
+// temperature on top, today's high and low below, and the condition in
+// words at the bottom. It implements render.Screen.
+type Screen struct {
+	source *Source
+}
+
+// NewScreen returns a screen showing reports from source.
+func NewScreen(source *Source) *Screen {
+	return &Screen{source: source}
+}
+
+// Name implements render.Screen.
+func (s *Screen) Name() string {
+	return ScreenName
+}
+
+// Draw implements render.Screen: the condition icon at the top left with the
+// temperature beside it, then the day's high and low and the condition's
+// name centered below. Without a report it shows "--°" and "No data".
+func (s *Screen) Draw(frame *image.RGBA, state render.FrameState) error {
+	bounds := frame.Bounds()
+	scale := max(min(bounds.Dx(), bounds.Dy())/64, 1)
+	report, ok := s.source.Latest(state.Now)
+
+	iconScale := 2 * scale
+	iconX, iconY := bounds.Min.X, bounds.Min.Y+2*scale
+	temperature, summary, label := "--°", "", "No data"
+	if ok {
+		drawIcon(frame, iconX, iconY, report.Condition, report.Day, iconScale)
+		temperature = formatTemperature(report.Temperature)
+		summary = "H" + formatTemperature(report.High) + " L" + formatTemperature(report.Low)
+		label = report.Condition.Label()
+	}
+
+	// The temperature fills the space beside the icon, dropping a size
+	// when three digits and a sign do not fit.
+	right := image.Rect(iconX+iconSize*iconScale, bounds.Min.Y, bounds.Max.X, iconY+iconSize*iconScale)
+	tempScale := 2 * scale
+	if text.Medium.Measure(temperature)*tempScale > right.Dx()-scale {
+		tempScale = scale
+	}
+	width := text.Medium.Measure(temperature) * tempScale
+	text.Medium.Draw(frame, right.Max.X-width-scale, right.Min.Y+(right.Dy()-text.Medium.Ascent()*tempScale)/2, temperature, state.Palette.Text, tempScale)
+
+	y := right.Max.Y + 3*scale
+	drawCentered(frame, y, summary, state.Palette.Text, scale)
+	drawCentered(frame, y+(text.Medium.Height()+3)*scale, label, state.Palette.Accent, scale)
+	return nil
+}
+
+// drawCentered draws s centered across frame at y in the Medium font, or in
+// the narrower Small font when Medium is too wide.
+func drawCentered(frame *image.RGBA, y int, s string, col color.RGBA, scale int) {
+	bounds := frame.Bounds()
+	font := text.Medium
+	if font.Measure(s)*scale > bounds.Dx() {
+		font = text.Small
+	}
+	width := font.Measure(s) * scale
+	font.Draw(frame, bounds.Min.X+(bounds.Dx()-width)/2, y, s, col, scale)
+}
+
+// formatTemperature rounds t to whole degrees.
+func formatTemperature(t float64) string {
+	return fmt.Sprintf("%d°", int(math.Round(t)))
+}
@@ -0,0 +1,391 @@
+// Package weather fetches the local forecast from Open-Meteo or
+// OpenWeatherMap and draws it as a screen the renderer can show while no
+// music plays or rotate with the clock.
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/logging"
+)
+
+var logger = logging.For("weather")
+
+// Providers a Provider can be built for.
+const (
+	ProviderOpenMeteo      = "open-meteo"
+	ProviderOpenWeatherMap = "openweathermap"
+)
+
+// Units for temperatures.
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+const (
+	defaultOpenMeteoURL      = "https://api.open-meteo.com/v1"
+	defaultOpenWeatherMapURL = "https://api.openweathermap.org/data/2.5"
+	requestTimeout           = 10 * time.Second
+
+	// DefaultRefresh is how often a Source fetches a new report.
+	DefaultRefresh = 15 * time.Minute
+	// retryDelay is how soon a Source tries again after a failed fetch.
+	retryDelay = time.Minute
+	// maxAge is how long a report is shown after fetches start failing.
+	maxAge = 3 * time.Hour
+)
+
+// Condition is the sky summarised for the icon.
+type Condition string
+
+// Conditions a Report can carry.
+const (
+	Clear        Condition = "clear"
+	PartlyCloudy Condition = "partly-cloudy"
+	Cloudy       Condition = "cloudy"
+	Fog          Condition = "fog"
+	Rain         Condition = "rain"
+	Snow         Condition = "snow"
+	Thunder      Condition = "thunder"
+)
+
+// Label is the condition in words, short enough for a 64-pixel line.
+func (c Condition) Label() string {
+	switch c {
+	case Clear:
+		return "Clear"
+	case PartlyCloudy:
+		return "Some clouds"
+	case Cloudy:
+		return "Cloudy"
+	case Fog:
+		return "Fog"
+	case Rain:
+		return "Rain"
+	case Snow:
+		return "Snow"
+	case Thunder:
+		return "Storm"
+	}
+	return ""
+}
+
+// Report is the weather now and today's range, in the provider's units.
+type Report struct {
+	Temperature float64
+	High        float64
+	Low         float64
+	Condition   Condition
+	// Day is false between sunset and sunrise, when clear skies show a
+	// moon.
+	Day bool
+	// Fetched is when the report was retrieved.
+	Fetched time.Time
+}
+
+// Provider fetches reports from a weather service.
+type Provider interface {
+	Fetch(ctx context.Context) (Report, error)
+}
+
+// OpenMeteo fetches from Open-Meteo, which needs no API key.
+type OpenMeteo struct {
+	Latitude, Longitude float64
+	Units               string
+
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenMeteo returns a provider for the forecast at the coordinates, in
+// Metric (default) or Imperial units.
+func NewOpenMeteo(latitude, longitude float64, units string) *OpenMeteo {
+	return &OpenMeteo{
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Units:      units,
+		baseURL:    defaultOpenMeteoURL,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Fetch implements Provider.
+func (p *OpenMeteo) Fetch(ctx context.Context) (Report, error) {
+	query := url.Values{
+		"latitude":      {formatCoordinate(p.Latitude)},
+		"longitude":     {formatCoordinate(p.Longitude)},
+		"current":       {"temperature_2m,weather_code,is_day"},
+		"daily":         {"temperature_2m_max,temperature_2m_min"},
+		"timezone":      {"auto"},
+		"forecast_days": {"1"},
+	}
+	if p.Units == Imperial {
+		query.Set("temperature_unit", "fahrenheit")
+	}
+	var payload struct {
+		Current struct {
+			Temperature float64 `json:"temperature_2m"`
+			Code        int     `json:"weather_code"`
+			IsDay       int     `json:"is_day"`
+		} `json:"current"`
+		Daily struct {
+			Max []float64 `json:"temperature_2m_max"`
+			Min []float64 `json:"temperature_2m_min"`
+		} `json:"daily"`
+	}
+	if err := getJSON(ctx, p.httpClient, p.baseURL+"/forecast?"+query.Encode(), "open-meteo", &payload); err != nil {
+		return Report{}, err
+	}
+	if len(payload.Daily.Max) == 0 || len(payload.Daily.Min) == 0 {
+		return Report{}, fmt.Errorf("weather: open-meteo: response has no daily forecast")
+	}
+	return Report{
+		Temperature: payload.Current.Temperature,
+		High:        payload.Daily.Max[0],
+		Low:         payload.Daily.Min[0],
+		Condition:   wmoCondition(payload.Current.Code),
+		Day:         payload.Current.IsDay != 0,
+	}, nil
+}
+
+// wmoCondition maps a WMO weather interpretation code, as Open-Meteo
+// reports, onto a Condition.
+func wmoCondition(code int) Condition {
+	switch {
+	case code == 0:
+		return Clear
+	case code <= 2:
+		return PartlyCloudy
+	case code == 3:
+		return Cloudy
+	case code == 45 || code == 48:
+		return Fog
+	case code >= 71 && code <= 77, code == 85, code == 86:
+		return Snow
+	case code >= 95:
+		return Thunder
+	case code >= 51:
+		return Rain
+	}
+	return Cloudy
+}
+
+// OpenWeatherMap fetches from OpenWeatherMap's free current weather and
+// forecast APIs.
+type OpenWeatherMap struct {
+	APIKey              string
+	Latitude, Longitude float64
+	Units               string
+
+	baseURL    string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewOpenWeatherMap returns a provider for the weather at the coordinates,
+// in Metric (default) or Imperial units.
+func NewOpenWeatherMap(apiKey string, latitude, longitude float64, units string) *OpenWeatherMap {
+	return &OpenWeatherMap{
+		APIKey:     apiKey,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Units:      units,
+		baseURL:    defaultOpenWeatherMapURL,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		now:        time.Now,
+	}
+}
+
+// Fetch implements Provider. The free API has no daily summary, so today's
+// high and low are taken from the current reading and the three-hourly
+// forecast for the rest of the local day.
+func (p *OpenWeatherMap) Fetch(ctx context.Context) (Report, error) {
+	units := Metric
+	if p.Units == Imperial {
+		units = Imperial
+	}
+	query := url.Values{
+		"lat":   {formatCoordinate(p.Latitude)},
+		"lon":   {formatCoordinate(p.Longitude)},
+		"units": {units},
+		"appid": {p.APIKey},
+	}
+	var current struct {
+		Weather []struct {
+			ID   int    `json:"id"`
+			Icon string `json:"icon"`
+		} `json:"weather"`
+		Main struct {
+			Temp float64 `json:"temp"`
+		} `json:"main"`
+		Timezone int `json:"timezone"`
+	}
+	if err := getJSON(ctx, p.httpClient, p.baseURL+"/weather?"+query.Encode(), "openweathermap", &current); err != nil {
+		return Report{}, err
+	}
+	if len(current.Weather) == 0 {
+		return Report{}, fmt.Errorf("weather: openweathermap: response has no conditions")
+	}
+	var forecast struct {
+		List []struct {
+			Time int64 `json:"dt"`
+			Main struct {
+				Temp float64 `json:"temp"`
+			} `json:"main"`
+		} `json:"list"`
+	}
+	if err := getJSON(ctx, p.httpClient, p.baseURL+"/forecast?"+query.Encode(), "openweathermap", &forecast); err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Temperature: current.Main.Temp,
+		High:        current.Main.Temp,
+		Low:         current.Main.Temp,
+		Condition:   owmCondition(current.Weather[0].ID),
+		Day:         !strings.HasSuffix(current.Weather[0].Icon, "n"),
+	}
+	zone := time.FixedZone("", current.Timezone)
+	year, month, day := p.now().In(zone).Date()
+	for _, entry := range forecast.List {
+		y, m, d := time.Unix(entry.Time, 0).In(zone).Date()
+		if y != year || m != month || d != day {
+			continue
+		}
+		report.High = math.Max(report.High, entry.Main.Temp)
+		report.Low = math.Min(report.Low, entry.Main.Temp)
+	}
+	return report, nil
+}
+
+// owmCondition maps an OpenWeatherMap condition ID onto a Condition.
+func owmCondition(id int) Condition {
+	switch {
+	case id >= 200 && id < 300:
+		return Thunder
+	case id >= 300 && id < 600:
+		return Rain
+	case id >= 600 && id < 700:
+		return Snow
+	case id >= 700 && id < 800:
+		return Fog
+	case id == 800:
+		return Clear
+	case id == 801 || id == 802:
+		return PartlyCloudy
+	}
+	return Cloudy
+}
+
+func formatCoordinate(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+// getJSON fetches endpoint and decodes its JSON body into dst.
+func getJSON(ctx context.Context, client *http.Client, endpoint, service string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("weather: %s: build request: %w", service, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error quotes the URL, which may carry an API key.
+		return fmt.Errorf("weather: %s: request failed: %w", service, redact(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("weather: %s: %s: %s", service, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("weather: %s: decode response: %w", service, err)
+	}
+	return nil
+}
+
+// redact strips the URL from a client error.
+func redact(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// Source keeps the latest report from a provider fresh.
+type Source struct {
+	provider Provider
+	refresh  time.Duration
+
+	mu     sync.Mutex
+	latest Report
+	ok     bool
+}
+
+// NewSource returns a source that fetches from provider every refresh
+// (DefaultRefresh when zero) once Run starts.
+func NewSource(provider Provider, refresh time.Duration) *Source {
+	if refresh <= 0 {
+		refresh = DefaultRefresh
+	}
+	return &Source{provider: provider, refresh: refresh}
+}
+
+// Latest returns the most recent report, if one is fresh enough to show.
+func (s *Source) Latest(now time.Time) (Report, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ok || now.Sub(s.latest.Fetched) > maxAge {
+		return Report{}, false
+	}
+	return s.latest, true
+}
+
+// Run fetches a report straight away and then every refresh until ctx is
+// canceled, retrying sooner after a failure.
+func (s *Source) Run(ctx context.Context, clk clock.Clock) {
+	clk = clock.Or(clk)
+	for {
+		wait := s.refresh
+		if err := s.update(ctx, clk.Now()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("weather fetch failed", "err", err)
+			wait = retryDelay
+		}
+		timer := clk.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+func (s *Source) update(ctx context.Context, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	report, err := s.provider.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	report.Fetched = now
+	s.mu.Lock()
+	s.latest, s.ok = report, true
+	s.mu.Unlock()
+	logger.Debug("weather updated", "temperature", report.Temperature, "condition", report.Condition)
+	return nil
+}
@@ -0,0 +1,202 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"musicDisplay/clock"
+	"musicDisplay/render"
+	"musicDisplay/theme"
+)
+
+func TestOpenMeteoFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/forecast" || query.Get("latitude") != "52.5200" || query.Get("temperature_unit") != "fahrenheit" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"current":{"temperature_2m":41.4,"weather_code":63,"is_day":0},"daily":{"temperature_2m_max":[45.1],"temperature_2m_min":[33.8]}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenMeteo(52.52, 13.405, Imperial)
+	p.baseURL = server.URL
+	report, err := p.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	want := Report{Temperature: 41.4, High: 45.1, Low: 33.8, Condition: Rain}
+	if report != want {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestOpenWeatherMapFetchTakesRangeFromToday(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("appid") != "key" || r.URL.Query().Get("units") != Metric {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/weather":
+			w.Write([]byte(`{"weather":[{"id":801,"icon":"02d"}],"main":{"temp":14.2},"timezone":7200}`))
+		case "/forecast":
+			w.Write([]byte(`{"list":[
+				{"dt":` + unix(now.Add(3*time.Hour)) + `,"main":{"temp":19.5}},
+				{"dt":` + unix(now.Add(12*time.Hour)) + `,"main":{"temp":9.0}},
+				{"dt":` + unix(now.Add(20*time.Hour)) + `,"main":{"temp":2.0}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOpenWeatherMap("key", 48.1, 11.6, "")
+	p.baseURL = server.URL
+	p.now = func() time.Time { return now }
+	report, err := p.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	// The 20h entry falls on the next local day at UTC+2.
+	want := Report{Temperature: 14.2, High: 19.5, Low: 9.0, Condition: PartlyCloudy, Day: true}
+	if report != want {
+		t.Fatalf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestFetchReportsHTTPErrorsWithoutTheKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Invalid API key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := NewOpenWeatherMap("secret", 0, 0, Metric)
+	p.baseURL = server.URL
+	if _, err := p.Fetch(context.Background()); err == nil {
+		t.Fatal("expected an error for a rejected key")
+	}
+
+	p.baseURL = "http://127.0.0.1:1"
+	_, err := p.Fetch(context.Background())
+	if err == nil {
+		t.Fatal("expected an error for an unreachable server")
+	}
+	if got := err.Error(); strings.Contains(got, "secret") {
+		t.Fatalf("error %q leaks the API key", got)
+	}
+}
+
+func TestConditionCodes(t *testing.T) {
+	for code, want := range map[int]Condition{0: Clear, 2: PartlyCloudy, 3: Cloudy, 45: Fog, 53: Rain, 81: Rain, 75: Snow, 86: Snow, 95: Thunder} {
+		if got := wmoCondition(code); got != want {
+			t.Errorf("wmoCondition(%d) = %s, want %s", code, got, want)
+		}
+	}
+	for id, want := range map[int]Condition{211: Thunder, 301: Rain, 511: Rain, 601: Snow, 741: Fog, 800: Clear, 802: PartlyCloudy, 804: Cloudy} {
+		if got := owmCondition(id); got != want {
+			t.Errorf("owmCondition(%d) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+type fakeProvider struct {
+	mu      sync.Mutex
+	reports []Report
+	errs    []error
+	calls   int
+}
+
+func (f *fakeProvider) Fetch(context.Context) (Report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := min(f.calls, len(f.reports)-1)
+	f.calls++
+	return f.reports[i], f.errs[i]
+}
+
+func (f *fakeProvider) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestSourceRefreshesAndRetries(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	provider := &fakeProvider{
+		reports: []Report{{}, {Temperature: 20}, {Temperature: 22}},
+		errs:    []error{errors.New("offline"), nil, nil},
+	}
+	source := NewSource(provider, 10*time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go source.Run(ctx, clk)
+
+	waitFor(t, func() bool { return provider.count() == 1 && clk.Timers() == 1 })
+	if _, ok := source.Latest(clk.Now()); ok {
+		t.Fatal("Latest reported a report after a failed fetch")
+	}
+	clk.Advance(retryDelay)
+	waitFor(t, func() bool { return provider.count() == 2 && clk.Timers() == 1 })
+	if report, ok := source.Latest(clk.Now()); !ok || report.Temperature != 20 || !report.Fetched.Equal(start.Add(retryDelay)) {
+		t.Fatalf("Latest = %+v, %v after the retry", report, ok)
+	}
+	clk.Advance(10 * time.Minute)
+	waitFor(t, func() bool { return provider.count() == 3 })
+	if _, ok := source.Latest(clk.Now().Add(maxAge + time.Second)); ok {
+		t.Fatal("Latest reported a report older than maxAge")
+	}
+}
+
+func TestScreenDrawsIconAndText(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	source := &Source{latest: Report{Temperature: 7.6, High: 9, Low: -1, Condition: Clear, Day: true, Fetched: now}, ok: true}
+	frame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	screen := NewScreen(source)
+	if screen.Name() != ScreenName {
+		t.Fatalf("Name = %q", screen.Name())
+	}
+	if err := screen.Draw(frame, render.FrameState{Palette: theme.DefaultPalette, Now: now}); err != nil {
+		t.Fatalf("Draw error: %v", err)
+	}
+	// The center of the sun, in the icon at the top left.
+	if got := frame.RGBAAt(14, 16); got != iconColors['Y'] {
+		t.Fatalf("icon pixel = %v, want the sun", got)
+	}
+	var label bool
+	for y := 48; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			label = label || frame.RGBAAt(x, y) == theme.DefaultPalette.Accent
+		}
+	}
+	if !label {
+		t.Fatal("condition label not drawn along the bottom")
+	}
+	if got := formatTemperature(-0.4); got != "0°" {
+		t.Fatalf("formatTemperature(-0.4) = %q", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func unix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}